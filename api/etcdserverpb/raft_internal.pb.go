@@ -85,6 +85,10 @@ type InternalRaftRequest struct {
 	LeaseRevoke              *LeaseRevokeRequest                       `protobuf:"bytes,9,opt,name=lease_revoke,json=leaseRevoke,proto3" json:"lease_revoke,omitempty"`
 	Alarm                    *AlarmRequest                             `protobuf:"bytes,10,opt,name=alarm,proto3" json:"alarm,omitempty"`
 	LeaseCheckpoint          *LeaseCheckpointRequest                   `protobuf:"bytes,11,opt,name=lease_checkpoint,json=leaseCheckpoint,proto3" json:"lease_checkpoint,omitempty"`
+	LeaseGrantBatch          *LeaseGrantBatchRequest                   `protobuf:"bytes,12,opt,name=lease_grant_batch,json=leaseGrantBatch,proto3" json:"lease_grant_batch,omitempty"`
+	CompactKey               *CompactKeyRequest                        `protobuf:"bytes,13,opt,name=compact_key,json=compactKey,proto3" json:"compact_key,omitempty"`
+	LeaseReattach            *LeaseReattachRequest                     `protobuf:"bytes,14,opt,name=lease_reattach,json=leaseReattach,proto3" json:"lease_reattach,omitempty"`
+	PrefixQuotaSet           *PrefixQuotaSetRequest                    `protobuf:"bytes,15,opt,name=prefix_quota_set,json=prefixQuotaSet,proto3" json:"prefix_quota_set,omitempty"`
 	AuthEnable               *AuthEnableRequest                        `protobuf:"bytes,1000,opt,name=auth_enable,json=authEnable,proto3" json:"auth_enable,omitempty"`
 	AuthDisable              *AuthDisableRequest                       `protobuf:"bytes,1011,opt,name=auth_disable,json=authDisable,proto3" json:"auth_disable,omitempty"`
 	AuthStatus               *AuthStatusRequest                        `protobuf:"bytes,1013,opt,name=auth_status,json=authStatus,proto3" json:"auth_status,omitempty"`
@@ -102,6 +106,8 @@ type InternalRaftRequest struct {
 	AuthRoleGet              *AuthRoleGetRequest                       `protobuf:"bytes,1202,opt,name=auth_role_get,json=authRoleGet,proto3" json:"auth_role_get,omitempty"`
 	AuthRoleGrantPermission  *AuthRoleGrantPermissionRequest           `protobuf:"bytes,1203,opt,name=auth_role_grant_permission,json=authRoleGrantPermission,proto3" json:"auth_role_grant_permission,omitempty"`
 	AuthRoleRevokePermission *AuthRoleRevokePermissionRequest          `protobuf:"bytes,1204,opt,name=auth_role_revoke_permission,json=authRoleRevokePermission,proto3" json:"auth_role_revoke_permission,omitempty"`
+	AuthExport               *AuthExportRequest                        `protobuf:"bytes,1250,opt,name=auth_export,json=authExport,proto3" json:"auth_export,omitempty"`
+	AuthImport               *AuthImportRequest                        `protobuf:"bytes,1251,opt,name=auth_import,json=authImport,proto3" json:"auth_import,omitempty"`
 	ClusterVersionSet        *membershippb.ClusterVersionSetRequest    `protobuf:"bytes,1300,opt,name=cluster_version_set,json=clusterVersionSet,proto3" json:"cluster_version_set,omitempty"`
 	ClusterMemberAttrSet     *membershippb.ClusterMemberAttrSetRequest `protobuf:"bytes,1301,opt,name=cluster_member_attr_set,json=clusterMemberAttrSet,proto3" json:"cluster_member_attr_set,omitempty"`
 	DowngradeInfoSet         *membershippb.DowngradeInfoSetRequest     `protobuf:"bytes,1302,opt,name=downgrade_info_set,json=downgradeInfoSet,proto3" json:"downgrade_info_set,omitempty"`
@@ -238,73 +244,82 @@ func init() {
 func init() { proto.RegisterFile("raft_internal.proto", fileDescriptor_b4c9a9be0cfca103) }
 
 var fileDescriptor_b4c9a9be0cfca103 = []byte{
-	// 1054 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x56, 0x5d, 0x6f, 0x1b, 0x45,
-	0x14, 0xad, 0xd3, 0x34, 0x89, 0xc7, 0x49, 0x9a, 0x4e, 0x52, 0x3a, 0x38, 0x92, 0x71, 0x03, 0x2d,
-	0x01, 0x8a, 0x53, 0x1c, 0x78, 0xe1, 0x05, 0x5c, 0x3b, 0x4a, 0x83, 0x4a, 0x15, 0x6d, 0x0b, 0xaa,
-	0x84, 0xd0, 0x32, 0xde, 0xbd, 0xb1, 0xb7, 0x59, 0xef, 0x2e, 0x33, 0x63, 0x37, 0x7d, 0xe5, 0x91,
-	0x67, 0x40, 0xfc, 0x0c, 0x3e, 0xff, 0x43, 0x85, 0xf8, 0x28, 0xf0, 0x07, 0x20, 0xbc, 0xf0, 0x0e,
-	0xbc, 0xa3, 0xf9, 0xd8, 0x5d, 0xaf, 0x3d, 0xce, 0xdb, 0xfa, 0xde, 0x73, 0xcf, 0x39, 0x33, 0x73,
-	0xef, 0x78, 0xd0, 0x3a, 0xa3, 0x47, 0xc2, 0x0d, 0x22, 0x01, 0x2c, 0xa2, 0x61, 0x23, 0x61, 0xb1,
-	0x88, 0xf1, 0x32, 0x08, 0xcf, 0xe7, 0xc0, 0x46, 0xc0, 0x92, 0x6e, 0x75, 0xa3, 0x17, 0xf7, 0x62,
-	0x95, 0xd8, 0x91, 0x5f, 0x1a, 0x53, 0x5d, 0xcb, 0x31, 0x26, 0x52, 0x66, 0x89, 0x67, 0x3e, 0xeb,
-	0x32, 0xb9, 0x43, 0x93, 0x60, 0x67, 0x04, 0x8c, 0x07, 0x71, 0x94, 0x74, 0xd3, 0x2f, 0x83, 0xb8,
-	0x9e, 0x21, 0x06, 0x30, 0xe8, 0x02, 0xe3, 0xfd, 0x20, 0x49, 0xba, 0x63, 0x3f, 0x34, 0x6e, 0x8b,
-	0xa1, 0x15, 0x07, 0x3e, 0x1e, 0x02, 0x17, 0xb7, 0x81, 0xfa, 0xc0, 0xf0, 0x2a, 0x9a, 0x3b, 0xe8,
-	0x90, 0x52, 0xbd, 0xb4, 0x3d, 0xef, 0xcc, 0x1d, 0x74, 0x70, 0x15, 0x2d, 0x0d, 0xb9, 0x34, 0x3f,
-	0x00, 0x32, 0x57, 0x2f, 0x6d, 0x97, 0x9d, 0xec, 0x37, 0xbe, 0x81, 0x56, 0xe8, 0x50, 0xf4, 0x5d,
-	0x06, 0xa3, 0x40, 0x6a, 0x93, 0xf3, 0xb2, 0xec, 0xd6, 0xe2, 0xa7, 0xdf, 0x93, 0xf3, 0xbb, 0x8d,
-	0xd7, 0x9c, 0x65, 0x99, 0x75, 0x4c, 0xf2, 0xcd, 0xc5, 0x4f, 0x54, 0xf8, 0xe6, 0xd6, 0x0f, 0x18,
-	0xad, 0x1f, 0x98, 0x1d, 0x71, 0xe8, 0x91, 0x30, 0x06, 0xf0, 0x2e, 0x5a, 0xe8, 0x2b, 0x13, 0xc4,
-	0xaf, 0x97, 0xb6, 0x2b, 0xcd, 0xcd, 0xc6, 0xf8, 0x3e, 0x35, 0x0a, 0x3e, 0x1d, 0x03, 0x9d, 0xf2,
-	0x7b, 0x0d, 0xcd, 0x8d, 0x9a, 0xca, 0x69, 0xa5, 0x79, 0xd9, 0x4a, 0xe0, 0xcc, 0x8d, 0x9a, 0xf8,
-	0x26, 0xba, 0xc0, 0x68, 0xd4, 0x03, 0x65, 0xb9, 0xd2, 0xac, 0x4e, 0x20, 0x65, 0x2a, 0x85, 0x6b,
-	0x20, 0x7e, 0x19, 0x9d, 0x4f, 0x86, 0x82, 0xcc, 0x2b, 0x3c, 0x29, 0xe2, 0x0f, 0x87, 0xe9, 0x22,
-	0x1c, 0x09, 0xc2, 0x6d, 0xb4, 0xec, 0x43, 0x08, 0x02, 0x5c, 0x2d, 0x72, 0x41, 0x15, 0xd5, 0x8b,
-	0x45, 0x1d, 0x85, 0x28, 0x48, 0x55, 0xfc, 0x3c, 0x26, 0x05, 0xc5, 0x49, 0x44, 0x16, 0x6c, 0x82,
-	0xf7, 0x4f, 0xa2, 0x4c, 0x50, 0x9c, 0x44, 0xf8, 0x2d, 0x84, 0xbc, 0x78, 0x90, 0x50, 0x4f, 0xc8,
-	0x63, 0x58, 0x54, 0x25, 0xcf, 0x15, 0x4b, 0xda, 0x59, 0x3e, 0xad, 0x1c, 0x2b, 0xc1, 0x6f, 0xa3,
-	0x4a, 0x08, 0x94, 0x83, 0xdb, 0x63, 0x34, 0x12, 0x64, 0xc9, 0xc6, 0x70, 0x47, 0x02, 0xf6, 0x65,
-	0x3e, 0x63, 0x08, 0xb3, 0x90, 0x5c, 0xb3, 0x66, 0x60, 0x30, 0x8a, 0x8f, 0x81, 0x94, 0x6d, 0x6b,
-	0x56, 0x14, 0x8e, 0x02, 0x64, 0x6b, 0x0e, 0xf3, 0x98, 0x3c, 0x16, 0x1a, 0x52, 0x36, 0x20, 0xc8,
-	0x76, 0x2c, 0x2d, 0x99, 0xca, 0x8e, 0x45, 0x01, 0xf1, 0x03, 0xb4, 0xa6, 0x65, 0xbd, 0x3e, 0x78,
-	0xc7, 0x49, 0x1c, 0x44, 0x82, 0x54, 0x54, 0xf1, 0x0b, 0x16, 0xe9, 0x76, 0x06, 0x32, 0x34, 0x69,
-	0xb3, 0xbe, 0xee, 0x5c, 0x0c, 0x8b, 0x00, 0xdc, 0x42, 0x15, 0xd5, 0xdd, 0x10, 0xd1, 0x6e, 0x08,
-	0xe4, 0x6f, 0xeb, 0xae, 0xb6, 0x86, 0xa2, 0xbf, 0xa7, 0x00, 0xd9, 0x9e, 0xd0, 0x2c, 0x84, 0x3b,
-	0x48, 0x8d, 0x80, 0xeb, 0x07, 0x5c, 0x71, 0xfc, 0xb3, 0x68, 0xdb, 0x14, 0xc9, 0xd1, 0xd1, 0x88,
-	0x6c, 0x53, 0x68, 0x1e, 0xc3, 0xef, 0x18, 0x23, 0x5c, 0x50, 0x31, 0xe4, 0xe4, 0xbf, 0x99, 0x46,
-	0xee, 0x29, 0xc0, 0xc4, 0xca, 0xde, 0xd0, 0x8e, 0x74, 0x0e, 0xdf, 0xd5, 0x8e, 0x20, 0x12, 0x81,
-	0x47, 0x05, 0x90, 0x7f, 0x35, 0xd9, 0x4b, 0x45, 0xb2, 0x74, 0x3a, 0x5b, 0x63, 0xd0, 0xd4, 0x5a,
-	0xa1, 0x1e, 0xef, 0x99, 0x2b, 0x40, 0xde, 0x09, 0x2e, 0xf5, 0x7d, 0xf2, 0xe3, 0xd2, 0xac, 0x25,
-	0xbe, 0xc7, 0x81, 0xb5, 0x7c, 0xbf, 0xb0, 0x44, 0x13, 0xc3, 0x77, 0xd1, 0x5a, 0x4e, 0xa3, 0x87,
-	0x80, 0xfc, 0xa4, 0x99, 0x9e, 0xb7, 0x33, 0x99, 0xe9, 0x31, 0x64, 0xab, 0xb4, 0x10, 0x2e, 0xda,
-	0xea, 0x81, 0x20, 0x3f, 0x9f, 0x69, 0x6b, 0x1f, 0xc4, 0x94, 0xad, 0x7d, 0x10, 0xb8, 0x87, 0x9e,
-	0xcd, 0x69, 0xbc, 0xbe, 0x1c, 0x4b, 0x37, 0xa1, 0x9c, 0x3f, 0x8a, 0x99, 0x4f, 0x7e, 0xd1, 0x94,
-	0xaf, 0xd8, 0x29, 0xdb, 0x0a, 0x7d, 0x68, 0xc0, 0x29, 0xfb, 0x33, 0xd4, 0x9a, 0xc6, 0x0f, 0xd0,
-	0xc6, 0x98, 0x5f, 0x39, 0x4f, 0x2e, 0x8b, 0x43, 0x20, 0x4f, 0xb5, 0xc6, 0xf5, 0x19, 0xb6, 0xd5,
-	0x2c, 0xc6, 0x79, 0xdb, 0x5c, 0xa2, 0x93, 0x19, 0xfc, 0x01, 0xba, 0x9c, 0x33, 0xeb, 0xd1, 0xd4,
-	0xd4, 0xbf, 0x6a, 0xea, 0x17, 0xed, 0xd4, 0x66, 0x46, 0xc7, 0xb8, 0x31, 0x9d, 0x4a, 0xe1, 0xdb,
-	0x68, 0x35, 0x27, 0x0f, 0x03, 0x2e, 0xc8, 0x6f, 0x9a, 0xf5, 0xaa, 0x9d, 0xf5, 0x4e, 0xc0, 0x45,
-	0xa1, 0x8f, 0xd2, 0x60, 0xc6, 0x24, 0xad, 0x69, 0xa6, 0xdf, 0x67, 0x32, 0x49, 0xe9, 0x29, 0xa6,
-	0x34, 0x98, 0x1d, 0xbd, 0x62, 0x92, 0x1d, 0xf9, 0x55, 0x79, 0xd6, 0xd1, 0xcb, 0x9a, 0xc9, 0x8e,
-	0x34, 0xb1, 0xac, 0x23, 0x15, 0x8d, 0xe9, 0xc8, 0xaf, 0xcb, 0xb3, 0x3a, 0x52, 0x56, 0x59, 0x3a,
-	0x32, 0x0f, 0x17, 0x6d, 0xc9, 0x8e, 0xfc, 0xe6, 0x4c, 0x5b, 0x93, 0x1d, 0x69, 0x62, 0xf8, 0x21,
-	0xaa, 0x8e, 0xd1, 0xa8, 0x46, 0x49, 0x80, 0x0d, 0x02, 0xae, 0xfe, 0x7f, 0xbf, 0xd5, 0x9c, 0x37,
-	0x66, 0x70, 0x4a, 0xf8, 0x61, 0x86, 0x4e, 0xf9, 0xaf, 0x50, 0x7b, 0x1e, 0x0f, 0xd0, 0x66, 0xae,
-	0x65, 0x5a, 0x67, 0x4c, 0xec, 0x3b, 0x2d, 0xf6, 0xaa, 0x5d, 0x4c, 0x77, 0xc9, 0xb4, 0x1a, 0xa1,
-	0x33, 0x00, 0xf8, 0x23, 0xb4, 0xee, 0x85, 0x43, 0x2e, 0x80, 0xb9, 0xe6, 0x2d, 0xe3, 0x72, 0x10,
-	0xe4, 0x33, 0x64, 0x46, 0x60, 0xfc, 0x21, 0xd3, 0x68, 0x6b, 0xe4, 0xfb, 0x1a, 0x78, 0x0f, 0xc4,
-	0xd4, 0xad, 0x77, 0xc9, 0x9b, 0x84, 0xe0, 0x87, 0xe8, 0x4a, 0xaa, 0xa0, 0xc9, 0x5c, 0x2a, 0x04,
-	0x53, 0x2a, 0x9f, 0x23, 0x73, 0x0f, 0xda, 0x54, 0xde, 0x55, 0xb1, 0x96, 0x10, 0xcc, 0x26, 0xb4,
-	0xe1, 0x59, 0x50, 0xf8, 0x43, 0x84, 0xfd, 0xf8, 0x51, 0xd4, 0x63, 0xd4, 0x07, 0x37, 0x88, 0x8e,
-	0x62, 0x25, 0xf3, 0x85, 0x96, 0xb9, 0x56, 0x94, 0xe9, 0xa4, 0xc0, 0x83, 0xe8, 0x28, 0xb6, 0x49,
-	0xac, 0xf9, 0x13, 0x88, 0xfc, 0x31, 0x75, 0x11, 0xad, 0xec, 0x0d, 0x12, 0xf1, 0xd8, 0x01, 0x9e,
-	0xc4, 0x11, 0x87, 0xad, 0xc7, 0x68, 0xf3, 0x8c, 0xeb, 0x1b, 0x63, 0x34, 0xaf, 0xde, 0x72, 0x25,
-	0xf5, 0x96, 0x53, 0xdf, 0xf2, 0x8d, 0x97, 0xdd, 0x6a, 0xe6, 0x8d, 0x97, 0xfe, 0xc6, 0x57, 0xd1,
-	0x32, 0x0f, 0x06, 0x49, 0x08, 0xae, 0x88, 0x8f, 0x41, 0x3f, 0xf1, 0xca, 0x4e, 0x45, 0xc7, 0xee,
-	0xcb, 0x50, 0xe6, 0xe5, 0xd6, 0xc6, 0x93, 0x3f, 0x6b, 0xe7, 0x9e, 0x9c, 0xd6, 0x4a, 0x4f, 0x4f,
-	0x6b, 0xa5, 0x3f, 0x4e, 0x6b, 0xa5, 0x2f, 0xff, 0xaa, 0x9d, 0xeb, 0x2e, 0xa8, 0x97, 0xe6, 0xee,
-	0xff, 0x01, 0x00, 0x00, 0xff, 0xff, 0x30, 0x36, 0x53, 0xc6, 0x0b, 0x0b, 0x00, 0x00,
+	// 1194 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x97, 0xcf, 0x73, 0xdb, 0x44,
+	0x14, 0xc7, 0xeb, 0xb4, 0x4d, 0xe2, 0x75, 0xe2, 0x24, 0x9b, 0xb4, 0x5d, 0x92, 0x99, 0x90, 0x06,
+	0x5a, 0x02, 0x94, 0xa4, 0x24, 0x94, 0x03, 0x17, 0x70, 0xe2, 0x4c, 0x6a, 0x5a, 0x3a, 0x41, 0x2d,
+	0x9d, 0x32, 0x0c, 0x23, 0xd6, 0xd2, 0x8b, 0xad, 0x46, 0x96, 0xd4, 0xd5, 0xda, 0x4d, 0xae, 0x1c,
+	0x39, 0x03, 0xc3, 0x9f, 0xc1, 0x8f, 0xf2, 0x3f, 0xf4, 0xc0, 0x8f, 0x02, 0xff, 0x00, 0xa4, 0x17,
+	0xee, 0xc0, 0x9d, 0xd9, 0x1f, 0x5a, 0x49, 0xb6, 0x94, 0x9b, 0xf4, 0xde, 0xf7, 0x7d, 0xde, 0xdb,
+	0xdd, 0xe7, 0xa7, 0x35, 0x9a, 0x67, 0xf4, 0x80, 0xdb, 0x5e, 0xc0, 0x81, 0x05, 0xd4, 0x5f, 0x8f,
+	0x58, 0xc8, 0x43, 0x3c, 0x05, 0xdc, 0x71, 0x63, 0x60, 0x03, 0x60, 0x51, 0x7b, 0x71, 0xa1, 0x13,
+	0x76, 0x42, 0xe9, 0xd8, 0x10, 0x4f, 0x4a, 0xb3, 0x38, 0x9b, 0x6a, 0xb4, 0xa5, 0xca, 0x22, 0x47,
+	0x3f, 0xae, 0x08, 0xe7, 0x06, 0x8d, 0xbc, 0x8d, 0x01, 0xb0, 0xd8, 0x0b, 0x83, 0xa8, 0x9d, 0x3c,
+	0x69, 0xc5, 0x55, 0xa3, 0xe8, 0x41, 0xaf, 0x0d, 0x2c, 0xee, 0x7a, 0x51, 0xd4, 0xce, 0xbc, 0x28,
+	0xdd, 0x2a, 0x43, 0xd3, 0x16, 0x3c, 0xea, 0x43, 0xcc, 0x6f, 0x02, 0x75, 0x81, 0xe1, 0x3a, 0x1a,
+	0x6b, 0x35, 0x49, 0x65, 0xa5, 0xb2, 0x76, 0xce, 0x1a, 0x6b, 0x35, 0xf1, 0x22, 0x9a, 0xec, 0xc7,
+	0xa2, 0xf8, 0x1e, 0x90, 0xb1, 0x95, 0xca, 0x5a, 0xd5, 0x32, 0xef, 0xf8, 0x1a, 0x9a, 0xa6, 0x7d,
+	0xde, 0xb5, 0x19, 0x0c, 0x3c, 0x91, 0x9b, 0x9c, 0x15, 0x61, 0xdb, 0x13, 0x5f, 0xfc, 0x48, 0xce,
+	0x6e, 0xad, 0xbf, 0x69, 0x4d, 0x09, 0xaf, 0xa5, 0x9d, 0xef, 0x4c, 0x7c, 0x2e, 0xcd, 0xd7, 0x57,
+	0x9f, 0x5c, 0x44, 0xf3, 0x2d, 0xbd, 0x23, 0x16, 0x3d, 0xe0, 0xba, 0x00, 0xbc, 0x85, 0xc6, 0xbb,
+	0xb2, 0x08, 0xe2, 0xae, 0x54, 0xd6, 0x6a, 0x9b, 0x4b, 0xeb, 0xd9, 0x7d, 0x5a, 0xcf, 0xd5, 0x69,
+	0x69, 0xe9, 0x48, 0xbd, 0x57, 0xd0, 0xd8, 0x60, 0x53, 0x56, 0x5a, 0xdb, 0xbc, 0x50, 0x08, 0xb0,
+	0xc6, 0x06, 0x9b, 0xf8, 0x3a, 0x3a, 0xcf, 0x68, 0xd0, 0x01, 0x59, 0x72, 0x6d, 0x73, 0x71, 0x48,
+	0x29, 0x5c, 0x89, 0x5c, 0x09, 0xf1, 0x6b, 0xe8, 0x6c, 0xd4, 0xe7, 0xe4, 0x9c, 0xd4, 0x93, 0xbc,
+	0x7e, 0xbf, 0x9f, 0x2c, 0xc2, 0x12, 0x22, 0xbc, 0x83, 0xa6, 0x5c, 0xf0, 0x81, 0x83, 0xad, 0x92,
+	0x9c, 0x97, 0x41, 0x2b, 0xf9, 0xa0, 0xa6, 0x54, 0xe4, 0x52, 0xd5, 0xdc, 0xd4, 0x26, 0x12, 0xf2,
+	0xa3, 0x80, 0x8c, 0x17, 0x25, 0xbc, 0x77, 0x14, 0x98, 0x84, 0xfc, 0x28, 0xc0, 0xef, 0x22, 0xe4,
+	0x84, 0xbd, 0x88, 0x3a, 0x5c, 0x1c, 0xc3, 0x84, 0x0c, 0x79, 0x31, 0x1f, 0xb2, 0x63, 0xfc, 0x49,
+	0x64, 0x26, 0x04, 0xbf, 0x87, 0x6a, 0x3e, 0xd0, 0x18, 0xec, 0x0e, 0xa3, 0x01, 0x27, 0x93, 0x45,
+	0x84, 0xdb, 0x42, 0xb0, 0x27, 0xfc, 0x86, 0xe0, 0x1b, 0x93, 0x58, 0xb3, 0x22, 0x30, 0x18, 0x84,
+	0x87, 0x40, 0xaa, 0x45, 0x6b, 0x96, 0x08, 0x4b, 0x0a, 0xcc, 0x9a, 0xfd, 0xd4, 0x26, 0x8e, 0x85,
+	0xfa, 0x94, 0xf5, 0x08, 0x2a, 0x3a, 0x96, 0x86, 0x70, 0x99, 0x63, 0x91, 0x42, 0xfc, 0x00, 0xcd,
+	0xaa, 0xb4, 0x4e, 0x17, 0x9c, 0xc3, 0x28, 0xf4, 0x02, 0x4e, 0x6a, 0x32, 0xf8, 0xe5, 0x82, 0xd4,
+	0x3b, 0x46, 0xa4, 0x31, 0x49, 0xb3, 0xbe, 0x65, 0xcd, 0xf8, 0x79, 0x01, 0xfe, 0x18, 0xcd, 0x65,
+	0xb6, 0xc4, 0x6e, 0x53, 0xee, 0x74, 0xc9, 0x54, 0x29, 0x5a, 0xee, 0xc2, 0xb6, 0x10, 0x0d, 0xa1,
+	0xdf, 0xd6, 0xe8, 0x54, 0x80, 0x5b, 0xa8, 0xa6, 0xf7, 0xde, 0x3e, 0x84, 0x63, 0x32, 0x7d, 0xca,
+	0x79, 0xdd, 0x82, 0xe3, 0x11, 0x5e, 0x72, 0x70, 0xb7, 0xe0, 0x18, 0x5b, 0xa8, 0x9e, 0x6c, 0x3b,
+	0xe5, 0x9c, 0x3a, 0x5d, 0x52, 0x97, 0xb4, 0xd5, 0xc2, 0x8d, 0x57, 0x92, 0x11, 0xe0, 0xb4, 0x9f,
+	0x75, 0xe3, 0xfb, 0x68, 0x36, 0x62, 0x70, 0xe0, 0x1d, 0xd9, 0x8f, 0xfa, 0x21, 0xa7, 0x76, 0x0c,
+	0x9c, 0xcc, 0x48, 0xea, 0x4b, 0x43, 0x7d, 0x2f, 0x55, 0x1f, 0x0a, 0xd1, 0x5d, 0xe0, 0x23, 0xd8,
+	0x7a, 0x94, 0xf3, 0xe3, 0x06, 0xaa, 0xc9, 0x79, 0x01, 0x01, 0x6d, 0xfb, 0x40, 0xfe, 0x2e, 0xec,
+	0xd3, 0x46, 0x9f, 0x77, 0x77, 0xa5, 0xc0, 0x74, 0x19, 0x35, 0x26, 0xdc, 0x44, 0x72, 0xa8, 0xd8,
+	0xae, 0x17, 0x4b, 0xc6, 0x3f, 0x13, 0x45, 0x6d, 0x26, 0x18, 0x4d, 0xa5, 0x30, 0x6d, 0x46, 0x53,
+	0x1b, 0x7e, 0x5f, 0x17, 0x12, 0x73, 0xca, 0xfb, 0x31, 0xf9, 0xaf, 0xb4, 0x90, 0xbb, 0x52, 0x30,
+	0xb4, 0xb0, 0x1b, 0xaa, 0x22, 0xe5, 0xc3, 0x77, 0x54, 0x45, 0x10, 0x70, 0xcf, 0xa1, 0x1c, 0xc8,
+	0xbf, 0x0a, 0xf6, 0x6a, 0x1e, 0x96, 0xcc, 0xbb, 0x46, 0x46, 0x9a, 0x94, 0x96, 0x8b, 0xc7, 0xbb,
+	0x7a, 0xa8, 0x8a, 0x29, 0x6b, 0x53, 0xd7, 0x25, 0x3f, 0x4d, 0x96, 0x2d, 0xf1, 0xa3, 0x18, 0x58,
+	0xc3, 0x75, 0x73, 0x4b, 0xd4, 0x36, 0x7c, 0x07, 0xcd, 0xa6, 0x18, 0x35, 0x56, 0xc8, 0xcf, 0x93,
+	0x45, 0x87, 0x98, 0x90, 0xf4, 0x3c, 0xd2, 0xb0, 0x3a, 0xcd, 0x99, 0xf3, 0x65, 0x75, 0x80, 0x93,
+	0x5f, 0x4e, 0x2d, 0x6b, 0xcf, 0xb4, 0x43, 0x5a, 0xd6, 0x1e, 0x70, 0xdc, 0x41, 0x2f, 0xa4, 0x18,
+	0xa7, 0x2b, 0x06, 0x9d, 0x1d, 0xd1, 0x38, 0x7e, 0x1c, 0x32, 0x97, 0xfc, 0xaa, 0x90, 0xaf, 0x17,
+	0x23, 0x77, 0xa4, 0x7a, 0x5f, 0x8b, 0x13, 0xfa, 0x45, 0x5a, 0xe8, 0xc6, 0x0f, 0xd0, 0x42, 0xa6,
+	0x5e, 0xf9, 0x0b, 0x66, 0xa1, 0x0f, 0xe4, 0x99, 0xca, 0x71, 0xb5, 0xa4, 0x6c, 0x39, 0xdd, 0xc2,
+	0xb4, 0x6d, 0xe6, 0xe8, 0xb0, 0x07, 0x7f, 0x82, 0x2e, 0xa4, 0x64, 0x35, 0xec, 0x14, 0xfa, 0x37,
+	0x85, 0x7e, 0xa5, 0x18, 0xad, 0xa7, 0x5e, 0x86, 0x8d, 0xe9, 0x88, 0x0b, 0xdf, 0x44, 0xf5, 0x14,
+	0xee, 0x7b, 0x31, 0x27, 0xbf, 0x2b, 0xea, 0xe5, 0x62, 0xea, 0x6d, 0x2f, 0xe6, 0xb9, 0x3e, 0x4a,
+	0x8c, 0x86, 0x24, 0x4a, 0x53, 0xa4, 0x3f, 0x4a, 0x49, 0x22, 0xf5, 0x08, 0x29, 0x31, 0x9a, 0xa3,
+	0x97, 0x24, 0xd1, 0x91, 0xdf, 0x56, 0xcb, 0x8e, 0x5e, 0xc4, 0x0c, 0x77, 0xa4, 0xb6, 0x99, 0x8e,
+	0x94, 0x18, 0xdd, 0x91, 0xdf, 0x55, 0xcb, 0x3a, 0x52, 0x44, 0x15, 0x74, 0x64, 0x6a, 0xce, 0x97,
+	0x25, 0x3a, 0xf2, 0xfb, 0x53, 0xcb, 0x1a, 0xee, 0x48, 0x6d, 0xc3, 0x0f, 0xd1, 0x62, 0x06, 0x23,
+	0x1b, 0x25, 0x02, 0xd6, 0xf3, 0x62, 0x79, 0xa3, 0xf9, 0x41, 0x31, 0xaf, 0x95, 0x30, 0x85, 0x7c,
+	0xdf, 0xa8, 0x13, 0xfe, 0x25, 0x5a, 0xec, 0xc7, 0x3d, 0xb4, 0x94, 0xe6, 0xd2, 0xad, 0x93, 0x49,
+	0xf6, 0x44, 0x25, 0x7b, 0xa3, 0x38, 0x99, 0xea, 0x92, 0xd1, 0x6c, 0x84, 0x96, 0x08, 0xcc, 0x98,
+	0x83, 0xa3, 0x28, 0x64, 0x9c, 0x9c, 0x54, 0x4b, 0xe7, 0xad, 0x14, 0x8c, 0x7e, 0x67, 0xa8, 0xf1,
+	0x19, 0x96, 0xd7, 0x93, 0xac, 0xe7, 0xa5, 0xac, 0x56, 0xaf, 0x9c, 0xa5, 0x7c, 0xf8, 0x33, 0x34,
+	0xef, 0xf8, 0xfd, 0x98, 0x03, 0xb3, 0xf5, 0xad, 0x55, 0x7e, 0x62, 0xbe, 0x44, 0xfa, 0xa7, 0x99,
+	0xbd, 0xb2, 0xae, 0xef, 0x28, 0xe5, 0x7d, 0x25, 0x1c, 0xfd, 0xcc, 0xdc, 0xb0, 0xe6, 0x9c, 0x61,
+	0x09, 0x7e, 0x88, 0x2e, 0x25, 0x19, 0x14, 0xcc, 0xa6, 0x9c, 0x33, 0x99, 0xe5, 0x2b, 0xa4, 0xe7,
+	0x73, 0x51, 0x96, 0x0f, 0xa4, 0xad, 0xc1, 0x39, 0x2b, 0x4a, 0xb4, 0xe0, 0x14, 0xa8, 0xf0, 0xa7,
+	0x08, 0xbb, 0xe1, 0xe3, 0xa0, 0xc3, 0xa8, 0x0b, 0xb6, 0x17, 0x1c, 0x84, 0x32, 0xcd, 0xd7, 0x2a,
+	0xcd, 0x95, 0x7c, 0x9a, 0x66, 0x22, 0x6c, 0x05, 0x07, 0x61, 0x51, 0x8a, 0x59, 0x77, 0x48, 0x91,
+	0x5e, 0x9b, 0x67, 0xd0, 0xf4, 0x6e, 0x2f, 0xe2, 0xc7, 0x16, 0xc4, 0x51, 0x18, 0xc4, 0xb0, 0x7a,
+	0x8c, 0x96, 0x4e, 0xf9, 0xac, 0x60, 0x8c, 0xce, 0xc9, 0x5b, 0x7b, 0x45, 0xde, 0xda, 0xe5, 0xb3,
+	0xb8, 0xcd, 0x9b, 0x69, 0xab, 0x6f, 0xf3, 0xc9, 0x3b, 0xbe, 0x8c, 0xa6, 0x62, 0xaf, 0x17, 0xf9,
+	0x60, 0xf3, 0xf0, 0x10, 0xd4, 0x65, 0xbe, 0x6a, 0xd5, 0x94, 0xed, 0x9e, 0x30, 0x99, 0x5a, 0xb6,
+	0x17, 0x9e, 0xfe, 0xb5, 0x7c, 0xe6, 0xe9, 0xc9, 0x72, 0xe5, 0xd9, 0xc9, 0x72, 0xe5, 0xcf, 0x93,
+	0xe5, 0xca, 0x37, 0xcf, 0x97, 0xcf, 0xb4, 0xc7, 0xe5, 0x7f, 0x8a, 0xad, 0xff, 0x03, 0x00, 0x00,
+	0xff, 0xff, 0x7d, 0xf2, 0x4b, 0x93, 0xf5, 0x0c, 0x00, 0x00,
 }
 
 func (m *RequestHeader) Marshal() (dAtA []byte, err error) {
@@ -417,6 +432,34 @@ func (m *InternalRaftRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0xa2
 	}
+	if m.AuthImport != nil {
+		{
+			size, err := m.AuthImport.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4e
+		i--
+		dAtA[i] = 0x9a
+	}
+	if m.AuthExport != nil {
+		{
+			size, err := m.AuthExport.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4e
+		i--
+		dAtA[i] = 0x92
+	}
 	if m.AuthRoleRevokePermission != nil {
 		{
 			size, err := m.AuthRoleRevokePermission.MarshalToSizedBuffer(dAtA[:i])
@@ -669,6 +712,54 @@ func (m *InternalRaftRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0xa2
 	}
+	if m.PrefixQuotaSet != nil {
+		{
+			size, err := m.PrefixQuotaSet.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x7a
+	}
+	if m.LeaseReattach != nil {
+		{
+			size, err := m.LeaseReattach.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x72
+	}
+	if m.CompactKey != nil {
+		{
+			size, err := m.CompactKey.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x6a
+	}
+	if m.LeaseGrantBatch != nil {
+		{
+			size, err := m.LeaseGrantBatch.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x62
+	}
 	if m.LeaseCheckpoint != nil {
 		{
 			size, err := m.LeaseCheckpoint.MarshalToSizedBuffer(dAtA[:i])
@@ -954,6 +1045,22 @@ func (m *InternalRaftRequest) Size() (n int) {
 		l = m.LeaseCheckpoint.Size()
 		n += 1 + l + sovRaftInternal(uint64(l))
 	}
+	if m.LeaseGrantBatch != nil {
+		l = m.LeaseGrantBatch.Size()
+		n += 1 + l + sovRaftInternal(uint64(l))
+	}
+	if m.CompactKey != nil {
+		l = m.CompactKey.Size()
+		n += 1 + l + sovRaftInternal(uint64(l))
+	}
+	if m.LeaseReattach != nil {
+		l = m.LeaseReattach.Size()
+		n += 1 + l + sovRaftInternal(uint64(l))
+	}
+	if m.PrefixQuotaSet != nil {
+		l = m.PrefixQuotaSet.Size()
+		n += 1 + l + sovRaftInternal(uint64(l))
+	}
 	if m.Header != nil {
 		l = m.Header.Size()
 		n += 2 + l + sovRaftInternal(uint64(l))
@@ -1026,6 +1133,14 @@ func (m *InternalRaftRequest) Size() (n int) {
 		l = m.AuthRoleRevokePermission.Size()
 		n += 2 + l + sovRaftInternal(uint64(l))
 	}
+	if m.AuthExport != nil {
+		l = m.AuthExport.Size()
+		n += 2 + l + sovRaftInternal(uint64(l))
+	}
+	if m.AuthImport != nil {
+		l = m.AuthImport.Size()
+		n += 2 + l + sovRaftInternal(uint64(l))
+	}
 	if m.ClusterVersionSet != nil {
 		l = m.ClusterVersionSet.Size()
 		n += 2 + l + sovRaftInternal(uint64(l))
@@ -1615,6 +1730,150 @@ func (m *InternalRaftRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaseGrantBatch", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.LeaseGrantBatch == nil {
+				m.LeaseGrantBatch = &LeaseGrantBatchRequest{}
+			}
+			if err := m.LeaseGrantBatch.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CompactKey", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.CompactKey == nil {
+				m.CompactKey = &CompactKeyRequest{}
+			}
+			if err := m.CompactKey.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaseReattach", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.LeaseReattach == nil {
+				m.LeaseReattach = &LeaseReattachRequest{}
+			}
+			if err := m.LeaseReattach.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 15:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrefixQuotaSet", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.PrefixQuotaSet == nil {
+				m.PrefixQuotaSet = &PrefixQuotaSetRequest{}
+			}
+			if err := m.PrefixQuotaSet.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 100:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Header", wireType)
@@ -2263,6 +2522,78 @@ func (m *InternalRaftRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 1250:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AuthExport", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.AuthExport == nil {
+				m.AuthExport = &AuthExportRequest{}
+			}
+			if err := m.AuthExport.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 1251:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AuthImport", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.AuthImport == nil {
+				m.AuthImport = &AuthImportRequest{}
+			}
+			if err := m.AuthImport.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 1300:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field ClusterVersionSet", wireType)
@@ -71,8 +71,12 @@ var xxx_messageInfo_RaftAttributes proto.InternalMessageInfo
 
 // Attributes represents all the non-raft related attributes of an etcd member.
 type Attributes struct {
-	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	ClientUrls           []string `protobuf:"bytes,2,rep,name=client_urls,json=clientUrls,proto3" json:"client_urls,omitempty"`
+	Name       string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ClientUrls []string `protobuf:"bytes,2,rep,name=client_urls,json=clientUrls,proto3" json:"client_urls,omitempty"`
+	// election_priority is the priority of the member to become the leader. The
+	// leader transfers its leadership to an up-to-date voting member with a
+	// higher priority than its own.
+	ElectionPriority     uint32   `protobuf:"varint,3,opt,name=election_priority,json=electionPriority,proto3" json:"election_priority,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -287,33 +291,35 @@ func init() {
 func init() { proto.RegisterFile("membership.proto", fileDescriptor_949fe0d019050ef5) }
 
 var fileDescriptor_949fe0d019050ef5 = []byte{
-	// 401 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x52, 0xcd, 0xae, 0xd2, 0x40,
-	0x14, 0xbe, 0xd3, 0xde, 0xdc, 0xdb, 0x9e, 0x6b, 0x10, 0x27, 0x24, 0x36, 0xa0, 0xb5, 0x61, 0xc5,
-	0xaa, 0x24, 0x12, 0x36, 0xee, 0x54, 0x58, 0x60, 0xc4, 0xc5, 0x18, 0xdc, 0x92, 0x29, 0x1c, 0xb0,
-	0x49, 0x69, 0xeb, 0xcc, 0x14, 0xf7, 0x2e, 0x7d, 0x02, 0xdf, 0xc2, 0x95, 0xef, 0xc0, 0xd2, 0x47,
-	0x50, 0x7c, 0x11, 0xd3, 0x99, 0x42, 0x4b, 0x74, 0x75, 0x77, 0xa7, 0x5f, 0xcf, 0xf9, 0xfe, 0x5a,
-	0x68, 0xef, 0x70, 0x17, 0xa1, 0x90, 0x1f, 0xe3, 0x3c, 0xcc, 0x45, 0xa6, 0x32, 0xfa, 0xa0, 0x46,
-	0xf2, 0xa8, 0xdb, 0xd9, 0x66, 0xdb, 0x4c, 0xbf, 0x18, 0x96, 0x93, 0xd9, 0xe9, 0x06, 0xa8, 0x56,
-	0xeb, 0x21, 0xcf, 0xe3, 0xe1, 0x1e, 0x85, 0x8c, 0xb3, 0x34, 0x8f, 0x4e, 0x93, 0xd9, 0xe8, 0x2f,
-	0xa0, 0xc5, 0xf8, 0x46, 0xbd, 0x54, 0x4a, 0xc4, 0x51, 0xa1, 0x50, 0xd2, 0x1e, 0xb8, 0x39, 0xa2,
-	0x58, 0x16, 0x22, 0x91, 0x1e, 0x09, 0xec, 0x81, 0xcb, 0x9c, 0x12, 0x58, 0x88, 0x44, 0xd2, 0xa7,
-	0x00, 0xb1, 0x5c, 0x26, 0xc8, 0x45, 0x8a, 0xc2, 0xb3, 0x02, 0x32, 0x70, 0x98, 0x1b, 0xcb, 0xb7,
-	0x06, 0x78, 0x71, 0xfb, 0xe5, 0x87, 0x67, 0x8f, 0xc2, 0x71, 0xff, 0x0d, 0x40, 0x83, 0x92, 0xc2,
-	0x75, 0xca, 0x77, 0xe8, 0x91, 0x80, 0x0c, 0x5c, 0xa6, 0x67, 0xfa, 0x0c, 0xee, 0x56, 0x49, 0x8c,
-	0xa9, 0x32, 0x42, 0x96, 0x16, 0x02, 0x03, 0x95, 0x52, 0x35, 0xd7, 0x77, 0x02, 0x37, 0x73, 0x9d,
-	0x95, 0xb6, 0xc0, 0x9a, 0x4d, 0x34, 0xcd, 0x35, 0xb3, 0x66, 0x13, 0x3a, 0x85, 0x87, 0x82, 0x6f,
-	0xd4, 0x92, 0x9f, 0xb5, 0xb4, 0xa7, 0xbb, 0xe7, 0x4f, 0xc2, 0x66, 0x3b, 0xe1, 0x65, 0x44, 0xd6,
-	0x12, 0x97, 0x91, 0xa7, 0xf0, 0xc8, 0xac, 0x37, 0x89, 0x6c, 0x4d, 0xe4, 0x5d, 0x12, 0x35, 0x48,
-	0xaa, 0x2f, 0x52, 0x23, 0xb5, 0xe3, 0x31, 0x78, 0xaf, 0x93, 0x42, 0x2a, 0x14, 0x1f, 0x4c, 0xd9,
-	0xef, 0x51, 0x31, 0xfc, 0x54, 0xa0, 0x54, 0xb4, 0x0d, 0xf6, 0x1e, 0x45, 0x55, 0x45, 0x39, 0xd6,
-	0x67, 0x5f, 0x09, 0xf4, 0xaa, 0xbb, 0xf9, 0x99, 0xbb, 0x71, 0xda, 0x03, 0xb7, 0xb2, 0x79, 0x2e,
-	0xc1, 0x31, 0x80, 0xae, 0xe2, 0x3f, 0x19, 0xac, 0xfb, 0x67, 0x78, 0x07, 0x8f, 0x27, 0xd9, 0xe7,
-	0x74, 0x2b, 0xf8, 0x1a, 0x67, 0xe9, 0x26, 0x6b, 0xf8, 0xf0, 0xe0, 0x16, 0x53, 0x1e, 0x25, 0xb8,
-	0xd6, 0x2e, 0x1c, 0x76, 0x7a, 0x3c, 0x85, 0xb3, 0xfe, 0x0d, 0xf7, 0xaa, 0x73, 0xf8, 0xed, 0x5f,
-	0x1d, 0x8e, 0x3e, 0xf9, 0x79, 0xf4, 0xc9, 0xaf, 0xa3, 0x4f, 0xbe, 0xfd, 0xf1, 0xaf, 0xa2, 0x1b,
-	0xfd, 0x17, 0x8e, 0xfe, 0x06, 0x00, 0x00, 0xff, 0xff, 0xf1, 0xa3, 0xbd, 0xee, 0xdf, 0x02, 0x00,
-	0x00,
+	// 438 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x52, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0xee, 0xda, 0x55, 0x13, 0x4f, 0x21, 0xa4, 0x56, 0x25, 0xac, 0x06, 0x8c, 0xd5, 0x53, 0x4e,
+	0x89, 0x44, 0x29, 0x07, 0x6e, 0x94, 0xf4, 0x10, 0x89, 0x22, 0xb4, 0xa8, 0x5c, 0x23, 0x3b, 0x99,
+	0x84, 0x95, 0x9c, 0x5d, 0x33, 0xbb, 0x29, 0xe2, 0x86, 0x38, 0xf6, 0x09, 0x78, 0x0b, 0x4e, 0xbc,
+	0x43, 0x8e, 0x3c, 0x02, 0x84, 0x17, 0x41, 0xd9, 0x75, 0x62, 0x47, 0x70, 0xea, 0x6d, 0xf2, 0x65,
+	0xe6, 0xfb, 0x5b, 0x43, 0x7b, 0x8e, 0xf3, 0x0c, 0x49, 0x7f, 0x10, 0x45, 0xaf, 0x20, 0x65, 0x54,
+	0x78, 0xaf, 0x42, 0x8a, 0xec, 0xe4, 0x78, 0xa6, 0x66, 0xca, 0xfe, 0xd1, 0x5f, 0x4f, 0x6e, 0xe7,
+	0x24, 0x41, 0x33, 0x9e, 0xf4, 0xd3, 0x42, 0xf4, 0x6f, 0x90, 0xb4, 0x50, 0xb2, 0xc8, 0x36, 0x93,
+	0xdb, 0x38, 0xbd, 0x86, 0x16, 0x4f, 0xa7, 0xe6, 0xa5, 0x31, 0x24, 0xb2, 0x85, 0x41, 0x1d, 0x76,
+	0x20, 0x28, 0x10, 0x69, 0xb4, 0xa0, 0x5c, 0x47, 0x2c, 0xf1, 0xbb, 0x01, 0x6f, 0xae, 0x81, 0x6b,
+	0xca, 0x75, 0xf8, 0x18, 0x40, 0xe8, 0x51, 0x8e, 0x29, 0x49, 0xa4, 0xc8, 0x4b, 0x58, 0xb7, 0xc9,
+	0x03, 0xa1, 0x5f, 0x3b, 0xe0, 0x45, 0xe3, 0xeb, 0x8f, 0xc8, 0x3f, 0xeb, 0x9d, 0x9f, 0x7e, 0x61,
+	0x00, 0x35, 0xce, 0x10, 0xf6, 0x65, 0x3a, 0xc7, 0x88, 0x25, 0xac, 0x1b, 0x70, 0x3b, 0x87, 0x4f,
+	0xe0, 0x70, 0x9c, 0x0b, 0x94, 0xc6, 0x29, 0x79, 0x56, 0x09, 0x1c, 0x64, 0xb5, 0x9e, 0xc1, 0x11,
+	0xe6, 0x38, 0x36, 0x42, 0xc9, 0x51, 0x41, 0x42, 0x91, 0x30, 0x9f, 0x23, 0x3f, 0x61, 0xdd, 0xfb,
+	0x17, 0x8d, 0x5b, 0xab, 0xf3, 0x9c, 0xb7, 0x37, 0x1b, 0x6f, 0xcb, 0x85, 0xca, 0xc2, 0x77, 0x06,
+	0x07, 0x57, 0xb6, 0xa2, 0xb0, 0x05, 0xde, 0x70, 0x60, 0xc5, 0xf7, 0xb9, 0x37, 0x1c, 0x84, 0x97,
+	0xf0, 0x80, 0xd2, 0xa9, 0x19, 0xa5, 0x5b, 0x87, 0x36, 0xca, 0xe1, 0xd3, 0x47, 0xbd, 0x7a, 0xa9,
+	0xbd, 0xdd, 0x66, 0x78, 0x8b, 0x76, 0x9b, 0xba, 0x84, 0x23, 0xb7, 0x5e, 0x27, 0xf2, 0x2d, 0x51,
+	0xb4, 0x4b, 0x54, 0x23, 0x29, 0x1f, 0xb2, 0x42, 0x2a, 0xc7, 0xe7, 0x10, 0xbd, 0xca, 0x17, 0xda,
+	0x20, 0xbd, 0x77, 0x6f, 0xf4, 0x0e, 0x0d, 0xc7, 0x8f, 0x0b, 0xd4, 0x26, 0x6c, 0x83, 0x7f, 0x83,
+	0x54, 0x16, 0xb8, 0x1e, 0xab, 0xb3, 0x5b, 0x06, 0x9d, 0xf2, 0xee, 0x6a, 0xcb, 0x5d, 0x3b, 0xed,
+	0x40, 0x50, 0xda, 0xdc, 0x96, 0xd0, 0x74, 0x80, 0xad, 0xe2, 0x3f, 0x19, 0xbc, 0xbb, 0x67, 0x78,
+	0x03, 0x0f, 0x07, 0xea, 0x93, 0x9c, 0x51, 0x3a, 0xc1, 0xa1, 0x9c, 0xaa, 0x9a, 0x8f, 0x08, 0x1a,
+	0x28, 0xd3, 0x2c, 0xc7, 0x89, 0x75, 0xd1, 0xe4, 0x9b, 0x9f, 0x9b, 0x70, 0xde, 0xbf, 0xe1, 0x2e,
+	0x8e, 0x97, 0xbf, 0xe3, 0xbd, 0xe5, 0x2a, 0x66, 0x3f, 0x57, 0x31, 0xfb, 0xb5, 0x8a, 0xd9, 0xb7,
+	0x3f, 0xf1, 0x5e, 0x76, 0x60, 0x3f, 0xde, 0xb3, 0xbf, 0x01, 0x00, 0x00, 0xff, 0xff, 0xbf, 0x81,
+	0x8a, 0x94, 0x16, 0x03, 0x00, 0x00,
 }
 
 func (m *RaftAttributes) Marshal() (dAtA []byte, err error) {
@@ -386,6 +392,11 @@ func (m *Attributes) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.ElectionPriority != 0 {
+		i = encodeVarintMembership(dAtA, i, uint64(m.ElectionPriority))
+		i--
+		dAtA[i] = 0x18
+	}
 	if len(m.ClientUrls) > 0 {
 		for iNdEx := len(m.ClientUrls) - 1; iNdEx >= 0; iNdEx-- {
 			i -= len(m.ClientUrls[iNdEx])
@@ -631,6 +642,9 @@ func (m *Attributes) Size() (n int) {
 			n += 1 + l + sovMembership(uint64(l))
 		}
 	}
+	if m.ElectionPriority != 0 {
+		n += 1 + sovMembership(uint64(m.ElectionPriority))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -916,6 +930,25 @@ func (m *Attributes) Unmarshal(dAtA []byte) error {
 			}
 			m.ClientUrls = append(m.ClientUrls, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ElectionPriority", wireType)
+			}
+			m.ElectionPriority = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMembership
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ElectionPriority |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMembership(dAtA[iNdEx:])
@@ -116,10 +116,13 @@ type Event struct {
 	// its modification revision set to the revision of deletion.
 	Kv *KeyValue `protobuf:"bytes,2,opt,name=kv,proto3" json:"kv,omitempty"`
 	// prev_kv holds the key-value pair before the event happens.
-	PrevKv               *KeyValue `protobuf:"bytes,3,opt,name=prev_kv,json=prevKv,proto3" json:"prev_kv,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
-	XXX_unrecognized     []byte    `json:"-"`
-	XXX_sizecache        int32     `json:"-"`
+	PrevKv *KeyValue `protobuf:"bytes,3,opt,name=prev_kv,json=prevKv,proto3" json:"prev_kv,omitempty"`
+	// range_index is the index of the range that matched the event, for a watch on several
+	// key ranges. 0 is the range of the watch create request, and i its i-th additional range.
+	RangeIndex           int32    `protobuf:"varint,4,opt,name=range_index,json=rangeIndex,proto3" json:"range_index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Event) Reset()         { *m = Event{} }
@@ -164,26 +167,28 @@ func init() {
 func init() { proto.RegisterFile("kv.proto", fileDescriptor_2216fe83c9c12408) }
 
 var fileDescriptor_2216fe83c9c12408 = []byte{
-	// 303 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x90, 0x41, 0x4e, 0xc2, 0x40,
-	0x14, 0x86, 0x3b, 0x14, 0x0a, 0x3e, 0x08, 0x36, 0x13, 0x12, 0x27, 0x2e, 0x26, 0x95, 0x8d, 0x18,
-	0x13, 0x4c, 0xf0, 0x06, 0xc6, 0xae, 0x70, 0x61, 0x1a, 0x74, 0x4b, 0x4a, 0x79, 0x21, 0xa4, 0x94,
-	0x69, 0x4a, 0x9d, 0xa4, 0x37, 0x71, 0xef, 0xde, 0x73, 0xb0, 0xe4, 0x08, 0x52, 0x2f, 0x62, 0xfa,
-	0xc6, 0xe2, 0xc6, 0xcd, 0xe4, 0xfd, 0xff, 0xff, 0x65, 0xe6, 0x7f, 0x03, 0x9d, 0x58, 0x8f, 0xd3,
-	0x4c, 0xe5, 0x8a, 0x3b, 0x89, 0x8e, 0xa2, 0x74, 0x71, 0x39, 0x58, 0xa9, 0x95, 0x22, 0xeb, 0xae,
-	0x9a, 0x4c, 0x3a, 0xfc, 0x64, 0xd0, 0x99, 0x62, 0xf1, 0x1a, 0x6e, 0xde, 0x90, 0xbb, 0x60, 0xc7,
-	0x58, 0x08, 0xe6, 0xb1, 0x51, 0x2f, 0xa8, 0x46, 0x7e, 0x0d, 0xe7, 0x51, 0x86, 0x61, 0x8e, 0xf3,
-	0x0c, 0xf5, 0x7a, 0xb7, 0x56, 0x5b, 0xd1, 0xf0, 0xd8, 0xc8, 0x0e, 0xfa, 0xc6, 0x0e, 0x7e, 0x5d,
-	0x7e, 0x05, 0xbd, 0x44, 0x2d, 0xff, 0x28, 0x9b, 0xa8, 0x6e, 0xa2, 0x96, 0x27, 0x44, 0x40, 0x5b,
-	0x63, 0x46, 0x69, 0x93, 0xd2, 0x5a, 0xf2, 0x01, 0xb4, 0x74, 0x55, 0x40, 0xb4, 0xe8, 0x65, 0x23,
-	0x2a, 0x77, 0x83, 0xe1, 0x0e, 0x85, 0x43, 0xb4, 0x11, 0xc3, 0x0f, 0x06, 0x2d, 0x5f, 0xe3, 0x36,
-	0xe7, 0xb7, 0xd0, 0xcc, 0x8b, 0x14, 0xa9, 0x6e, 0x7f, 0x72, 0x31, 0x36, 0x7b, 0x8e, 0x29, 0x34,
-	0xe7, 0xac, 0x48, 0x31, 0x20, 0x88, 0x7b, 0xd0, 0x88, 0x35, 0x75, 0xef, 0x4e, 0xdc, 0x1a, 0xad,
-	0x17, 0x0f, 0x1a, 0xb1, 0xe6, 0x37, 0xd0, 0x4e, 0x33, 0xd4, 0xf3, 0x58, 0x53, 0xf9, 0xff, 0x30,
-	0xa7, 0x02, 0xa6, 0x7a, 0xe8, 0xc1, 0xd9, 0xe9, 0x7e, 0xde, 0x06, 0xfb, 0xf9, 0x65, 0xe6, 0x5a,
-	0x1c, 0xc0, 0x79, 0xf4, 0x9f, 0xfc, 0x99, 0xef, 0xb2, 0x07, 0xb1, 0x3f, 0x4a, 0xeb, 0x70, 0x94,
-	0xd6, 0xbe, 0x94, 0xec, 0x50, 0x4a, 0xf6, 0x55, 0x4a, 0xf6, 0xfe, 0x2d, 0xad, 0x85, 0x43, 0xff,
-	0x7e, 0xff, 0x13, 0x00, 0x00, 0xff, 0xff, 0xb5, 0x45, 0x92, 0x5d, 0xa1, 0x01, 0x00, 0x00,
+	// 325 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x91, 0x41, 0x4e, 0xf2, 0x40,
+	0x14, 0xc7, 0x3b, 0x94, 0x16, 0xbe, 0x07, 0xe1, 0x6b, 0x26, 0x24, 0x36, 0x2e, 0x6a, 0x65, 0x23,
+	0xc6, 0x04, 0x13, 0xbc, 0x81, 0xb1, 0x0b, 0x83, 0x0b, 0xd3, 0xa0, 0x5b, 0x52, 0xe0, 0x85, 0x90,
+	0x42, 0xa7, 0x19, 0xea, 0xc4, 0xde, 0xc4, 0x53, 0x78, 0x0a, 0x17, 0x2c, 0x39, 0x82, 0xe0, 0x45,
+	0xcc, 0xbc, 0xb1, 0xb8, 0x71, 0xd3, 0xbc, 0xf7, 0xfb, 0xff, 0x92, 0xfe, 0x5f, 0x06, 0x9a, 0xa9,
+	0x1a, 0xe4, 0x52, 0x14, 0x82, 0xbb, 0x6b, 0x35, 0x9b, 0xe5, 0xd3, 0xd3, 0xee, 0x42, 0x2c, 0x04,
+	0xa1, 0x6b, 0x3d, 0x99, 0xb4, 0xf7, 0xce, 0xa0, 0x39, 0xc2, 0xf2, 0x39, 0x59, 0xbd, 0x20, 0xf7,
+	0xc0, 0x4e, 0xb1, 0xf4, 0x59, 0xc8, 0xfa, 0xed, 0x58, 0x8f, 0xfc, 0x02, 0xfe, 0xcf, 0x24, 0x26,
+	0x05, 0x4e, 0x24, 0xaa, 0xe5, 0x66, 0x29, 0x32, 0xbf, 0x16, 0xb2, 0xbe, 0x1d, 0x77, 0x0c, 0x8e,
+	0x7f, 0x28, 0x3f, 0x87, 0xf6, 0x5a, 0xcc, 0x7f, 0x2d, 0x9b, 0xac, 0xd6, 0x5a, 0xcc, 0x8f, 0x8a,
+	0x0f, 0x0d, 0x85, 0x92, 0xd2, 0x3a, 0xa5, 0xd5, 0xca, 0xbb, 0xe0, 0x28, 0x5d, 0xc0, 0x77, 0xe8,
+	0xcf, 0x66, 0xd1, 0x74, 0x85, 0xc9, 0x06, 0x7d, 0x97, 0x6c, 0xb3, 0xf4, 0x3e, 0x18, 0x38, 0x91,
+	0xc2, 0xac, 0xe0, 0x57, 0x50, 0x2f, 0xca, 0x1c, 0xa9, 0x6e, 0x67, 0x78, 0x32, 0x30, 0x77, 0x0e,
+	0x28, 0x34, 0xdf, 0x71, 0x99, 0x63, 0x4c, 0x12, 0x0f, 0xa1, 0x96, 0x2a, 0xea, 0xde, 0x1a, 0x7a,
+	0x95, 0x5a, 0x1d, 0x1e, 0xd7, 0x52, 0xc5, 0x2f, 0xa1, 0x91, 0x4b, 0x54, 0x93, 0x54, 0x51, 0xf9,
+	0xbf, 0x34, 0x57, 0x0b, 0x23, 0xc5, 0xcf, 0xa0, 0x25, 0x93, 0x6c, 0x81, 0x93, 0x65, 0x36, 0xc7,
+	0x57, 0xba, 0xc6, 0x89, 0x81, 0xd0, 0xbd, 0x26, 0xbd, 0x10, 0xfe, 0x1d, 0x0b, 0xf0, 0x06, 0xd8,
+	0x8f, 0x4f, 0x63, 0xcf, 0xe2, 0x00, 0xee, 0x5d, 0xf4, 0x10, 0x8d, 0x23, 0x8f, 0xdd, 0xfa, 0xdb,
+	0x7d, 0x60, 0xed, 0xf6, 0x81, 0xb5, 0x3d, 0x04, 0x6c, 0x77, 0x08, 0xd8, 0xe7, 0x21, 0x60, 0x6f,
+	0x5f, 0x81, 0x35, 0x75, 0xe9, 0x61, 0x6e, 0xbe, 0x03, 0x00, 0x00, 0xff, 0xff, 0xa0, 0x4f, 0x86,
+	0x5c, 0xc2, 0x01, 0x00, 0x00,
 }
 
 func (m *KeyValue) Marshal() (dAtA []byte, err error) {
@@ -271,6 +276,11 @@ func (m *Event) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.RangeIndex != 0 {
+		i = encodeVarintKv(dAtA, i, uint64(m.RangeIndex))
+		i--
+		dAtA[i] = 0x20
+	}
 	if m.PrevKv != nil {
 		{
 			size, err := m.PrevKv.MarshalToSizedBuffer(dAtA[:i])
@@ -363,6 +373,9 @@ func (m *Event) Size() (n int) {
 		l = m.PrevKv.Size()
 		n += 1 + l + sovKv(uint64(l))
 	}
+	if m.RangeIndex != 0 {
+		n += 1 + sovKv(uint64(m.RangeIndex))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -690,6 +703,25 @@ func (m *Event) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RangeIndex", wireType)
+			}
+			m.RangeIndex = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowKv
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RangeIndex |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipKv(dAtA[iNdEx:])
@@ -15,6 +15,10 @@
 package rpctypes
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -32,25 +36,39 @@ var (
 	ErrGRPCCompacted               = status.Error(codes.OutOfRange, "etcdserver: mvcc: required revision has been compacted")
 	ErrGRPCFutureRev               = status.Error(codes.OutOfRange, "etcdserver: mvcc: required revision is a future revision")
 	ErrGRPCNoSpace                 = status.Error(codes.ResourceExhausted, "etcdserver: mvcc: database space exceeded")
+	ErrGRPCInvalidKeep             = status.Error(codes.InvalidArgument, "etcdserver: mvcc: number of revisions to keep must be positive")
+	ErrGRPCInvalidKeyTTL           = status.Error(codes.InvalidArgument, "etcdserver: key TTL must be positive")
+	ErrGRPCKeyTTLInTxn             = status.Error(codes.InvalidArgument, "etcdserver: key TTL given in txn request")
+	ErrGRPCKeyTTLIgnoreLease       = status.Error(codes.InvalidArgument, "etcdserver: key TTL given with ignore lease")
+	ErrGRPCQuotaExceeded           = status.Error(codes.ResourceExhausted, "etcdserver: mvcc: prefix quota exceeded")
+	ErrGRPCInvalidPrefixQuota      = status.Error(codes.InvalidArgument, "etcdserver: prefix quota limits must not be negative")
 
 	ErrGRPCLeaseNotFound    = status.Error(codes.NotFound, "etcdserver: requested lease not found")
 	ErrGRPCLeaseExist       = status.Error(codes.FailedPrecondition, "etcdserver: lease already exists")
 	ErrGRPCLeaseTTLTooLarge = status.Error(codes.OutOfRange, "etcdserver: too large lease TTL")
+	ErrGRPCLeaseTTLTooShort = status.Error(codes.FailedPrecondition, "etcdserver: lease TTL shorter than the remaining TTL of the old lease")
 
-	ErrGRPCWatchCanceled = status.Error(codes.Canceled, "etcdserver: watch canceled")
+	ErrGRPCWatchCanceled       = status.Error(codes.Canceled, "etcdserver: watch canceled")
+	ErrGRPCTooManyWatchStreams = status.Error(codes.ResourceExhausted, "etcdserver: too many watch streams on connection")
 
 	ErrGRPCMemberExist            = status.Error(codes.FailedPrecondition, "etcdserver: member ID already exist")
 	ErrGRPCPeerURLExist           = status.Error(codes.FailedPrecondition, "etcdserver: Peer URLs already exists")
 	ErrGRPCMemberNotEnoughStarted = status.Error(codes.FailedPrecondition, "etcdserver: re-configuration failed due to not enough started members")
 	ErrGRPCMemberBadURLs          = status.Error(codes.InvalidArgument, "etcdserver: given member URLs are invalid")
 	ErrGRPCMemberNotFound         = status.Error(codes.NotFound, "etcdserver: member not found")
+	ErrGRPCMemberNameNotFound     = status.Error(codes.NotFound, "etcdserver: member name not found")
+	ErrGRPCMemberNameAmbiguous    = status.Error(codes.FailedPrecondition, "etcdserver: member name matches more than one member")
 	ErrGRPCMemberNotLearner       = status.Error(codes.FailedPrecondition, "etcdserver: can only promote a learner member")
 	ErrGRPCLearnerNotReady        = status.Error(codes.FailedPrecondition, "etcdserver: can only promote a learner member which is in sync with leader")
+	ErrGRPCMemberNotPromotable    = status.Error(codes.FailedPrecondition, "etcdserver: member is not promotable")
 	ErrGRPCTooManyLearners        = status.Error(codes.FailedPrecondition, "etcdserver: too many learner members in cluster")
 	ErrGRPCClusterIdMismatch      = status.Error(codes.FailedPrecondition, "etcdserver: cluster ID mismatch")
+	ErrGRPCMemberNoChange         = status.Error(codes.InvalidArgument, "etcdserver: no member to add or remove")
 
 	ErrGRPCRequestTooLarge        = status.Error(codes.InvalidArgument, "etcdserver: request is too large")
+	ErrGRPCValueTooLarge          = status.Error(codes.InvalidArgument, "etcdserver: value is too large")
 	ErrGRPCRequestTooManyRequests = status.Error(codes.ResourceExhausted, "etcdserver: too many requests")
+	ErrGRPCUserRateLimited        = status.Error(codes.ResourceExhausted, "etcdserver: user request rate exceeded")
 
 	ErrGRPCRootUserNotExist     = status.Error(codes.FailedPrecondition, "etcdserver: root user does not exist")
 	ErrGRPCRootRoleNotExist     = status.Error(codes.FailedPrecondition, "etcdserver: root user does not have root role")
@@ -83,6 +101,11 @@ var (
 	ErrGRPCCorrupt                    = status.Error(codes.DataLoss, "etcdserver: corrupt cluster")
 	ErrGRPCNotSupportedForLearner     = status.Error(codes.FailedPrecondition, "etcdserver: rpc not supported for learner")
 	ErrGRPCBadLeaderTransferee        = status.Error(codes.FailedPrecondition, "etcdserver: bad leader transferee")
+	ErrGRPCSnapshotExpired            = status.Error(codes.FailedPrecondition, "etcdserver: snapshot expired")
+	ErrGRPCSnapshotOffsetOutOfRange   = status.Error(codes.OutOfRange, "etcdserver: snapshot offset out of range")
+	ErrGRPCHotKeyTrackingDisabled     = status.Error(codes.FailedPrecondition, "etcdserver: hot key tracking is disabled")
+	ErrGRPCRebuildIndexOnLeader       = status.Error(codes.FailedPrecondition, "etcdserver: refusing to rebuild the key index of the leader without force")
+	ErrGRPCRebuildingIndex            = status.Error(codes.Unavailable, "etcdserver: rebuilding the key index")
 
 	ErrGRPCWrongDowngradeVersionFormat   = status.Error(codes.InvalidArgument, "etcdserver: wrong downgrade target version format")
 	ErrGRPCInvalidDowngradeTargetVersion = status.Error(codes.InvalidArgument, "etcdserver: invalid downgrade target version")
@@ -99,29 +122,44 @@ var (
 		ErrorDesc(ErrGRPCValueProvided): ErrGRPCValueProvided,
 		ErrorDesc(ErrGRPCLeaseProvided): ErrGRPCLeaseProvided,
 
-		ErrorDesc(ErrGRPCTooManyOps):        ErrGRPCTooManyOps,
-		ErrorDesc(ErrGRPCDuplicateKey):      ErrGRPCDuplicateKey,
-		ErrorDesc(ErrGRPCInvalidSortOption): ErrGRPCInvalidSortOption,
-		ErrorDesc(ErrGRPCCompacted):         ErrGRPCCompacted,
-		ErrorDesc(ErrGRPCFutureRev):         ErrGRPCFutureRev,
-		ErrorDesc(ErrGRPCNoSpace):           ErrGRPCNoSpace,
+		ErrorDesc(ErrGRPCTooManyOps):         ErrGRPCTooManyOps,
+		ErrorDesc(ErrGRPCDuplicateKey):       ErrGRPCDuplicateKey,
+		ErrorDesc(ErrGRPCInvalidSortOption):  ErrGRPCInvalidSortOption,
+		ErrorDesc(ErrGRPCCompacted):          ErrGRPCCompacted,
+		ErrorDesc(ErrGRPCFutureRev):          ErrGRPCFutureRev,
+		ErrorDesc(ErrGRPCNoSpace):            ErrGRPCNoSpace,
+		ErrorDesc(ErrGRPCInvalidKeep):        ErrGRPCInvalidKeep,
+		ErrorDesc(ErrGRPCInvalidKeyTTL):      ErrGRPCInvalidKeyTTL,
+		ErrorDesc(ErrGRPCKeyTTLInTxn):        ErrGRPCKeyTTLInTxn,
+		ErrorDesc(ErrGRPCKeyTTLIgnoreLease):  ErrGRPCKeyTTLIgnoreLease,
+		ErrorDesc(ErrGRPCQuotaExceeded):      ErrGRPCQuotaExceeded,
+		ErrorDesc(ErrGRPCInvalidPrefixQuota): ErrGRPCInvalidPrefixQuota,
 
 		ErrorDesc(ErrGRPCLeaseNotFound):    ErrGRPCLeaseNotFound,
 		ErrorDesc(ErrGRPCLeaseExist):       ErrGRPCLeaseExist,
 		ErrorDesc(ErrGRPCLeaseTTLTooLarge): ErrGRPCLeaseTTLTooLarge,
+		ErrorDesc(ErrGRPCLeaseTTLTooShort): ErrGRPCLeaseTTLTooShort,
+
+		ErrorDesc(ErrGRPCTooManyWatchStreams): ErrGRPCTooManyWatchStreams,
 
 		ErrorDesc(ErrGRPCMemberExist):            ErrGRPCMemberExist,
 		ErrorDesc(ErrGRPCPeerURLExist):           ErrGRPCPeerURLExist,
 		ErrorDesc(ErrGRPCMemberNotEnoughStarted): ErrGRPCMemberNotEnoughStarted,
 		ErrorDesc(ErrGRPCMemberBadURLs):          ErrGRPCMemberBadURLs,
 		ErrorDesc(ErrGRPCMemberNotFound):         ErrGRPCMemberNotFound,
+		ErrorDesc(ErrGRPCMemberNameNotFound):     ErrGRPCMemberNameNotFound,
+		ErrorDesc(ErrGRPCMemberNameAmbiguous):    ErrGRPCMemberNameAmbiguous,
 		ErrorDesc(ErrGRPCMemberNotLearner):       ErrGRPCMemberNotLearner,
 		ErrorDesc(ErrGRPCLearnerNotReady):        ErrGRPCLearnerNotReady,
+		ErrorDesc(ErrGRPCMemberNotPromotable):    ErrGRPCMemberNotPromotable,
 		ErrorDesc(ErrGRPCTooManyLearners):        ErrGRPCTooManyLearners,
 		ErrorDesc(ErrGRPCClusterIdMismatch):      ErrGRPCClusterIdMismatch,
+		ErrorDesc(ErrGRPCMemberNoChange):         ErrGRPCMemberNoChange,
 
 		ErrorDesc(ErrGRPCRequestTooLarge):        ErrGRPCRequestTooLarge,
+		ErrorDesc(ErrGRPCValueTooLarge):          ErrGRPCValueTooLarge,
 		ErrorDesc(ErrGRPCRequestTooManyRequests): ErrGRPCRequestTooManyRequests,
+		ErrorDesc(ErrGRPCUserRateLimited):        ErrGRPCUserRateLimited,
 
 		ErrorDesc(ErrGRPCRootUserNotExist):     ErrGRPCRootUserNotExist,
 		ErrorDesc(ErrGRPCRootRoleNotExist):     ErrGRPCRootRoleNotExist,
@@ -152,6 +190,11 @@ var (
 		ErrorDesc(ErrGRPCCorrupt):                    ErrGRPCCorrupt,
 		ErrorDesc(ErrGRPCNotSupportedForLearner):     ErrGRPCNotSupportedForLearner,
 		ErrorDesc(ErrGRPCBadLeaderTransferee):        ErrGRPCBadLeaderTransferee,
+		ErrorDesc(ErrGRPCSnapshotExpired):            ErrGRPCSnapshotExpired,
+		ErrorDesc(ErrGRPCSnapshotOffsetOutOfRange):   ErrGRPCSnapshotOffsetOutOfRange,
+		ErrorDesc(ErrGRPCHotKeyTrackingDisabled):     ErrGRPCHotKeyTrackingDisabled,
+		ErrorDesc(ErrGRPCRebuildIndexOnLeader):       ErrGRPCRebuildIndexOnLeader,
+		ErrorDesc(ErrGRPCRebuildingIndex):            ErrGRPCRebuildingIndex,
 
 		ErrorDesc(ErrGRPCClusterVersionUnavailable):     ErrGRPCClusterVersionUnavailable,
 		ErrorDesc(ErrGRPCWrongDowngradeVersionFormat):   ErrGRPCWrongDowngradeVersionFormat,
@@ -163,32 +206,47 @@ var (
 
 // client-side error
 var (
-	ErrEmptyKey          = Error(ErrGRPCEmptyKey)
-	ErrKeyNotFound       = Error(ErrGRPCKeyNotFound)
-	ErrValueProvided     = Error(ErrGRPCValueProvided)
-	ErrLeaseProvided     = Error(ErrGRPCLeaseProvided)
-	ErrTooManyOps        = Error(ErrGRPCTooManyOps)
-	ErrDuplicateKey      = Error(ErrGRPCDuplicateKey)
-	ErrInvalidSortOption = Error(ErrGRPCInvalidSortOption)
-	ErrCompacted         = Error(ErrGRPCCompacted)
-	ErrFutureRev         = Error(ErrGRPCFutureRev)
-	ErrNoSpace           = Error(ErrGRPCNoSpace)
+	ErrEmptyKey           = Error(ErrGRPCEmptyKey)
+	ErrKeyNotFound        = Error(ErrGRPCKeyNotFound)
+	ErrValueProvided      = Error(ErrGRPCValueProvided)
+	ErrLeaseProvided      = Error(ErrGRPCLeaseProvided)
+	ErrTooManyOps         = Error(ErrGRPCTooManyOps)
+	ErrDuplicateKey       = Error(ErrGRPCDuplicateKey)
+	ErrInvalidSortOption  = Error(ErrGRPCInvalidSortOption)
+	ErrCompacted          = Error(ErrGRPCCompacted)
+	ErrFutureRev          = Error(ErrGRPCFutureRev)
+	ErrNoSpace            = Error(ErrGRPCNoSpace)
+	ErrInvalidKeep        = Error(ErrGRPCInvalidKeep)
+	ErrInvalidKeyTTL      = Error(ErrGRPCInvalidKeyTTL)
+	ErrKeyTTLInTxn        = Error(ErrGRPCKeyTTLInTxn)
+	ErrKeyTTLIgnoreLease  = Error(ErrGRPCKeyTTLIgnoreLease)
+	ErrQuotaExceeded      = Error(ErrGRPCQuotaExceeded)
+	ErrInvalidPrefixQuota = Error(ErrGRPCInvalidPrefixQuota)
 
 	ErrLeaseNotFound    = Error(ErrGRPCLeaseNotFound)
 	ErrLeaseExist       = Error(ErrGRPCLeaseExist)
 	ErrLeaseTTLTooLarge = Error(ErrGRPCLeaseTTLTooLarge)
+	ErrLeaseTTLTooShort = Error(ErrGRPCLeaseTTLTooShort)
+
+	ErrTooManyWatchStreams = Error(ErrGRPCTooManyWatchStreams)
 
 	ErrMemberExist            = Error(ErrGRPCMemberExist)
 	ErrPeerURLExist           = Error(ErrGRPCPeerURLExist)
 	ErrMemberNotEnoughStarted = Error(ErrGRPCMemberNotEnoughStarted)
 	ErrMemberBadURLs          = Error(ErrGRPCMemberBadURLs)
 	ErrMemberNotFound         = Error(ErrGRPCMemberNotFound)
+	ErrMemberNameNotFound     = Error(ErrGRPCMemberNameNotFound)
+	ErrMemberNameAmbiguous    = Error(ErrGRPCMemberNameAmbiguous)
 	ErrMemberNotLearner       = Error(ErrGRPCMemberNotLearner)
 	ErrMemberLearnerNotReady  = Error(ErrGRPCLearnerNotReady)
+	ErrMemberNotPromotable    = Error(ErrGRPCMemberNotPromotable)
 	ErrTooManyLearners        = Error(ErrGRPCTooManyLearners)
+	ErrMemberNoChange         = Error(ErrGRPCMemberNoChange)
 
 	ErrRequestTooLarge = Error(ErrGRPCRequestTooLarge)
+	ErrValueTooLarge   = Error(ErrGRPCValueTooLarge)
 	ErrTooManyRequests = Error(ErrGRPCRequestTooManyRequests)
+	ErrUserRateLimited = Error(ErrGRPCUserRateLimited)
 
 	ErrRootUserNotExist     = Error(ErrGRPCRootUserNotExist)
 	ErrRootRoleNotExist     = Error(ErrGRPCRootRoleNotExist)
@@ -220,6 +278,11 @@ var (
 	ErrUnhealthy                  = Error(ErrGRPCUnhealthy)
 	ErrCorrupt                    = Error(ErrGRPCCorrupt)
 	ErrBadLeaderTransferee        = Error(ErrGRPCBadLeaderTransferee)
+	ErrSnapshotExpired            = Error(ErrGRPCSnapshotExpired)
+	ErrSnapshotOffsetOutOfRange   = Error(ErrGRPCSnapshotOffsetOutOfRange)
+	ErrHotKeyTrackingDisabled     = Error(ErrGRPCHotKeyTrackingDisabled)
+	ErrRebuildIndexOnLeader       = Error(ErrGRPCRebuildIndexOnLeader)
+	ErrRebuildingIndex            = Error(ErrGRPCRebuildingIndex)
 
 	ErrClusterVersionUnavailable     = Error(ErrGRPCClusterVersionUnavailable)
 	ErrWrongDowngradeVersionFormat   = Error(ErrGRPCWrongDowngradeVersionFormat)
@@ -250,7 +313,11 @@ func Error(err error) error {
 		return nil
 	}
 	verr, ok := errStringToError[ErrorDesc(err)]
-	if !ok { // not gRPC error
+	if !ok {
+		if _, scoped := QuotaExceededPrefix(err); scoped {
+			return EtcdError{code: codes.ResourceExhausted, desc: ErrorDesc(err)}
+		}
+		// not gRPC error
 		return err
 	}
 	ev, ok := status.FromError(verr)
@@ -269,3 +336,20 @@ func ErrorDesc(err error) string {
 	}
 	return err.Error()
 }
+
+// ErrGRPCQuotaExceededForPrefix returns ErrGRPCQuotaExceeded scoped to the
+// prefix whose quota was exceeded.
+func ErrGRPCQuotaExceededForPrefix(prefix []byte) error {
+	return status.Error(codes.ResourceExhausted, fmt.Sprintf("%s: %q", ErrorDesc(ErrGRPCQuotaExceeded), prefix))
+}
+
+// QuotaExceededPrefix returns the prefix whose quota was exceeded if err is
+// ErrQuotaExceeded scoped to a prefix.
+func QuotaExceededPrefix(err error) (prefix string, ok bool) {
+	quoted, ok := strings.CutPrefix(ErrorDesc(err), ErrorDesc(ErrGRPCQuotaExceeded)+": ")
+	if !ok {
+		return "", false
+	}
+	prefix, uerr := strconv.Unquote(quoted)
+	return prefix, uerr == nil
+}
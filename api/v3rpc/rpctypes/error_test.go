@@ -15,6 +15,8 @@
 package rpctypes
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"google.golang.org/grpc/codes"
@@ -40,3 +42,30 @@ func TestConvert(t *testing.T) {
 		t.Fatalf("expected them to be equal, got %v / %v", ev2.Code(), e3.(EtcdError).Code())
 	}
 }
+
+func TestErrorIs(t *testing.T) {
+	err := Error(status.Error(codes.Unavailable, "etcdserver: no leader"))
+	if !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("expected errors.Is(%v, ErrNoLeader)", err)
+	}
+	if !errors.Is(fmt.Errorf("member add: %w", err), ErrNoLeader) {
+		t.Fatalf("expected wrapped %v to match ErrNoLeader", err)
+	}
+	if errors.Is(err, ErrMemberNotEnoughStarted) {
+		t.Fatalf("expected %v not to match ErrMemberNotEnoughStarted", err)
+	}
+}
+
+func TestQuotaExceededPrefix(t *testing.T) {
+	err := Error(ErrGRPCQuotaExceededForPrefix([]byte("/tenant-a/")))
+	if ev, ok := err.(EtcdError); !ok || ev.Code() != codes.ResourceExhausted {
+		t.Fatalf("expected a resource exhausted EtcdError, got %v", err)
+	}
+	prefix, ok := QuotaExceededPrefix(err)
+	if !ok || prefix != "/tenant-a/" {
+		t.Fatalf("expected prefix %q, got %q (%v)", "/tenant-a/", prefix, ok)
+	}
+	if _, ok := QuotaExceededPrefix(ErrNoSpace); ok {
+		t.Fatalf("expected %v not to be scoped to a prefix", ErrNoSpace)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+
+	"go.etcd.io/etcd/client/pkg/v3/tlsutil"
+
+	"go.uber.org/zap"
+)
+
+// certReloader watches a cert and key file pair and serves the certificate
+// they hold. The files are checked on every handshake, and the certificate
+// is only reloaded after one of them changed, so that rotating the files
+// on disk takes effect on the next handshake without a restart. A
+// replacement that fails to load is rejected and the previous certificate
+// is kept, so a half-written or malformed pair never breaks new handshakes.
+type certReloader struct {
+	lg        *zap.Logger
+	kind      string
+	certFile  string
+	keyFile   string
+	parseFunc func([]byte, []byte) (tls.Certificate, error)
+
+	mu   sync.Mutex
+	cert *tls.Certificate
+	// certStat and keyStat describe the files cert was last checked against.
+	certStat os.FileInfo
+	keyStat  os.FileInfo
+	// statErr suppresses repeated warnings while the files cannot be found.
+	statErr bool
+}
+
+// newCertReloader loads the certificate in certFile and keyFile, failing if
+// it cannot be loaded.
+func newCertReloader(lg *zap.Logger, kind, certFile, keyFile string, parseFunc func([]byte, []byte) (tls.Certificate, error)) (*certReloader, error) {
+	r := &certReloader{lg: lg, kind: kind, certFile: certFile, keyFile: keyFile, parseFunc: parseFunc}
+	var err error
+	if r.cert, err = tlsutil.NewCert(certFile, keyFile, parseFunc); err != nil {
+		return nil, err
+	}
+	if r.certStat, r.keyStat, err = r.stat(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) stat() (certStat, keyStat os.FileInfo, err error) {
+	if certStat, err = os.Stat(r.certFile); err != nil {
+		return nil, nil, err
+	}
+	if keyStat, err = os.Stat(r.keyFile); err != nil {
+		return nil, nil, err
+	}
+	return certStat, keyStat, nil
+}
+
+// certificate returns the current certificate, reloading it first if the
+// cert or key file changed since it was last checked.
+func (r *certReloader) certificate() *tls.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certStat, keyStat, err := r.stat()
+	if err != nil {
+		if !r.statErr {
+			r.lg.Warn(
+				"failed to find "+r.kind+" cert files, keeping previous certificate",
+				zap.String("cert-file", r.certFile),
+				zap.String("key-file", r.keyFile),
+				zap.Error(err),
+			)
+			r.statErr = true
+		}
+		return r.cert
+	}
+	r.statErr = false
+	if !fileChanged(r.certStat, certStat) && !fileChanged(r.keyStat, keyStat) {
+		return r.cert
+	}
+	// the files are only retried once they change again
+	r.certStat, r.keyStat = certStat, keyStat
+
+	cert, err := tlsutil.NewCert(r.certFile, r.keyFile, r.parseFunc)
+	if err != nil {
+		r.lg.Warn(
+			"failed to reload "+r.kind+" certificate, keeping previous certificate",
+			zap.String("cert-file", r.certFile),
+			zap.String("key-file", r.keyFile),
+			zap.Error(err),
+		)
+		return r.cert
+	}
+	r.cert = cert
+	r.lg.Info(
+		"reloaded "+r.kind+" certificate",
+		zap.String("cert-file", r.certFile),
+		zap.String("key-file", r.keyFile),
+	)
+	return r.cert
+}
+
+// fileChanged reports whether the file described by cur was modified or
+// replaced since prev was taken.
+func fileChanged(prev, cur os.FileInfo) bool {
+	return !os.SameFile(prev, cur) || !prev.ModTime().Equal(cur.ModTime()) || prev.Size() != cur.Size()
+}
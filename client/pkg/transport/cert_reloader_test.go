@@ -0,0 +1,69 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCertReloader(t *testing.T) {
+	info, err := createSelfCert(t)
+	if err != nil {
+		t.Fatalf("unable to create cert: %v", err)
+	}
+	r, err := newCertReloader(zaptest.NewLogger(t), "peer", info.CertFile, info.KeyFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := r.certificate()
+	if r.certificate() != first {
+		t.Fatal("expected the certificate to be cached while its files are unchanged")
+	}
+
+	rotated, err := createSelfCert(t)
+	if err != nil {
+		t.Fatalf("unable to create cert: %v", err)
+	}
+	for src, dst := range map[string]string{rotated.CertFile: info.CertFile, rotated.KeyFile: info.KeyFile} {
+		b, rerr := os.ReadFile(src)
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		if err = os.WriteFile(dst, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := r.certificate()
+	if bytes.Equal(second.Certificate[0], first.Certificate[0]) {
+		t.Fatal("expected the rotated certificate to be loaded")
+	}
+
+	if err = os.WriteFile(info.CertFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if r.certificate() != second {
+		t.Fatal("expected a malformed certificate to be rejected")
+	}
+	if err = os.Remove(info.KeyFile); err != nil {
+		t.Fatal(err)
+	}
+	if r.certificate() != second {
+		t.Fatal("expected a missing key file to keep the previous certificate")
+	}
+}
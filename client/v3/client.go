@@ -35,6 +35,7 @@ import (
 	"go.etcd.io/etcd/api/v3/version"
 	"go.etcd.io/etcd/client/pkg/v3/logutil"
 	"go.etcd.io/etcd/client/v3/credentials"
+	"go.etcd.io/etcd/client/v3/internal/balancer"
 	"go.etcd.io/etcd/client/v3/internal/endpoint"
 	"go.etcd.io/etcd/client/v3/internal/resolver"
 )
@@ -42,6 +43,7 @@ import (
 var (
 	ErrNoAvailableEndpoints = errors.New("etcdclient: no available endpoints")
 	ErrOldCluster           = errors.New("etcdclient: old cluster version")
+	ErrDryRunUnsupported    = errors.New("etcdclient: dry run txns are not supported by the cluster version")
 )
 
 // Client provides and manages an etcd v3 client session.
@@ -58,10 +60,18 @@ type Client struct {
 	cfg      Config
 	creds    grpccredentials.TransportCredentials
 	resolver *resolver.EtcdManualResolver
+	// scores tracks the health of the endpoints if they are balanced by it.
+	scores *balancer.Scores
 
 	epMu      *sync.RWMutex
 	endpoints []string
 
+	// readConn serves the serializable range requests if read load
+	// balancing is enabled, over the endpoints of readResolver.
+	readConn            *grpc.ClientConn
+	readResolver        *resolver.EtcdManualResolver
+	readLeaderEndpoints map[string]struct{}
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
@@ -73,6 +83,10 @@ type Client struct {
 
 	callOpts []grpc.CallOption
 
+	// dryRunEndpoints are the endpoints last found to support dry run txns.
+	dryRunMu        *sync.Mutex
+	dryRunEndpoints []string
+
 	lgMu *sync.RWMutex
 	lg   *zap.Logger
 }
@@ -91,7 +105,7 @@ func New(cfg Config) (*Client, error) {
 // service interface implementations and do not need connection management.
 func NewCtxClient(ctx context.Context, opts ...Option) *Client {
 	cctx, cancel := context.WithCancel(ctx)
-	c := &Client{ctx: cctx, cancel: cancel, lgMu: new(sync.RWMutex)}
+	c := &Client{ctx: cctx, cancel: cancel, lgMu: new(sync.RWMutex), dryRunMu: new(sync.Mutex)}
 	for _, opt := range opts {
 		opt(c)
 	}
@@ -152,6 +166,9 @@ func (c *Client) Close() error {
 	if c.Lease != nil {
 		c.Lease.Close()
 	}
+	if c.readConn != nil {
+		c.readConn.Close()
+	}
 	if c.conn != nil {
 		return toErr(c.ctx, c.conn.Close())
 	}
@@ -180,6 +197,9 @@ func (c *Client) SetEndpoints(eps ...string) {
 	c.endpoints = eps
 
 	c.resolver.SetEndpoints(eps)
+	if c.readResolver != nil {
+		c.readResolver.SetEndpoints(c.readEndpoints())
+	}
 }
 
 // Sync synchronizes client's endpoints with the known endpoints from the etcd membership.
@@ -369,6 +389,7 @@ func newClient(cfg *Config) (*Client, error) {
 		epMu:     new(sync.RWMutex),
 		callOpts: defaultCallOpts,
 		lgMu:     new(sync.RWMutex),
+		dryRunMu: new(sync.Mutex),
 	}
 
 	var err error
@@ -391,25 +412,32 @@ func newClient(cfg *Config) (*Client, error) {
 		client.Password = cfg.Password
 		client.authTokenBundle = credentials.NewPerRPCCredentialBundle()
 	}
-	if cfg.MaxCallSendMsgSize > 0 || cfg.MaxCallRecvMsgSize > 0 {
+	if cfg.MaxCallSendMsgSize > 0 || cfg.MaxCallRecvMsgSize > 0 || cfg.FailFast {
 		if cfg.MaxCallRecvMsgSize > 0 && cfg.MaxCallSendMsgSize > cfg.MaxCallRecvMsgSize {
 			return nil, fmt.Errorf("gRPC message recv limit (%d bytes) must be greater than send limit (%d bytes)", cfg.MaxCallRecvMsgSize, cfg.MaxCallSendMsgSize)
 		}
-		callOpts := []grpc.CallOption{
-			defaultWaitForReady,
-			defaultMaxCallSendMsgSize,
-			defaultMaxCallRecvMsgSize,
+		waitForReady := defaultWaitForReady
+		if cfg.FailFast {
+			// the requests fail instead of waiting while the connection is down
+			waitForReady = grpc.WaitForReady(false)
 		}
+		maxCallSendMsgSize := defaultMaxCallSendMsgSize
 		if cfg.MaxCallSendMsgSize > 0 {
-			callOpts[1] = grpc.MaxCallSendMsgSize(cfg.MaxCallSendMsgSize)
+			maxCallSendMsgSize = grpc.MaxCallSendMsgSize(cfg.MaxCallSendMsgSize)
 		}
+		maxCallRecvMsgSize := defaultMaxCallRecvMsgSize
 		if cfg.MaxCallRecvMsgSize > 0 {
-			callOpts[2] = grpc.MaxCallRecvMsgSize(cfg.MaxCallRecvMsgSize)
+			maxCallRecvMsgSize = grpc.MaxCallRecvMsgSize(cfg.MaxCallRecvMsgSize)
 		}
-		client.callOpts = callOpts
+		client.callOpts = []grpc.CallOption{waitForReady, maxCallSendMsgSize, maxCallRecvMsgSize}
 	}
 
-	client.resolver = resolver.New(cfg.Endpoints...)
+	if cfg.EndpointHealthScoring {
+		client.scores = balancer.NewScores()
+		client.resolver = resolver.NewScored(client.scores, cfg.Endpoints...)
+	} else {
+		client.resolver = resolver.New(cfg.Endpoints...)
+	}
 
 	if len(cfg.Endpoints) < 1 {
 		client.cancel()
@@ -428,6 +456,14 @@ func newClient(cfg *Config) (*Client, error) {
 	}
 	client.conn = conn
 
+	if cfg.ReadLoadBalancing {
+		if err = client.dialReadBalancer(); err != nil {
+			client.cancel()
+			client.conn.Close()
+			return nil, err
+		}
+	}
+
 	client.Cluster = NewCluster(client)
 	client.KV = NewKV(client)
 	client.Lease = NewLease(client)
@@ -456,6 +492,14 @@ func newClient(cfg *Config) (*Client, error) {
 		}
 	}
 
+	if client.readConn != nil {
+		// route serializable requests to the followers right away if possible
+		ctx, cancel = context.WithTimeout(client.ctx, readLeaderRefreshTimeout)
+		client.updateReadLeader(ctx)
+		cancel()
+		go client.refreshReadLeader()
+	}
+
 	go client.autoSync()
 	return client, nil
 }
@@ -492,16 +536,46 @@ func minSupportedVersion() *semver.Version {
 	panic("current version is not in the version list")
 }
 
-func (c *Client) checkVersion() (err error) {
-	var wg sync.WaitGroup
-
-	eps := c.Endpoints()
-	errc := make(chan error, len(eps))
+func (c *Client) checkVersion() error {
 	ctx, cancel := context.WithCancel(c.ctx)
 	if c.cfg.DialTimeout > 0 {
 		cancel()
 		ctx, cancel = context.WithTimeout(c.ctx, c.cfg.DialTimeout)
 	}
+	defer cancel()
+	return c.checkEndpointVersions(ctx, c.Endpoints(), minSupportedVersion(), ErrOldCluster)
+}
+
+// checkDryRun returns ErrDryRunUnsupported if an endpoint runs a version
+// older than 3.6, which would execute the writes of a dry run txn rather than
+// ignore them. The endpoints are checked again only once they change.
+func (c *Client) checkDryRun(ctx context.Context) error {
+	c.dryRunMu.Lock()
+	defer c.dryRunMu.Unlock()
+	eps := c.Endpoints()
+	if len(eps) == len(c.dryRunEndpoints) {
+		same := true
+		for i := range eps {
+			same = same && eps[i] == c.dryRunEndpoints[i]
+		}
+		if same {
+			return nil
+		}
+	}
+	if err := c.checkEndpointVersions(ctx, eps, &version.V3_6, ErrDryRunUnsupported); err != nil {
+		return err
+	}
+	c.dryRunEndpoints = eps
+	return nil
+}
+
+// checkEndpointVersions returns errOld if one of eps runs a version older
+// than min.
+func (c *Client) checkEndpointVersions(ctx context.Context, eps []string, min *semver.Version, errOld error) (err error) {
+	var wg sync.WaitGroup
+
+	errc := make(chan error, len(eps))
+	ctx, cancel := context.WithCancel(ctx)
 
 	wg.Add(len(eps))
 	for _, ep := range eps {
@@ -519,8 +593,9 @@ func (c *Client) checkVersion() (err error) {
 				return
 			}
 
-			if vs.LessThan(*minSupportedVersion()) {
-				rerr = ErrOldCluster
+			// consider only major and minor version
+			if (&semver.Version{Major: vs.Major, Minor: vs.Minor}).LessThan(*min) {
+				rerr = errOld
 			}
 			errc <- rerr
 		}(ep)
@@ -37,6 +37,8 @@ import (
 	"go.etcd.io/etcd/client/pkg/v3/testutil"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func NewClient(t *testing.T, cfg Config) (*Client, error) {
@@ -158,6 +160,43 @@ func TestDialNoTimeout(t *testing.T) {
 	c.Close()
 }
 
+func TestFailFast(t *testing.T) {
+	// an address nothing listens on refuses the connections
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ep := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	c, err := NewClient(t, Config{Endpoints: []string{ep}})
+	require.NoError(t, err)
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err = c.Get(ctx, "foo")
+	require.ErrorIs(t, err, context.DeadlineExceeded, "a request should wait for a connection by default")
+
+	c, err = NewClient(t, Config{Endpoints: []string{ep}, FailFast: true})
+	require.NoError(t, err)
+	defer c.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		_, err = c.Get(ctx, "foo")
+		var uerr *UnavailableError
+		require.True(t, errors.As(err, &uerr), "expected an unavailable error, got %v", err)
+		assert.Equal(t, []string{ep}, uerr.Endpoints)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Less(t, time.Since(start), 2*time.Second)
+	}
+
+	// the other call options are kept
+	c, err = NewClient(t, Config{Endpoints: []string{ep}, FailFast: true, MaxCallSendMsgSize: 1024})
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, []grpc.CallOption{grpc.WaitForReady(false), grpc.MaxCallSendMsgSize(1024), defaultMaxCallRecvMsgSize}, c.callOpts)
+}
+
 func TestIsHaltErr(t *testing.T) {
 	assert.Equal(t,
 		isHaltErr(context.TODO(), errors.New("etcdserver: some etcdserver error")),
@@ -418,6 +457,31 @@ func TestClientRejectOldCluster(t *testing.T) {
 
 }
 
+func TestClientCheckDryRun(t *testing.T) {
+	testutil.BeforeTest(t)
+	mm := &mockMaintenance{Version: map[string]string{
+		"192.168.3.41:22379": "3.6.0-alpha.0",
+		"192.168.3.41:22479": "3.5.9",
+	}}
+	c := &Client{
+		ctx:         context.Background(),
+		endpoints:   []string{"192.168.3.41:22379", "192.168.3.41:22479"},
+		epMu:        new(sync.RWMutex),
+		dryRunMu:    new(sync.Mutex),
+		Maintenance: mm,
+	}
+	require.ErrorIs(t, c.checkDryRun(context.Background()), ErrDryRunUnsupported)
+
+	c.endpoints = []string{"192.168.3.41:22379"}
+	require.NoError(t, c.checkDryRun(context.Background()))
+
+	// the endpoints are not checked again until they change
+	mm.Version["192.168.3.41:22379"] = "3.5.9"
+	require.NoError(t, c.checkDryRun(context.Background()))
+	c.endpoints = []string{"192.168.3.41:22379", "192.168.3.41:22479"}
+	require.ErrorIs(t, c.checkDryRun(context.Background()), ErrDryRunUnsupported)
+}
+
 type mockMaintenance struct {
 	Version map[string]string
 }
@@ -446,6 +510,14 @@ func (mm mockMaintenance) SnapshotWithVersion(ctx context.Context) (*SnapshotRes
 	return nil, nil
 }
 
+func (mm mockMaintenance) SnapshotFrom(ctx context.Context, id uint64, offset int64) (*SnapshotResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) SnapshotAtRevision(ctx context.Context, rev int64) (*SnapshotResponse, error) {
+	return nil, nil
+}
+
 func (mm mockMaintenance) Snapshot(ctx context.Context) (io.ReadCloser, error) {
 	return nil, nil
 }
@@ -458,6 +530,58 @@ func (mm mockMaintenance) Downgrade(ctx context.Context, action DowngradeAction,
 	return nil, nil
 }
 
+func (mm mockMaintenance) CompactionStatus(ctx context.Context, endpoint string) (*CompactionStatusResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) HotKeys(ctx context.Context, endpoint string, limit int64, reset bool) (*HotKeysResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) HashKVByRange(ctx context.Context, endpoint string, rev int64, key, end string) (*HashKVResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) SpaceReclaim(ctx context.Context, endpoint string, enable bool) (*SpaceReclaimResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) MemberSelf(ctx context.Context, endpoint string) (*MemberSelfResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) TriggerSnapshot(ctx context.Context, endpoint string) (*TriggerSnapshotResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) AuthExport(ctx context.Context) (*AuthExportResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) AuthImport(ctx context.Context, exported *AuthExportResponse, replace bool) (*AuthImportResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) PrefixQuotaSet(ctx context.Context, prefix string, maxBytes, maxKeys int64) (*PrefixQuotaSetResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) PrefixQuotaList(ctx context.Context, endpoint string) (*PrefixQuotaListResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) VerifyBackend(ctx context.Context, endpoint string, batchLimit int64, batchInterval time.Duration) (*VerifyBackendResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) KeyspaceStats(ctx context.Context, endpoint string, depth, limit int64) (*KeyspaceStatsResponse, error) {
+	return nil, nil
+}
+
+func (mm mockMaintenance) RebuildIndex(ctx context.Context, endpoint string, force bool) (*RebuildIndexResponse, error) {
+	return nil, nil
+}
+
 type mockAuthServer struct {
 	*etcdserverpb.UnimplementedAuthServer
 }
@@ -482,7 +606,11 @@ func (mc *mockCluster) MemberAddAsLearner(ctx context.Context, peerAddrs []strin
 	return nil, nil
 }
 
-func (mc *mockCluster) MemberRemove(ctx context.Context, id uint64) (*MemberRemoveResponse, error) {
+func (mc *mockCluster) MemberRemove(ctx context.Context, id uint64, opts ...MemberRemoveOption) (*MemberRemoveResponse, error) {
+	return nil, nil
+}
+
+func (mc *mockCluster) MemberRemoveByName(ctx context.Context, name string, opts ...MemberRemoveOption) (*MemberRemoveResponse, error) {
 	return nil, nil
 }
 
@@ -493,3 +621,7 @@ func (mc *mockCluster) MemberUpdate(ctx context.Context, id uint64, peerAddrs []
 func (mc *mockCluster) MemberPromote(ctx context.Context, id uint64) (*MemberPromoteResponse, error) {
 	return nil, nil
 }
+
+func (mc *mockCluster) MemberReconfigure(ctx context.Context, adds [][]string, removes []uint64) (*MemberReconfigureResponse, error) {
+	return nil, nil
+}
@@ -16,8 +16,11 @@ package clientv3
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/pkg/v3/types"
 
 	"google.golang.org/grpc"
@@ -30,10 +33,15 @@ type (
 	MemberRemoveResponse  pb.MemberRemoveResponse
 	MemberUpdateResponse  pb.MemberUpdateResponse
 	MemberPromoteResponse pb.MemberPromoteResponse
+
+	MemberReconfigureResponse pb.MemberReconfigureResponse
 )
 
 type Cluster interface {
-	// MemberList lists the current cluster membership.
+	// MemberList lists the current cluster membership. With WithSerializable, the
+	// member answers from its local view of the membership without requiring a
+	// leader, e.g. while it is partitioned; Header.MemberId and Header.RaftTerm
+	// identify the member and the raft term of that view.
 	MemberList(ctx context.Context, opts ...OpOption) (*MemberListResponse, error)
 
 	// MemberAdd adds a new member into the cluster.
@@ -42,25 +50,137 @@ type Cluster interface {
 	// MemberAddAsLearner adds a new learner member into the cluster.
 	MemberAddAsLearner(ctx context.Context, peerAddrs []string) (*MemberAddResponse, error)
 
-	// MemberRemove removes an existing member from the cluster.
-	MemberRemove(ctx context.Context, id uint64) (*MemberRemoveResponse, error)
+	// MemberRemove removes an existing member from the cluster. With
+	// WithLeadershipTransfer, a leader is removed only after handing off its leadership.
+	// With WithForce, the member is removed even if the other members are not all healthy.
+	MemberRemove(ctx context.Context, id uint64, opts ...MemberRemoveOption) (*MemberRemoveResponse, error)
+
+	// MemberRemoveByName removes an existing member with the given name from the cluster.
+	// It fails with ErrMemberNameNotFound if no member has the name, or with
+	// ErrMemberNameAmbiguous if more than one member has it.
+	MemberRemoveByName(ctx context.Context, name string, opts ...MemberRemoveOption) (*MemberRemoveResponse, error)
 
 	// MemberUpdate updates the peer addresses of the member.
 	MemberUpdate(ctx context.Context, id uint64, peerAddrs []string) (*MemberUpdateResponse, error)
 
 	// MemberPromote promotes a member from raft learner (non-voting) to raft voting member.
 	MemberPromote(ctx context.Context, id uint64) (*MemberPromoteResponse, error)
+
+	// MemberReconfigure adds a voting member for each of the peer addresses in
+	// adds and removes the members with the IDs in removes in a single change,
+	// which goes through a joint configuration of the current and the new
+	// voting members. It fails with ErrUnhealthy if the kept voting members
+	// would not form a quorum of both.
+	// Supported since etcd 3.6.
+	MemberReconfigure(ctx context.Context, adds [][]string, removes []uint64) (*MemberReconfigureResponse, error)
+}
+
+// ReconfigRetryPolicy bounds the retries of membership reconfiguration requests
+// rejected because the cluster has no leader, e.g. during a leader election.
+// Rejections that protect quorum, such as not enough started members or an
+// unhealthy cluster, are never retried.
+type ReconfigRetryPolicy struct {
+	// MaxRetries is the maximum number of retries. 0 means the number of
+	// retries is only bounded by Budget.
+	MaxRetries uint
+	// Budget is the total time spent retrying a single request.
+	// If 0, it defaults to 5 seconds.
+	Budget time.Duration
+	// WaitBetween is the backoff between two attempts.
+	// If 0, it defaults to 100 milliseconds.
+	WaitBetween time.Duration
+	// JitterFraction is the fraction of WaitBetween randomly added or subtracted.
+	// If 0, it defaults to 0.10.
+	JitterFraction float64
+}
+
+const (
+	defaultReconfigRetryBudget      = 5 * time.Second
+	defaultReconfigRetryWaitBetween = 100 * time.Millisecond
+)
+
+type reconfigRetriesKey struct{}
+
+// MemberAddOption configures a member add operation.
+type MemberAddOption func(*pb.MemberAddRequest)
+
+// ErrMemberAddOptionsNotSupported is returned by MemberAddAsLearnerWithOptions
+// when options are given for a Cluster that cannot apply them.
+var ErrMemberAddOptionsNotSupported = errors.New("etcdclient: member add options not supported by the cluster")
+
+// LearnerAdderWithOptions is implemented by the Clusters that can configure
+// the learners they add with MemberAddOptions. See
+// MemberAddAsLearnerWithOptions.
+type LearnerAdderWithOptions interface {
+	// MemberAddAsLearnerWithOptions adds a new learner member into the
+	// cluster like MemberAddAsLearner. With WithNonPromotable, the learner
+	// can never be promoted to a voting member.
+	MemberAddAsLearnerWithOptions(ctx context.Context, peerAddrs []string, opts ...MemberAddOption) (*MemberAddResponse, error)
+}
+
+// MemberAddAsLearnerWithOptions adds a new learner member into the cluster
+// with the MemberAddAsLearnerWithOptions method of c if it is a
+// LearnerAdderWithOptions, as are a Client and its Cluster. Otherwise the
+// learner is added with MemberAddAsLearner if there are no options, and
+// ErrMemberAddOptionsNotSupported is returned if there are.
+func MemberAddAsLearnerWithOptions(ctx context.Context, c Cluster, peerAddrs []string, opts ...MemberAddOption) (*MemberAddResponse, error) {
+	if cli, ok := c.(*Client); ok {
+		c = cli.Cluster
+	}
+	if la, ok := c.(LearnerAdderWithOptions); ok {
+		return la.MemberAddAsLearnerWithOptions(ctx, peerAddrs, opts...)
+	}
+	if len(opts) != 0 {
+		return nil, ErrMemberAddOptionsNotSupported
+	}
+	return c.MemberAddAsLearner(ctx, peerAddrs)
+}
+
+// WithNonPromotable adds a learner that can never be promoted to a voting
+// member, e.g. a permanent read replica. MemberPromote fails with
+// rpctypes.ErrMemberNotPromotable for such a learner.
+func WithNonPromotable() MemberAddOption {
+	return func(r *pb.MemberAddRequest) { r.NonPromotable = true }
+}
+
+// MemberRemoveOption configures a member remove operation.
+type MemberRemoveOption func(*pb.MemberRemoveRequest)
+
+// WithLeadershipTransfer transfers the leadership to a healthy voting member
+// before removing the member if it is the leader, rather than disrupting the
+// cluster with an election. The member is still removed, without a transfer,
+// if no voting member is healthy enough to take over.
+// Supported since etcd 3.6; older servers ignore it.
+func WithLeadershipTransfer() MemberRemoveOption {
+	return func(r *pb.MemberRemoveRequest) { r.LeadershipTransfer = true }
+}
+
+// WithForce removes the member without checking that the other members are
+// healthy, to remove a member whose host is gone for good. The removal is
+// still rejected if the remaining voting members would not form a quorum.
+// Supported since etcd 3.6; older servers ignore it.
+func WithForce() MemberRemoveOption {
+	return func(r *pb.MemberRemoveRequest) { r.Force = true }
+}
+
+// WithReconfigRetries returns a context that records into retries the number of
+// retries attempted by MemberAdd, MemberAddAsLearner, MemberRemove, MemberRemoveByName,
+// MemberPromote and MemberReconfigure under the client ReconfigRetryPolicy.
+func WithReconfigRetries(ctx context.Context, retries *int) context.Context {
+	return context.WithValue(ctx, reconfigRetriesKey{}, retries)
 }
 
 type cluster struct {
-	remote   pb.ClusterClient
-	callOpts []grpc.CallOption
+	remote      pb.ClusterClient
+	callOpts    []grpc.CallOption
+	retryPolicy *ReconfigRetryPolicy
 }
 
 func NewCluster(c *Client) Cluster {
 	api := &cluster{remote: RetryClusterClient(c)}
 	if c != nil {
 		api.callOpts = c.callOpts
+		api.retryPolicy = c.cfg.ReconfigRetryPolicy
 	}
 	return api
 }
@@ -69,6 +189,7 @@ func NewClusterFromClusterClient(remote pb.ClusterClient, c *Client) Cluster {
 	api := &cluster{remote: remote}
 	if c != nil {
 		api.callOpts = c.callOpts
+		api.retryPolicy = c.cfg.ReconfigRetryPolicy
 	}
 	return api
 }
@@ -81,7 +202,11 @@ func (c *cluster) MemberAddAsLearner(ctx context.Context, peerAddrs []string) (*
 	return c.memberAdd(ctx, peerAddrs, true)
 }
 
-func (c *cluster) memberAdd(ctx context.Context, peerAddrs []string, isLearner bool) (*MemberAddResponse, error) {
+func (c *cluster) MemberAddAsLearnerWithOptions(ctx context.Context, peerAddrs []string, opts ...MemberAddOption) (*MemberAddResponse, error) {
+	return c.memberAdd(ctx, peerAddrs, true, opts...)
+}
+
+func (c *cluster) memberAdd(ctx context.Context, peerAddrs []string, isLearner bool, opts ...MemberAddOption) (*MemberAddResponse, error) {
 	// fail-fast before panic in rafthttp
 	if _, err := types.NewURLs(peerAddrs); err != nil {
 		return nil, err
@@ -91,18 +216,51 @@ func (c *cluster) memberAdd(ctx context.Context, peerAddrs []string, isLearner b
 		PeerURLs:  peerAddrs,
 		IsLearner: isLearner,
 	}
-	resp, err := c.remote.MemberAdd(ctx, r, c.callOpts...)
+	for _, opt := range opts {
+		opt(r)
+	}
+	var resp *pb.MemberAddResponse
+	err := c.retryReconfig(ctx, func() (err error) {
+		resp, err = c.remote.MemberAdd(ctx, r, c.callOpts...)
+		return err
+	})
 	if err != nil {
-		return nil, toErr(ctx, err)
+		return nil, err
 	}
 	return (*MemberAddResponse)(resp), nil
 }
 
-func (c *cluster) MemberRemove(ctx context.Context, id uint64) (*MemberRemoveResponse, error) {
+func (c *cluster) MemberRemove(ctx context.Context, id uint64, opts ...MemberRemoveOption) (*MemberRemoveResponse, error) {
 	r := &pb.MemberRemoveRequest{ID: id}
-	resp, err := c.remote.MemberRemove(ctx, r, c.callOpts...)
+	for _, opt := range opts {
+		opt(r)
+	}
+	var resp *pb.MemberRemoveResponse
+	err := c.retryReconfig(ctx, func() (err error) {
+		resp, err = c.remote.MemberRemove(ctx, r, c.callOpts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return (*MemberRemoveResponse)(resp), nil
+}
+
+func (c *cluster) MemberRemoveByName(ctx context.Context, name string, opts ...MemberRemoveOption) (*MemberRemoveResponse, error) {
+	if name == "" {
+		return nil, rpctypes.ErrMemberNameNotFound
+	}
+	r := &pb.MemberRemoveRequest{Name: name}
+	for _, opt := range opts {
+		opt(r)
+	}
+	var resp *pb.MemberRemoveResponse
+	err := c.retryReconfig(ctx, func() (err error) {
+		resp, err = c.remote.MemberRemove(ctx, r, c.callOpts...)
+		return err
+	})
 	if err != nil {
-		return nil, toErr(ctx, err)
+		return nil, err
 	}
 	return (*MemberRemoveResponse)(resp), nil
 }
@@ -133,9 +291,78 @@ func (c *cluster) MemberList(ctx context.Context, opts ...OpOption) (*MemberList
 
 func (c *cluster) MemberPromote(ctx context.Context, id uint64) (*MemberPromoteResponse, error) {
 	r := &pb.MemberPromoteRequest{ID: id}
-	resp, err := c.remote.MemberPromote(ctx, r, c.callOpts...)
+	var resp *pb.MemberPromoteResponse
+	err := c.retryReconfig(ctx, func() (err error) {
+		resp, err = c.remote.MemberPromote(ctx, r, c.callOpts...)
+		return err
+	})
 	if err != nil {
-		return nil, toErr(ctx, err)
+		return nil, err
 	}
 	return (*MemberPromoteResponse)(resp), nil
 }
+
+func (c *cluster) MemberReconfigure(ctx context.Context, adds [][]string, removes []uint64) (*MemberReconfigureResponse, error) {
+	r := &pb.MemberReconfigureRequest{Remove: removes}
+	for _, peerAddrs := range adds {
+		// fail-fast before panic in rafthttp
+		if _, err := types.NewURLs(peerAddrs); err != nil {
+			return nil, err
+		}
+		r.Add = append(r.Add, &pb.MemberAddRequest{PeerURLs: peerAddrs})
+	}
+	var resp *pb.MemberReconfigureResponse
+	err := c.retryReconfig(ctx, func() (err error) {
+		resp, err = c.remote.MemberReconfigure(ctx, r, c.callOpts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return (*MemberReconfigureResponse)(resp), nil
+}
+
+// retryReconfig calls f, retrying it under the cluster ReconfigRetryPolicy while
+// the server rejects the request because the cluster has no leader. The returned
+// error is already converted with toErr.
+func (c *cluster) retryReconfig(ctx context.Context, f func() error) error {
+	err := toErr(ctx, f())
+	if err == nil || c.retryPolicy == nil {
+		return err
+	}
+
+	budget := c.retryPolicy.Budget
+	if budget == 0 {
+		budget = defaultReconfigRetryBudget
+	}
+	waitBetween := c.retryPolicy.WaitBetween
+	if waitBetween == 0 {
+		waitBetween = defaultReconfigRetryWaitBetween
+	}
+	jitter := c.retryPolicy.JitterFraction
+	if jitter == 0 {
+		jitter = defaultBackoffJitterFraction
+	}
+	retries, _ := ctx.Value(reconfigRetriesKey{}).(*int)
+
+	deadline := time.Now().Add(budget)
+	for attempt := uint(0); errors.Is(err, rpctypes.ErrNoLeader); attempt++ {
+		if c.retryPolicy.MaxRetries > 0 && attempt >= c.retryPolicy.MaxRetries {
+			return err
+		}
+		wait := jitterUp(waitBetween, jitter)
+		if time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if retries != nil {
+			*retries++
+		}
+		err = toErr(ctx, f())
+	}
+	return err
+}
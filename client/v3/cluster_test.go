@@ -0,0 +1,123 @@
+// Copyright 2024 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+type fakeReconfigClusterClient struct {
+	pb.ClusterClient
+	errs  []error
+	calls int
+}
+
+func (f *fakeReconfigClusterClient) MemberRemove(ctx context.Context, in *pb.MemberRemoveRequest, opts ...grpc.CallOption) (*pb.MemberRemoveResponse, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &pb.MemberRemoveResponse{}, nil
+}
+
+func TestReconfigRetryPolicy(t *testing.T) {
+	policy := &ReconfigRetryPolicy{WaitBetween: time.Millisecond}
+	tcs := []struct {
+		name        string
+		policy      *ReconfigRetryPolicy
+		errs        []error
+		wantErr     error
+		wantRetries int
+	}{
+		{
+			name:        "no leader is retried until success",
+			policy:      policy,
+			errs:        []error{rpctypes.ErrGRPCNoLeader, rpctypes.ErrGRPCNoLeader},
+			wantRetries: 2,
+		},
+		{
+			name:    "no leader is not retried without policy",
+			errs:    []error{rpctypes.ErrGRPCNoLeader},
+			wantErr: rpctypes.ErrNoLeader,
+		},
+		{
+			name:        "max retries bounds retries",
+			policy:      &ReconfigRetryPolicy{MaxRetries: 1, WaitBetween: time.Millisecond},
+			errs:        []error{rpctypes.ErrGRPCNoLeader, rpctypes.ErrGRPCNoLeader},
+			wantErr:     rpctypes.ErrNoLeader,
+			wantRetries: 1,
+		},
+		{
+			name:        "budget bounds retries",
+			policy:      &ReconfigRetryPolicy{Budget: time.Millisecond, WaitBetween: 10 * time.Millisecond},
+			errs:        []error{rpctypes.ErrGRPCNoLeader},
+			wantErr:     rpctypes.ErrNoLeader,
+			wantRetries: 0,
+		},
+		{
+			name:    "not enough started members is not retried",
+			policy:  policy,
+			errs:    []error{rpctypes.ErrGRPCMemberNotEnoughStarted},
+			wantErr: rpctypes.ErrMemberNotEnoughStarted,
+		},
+		{
+			name:    "unhealthy is not retried",
+			policy:  policy,
+			errs:    []error{rpctypes.ErrGRPCUnhealthy},
+			wantErr: rpctypes.ErrUnhealthy,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			remote := &fakeReconfigClusterClient{errs: tc.errs}
+			c := &cluster{remote: remote, retryPolicy: tc.policy}
+
+			var retries int
+			_, err := c.MemberRemove(WithReconfigRetries(context.Background(), &retries), 1)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if retries != tc.wantRetries {
+				t.Errorf("expected %d retries, got %d", tc.wantRetries, retries)
+			}
+			if remote.calls != tc.wantRetries+1 {
+				t.Errorf("expected %d calls, got %d", tc.wantRetries+1, remote.calls)
+			}
+		})
+	}
+}
+
+// TestMemberAddAsLearnerWithOptionsUnsupported ensures the options of a learner
+// added to a Cluster that cannot apply them are reported rather than dropped.
+func TestMemberAddAsLearnerWithOptionsUnsupported(t *testing.T) {
+	c := &mockCluster{}
+	if _, err := MemberAddAsLearnerWithOptions(context.TODO(), c, []string{"http://127.0.0.1:2380"}); err != nil {
+		t.Fatalf("expected no error without options, got %v", err)
+	}
+	_, err := MemberAddAsLearnerWithOptions(context.TODO(), c, []string{"http://127.0.0.1:2380"}, WithNonPromotable())
+	if !errors.Is(err, ErrMemberAddOptionsNotSupported) {
+		t.Fatalf("expected %v, got %v", ErrMemberAddOptionsNotSupported, err)
+	}
+}
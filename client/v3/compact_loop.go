@@ -0,0 +1,94 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// ErrInvalidRetainRevisions is sent by CompactLoop if it is asked to retain
+// no revision.
+var ErrInvalidRetainRevisions = errors.New("etcdclient: number of revisions to retain must be positive")
+
+// CompactLoop compacts the keyspace every interval, keeping the last
+// retainRevisions revisions: it reads the current revision and physically
+// compacts everything older than the current revision minus retainRevisions.
+// The first compaction happens right away.
+//
+// The loop runs until the context is done, and then closes the returned
+// channel. Failed compactions are logged and sent on the channel; an error
+// is dropped rather than sent if the previous one was not received yet, so
+// that a caller not draining the channel does not stall the loop. A target
+// revision that was already compacted, by this loop or anyone else, is not an
+// error. If retainRevisions is not positive, the loop does not start and the
+// channel only carries ErrInvalidRetainRevisions.
+func CompactLoop(ctx context.Context, c *Client, retainRevisions int64, interval time.Duration) <-chan error {
+	errc := make(chan error, 1)
+	if retainRevisions <= 0 {
+		errc <- ErrInvalidRetainRevisions
+		close(errc)
+		return errc
+	}
+	go func() {
+		defer close(errc)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var compacted int64
+		for {
+			rev, err := compactOnce(ctx, c, retainRevisions, compacted)
+			if err != nil && ctx.Err() == nil {
+				c.lg.Warn("compaction failed", zap.Int64("retain-revisions", retainRevisions), zap.Error(err))
+				select {
+				case errc <- err:
+				default:
+				}
+			}
+			if rev > compacted {
+				compacted = rev
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return errc
+}
+
+// compactOnce compacts the revisions older than the current revision minus
+// retainRevisions, unless they were compacted already, and returns the
+// revision compacted at.
+func compactOnce(ctx context.Context, c *Client, retainRevisions, compacted int64) (int64, error) {
+	resp, err := c.Get(ctx, "\x00", WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	target := resp.Header.Revision - retainRevisions
+	if target <= compacted {
+		return 0, nil
+	}
+	_, err = c.Compact(ctx, target, WithCompactPhysical())
+	if err != nil && err != rpctypes.ErrCompacted {
+		return 0, err
+	}
+	return target, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrLockUpgrade is returned when a session locks a RWMutex for writing while
+// it holds it for reading, or the reverse. Waiting would deadlock on the key
+// of the session itself, so the lock has to be released first.
+var ErrLockUpgrade = errors.New("rwmutex: lock is held by the session in the other mode")
+
+// RWMutex is a reader/writer mutual exclusion lock with etcd. It can be held
+// by any number of readers or by a single writer.
+//
+// Readers register a key under the "read/" prefix of the lock, writers under
+// its "write/" prefix, and they are served in the order of the creation of
+// their keys: a writer waits for all the readers and writers registered before
+// it, and a reader for the writers registered before it. A waiting writer
+// thus blocks the readers registering after it, so that it is not starved by
+// a steady flow of readers. The keys are attached to the lease of the
+// session, so the lock held by a session is released when it expires.
+//
+// Like Mutex, the RWMutexes of a session on the same prefix share their keys.
+// A session cannot upgrade its read lock to a write lock, nor downgrade the
+// reverse, in place: RLock and Lock return ErrLockUpgrade instead.
+type RWMutex struct {
+	s *Session
+
+	pfx   string
+	myKey string
+	myRev int64
+	hdr   *pb.ResponseHeader
+}
+
+func NewRWMutex(s *Session, pfx string) *RWMutex {
+	return &RWMutex{s: s, pfx: pfx + "/", myRev: -1}
+}
+
+// RLock locks rwm for reading with a cancelable context. If the context is
+// canceled while waiting for the writers, the key of the reader is deleted.
+func (rwm *RWMutex) RLock(ctx context.Context) error {
+	return rwm.lock(ctx, rwm.pfx+"read/", rwm.pfx+"write/", rwm.pfx+"write/")
+}
+
+// Lock locks rwm for writing with a cancelable context. If the context is
+// canceled while waiting for the readers and writers, the key of the writer
+// is deleted.
+func (rwm *RWMutex) Lock(ctx context.Context) error {
+	return rwm.lock(ctx, rwm.pfx+"write/", rwm.pfx, rwm.pfx+"read/")
+}
+
+// lock registers the key of the session under kpfx, then waits until the keys
+// under waitPfx created before it are deleted. It fails with ErrLockUpgrade
+// if the session holds a key under otherPfx.
+func (rwm *RWMutex) lock(ctx context.Context, kpfx, waitPfx, otherPfx string) error {
+	s := rwm.s
+	client := s.Client()
+
+	myKey := fmt.Sprintf("%s%x", kpfx, s.Lease())
+	otherKey := fmt.Sprintf("%s%x", otherPfx, s.Lease())
+	cmp := v3.Compare(v3.CreateRevision(myKey), "=", 0)
+	cmpOther := v3.Compare(v3.CreateRevision(otherKey), "=", 0)
+	put := v3.OpPut(myKey, "", v3.WithLease(s.Lease()))
+	// reuse key in case this session already holds the lock
+	get := v3.OpGet(myKey)
+	// fetch the oldest key to wait on to complete the uncontended path with
+	// only one RPC
+	getFirst := v3.OpGet(waitPfx, v3.WithFirstCreate()...)
+	getOther := v3.OpGet(otherKey)
+	resp, err := client.Txn(ctx).If(cmp, cmpOther).Then(put, getFirst).Else(get, getFirst, getOther).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded && len(resp.Responses[2].GetResponseRange().Kvs) != 0 {
+		return ErrLockUpgrade
+	}
+	rwm.myKey = myKey
+	rwm.myRev = resp.Header.Revision
+	if !resp.Succeeded {
+		rwm.myRev = resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision
+	}
+	firstKey := resp.Responses[1].GetResponseRange().Kvs
+	if len(firstKey) == 0 || firstKey[0].CreateRevision >= rwm.myRev {
+		rwm.hdr = resp.Header
+		return nil
+	}
+
+	// wait for deletion revisions prior to myKey
+	_, werr := waitDeletes(ctx, client, waitPfx, rwm.myRev-1)
+	// release lock key if wait failed
+	if werr != nil {
+		rwm.Unlock(client.Ctx())
+		return werr
+	}
+
+	// make sure the session is not expired, and the key still exists.
+	gresp, werr := client.Get(ctx, rwm.myKey)
+	if werr != nil {
+		rwm.Unlock(client.Ctx())
+		return werr
+	}
+	if len(gresp.Kvs) == 0 { // is the session key lost?
+		return ErrSessionExpired
+	}
+	rwm.hdr = gresp.Header
+	return nil
+}
+
+// RUnlock unlocks rwm locked for reading.
+func (rwm *RWMutex) RUnlock(ctx context.Context) error { return rwm.Unlock(ctx) }
+
+// Unlock unlocks rwm locked for writing.
+func (rwm *RWMutex) Unlock(ctx context.Context) error {
+	if rwm.myKey == "" || rwm.myRev <= 0 || rwm.myKey == "\x00" {
+		return ErrLockReleased
+	}
+
+	if !strings.HasPrefix(rwm.myKey, rwm.pfx) {
+		return fmt.Errorf("invalid key %q, it should have prefix %q", rwm.myKey, rwm.pfx)
+	}
+
+	client := rwm.s.Client()
+	if _, err := client.Delete(ctx, rwm.myKey); err != nil {
+		return err
+	}
+	rwm.myKey = "\x00"
+	rwm.myRev = -1
+	return nil
+}
+
+// IsOwner returns a comparison that holds while rwm is locked by its session,
+// for reading or for writing.
+func (rwm *RWMutex) IsOwner() v3.Cmp {
+	return v3.Compare(v3.CreateRevision(rwm.myKey), "=", rwm.myRev)
+}
+
+func (rwm *RWMutex) Key() string { return rwm.myKey }
+
+// Header is the response header received from etcd on acquiring the lock.
+func (rwm *RWMutex) Header() *pb.ResponseHeader { return rwm.hdr }
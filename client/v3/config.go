@@ -57,6 +57,12 @@ type Config struct {
 	// ("--max-recv-bytes" flag to etcd).
 	MaxCallRecvMsgSize int
 
+	// MaxTxnOps is the maximum number of operations the server allows in a
+	// transaction ("--max-txn-ops" flag to etcd). Transactions nested deeper
+	// than it are rejected by the client without being sent.
+	// If 0, it defaults to 128, the default of the server.
+	MaxTxnOps int `json:"max-txn-ops"`
+
 	// TLS holds the client secure credentials, if any.
 	TLS *tls.Config
 
@@ -90,6 +96,38 @@ type Config struct {
 	// PermitWithoutStream when set will allow client to send keepalive pings to server without any active streams(RPCs).
 	PermitWithoutStream bool `json:"permit-without-stream"`
 
+	// ReconfigRetryPolicy governs automatic retries of MemberAdd, MemberAddAsLearner,
+	// MemberRemove and MemberPromote rejected because the cluster has no leader.
+	// If nil, membership reconfiguration requests are not retried on that error.
+	ReconfigRetryPolicy *ReconfigRetryPolicy
+
+	// ReadLoadBalancing when set routes serializable range requests to the
+	// endpoints of followers, to offload the leader. The leader is looked up
+	// periodically in the member list and the status of the endpoints.
+	// Serializable requests are routed to any endpoint while the leader is
+	// unknown or if all endpoints belong to it.
+	ReadLoadBalancing bool `json:"read-load-balancing"`
+
+	// EndpointHealthScoring when set balances the requests over the endpoints
+	// by health scores decaying over time, instead of round robin. Endpoints
+	// failing requests or answering slower than the others get proportionally
+	// fewer requests, and are probed periodically until they recover.
+	// The scores are reported by Client.EndpointScores.
+	EndpointHealthScoring bool `json:"endpoint-health-scoring"`
+
+	// FailFast when set makes the requests fail right away with an
+	// *UnavailableError if no connection to any endpoint can be established,
+	// instead of waiting for one until their context is done. A request made
+	// while the first connection attempts are still pending waits for their
+	// outcome. Watches and lease keep-alives keep waiting for a connection.
+	FailFast bool `json:"fail-fast"`
+
+	// RequireLeader when set requires a leader for all the streaming calls,
+	// as WithRequireLeader does: watches fail with rpctypes.ErrNoLeader and
+	// lease keep-alive channels are closed once their member loses its
+	// leader. Unary requests, serializable ones included, are not affected.
+	RequireLeader bool `json:"require-leader"`
+
 	// TODO: support custom balancer picker
 }
 
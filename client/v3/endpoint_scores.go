@@ -0,0 +1,45 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import "time"
+
+// EndpointScore is the health score of an endpoint balanced with
+// EndpointHealthScoring.
+type EndpointScore struct {
+	// ErrorRate is the decaying average of the rate of requests failed by
+	// the endpoint because it was unavailable, overloaded or timed out,
+	// between 0 and 1.
+	ErrorRate float64
+	// Latency is the decaying average of the latency of the unary requests
+	// served by the endpoint.
+	Latency time.Duration
+	// Weight is the share of requests the endpoint gets relative to a
+	// healthy endpoint, between 0.05 and 1.
+	Weight float64
+}
+
+// EndpointScores returns the health scores of the endpoints that served
+// requests, for debugging. It returns nil unless EndpointHealthScoring is set.
+func (c *Client) EndpointScores() map[string]EndpointScore {
+	if c.scores == nil {
+		return nil
+	}
+	scores := make(map[string]EndpointScore)
+	for ep, s := range c.scores.Scores() {
+		scores[ep] = EndpointScore{ErrorRate: s.ErrorRate, Latency: s.Latency, Weight: s.Weight}
+	}
+	return scores
+}
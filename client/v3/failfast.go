@@ -0,0 +1,58 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// UnavailableError is returned by the requests of a client configured with
+// FailFast if no connection to any of its endpoints can be established. It
+// wraps the error of the connection attempts, and has the gRPC status code
+// codes.Unavailable.
+type UnavailableError struct {
+	Endpoints []string
+	Err       error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("etcdclient: no endpoint is reachable [%s]: %v", strings.Join(e.Endpoints, ", "), e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error { return e.Err }
+
+func (e *UnavailableError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+// failFastError returns an *UnavailableError if err is the failure of a
+// request that could not be sent because cc is not connected to any endpoint,
+// and nil otherwise. An unavailable error sent by a connected endpoint, for
+// example while it has no leader, is left to the retries.
+func (c *Client) failFastError(cc *grpc.ClientConn, err error) error {
+	if !c.cfg.FailFast || status.Code(err) != codes.Unavailable {
+		return nil
+	}
+	if cc.GetState() == connectivity.Ready {
+		return nil
+	}
+	return &UnavailableError{Endpoints: c.Endpoints(), Err: err}
+}
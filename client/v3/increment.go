@@ -0,0 +1,104 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrIncrementContended is returned by Increment if the key was modified
+// concurrently by every one of its attempts.
+var ErrIncrementContended = errors.New("etcdclient: too many concurrent modifications of incremented key")
+
+// incrementMaxAttempts is the number of transactions Increment tries before
+// giving up.
+const incrementMaxAttempts = 100
+
+// incrementMinBackoff and incrementMaxBackoff bound the delay between the
+// attempts of Increment, doubled after every failed attempt and jittered so
+// that concurrent increments of a key spread out.
+const (
+	incrementMinBackoff = time.Millisecond
+	incrementMaxBackoff = 100 * time.Millisecond
+)
+
+// NotANumberError is returned by Increment if the value of the key is not a
+// decimal int64.
+type NotANumberError struct {
+	Key   string
+	Value string
+}
+
+func (e *NotANumberError) Error() string {
+	return fmt.Sprintf("etcdclient: value %q of key %q is not a number", e.Value, e.Key)
+}
+
+// Increment adds delta to the value of the key, a decimal int64, and returns
+// the new value. A key that does not exist counts as 0 and is created. The
+// value is put in a transaction guarded by the modification revision of the
+// key, which is retried with the value read by the failed transaction while
+// the key is modified concurrently, so that no increment is lost. Failed
+// attempts are retried after a jittered exponential backoff. After too many
+// attempts, Increment returns ErrIncrementContended. The key keeps its lease.
+func Increment(ctx context.Context, kv KV, key string, delta int64) (int64, error) {
+	resp, err := kv.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	kvs := resp.Kvs
+	backoff := incrementMinBackoff
+	for i := 0; i < incrementMaxAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(jitterUp(backoff, defaultBackoffJitterFraction)):
+			}
+			if backoff *= 2; backoff > incrementMaxBackoff {
+				backoff = incrementMaxBackoff
+			}
+		}
+		var cur, modRev int64
+		if len(kvs) != 0 {
+			if cur, err = strconv.ParseInt(string(kvs[0].Value), 10, 64); err != nil {
+				return 0, &NotANumberError{Key: key, Value: string(kvs[0].Value)}
+			}
+			modRev = kvs[0].ModRevision
+		}
+		next := cur + delta
+		if (delta > 0 && next < cur) || (delta < 0 && next > cur) {
+			return 0, fmt.Errorf("etcdclient: incrementing %d of key %q by %d overflows", cur, key, delta)
+		}
+
+		var opts []OpOption
+		if modRev != 0 {
+			opts = append(opts, WithIgnoreLease())
+		}
+		put := OpPut(key, strconv.FormatInt(next, 10), opts...)
+		tresp, err := kv.Txn(ctx).If(Compare(ModRevision(key), "=", modRev)).Then(put).Else(OpGet(key)).Commit()
+		if err != nil {
+			return 0, err
+		}
+		if tresp.Succeeded {
+			return next, nil
+		}
+		kvs = tresp.Responses[0].GetResponseRange().Kvs
+	}
+	return 0, ErrIncrementContended
+}
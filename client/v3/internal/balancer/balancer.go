@@ -0,0 +1,126 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package balancer implements a gRPC balancer that deprioritizes the
+// endpoints by the health scores they earn from the requests sent to them.
+package balancer
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+// Name is the load balancing policy of the endpoints balanced by their
+// health scores.
+const Name = "etcd_health_scored"
+
+// streamingMethods do not report a meaningful latency since they complete
+// when the stream is closed.
+var streamingMethods = map[string]bool{
+	"/etcdserverpb.KV/TxnStream":         true,
+	"/etcdserverpb.Watch/Watch":          true,
+	"/etcdserverpb.Lease/LeaseKeepAlive": true,
+	"/etcdserverpb.Maintenance/Snapshot": true,
+}
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(Name, &pickerBuilder{}, base.Config{HealthCheck: false}))
+}
+
+type addressKey struct{}
+
+type addressScores struct {
+	scores   *Scores
+	endpoint string
+}
+
+// Address returns addr balanced by the health scores of s as endpoint.
+func (s *Scores) Address(addr resolver.Address, endpoint string) resolver.Address {
+	addr.BalancerAttributes = addr.BalancerAttributes.WithValue(addressKey{}, addressScores{scores: s, endpoint: endpoint})
+	return addr
+}
+
+type pickerBuilder struct{}
+
+func (*pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &picker{}
+	for sc, scInfo := range info.ReadySCs {
+		as, ok := scInfo.Address.BalancerAttributes.Value(addressKey{}).(addressScores)
+		if !ok {
+			continue
+		}
+		p.scores = as.scores
+		p.subConns = append(p.subConns, sc)
+		p.endpoints = append(p.endpoints, as.endpoint)
+	}
+	if p.scores == nil {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p.current = make([]float64, len(p.subConns))
+	return p
+}
+
+// picker spreads the requests over the endpoints by smooth weighted
+// round-robin, weighting the endpoints by their health scores.
+type picker struct {
+	scores    *Scores
+	subConns  []balancer.SubConn
+	endpoints []string
+
+	mu      sync.Mutex
+	current []float64
+}
+
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	start := p.scores.now()
+	p.mu.Lock()
+	i := p.next(start)
+	p.mu.Unlock()
+
+	ep := p.endpoints[i]
+	trackLatency := !streamingMethods[info.FullMethodName]
+	done := func(di balancer.DoneInfo) {
+		var latency time.Duration
+		if trackLatency {
+			latency = p.scores.now().Sub(start)
+		}
+		p.scores.observe(ep, di.Err, latency)
+	}
+	return balancer.PickResult{SubConn: p.subConns[i], Done: done}, nil
+}
+
+func (p *picker) next(now time.Time) int {
+	weights, probe := p.scores.weights(p.endpoints, now)
+	i := probe
+	if i < 0 {
+		var total float64
+		for j, w := range weights {
+			p.current[j] += w
+			total += w
+			if i < 0 || p.current[j] > p.current[i] {
+				i = j
+			}
+		}
+		p.current[i] -= total
+	}
+	p.scores.picked(p.endpoints[i], now)
+	return i
+}
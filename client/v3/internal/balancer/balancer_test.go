@@ -0,0 +1,131 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balancer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+type fakeSubConn struct {
+	balancer.SubConn
+	endpoint string
+}
+
+func newTestPicker(t *testing.T, s *Scores, endpoints ...string) balancer.Picker {
+	info := base.PickerBuildInfo{ReadySCs: make(map[balancer.SubConn]base.SubConnInfo)}
+	for _, ep := range endpoints {
+		info.ReadySCs[&fakeSubConn{endpoint: ep}] = base.SubConnInfo{Address: s.Address(resolver.Address{Addr: ep}, ep)}
+	}
+	p := (&pickerBuilder{}).Build(info)
+	require.IsType(t, &picker{}, p)
+	return p
+}
+
+func TestPickerDeprioritizesUnhealthyEndpoint(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewScores()
+	s.now = func() time.Time { return now }
+	p := newTestPicker(t, s, "a", "b", "c")
+
+	// c fails half of the requests and is ten times slower than a and b
+	picks := make(map[string]int)
+	var lastC time.Time
+	for i := 0; i < 3000; i++ {
+		res, err := p.Pick(balancer.PickInfo{FullMethodName: "/etcdserverpb.KV/Range"})
+		require.NoError(t, err)
+		ep := res.SubConn.(*fakeSubConn).endpoint
+		picks[ep]++
+
+		var err2 error
+		latency := time.Millisecond
+		if ep == "c" {
+			latency = 10 * time.Millisecond
+			if picks[ep]%2 == 0 {
+				err2 = status.Error(codes.Unavailable, "unavailable")
+			}
+			if !lastC.IsZero() {
+				assert.LessOrEqual(t, now.Sub(lastC), probeInterval+10*time.Millisecond, "c must be probed")
+			}
+			lastC = now
+		}
+		now = now.Add(latency)
+		res.Done(balancer.DoneInfo{Err: err2})
+	}
+
+	assert.Greater(t, picks["c"], 0, "c must still be probed")
+	assert.Less(t, picks["c"], 3000/20, "c must get far fewer requests, got %v", picks)
+	assert.InDelta(t, picks["a"], picks["b"], 3000/100)
+
+	scores := s.Scores()
+	assert.Greater(t, scores["c"].ErrorRate, 0.2)
+	assert.Equal(t, 10*time.Millisecond, scores["c"].Latency.Round(time.Millisecond))
+	assert.Less(t, scores["c"].Weight, 0.1)
+	assert.Equal(t, 1.0, scores["a"].Weight)
+
+	// the scores decay, so that c is not deprioritized forever
+	now = now.Add(10 * scoreHalfLife)
+	scores = s.Scores()
+	assert.Less(t, scores["c"].ErrorRate, 0.001)
+	assert.Greater(t, scores["c"].Weight, 0.99)
+}
+
+func TestPickerIgnoresRequestErrors(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewScores()
+	s.now = func() time.Time { return now }
+	p := newTestPicker(t, s, "a", "b")
+
+	picks := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		res, err := p.Pick(balancer.PickInfo{FullMethodName: "/etcdserverpb.Watch/Watch"})
+		require.NoError(t, err)
+		ep := res.SubConn.(*fakeSubConn).endpoint
+		picks[ep]++
+		// streams of b last long and are canceled by the client, and
+		// requests to a are rejected by the server
+		var err2 error
+		if ep == "a" {
+			err2 = status.Error(codes.FailedPrecondition, "compacted")
+		} else {
+			now = now.Add(time.Hour)
+			err2 = status.Error(codes.Canceled, "canceled")
+		}
+		res.Done(balancer.DoneInfo{Err: err2})
+	}
+	assert.Equal(t, 500, picks["a"])
+	assert.Equal(t, 500, picks["b"])
+	for ep, score := range s.Scores() {
+		assert.Equal(t, Score{Weight: 1}, score, "endpoint %s", ep)
+	}
+}
+
+func TestScoresRetain(t *testing.T) {
+	s := NewScores()
+	s.observe("a", nil, time.Millisecond)
+	s.observe("b", nil, time.Millisecond)
+	s.Retain([]string{"b", "c"})
+	scores := s.Scores()
+	assert.Len(t, scores, 1)
+	assert.Contains(t, scores, "b")
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balancer
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// scoreHalfLife is the time it takes the penalty of an endpoint to halve
+	// without new requests, so that no endpoint is deprioritized forever.
+	scoreHalfLife = 10 * time.Second
+	// probeInterval is the longest an endpoint goes without being picked.
+	probeInterval = time.Second
+)
+
+const (
+	// observationWeight is the weight of a request in the moving averages of
+	// the error rate and latency of its endpoint.
+	observationWeight = 0.1
+	// minWeight is the lowest weight of an endpoint, relative to a healthy
+	// endpoint of weight 1.
+	minWeight = 0.05
+)
+
+// Score is the health score of an endpoint.
+type Score struct {
+	// ErrorRate is the moving average of the rate of requests failed by the
+	// endpoint, between 0 and 1.
+	ErrorRate float64
+	// Latency is the moving average of the latency of the requests served
+	// by the endpoint.
+	Latency time.Duration
+	// Weight is the share of requests the endpoint gets relative to a
+	// healthy endpoint, between minWeight and 1.
+	Weight float64
+}
+
+type score struct {
+	errRate float64
+	latency time.Duration
+	// updated is when errRate and latency were last updated
+	updated    time.Time
+	lastPicked time.Time
+}
+
+// Scores tracks the health scores of the endpoints of a client.
+type Scores struct {
+	mu     sync.Mutex
+	scores map[string]*score
+
+	now func() time.Time
+}
+
+func NewScores() *Scores {
+	return &Scores{scores: make(map[string]*score), now: time.Now}
+}
+
+// Retain forgets the scores of the endpoints not in endpoints.
+func (s *Scores) Retain(endpoints []string) {
+	keep := make(map[string]struct{}, len(endpoints))
+	for _, ep := range endpoints {
+		keep[ep] = struct{}{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ep := range s.scores {
+		if _, ok := keep[ep]; !ok {
+			delete(s.scores, ep)
+		}
+	}
+}
+
+// Scores returns the current health scores of the endpoints.
+func (s *Scores) Scores() map[string]Score {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	best := s.bestLatency()
+	scores := make(map[string]Score, len(s.scores))
+	for ep, sc := range s.scores {
+		errRate, latency := sc.decayed(now, best)
+		scores[ep] = Score{ErrorRate: errRate, Latency: latency, Weight: weight(errRate, latency, best)}
+	}
+	return scores
+}
+
+// failed returns true if err hints that the endpoint is unhealthy, rather
+// than the request being invalid or canceled by the client.
+func failed(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// observe updates the score of the endpoint with the outcome of a request. A
+// zero latency is not taken into account.
+func (s *Scores) observe(ep string, err error, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc := s.get(ep)
+	now := s.now()
+	sc.errRate, sc.latency = sc.decayed(now, s.bestLatency())
+	sc.updated = now
+
+	var x float64
+	if failed(err) {
+		x = 1
+	}
+	sc.errRate += observationWeight * (x - sc.errRate)
+	if latency > 0 {
+		if sc.latency == 0 {
+			sc.latency = latency
+		} else {
+			sc.latency += time.Duration(observationWeight * float64(latency-sc.latency))
+		}
+	}
+}
+
+func (s *Scores) picked(ep string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(ep).lastPicked = now
+}
+
+// weights returns the weights of the endpoints, and the index of an endpoint
+// not picked for probeInterval, if any, or -1.
+func (s *Scores) weights(endpoints []string, now time.Time) ([]float64, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	best := s.bestLatency()
+	probe := -1
+	weights := make([]float64, len(endpoints))
+	for i, ep := range endpoints {
+		sc := s.get(ep)
+		if sc.lastPicked.IsZero() {
+			// endpoints are picked by weight from their first pick on
+			sc.lastPicked = now
+		}
+		errRate, latency := sc.decayed(now, best)
+		weights[i] = weight(errRate, latency, best)
+		if probe < 0 && weights[i] < 1 && now.Sub(sc.lastPicked) >= probeInterval {
+			probe = i
+		}
+	}
+	return weights, probe
+}
+
+func (s *Scores) get(ep string) *score {
+	sc, ok := s.scores[ep]
+	if !ok {
+		sc = &score{}
+		s.scores[ep] = sc
+	}
+	return sc
+}
+
+// bestLatency returns the lowest latency of the endpoints, or 0 if none is
+// known.
+func (s *Scores) bestLatency() time.Duration {
+	var best time.Duration
+	for _, sc := range s.scores {
+		if sc.latency > 0 && (best == 0 || sc.latency < best) {
+			best = sc.latency
+		}
+	}
+	return best
+}
+
+// decayed returns the error rate and latency of the endpoint decayed towards
+// a healthy endpoint over the time elapsed since they were updated.
+func (sc *score) decayed(now time.Time, best time.Duration) (float64, time.Duration) {
+	if sc.updated.IsZero() {
+		return sc.errRate, sc.latency
+	}
+	d := math.Pow(0.5, float64(now.Sub(sc.updated))/float64(scoreHalfLife))
+	latency := sc.latency
+	if best > 0 && latency > best {
+		latency = best + time.Duration(d*float64(latency-best))
+	}
+	return sc.errRate * d, latency
+}
+
+func weight(errRate float64, latency, best time.Duration) float64 {
+	w := (1 - errRate) * (1 - errRate)
+	if best > 0 && latency > best {
+		w *= float64(best) / float64(latency)
+	}
+	return math.Max(w, minWeight)
+}
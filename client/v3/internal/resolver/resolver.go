@@ -15,10 +15,13 @@
 package resolver
 
 import (
+	"fmt"
+
 	"google.golang.org/grpc/resolver"
 	"google.golang.org/grpc/resolver/manual"
 	"google.golang.org/grpc/serviceconfig"
 
+	"go.etcd.io/etcd/client/v3/internal/balancer"
 	"go.etcd.io/etcd/client/v3/internal/endpoint"
 )
 
@@ -32,6 +35,9 @@ type EtcdManualResolver struct {
 	*manual.Resolver
 	endpoints     []string
 	serviceConfig *serviceconfig.ParseResult
+	// scores balances the endpoints by their health if set, instead of
+	// round robin.
+	scores *balancer.Scores
 }
 
 func New(endpoints ...string) *EtcdManualResolver {
@@ -39,9 +45,21 @@ func New(endpoints ...string) *EtcdManualResolver {
 	return &EtcdManualResolver{Resolver: r, endpoints: endpoints, serviceConfig: nil}
 }
 
+// NewScored returns a resolver whose endpoints are balanced by the health
+// scores tracked in scores.
+func NewScored(scores *balancer.Scores, endpoints ...string) *EtcdManualResolver {
+	r := New(endpoints...)
+	r.scores = scores
+	return r
+}
+
 // Build returns itself for Resolver, because it's both a builder and a resolver.
 func (r *EtcdManualResolver) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
-	r.serviceConfig = cc.ParseServiceConfig(`{"loadBalancingPolicy": "round_robin"}`)
+	policy := "round_robin"
+	if r.scores != nil {
+		policy = balancer.Name
+	}
+	r.serviceConfig = cc.ParseServiceConfig(fmt.Sprintf(`{"loadBalancingPolicy": %q}`, policy))
 	if r.serviceConfig.Err != nil {
 		return nil, r.serviceConfig.Err
 	}
@@ -56,6 +74,9 @@ func (r *EtcdManualResolver) Build(target resolver.Target, cc resolver.ClientCon
 
 func (r *EtcdManualResolver) SetEndpoints(endpoints []string) {
 	r.endpoints = endpoints
+	if r.scores != nil {
+		r.scores.Retain(endpoints)
+	}
 	r.updateState()
 }
 
@@ -65,6 +86,9 @@ func (r EtcdManualResolver) updateState() {
 		for i, ep := range r.endpoints {
 			addr, serverName := endpoint.Interpret(ep)
 			addresses[i] = resolver.Address{Addr: addr, ServerName: serverName}
+			if r.scores != nil {
+				addresses[i] = r.scores.Address(addresses[i], ep)
+			}
 		}
 		state := resolver.State{
 			Addresses:     addresses,
@@ -29,6 +29,8 @@ type (
 	GetResponse     pb.RangeResponse
 	DeleteResponse  pb.DeleteRangeResponse
 	TxnResponse     pb.TxnResponse
+
+	CompactKeyResponse pb.CompactKeyResponse
 )
 
 type KV interface {
@@ -70,12 +72,15 @@ type OpResponse struct {
 	get *GetResponse
 	del *DeleteResponse
 	txn *TxnResponse
+
+	compactKey *CompactKeyResponse
 }
 
-func (op OpResponse) Put() *PutResponse    { return op.put }
-func (op OpResponse) Get() *GetResponse    { return op.get }
-func (op OpResponse) Del() *DeleteResponse { return op.del }
-func (op OpResponse) Txn() *TxnResponse    { return op.txn }
+func (op OpResponse) Put() *PutResponse               { return op.put }
+func (op OpResponse) Get() *GetResponse               { return op.get }
+func (op OpResponse) Del() *DeleteResponse            { return op.del }
+func (op OpResponse) Txn() *TxnResponse               { return op.txn }
+func (op OpResponse) CompactKey() *CompactKeyResponse { return op.compactKey }
 
 func (resp *PutResponse) OpResponse() OpResponse {
 	return OpResponse{put: resp}
@@ -90,15 +95,48 @@ func (resp *TxnResponse) OpResponse() OpResponse {
 	return OpResponse{txn: resp}
 }
 
+// OpResponses returns the responses of the operations executed by the
+// transaction, in order. Responses of nested transactions are exposed
+// through OpResponse.Txn.
+func (resp *TxnResponse) OpResponses() []OpResponse {
+	ors := make([]OpResponse, 0, len(resp.Responses))
+	for _, r := range resp.Responses {
+		switch tv := r.Response.(type) {
+		case *pb.ResponseOp_ResponseRange:
+			ors = append(ors, OpResponse{get: (*GetResponse)(tv.ResponseRange)})
+		case *pb.ResponseOp_ResponsePut:
+			ors = append(ors, OpResponse{put: (*PutResponse)(tv.ResponsePut)})
+		case *pb.ResponseOp_ResponseDeleteRange:
+			ors = append(ors, OpResponse{del: (*DeleteResponse)(tv.ResponseDeleteRange)})
+		case *pb.ResponseOp_ResponseTxn:
+			ors = append(ors, OpResponse{txn: (*TxnResponse)(tv.ResponseTxn)})
+		default:
+			ors = append(ors, OpResponse{})
+		}
+	}
+	return ors
+}
+
 type kv struct {
-	remote   pb.KVClient
-	callOpts []grpc.CallOption
+	remote pb.KVClient
+	// readRemote serves the serializable range requests if set.
+	readRemote pb.KVClient
+	callOpts   []grpc.CallOption
+	// client checks the endpoints support dry run txns if set.
+	client *Client
+	// maxTxnOps is the server's --max-txn-ops, bounding the nesting depth of
+	// transactions. The default is used if unset.
+	maxTxnOps int
 }
 
 func NewKV(c *Client) KV {
-	api := &kv{remote: RetryKVClient(c)}
+	api := &kv{remote: RetryKVClient(c), client: c}
 	if c != nil {
 		api.callOpts = c.callOpts
+		api.maxTxnOps = c.cfg.MaxTxnOps
+		if c.readConn != nil {
+			api.readRemote = &retryKVClient{kc: pb.NewKVClient(c.readConn)}
+		}
 	}
 	return api
 }
@@ -107,15 +145,31 @@ func NewKVFromKVClient(remote pb.KVClient, c *Client) KV {
 	api := &kv{remote: remote}
 	if c != nil {
 		api.callOpts = c.callOpts
+		api.maxTxnOps = c.cfg.MaxTxnOps
 	}
 	return api
 }
 
+// maxTxnDepth returns the maximum nesting depth of the transactions passed into
+// Then or Else through OpTxn.
+func (kv *kv) maxTxnDepth() int {
+	if kv.maxTxnOps > 0 {
+		return kv.maxTxnOps
+	}
+	return defaultMaxTxnOps
+}
+
 func (kv *kv) Put(ctx context.Context, key, val string, opts ...OpOption) (*PutResponse, error) {
 	r, err := kv.Do(ctx, OpPut(key, val, opts...))
 	return r.put, toErr(ctx, err)
 }
 
+// PutWithTTL puts a key-value pair which expires after ttl seconds, without
+// managing a lease. See WithTTL.
+func (c *Client) PutWithTTL(ctx context.Context, key, val string, ttl int64, opts ...OpOption) (*PutResponse, error) {
+	return c.Put(ctx, key, val, append(opts, WithTTL(ttl))...)
+}
+
 func (kv *kv) Get(ctx context.Context, key string, opts ...OpOption) (*GetResponse, error) {
 	r, err := kv.Do(ctx, OpGet(key, opts...))
 	return r.get, toErr(ctx, err)
@@ -147,8 +201,16 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 	switch op.t {
 	case tRange:
 		if op.IsSortOptionValid() {
+			remote := kv.remote
+			if op.serializable && kv.readRemote != nil {
+				remote = kv.readRemote
+			}
 			var resp *pb.RangeResponse
-			resp, err = kv.remote.Range(ctx, op.toRangeRequest(), kv.callOpts...)
+			if s := sessionFromContext(ctx); s != nil && op.serializable && op.rev == 0 {
+				resp, err = kv.sessionRange(ctx, s, remote, op.toRangeRequest())
+			} else {
+				resp, err = remote.Range(ctx, op.toRangeRequest(), kv.callOpts...)
+			}
 			if err == nil {
 				return OpResponse{get: (*GetResponse)(resp)}, nil
 			}
@@ -157,9 +219,10 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 		}
 	case tPut:
 		var resp *pb.PutResponse
-		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease}
+		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease, LeaseTtl: op.leaseTTLInResponse, Ttl: op.ttl, CopyFrom: op.copyFrom, CopyLease: op.copyLease}
 		resp, err = kv.remote.Put(ctx, r, kv.callOpts...)
 		if err == nil {
+			sessionFromContext(ctx).observe(resp.Header)
 			return OpResponse{put: (*PutResponse)(resp)}, nil
 		}
 	case tDeleteRange:
@@ -167,14 +230,39 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 		r := &pb.DeleteRangeRequest{Key: op.key, RangeEnd: op.end, PrevKv: op.prevKV}
 		resp, err = kv.remote.DeleteRange(ctx, r, kv.callOpts...)
 		if err == nil {
+			sessionFromContext(ctx).observe(resp.Header)
 			return OpResponse{del: (*DeleteResponse)(resp)}, nil
 		}
 	case tTxn:
+		if err = checkTxnOps([]Op{op}); err != nil {
+			break
+		}
+		r := op.toTxnRequest()
+		if err = checkTxnRequest(r, 1, kv.maxTxnDepth()); err != nil {
+			break
+		}
 		var resp *pb.TxnResponse
-		resp, err = kv.remote.Txn(ctx, op.toTxnRequest(), kv.callOpts...)
+		resp, err = kv.remote.Txn(ctx, r, kv.callOpts...)
 		if err == nil {
+			if op.isWrite() {
+				sessionFromContext(ctx).observe(resp.Header)
+			}
 			return OpResponse{txn: (*TxnResponse)(resp)}, nil
 		}
+	case tMove:
+		var resp *TxnResponse
+		resp, err = Move(ctx, kv, op)
+		if err == nil {
+			return OpResponse{txn: resp}, nil
+		}
+	case tCompactKey:
+		var resp *pb.CompactKeyResponse
+		r := &pb.CompactKeyRequest{Key: op.key, Keep: op.keep}
+		resp, err = kv.remote.CompactKey(ctx, r, kv.callOpts...)
+		if err == nil {
+			sessionFromContext(ctx).observe(resp.Header)
+			return OpResponse{compactKey: (*CompactKeyResponse)(resp)}, nil
+		}
 	default:
 		panic("Unknown op")
 	}
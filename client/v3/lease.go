@@ -16,6 +16,7 @@ package clientv3
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -28,8 +29,9 @@ import (
 )
 
 type (
-	LeaseRevokeResponse pb.LeaseRevokeResponse
-	LeaseID             int64
+	LeaseRevokeResponse   pb.LeaseRevokeResponse
+	LeaseReattachResponse pb.LeaseReattachResponse
+	LeaseID               int64
 )
 
 // LeaseGrantResponse wraps the protobuf message LeaseGrantResponse.
@@ -40,13 +42,33 @@ type LeaseGrantResponse struct {
 	Error string
 }
 
+// LeaseGrantBatchResponse wraps the protobuf message LeaseGrantBatchResponse.
+type LeaseGrantBatchResponse struct {
+	*pb.ResponseHeader
+	// Leases are the granted leases, in the order of the requested TTLs.
+	Leases []GrantedLease
+}
+
+// GrantedLease is a lease granted by GrantBatch.
+type GrantedLease struct {
+	ID  LeaseID
+	TTL int64
+}
+
 // LeaseKeepAliveResponse wraps the protobuf message LeaseKeepAliveResponse.
 type LeaseKeepAliveResponse struct {
 	*pb.ResponseHeader
-	ID  LeaseID
+	ID LeaseID
+	// TTL is the TTL in seconds granted by the server on renewal.
 	TTL int64
+
+	err error
 }
 
+// Err returns the error that closes the keep alive channel after this
+// response, if any.
+func (resp *LeaseKeepAliveResponse) Err() error { return resp.err }
+
 // LeaseTimeToLiveResponse wraps the protobuf message LeaseTimeToLiveResponse.
 type LeaseTimeToLiveResponse struct {
 	*pb.ResponseHeader
@@ -74,6 +96,30 @@ type LeaseLeasesResponse struct {
 	Leases []LeaseStatus `json:"leases"`
 }
 
+// LeaseDetail represents a lease listed by LeaseListDetailed.
+type LeaseDetail struct {
+	ID LeaseID `json:"id"`
+
+	// TTL is the remaining TTL in seconds for the lease; the lease will expire in under TTL+1 seconds.
+	TTL int64 `json:"ttl"`
+
+	// GrantedTTL is the initial granted time in seconds upon lease creation/renewal.
+	GrantedTTL int64 `json:"granted-ttl"`
+
+	// KeyCount is the number of keys attached to this lease.
+	KeyCount int64 `json:"key-count"`
+
+	// Keys is the list of keys attached to this lease, only set if requested
+	// with WithAttachedKeys.
+	Keys [][]byte `json:"keys,omitempty"`
+}
+
+// LeaseListDetailedResponse wraps the protobuf message LeaseListDetailedResponse.
+type LeaseListDetailedResponse struct {
+	*pb.ResponseHeader
+	Leases []LeaseDetail `json:"leases"`
+}
+
 const (
 	// defaultTTL is the assumed lease TTL used for the first keepalive
 	// deadline before the actual TTL is known to the client.
@@ -105,19 +151,52 @@ func (e ErrKeepAliveHalted) Error() string {
 	return s
 }
 
+// ErrKeepAliveTTLTooShort is reported by the last response of a keep alive
+// channel created with WithMinKeepAliveTTL when the server grants the lease a
+// shorter TTL.
+type ErrKeepAliveTTLTooShort struct {
+	// TTL is the TTL in seconds granted by the server.
+	TTL int64
+	// MinTTL is the minimum TTL requested by the keep alive.
+	MinTTL time.Duration
+}
+
+func (e ErrKeepAliveTTLTooShort) Error() string {
+	return fmt.Sprintf("etcdclient: granted lease TTL %ds is shorter than %v", e.TTL, e.MinTTL)
+}
+
 type Lease interface {
 	// Grant creates a new lease.
 	Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error)
 
+	// GrantBatch creates a lease for each of the given TTLs in a single request.
+	// Either all leases are granted or none of them.
+	// Supported since etcd 3.6.
+	GrantBatch(ctx context.Context, ttls []int64) (*LeaseGrantBatchResponse, error)
+
 	// Revoke revokes the given lease.
 	Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error)
 
+	// Reattach moves all keys attached to the lease oldID onto the lease newID
+	// in a single request, so that none of the keys expires in between. The
+	// remaining TTL of the new lease must be at least the remaining TTL of the
+	// old lease, unless WithShorterTTL is given. The old lease is kept.
+	// Supported since etcd 3.6.
+	Reattach(ctx context.Context, oldID, newID LeaseID, opts ...LeaseOption) (*LeaseReattachResponse, error)
+
 	// TimeToLive retrieves the lease information of the given lease ID.
 	TimeToLive(ctx context.Context, id LeaseID, opts ...LeaseOption) (*LeaseTimeToLiveResponse, error)
 
 	// Leases retrieves all leases.
 	Leases(ctx context.Context) (*LeaseLeasesResponse, error)
 
+	// LeaseListDetailed retrieves all leases with their TTLs and the number of
+	// keys attached to them. The keys themselves are only listed if
+	// WithAttachedKeys is given, which requires admin permission when auth
+	// is enabled.
+	// Supported since etcd 3.6.
+	LeaseListDetailed(ctx context.Context, opts ...LeaseOption) (*LeaseListDetailedResponse, error)
+
 	// KeepAlive attempts to keep the given lease alive forever. If the keepalive responses posted
 	// to the channel are not consumed promptly the channel may become full. When full, the lease
 	// client will continue sending keep alive requests to the etcd server, but will drop responses
@@ -147,6 +226,88 @@ type Lease interface {
 	Close() error
 }
 
+// LeaseKeepAliverWithOptions is implemented by the Leases that can configure
+// their keep alives with LeaseOptions. See KeepAliveWithOptions.
+type LeaseKeepAliverWithOptions interface {
+	// KeepAliveWithOptions keeps the given lease alive like KeepAlive. With
+	// WithMinKeepAliveTTL, the channel closes after a response whose Err
+	// reports that the server granted a shorter TTL.
+	KeepAliveWithOptions(ctx context.Context, id LeaseID, opts ...LeaseOption) (<-chan *LeaseKeepAliveResponse, error)
+}
+
+// KeepAliveWithOptions keeps the lease id alive with the KeepAliveWithOptions
+// method of l if it is a LeaseKeepAliverWithOptions, as are a Client and its
+// Lease. Otherwise the responses of KeepAlive are checked against the
+// minimum TTL of WithMinKeepAliveTTL, if any, as they are received.
+func KeepAliveWithOptions(ctx context.Context, l Lease, id LeaseID, opts ...LeaseOption) (<-chan *LeaseKeepAliveResponse, error) {
+	if c, ok := l.(*Client); ok {
+		l = c.Lease
+	}
+	if ka, ok := l.(LeaseKeepAliverWithOptions); ok {
+		return ka.KeepAliveWithOptions(ctx, id, opts...)
+	}
+	op := &LeaseOp{id: id}
+	op.applyOpts(opts)
+	if op.minKeepAliveTTL == 0 {
+		return l.KeepAlive(ctx, id)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	kach, err := l.KeepAlive(ctx, id)
+	if err != nil {
+		cancel()
+		return kach, err
+	}
+	ch := make(chan *LeaseKeepAliveResponse, keepAliveChSize(op))
+	go func() {
+		defer func() {
+			close(ch)
+			cancel()
+			// KeepAlive closes kach once ctx is canceled
+			for range kach {
+			}
+		}()
+		for {
+			var resp *LeaseKeepAliveResponse
+			select {
+			case r, ok := <-kach:
+				if !ok {
+					return
+				}
+				resp = r
+			case <-ctx.Done():
+				return
+			}
+			if time.Duration(resp.TTL)*time.Second >= op.minKeepAliveTTL {
+				// the last slot of ch is kept for the response reporting a
+				// shorter TTL
+				if len(ch) < cap(ch)-1 {
+					ch <- resp
+				}
+				continue
+			}
+			ch <- &LeaseKeepAliveResponse{
+				ResponseHeader: resp.ResponseHeader,
+				ID:             resp.ID,
+				TTL:            resp.TTL,
+				err:            ErrKeepAliveTTLTooShort{TTL: resp.TTL, MinTTL: op.minKeepAliveTTL},
+			}
+			return
+		}
+	}()
+	return ch, nil
+}
+
+// keepAliveChSize returns the size of the channel of a keep alive with op,
+// which keeps a slot for the response reporting a shorter TTL than the
+// minimum TTL of op, if any.
+func keepAliveChSize(op *LeaseOp) int {
+	if op.minKeepAliveTTL > 0 {
+		return LeaseResponseChSize + 1
+	}
+	return LeaseResponseChSize
+}
+
 type lessor struct {
 	mu sync.Mutex // guards all fields
 
@@ -173,6 +334,10 @@ type lessor struct {
 
 	callOpts []grpc.CallOption
 
+	// requireLeader closes all the keep alive channels on leader loss,
+	// as if their ctxs had require leader.
+	requireLeader bool
+
 	lg *zap.Logger
 }
 
@@ -180,6 +345,8 @@ type lessor struct {
 type keepAlive struct {
 	chs  []chan<- *LeaseKeepAliveResponse
 	ctxs []context.Context
+	// minTTLs holds the minimum TTL of each channel, or zero if any TTL is accepted
+	minTTLs []time.Duration
 	// deadline is the time the keep alive channels close if no response
 	deadline time.Time
 	// nextKeepAlive is when to send the next keep alive message
@@ -205,6 +372,7 @@ func NewLeaseFromLeaseClient(remote pb.LeaseClient, c *Client, keepAliveTimeout
 	}
 	if c != nil {
 		l.callOpts = c.callOpts
+		l.requireLeader = c.cfg.RequireLeader
 	}
 	reqLeaderCtx := WithRequireLeader(context.Background())
 	l.stopCtx, l.stopCancel = context.WithCancel(reqLeaderCtx)
@@ -226,6 +394,22 @@ func (l *lessor) Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, err
 	return nil, toErr(ctx, err)
 }
 
+func (l *lessor) GrantBatch(ctx context.Context, ttls []int64) (*LeaseGrantBatchResponse, error) {
+	r := &pb.LeaseGrantBatchRequest{Leases: make([]*pb.LeaseGrantRequest, len(ttls))}
+	for i, ttl := range ttls {
+		r.Leases[i] = &pb.LeaseGrantRequest{TTL: ttl}
+	}
+	resp, err := l.remote.LeaseGrantBatch(ctx, r, l.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	leases := make([]GrantedLease, len(resp.Leases))
+	for i, gl := range resp.Leases {
+		leases[i] = GrantedLease{ID: LeaseID(gl.ID), TTL: gl.TTL}
+	}
+	return &LeaseGrantBatchResponse{ResponseHeader: resp.GetHeader(), Leases: leases}, nil
+}
+
 func (l *lessor) Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error) {
 	r := &pb.LeaseRevokeRequest{ID: int64(id)}
 	resp, err := l.remote.LeaseRevoke(ctx, r, l.callOpts...)
@@ -235,6 +419,15 @@ func (l *lessor) Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse,
 	return nil, toErr(ctx, err)
 }
 
+func (l *lessor) Reattach(ctx context.Context, oldID, newID LeaseID, opts ...LeaseOption) (*LeaseReattachResponse, error) {
+	r := toLeaseReattachRequest(oldID, newID, opts...)
+	resp, err := l.remote.LeaseReattach(ctx, r, l.callOpts...)
+	if err == nil {
+		return (*LeaseReattachResponse)(resp), nil
+	}
+	return nil, toErr(ctx, err)
+}
+
 func (l *lessor) TimeToLive(ctx context.Context, id LeaseID, opts ...LeaseOption) (*LeaseTimeToLiveResponse, error) {
 	r := toLeaseTimeToLiveRequest(id, opts...)
 	resp, err := l.remote.LeaseTimeToLive(ctx, r, l.callOpts...)
@@ -263,8 +456,33 @@ func (l *lessor) Leases(ctx context.Context) (*LeaseLeasesResponse, error) {
 	return nil, toErr(ctx, err)
 }
 
+func (l *lessor) LeaseListDetailed(ctx context.Context, opts ...LeaseOption) (*LeaseListDetailedResponse, error) {
+	r := toLeaseListDetailedRequest(opts...)
+	resp, err := l.remote.LeaseListDetailed(ctx, r, l.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	leases := make([]LeaseDetail, len(resp.Leases))
+	for i, d := range resp.Leases {
+		leases[i] = LeaseDetail{
+			ID:         LeaseID(d.ID),
+			TTL:        d.TTL,
+			GrantedTTL: d.GrantedTTL,
+			KeyCount:   d.KeyCount,
+			Keys:       d.Keys,
+		}
+	}
+	return &LeaseListDetailedResponse{ResponseHeader: resp.GetHeader(), Leases: leases}, nil
+}
+
 func (l *lessor) KeepAlive(ctx context.Context, id LeaseID) (<-chan *LeaseKeepAliveResponse, error) {
-	ch := make(chan *LeaseKeepAliveResponse, LeaseResponseChSize)
+	return l.KeepAliveWithOptions(ctx, id)
+}
+
+func (l *lessor) KeepAliveWithOptions(ctx context.Context, id LeaseID, opts ...LeaseOption) (<-chan *LeaseKeepAliveResponse, error) {
+	op := &LeaseOp{id: id}
+	op.applyOpts(opts)
+	ch := make(chan *LeaseKeepAliveResponse, keepAliveChSize(op))
 
 	l.mu.Lock()
 	// ensure that recvKeepAliveLoop is still running
@@ -282,6 +500,7 @@ func (l *lessor) KeepAlive(ctx context.Context, id LeaseID) (<-chan *LeaseKeepAl
 		ka = &keepAlive{
 			chs:           []chan<- *LeaseKeepAliveResponse{ch},
 			ctxs:          []context.Context{ctx},
+			minTTLs:       []time.Duration{op.minKeepAliveTTL},
 			deadline:      time.Now().Add(l.firstKeepAliveTimeout),
 			nextKeepAlive: time.Now(),
 			donec:         make(chan struct{}),
@@ -291,6 +510,7 @@ func (l *lessor) KeepAlive(ctx context.Context, id LeaseID) (<-chan *LeaseKeepAl
 		// add channel and context to existing keep alive
 		ka.ctxs = append(ka.ctxs, ctx)
 		ka.chs = append(ka.chs, ch)
+		ka.minTTLs = append(ka.minTTLs, op.minKeepAliveTTL)
 	}
 	l.mu.Unlock()
 
@@ -349,8 +569,7 @@ func (l *lessor) keepAliveCtxCloser(ctx context.Context, id LeaseID, donec <-cha
 	for i, c := range ka.ctxs {
 		if c == ctx {
 			close(ka.chs[i])
-			ka.ctxs = append(ka.ctxs[:i], ka.ctxs[i+1:]...)
-			ka.chs = append(ka.chs[:i], ka.chs[i+1:]...)
+			ka.remove(i)
 			break
 		}
 	}
@@ -360,6 +579,16 @@ func (l *lessor) keepAliveCtxCloser(ctx context.Context, id LeaseID, donec <-cha
 	}
 }
 
+// hasRequireLeader returns true if ctx was set up by WithRequireLeader.
+func hasRequireLeader(ctx context.Context) bool {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return false
+	}
+	ks := md[rpctypes.MetadataRequireLeaderKey]
+	return len(ks) > 0 && ks[0] == rpctypes.MetadataHasLeader
+}
+
 // closeRequireLeader scans keepAlives for ctxs that have require leader
 // and closes the associated channels.
 func (l *lessor) closeRequireLeader() {
@@ -369,12 +598,7 @@ func (l *lessor) closeRequireLeader() {
 		reqIdxs := 0
 		// find all required leader channels, close, mark as nil
 		for i, ctx := range ka.ctxs {
-			md, ok := metadata.FromOutgoingContext(ctx)
-			if !ok {
-				continue
-			}
-			ks := md[rpctypes.MetadataRequireLeaderKey]
-			if len(ks) < 1 || ks[0] != rpctypes.MetadataHasLeader {
+			if !l.requireLeader && !hasRequireLeader(ctx) {
 				continue
 			}
 			close(ka.chs[i])
@@ -387,15 +611,16 @@ func (l *lessor) closeRequireLeader() {
 		// remove all channels that required a leader from keepalive
 		newChs := make([]chan<- *LeaseKeepAliveResponse, len(ka.chs)-reqIdxs)
 		newCtxs := make([]context.Context, len(newChs))
+		newMinTTLs := make([]time.Duration, len(newChs))
 		newIdx := 0
 		for i := range ka.chs {
 			if ka.chs[i] == nil {
 				continue
 			}
-			newChs[newIdx], newCtxs[newIdx] = ka.chs[i], ka.ctxs[newIdx]
+			newChs[newIdx], newCtxs[newIdx], newMinTTLs[newIdx] = ka.chs[i], ka.ctxs[newIdx], ka.minTTLs[i]
 			newIdx++
 		}
-		ka.chs, ka.ctxs = newChs, newCtxs
+		ka.chs, ka.ctxs, ka.minTTLs = newChs, newCtxs, newMinTTLs
 	}
 }
 
@@ -528,21 +753,46 @@ func (l *lessor) recvKeepAlive(resp *pb.LeaseKeepAliveResponse) {
 	}
 
 	// send update to all channels
-	nextKeepAlive := time.Now().Add((time.Duration(karesp.TTL) * time.Second) / 3.0)
-	ka.deadline = time.Now().Add(time.Duration(karesp.TTL) * time.Second)
-	for _, ch := range ka.chs {
-		select {
-		case ch <- karesp:
-		default:
-			if l.lg != nil {
-				l.lg.Warn("lease keepalive response queue is full; dropping response send",
-					zap.Int("queue-size", len(ch)),
-					zap.Int("queue-capacity", cap(ch)),
-				)
+	ttl := time.Duration(karesp.TTL) * time.Second
+	nextKeepAlive := time.Now().Add(ttl / 3.0)
+	ka.deadline = time.Now().Add(ttl)
+	for i := 0; i < len(ka.chs); i++ {
+		ch, resp := ka.chs[i], karesp
+		tooShort := ttl < ka.minTTLs[i]
+		if tooShort {
+			resp = &LeaseKeepAliveResponse{
+				ResponseHeader: karesp.ResponseHeader,
+				ID:             karesp.ID,
+				TTL:            karesp.TTL,
+				err:            ErrKeepAliveTTLTooShort{TTL: karesp.TTL, MinTTL: ka.minTTLs[i]},
 			}
 		}
+		// the channels with a minimum TTL keep a slot for the response
+		// reporting a shorter TTL, so that it is never dropped; the other
+		// responses are only sent here, so they do not fill the slot
+		free := cap(ch) - len(ch)
+		if ka.minTTLs[i] > 0 && !tooShort {
+			free--
+		}
+		if free > 0 {
+			ch <- resp
+		} else if l.lg != nil {
+			l.lg.Warn("lease keepalive response queue is full; dropping response send",
+				zap.Int("queue-size", len(ch)),
+				zap.Int("queue-capacity", cap(ch)),
+			)
+		}
 		// still advance in order to rate-limit keep-alive sends
 		ka.nextKeepAlive = nextKeepAlive
+		if tooShort {
+			close(ch)
+			ka.remove(i)
+			i--
+		}
+	}
+	if len(ka.chs) == 0 {
+		delete(l.keepAlives, karesp.ID)
+		close(ka.donec)
 	}
 }
 
@@ -604,6 +854,13 @@ func (l *lessor) sendKeepAliveLoop(stream pb.Lease_LeaseKeepAliveClient) {
 	}
 }
 
+// remove removes the i-th channel and its context.
+func (ka *keepAlive) remove(i int) {
+	ka.ctxs = append(ka.ctxs[:i], ka.ctxs[i+1:]...)
+	ka.chs = append(ka.chs[:i], ka.chs[i+1:]...)
+	ka.minTTLs = append(ka.minTTLs[:i], ka.minTTLs[i+1:]...)
+}
+
 func (ka *keepAlive) close() {
 	close(ka.donec)
 	for _, ch := range ka.chs {
@@ -0,0 +1,104 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKeepAliveLease is a Lease whose keep alive responses are sent by the
+// test. Like the lessor, it closes the keep alive channel once its ctx is
+// done.
+type fakeKeepAliveLease struct {
+	Lease
+	kach chan *LeaseKeepAliveResponse
+}
+
+func (l *fakeKeepAliveLease) KeepAlive(ctx context.Context, id LeaseID) (<-chan *LeaseKeepAliveResponse, error) {
+	ch := make(chan *LeaseKeepAliveResponse)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case resp := <-l.kach:
+				select {
+				case ch <- resp:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func requireKeepAliveClosed(t *testing.T, ch <-chan *LeaseKeepAliveResponse) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the keep alive channel to close")
+		}
+	}
+}
+
+// TestKeepAliveWithOptionsTTLTooShort ensures the response reporting a
+// shorter TTL than the minimum is delivered even if the channel is full.
+func TestKeepAliveWithOptionsTTLTooShort(t *testing.T) {
+	l := &fakeKeepAliveLease{kach: make(chan *LeaseKeepAliveResponse)}
+	ch, err := KeepAliveWithOptions(context.Background(), l, 1, WithMinKeepAliveTTL(5*time.Second))
+	require.NoError(t, err)
+
+	for i := 0; i < LeaseResponseChSize+2; i++ {
+		l.kach <- &LeaseKeepAliveResponse{ID: 1, TTL: 10}
+	}
+	l.kach <- &LeaseKeepAliveResponse{ID: 1, TTL: 3}
+
+	var last *LeaseKeepAliveResponse
+	for resp := range ch {
+		last = resp
+	}
+	require.NotNil(t, last)
+	var terr ErrKeepAliveTTLTooShort
+	require.True(t, errors.As(last.Err(), &terr), "expected %T, got %v", terr, last.Err())
+	require.Equal(t, int64(3), terr.TTL)
+}
+
+// TestKeepAliveWithOptionsCtxDone ensures the keep alive channel closes once
+// the ctx is done, even if its responses are not received.
+func TestKeepAliveWithOptionsCtxDone(t *testing.T) {
+	l := &fakeKeepAliveLease{kach: make(chan *LeaseKeepAliveResponse)}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := KeepAliveWithOptions(ctx, l, 1, WithMinKeepAliveTTL(5*time.Second))
+	require.NoError(t, err)
+
+	for i := 0; i < LeaseResponseChSize+2; i++ {
+		l.kach <- &LeaseKeepAliveResponse{ID: 1, TTL: 10}
+	}
+	cancel()
+	requireKeepAliveClosed(t, ch)
+}
@@ -174,6 +174,10 @@ func (lc *leaseCache) delete(key string, hdr *v3pb.ResponseHeader) {
 func (lc *leaseCache) Evict(key string) (rev int64) {
 	lc.mu.Lock()
 	defer lc.mu.Unlock()
+	return lc.evict(key)
+}
+
+func (lc *leaseCache) evict(key string) (rev int64) {
 	if li := lc.entries[key]; li != nil {
 		rev = li.rev
 		delete(lc.entries, key)
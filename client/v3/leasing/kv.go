@@ -109,6 +109,12 @@ func (lkv *leasingKV) Do(ctx context.Context, op v3.Op) (v3.OpResponse, error) {
 		cmps, thenOps, elseOps := op.Txn()
 		resp, err := lkv.Txn(ctx).If(cmps...).Then(thenOps...).Else(elseOps...).Commit()
 		return resp.OpResponse(), err
+	case op.IsMove():
+		resp, err := v3.Move(ctx, lkv, op)
+		return resp.OpResponse(), err
+	case op.IsCompactKey():
+		// the cached values are the latest revisions, which are kept
+		return lkv.kv.Do(ctx, op)
 	}
 	return v3.OpResponse{}, nil
 }
@@ -242,7 +248,12 @@ func (lkv *leasingKV) put(ctx context.Context, op v3.Op) (pr *v3.PutResponse, er
 		}
 		if resp.Succeeded {
 			lkv.leases.mu.Lock()
-			lkv.leases.Update(op.KeyBytes(), op.ValueBytes(), resp.Header)
+			if len(op.CopyFromBytes()) != 0 {
+				// the copied value is not known
+				lkv.leases.evict(string(op.KeyBytes()))
+			} else {
+				lkv.leases.Update(op.KeyBytes(), op.ValueBytes(), resp.Header)
+			}
 			lkv.leases.mu.Unlock()
 			pr = (*v3.PutResponse)(resp.Responses[0].GetResponsePut())
 			pr.Header = resp.Header
@@ -29,6 +29,8 @@ type txnLeasing struct {
 	cs   []v3.Cmp
 	opst []v3.Op
 	opse []v3.Op
+
+	dryRun bool
 }
 
 func (txn *txnLeasing) If(cs ...v3.Cmp) v3.Txn {
@@ -49,7 +51,17 @@ func (txn *txnLeasing) Else(ops ...v3.Op) v3.Txn {
 	return txn
 }
 
+func (txn *txnLeasing) WithDryRun() v3.Txn {
+	txn.dryRun = true
+	txn.Txn = txn.Txn.WithDryRun()
+	return txn
+}
+
 func (txn *txnLeasing) Commit() (*v3.TxnResponse, error) {
+	if txn.dryRun {
+		// nothing is written, so the cache is neither used nor updated
+		return txn.Txn.Commit()
+	}
 	if resp, err := txn.eval(); resp != nil || err != nil {
 		return resp, err
 	}
@@ -172,7 +184,12 @@ func (txn *txnLeasing) commitToCache(txnResp *v3pb.TxnResponse, userTxn v3.Op) {
 			txn.lkv.leases.delete(key, txnResp.Header)
 		}
 		if op.IsPut() {
-			txn.lkv.leases.Update(op.KeyBytes(), op.ValueBytes(), txnResp.Header)
+			if len(op.CopyFromBytes()) != 0 {
+				// the copied value is not known
+				txn.lkv.leases.evict(key)
+			} else {
+				txn.lkv.leases.Update(op.KeyBytes(), op.ValueBytes(), txnResp.Header)
+			}
 		}
 	}
 	txn.lkv.leases.mu.Unlock()
@@ -19,11 +19,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 )
 
 type (
@@ -35,6 +37,19 @@ type (
 	MoveLeaderResponse pb.MoveLeaderResponse
 	DowngradeResponse  pb.DowngradeResponse
 
+	CompactionStatusResponse pb.CompactionStatusResponse
+	HotKeysResponse          pb.HotKeysResponse
+	SpaceReclaimResponse     pb.SpaceReclaimResponse
+	MemberSelfResponse       pb.MemberSelfResponse
+	TriggerSnapshotResponse  pb.TriggerSnapshotResponse
+	AuthExportResponse       pb.AuthExportResponse
+	AuthImportResponse       pb.AuthImportResponse
+	PrefixQuotaSetResponse   pb.PrefixQuotaSetResponse
+	PrefixQuotaListResponse  pb.PrefixQuotaListResponse
+	VerifyBackendResponse    pb.VerifyBackendResponse
+	KeyspaceStatsResponse    pb.KeyspaceStatsResponse
+	RebuildIndexResponse     pb.RebuildIndexResponse
+
 	DowngradeAction pb.DowngradeRequest_DowngradeAction
 )
 
@@ -73,6 +88,24 @@ type Maintenance interface {
 	// "io.ReadCloser" would error out (e.g. context.Canceled, context.DeadlineExceeded).
 	SnapshotWithVersion(ctx context.Context) (*SnapshotResponse, error)
 
+	// SnapshotFrom returns a reader for a point-in-time snapshot that can be resumed.
+	// With a zero offset, it starts the download of a new snapshot identified by the
+	// SnapshotID of the response. An interrupted download is resumed by passing that
+	// ID and the number of bytes already read as offset; the stream continues the
+	// same snapshot, so the bytes read across attempts are identical to an uninterrupted
+	// download. If the server no longer keeps the snapshot, ErrSnapshotExpired is
+	// returned and the download must start over from a zero offset.
+	// Supported since etcd 3.6.
+	SnapshotFrom(ctx context.Context, id uint64, offset int64) (*SnapshotResponse, error)
+
+	// SnapshotAtRevision returns a reader for a snapshot whose key-value store is the
+	// store as of rev rather than the current one, so that backups of several members
+	// can be taken at the same revision. Leases, auth and membership are current.
+	// ErrCompacted is returned if rev is compacted, and ErrFutureRev if the member has
+	// not applied rev yet.
+	// Supported since etcd 3.6.
+	SnapshotAtRevision(ctx context.Context, rev int64) (*SnapshotResponse, error)
+
 	// Snapshot provides a reader for a point-in-time snapshot of etcd.
 	// If the context "ctx" is canceled or timed out, reading from returned
 	// "io.ReadCloser" would error out (e.g. context.Canceled, context.DeadlineExceeded).
@@ -87,6 +120,100 @@ type Maintenance interface {
 	// on the cluster version.
 	// Supported since etcd 3.5.
 	Downgrade(ctx context.Context, action DowngradeAction, version string) (*DowngradeResponse, error)
+
+	// CompactionStatus reports the progress of the compaction running on the endpoint.
+	// Supported since etcd 3.6.
+	CompactionStatus(ctx context.Context, endpoint string) (*CompactionStatusResponse, error)
+
+	// HotKeys reports up to limit most read and most written keys on the endpoint,
+	// or all tracked keys if limit is zero. If reset is true, the endpoint discards
+	// the access counts after reporting them. The endpoint must have been started
+	// with --experimental-hot-key-tracking.
+	// Supported since etcd 3.6.
+	HotKeys(ctx context.Context, endpoint string, limit int64, reset bool) (*HotKeysResponse, error)
+
+	// HashKVByRange returns a hash of the KV state of the keys in the range
+	// [key, end) at or below the given revision, or at the current revision
+	// if rev is zero. The range is interpreted as in Get with WithRange; an
+	// empty end hashes the single key.
+	// Supported since etcd 3.6.
+	HashKVByRange(ctx context.Context, endpoint string, rev int64, key, end string) (*HashKVResponse, error)
+
+	// SpaceReclaim enables or disables the incremental space reclaim of the
+	// endpoint's backend, which reuses the space freed by deletions without a
+	// defragmentation. The response reports the bytes reclaimed so far. The
+	// setting is lost when the endpoint restarts.
+	// Supported since etcd 3.6.
+	SpaceReclaim(ctx context.Context, endpoint string, enable bool) (*SpaceReclaimResponse, error)
+
+	// MemberSelf returns the identity of the endpoint's member and the ID of its
+	// cluster. If the member has not joined the cluster yet, the response is only
+	// partially populated and Joined is false.
+	// Supported since etcd 3.6.
+	MemberSelf(ctx context.Context, endpoint string) (*MemberSelfResponse, error)
+
+	// TriggerSnapshot makes the endpoint's member take a raft snapshot now and
+	// compact its raft log, and returns the index of the snapshot. If a snapshot
+	// is already being saved, nothing is done and InProgress is true.
+	// Unlike Snapshot, it does not download anything.
+	// Supported since etcd 3.6.
+	TriggerSnapshot(ctx context.Context, endpoint string) (*TriggerSnapshotResponse, error)
+
+	// AuthExport returns the users and roles of the cluster's auth store, with
+	// the hashed passwords of the users and the permissions of the roles. The
+	// response can be marshaled to back up the auth store on its own.
+	// Supported since etcd 3.6.
+	AuthExport(ctx context.Context) (*AuthExportResponse, error)
+
+	// AuthImport applies the users and roles of an AuthExport response, possibly
+	// of another cluster, in a single proposal. Unless replace is set, they are
+	// merged into the existing users and roles, and the import fails with
+	// rpctypes.ErrUserAlreadyExist or rpctypes.ErrRoleAlreadyExist if one of
+	// them is already defined differently. With replace set, all other users
+	// and roles are deleted, except the root role. If authentication is enabled,
+	// the root user must keep the root role.
+	// Supported since etcd 3.6.
+	AuthImport(ctx context.Context, exported *AuthExportResponse, replace bool) (*AuthImportResponse, error)
+
+	// PrefixQuotaSet limits the keys with prefix to maxKeys keys, with keys and
+	// values of maxBytes bytes in total. Zero means no limit, and a quota
+	// without limits is removed. Puts and txns that would take the keys of a
+	// prefix over its quota fail with an error for which
+	// rpctypes.QuotaExceededPrefix returns the prefix.
+	// Supported since etcd 3.6.
+	PrefixQuotaSet(ctx context.Context, prefix string, maxBytes, maxKeys int64) (*PrefixQuotaSetResponse, error)
+
+	// PrefixQuotaList lists the prefix quotas along with the usage of their
+	// prefixes on the endpoint.
+	// Supported since etcd 3.6.
+	PrefixQuotaList(ctx context.Context, endpoint string) (*PrefixQuotaListResponse, error)
+
+	// VerifyBackend checks the consistency of the endpoint's backend database,
+	// and that the key index of the member matches it. The response lists the
+	// anomalies found; a healthy member has none. The scan pauses for
+	// batchInterval after every batchLimit revisions so that it does not impact
+	// serving; zero values use the defaults of the member. Canceling ctx stops
+	// the scan.
+	// Supported since etcd 3.6.
+	VerifyBackend(ctx context.Context, endpoint string, batchLimit int64, batchInterval time.Duration) (*VerifyBackendResponse, error)
+
+	// KeyspaceStats returns the number and the size of the keys of the
+	// endpoint, along with up to limit largest prefixes of the keys, cut
+	// after depth '/' separated segments. Zero values use the defaults of the
+	// member. The result is cached by the member for a while, so it may lag
+	// behind; its Revision tells which revision it reflects.
+	// Supported since etcd 3.6.
+	KeyspaceStats(ctx context.Context, endpoint string, depth, limit int64) (*KeyspaceStatsResponse, error)
+
+	// RebuildIndex rebuilds the key index of the endpoint from its backend.
+	// The response lists the revisions the replaced index differed from the
+	// backend by, then the anomalies found by verifying the rebuilt index,
+	// which has none if the rebuild succeeded. The endpoint rejects writes
+	// and holds up its reads while the index is rebuilt; it refuses to
+	// rebuild if it is the leader, unless force is set, in which case it
+	// first transfers the leadership to another member.
+	// Supported since etcd 3.6.
+	RebuildIndex(ctx context.Context, endpoint string, force bool) (*RebuildIndexResponse, error)
 }
 
 // SnapshotResponse is aggregated response from the snapshot stream.
@@ -102,6 +229,9 @@ type SnapshotResponse struct {
 	// Informs which etcd server version should be used when restoring the snapshot.
 	// Supported on etcd >= v3.6.
 	Version string
+	// SnapshotID identifies the snapshot to resume its download with SnapshotFrom.
+	// Supported on etcd >= v3.6.
+	SnapshotID uint64
 }
 
 type maintenance struct {
@@ -270,6 +400,76 @@ func (m *maintenance) SnapshotWithVersion(ctx context.Context) (*SnapshotRespons
 	}, err
 }
 
+func (m *maintenance) SnapshotFrom(ctx context.Context, id uint64, offset int64) (*SnapshotResponse, error) {
+	if offset == 0 {
+		id = 0
+	}
+	return m.revisionSnapshot(ctx, &pb.SnapshotRequest{Offset: offset, SnapshotId: id}, 0)
+}
+
+func (m *maintenance) SnapshotAtRevision(ctx context.Context, rev int64) (*SnapshotResponse, error) {
+	return m.revisionSnapshot(ctx, &pb.SnapshotRequest{AtRevision: rev}, rev)
+}
+
+// revisionSnapshot downloads the snapshot of the request, which must be at
+// revision rev unless it is zero, and must be the snapshot of
+// req.SnapshotId unless it is zero.
+func (m *maintenance) revisionSnapshot(ctx context.Context, req *pb.SnapshotRequest, rev int64) (*SnapshotResponse, error) {
+	ss, err := m.remote.Snapshot(ctx, req, append(m.callOpts, withMax(defaultStreamMaxRetries))...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+
+	m.lg.Info("opened snapshot stream; downloading",
+		zap.Uint64("snapshot-id", req.SnapshotId),
+		zap.Int64("offset", req.Offset),
+		zap.Int64("at-revision", req.AtRevision),
+	)
+	pr, pw := io.Pipe()
+
+	resp, err := ss.Recv()
+	if err != nil {
+		err = toErr(ctx, err)
+		m.logAndCloseWithError(err, pw)
+		return nil, err
+	}
+	// servers not supporting resumable or revision snapshots ignore the
+	// request and do not identify the snapshot
+	if resp.Header == nil || resp.SnapshotId == 0 ||
+		(rev != 0 && resp.Header.Revision != rev) ||
+		(req.SnapshotId != 0 && resp.SnapshotId != req.SnapshotId) {
+		m.logAndCloseWithError(rpctypes.ErrNotCapable, pw)
+		return nil, rpctypes.ErrNotCapable
+	}
+	go func() {
+		// Saving response is blocking
+		err := m.save(resp, pw)
+		if err != nil {
+			m.logAndCloseWithError(err, pw)
+			return
+		}
+		for {
+			resp, err := ss.Recv()
+			if err != nil {
+				m.logAndCloseWithError(err, pw)
+				return
+			}
+			err = m.save(resp, pw)
+			if err != nil {
+				m.logAndCloseWithError(err, pw)
+				return
+			}
+		}
+	}()
+
+	return &SnapshotResponse{
+		Header:     resp.GetHeader(),
+		Snapshot:   &snapshotReadCloser{ctx: ctx, ReadCloser: pr},
+		Version:    resp.GetVersion(),
+		SnapshotID: resp.GetSnapshotId(),
+	}, nil
+}
+
 func (m *maintenance) Snapshot(ctx context.Context) (io.ReadCloser, error) {
 	ss, err := m.remote.Snapshot(ctx, &pb.SnapshotRequest{}, append(m.callOpts, withMax(defaultStreamMaxRetries))...)
 	if err != nil {
@@ -348,3 +548,160 @@ func (m *maintenance) Downgrade(ctx context.Context, action DowngradeAction, ver
 	resp, err := m.remote.Downgrade(ctx, &pb.DowngradeRequest{Action: actionType, Version: version}, m.callOpts...)
 	return (*DowngradeResponse)(resp), toErr(ctx, err)
 }
+
+func (m *maintenance) CompactionStatus(ctx context.Context, endpoint string) (*CompactionStatusResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.CompactionStatus(ctx, &pb.CompactionStatusRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*CompactionStatusResponse)(resp), nil
+}
+
+func (m *maintenance) HotKeys(ctx context.Context, endpoint string, limit int64, reset bool) (*HotKeysResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.HotKeys(ctx, &pb.HotKeysRequest{Limit: limit, Reset_: reset}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*HotKeysResponse)(resp), nil
+}
+
+func (m *maintenance) HashKVByRange(ctx context.Context, endpoint string, rev int64, key, end string) (*HashKVResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.HashKVByRange(ctx, &pb.HashKVByRangeRequest{Revision: rev, Key: []byte(key), RangeEnd: []byte(end)}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*HashKVResponse)(resp), nil
+}
+
+func (m *maintenance) SpaceReclaim(ctx context.Context, endpoint string, enable bool) (*SpaceReclaimResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.SpaceReclaim(ctx, &pb.SpaceReclaimRequest{Enable: enable}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*SpaceReclaimResponse)(resp), nil
+}
+
+func (m *maintenance) MemberSelf(ctx context.Context, endpoint string) (*MemberSelfResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.MemberSelf(ctx, &pb.MemberSelfRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*MemberSelfResponse)(resp), nil
+}
+
+func (m *maintenance) TriggerSnapshot(ctx context.Context, endpoint string) (*TriggerSnapshotResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.TriggerSnapshot(ctx, &pb.TriggerSnapshotRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*TriggerSnapshotResponse)(resp), nil
+}
+
+func (m *maintenance) AuthExport(ctx context.Context) (*AuthExportResponse, error) {
+	resp, err := m.remote.AuthExport(ctx, &pb.AuthExportRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*AuthExportResponse)(resp), nil
+}
+
+func (m *maintenance) AuthImport(ctx context.Context, exported *AuthExportResponse, replace bool) (*AuthImportResponse, error) {
+	r := &pb.AuthImportRequest{Users: exported.Users, Roles: exported.Roles, Replace: replace}
+	resp, err := m.remote.AuthImport(ctx, r, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*AuthImportResponse)(resp), nil
+}
+
+func (m *maintenance) PrefixQuotaSet(ctx context.Context, prefix string, maxBytes, maxKeys int64) (*PrefixQuotaSetResponse, error) {
+	r := &pb.PrefixQuotaSetRequest{Quota: &pb.PrefixQuota{Prefix: []byte(prefix), MaxBytes: maxBytes, MaxKeys: maxKeys}}
+	resp, err := m.remote.PrefixQuotaSet(ctx, r, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*PrefixQuotaSetResponse)(resp), nil
+}
+
+func (m *maintenance) PrefixQuotaList(ctx context.Context, endpoint string) (*PrefixQuotaListResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.PrefixQuotaList(ctx, &pb.PrefixQuotaListRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*PrefixQuotaListResponse)(resp), nil
+}
+
+func (m *maintenance) VerifyBackend(ctx context.Context, endpoint string, batchLimit int64, batchInterval time.Duration) (*VerifyBackendResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	r := &pb.VerifyBackendRequest{BatchLimit: batchLimit, BatchIntervalMs: batchInterval.Milliseconds()}
+	resp, err := remote.VerifyBackend(ctx, r, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*VerifyBackendResponse)(resp), nil
+}
+
+func (m *maintenance) KeyspaceStats(ctx context.Context, endpoint string, depth, limit int64) (*KeyspaceStatsResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.KeyspaceStats(ctx, &pb.KeyspaceStatsRequest{Depth: depth, Limit: limit}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*KeyspaceStatsResponse)(resp), nil
+}
+
+func (m *maintenance) RebuildIndex(ctx context.Context, endpoint string, force bool) (*RebuildIndexResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.RebuildIndex(ctx, &pb.RebuildIndexRequest{Force: force}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*RebuildIndexResponse)(resp), nil
+}
@@ -183,10 +183,18 @@ func (m *mockKVServer) Txn(context.Context, *pb.TxnRequest) (*pb.TxnResponse, er
 	return &pb.TxnResponse{}, nil
 }
 
+func (m *mockKVServer) TxnStream(_ *pb.TxnRequest, stream pb.KV_TxnStreamServer) error {
+	return stream.Send(&pb.TxnStreamResponse{Header: &pb.ResponseHeader{}})
+}
+
 func (m *mockKVServer) Compact(context.Context, *pb.CompactionRequest) (*pb.CompactionResponse, error) {
 	return &pb.CompactionResponse{}, nil
 }
 
+func (m *mockKVServer) CompactKey(context.Context, *pb.CompactKeyRequest) (*pb.CompactKeyResponse, error) {
+	return &pb.CompactKeyResponse{}, nil
+}
+
 func (m *mockKVServer) Lease(context.Context, *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
 	return &pb.LeaseGrantResponse{}, nil
 }
@@ -197,6 +205,14 @@ func (s mockLeaseServer) LeaseGrant(context.Context, *pb.LeaseGrantRequest) (*pb
 	return &pb.LeaseGrantResponse{}, nil
 }
 
+func (s mockLeaseServer) LeaseGrantBatch(context.Context, *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	return &pb.LeaseGrantBatchResponse{}, nil
+}
+
+func (s mockLeaseServer) LeaseReattach(context.Context, *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	return &pb.LeaseReattachResponse{}, nil
+}
+
 func (s *mockLeaseServer) LeaseRevoke(context.Context, *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
 	return &pb.LeaseRevokeResponse{}, nil
 }
@@ -212,3 +228,7 @@ func (s *mockLeaseServer) LeaseTimeToLive(context.Context, *pb.LeaseTimeToLiveRe
 func (s *mockLeaseServer) LeaseLeases(context.Context, *pb.LeaseLeasesRequest) (*pb.LeaseLeasesResponse, error) {
 	return &pb.LeaseLeasesResponse{}, nil
 }
+
+func (s *mockLeaseServer) LeaseListDetailed(context.Context, *pb.LeaseListDetailedRequest) (*pb.LeaseListDetailedResponse, error) {
+	return &pb.LeaseListDetailedResponse{}, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+var (
+	// ErrMoveSourceNotFound is returned by a move operation if its source key
+	// does not exist.
+	ErrMoveSourceNotFound = errors.New("etcdclient: move source key not found")
+	// ErrMoveTargetExists is returned by a move operation if its target key
+	// exists and WithOverwrite is not set.
+	ErrMoveTargetExists = errors.New("etcdclient: move target key already exists")
+	// ErrMoveInTxn is returned by the transactions holding a move operation.
+	ErrMoveInTxn = errors.New("etcdclient: move operation in a transaction")
+
+	errMoveSameKey = errors.New("etcdclient: move source and target keys are the same")
+)
+
+// OpMove returns an operation moving the key src to the key dst. KV.Do runs
+// it as a single transaction that creates dst with the value and lease of src
+// and deletes src, so the move is atomic.
+//
+// The move fails with ErrMoveSourceNotFound if src does not exist, and with
+// ErrMoveTargetExists if dst exists unless WithOverwrite is set. With
+// WithDetachLease, dst is created without the lease of src.
+//
+// A move operation cannot be part of a transaction; a transaction holding one
+// fails with ErrMoveInTxn. The OpResponse of a move holds the response of its
+// transaction.
+func OpMove(src, dst string, opts ...OpOption) Op {
+	ret := Op{t: tMove, key: []byte(src), dst: []byte(dst)}
+	ret.applyOpts(opts)
+	switch {
+	case ret.end != nil:
+		panic("unexpected range in move")
+	case ret.rev != 0:
+		panic("unexpected revision in move")
+	case ret.leaseID != 0:
+		panic("unexpected lease in move")
+	}
+	return ret
+}
+
+// WithOverwrite makes a move operation replace its target key if it exists.
+func WithOverwrite() OpOption {
+	return func(op *Op) { op.overwrite = true }
+}
+
+// WithDetachLease makes a move operation create its target key without the
+// lease of its source key.
+func WithDetachLease() OpOption {
+	return func(op *Op) { op.detachLease = true }
+}
+
+// Move runs the move operation op as a transaction of kv. It lets the
+// implementations of KV that wrap another KV run moves through their own
+// transactions.
+func Move(ctx context.Context, kv KV, op Op) (*TxnResponse, error) {
+	if bytes.Equal(op.key, op.dst) {
+		return nil, errMoveSameKey
+	}
+	src, dst := string(op.key), string(op.dst)
+	cmps := []Cmp{Compare(CreateRevision(src), ">", 0)}
+	if !op.overwrite {
+		cmps = append(cmps, Compare(CreateRevision(dst), "=", 0))
+	}
+	putOpts := []OpOption{WithCopyFrom(src)}
+	if !op.detachLease {
+		putOpts = append(putOpts, WithCopyLease())
+	}
+	resp, err := kv.Txn(ctx).If(cmps...).Then(
+		OpPut(dst, "", putOpts...),
+		OpDelete(src),
+	).Else(
+		OpGet(src, WithKeysOnly()),
+	).Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Succeeded {
+		// either src does not exist, or dst does
+		if len(resp.Responses[0].GetResponseRange().Kvs) == 0 {
+			return nil, ErrMoveSourceNotFound
+		}
+		return nil, ErrMoveTargetExists
+	}
+	return resp, nil
+}
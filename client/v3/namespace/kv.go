@@ -122,6 +122,11 @@ func (txn *txnPrefix) Else(ops ...clientv3.Op) clientv3.Txn {
 	return txn
 }
 
+func (txn *txnPrefix) WithDryRun() clientv3.Txn {
+	txn.Txn = txn.Txn.WithDryRun()
+	return txn
+}
+
 func (txn *txnPrefix) Commit() (*clientv3.TxnResponse, error) {
 	resp, err := txn.Txn.Commit()
 	if err != nil {
@@ -131,11 +136,32 @@ func (txn *txnPrefix) Commit() (*clientv3.TxnResponse, error) {
 	return resp, nil
 }
 
+func (txn *txnPrefix) CommitStream() (*clientv3.TxnStream, error) {
+	ts, err := clientv3.CommitStream(txn.Txn)
+	if err != nil {
+		return nil, err
+	}
+	return clientv3.NewTxnStream(ts.Header, ts.Succeeded, func() (*pb.ResponseOp, error) {
+		resp, err := ts.Recv()
+		if err != nil {
+			return nil, err
+		}
+		txn.kv.unprefixTxnResponse(&clientv3.TxnResponse{Responses: []*pb.ResponseOp{resp}})
+		return resp, nil
+	}), nil
+}
+
 func (kv *kvPrefix) prefixOp(op clientv3.Op) clientv3.Op {
 	if !op.IsTxn() {
 		begin, end := kv.prefixInterval(op.KeyBytes(), op.RangeBytes())
 		op.WithKeyBytes(begin)
 		op.WithRangeBytes(end)
+		if op.IsMove() {
+			op.WithDstBytes(kv.prefixKey(op.DstBytes()))
+		}
+		if from := op.CopyFromBytes(); len(from) != 0 {
+			op.WithCopyFromBytes(kv.prefixKey(from))
+		}
 		return op
 	}
 	cmps, thenOps, elseOps := op.Txn()
@@ -188,6 +214,11 @@ func (kv *kvPrefix) prefixInterval(key, end []byte) (pfxKey []byte, pfxEnd []byt
 	return prefixInterval(kv.pfx, key, end)
 }
 
+func (kv *kvPrefix) prefixKey(key []byte) []byte {
+	pfxKey, _ := kv.prefixInterval(key, nil)
+	return pfxKey
+}
+
 func (kv *kvPrefix) prefixCmps(cs []clientv3.Cmp) []clientv3.Cmp {
 	newCmps := make([]clientv3.Cmp, len(cs))
 	for i := range cs {
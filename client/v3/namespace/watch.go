@@ -15,6 +15,7 @@
 package namespace
 
 import (
+	"bytes"
 	"context"
 	"sync"
 
@@ -45,10 +46,32 @@ func (w *watcherPrefix) Watch(ctx context.Context, key string, opts ...clientv3.
 	if pfxEnd != nil {
 		opts = append(opts, clientv3.WithRange(string(pfxEnd)))
 	}
+	if ranges := op.WatchRanges(); len(ranges) != 0 {
+		pfxRanges := make([]clientv3.Op, len(ranges))
+		for i, r := range ranges {
+			begin, end := prefixInterval(w.pfx, r.KeyBytes(), r.RangeBytes())
+			pfxRanges[i] = r
+			pfxRanges[i].WithKeyBytes(begin)
+			pfxRanges[i].WithRangeBytes(end)
+		}
+		opts = append(opts, clientv3.WithWatchRanges(pfxRanges))
+	}
 
-	wch := w.Watcher.Watch(ctx, string(pfxBegin), opts...)
+	return w.unprefix(ctx, w.Watcher.Watch(ctx, string(pfxBegin), opts...))
+}
 
-	// translate watch events from prefixed to unprefixed
+func (w *watcherPrefix) Replay(ctx context.Context, key string, startRev, endRev int64, opts ...clientv3.OpOption) clientv3.WatchChan {
+	op := clientv3.OpGet(key, opts...)
+	end := op.RangeBytes()
+	pfxBegin, pfxEnd := prefixInterval(w.pfx, []byte(key), end)
+	if pfxEnd != nil {
+		opts = append(opts, clientv3.WithRange(string(pfxEnd)))
+	}
+	return w.unprefix(ctx, clientv3.Replay(ctx, w.Watcher, string(pfxBegin), startRev, endRev, opts...))
+}
+
+// unprefix translates watch events from prefixed to unprefixed.
+func (w *watcherPrefix) unprefix(ctx context.Context, wch clientv3.WatchChan) clientv3.WatchChan {
 	pfxWch := make(chan clientv3.WatchResponse)
 	w.wg.Add(1)
 	go func() {
@@ -57,12 +80,22 @@ func (w *watcherPrefix) Watch(ctx context.Context, key string, opts ...clientv3.
 			w.wg.Done()
 		}()
 		for wr := range wch {
-			for i := range wr.Events {
-				wr.Events[i].Kv.Key = wr.Events[i].Kv.Key[len(w.pfx):]
-				if wr.Events[i].PrevKv != nil {
-					wr.Events[i].PrevKv.Key = wr.Events[i].Kv.Key
+			n, evs := len(wr.Events), wr.Events[:0]
+			for _, ev := range wr.Events {
+				if !bytes.HasPrefix(ev.Kv.Key, []byte(w.pfx)) {
+					// outside of the namespace
+					continue
+				}
+				ev.Kv.Key = ev.Kv.Key[len(w.pfx):]
+				if ev.PrevKv != nil {
+					ev.PrevKv.Key = ev.Kv.Key
 				}
+				evs = append(evs, ev)
+			}
+			if n != 0 && len(evs) == 0 {
+				continue
 			}
+			wr.Events = evs
 			select {
 			case pfxWch <- wr:
 			case <-ctx.Done():
@@ -14,7 +14,12 @@
 
 package clientv3
 
-import pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+import (
+	"errors"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
 
 type opType int
 
@@ -24,10 +29,16 @@ const (
 	tPut
 	tDeleteRange
 	tTxn
+	tMove
+	tCompactKey
 )
 
 var noPrefixEnd = []byte{0}
 
+// ErrCompactKeyInTxn is returned by the transactions holding a compact key
+// operation.
+var ErrCompactKeyInTxn = errors.New("etcdclient: compact key operation in a transaction")
+
 // Op represents an Operation that kv can execute.
 type Op struct {
 	t   opType
@@ -44,6 +55,7 @@ type Op struct {
 	maxModRev    int64
 	minCreateRev int64
 	maxCreateRev int64
+	reverse      bool
 
 	// for range, watch
 	rev int64
@@ -58,16 +70,34 @@ type Op struct {
 	fragment bool
 
 	// for put
-	ignoreValue bool
-	ignoreLease bool
+	ignoreValue        bool
+	ignoreLease        bool
+	leaseTTLInResponse bool
+	ttl                int64
+	copyFrom           []byte
+	copyLease          bool
 
 	// progressNotify is for progress updates.
 	progressNotify bool
+	// caughtUpNotify is for the caught up notification.
+	caughtUpNotify bool
+	// autoResumeFromCompaction re-establishes the watch after its revision is compacted.
+	autoResumeFromCompaction bool
+	// maxBatch and maxBatchWait coalesce watch events into batches.
+	maxBatch     int
+	maxBatchWait time.Duration
+	// coalesceWindow makes the server coalesce the events of each key.
+	coalesceWindow time.Duration
+	// watchRanges are the ranges watched in addition to the key of a watch.
+	watchRanges []Op
+	// globalOrder merges the events of the watch ranges in revision order.
+	globalOrder bool
 	// createdNotify is for created event
 	createdNotify bool
 	// filters for watchers
 	filterPut    bool
 	filterDelete bool
+	filterValue  ValueFilter
 
 	// for put
 	val     []byte
@@ -80,6 +110,14 @@ type Op struct {
 
 	isOptsWithFromKey bool
 	isOptsWithPrefix  bool
+
+	// for move
+	dst         []byte
+	overwrite   bool
+	detachLease bool
+
+	// for compact key
+	keep int64
 }
 
 // accessors / mutators
@@ -124,6 +162,9 @@ func (op Op) IsKeysOnly() bool { return op.keysOnly }
 // IsCountOnly returns whether countOnly is set.
 func (op Op) IsCountOnly() bool { return op.countOnly }
 
+// IsReverse returns whether reverse is set.
+func (op Op) IsReverse() bool { return op.reverse }
+
 func (op Op) IsOptsWithFromKey() bool { return op.isOptsWithFromKey }
 
 func (op Op) IsOptsWithPrefix() bool { return op.isOptsWithPrefix }
@@ -143,6 +184,30 @@ func (op Op) MaxCreateRev() int64 { return op.maxCreateRev }
 // WithRangeBytes sets the byte slice for the Op's range end.
 func (op *Op) WithRangeBytes(end []byte) { op.end = end }
 
+// IsMove returns true if the "Op" type is move.
+func (op Op) IsMove() bool { return op.t == tMove }
+
+// IsCompactKey returns true if the "Op" type is compact key.
+func (op Op) IsCompactKey() bool { return op.t == tCompactKey }
+
+// DstBytes returns the byte slice holding the target key of a move operation.
+func (op Op) DstBytes() []byte { return op.dst }
+
+// WithDstBytes sets the byte slice for the target key of a move operation.
+func (op *Op) WithDstBytes(dst []byte) { op.dst = dst }
+
+// CopyFromBytes returns the byte slice holding the key whose value a put
+// operation copies, if any.
+func (op Op) CopyFromBytes() []byte { return op.copyFrom }
+
+// WithCopyFromBytes sets the byte slice for the key whose value a put
+// operation copies.
+func (op *Op) WithCopyFromBytes(key []byte) { op.copyFrom = key }
+
+// WatchRanges returns the ranges given by WithWatchRange. Their keys and range
+// ends are accessed with KeyBytes and RangeBytes.
+func (op Op) WatchRanges() []Op { return op.watchRanges }
+
 // ValueBytes returns the byte slice holding the Op's value, if any.
 func (op Op) ValueBytes() []byte { return op.val }
 
@@ -165,9 +230,20 @@ func (op Op) toRangeRequest() *pb.RangeRequest {
 		MaxModRevision:    op.maxModRev,
 		MinCreateRevision: op.minCreateRev,
 		MaxCreateRevision: op.maxCreateRev,
+		Reverse:           op.reverse,
 	}
 	if op.sort != nil {
-		r.SortOrder = pb.RangeRequest_SortOrder(op.sort.Order)
+		order := op.sort.Order
+		if op.sort.Target == SortByKey && order == SortAscend {
+			// If order != SortNone, server fetches the entire key-space,
+			// and then applies the sort and limit, if provided.
+			// Since by default the server returns results sorted by keys
+			// in lexicographically ascending order, the client should ignore
+			// SortOrder if the target is SortByKey, and must not reverse the keys.
+			order = SortNone
+			r.Reverse = false
+		}
+		r.SortOrder = pb.RangeRequest_SortOrder(order)
 		r.SortTarget = pb.RangeRequest_SortTarget(op.sort.Target)
 	}
 	return r
@@ -194,7 +270,7 @@ func (op Op) toRequestOp() *pb.RequestOp {
 	case tRange:
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: op.toRangeRequest()}}
 	case tPut:
-		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease}
+		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease, Ttl: op.ttl, CopyFrom: op.copyFrom, CopyLease: op.copyLease}
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: r}}
 	case tDeleteRange:
 		r := &pb.DeleteRangeRequest{Key: op.key, RangeEnd: op.end, PrevKv: op.prevKV}
@@ -259,11 +335,13 @@ func OpDelete(key string, opts ...OpOption) Op {
 		panic("unexpected serializable in delete")
 	case ret.countOnly:
 		panic("unexpected countOnly in delete")
+	case ret.reverse:
+		panic("unexpected reverse in delete")
 	case ret.minModRev != 0, ret.maxModRev != 0:
 		panic("unexpected mod revision filter in delete")
 	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
 		panic("unexpected create revision filter in delete")
-	case ret.filterDelete, ret.filterPut:
+	case ret.filterDelete, ret.filterPut, ret.filterValue != ValueFilter{}:
 		panic("unexpected filter in delete")
 	case ret.createdNotify:
 		panic("unexpected createdNotify in delete")
@@ -292,7 +370,7 @@ func OpPut(key, val string, opts ...OpOption) Op {
 		panic("unexpected mod revision filter in put")
 	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
 		panic("unexpected create revision filter in put")
-	case ret.filterDelete, ret.filterPut:
+	case ret.filterDelete, ret.filterPut, ret.filterValue != ValueFilter{}:
 		panic("unexpected filter in put")
 	case ret.createdNotify:
 		panic("unexpected createdNotify in put")
@@ -305,6 +383,17 @@ func OpTxn(cmps []Cmp, thenOps []Op, elseOps []Op) Op {
 	return Op{t: tTxn, cmps: cmps, thenOps: thenOps, elseOps: elseOps}
 }
 
+// OpCompactKey returns an operation compacting the history of the key,
+// keeping only its keep most recent revisions. Reading the key at an older
+// revision fails with ErrCompacted, while other keys are not affected. The
+// compaction generates no event.
+//
+// A compact key operation cannot be part of a transaction: a transaction
+// holding one fails with ErrCompactKeyInTxn.
+func OpCompactKey(key string, keep int64) Op {
+	return Op{t: tCompactKey, key: []byte(key), keep: keep}
+}
+
 func opWatch(key string, opts ...OpOption) Op {
 	ret := Op{t: tRange, key: []byte(key)}
 	ret.applyOpts(opts)
@@ -319,10 +408,16 @@ func opWatch(key string, opts ...OpOption) Op {
 		panic("unexpected serializable in watch")
 	case ret.countOnly:
 		panic("unexpected countOnly in watch")
+	case ret.reverse:
+		panic("unexpected reverse in watch")
 	case ret.minModRev != 0, ret.maxModRev != 0:
 		panic("unexpected mod revision filter in watch")
 	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
 		panic("unexpected create revision filter in watch")
+	case ret.maxBatch < 0, ret.maxBatch > 0 && ret.maxBatchWait <= 0:
+		panic("unexpected max batch in watch")
+	case ret.coalesceWindow < 0:
+		panic("unexpected coalesce window in watch")
 	}
 	return ret
 }
@@ -355,14 +450,6 @@ func WithRev(rev int64) OpOption { return func(op *Op) { op.rev = rev } }
 // 'order' can be either 'SortNone', 'SortAscend', 'SortDescend'.
 func WithSort(target SortTarget, order SortOrder) OpOption {
 	return func(op *Op) {
-		if target == SortByKey && order == SortAscend {
-			// If order != SortNone, server fetches the entire key-space,
-			// and then applies the sort and limit, if provided.
-			// Since by default the server returns results sorted by keys
-			// in lexicographically ascending order, the client should ignore
-			// SortOrder if the target is SortByKey.
-			order = SortNone
-		}
 		op.sort = &SortOption{target, order}
 	}
 }
@@ -441,6 +528,22 @@ func WithKeysOnly() OpOption {
 	return func(op *Op) { op.keysOnly = true }
 }
 
+// WithReverse makes the 'Get' request return the keys in descending order.
+// The server walks the range backward, so combined with 'WithLimit' it reads
+// only the last keys of the range instead of sorting the entire range like
+// 'WithSort(SortByKey, SortDescend)'. The next page of a range ends at the
+// last key returned, for example:
+//
+//	resp, err := kv.Get(ctx, "a", WithFromKey(), WithReverse(), WithLimit(100))
+//	// next page
+//	last := resp.Kvs[len(resp.Kvs)-1].Key
+//	resp, err = kv.Get(ctx, "a", WithRange(string(last)), WithReverse(), WithLimit(100))
+//
+// A sort order set by 'WithSort' takes precedence over 'WithReverse'.
+func WithReverse() OpOption {
+	return func(op *Op) { op.reverse = true }
+}
+
 // WithCountOnly makes the 'Get' request return only the count of keys.
 func WithCountOnly() OpOption {
 	return func(op *Op) { op.countOnly = true }
@@ -490,6 +593,93 @@ func WithProgressNotify() OpOption {
 	}
 }
 
+// WithProgressNotifyOnCreate makes watch server send a single WatchResponse
+// without events once the watcher has delivered all events up to the store
+// revision at the time it was created. The response is recognized by
+// WatchResponse.IsCaughtUp.
+func WithProgressNotifyOnCreate() OpOption {
+	return func(op *Op) {
+		op.caughtUpNotify = true
+	}
+}
+
+// WithAutoResumeFromCompaction makes the watcher re-establish the watch from the
+// compacted revision instead of canceling it when its start revision has been
+// compacted away. A WatchResponse recognized by WatchResponse.CompactionResumed
+// is sent in place of the compaction error, so the subscriber can rebuild any
+// state that depended on the events it missed.
+func WithAutoResumeFromCompaction() OpOption {
+	return func(op *Op) {
+		op.autoResumeFromCompaction = true
+	}
+}
+
+// WithMaxBatch makes the watcher coalesce the events of consecutive watch
+// responses into responses of up to n events, which are delivered once full or
+// maxWait after their first event was received. The events of one revision are
+// never split across responses, so a revision with more than n events is
+// delivered in a larger response of its own. Responses other than events, such
+// as progress notifications or cancelations, deliver the pending events first.
+// The events are batched by the client; the server is not affected.
+func WithMaxBatch(n int, maxWait time.Duration) OpOption {
+	return func(op *Op) {
+		op.maxBatch = n
+		op.maxBatchWait = maxWait
+	}
+}
+
+// WithCoalesce makes the watch server hold the events of the watcher for the
+// given window and then send only the latest PUT event of each key, dropping
+// the intermediate revisions of keys updated again within the window. DELETE
+// events are never dropped, and the response reports the revision of the last
+// event held. If previous key-value pairs are requested, the previous
+// key-value of a coalesced event is the one before the window. The window is
+// rounded down to milliseconds, and clamped to 10 seconds by the server, which
+// also sends the events early once it holds 1000 of them. Supported since etcd
+// 3.6.
+func WithCoalesce(window time.Duration) OpOption {
+	return func(op *Op) { op.coalesceWindow = window }
+}
+
+// WithWatchRange makes a watch also watch key, or the range given by opts
+// such as WithPrefix, WithRange and WithFromKey, so that a single watch
+// reports the events of several key ranges. The filters WithFilterPut and
+// WithFilterDelete in opts apply to the events of that range only. The events
+// of the watch have RangeIndex set to the index of the range they matched: 0
+// for the key passed to Watch, and i for the i-th WithWatchRange. An event
+// matched by several ranges is reported once. Supported since etcd 3.6.
+func WithWatchRange(key string, opts ...OpOption) OpOption {
+	r := Op{key: []byte(key)}
+	r.applyOpts(opts)
+	return func(op *Op) { op.watchRanges = append(op.watchRanges, r) }
+}
+
+// WithWatchRanges replaces the ranges given by WithWatchRange with ranges, as
+// returned by WatchRanges. It lets the implementations of Watcher that wrap
+// another Watcher rewrite the ranges of a watch.
+func WithWatchRanges(ranges []Op) OpOption {
+	return func(op *Op) { op.watchRanges = ranges }
+}
+
+// WithGlobalOrder makes a watch on several ranges, given by WithWatchRange,
+// watch each range with a watch of its own and merge their events in revision
+// order. The ranges are watched on a grpc stream of their own, so that their
+// progress requests reach no other watcher. Without WithRev, Watch first reads
+// the current revision with a linearizable Get, blocking until it returns, and
+// every range is watched from the revision after it. The events of a range are
+// held until every other range has reported progress up to their revision, and
+// the progress of the ranges is requested while events are held. A range whose watcher falls behind thus holds back
+// the events of the others: once more than GlobalOrderMaxBuffered events are
+// held, the events that can be ordered are delivered and the watch is canceled
+// with ErrGlobalOrderBufferFull, so that it can be resumed from the revision
+// after the last event received. Progress notifications are sent for the
+// lowest progress of the ranges, once no event is held. The events have
+// RangeIndex set as for a watch on several ranges. A watch on a single range
+// delivers its events in revision order without this option.
+func WithGlobalOrder() OpOption {
+	return func(op *Op) { op.globalOrder = true }
+}
+
 // WithCreatedNotify makes watch server sends the created event.
 func WithCreatedNotify() OpOption {
 	return func(op *Op) {
@@ -507,6 +697,38 @@ func WithFilterDelete() OpOption {
 	return func(op *Op) { op.filterDelete = true }
 }
 
+// ValueFilter is a predicate on the values of the PUT events of a watcher,
+// evaluated by the server. Since functions cannot be sent to the server,
+// only the predicates returned by the ValueFilter constructors are supported.
+type ValueFilter struct {
+	t      pb.WatchCreateRequest_ValueFilterType
+	prefix string
+}
+
+// CreateOnly returns a ValueFilter matching the PUT events that create a key.
+func CreateOnly() ValueFilter {
+	return ValueFilter{t: pb.WatchCreateRequest_CREATE_ONLY}
+}
+
+// ValuePrefix returns a ValueFilter matching the PUT events with a value
+// starting with the given prefix.
+func ValuePrefix(prefix string) ValueFilter {
+	return ValueFilter{t: pb.WatchCreateRequest_VALUE_PREFIX, prefix: prefix}
+}
+
+// ValueChanged returns a ValueFilter matching the PUT events that create a
+// key or change its value, discarding the PUT events rewriting the same value.
+func ValueChanged() ValueFilter {
+	return ValueFilter{t: pb.WatchCreateRequest_VALUE_CHANGED}
+}
+
+// WithFilterValue discards the PUT events not matching the given ValueFilter
+// from the watcher. DELETE events are not affected; use WithFilterDelete to
+// discard them. Supported since etcd 3.6.
+func WithFilterValue(f ValueFilter) OpOption {
+	return func(op *Op) { op.filterValue = f }
+}
+
 // WithPrevKV gets the previous key-value pair before the event happens. If the previous KV is already compacted,
 // nothing will be returned.
 func WithPrevKV() OpOption {
@@ -544,12 +766,62 @@ func WithIgnoreLease() OpOption {
 	}
 }
 
+// WithCopyFrom puts the value key has before the put, or before the
+// transaction holding it, is applied, instead of the value given to the put.
+// Returns an error if key does not exist.
+// This option can not be combined with WithIgnoreValue.
+func WithCopyFrom(key string) OpOption {
+	return func(op *Op) {
+		op.copyFrom = []byte(key)
+	}
+}
+
+// WithCopyLease attaches the key put with WithCopyFrom to the lease of the
+// copied key. This option can not be combined with WithLease, WithIgnoreLease
+// or WithTTL.
+func WithCopyLease() OpOption {
+	return func(op *Op) {
+		op.copyLease = true
+	}
+}
+
+// WithLeaseTTLInResponse makes Put return the remaining TTL of the lease
+// attached by WithLease in PutResponse.LeaseTtl. The put fails with
+// rpctypes.ErrLeaseNotFound if the lease is revoked before the put is
+// applied, and a TTL of -1 is returned if the lease expired or was revoked
+// right after the put was applied.
+// It is ignored for puts within transactions.
+func WithLeaseTTLInResponse() OpOption {
+	return func(op *Op) {
+		op.leaseTTLInResponse = true
+	}
+}
+
+// WithTTL makes Put attach the key to a lease granted by the server to expire
+// the key after ttl seconds, which the server returns in PutResponse.Lease.
+// Keys put with the same TTL around the same time share a lease, so a key
+// may expire up to a tenth of the TTL late, or a second for a TTL under 10s.
+// Putting the key again detaches it from the lease.
+// This option can not be combined with WithLease or WithIgnoreLease, nor be
+// used for puts within transactions.
+func WithTTL(ttl int64) OpOption {
+	return func(op *Op) {
+		op.ttl = ttl
+	}
+}
+
 // LeaseOp represents an Operation that lease can execute.
 type LeaseOp struct {
 	id LeaseID
 
-	// for TimeToLive
+	// for TimeToLive and LeaseListDetailed
 	attachedKeys bool
+
+	// for KeepAlive
+	minKeepAliveTTL time.Duration
+
+	// for Reattach
+	shorterTTL bool
 }
 
 // LeaseOption configures lease operations.
@@ -561,17 +833,43 @@ func (op *LeaseOp) applyOpts(opts []LeaseOption) {
 	}
 }
 
-// WithAttachedKeys makes TimeToLive list the keys attached to the given lease ID.
+// WithAttachedKeys makes TimeToLive list the keys attached to the given lease ID,
+// and LeaseListDetailed list the keys attached to each lease.
 func WithAttachedKeys() LeaseOption {
 	return func(op *LeaseOp) { op.attachedKeys = true }
 }
 
+// WithMinKeepAliveTTL makes KeepAliveWithOptions post a response with an
+// ErrKeepAliveTTLTooShort error and close the channel if the server grants
+// the lease a TTL shorter than ttl.
+func WithMinKeepAliveTTL(ttl time.Duration) LeaseOption {
+	return func(op *LeaseOp) { op.minKeepAliveTTL = ttl }
+}
+
+// WithShorterTTL makes Reattach move the keys even if the new lease expires
+// before the old one.
+func WithShorterTTL() LeaseOption {
+	return func(op *LeaseOp) { op.shorterTTL = true }
+}
+
+func toLeaseReattachRequest(oldID, newID LeaseID, opts ...LeaseOption) *pb.LeaseReattachRequest {
+	ret := &LeaseOp{id: oldID}
+	ret.applyOpts(opts)
+	return &pb.LeaseReattachRequest{OldID: int64(oldID), NewID: int64(newID), AllowShorterTTL: ret.shorterTTL}
+}
+
 func toLeaseTimeToLiveRequest(id LeaseID, opts ...LeaseOption) *pb.LeaseTimeToLiveRequest {
 	ret := &LeaseOp{id: id}
 	ret.applyOpts(opts)
 	return &pb.LeaseTimeToLiveRequest{ID: int64(id), Keys: ret.attachedKeys}
 }
 
+func toLeaseListDetailedRequest(opts ...LeaseOption) *pb.LeaseListDetailedRequest {
+	ret := &LeaseOp{}
+	ret.applyOpts(opts)
+	return &pb.LeaseListDetailedRequest{Keys: ret.attachedKeys}
+}
+
 // IsOptsWithPrefix returns true if WithPrefix option is called in the given opts.
 func IsOptsWithPrefix(opts []OpOption) bool {
 	ret := NewOp()
@@ -84,6 +84,7 @@ func (kv *kvOrdering) Txn(ctx context.Context) clientv3.Txn {
 		[]clientv3.Cmp{},
 		[]clientv3.Op{},
 		[]clientv3.Op{},
+		false,
 	}
 }
 
@@ -98,6 +99,7 @@ type txnOrdering struct {
 	cmps    []clientv3.Cmp
 	thenOps []clientv3.Op
 	elseOps []clientv3.Op
+	dryRun  bool
 }
 
 func (txn *txnOrdering) If(cs ...clientv3.Cmp) clientv3.Txn {
@@ -124,6 +126,14 @@ func (txn *txnOrdering) Else(ops ...clientv3.Op) clientv3.Txn {
 	return txn
 }
 
+func (txn *txnOrdering) WithDryRun() clientv3.Txn {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.dryRun = true
+	txn.Txn.WithDryRun()
+	return txn
+}
+
 func (txn *txnOrdering) Commit() (*clientv3.TxnResponse, error) {
 	// prevRev is stored in a local variable in order to record the prevRev
 	// at the beginning of the Commit operation, because concurrent
@@ -131,8 +141,19 @@ func (txn *txnOrdering) Commit() (*clientv3.TxnResponse, error) {
 	// middle of the Commit operation.
 	prevRev := txn.getPrevRev()
 	opTxn := clientv3.OpTxn(txn.cmps, txn.thenOps, txn.elseOps)
+	commit := func() (clientv3.OpResponse, error) { return txn.KV.Do(txn.ctx, opTxn) }
+	if txn.dryRun {
+		// an OpTxn cannot be a dry run
+		commit = func() (clientv3.OpResponse, error) {
+			resp, err := txn.Txn.Commit()
+			if err != nil {
+				return clientv3.OpResponse{}, err
+			}
+			return resp.OpResponse(), nil
+		}
+	}
 	for {
-		opResp, err := txn.KV.Do(txn.ctx, opTxn)
+		opResp, err := commit()
 		if err != nil {
 			return nil, err
 		}
@@ -147,3 +168,31 @@ func (txn *txnOrdering) Commit() (*clientv3.TxnResponse, error) {
 		}
 	}
 }
+
+func (txn *txnOrdering) CommitStream() (*clientv3.TxnStream, error) {
+	// the header arrives before any operation response, so the order is
+	// checked before the caller receives anything.
+	prevRev := txn.getPrevRev()
+	for {
+		t := txn.KV.Txn(txn.ctx).If(txn.cmps...).Then(txn.thenOps...).Else(txn.elseOps...)
+		if txn.dryRun {
+			t = t.WithDryRun()
+		}
+		ts, err := clientv3.CommitStream(t)
+		if err != nil {
+			return nil, err
+		}
+		if ts.Header.Revision >= prevRev {
+			txn.setPrevRev(ts.Header.Revision)
+			return ts, nil
+		}
+		txnResp, err := ts.Response()
+		if err != nil {
+			return nil, err
+		}
+		err = txn.orderViolationFunc(clientv3.OpTxn(txn.cmps, txn.thenOps, txn.elseOps), txnResp.OpResponse(), prevRev)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
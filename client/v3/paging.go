@@ -0,0 +1,121 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+var errInvalidPageSize = errors.New("etcdclient: page size must be positive")
+
+// PagedScanCompactedError is returned by a Pager when the revision pinned for
+// the scan is compacted before the scan completes.
+type PagedScanCompactedError struct {
+	// Revision is the revision pinned for the scan.
+	Revision int64
+	// NextKey is the key the next page would have started from. Scanning can
+	// be resumed from it at a newer revision.
+	NextKey string
+}
+
+func (e *PagedScanCompactedError) Error() string {
+	return fmt.Sprintf("etcdclient: revision %d of paged scan has been compacted", e.Revision)
+}
+
+// Unwrap returns rpctypes.ErrCompacted.
+func (e *PagedScanCompactedError) Unwrap() error { return rpctypes.ErrCompacted }
+
+// Pager iterates over the keys with a given prefix page by page. All pages
+// are read at the revision of the first page, so that the scan returns each
+// key exactly once even if the keys are modified concurrently.
+//
+//	p := clientv3.GetPaged(ctx, cli, "foo/", 1000)
+//	for p.Next() {
+//		for _, kv := range p.Page() {
+//			...
+//		}
+//	}
+//	if err := p.Err(); err != nil {
+//		...
+//	}
+type Pager struct {
+	ctx      context.Context
+	kv       KV
+	key      string
+	end      string
+	pageSize int64
+
+	rev  int64
+	page []*mvccpb.KeyValue
+	done bool
+	err  error
+}
+
+// GetPaged returns a Pager over the keys with the given prefix, sorted by key,
+// reading at most pageSize keys per request. An empty prefix scans the
+// entire keyspace.
+func GetPaged(ctx context.Context, kv KV, prefix string, pageSize int64) *Pager {
+	p := &Pager{ctx: ctx, kv: kv, key: prefix, end: GetPrefixRangeEnd(prefix), pageSize: pageSize}
+	if len(prefix) == 0 {
+		// the empty key is invalid; start from the smallest key instead
+		p.key = "\x00"
+	}
+	if pageSize <= 0 {
+		p.err, p.done = errInvalidPageSize, true
+	}
+	return p
+}
+
+// Next fetches the next page. It returns false once all keys have been
+// returned or if an error occurred, which is then reported by Err.
+func (p *Pager) Next() bool {
+	if p.done {
+		return false
+	}
+	resp, err := p.kv.Get(p.ctx, p.key, WithRange(p.end), WithLimit(p.pageSize), WithRev(p.rev))
+	if err != nil {
+		if err == rpctypes.ErrCompacted && p.rev != 0 {
+			err = &PagedScanCompactedError{Revision: p.rev, NextKey: p.key}
+		}
+		p.err, p.done, p.page = err, true, nil
+		return false
+	}
+	if p.rev == 0 {
+		p.rev = resp.Header.Revision
+	}
+	p.page = resp.Kvs
+	if !resp.More || len(resp.Kvs) == 0 {
+		p.done = true
+		return len(resp.Kvs) > 0
+	}
+	// the smallest key after the last one of the page
+	p.key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	return true
+}
+
+// Page returns the keys fetched by the last call to Next.
+func (p *Pager) Page() []*mvccpb.KeyValue { return p.page }
+
+// Revision returns the revision the scan is pinned to, or zero before the
+// first page is fetched.
+func (p *Pager) Revision() int64 { return p.rev }
+
+// Err returns the error that stopped the scan, if any.
+func (p *Pager) Err() error { return p.err }
@@ -0,0 +1,76 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sort"
+)
+
+// PutIfAbsent puts the key with the given value if the key does not exist,
+// including if it was deleted, and reports whether the put happened. The
+// check and the put are a single transaction, so of concurrent calls for the
+// same key exactly one succeeds. The options are those of the put, such as
+// WithLease.
+func PutIfAbsent(ctx context.Context, kv KV, key, val string, opts ...OpOption) (bool, error) {
+	return putIf(ctx, kv, Compare(CreateRevision(key), "=", 0), key, val, opts)
+}
+
+// PutIfEqual puts the key with the given value if the version of the key is
+// expectedVersion, and reports whether the put happened. The version of a
+// key is the number of times it was put since it was created, so a version of
+// 0 expects the key not to exist. The options are those of the put, such as
+// WithLease.
+func PutIfEqual(ctx context.Context, kv KV, key, val string, expectedVersion int64, opts ...OpOption) (bool, error) {
+	return putIf(ctx, kv, Compare(Version(key), "=", expectedVersion), key, val, opts)
+}
+
+// CompareAndSwapMany puts the keys of writes with their values if the
+// version of every key of expected is its expected version, and reports
+// whether the puts happened. As with PutIfEqual, a version of 0 expects the
+// key not to exist. The comparisons and the puts are a single transaction, so
+// either all the keys are written or, if any version does not match, none.
+func CompareAndSwapMany(ctx context.Context, kv KV, expected map[string]int64, writes map[string]string) (bool, error) {
+	cmps := make([]Cmp, 0, len(expected))
+	for _, key := range sortedKeys(expected) {
+		cmps = append(cmps, Compare(Version(key), "=", expected[key]))
+	}
+	ops := make([]Op, 0, len(writes))
+	for _, key := range sortedKeys(writes) {
+		ops = append(ops, OpPut(key, writes[key]))
+	}
+	resp, err := kv.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func putIf(ctx context.Context, kv KV, cmp Cmp, key, val string, opts []OpOption) (bool, error) {
+	resp, err := kv.Txn(ctx).If(cmp).Then(OpPut(key, val, opts...)).Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"go.etcd.io/etcd/client/v3/internal/resolver"
+)
+
+var (
+	// readLeaderRefreshInterval is the interval to look up the leader for
+	// read load balancing.
+	readLeaderRefreshInterval = 5 * time.Second
+	// readLeaderRefreshTimeout is the timeout of a leader look up.
+	readLeaderRefreshTimeout = 5 * time.Second
+
+	errReadLeaderUnknown = errors.New("etcdclient: no leader in the member list")
+)
+
+// dialReadBalancer dials the connection serving the serializable range
+// requests. It balances over all endpoints until the leader is known.
+func (c *Client) dialReadBalancer() error {
+	c.readResolver = resolver.New(c.Endpoints()...)
+	creds := c.credentialsForEndpoint(c.Endpoints()[0])
+	conn, err := c.dial(creds, grpc.WithResolvers(c.readResolver))
+	if err != nil {
+		c.readResolver.Close()
+		c.readResolver = nil
+		return err
+	}
+	c.readConn = conn
+	return nil
+}
+
+// readEndpoints returns the endpoints that do not belong to the leader, or
+// all endpoints if the leader is unknown or serves all of them.
+func (c *Client) readEndpoints() []string {
+	var eps []string
+	for _, ep := range c.endpoints {
+		if _, ok := c.readLeaderEndpoints[ep]; !ok {
+			eps = append(eps, ep)
+		}
+	}
+	if len(eps) == 0 {
+		return c.endpoints
+	}
+	return eps
+}
+
+func (c *Client) refreshReadLeader() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(readLeaderRefreshInterval):
+			ctx, cancel := context.WithTimeout(c.ctx, readLeaderRefreshTimeout)
+			c.updateReadLeader(ctx)
+			cancel()
+		}
+	}
+}
+
+// updateReadLeader looks up the leader and routes the serializable range
+// requests to the other endpoints. It routes them to all endpoints if the
+// leader cannot be found.
+func (c *Client) updateReadLeader(ctx context.Context) {
+	eps, err := c.leaderEndpoints(ctx)
+	if err != nil && err != c.ctx.Err() {
+		c.lg.Info("Looking up leader for read load balancing failed.", zap.Error(err))
+	}
+
+	c.epMu.Lock()
+	defer c.epMu.Unlock()
+	c.readLeaderEndpoints = eps
+	c.readResolver.SetEndpoints(c.readEndpoints())
+}
+
+// leaderEndpoints returns the endpoints served by the leader, which must be
+// in the member list.
+func (c *Client) leaderEndpoints(ctx context.Context) (map[string]struct{}, error) {
+	mresp, err := c.MemberList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var leader uint64
+	eps := make(map[string]struct{})
+	for _, ep := range c.Endpoints() {
+		resp, err := c.Maintenance.Status(ctx, ep)
+		if err != nil {
+			continue
+		}
+		if resp.Header.MemberId == resp.Leader {
+			eps[ep] = struct{}{}
+		}
+		if resp.Leader != 0 {
+			leader = resp.Leader
+		}
+	}
+	for _, m := range mresp.Members {
+		if m.ID == leader {
+			return eps, nil
+		}
+	}
+	return nil, errReadLeaderUnknown
+}
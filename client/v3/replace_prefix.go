@@ -0,0 +1,71 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// ReplacePrefix replaces the keys with the given prefix by the keys of kvs
+// with their values, in a single transaction: the keys not in kvs are deleted
+// and the keys of kvs are put, so that a reader or a watcher sees either all
+// the old keys or all the new ones, never an empty prefix. The options are
+// those of the puts; without WithLease, the keys put are detached from their
+// leases.
+//
+// The transaction deletes the ranges between the keys of kvs, so it holds up
+// to 2*len(kvs)+1 operations, and fails with rpctypes.ErrTooManyOps if this
+// exceeds the --max-txn-ops of the server. All the keys of kvs must have the
+// prefix.
+func ReplacePrefix(ctx context.Context, kv KV, prefix string, kvs map[string]string, opts ...OpOption) (*TxnResponse, error) {
+	keys := sortedKeys(kvs)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			return nil, fmt.Errorf("etcdclient: key %q does not have the replaced prefix %q", key, prefix)
+		}
+	}
+
+	start := prefix
+	if start == "" {
+		start = "\x00"
+	}
+	var ops []Op
+	deleteRange := func(end string) {
+		if start < end {
+			ops = append(ops, OpDelete(start, WithRange(end)))
+		}
+	}
+	for _, key := range keys {
+		deleteRange(key)
+		ops = append(ops, OpPut(key, kvs[key], opts...))
+		start = key + "\x00"
+	}
+	if end := GetPrefixRangeEnd(prefix); end == "\x00" {
+		ops = append(ops, OpDelete(start, WithFromKey()))
+	} else {
+		deleteRange(end)
+	}
+
+	resp, err := kv.Txn(ctx).Then(ops...).Commit()
+	if errors.Is(err, rpctypes.ErrTooManyOps) {
+		return nil, fmt.Errorf("etcdclient: replacing prefix %q by %d keys takes %d operations: %w", prefix, len(keys), len(ops), err)
+	}
+	return resp, err
+}
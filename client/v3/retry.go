@@ -117,10 +117,18 @@ func (rkv *retryKVClient) Txn(ctx context.Context, in *pb.TxnRequest, opts ...gr
 	return rkv.kc.Txn(ctx, in, opts...)
 }
 
+func (rkv *retryKVClient) TxnStream(ctx context.Context, in *pb.TxnRequest, opts ...grpc.CallOption) (stream pb.KV_TxnStreamClient, err error) {
+	return rkv.kc.TxnStream(ctx, in, opts...)
+}
+
 func (rkv *retryKVClient) Compact(ctx context.Context, in *pb.CompactionRequest, opts ...grpc.CallOption) (resp *pb.CompactionResponse, err error) {
 	return rkv.kc.Compact(ctx, in, opts...)
 }
 
+func (rkv *retryKVClient) CompactKey(ctx context.Context, in *pb.CompactKeyRequest, opts ...grpc.CallOption) (resp *pb.CompactKeyResponse, err error) {
+	return rkv.kc.CompactKey(ctx, in, opts...)
+}
+
 type retryLeaseClient struct {
 	lc pb.LeaseClient
 }
@@ -140,10 +148,22 @@ func (rlc *retryLeaseClient) LeaseLeases(ctx context.Context, in *pb.LeaseLeases
 	return rlc.lc.LeaseLeases(ctx, in, append(opts, withRetryPolicy(repeatable))...)
 }
 
+func (rlc *retryLeaseClient) LeaseListDetailed(ctx context.Context, in *pb.LeaseListDetailedRequest, opts ...grpc.CallOption) (resp *pb.LeaseListDetailedResponse, err error) {
+	return rlc.lc.LeaseListDetailed(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
 func (rlc *retryLeaseClient) LeaseGrant(ctx context.Context, in *pb.LeaseGrantRequest, opts ...grpc.CallOption) (resp *pb.LeaseGrantResponse, err error) {
 	return rlc.lc.LeaseGrant(ctx, in, append(opts, withRetryPolicy(repeatable))...)
 }
 
+func (rlc *retryLeaseClient) LeaseGrantBatch(ctx context.Context, in *pb.LeaseGrantBatchRequest, opts ...grpc.CallOption) (resp *pb.LeaseGrantBatchResponse, err error) {
+	return rlc.lc.LeaseGrantBatch(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rlc *retryLeaseClient) LeaseReattach(ctx context.Context, in *pb.LeaseReattachRequest, opts ...grpc.CallOption) (resp *pb.LeaseReattachResponse, err error) {
+	return rlc.lc.LeaseReattach(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
 func (rlc *retryLeaseClient) LeaseRevoke(ctx context.Context, in *pb.LeaseRevokeRequest, opts ...grpc.CallOption) (resp *pb.LeaseRevokeResponse, err error) {
 	return rlc.lc.LeaseRevoke(ctx, in, append(opts, withRetryPolicy(repeatable))...)
 }
@@ -183,6 +203,10 @@ func (rcc *retryClusterClient) MemberPromote(ctx context.Context, in *pb.MemberP
 	return rcc.cc.MemberPromote(ctx, in, opts...)
 }
 
+func (rcc *retryClusterClient) MemberReconfigure(ctx context.Context, in *pb.MemberReconfigureRequest, opts ...grpc.CallOption) (resp *pb.MemberReconfigureResponse, err error) {
+	return rcc.cc.MemberReconfigure(ctx, in, opts...)
+}
+
 type retryMaintenanceClient struct {
 	mc pb.MaintenanceClient
 }
@@ -226,6 +250,58 @@ func (rmc *retryMaintenanceClient) Downgrade(ctx context.Context, in *pb.Downgra
 	return rmc.mc.Downgrade(ctx, in, opts...)
 }
 
+func (rmc *retryMaintenanceClient) CompactionStatus(ctx context.Context, in *pb.CompactionStatusRequest, opts ...grpc.CallOption) (resp *pb.CompactionStatusResponse, err error) {
+	return rmc.mc.CompactionStatus(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) HotKeys(ctx context.Context, in *pb.HotKeysRequest, opts ...grpc.CallOption) (resp *pb.HotKeysResponse, err error) {
+	return rmc.mc.HotKeys(ctx, in, opts...)
+}
+
+func (rmc *retryMaintenanceClient) HashKVByRange(ctx context.Context, in *pb.HashKVByRangeRequest, opts ...grpc.CallOption) (resp *pb.HashKVResponse, err error) {
+	return rmc.mc.HashKVByRange(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) SpaceReclaim(ctx context.Context, in *pb.SpaceReclaimRequest, opts ...grpc.CallOption) (resp *pb.SpaceReclaimResponse, err error) {
+	return rmc.mc.SpaceReclaim(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) MemberSelf(ctx context.Context, in *pb.MemberSelfRequest, opts ...grpc.CallOption) (resp *pb.MemberSelfResponse, err error) {
+	return rmc.mc.MemberSelf(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) TriggerSnapshot(ctx context.Context, in *pb.TriggerSnapshotRequest, opts ...grpc.CallOption) (resp *pb.TriggerSnapshotResponse, err error) {
+	return rmc.mc.TriggerSnapshot(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) AuthExport(ctx context.Context, in *pb.AuthExportRequest, opts ...grpc.CallOption) (resp *pb.AuthExportResponse, err error) {
+	return rmc.mc.AuthExport(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) AuthImport(ctx context.Context, in *pb.AuthImportRequest, opts ...grpc.CallOption) (resp *pb.AuthImportResponse, err error) {
+	return rmc.mc.AuthImport(ctx, in, opts...)
+}
+
+func (rmc *retryMaintenanceClient) PrefixQuotaSet(ctx context.Context, in *pb.PrefixQuotaSetRequest, opts ...grpc.CallOption) (resp *pb.PrefixQuotaSetResponse, err error) {
+	return rmc.mc.PrefixQuotaSet(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) PrefixQuotaList(ctx context.Context, in *pb.PrefixQuotaListRequest, opts ...grpc.CallOption) (resp *pb.PrefixQuotaListResponse, err error) {
+	return rmc.mc.PrefixQuotaList(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) VerifyBackend(ctx context.Context, in *pb.VerifyBackendRequest, opts ...grpc.CallOption) (resp *pb.VerifyBackendResponse, err error) {
+	return rmc.mc.VerifyBackend(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) KeyspaceStats(ctx context.Context, in *pb.KeyspaceStatsRequest, opts ...grpc.CallOption) (resp *pb.KeyspaceStatsResponse, err error) {
+	return rmc.mc.KeyspaceStats(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) RebuildIndex(ctx context.Context, in *pb.RebuildIndexRequest, opts ...grpc.CallOption) (resp *pb.RebuildIndexResponse, err error) {
+	return rmc.mc.RebuildIndex(ctx, in, opts...)
+}
+
 type retryAuthClient struct {
 	ac pb.AuthClient
 }
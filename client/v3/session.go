@@ -0,0 +1,117 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+var (
+	// SessionCatchUpTimeout is the maximum time a serializable read of a
+	// session waits for the endpoint to catch up with the session's writes.
+	SessionCatchUpTimeout = 5 * time.Second
+
+	sessionMinRetryInterval = 10 * time.Millisecond
+	sessionMaxRetryInterval = 200 * time.Millisecond
+)
+
+// SessionTimeoutError is returned by a serializable read of a session when
+// the endpoint serving it does not catch up with the last write of the
+// session in time, for example because it is partitioned from the leader.
+type SessionTimeoutError struct {
+	// Revision is the revision of the last write of the session.
+	Revision int64
+	// EndpointRevision is the latest revision the endpoint reported.
+	EndpointRevision int64
+}
+
+func (e *SessionTimeoutError) Error() string {
+	return fmt.Sprintf("etcdclient: endpoint at revision %d did not catch up with session revision %d", e.EndpointRevision, e.Revision)
+}
+
+type sessionKey struct{}
+
+// session records the revision of the last write made with a context
+// returned by WithSession.
+type session struct {
+	rev atomic.Int64
+}
+
+// WithSession returns a context carrying a read-your-writes session. The
+// session records the revision of each write made with the context, and
+// serializable reads made with the context wait until the endpoint serving
+// them has applied that revision, so that they reflect the writes of the
+// session even when served by a lagging follower.
+//
+// A read that cannot catch up before SessionCatchUpTimeout, or the deadline
+// of its context, fails with a *SessionTimeoutError. The context may be
+// shared by concurrent requests and across clients of the same cluster.
+func WithSession(ctx context.Context) context.Context {
+	if sessionFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+func sessionFromContext(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+// observe records the revision of a write response header.
+func (s *session) observe(h *pb.ResponseHeader) {
+	if s == nil || h == nil {
+		return
+	}
+	for {
+		rev := s.rev.Load()
+		if h.Revision <= rev || s.rev.CompareAndSwap(rev, h.Revision) {
+			return
+		}
+	}
+}
+
+// sessionRange serves a serializable range request of a session, retrying it
+// until the endpoint has caught up with the last write of the session.
+func (kv *kv) sessionRange(ctx context.Context, s *session, remote pb.KVClient, r *pb.RangeRequest) (*pb.RangeResponse, error) {
+	rev := s.rev.Load()
+	deadline := time.Now().Add(SessionCatchUpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	wait := sessionMinRetryInterval
+	for {
+		resp, err := remote.Range(ctx, r, kv.callOpts...)
+		if err != nil || resp.Header.Revision >= rev {
+			return resp, err
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return nil, &SessionTimeoutError{Revision: rev, EndpointRevision: resp.Header.Revision}
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if wait *= 2; wait > sessionMaxRetryInterval {
+			wait = sessionMaxRetryInterval
+		}
+	}
+}
@@ -16,13 +16,19 @@ package clientv3
 
 import (
 	"context"
+	"io"
 	"sync"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 
 	"google.golang.org/grpc"
 )
 
+// defaultMaxTxnOps is the default of the server's --max-txn-ops flag, used as
+// Config.MaxTxnOps if unset.
+const defaultMaxTxnOps = 128
+
 // Txn is the interface that wraps mini-transactions.
 //
 //	Txn(context.TODO()).If(
@@ -40,13 +46,24 @@ type Txn interface {
 	If(cs ...Cmp) Txn
 
 	// Then takes a list of operations. The Ops list will be executed, if the
-	// comparisons passed in If() succeed.
+	// comparisons passed in If() succeed. Transactions built with OpTxn can be
+	// passed in to be nested.
 	Then(ops ...Op) Txn
 
 	// Else takes a list of operations. The Ops list will be executed, if the
-	// comparisons passed in If() fail.
+	// comparisons passed in If() fail. Transactions built with OpTxn can be
+	// passed in to be nested.
 	Else(ops ...Op) Txn
 
+	// WithDryRun makes Commit evaluate the comparisons at the current revision
+	// and execute the reads of the chosen branch, including those of nested
+	// transactions, without performing any write or bumping the revision. Puts
+	// report the current revision and deletes the number of keys they would
+	// delete; reads do not observe the writes preceding them. Servers older
+	// than 3.6 would execute the writes, so Commit fails with
+	// ErrDryRunUnsupported if an endpoint of the client runs one.
+	WithDryRun() Txn
+
 	// Commit tries to commit the transaction.
 	Commit() (*TxnResponse, error)
 }
@@ -61,12 +78,16 @@ type txn struct {
 	celse bool
 
 	isWrite bool
+	dryRun  bool
 
 	cmps []*pb.Compare
 
 	sus []*pb.RequestOp
 	fas []*pb.RequestOp
 
+	// err is set if an op passed to Then or Else cannot be part of a txn
+	err error
+
 	callOpts []grpc.CallOption
 }
 
@@ -107,6 +128,9 @@ func (txn *txn) Then(ops ...Op) Txn {
 	}
 
 	txn.cthen = true
+	if txn.err = checkTxnOps(ops); txn.err != nil {
+		return txn
+	}
 
 	for _, op := range ops {
 		txn.isWrite = txn.isWrite || op.isWrite()
@@ -125,6 +149,12 @@ func (txn *txn) Else(ops ...Op) Txn {
 	}
 
 	txn.celse = true
+	if txn.err == nil {
+		txn.err = checkTxnOps(ops)
+	}
+	if txn.err != nil {
+		return txn
+	}
 
 	for _, op := range ops {
 		txn.isWrite = txn.isWrite || op.isWrite()
@@ -134,11 +164,30 @@ func (txn *txn) Else(ops ...Op) Txn {
 	return txn
 }
 
+func (txn *txn) WithDryRun() Txn {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+
+	txn.dryRun = true
+	return txn
+}
+
 func (txn *txn) Commit() (*TxnResponse, error) {
 	txn.mu.Lock()
 	defer txn.mu.Unlock()
 
-	r := &pb.TxnRequest{Compare: txn.cmps, Success: txn.sus, Failure: txn.fas}
+	if txn.err != nil {
+		return nil, txn.err
+	}
+	r := &pb.TxnRequest{Compare: txn.cmps, Success: txn.sus, Failure: txn.fas, DryRun: txn.dryRun}
+	if err := checkTxnRequest(r, 1, txn.kv.maxTxnDepth()); err != nil {
+		return nil, err
+	}
+	if txn.dryRun && txn.kv.client != nil {
+		if err := txn.kv.client.checkDryRun(txn.ctx); err != nil {
+			return nil, toErr(txn.ctx, err)
+		}
+	}
 
 	var resp *pb.TxnResponse
 	var err error
@@ -146,5 +195,89 @@ func (txn *txn) Commit() (*TxnResponse, error) {
 	if err != nil {
 		return nil, toErr(txn.ctx, err)
 	}
+	if txn.isWrite && !txn.dryRun {
+		sessionFromContext(txn.ctx).observe(resp.Header)
+	}
 	return (*TxnResponse)(resp), nil
 }
+
+func (txn *txn) CommitStream() (*TxnStream, error) {
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+
+	if txn.err != nil {
+		return nil, txn.err
+	}
+	r := &pb.TxnRequest{Compare: txn.cmps, Success: txn.sus, Failure: txn.fas, DryRun: txn.dryRun}
+	if err := checkTxnRequest(r, 1, txn.kv.maxTxnDepth()); err != nil {
+		return nil, err
+	}
+
+	stream, err := txn.kv.remote.TxnStream(txn.ctx, r, txn.callOpts...)
+	if err != nil {
+		return nil, toErr(txn.ctx, err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, toErr(txn.ctx, err)
+	}
+	if txn.isWrite && !txn.dryRun {
+		sessionFromContext(txn.ctx).observe(resp.Header)
+	}
+	return NewTxnStream(resp.Header, resp.Succeeded, func() (*pb.ResponseOp, error) {
+		resp, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, toErr(txn.ctx, err)
+		}
+		return resp.Response, nil
+	}), nil
+}
+
+// checkTxnOps returns an error if ops, or the ops of the txns nested in ops,
+// cannot be part of a transaction.
+func checkTxnOps(ops []Op) error {
+	for _, op := range ops {
+		switch op.t {
+		case tMove:
+			return ErrMoveInTxn
+		case tCompactKey:
+			return ErrCompactKeyInTxn
+		case tTxn:
+			if err := checkTxnOps(op.thenOps); err != nil {
+				return err
+			}
+			if err := checkTxnOps(op.elseOps); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkTxnRequest rejects transactions the server would refuse because of their
+// nesting depth or comparisons, before sending them. Every nesting level uses at
+// least one operation out of the server's --max-txn-ops budget, so transactions
+// nested deeper than maxDepth are always rejected by the server.
+func checkTxnRequest(r *pb.TxnRequest, depth, maxDepth int) error {
+	if depth > maxDepth {
+		return rpctypes.ErrTooManyOps
+	}
+	for _, c := range r.Compare {
+		if len(c.Key) == 0 {
+			return rpctypes.ErrEmptyKey
+		}
+	}
+	for _, reqs := range [][]*pb.RequestOp{r.Success, r.Failure} {
+		for _, req := range reqs {
+			if tv, ok := req.Request.(*pb.RequestOp_RequestTxn); ok {
+				if err := checkTxnRequest(tv.RequestTxn, depth+1, maxDepth); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"io"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+// TxnStreamer is implemented by the Txns that can receive the responses of
+// their operations one at a time. See CommitStream.
+type TxnStreamer interface {
+	// CommitStream tries to commit the transaction like Commit, but receives
+	// the responses of the executed operations one at a time instead of in a
+	// single message, so large read-only transactions do not have to be held
+	// in memory at once. The responses of a read-only transaction are all read
+	// at the revision of the header, even if the keys are written while they
+	// are received; Recv fails with ErrCompacted if that revision is compacted
+	// meanwhile. A transaction with writes is applied at once like Commit, and
+	// its responses are held by the server until they are received.
+	CommitStream() (*TxnStream, error)
+}
+
+// CommitStream commits txn with its CommitStream method if it is a
+// TxnStreamer. Otherwise txn is committed with Commit, and the operation
+// responses of the complete response are handed out one at a time.
+func CommitStream(txn Txn) (*TxnStream, error) {
+	if ts, ok := txn.(TxnStreamer); ok {
+		return ts.CommitStream()
+	}
+	resp, err := txn.Commit()
+	if err != nil {
+		return nil, err
+	}
+	resps := resp.Responses
+	return NewTxnStream(resp.Header, resp.Succeeded, func() (*pb.ResponseOp, error) {
+		if len(resps) == 0 {
+			return nil, io.EOF
+		}
+		r := resps[0]
+		resps = resps[1:]
+		return r, nil
+	}), nil
+}
+
+// TxnStream is the response of a transaction committed with CommitStream.
+// The responses of the executed operations are received one at a time with
+// Recv, in the order of the operations.
+type TxnStream struct {
+	// Header is the header of the transaction response.
+	Header *pb.ResponseHeader
+	// Succeeded is true if the comparisons of the transaction succeeded, that
+	// is if the responses belong to the operations passed into Then.
+	Succeeded bool
+
+	recv func() (*pb.ResponseOp, error)
+}
+
+// NewTxnStream returns a TxnStream whose operation responses are received
+// with recv. recv returns io.EOF once all responses have been received. It
+// is meant for implementations of TxnStreamer that wrap another Txn.
+func NewTxnStream(header *pb.ResponseHeader, succeeded bool, recv func() (*pb.ResponseOp, error)) *TxnStream {
+	return &TxnStream{Header: header, Succeeded: succeeded, recv: recv}
+}
+
+// Recv returns the response of the next executed operation. It returns
+// io.EOF once the responses of all operations have been received.
+func (s *TxnStream) Recv() (*pb.ResponseOp, error) {
+	return s.recv()
+}
+
+// Response receives the remaining operation responses and assembles them into
+// the TxnResponse Commit would have returned.
+func (s *TxnStream) Response() (*TxnResponse, error) {
+	resp := &TxnResponse{Header: s.Header, Succeeded: s.Succeeded}
+	for {
+		r, err := s.recv()
+		if err == io.EOF {
+			return resp, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp.Responses = append(resp.Responses, r)
+	}
+}
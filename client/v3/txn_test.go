@@ -19,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/pkg/v3/testutil"
 )
 
@@ -104,3 +105,62 @@ func TestTxnPanics(t *testing.T) {
 		}
 	}
 }
+
+func TestTxnNestedCheck(t *testing.T) {
+	nested := func(depth int, cmp Cmp) Op {
+		op := OpTxn([]Cmp{cmp}, []Op{OpPut("foo", "bar")}, nil)
+		for i := 1; i < depth; i++ {
+			op = OpTxn(nil, []Op{op}, nil)
+		}
+		return op
+	}
+	cmp := Compare(Version("foo"), "=", 0)
+	emptyKeyCmp := Compare(Version(""), "=", 0)
+
+	tests := []struct {
+		name      string
+		maxTxnOps int
+		op        Op
+		err       error
+	}{
+		{
+			name: "too deep in then",
+			op:   OpTxn(nil, []Op{nested(defaultMaxTxnOps, cmp)}, nil),
+			err:  rpctypes.ErrTooManyOps,
+		},
+		{
+			name: "too deep in else",
+			op:   OpTxn(nil, nil, []Op{nested(defaultMaxTxnOps, cmp)}),
+			err:  rpctypes.ErrTooManyOps,
+		},
+		{
+			name:      "too deep for the configured max txn ops",
+			maxTxnOps: 4,
+			op:        OpTxn(nil, []Op{nested(4, cmp)}, nil),
+			err:       rpctypes.ErrTooManyOps,
+		},
+		{
+			name: "empty compare key",
+			op:   OpTxn([]Cmp{emptyKeyCmp}, nil, nil),
+			err:  rpctypes.ErrEmptyKey,
+		},
+		{
+			name: "nested empty compare key",
+			op:   OpTxn(nil, nil, []Op{nested(2, emptyKeyCmp)}),
+			err:  rpctypes.ErrEmptyKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// remote is left unset, rejected transactions must not be sent
+			kv := &kv{maxTxnOps: tt.maxTxnOps}
+			cmps, thenOps, elseOps := tt.op.Txn()
+			if _, err := kv.Txn(context.TODO()).If(cmps...).Then(thenOps...).Else(elseOps...).Commit(); err != tt.err {
+				t.Errorf("Commit: expected %v, got %v", tt.err, err)
+			}
+			if _, err := kv.Do(context.TODO(), tt.op); err != tt.err {
+				t.Errorf("Do: expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
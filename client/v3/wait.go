@@ -0,0 +1,109 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// ValuePredicateKind is the kind of condition a ValuePredicate checks.
+type ValuePredicateKind int
+
+const (
+	// ValueEquals is satisfied by a key whose value is the predicate value.
+	ValueEquals ValuePredicateKind = iota
+	// ValueExists is satisfied by a key that exists, whatever its value.
+	ValueExists
+	// ValueHasPrefix is satisfied by a key whose value starts with the
+	// predicate value.
+	ValueHasPrefix
+)
+
+// ValuePredicate is a condition on a key waited for by WaitForValue.
+type ValuePredicate struct {
+	Kind  ValuePredicateKind
+	Value string
+}
+
+func (p ValuePredicate) match(kv *mvccpb.KeyValue) bool {
+	switch p.Kind {
+	case ValueEquals:
+		return string(kv.Value) == p.Value
+	case ValueExists:
+		return true
+	case ValueHasPrefix:
+		return bytes.HasPrefix(kv.Value, []byte(p.Value))
+	}
+	return false
+}
+
+var errWaitWatchClosed = errors.New("etcdclient: watch closed while waiting for value")
+
+// WaitForValue waits until the key satisfies the predicate and returns the
+// key as it was when it did. If the key already satisfies it, WaitForValue
+// returns immediately; otherwise it watches the key from the revision it was
+// read at, so that no change is missed. If the watched revision is compacted,
+// the key is read again and watched from there.
+//
+// If the context expires first, the returned error wraps the context error,
+// so errors.Is(err, context.DeadlineExceeded) reports a timeout.
+func WaitForValue(ctx context.Context, c *Client, key string, p ValuePredicate) (*mvccpb.KeyValue, error) {
+	for {
+		resp, err := c.Get(ctx, key)
+		if err != nil {
+			return nil, waitForValueErr(ctx, key, err)
+		}
+		if len(resp.Kvs) != 0 && p.match(resp.Kvs[0]) {
+			return resp.Kvs[0], nil
+		}
+		kv, err := waitForValueFrom(ctx, c, key, p, resp.Header.Revision+1)
+		if err == rpctypes.ErrCompacted {
+			continue
+		}
+		if err != nil {
+			return nil, waitForValueErr(ctx, key, err)
+		}
+		return kv, nil
+	}
+}
+
+func waitForValueFrom(ctx context.Context, c *Client, key string, p ValuePredicate, rev int64) (*mvccpb.KeyValue, error) {
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for wresp := range c.Watch(wctx, key, WithRev(rev)) {
+		if err := wresp.Err(); err != nil {
+			return nil, err
+		}
+		for _, ev := range wresp.Events {
+			if ev.Type == EventTypePut && p.match(ev.Kv) {
+				return ev.Kv, nil
+			}
+		}
+	}
+	return nil, errWaitWatchClosed
+}
+
+func waitForValueErr(ctx context.Context, key string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("etcdclient: waiting for value of key %q: %w", key, ctxErr)
+	}
+	return err
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
@@ -93,6 +94,55 @@ type Watcher interface {
 	Close() error
 }
 
+// ErrReplayNotSupported ends the replays of the Watchers that cannot replay
+// events. See Replay.
+var ErrReplayNotSupported = errors.New("etcdclient: replay not supported by the watcher")
+
+// Replayer is implemented by the Watchers that can replay the events of a
+// revision range. See Replay.
+type Replayer interface {
+	// Replay returns the events on a key or prefix with a revision from
+	// startRev up to endRev, in revision order, as a watch started at startRev
+	// would have received them. If endRev is 0 or after the current revision,
+	// the events are replayed up to the current revision. Unlike a watch, the
+	// returned channel is closed once all the events are sent. A replay that
+	// fails, for example because startRev was compacted, ends with a canceled
+	// response whose "Err()" is not nil.
+	Replay(ctx context.Context, key string, startRev, endRev int64, opts ...OpOption) WatchChan
+}
+
+// Replay replays the events on a key or prefix with the Replay method of w if
+// it is a Replayer, as are a Client and its Watcher. Otherwise the returned
+// channel only receives a canceled response whose "Err()" is
+// ErrReplayNotSupported.
+func Replay(ctx context.Context, w Watcher, key string, startRev, endRev int64, opts ...OpOption) WatchChan {
+	if c, ok := w.(*Client); ok {
+		w = c.Watcher
+	}
+	if r, ok := w.(Replayer); ok {
+		return r.Replay(ctx, key, startRev, endRev, opts...)
+	}
+	ch := make(chan WatchResponse, 1)
+	ch <- WatchResponse{Canceled: true, closeErr: ErrReplayNotSupported}
+	close(ch)
+	return ch
+}
+
+// WatchWithCancel watches on w like Watch, and also returns a function that
+// cancels this watch alone. Watches opened with contexts carrying the same
+// metadata share a gRPC stream, and canceling their context cancels all of
+// them; the returned function instead sends a cancel request for the watch ID
+// of this watch only, and closes its channel, dropping the responses not
+// received yet. The other watches on the stream keep delivering events. The
+// function must be called once the watch is no longer used.
+func WatchWithCancel(ctx context.Context, w Watcher, key string, opts ...OpOption) (WatchChan, context.CancelFunc) {
+	// the stream of a watch is looked up by the metadata of its context, so a
+	// child context shares the stream, while its cancellation only closes the
+	// substream of the watch
+	ctx, cancel := context.WithCancel(ctx)
+	return w.Watch(ctx, key, opts...), cancel
+}
+
 type WatchResponse struct {
 	Header pb.ResponseHeader
 	Events []*Event
@@ -112,6 +162,14 @@ type WatchResponse struct {
 
 	// cancelReason is a reason of canceling watch
 	cancelReason string
+
+	// caughtUp is set if the watcher has delivered all events up to the
+	// store revision at the time it was created
+	caughtUp bool
+
+	// compactionResumed is set if the watch was re-established from
+	// CompactRevision after its revision had been compacted
+	compactionResumed bool
 }
 
 // IsCreate returns true if the event tells that the key is newly created.
@@ -129,6 +187,8 @@ func (wr *WatchResponse) Err() error {
 	switch {
 	case wr.closeErr != nil:
 		return v3rpc.Error(wr.closeErr)
+	case wr.compactionResumed:
+		return nil
 	case wr.CompactRevision != 0:
 		return v3rpc.ErrCompacted
 	case wr.Canceled:
@@ -142,7 +202,21 @@ func (wr *WatchResponse) Err() error {
 
 // IsProgressNotify returns true if the WatchResponse is progress notification.
 func (wr *WatchResponse) IsProgressNotify() bool {
-	return len(wr.Events) == 0 && !wr.Canceled && !wr.Created && wr.CompactRevision == 0 && wr.Header.Revision != 0
+	return len(wr.Events) == 0 && !wr.Canceled && !wr.Created && wr.CompactRevision == 0 && wr.Header.Revision != 0 && !wr.caughtUp
+}
+
+// IsCaughtUp returns true if the WatchResponse is the notification requested by
+// WithProgressNotifyOnCreate. It is sent once the watcher has delivered all events
+// up to the store revision at the time it was created.
+func (wr *WatchResponse) IsCaughtUp() bool {
+	return wr.caughtUp
+}
+
+// CompactionResumed returns true if the WatchResponse is the notification sent by
+// a watcher created with WithAutoResumeFromCompaction whose revision was compacted.
+// The watch continues from CompactRevision; events before it were missed.
+func (wr *WatchResponse) CompactionResumed() bool {
+	return wr.compactionResumed
 }
 
 // watcher implements the Watcher interface
@@ -156,8 +230,20 @@ type watcher struct {
 	// streams holds all the active grpc streams keyed by ctx value.
 	streams map[string]*watchGrpcStream
 	lg      *zap.Logger
+
+	// client reads the revision the ranges of a WithGlobalOrder watch start
+	// from; it may be nil.
+	client *Client
+
+	// lastStreamID is the ID of the last dedicated grpc stream, on which the
+	// ranges of a WithGlobalOrder watch request their progress without
+	// notifying the other watchers of their ctx. Protected by mu.
+	lastStreamID uint64
 }
 
+// dedicatedStreamKey is the context key of the ID of a dedicated grpc stream.
+type dedicatedStreamKey struct{}
+
 // watchGrpcStream tracks all watch resources attached to a single grpc stream.
 type watchGrpcStream struct {
 	owner    *watcher
@@ -174,6 +260,8 @@ type watchGrpcStream struct {
 	substreams map[int64]*watcherStream
 	// resuming holds all resuming watchers on this grpc stream
 	resuming []*watcherStream
+	// delayed holds the watchers backing off before resuming from a compaction
+	delayed []*watcherStream
 
 	// reqc sends a watch request from Watch() to the main goroutine
 	reqc chan watchStreamRequest
@@ -212,6 +300,10 @@ type watchRequest struct {
 	createdNotify bool
 	// progressNotify is for progress updates
 	progressNotify bool
+	// caughtUpNotify is for the caught up notification
+	caughtUpNotify bool
+	// autoResumeFromCompaction re-establishes the watch after its revision is compacted
+	autoResumeFromCompaction bool
 	// fragmentation should be disabled by default
 	// if true, split watch events when total exceeds
 	// "--max-request-bytes" flag value + 512-byte
@@ -219,6 +311,12 @@ type watchRequest struct {
 
 	// filters is the list of events to filter out
 	filters []pb.WatchCreateRequest_FilterType
+	// filterValue is the predicate on the values of the put events to send
+	filterValue ValueFilter
+	// coalesceWindow is the window the server coalesces the events of each key in
+	coalesceWindow time.Duration
+	// ranges are the ranges watched in addition to [key, end)
+	ranges []*pb.WatchRange
 	// get the previous key-value pair before the event happens
 	prevKV bool
 	// retc receives a chan WatchResponse once the watcher is established
@@ -244,6 +342,10 @@ type watcherStream struct {
 	closing bool
 	// id is the registered watch id on the grpc stream
 	id int64
+	// compactBackoff delays re-establishing the watch after repeated compactions
+	compactBackoff time.Duration
+	// resumeAt is when a delayed watcher resumes from a compaction
+	resumeAt time.Time
 
 	// buf holds all events received from etcd but not yet consumed by the client
 	buf []*WatchResponse
@@ -257,6 +359,7 @@ func NewWatchFromWatchClient(wc pb.WatchClient, c *Client) Watcher {
 	w := &watcher{
 		remote:  wc,
 		streams: make(map[string]*watchGrpcStream),
+		client:  c,
 	}
 	if c != nil {
 		w.callOpts = c.callOpts
@@ -301,7 +404,53 @@ func (w *watcher) newWatcherGrpcStream(inctx context.Context) *watchGrpcStream {
 // Watch posts a watch request to run() and waits for a new watcher channel
 func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) WatchChan {
 	ow := opWatch(key, opts...)
+	if ow.globalOrder && len(ow.watchRanges) != 0 {
+		return w.watchInGlobalOrder(ctx, ow)
+	}
+	return w.watch(ctx, ow)
+}
+
+// watchInGlobalOrder watches each range of ow with a watch of its own, and
+// merges their events in revision order.
+func (w *watcher) watchInGlobalOrder(ctx context.Context, ow Op) WatchChan {
+	if ow.rev == 0 && w.client != nil && w.client.KV != nil {
+		// the ranges start from the same revision, or the events of one
+		// could be ordered before the earlier events of another
+		resp, err := w.client.KV.Get(ctx, string(ow.key), WithCountOnly())
+		if err != nil {
+			ch := make(chan WatchResponse, 1)
+			ch <- WatchResponse{Canceled: true, closeErr: err}
+			close(ch)
+			return ch
+		}
+		ow.rev = resp.Header.Revision + 1
+	}
+	w.mu.Lock()
+	w.lastStreamID++
+	ctx = context.WithValue(ctx, dedicatedStreamKey{}, w.lastStreamID)
+	w.mu.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	ranges := append([]Op{ow}, ow.watchRanges...)
+	wchs := make([]WatchChan, len(ranges))
+	for i, r := range ranges {
+		rop := ow
+		rop.key, rop.end = r.key, r.end
+		rop.filterPut, rop.filterDelete = r.filterPut, r.filterDelete
+		rop.watchRanges, rop.globalOrder = nil, false
+		// the merged events are batched
+		rop.maxBatch = 0
+		wchs[i] = w.watch(ctx, rop)
+	}
+	wch := orderWatchResponses(ctx, cancel, wchs, ow.rev, w.RequestProgress, GlobalOrderMaxBuffered)
+	if ow.maxBatch > 0 {
+		return batchWatchResponses(ctx, wch, ow.maxBatch, ow.maxBatchWait)
+	}
+	return wch
+}
 
+// watch posts the watch request of ow to run() and waits for a new watcher
+// channel.
+func (w *watcher) watch(ctx context.Context, ow Op) WatchChan {
 	var filters []pb.WatchCreateRequest_FilterType
 	if ow.filterPut {
 		filters = append(filters, pb.WatchCreateRequest_NOPUT)
@@ -309,6 +458,17 @@ func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) Watch
 	if ow.filterDelete {
 		filters = append(filters, pb.WatchCreateRequest_NODELETE)
 	}
+	var ranges []*pb.WatchRange
+	for _, r := range ow.watchRanges {
+		wrange := &pb.WatchRange{Key: r.key, RangeEnd: r.end}
+		if r.filterPut {
+			wrange.Filters = append(wrange.Filters, pb.WatchCreateRequest_NOPUT)
+		}
+		if r.filterDelete {
+			wrange.Filters = append(wrange.Filters, pb.WatchCreateRequest_NODELETE)
+		}
+		ranges = append(ranges, wrange)
+	}
 
 	wr := &watchRequest{
 		ctx:            ctx,
@@ -317,10 +477,16 @@ func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) Watch
 		end:            string(ow.end),
 		rev:            ow.rev,
 		progressNotify: ow.progressNotify,
+		caughtUpNotify: ow.caughtUpNotify,
 		fragment:       ow.fragment,
 		filters:        filters,
+		filterValue:    ow.filterValue,
+		coalesceWindow: ow.coalesceWindow,
+		ranges:         ranges,
 		prevKV:         ow.prevKV,
 		retc:           make(chan chan WatchResponse, 1),
+
+		autoResumeFromCompaction: ow.autoResumeFromCompaction,
 	}
 
 	ok := false
@@ -371,6 +537,9 @@ func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) Watch
 		if ok {
 			select {
 			case ret := <-wr.retc:
+				if ow.maxBatch > 0 {
+					return batchWatchResponses(ctx, ret, ow.maxBatch, ow.maxBatchWait)
+				}
 				return ret
 			case <-ctx.Done():
 			case <-donec:
@@ -389,6 +558,51 @@ func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) Watch
 	return closeCh
 }
 
+func (w *watcher) Replay(ctx context.Context, key string, startRev, endRev int64, opts ...OpOption) WatchChan {
+	ow := opWatch(key, opts...)
+	req := &pb.ReplayRequest{
+		Key:           ow.key,
+		RangeEnd:      ow.end,
+		StartRevision: startRev,
+		EndRevision:   endRev,
+	}
+	ch := make(chan WatchResponse)
+	go func() {
+		defer close(ch)
+		send := func(wr WatchResponse) bool {
+			select {
+			case ch <- wr:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		stream, err := w.remote.Replay(ctx, req, w.callOpts...)
+		if err != nil {
+			send(WatchResponse{Canceled: true, closeErr: err})
+			return
+		}
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				send(WatchResponse{Canceled: true, closeErr: err})
+				return
+			}
+			events := make([]*Event, len(resp.Events))
+			for i, ev := range resp.Events {
+				events[i] = (*Event)(ev)
+			}
+			if !send(WatchResponse{Header: *resp.Header, Events: events}) {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
 func (w *watcher) Close() (err error) {
 	w.mu.Lock()
 	streams := w.streams
@@ -503,6 +717,12 @@ func (w *watchGrpcStream) closeSubstream(ws *watcherStream) {
 			return
 		}
 	}
+	for i := range w.delayed {
+		if w.delayed[i] == ws {
+			w.delayed = append(w.delayed[:i], w.delayed[i+1:]...)
+			return
+		}
+	}
 }
 
 // run is the root of the goroutines for managing a watcher client
@@ -529,6 +749,12 @@ func (w *watchGrpcStream) run() {
 				closing[ws] = struct{}{}
 			}
 		}
+		for _, ws := range w.delayed {
+			if _, ok := closing[ws]; !ok {
+				close(ws.recvc)
+				closing[ws] = struct{}{}
+			}
+		}
 		w.joinSubstreams()
 		for range closing {
 			w.closeSubstream(<-w.closingc)
@@ -546,6 +772,32 @@ func (w *watchGrpcStream) run() {
 
 	var cur *pb.WatchResponse
 	backoff := time.Millisecond
+
+	// resumeTimer fires once the earliest delayed watcher may resume
+	var resumeTimer *time.Timer
+	var resumeC <-chan time.Time
+	scheduleResume := func() {
+		if resumeTimer != nil {
+			resumeTimer.Stop()
+		}
+		resumeTimer, resumeC = nil, nil
+		var at time.Time
+		for _, ws := range w.delayed {
+			if at.IsZero() || ws.resumeAt.Before(at) {
+				at = ws.resumeAt
+			}
+		}
+		if !at.IsZero() {
+			resumeTimer = time.NewTimer(time.Until(at))
+			resumeC = resumeTimer.C
+		}
+	}
+	defer func() {
+		if resumeTimer != nil {
+			resumeTimer.Stop()
+		}
+	}()
+
 	for {
 		select {
 		// Watch() requested
@@ -641,12 +893,24 @@ func (w *watchGrpcStream) run() {
 				// reset for next iteration
 				cur = nil
 
+			case pbresp.Canceled && w.resumeFromCompaction(wc, pbresp):
+				delete(cancelSet, pbresp.WatchId)
+				scheduleResume()
+
+				// reset for next iteration
+				cur = nil
+
 			case cur.Fragment:
 				// watch response events are still fragmented
 				// continue to fetch next fragmented event arrival
 				continue
 
 			default:
+				// the watcher resumed from a compaction made progress
+				if ws, ok := w.substreams[pbresp.WatchId]; ok {
+					ws.compactBackoff = 0
+				}
+
 				// dispatch to appropriate watch stream
 				ok := w.dispatchEvent(cur)
 
@@ -685,6 +949,7 @@ func (w *watchGrpcStream) run() {
 			if wc, closeErr = w.newWatchClient(); closeErr != nil {
 				return
 			}
+			scheduleResume()
 			if ws := w.nextResume(); ws != nil {
 				if err := wc.Send(ws.initReq.toPB()); err != nil {
 					w.lg.Debug("error when sending request", zap.Error(err))
@@ -692,6 +957,10 @@ func (w *watchGrpcStream) run() {
 			}
 			cancelSet = make(map[int64]struct{})
 
+		case now := <-resumeC:
+			w.resumeDelayed(wc, now)
+			scheduleResume()
+
 		case <-w.ctx.Done():
 			return
 
@@ -699,7 +968,7 @@ func (w *watchGrpcStream) run() {
 			w.closeSubstream(ws)
 			delete(closing, ws)
 			// no more watchers on this stream, shutdown, skip cancellation
-			if len(w.substreams)+len(w.resuming) == 0 {
+			if len(w.substreams)+len(w.resuming)+len(w.delayed) == 0 {
 				return
 			}
 			if ws.id != InvalidWatchID {
@@ -721,6 +990,78 @@ func (w *watchGrpcStream) run() {
 	}
 }
 
+// resumeFromCompaction re-registers a watcher created with WithAutoResumeFromCompaction
+// whose revision was compacted, starting from the compacted revision. It returns false
+// if the response is not a compaction cancel of such a watcher.
+func (w *watchGrpcStream) resumeFromCompaction(wc pb.Watch_WatchClient, pbresp *pb.WatchResponse) bool {
+	if pbresp.CompactRevision == 0 {
+		return false
+	}
+	ws, ok := w.substreams[pbresp.WatchId]
+	if !ok || !ws.initReq.autoResumeFromCompaction {
+		return false
+	}
+
+	delete(w.substreams, ws.id)
+	ws.id = InvalidWatchID
+	wr := &WatchResponse{
+		Header:            *pbresp.Header,
+		CompactRevision:   pbresp.CompactRevision,
+		compactionResumed: true,
+	}
+	// the substream goroutine moves the watch to the compacted revision and
+	// stops; wait for it before reading its request
+	select {
+	case ws.recvc <- wr:
+	case <-ws.donec:
+	}
+	<-ws.donec
+	if ws.closing {
+		return true
+	}
+	ws.donec = make(chan struct{})
+	w.wg.Add(1)
+	go w.serveSubstream(ws, w.resumec)
+
+	// a watcher compacted again before it made progress backs off, so that
+	// a compaction racing ahead of it does not cause a tight loop
+	if ws.compactBackoff > 0 {
+		ws.resumeAt = time.Now().Add(ws.compactBackoff)
+		w.delayed = append(w.delayed, ws)
+		ws.compactBackoff *= 2
+		if ws.compactBackoff > maxBackoff {
+			ws.compactBackoff = maxBackoff
+		}
+		return true
+	}
+	ws.compactBackoff = minCompactBackoff
+	w.resume(wc, ws)
+	return true
+}
+
+// resumeDelayed re-registers the delayed watchers whose backoff ended by now.
+func (w *watchGrpcStream) resumeDelayed(wc pb.Watch_WatchClient, now time.Time) {
+	delayed := w.delayed[:0]
+	for _, ws := range w.delayed {
+		if ws.resumeAt.After(now) {
+			delayed = append(delayed, ws)
+			continue
+		}
+		w.resume(wc, ws)
+	}
+	w.delayed = delayed
+}
+
+// resume queues ws for registration, sending its request if it heads the queue.
+func (w *watchGrpcStream) resume(wc pb.Watch_WatchClient, ws *watcherStream) {
+	w.resuming = append(w.resuming, ws)
+	if w.nextResume() == ws {
+		if err := wc.Send(ws.initReq.toPB()); err != nil {
+			w.lg.Debug("error when sending request", zap.Error(err))
+		}
+	}
+}
+
 func shouldRetryWatch(cancelReason string) bool {
 	if cancelReason == "" {
 		return false
@@ -755,6 +1096,7 @@ func (w *watchGrpcStream) dispatchEvent(pbresp *pb.WatchResponse) bool {
 		Created:         pbresp.Created,
 		Canceled:        pbresp.Canceled,
 		cancelReason:    pbresp.CancelReason,
+		caughtUp:        pbresp.CaughtUp,
 	}
 
 	// watch IDs are zero indexed, so request notify watch responses are assigned a watch ID of InvalidWatchID to
@@ -854,6 +1196,15 @@ func (w *watchGrpcStream) serveSubstream(ws *watcherStream, resumec chan struct{
 				return
 			}
 
+			if wr.compactionResumed {
+				// run() registers the watch again from the compacted revision
+				nextRev = wr.CompactRevision
+				ws.initReq.rev = nextRev
+				ws.buf = append(ws.buf, wr)
+				resuming = true
+				return
+			}
+
 			if wr.Created {
 				if ws.initReq.retc != nil {
 					ws.initReq.retc <- ws.outc
@@ -888,6 +1239,11 @@ func (w *watchGrpcStream) serveSubstream(ws *watcherStream, resumec chan struct{
 			}
 
 			ws.initReq.rev = nextRev
+			// the caught up notification is delivered once; do not request it
+			// again when the watch resumes
+			if wr.IsCaughtUp() {
+				ws.initReq.caughtUpNotify = false
+			}
 
 			// created event is already sent above,
 			// watcher should not post duplicate events
@@ -918,6 +1274,9 @@ func (w *watchGrpcStream) newWatchClient() (pb.Watch_WatchClient, error) {
 		ws.id = InvalidWatchID
 		w.resuming = append(w.resuming, ws)
 	}
+	// the delayed watchers resume with the new stream
+	w.resuming = append(w.resuming, w.delayed...)
+	w.delayed = nil
 	// strip out nils, if any
 	var resuming []*watcherStream
 	for _, ws := range w.resuming {
@@ -1001,10 +1360,17 @@ func (w *watchGrpcStream) joinSubstreams() {
 			<-ws.donec
 		}
 	}
+	for _, ws := range w.delayed {
+		<-ws.donec
+	}
 }
 
 var maxBackoff = 100 * time.Millisecond
 
+// minCompactBackoff is the first delay of a watcher resuming from a compaction
+// again before it made progress.
+var minCompactBackoff = time.Millisecond
+
 func (w *watchGrpcStream) backoffIfUnavailable(backoff time.Duration, err error) time.Duration {
 	if isUnavailableErr(w.ctx, err) {
 		// retry, but backoff
@@ -1052,9 +1418,15 @@ func (wr *watchRequest) toPB() *pb.WatchRequest {
 		Key:            []byte(wr.key),
 		RangeEnd:       []byte(wr.end),
 		ProgressNotify: wr.progressNotify,
+		CaughtUpNotify: wr.caughtUpNotify,
 		Filters:        wr.filters,
+		ValueFilter:    wr.filterValue.t,
+		ValuePrefix:    []byte(wr.filterValue.prefix),
 		PrevKv:         wr.prevKV,
 		Fragment:       wr.fragment,
+
+		CoalesceWindowMs: wr.coalesceWindow.Milliseconds(),
+		Ranges:           wr.ranges,
 	}
 	cr := &pb.WatchRequest_CreateRequest{CreateRequest: req}
 	return &pb.WatchRequest{RequestUnion: cr}
@@ -1068,8 +1440,12 @@ func (pr *progressRequest) toPB() *pb.WatchRequest {
 }
 
 func streamKeyFromCtx(ctx context.Context) string {
+	var key string
 	if md, ok := metadata.FromOutgoingContext(ctx); ok {
-		return fmt.Sprintf("%+v", md)
+		key = fmt.Sprintf("%+v", md)
+	}
+	if id, ok := ctx.Value(dedicatedStreamKey{}).(uint64); ok {
+		key += fmt.Sprintf("#%d", id)
 	}
-	return ""
+	return key
 }
@@ -0,0 +1,116 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"time"
+)
+
+// batchWatchResponses coalesces the events of the responses received on wch
+// into responses of up to maxBatch events, which are sent once they are full
+// or maxWait after their first event. The events of a revision are never split
+// across responses; a revision with more than maxBatch events is sent in a
+// larger response of its own. Responses that are not plain events, such as
+// progress notifications and cancelations, flush the pending events and are
+// passed on unchanged.
+func batchWatchResponses(ctx context.Context, wch WatchChan, maxBatch int, maxWait time.Duration) WatchChan {
+	outc := make(chan WatchResponse)
+	go func() {
+		defer close(outc)
+
+		var (
+			batch  *WatchResponse
+			timer  *time.Timer
+			timerc <-chan time.Time
+		)
+		send := func(wr WatchResponse) bool {
+			select {
+			case outc <- wr:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		flush := func() bool {
+			if batch == nil {
+				return true
+			}
+			timer.Stop()
+			timerc = nil
+			wr := *batch
+			batch = nil
+			return send(wr)
+		}
+
+		for {
+			select {
+			case wr, ok := <-wch:
+				if !ok {
+					flush()
+					return
+				}
+				if !isEventsOnly(wr) {
+					if !flush() || !send(wr) {
+						return
+					}
+					continue
+				}
+				for _, evs := range splitEventsByRevision(wr.Events) {
+					if batch != nil && len(batch.Events)+len(evs) > maxBatch && !flush() {
+						return
+					}
+					if batch == nil {
+						batch = &WatchResponse{}
+						timer = time.NewTimer(maxWait)
+						timerc = timer.C
+					}
+					batch.Header = wr.Header
+					batch.Events = append(batch.Events, evs...)
+					if len(batch.Events) >= maxBatch && !flush() {
+						return
+					}
+				}
+			case <-timerc:
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return outc
+}
+
+// isEventsOnly returns true if the response carries nothing but events.
+func isEventsOnly(wr WatchResponse) bool {
+	return len(wr.Events) != 0 && !wr.Created && !wr.Canceled && wr.CompactRevision == 0 &&
+		wr.closeErr == nil && !wr.caughtUp && !wr.compactionResumed
+}
+
+// splitEventsByRevision splits events ordered by revision into runs of the
+// same revision.
+func splitEventsByRevision(evs []*Event) [][]*Event {
+	var runs [][]*Event
+	start := 0
+	for i := 1; i <= len(evs); i++ {
+		if i == len(evs) || evs[i].Kv.ModRevision != evs[start].Kv.ModRevision {
+			runs = append(runs, evs[start:i])
+			start = i
+		}
+	}
+	return runs
+}
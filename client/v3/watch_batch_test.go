@@ -0,0 +1,125 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// eventsAt returns n put events at revision rev.
+func eventsAt(rev int64, n int) []*Event {
+	evs := make([]*Event, n)
+	for i := range evs {
+		evs[i] = &Event{Type: EventTypePut, Kv: &mvccpb.KeyValue{ModRevision: rev}}
+	}
+	return evs
+}
+
+// revisions returns the revision of each event of wr.
+func revisions(wr WatchResponse) []int64 {
+	revs := make([]int64, len(wr.Events))
+	for i, ev := range wr.Events {
+		revs[i] = ev.Kv.ModRevision
+	}
+	return revs
+}
+
+func recvBatch(t *testing.T, wch WatchChan) WatchResponse {
+	select {
+	case wr, ok := <-wch:
+		require.True(t, ok, "watch channel closed")
+		return wr
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a batch")
+	}
+	return WatchResponse{}
+}
+
+func TestBatchWatchResponsesGrouping(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inc := make(chan WatchResponse)
+	outc := batchWatchResponses(ctx, inc, 4, time.Hour)
+
+	go func() {
+		inc <- WatchResponse{Events: eventsAt(2, 1)}
+		inc <- WatchResponse{Events: append(eventsAt(3, 2), eventsAt(4, 1)...)}
+		// the revision 6 would not fit in the first batch
+		inc <- WatchResponse{Events: append(eventsAt(5, 1), eventsAt(6, 2)...)}
+		// a revision larger than a batch is not split
+		inc <- WatchResponse{Events: eventsAt(7, 6)}
+		inc <- WatchResponse{Events: eventsAt(8, 1)}
+		close(inc)
+	}()
+
+	assert.Equal(t, []int64{2, 3, 3, 4}, revisions(recvBatch(t, outc)))
+	assert.Equal(t, []int64{5, 6, 6}, revisions(recvBatch(t, outc)))
+	assert.Equal(t, []int64{7, 7, 7, 7, 7, 7}, revisions(recvBatch(t, outc)))
+	// the pending events are sent when the source is closed
+	assert.Equal(t, []int64{8}, revisions(recvBatch(t, outc)))
+	_, ok := <-outc
+	assert.False(t, ok)
+}
+
+func TestBatchWatchResponsesMaxWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inc := make(chan WatchResponse)
+	outc := batchWatchResponses(ctx, inc, 100, 50*time.Millisecond)
+
+	start := time.Now()
+	inc <- WatchResponse{Events: eventsAt(2, 1)}
+	inc <- WatchResponse{Events: eventsAt(3, 2)}
+	wr := recvBatch(t, outc)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, []int64{2, 3, 3}, revisions(wr))
+
+	inc <- WatchResponse{Events: eventsAt(4, 1)}
+	assert.Equal(t, []int64{4}, revisions(recvBatch(t, outc)))
+}
+
+func TestBatchWatchResponsesFlush(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inc := make(chan WatchResponse)
+	outc := batchWatchResponses(ctx, inc, 100, time.Hour)
+
+	go func() {
+		inc <- WatchResponse{Events: eventsAt(2, 2)}
+		inc <- WatchResponse{Header: pb.ResponseHeader{Revision: 5}}
+		inc <- WatchResponse{Events: eventsAt(6, 1)}
+		inc <- WatchResponse{Canceled: true, CompactRevision: 7}
+	}()
+
+	// a progress notification flushes the pending events first
+	assert.Equal(t, []int64{2, 2}, revisions(recvBatch(t, outc)))
+	wr := recvBatch(t, outc)
+	assert.True(t, wr.IsProgressNotify())
+	assert.Equal(t, int64(5), wr.Header.Revision)
+
+	// so does a cancelation caused by a compaction
+	assert.Equal(t, []int64{6}, revisions(recvBatch(t, outc)))
+	wr = recvBatch(t, outc)
+	assert.True(t, wr.Canceled)
+	assert.Equal(t, int64(7), wr.CompactRevision)
+}
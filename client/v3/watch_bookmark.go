@@ -0,0 +1,271 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// watchBookmarkFormat is the version of the serialization format of
+// WatchBookmark. It changes only if a bookmark of a previous version could be
+// misread.
+const watchBookmarkFormat = 1
+
+var (
+	ErrBookmarkNoRevision  = errors.New("watch bookmark requires a start revision")
+	ErrBookmarkUnsupported = errors.New("watch bookmark does not support coalesced, fragmented or auto-resumed watches")
+)
+
+// WatchBookmarkCompactedError is returned by WatchFromBookmark if the revision
+// of the bookmark was compacted, so the watch cannot resume without missing
+// events. It wraps rpctypes.ErrCompacted.
+type WatchBookmarkCompactedError struct {
+	Revision int64
+}
+
+func (e *WatchBookmarkCompactedError) Error() string {
+	return fmt.Sprintf("watch bookmark revision %d was compacted", e.Revision)
+}
+
+func (e *WatchBookmarkCompactedError) Unwrap() error { return rpctypes.ErrCompacted }
+
+// WatchBookmark records what a watch watches and the revision its events were
+// processed up to, so that a consumer can persist it and resume the watch with
+// WatchFromBookmark, for example after a restart, without missing or repeating
+// events. It is serialized to JSON in a stable format.
+type WatchBookmark struct {
+	// Key and End are the range of the watch, End being empty for a single key
+	// and "\x00" for all the keys from Key on.
+	Key []byte
+	End []byte
+	// Ranges are the ranges watched in addition to Key with WithWatchRange.
+	Ranges []WatchBookmarkRange
+
+	FilterPut    bool
+	FilterDelete bool
+	// ValueFilter is the name of the type of the WithFilterValue predicate,
+	// and ValuePrefix its prefix.
+	ValueFilter string
+	ValuePrefix string
+	PrevKV      bool
+
+	// Revision is the revision the watch resumes from: the events before it
+	// were processed.
+	Revision int64
+}
+
+// WatchBookmarkRange is a range watched with WithWatchRange.
+type WatchBookmarkRange struct {
+	Key          []byte
+	End          []byte
+	FilterPut    bool
+	FilterDelete bool
+}
+
+// NewWatchBookmark returns a bookmark for a watch on key with the given
+// options, starting at the revision given with WithRev. The options only
+// delivering notifications, like WithProgressNotify, are not recorded.
+func NewWatchBookmark(key string, opts ...OpOption) (*WatchBookmark, error) {
+	ow := opWatch(key, opts...)
+	if ow.rev <= 0 {
+		return nil, ErrBookmarkNoRevision
+	}
+	if ow.coalesceWindow != 0 || ow.fragment || ow.autoResumeFromCompaction {
+		return nil, ErrBookmarkUnsupported
+	}
+	b := &WatchBookmark{
+		Key:          ow.key,
+		End:          ow.end,
+		FilterPut:    ow.filterPut,
+		FilterDelete: ow.filterDelete,
+		PrevKV:       ow.prevKV,
+		Revision:     ow.rev,
+	}
+	if ow.filterValue != (ValueFilter{}) {
+		b.ValueFilter = ow.filterValue.t.String()
+		b.ValuePrefix = ow.filterValue.prefix
+	}
+	for _, r := range ow.watchRanges {
+		b.Ranges = append(b.Ranges, WatchBookmarkRange{Key: r.key, End: r.end, FilterPut: r.filterPut, FilterDelete: r.filterDelete})
+	}
+	return b, nil
+}
+
+// Update moves the bookmark past the events of wr, or to the revision of a
+// progress notification. It must be called once the events are processed.
+func (b *WatchBookmark) Update(wr WatchResponse) {
+	rev := int64(0)
+	switch {
+	case len(wr.Events) > 0:
+		// the header revision of a response may be ahead of its events while
+		// the watch catches up, but the events of a revision are never split
+		// across responses
+		rev = wr.Events[len(wr.Events)-1].Kv.ModRevision
+	case wr.IsProgressNotify():
+		rev = wr.Header.Revision
+	}
+	if rev+1 > b.Revision {
+		b.Revision = rev + 1
+	}
+}
+
+func (b *WatchBookmark) opts() ([]OpOption, error) {
+	if b.Revision <= 0 {
+		return nil, ErrBookmarkNoRevision
+	}
+	opts := []OpOption{WithRev(b.Revision)}
+	if len(b.End) > 0 {
+		opts = append(opts, WithRange(string(b.End)))
+	}
+	if b.FilterPut {
+		opts = append(opts, WithFilterPut())
+	}
+	if b.FilterDelete {
+		opts = append(opts, WithFilterDelete())
+	}
+	if b.PrevKV {
+		opts = append(opts, WithPrevKV())
+	}
+	if b.ValueFilter != "" {
+		t, ok := pb.WatchCreateRequest_ValueFilterType_value[b.ValueFilter]
+		if !ok {
+			return nil, fmt.Errorf("watch bookmark has unknown value filter %q", b.ValueFilter)
+		}
+		opts = append(opts, WithFilterValue(ValueFilter{t: pb.WatchCreateRequest_ValueFilterType(t), prefix: b.ValuePrefix}))
+	}
+	for _, r := range b.Ranges {
+		var ropts []OpOption
+		if len(r.End) > 0 {
+			ropts = append(ropts, WithRange(string(r.End)))
+		}
+		if r.FilterPut {
+			ropts = append(ropts, WithFilterPut())
+		}
+		if r.FilterDelete {
+			ropts = append(ropts, WithFilterDelete())
+		}
+		opts = append(opts, WithWatchRange(string(r.Key), ropts...))
+	}
+	return opts, nil
+}
+
+// WatchFromBookmark resumes the watch of the bookmark from its revision. The
+// given options are added to the options of the bookmark; they must only
+// deliver notifications, like WithProgressNotify.
+//
+// It fails with a *WatchBookmarkCompactedError if the revision of the bookmark
+// was compacted. A compaction racing with the creation of the watch cancels
+// the watch with ErrCompacted instead.
+func WatchFromBookmark(ctx context.Context, w Watcher, b *WatchBookmark, opts ...OpOption) (WatchChan, error) {
+	bopts, err := b.opts()
+	if err != nil {
+		return nil, err
+	}
+	// the history of the ranges may be compacted separately, so every range
+	// is checked
+	ranges := append([]WatchBookmarkRange{{Key: b.Key, End: b.End}}, b.Ranges...)
+	for _, r := range ranges {
+		if err := checkBookmarkRange(ctx, w, r.Key, r.End, b.Revision); err != nil {
+			return nil, err
+		}
+	}
+	return w.Watch(ctx, string(b.Key), append(bopts, opts...)...), nil
+}
+
+// checkBookmarkRange fails with a *WatchBookmarkCompactedError if rev was
+// compacted in the range [key, end), as replaying it fails then.
+func checkBookmarkRange(ctx context.Context, w Watcher, key, end []byte, rev int64) error {
+	var opts []OpOption
+	if len(end) > 0 {
+		opts = append(opts, WithRange(string(end)))
+	}
+	for wr := range Replay(ctx, w, string(key), rev, rev, opts...) {
+		if err := wr.Err(); err != nil {
+			if errors.Is(err, rpctypes.ErrCompacted) {
+				return &WatchBookmarkCompactedError{Revision: rev}
+			}
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+type watchBookmarkJSON struct {
+	Format       int                      `json:"format"`
+	Key          []byte                   `json:"key"`
+	End          []byte                   `json:"end,omitempty"`
+	Ranges       []watchBookmarkRangeJSON `json:"ranges,omitempty"`
+	FilterPut    bool                     `json:"filterPut,omitempty"`
+	FilterDelete bool                     `json:"filterDelete,omitempty"`
+	ValueFilter  string                   `json:"valueFilter,omitempty"`
+	ValuePrefix  string                   `json:"valuePrefix,omitempty"`
+	PrevKV       bool                     `json:"prevKV,omitempty"`
+	Revision     int64                    `json:"revision"`
+}
+
+type watchBookmarkRangeJSON struct {
+	Key          []byte `json:"key"`
+	End          []byte `json:"end,omitempty"`
+	FilterPut    bool   `json:"filterPut,omitempty"`
+	FilterDelete bool   `json:"filterDelete,omitempty"`
+}
+
+func (b WatchBookmark) MarshalJSON() ([]byte, error) {
+	j := watchBookmarkJSON{
+		Format:       watchBookmarkFormat,
+		Key:          b.Key,
+		End:          b.End,
+		FilterPut:    b.FilterPut,
+		FilterDelete: b.FilterDelete,
+		ValueFilter:  b.ValueFilter,
+		ValuePrefix:  b.ValuePrefix,
+		PrevKV:       b.PrevKV,
+		Revision:     b.Revision,
+	}
+	for _, r := range b.Ranges {
+		j.Ranges = append(j.Ranges, watchBookmarkRangeJSON(r))
+	}
+	return json.Marshal(j)
+}
+
+func (b *WatchBookmark) UnmarshalJSON(data []byte) error {
+	var j watchBookmarkJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Format != watchBookmarkFormat {
+		return fmt.Errorf("unsupported watch bookmark format %d", j.Format)
+	}
+	*b = WatchBookmark{
+		Key:          j.Key,
+		End:          j.End,
+		FilterPut:    j.FilterPut,
+		FilterDelete: j.FilterDelete,
+		ValueFilter:  j.ValueFilter,
+		ValuePrefix:  j.ValuePrefix,
+		PrevKV:       j.PrevKV,
+		Revision:     j.Revision,
+	}
+	for _, r := range j.Ranges {
+		b.Ranges = append(b.Ranges, WatchBookmarkRange(r))
+	}
+	return nil
+}
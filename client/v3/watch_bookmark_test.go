@@ -0,0 +1,77 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestWatchBookmarkJSON(t *testing.T) {
+	b, err := NewWatchBookmark("a", WithRev(5), WithPrefix(), WithFilterDelete(), WithFilterValue(ValuePrefix("v")),
+		WithWatchRange("c", WithFilterPut()), WithProgressNotify())
+	require.NoError(t, err)
+
+	data, err := json.Marshal(b)
+	require.NoError(t, err)
+	// the format is persisted by clients, so it must not change
+	assert.JSONEq(t, `{"format":1,"key":"YQ==","end":"Yg==","ranges":[{"key":"Yw==","filterPut":true}],`+
+		`"filterDelete":true,"valueFilter":"VALUE_PREFIX","valuePrefix":"v","revision":5}`, string(data))
+
+	var got WatchBookmark
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, b, &got)
+	_, err = got.opts()
+	require.NoError(t, err)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"format":2,"key":"YQ==","revision":5}`), &got))
+	require.NoError(t, json.Unmarshal([]byte(`{"format":1,"key":"YQ==","valueFilter":"UNKNOWN","revision":5}`), &got))
+	_, err = got.opts()
+	assert.Error(t, err)
+}
+
+func TestNewWatchBookmarkUnsupported(t *testing.T) {
+	_, err := NewWatchBookmark("a")
+	assert.ErrorIs(t, err, ErrBookmarkNoRevision)
+	for _, opt := range []OpOption{WithCoalesce(time.Second), WithFragment(), WithAutoResumeFromCompaction()} {
+		_, err = NewWatchBookmark("a", WithRev(1), opt)
+		assert.ErrorIs(t, err, ErrBookmarkUnsupported)
+	}
+}
+
+func TestWatchBookmarkUpdate(t *testing.T) {
+	b, err := NewWatchBookmark("a", WithRev(5))
+	require.NoError(t, err)
+
+	event := func(rev int64) *Event { return &Event{Kv: &mvccpb.KeyValue{Key: []byte("a"), ModRevision: rev}} }
+	// a response catching up may be ahead of its events
+	b.Update(WatchResponse{Header: pb.ResponseHeader{Revision: 20}, Events: []*Event{event(6), event(8)}})
+	assert.Equal(t, int64(9), b.Revision)
+	// a created response is not a progress notification
+	b.Update(WatchResponse{Header: pb.ResponseHeader{Revision: 20}, Created: true})
+	assert.Equal(t, int64(9), b.Revision)
+	b.Update(WatchResponse{Header: pb.ResponseHeader{Revision: 12}})
+	assert.Equal(t, int64(13), b.Revision)
+	// the bookmark never moves back
+	b.Update(WatchResponse{Header: pb.ResponseHeader{Revision: 10}, Events: []*Event{event(10)}})
+	assert.Equal(t, int64(13), b.Revision)
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// fakeWatchClient serves a single watch stream whose requests and responses
+// are driven by the test.
+type fakeWatchClient struct {
+	pb.WatchClient
+	reqc  chan *pb.WatchRequest
+	respc chan *pb.WatchResponse
+}
+
+func newFakeWatchClient() *fakeWatchClient {
+	return &fakeWatchClient{reqc: make(chan *pb.WatchRequest, 10), respc: make(chan *pb.WatchResponse)}
+}
+
+func (c *fakeWatchClient) Watch(ctx context.Context, _ ...grpc.CallOption) (pb.Watch_WatchClient, error) {
+	return &fakeWatchStream{ctx: ctx, c: c}, nil
+}
+
+type fakeWatchStream struct {
+	grpc.ClientStream
+	ctx context.Context
+	c   *fakeWatchClient
+}
+
+func (s *fakeWatchStream) Send(req *pb.WatchRequest) error {
+	s.c.reqc <- req
+	return nil
+}
+
+func (s *fakeWatchStream) Recv() (*pb.WatchResponse, error) {
+	select {
+	case resp := <-s.c.respc:
+		return resp, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+// recvCreate returns the next create request sent on the stream.
+func (c *fakeWatchClient) recvCreate(t *testing.T) *pb.WatchCreateRequest {
+	select {
+	case req := <-c.reqc:
+		creq := req.GetCreateRequest()
+		require.NotNil(t, creq, "unexpected request %+v", req)
+		return creq
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a create request")
+	}
+	return nil
+}
+
+func (c *fakeWatchClient) send(resp *pb.WatchResponse) {
+	if resp.Header == nil {
+		resp.Header = &pb.ResponseHeader{}
+	}
+	c.respc <- resp
+}
+
+func (c *fakeWatchClient) compact(id, rev int64) {
+	c.send(&pb.WatchResponse{WatchId: id, Canceled: true, CompactRevision: rev, Header: &pb.ResponseHeader{Revision: rev}})
+}
+
+func (c *fakeWatchClient) put(id, rev int64) {
+	c.send(&pb.WatchResponse{
+		WatchId: id,
+		Header:  &pb.ResponseHeader{Revision: rev},
+		Events:  []*mvccpb.Event{{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("foo"), ModRevision: rev}}},
+	})
+}
+
+func recvWatchResponse(t *testing.T, wch WatchChan) *WatchResponse {
+	select {
+	case wr, ok := <-wch:
+		require.True(t, ok, "unexpected closed watch channel")
+		return &wr
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a watch response")
+	}
+	return nil
+}
+
+// watch creates a watch served by fc, which answers its create request.
+func (c *fakeWatchClient) watch(t *testing.T, w Watcher, ctx context.Context, id int64, key string, opts ...OpOption) (WatchChan, *pb.WatchCreateRequest) {
+	wchc := make(chan WatchChan, 1)
+	go func() { wchc <- w.Watch(ctx, key, opts...) }()
+	creq := c.recvCreate(t)
+	c.send(&pb.WatchResponse{WatchId: id, Created: true})
+	return <-wchc, creq
+}
+
+func newFakeWatcher(t *testing.T) (Watcher, *fakeWatchClient) {
+	fc := newFakeWatchClient()
+	w := NewWatchFromWatchClient(fc, &Client{lg: zaptest.NewLogger(t)})
+	t.Cleanup(func() { w.Close() })
+	return w, fc
+}
+
+// TestWatchResumeFromCompactionBackoff ensures a watcher compacted again before
+// it made progress resumes after a backoff without blocking the other watchers
+// of its stream.
+func TestWatchResumeFromCompactionBackoff(t *testing.T) {
+	defer func(d time.Duration) { minCompactBackoff = d }(minCompactBackoff)
+	minCompactBackoff = time.Hour
+
+	w, fc := newFakeWatcher(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wch, creq := fc.watch(t, w, ctx, 0, "foo", WithRev(2), WithAutoResumeFromCompaction())
+	require.Equal(t, int64(2), creq.StartRevision)
+
+	// the first compaction resumes at once
+	fc.compact(0, 4)
+	require.True(t, recvWatchResponse(t, wch).CompactionResumed())
+	require.Equal(t, int64(4), fc.recvCreate(t).StartRevision)
+	fc.send(&pb.WatchResponse{WatchId: 1, Created: true})
+
+	// compacted again before it made progress, the watcher backs off
+	fc.compact(1, 6)
+	require.True(t, recvWatchResponse(t, wch).CompactionResumed())
+
+	// the other watchers of the stream are served meanwhile
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	wch2, creq := fc.watch(t, w, ctx2, 2, "bar")
+	require.Equal(t, []byte("bar"), creq.Key)
+	fc.put(2, 7)
+	require.Len(t, recvWatchResponse(t, wch2).Events, 1)
+
+	// the delayed watcher closes without resuming
+	cancel()
+	select {
+	case _, ok := <-wch:
+		require.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the delayed watcher to close")
+	}
+	select {
+	case req := <-fc.reqc:
+		t.Fatalf("unexpected request %+v", req)
+	default:
+	}
+}
+
+// TestWatchResumeFromCompactionBackoffReset ensures the backoff of a watcher
+// resuming from a compaction is reset once it makes progress.
+func TestWatchResumeFromCompactionBackoffReset(t *testing.T) {
+	defer func(d time.Duration) { minCompactBackoff = d }(minCompactBackoff)
+	minCompactBackoff = time.Hour
+
+	w, fc := newFakeWatcher(t)
+	wch, _ := fc.watch(t, w, context.Background(), 0, "foo", WithRev(2), WithAutoResumeFromCompaction())
+
+	fc.compact(0, 4)
+	require.True(t, recvWatchResponse(t, wch).CompactionResumed())
+	require.Equal(t, int64(4), fc.recvCreate(t).StartRevision)
+	fc.send(&pb.WatchResponse{WatchId: 1, Created: true})
+	fc.put(1, 5)
+	require.Len(t, recvWatchResponse(t, wch).Events, 1)
+
+	// the watcher made progress, so it resumes at once again
+	fc.compact(1, 6)
+	require.True(t, recvWatchResponse(t, wch).CompactionResumed())
+	require.Equal(t, int64(6), fc.recvCreate(t).StartRevision)
+}
@@ -0,0 +1,269 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// GlobalOrderMaxBuffered is the number of events a watch made with
+// WithGlobalOrder holds at most before it is canceled.
+const GlobalOrderMaxBuffered = 10000
+
+// ErrGlobalOrderBufferFull is the error of a watch made with WithGlobalOrder
+// canceled because one of its ranges held back too many events.
+var ErrGlobalOrderBufferFull = errors.New("etcdclient: too many events held for global order")
+
+// globalOrderProgressInterval is the interval at which the progress of the
+// ranges is requested while events are held.
+var globalOrderProgressInterval = 100 * time.Millisecond
+
+// orderedInput is a response received on the watch channel of range i.
+type orderedInput struct {
+	i      int
+	wr     WatchResponse
+	closed bool
+}
+
+// orderWatchResponses merges the responses of the watches of several ranges
+// into responses whose events are in revision order. The events of a range are
+// held until every range has progressed up to their revision, either by
+// delivering an event at or after it or by a progress notification; while
+// events are held, requestProgress is called to get the progress of the
+// ranges. Once more than maxBuffered events are held, the events that can be
+// ordered are sent and the watch is canceled with ErrGlobalOrderBufferFull.
+// The created and caught up notifications are sent once all the ranges have
+// sent them; a canceled or closed range cancels the whole watch. The ranges
+// start from startRev, or from the revision they were created at if startRev
+// is 0. cancel is called once the merged watch is done.
+func orderWatchResponses(ctx context.Context, cancel context.CancelFunc, wchs []WatchChan, startRev int64, requestProgress func(context.Context) error, maxBuffered int) WatchChan {
+	inc := make(chan orderedInput)
+	for i, wch := range wchs {
+		go func(i int, wch WatchChan) {
+			for {
+				var in orderedInput
+				select {
+				case wr, ok := <-wch:
+					in = orderedInput{i: i, wr: wr, closed: !ok}
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case inc <- in:
+				case <-ctx.Done():
+					return
+				}
+				if in.closed {
+					return
+				}
+			}
+		}(i, wch)
+	}
+
+	outc := make(chan WatchResponse)
+	go func() {
+		defer close(outc)
+		defer cancel()
+
+		var (
+			// progress is the revision up to which each range delivered its
+			// events
+			progress = make([]int64, len(wchs))
+			pending  = make([][]*Event, len(wchs))
+			npending int
+			// sentProgress is the last revision notified as progress
+			sentProgress int64
+			header       WatchResponse
+			created      = make([]bool, len(wchs))
+			caughtUp     = make([]bool, len(wchs))
+			ticker       *time.Ticker
+			tickc        <-chan time.Time
+		)
+		send := func(wr WatchResponse) bool {
+			select {
+			case outc <- wr:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		minProgress := func() int64 {
+			min := progress[0]
+			for _, p := range progress[1:] {
+				if p < min {
+					min = p
+				}
+			}
+			return min
+		}
+		// release sends the held events up to rev in revision order, the
+		// events of the lower ranges first within a revision.
+		release := func(rev int64) bool {
+			var evs []*Event
+			for {
+				next := -1
+				for i, p := range pending {
+					if len(p) == 0 || p[0].Kv.ModRevision > rev {
+						continue
+					}
+					if next == -1 || p[0].Kv.ModRevision < pending[next][0].Kv.ModRevision {
+						next = i
+					}
+				}
+				if next == -1 {
+					break
+				}
+				ev := pending[next][0]
+				pending[next] = pending[next][1:]
+				npending--
+				// a key in several ranges has its events in each of them
+				if !containsEvent(evs, ev) {
+					evs = append(evs, ev)
+				}
+			}
+			if len(evs) == 0 {
+				return true
+			}
+			if rev > sentProgress {
+				sentProgress = rev
+			}
+			wr := header
+			wr.Events = evs
+			return send(wr)
+		}
+		stopTicker := func() {
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tickc = nil, nil
+			}
+		}
+		defer stopTicker()
+
+		for {
+			var in orderedInput
+			select {
+			case in = <-inc:
+			case <-tickc:
+				requestProgress(ctx)
+				continue
+			case <-ctx.Done():
+				return
+			}
+
+			wr := in.wr
+			switch {
+			case in.closed || wr.Canceled:
+				// the events that can no longer be ordered are dropped; the
+				// watch resumes after the last event sent
+				if !release(minProgress()) || in.closed {
+					return
+				}
+				send(wr)
+				return
+			case wr.Created:
+				created[in.i] = true
+				// a range created at a revision at or after its start
+				// revision still sends the events from the start revision
+				p := wr.Header.Revision
+				if startRev > 0 && startRev-1 < p {
+					p = startRev - 1
+				}
+				if p > progress[in.i] {
+					progress[in.i] = p
+				}
+				if allSet(created) {
+					header.Header = wr.Header
+					if !send(wr) {
+						return
+					}
+				}
+				continue
+			case isEventsOnly(wr):
+				for _, ev := range wr.Events {
+					ev.RangeIndex = int32(in.i)
+				}
+				pending[in.i] = append(pending[in.i], wr.Events...)
+				npending += len(wr.Events)
+				progress[in.i] = wr.Events[len(wr.Events)-1].Kv.ModRevision
+				header.Header = wr.Header
+			case wr.IsProgressNotify():
+				if wr.Header.Revision > progress[in.i] {
+					progress[in.i] = wr.Header.Revision
+				}
+				header.Header = wr.Header
+			case wr.IsCaughtUp():
+				caughtUp[in.i] = true
+				if !allSet(caughtUp) {
+					continue
+				}
+				fallthrough
+			default:
+				if !release(minProgress()) || !send(wr) {
+					return
+				}
+				continue
+			}
+
+			rev := minProgress()
+			if !release(rev) {
+				return
+			}
+			if npending > maxBuffered {
+				send(WatchResponse{Header: header.Header, Canceled: true, closeErr: ErrGlobalOrderBufferFull})
+				return
+			}
+			if npending == 0 {
+				stopTicker()
+				if rev > sentProgress && wr.IsProgressNotify() {
+					sentProgress = rev
+					pwr := header
+					pwr.Header.Revision = rev
+					if !send(pwr) {
+						return
+					}
+				}
+				continue
+			}
+			if ticker == nil {
+				requestProgress(ctx)
+				ticker = time.NewTicker(globalOrderProgressInterval)
+				tickc = ticker.C
+			}
+		}
+	}()
+	return outc
+}
+
+// containsEvent returns true if evs, ordered by revision, ends with an event
+// on the key of ev at its revision.
+func containsEvent(evs []*Event, ev *Event) bool {
+	for i := len(evs) - 1; i >= 0 && evs[i].Kv.ModRevision == ev.Kv.ModRevision; i-- {
+		if string(evs[i].Kv.Key) == string(ev.Kv.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+func allSet(bs []bool) bool {
+	for _, b := range bs {
+		if !b {
+			return false
+		}
+	}
+	return true
+}
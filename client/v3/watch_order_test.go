@@ -0,0 +1,165 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// orderedFixture feeds the merged watch of n ranges and counts the progress
+// requests.
+type orderedFixture struct {
+	inc      []chan WatchResponse
+	out      WatchChan
+	progress chan struct{}
+}
+
+func newOrderedFixture(t *testing.T, n, maxBuffered int, startRev int64) *orderedFixture {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	f := &orderedFixture{progress: make(chan struct{}, 100)}
+	wchs := make([]WatchChan, n)
+	for i := range wchs {
+		c := make(chan WatchResponse)
+		f.inc = append(f.inc, c)
+		wchs[i] = c
+	}
+	requestProgress := func(context.Context) error {
+		select {
+		case f.progress <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	f.out = orderWatchResponses(ctx, cancel, wchs, startRev, requestProgress, maxBuffered)
+	return f
+}
+
+func putsAt(revs ...int64) WatchResponse {
+	wr := WatchResponse{Header: pb.ResponseHeader{Revision: revs[len(revs)-1]}}
+	for _, rev := range revs {
+		wr.Events = append(wr.Events, &Event{Type: EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte("k"), ModRevision: rev}})
+	}
+	return wr
+}
+
+func progressAt(rev int64) WatchResponse {
+	return WatchResponse{Header: pb.ResponseHeader{Revision: rev}}
+}
+
+// recvRevisions returns the revisions of the next n events of wch, whatever
+// the responses they are sent in.
+func recvRevisions(t *testing.T, wch WatchChan, n int) []int64 {
+	var revs []int64
+	for len(revs) < n {
+		wr := recvBatch(t, wch)
+		require.NotEmpty(t, wr.Events, "unexpected response %+v", wr)
+		revs = append(revs, revisions(wr)...)
+	}
+	return revs
+}
+
+func TestOrderWatchResponsesInterleaved(t *testing.T) {
+	f := newOrderedFixture(t, 2, 100, 0)
+
+	f.inc[0] <- putsAt(2, 4)
+	<-f.progress
+	f.inc[1] <- putsAt(3)
+	// range 1 may still send events at revision 4
+	f.inc[0] <- putsAt(5)
+	wr := recvBatch(t, f.out)
+	assert.Equal(t, []int64{2, 3}, revisions(wr))
+	assert.Equal(t, []int32{0, 1}, []int32{wr.Events[0].RangeIndex, wr.Events[1].RangeIndex})
+
+	// the progress of range 1 may be received before the event at revision
+	// 5, which is then sent on its own
+	f.inc[1] <- progressAt(6)
+	assert.Equal(t, []int64{4, 5}, recvRevisions(t, f.out, 2))
+
+	f.inc[0] <- progressAt(6)
+	wr = recvBatch(t, f.out)
+	require.True(t, wr.IsProgressNotify())
+	assert.Equal(t, int64(6), wr.Header.Revision)
+}
+
+func TestOrderWatchResponsesSameKeyInRanges(t *testing.T) {
+	f := newOrderedFixture(t, 2, 100, 0)
+
+	f.inc[1] <- putsAt(2)
+	f.inc[0] <- putsAt(2)
+	wr := recvBatch(t, f.out)
+	require.Len(t, wr.Events, 1)
+	assert.Equal(t, int32(0), wr.Events[0].RangeIndex)
+}
+
+func TestOrderWatchResponsesBufferFull(t *testing.T) {
+	f := newOrderedFixture(t, 2, 3, 0)
+
+	f.inc[1] <- putsAt(3)
+	f.inc[0] <- putsAt(2)
+	wr := recvBatch(t, f.out)
+	assert.Equal(t, []int64{2}, revisions(wr))
+
+	// range 1 stalls at revision 3
+	f.inc[0] <- putsAt(4, 5, 6)
+	wr = recvBatch(t, f.out)
+	assert.Equal(t, []int64{3}, revisions(wr))
+	f.inc[0] <- putsAt(7)
+	wr = recvBatch(t, f.out)
+	require.True(t, wr.Canceled)
+	assert.ErrorIs(t, wr.Err(), ErrGlobalOrderBufferFull)
+	_, ok := <-f.out
+	assert.False(t, ok)
+}
+
+func TestOrderWatchResponsesCreated(t *testing.T) {
+	f := newOrderedFixture(t, 2, 100, 0)
+
+	f.inc[0] <- WatchResponse{Header: pb.ResponseHeader{Revision: 1}, Created: true}
+	f.inc[1] <- WatchResponse{Header: pb.ResponseHeader{Revision: 1}, Created: true}
+	wr := recvBatch(t, f.out)
+	assert.True(t, wr.Created)
+
+	close(f.inc[0])
+	_, ok := <-f.out
+	assert.False(t, ok)
+}
+
+func TestOrderWatchResponsesCreatedAfterStartRev(t *testing.T) {
+	f := newOrderedFixture(t, 2, 100, 5)
+
+	f.inc[0] <- WatchResponse{Header: pb.ResponseHeader{Revision: 10}, Created: true}
+	f.inc[1] <- WatchResponse{Header: pb.ResponseHeader{Revision: 10}, Created: true}
+	wr := recvBatch(t, f.out)
+	assert.True(t, wr.Created)
+
+	// range 1 may still send its events from revision 5
+	f.inc[0] <- putsAt(6, 8)
+	<-f.progress
+	f.inc[1] <- putsAt(7)
+	wr = recvBatch(t, f.out)
+	assert.Equal(t, []int64{6, 7}, revisions(wr))
+
+	f.inc[1] <- progressAt(10)
+	wr = recvBatch(t, f.out)
+	assert.Equal(t, []int64{8}, revisions(wr))
+}
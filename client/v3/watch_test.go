@@ -15,6 +15,8 @@
 package clientv3
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -92,3 +94,16 @@ func TestShouldRetryWatch(t *testing.T) {
 		})
 	}
 }
+
+// TestReplayNotSupported ensures replaying on a Watcher that is not a Replayer
+// ends with ErrReplayNotSupported.
+func TestReplayNotSupported(t *testing.T) {
+	var w struct{ Watcher }
+	var errs []error
+	for wr := range Replay(context.TODO(), w, "foo", 1, 0) {
+		errs = append(errs, wr.Err())
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrReplayNotSupported) {
+		t.Fatalf("expected a single response with %v, got %v", ErrReplayNotSupported, errs)
+	}
+}
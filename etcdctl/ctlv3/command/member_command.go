@@ -27,9 +27,12 @@ import (
 )
 
 var (
-	memberPeerURLs    string
-	isLearner         bool
-	memberConsistency string
+	memberPeerURLs     string
+	isLearner          bool
+	nonPromotable      bool
+	leadershipTransfer bool
+	forceRemove        bool
+	memberConsistency  string
 )
 
 // NewMemberCommand returns the cobra command for "member".
@@ -59,6 +62,7 @@ func NewMemberAddCommand() *cobra.Command {
 
 	cc.Flags().StringVar(&memberPeerURLs, "peer-urls", "", "comma separated peer URLs for the new member.")
 	cc.Flags().BoolVar(&isLearner, "learner", false, "indicates if the new member is raft learner")
+	cc.Flags().BoolVar(&nonPromotable, "non-promotable", false, "indicates if the new learner can never be promoted to a voting member (requires --learner)")
 
 	return cc
 }
@@ -72,6 +76,9 @@ func NewMemberRemoveCommand() *cobra.Command {
 		Run: memberRemoveCommandFunc,
 	}
 
+	cc.Flags().BoolVar(&leadershipTransfer, "leadership-transfer", false, "transfers the leadership to a healthy voting member first if the removed member is the leader")
+	cc.Flags().BoolVar(&forceRemove, "force", false, "removes the member without checking that the other members are healthy, as long as the remaining members form a quorum")
+
 	return cc
 }
 
@@ -140,6 +147,10 @@ func memberAddCommandFunc(cmd *cobra.Command, args []string) {
 		cobrautl.ExitWithError(cobrautl.ExitBadArgs, errors.New("member peer urls not provided"))
 	}
 
+	if nonPromotable && !isLearner {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, errors.New("--non-promotable requires --learner"))
+	}
+
 	urls := strings.Split(memberPeerURLs, ",")
 	ctx, cancel := commandCtx(cmd)
 	cli := mustClientFromCmd(cmd)
@@ -148,7 +159,11 @@ func memberAddCommandFunc(cmd *cobra.Command, args []string) {
 		err  error
 	)
 	if isLearner {
-		resp, err = cli.MemberAddAsLearner(ctx, urls)
+		var opts []clientv3.MemberAddOption
+		if nonPromotable {
+			opts = append(opts, clientv3.WithNonPromotable())
+		}
+		resp, err = clientv3.MemberAddAsLearnerWithOptions(ctx, cli, urls, opts...)
 	} else {
 		resp, err = cli.MemberAdd(ctx, urls)
 	}
@@ -191,8 +206,16 @@ func memberRemoveCommandFunc(cmd *cobra.Command, args []string) {
 		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("bad member ID arg (%v), expecting ID in Hex", err))
 	}
 
+	var opts []clientv3.MemberRemoveOption
+	if leadershipTransfer {
+		opts = append(opts, clientv3.WithLeadershipTransfer())
+	}
+	if forceRemove {
+		opts = append(opts, clientv3.WithForce())
+	}
+
 	ctx, cancel := commandCtx(cmd)
-	resp, err := mustClientFromCmd(cmd).MemberRemove(ctx, id)
+	resp, err := mustClientFromCmd(cmd).MemberRemove(ctx, id, opts...)
 	cancel()
 	if err != nil {
 		cobrautl.ExitWithError(cobrautl.ExitError, err)
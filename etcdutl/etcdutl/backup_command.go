@@ -151,6 +151,7 @@ func HandleBackup(withV3 bool, srcDir string, destDir string, srcWAL string, des
 	verify.MustVerifyIfEnabled(verify.Config{
 		Logger:     lg,
 		DataDir:    destDir,
+		WALDir:     destWAL,
 		ExactIndex: false,
 	})
 
@@ -303,6 +303,7 @@ func (s *v3Manager) Restore(cfg RestoreConfig) error {
 		ExactIndex: true,
 		Logger:     s.lg,
 		DataDir:    dataDir,
+		WALDir:     s.walDir,
 	})
 }
 
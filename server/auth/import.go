@@ -0,0 +1,198 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"sort"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"go.etcd.io/etcd/api/v3/authpb"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+func (as *authStore) AuthExport(r *pb.AuthExportRequest) (*pb.AuthExportResponse, error) {
+	tx := as.be.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	return &pb.AuthExportResponse{Users: tx.UnsafeGetAllUsers(), Roles: tx.UnsafeGetAllRoles()}, nil
+}
+
+func (as *authStore) AuthImport(r *pb.AuthImportRequest) (*pb.AuthImportResponse, error) {
+	users, roles, err := importedAuth(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := as.be.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+
+	oldUsers := make(map[string]*authpb.User)
+	for _, u := range tx.UnsafeGetAllUsers() {
+		oldUsers[string(u.Name)] = u
+	}
+	oldRoles := make(map[string]*authpb.Role)
+	for _, role := range tx.UnsafeGetAllRoles() {
+		oldRoles[string(role.Name)] = role
+	}
+
+	// everything is checked before anything is written, so that a failed
+	// import leaves the auth store untouched
+	if !r.Replace {
+		for name, u := range users {
+			if old, ok := oldUsers[name]; ok && !proto.Equal(old, u) {
+				as.lg.Warn("cannot import a user that already exists", zap.String("user-name", name))
+				return nil, ErrUserAlreadyExist
+			}
+		}
+		for name, role := range roles {
+			if old, ok := oldRoles[name]; ok && !proto.Equal(old, role) {
+				as.lg.Warn("cannot import a role that already exists", zap.String("role-name", name))
+				return nil, ErrRoleAlreadyExist
+			}
+		}
+	}
+	for name, u := range users {
+		for _, role := range u.Roles {
+			if _, ok := roles[role]; ok || role == rootRole {
+				continue
+			}
+			if _, ok := oldRoles[role]; ok && !r.Replace {
+				continue
+			}
+			as.lg.Warn(
+				"cannot import a user granted a role that does not exist",
+				zap.String("user-name", name),
+				zap.String("role-name", role),
+			)
+			return nil, ErrRoleNotFound
+		}
+	}
+	if as.enabled {
+		root, ok := users[rootUser]
+		if !ok && !r.Replace {
+			root = oldUsers[rootUser]
+		}
+		if root == nil {
+			return nil, ErrRootUserNotExist
+		}
+		if !hasRootRole(root) {
+			return nil, ErrRootRoleNotExist
+		}
+	}
+
+	var changedUsers []string
+	if r.Replace {
+		for name := range oldUsers {
+			if _, ok := users[name]; !ok {
+				tx.UnsafeDeleteUser(name)
+				changedUsers = append(changedUsers, name)
+			}
+		}
+		for name := range oldRoles {
+			if _, ok := roles[name]; !ok && name != rootRole {
+				tx.UnsafeDeleteRole(name)
+			}
+		}
+	}
+	for name, u := range users {
+		old, ok := oldUsers[name]
+		if ok && proto.Equal(old, u) {
+			continue
+		}
+		tx.UnsafePutUser(u)
+		if ok {
+			changedUsers = append(changedUsers, name)
+		}
+	}
+	for name, role := range roles {
+		if old, ok := oldRoles[name]; !ok || !proto.Equal(old, role) {
+			tx.UnsafePutRole(role)
+		}
+	}
+
+	as.commitRevision(tx)
+	as.refreshRangePermCache(tx)
+
+	for _, name := range changedUsers {
+		as.tokenProvider.invalidateUser(name)
+		if as.tokenValidation != nil {
+			as.tokenValidation.invalidateUser(name)
+		}
+	}
+
+	as.lg.Info(
+		"imported users and roles",
+		zap.Int("user-count", len(users)),
+		zap.Int("role-count", len(roles)),
+		zap.Bool("replace", r.Replace),
+	)
+	return &pb.AuthImportResponse{}, nil
+}
+
+// importedAuth validates the users and roles of r and returns them by name,
+// with the roles of the users and the permissions of the roles sorted as the
+// auth store keeps them.
+func importedAuth(r *pb.AuthImportRequest) (map[string]*authpb.User, map[string]*authpb.Role, error) {
+	roles := make(map[string]*authpb.Role, len(r.Roles))
+	for _, role := range r.Roles {
+		if len(role.Name) == 0 {
+			return nil, nil, ErrRoleEmpty
+		}
+		if _, ok := roles[string(role.Name)]; ok {
+			return nil, nil, ErrRoleAlreadyExist
+		}
+		var perms []*authpb.Permission
+		for _, perm := range role.KeyPermission {
+			if !isValidPermissionRange(perm.Key, perm.RangeEnd) {
+				return nil, nil, ErrInvalidAuthMgmt
+			}
+			perms = append(perms, &authpb.Permission{PermType: perm.PermType, Key: perm.Key, RangeEnd: perm.RangeEnd})
+		}
+		sort.Stable(permSlice(perms))
+		roles[string(role.Name)] = &authpb.Role{Name: role.Name, KeyPermission: perms}
+	}
+
+	users := make(map[string]*authpb.User, len(r.Users))
+	for _, u := range r.Users {
+		if len(u.Name) == 0 {
+			return nil, nil, ErrUserEmpty
+		}
+		if _, ok := users[string(u.Name)]; ok {
+			return nil, nil, ErrUserAlreadyExist
+		}
+		userRoles := append([]string(nil), u.Roles...)
+		sort.Strings(userRoles)
+		userRoles = dedupSorted(userRoles)
+		users[string(u.Name)] = &authpb.User{Name: u.Name, Password: u.Password, Roles: userRoles, Options: u.Options}
+	}
+	return users, roles, nil
+}
+
+func dedupSorted(s []string) []string {
+	if len(s) == 0 {
+		return s
+	}
+	n := 1
+	for _, v := range s[1:] {
+		if v != s[n-1] {
+			s[n] = v
+			n++
+		}
+	}
+	return s[:n]
+}
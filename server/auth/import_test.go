@@ -0,0 +1,182 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/crypto/bcrypt"
+
+	"go.etcd.io/etcd/api/v3/authpb"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+func newTestAuthStore(t *testing.T) *authStore {
+	tp, err := NewTokenProvider(zaptest.NewLogger(t), tokenTypeSimple, dummyIndexWaiter, simpleTokenTTLDefault)
+	require.NoError(t, err)
+	as := NewAuthStore(zaptest.NewLogger(t), newBackendMock(), tp, bcrypt.MinCost)
+	t.Cleanup(func() { as.Close() })
+	return as
+}
+
+func TestAuthExportImport(t *testing.T) {
+	src, tearDown := setupAuthStore(t)
+	defer tearDown(t)
+	_, err := src.RoleGrantPermission(&pb.AuthRoleGrantPermissionRequest{
+		Name: "role-test",
+		Perm: &authpb.Permission{PermType: authpb.WRITE, Key: []byte("foo"), RangeEnd: []byte("fop")},
+	})
+	require.NoError(t, err)
+	_, err = src.UserGrantRole(&pb.AuthUserGrantRoleRequest{User: "foo", Role: "role-test"})
+	require.NoError(t, err)
+
+	exported, err := src.AuthExport(&pb.AuthExportRequest{})
+	require.NoError(t, err)
+	assert.Len(t, exported.Users, 3)
+	assert.Len(t, exported.Roles, 2)
+
+	dst := newTestAuthStore(t)
+	_, err = dst.AuthImport(&pb.AuthImportRequest{Users: exported.Users, Roles: exported.Roles})
+	require.NoError(t, err)
+	require.NoError(t, dst.AuthEnable())
+
+	imported, err := dst.AuthExport(&pb.AuthExportRequest{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, exported.Users, imported.Users)
+	assert.ElementsMatch(t, exported.Roles, imported.Roles)
+
+	// the passwords and permissions are carried over
+	_, err = dst.CheckPassword("foo", "bar")
+	assert.NoError(t, err)
+	rev := dst.Revision()
+	assert.NoError(t, dst.IsPutPermitted(&AuthInfo{Username: "foo", Revision: rev}, []byte("foo")))
+	assert.ErrorIs(t, dst.IsPutPermitted(&AuthInfo{Username: "foo", Revision: rev}, []byte("fop")), ErrPermissionDenied)
+	assert.NoError(t, dst.IsAdminPermitted(&AuthInfo{Username: "root", Revision: rev}))
+
+	// importing the same users and roles again changes nothing
+	_, err = dst.AuthImport(&pb.AuthImportRequest{Users: exported.Users, Roles: exported.Roles})
+	assert.NoError(t, err)
+}
+
+func TestAuthImportMergeConflict(t *testing.T) {
+	as, tearDown := setupAuthStore(t)
+	defer tearDown(t)
+
+	_, err := as.AuthImport(&pb.AuthImportRequest{
+		Users: []*authpb.User{
+			{Name: []byte("bar"), Password: []byte("bar")},
+			{Name: []byte("foo"), Password: []byte("other")},
+		},
+	})
+	assert.ErrorIs(t, err, ErrUserAlreadyExist)
+	_, err = as.AuthImport(&pb.AuthImportRequest{
+		Roles: []*authpb.Role{
+			{Name: []byte("role-test"), KeyPermission: []*authpb.Permission{{Key: []byte("foo")}}},
+		},
+	})
+	assert.ErrorIs(t, err, ErrRoleAlreadyExist)
+
+	// nothing is imported when the import fails
+	assert.False(t, as.userExists("bar"))
+	_, err = as.CheckPassword("foo", "bar")
+	assert.NoError(t, err)
+}
+
+func TestAuthImportReplace(t *testing.T) {
+	as, tearDown := setupAuthStore(t)
+	defer tearDown(t)
+	root := &authpb.User{Name: []byte("root"), Password: []byte("root"), Roles: []string{"root"}}
+
+	_, err := as.AuthImport(&pb.AuthImportRequest{
+		Users:   []*authpb.User{{Name: []byte("bar"), Roles: []string{"root"}}},
+		Replace: true,
+	})
+	assert.ErrorIs(t, err, ErrRootUserNotExist)
+	_, err = as.AuthImport(&pb.AuthImportRequest{
+		Users:   []*authpb.User{{Name: []byte("root"), Password: []byte("root"), Roles: []string{"role-bar"}}},
+		Roles:   []*authpb.Role{{Name: []byte("role-bar")}},
+		Replace: true,
+	})
+	assert.ErrorIs(t, err, ErrRootRoleNotExist)
+	_, err = as.AuthImport(&pb.AuthImportRequest{
+		Users:   []*authpb.User{root, {Name: []byte("bar"), Roles: []string{"role-test"}}},
+		Replace: true,
+	})
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+
+	_, err = as.AuthImport(&pb.AuthImportRequest{
+		Users:   []*authpb.User{root, {Name: []byte("bar"), Roles: []string{"role-bar"}}},
+		Roles:   []*authpb.Role{{Name: []byte("role-bar")}},
+		Replace: true,
+	})
+	require.NoError(t, err)
+
+	users, err := as.UserList(&pb.AuthUserListRequest{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bar", "root"}, users.Users)
+	// the root role is kept even though it was not imported
+	roles, err := as.RoleList(&pb.AuthRoleListRequest{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"role-bar", "root"}, roles.Roles)
+	assert.NoError(t, as.IsAdminPermitted(&AuthInfo{Username: "root", Revision: as.Revision()}))
+}
+
+func TestAuthImportInvalid(t *testing.T) {
+	as := newTestAuthStore(t)
+
+	tests := []struct {
+		name string
+		r    *pb.AuthImportRequest
+		err  error
+	}{
+		{
+			name: "empty user name",
+			r:    &pb.AuthImportRequest{Users: []*authpb.User{{}}},
+			err:  ErrUserEmpty,
+		},
+		{
+			name: "empty role name",
+			r:    &pb.AuthImportRequest{Roles: []*authpb.Role{{}}},
+			err:  ErrRoleEmpty,
+		},
+		{
+			name: "duplicate user",
+			r:    &pb.AuthImportRequest{Users: []*authpb.User{{Name: []byte("foo")}, {Name: []byte("foo")}}},
+			err:  ErrUserAlreadyExist,
+		},
+		{
+			name: "invalid permission",
+			r: &pb.AuthImportRequest{Roles: []*authpb.Role{{
+				Name:          []byte("role-foo"),
+				KeyPermission: []*authpb.Permission{{Key: []byte("b"), RangeEnd: []byte("a")}},
+			}}},
+			err: ErrInvalidAuthMgmt,
+		},
+		{
+			name: "user with unknown role",
+			r:    &pb.AuthImportRequest{Users: []*authpb.User{{Name: []byte("foo"), Roles: []string{"role-foo"}}}},
+			err:  ErrRoleNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := as.AuthImport(tt.r)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -142,6 +142,13 @@ type AuthStore interface {
 	// RoleList gets a list of all roles
 	RoleList(r *pb.AuthRoleListRequest) (*pb.AuthRoleListResponse, error)
 
+	// AuthExport gets all users and roles with their passwords and permissions
+	AuthExport(r *pb.AuthExportRequest) (*pb.AuthExportResponse, error)
+
+	// AuthImport merges the given users and roles into the auth store or
+	// replaces all users and roles with them
+	AuthImport(r *pb.AuthImportRequest) (*pb.AuthImportResponse, error)
+
 	// IsPutPermitted checks put permission of the user
 	IsPutPermitted(authInfo *AuthInfo, key []byte) error
 
@@ -260,6 +267,9 @@ type authStore struct {
 
 	tokenProvider TokenProvider
 	bcryptCost    int // the algorithm cost / strength for hashing auth passwords
+
+	// tokenValidation is nil unless a TokenValidator is set
+	tokenValidation *tokenValidation
 }
 
 func (as *authStore) AuthEnable() error {
@@ -315,6 +325,9 @@ func (as *authStore) AuthDisable() {
 
 	as.enabled = false
 	as.tokenProvider.disable()
+	if as.tokenValidation != nil {
+		as.tokenValidation.reset()
+	}
 
 	as.lg.Info("disabled authentication")
 }
@@ -485,6 +498,9 @@ func (as *authStore) UserDelete(r *pb.AuthUserDeleteRequest) (*pb.AuthUserDelete
 	as.refreshRangePermCache(tx)
 
 	as.tokenProvider.invalidateUser(r.Name)
+	if as.tokenValidation != nil {
+		as.tokenValidation.invalidateUser(r.Name)
+	}
 
 	as.lg.Info(
 		"deleted a user",
@@ -789,7 +805,28 @@ func (as *authStore) RoleAdd(r *pb.AuthRoleAddRequest) (*pb.AuthRoleAddResponse,
 }
 
 func (as *authStore) authInfoFromToken(ctx context.Context, token string) (*AuthInfo, bool) {
-	return as.tokenProvider.info(ctx, token, as.Revision())
+	if ai, ok := as.tokenProvider.info(ctx, token, as.Revision()); ok || as.tokenValidation == nil {
+		return ai, ok
+	}
+	username, ok := as.tokenValidation.info(ctx, token, as.userExists)
+	if !ok {
+		return nil, false
+	}
+	return &AuthInfo{Username: username, Revision: as.Revision()}, true
+}
+
+func (as *authStore) userExists(username string) bool {
+	tx := as.be.ReadTx()
+	tx.RLock()
+	defer tx.RUnlock()
+	return tx.UnsafeGetUser(username) != nil
+}
+
+// SetTokenValidator makes the auth store accept the tokens validated by v
+// in addition to its own tokens. It must be called before the auth store
+// serves requests.
+func (as *authStore) SetTokenValidator(v TokenValidator) {
+	as.tokenValidation = newTokenValidation(as.lg, v)
 }
 
 type permSlice []*authpb.Permission
@@ -0,0 +1,123 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxValidatedTokens bounds the number of cached token validations.
+const maxValidatedTokens = 4096
+
+// TokenValidator validates auth tokens issued outside of etcd, such as OIDC
+// JWTs. A validated token authenticates requests as an existing etcd user,
+// whose roles grant the permissions of the token, so the auth store remains
+// the authority for permissions.
+type TokenValidator interface {
+	// Validate validates the token. It returns the name of the etcd user the
+	// token authenticates as, and the time the validation expires.
+	Validate(ctx context.Context, token string) (username string, expiry time.Time, err error)
+}
+
+type validatedToken struct {
+	username string
+	expiry   time.Time
+}
+
+// tokenValidation caches the tokens validated by a TokenValidator until
+// their validation expires. Any failure of the validator rejects the token.
+type tokenValidation struct {
+	lg *zap.Logger
+	v  TokenValidator
+
+	mu     sync.Mutex
+	tokens map[string]validatedToken
+}
+
+func newTokenValidation(lg *zap.Logger, v TokenValidator) *tokenValidation {
+	return &tokenValidation{lg: lg, v: v, tokens: make(map[string]validatedToken)}
+}
+
+// info returns the user authenticated by the token. The exists function
+// reports whether a user exists.
+func (tv *tokenValidation) info(ctx context.Context, token string, exists func(username string) bool) (string, bool) {
+	now := time.Now()
+	tv.mu.Lock()
+	vt, ok := tv.tokens[token]
+	if ok && now.Before(vt.expiry) {
+		tv.mu.Unlock()
+		return vt.username, true
+	}
+	delete(tv.tokens, token)
+	tv.mu.Unlock()
+
+	username, expiry, err := tv.validate(ctx, token)
+	switch {
+	case err != nil:
+		tv.lg.Warn("failed to validate an auth token", zap.Error(err))
+		return "", false
+	case username == "" || !now.Before(expiry):
+		tv.lg.Warn("validated auth token has no user or has expired", zap.String("user-name", username), zap.Time("expiry", expiry))
+		return "", false
+	case !exists(username):
+		tv.lg.Warn("validated auth token maps to an unknown user", zap.String("user-name", username))
+		return "", false
+	}
+
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+	if len(tv.tokens) >= maxValidatedTokens {
+		for t, vt := range tv.tokens {
+			if !now.Before(vt.expiry) {
+				delete(tv.tokens, t)
+			}
+		}
+	}
+	if len(tv.tokens) < maxValidatedTokens {
+		tv.tokens[token] = validatedToken{username: username, expiry: expiry}
+	}
+	return username, true
+}
+
+// validate calls the validator, turning a panic into an error.
+func (tv *tokenValidation) validate(ctx context.Context, token string) (username string, expiry time.Time, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			username, err = "", ErrInvalidAuthToken
+			tv.lg.Error("auth token validator panicked", zap.Any("panic", r))
+		}
+	}()
+	return tv.v.Validate(ctx, token)
+}
+
+func (tv *tokenValidation) invalidateUser(username string) {
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+	for t, vt := range tv.tokens {
+		if vt.username == username {
+			delete(tv.tokens, t)
+		}
+	}
+}
+
+func (tv *tokenValidation) reset() {
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+	tv.tokens = make(map[string]validatedToken)
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"go.etcd.io/etcd/api/v3/authpb"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// fakeValidator validates tokens of the form "user.expiry.signature", where
+// the signature is the HMAC of "user.expiry".
+type fakeValidator struct {
+	key   []byte
+	calls atomic.Int32
+	panic bool
+}
+
+func (v *fakeValidator) sign(username string, expiry time.Time) string {
+	payload := fmt.Sprintf("%s.%d", username, expiry.UnixNano())
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte(payload))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (v *fakeValidator) Validate(_ context.Context, token string) (string, time.Time, error) {
+	v.calls.Add(1)
+	if v.panic {
+		panic("validator failure")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", time.Time{}, errors.New("malformed token")
+	}
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := hex.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", time.Time{}, errors.New("invalid signature")
+	}
+	nsec, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return parts[0], time.Unix(0, nsec), nil
+}
+
+func tokenCtx(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{rpctypes.TokenFieldNameGRPC: token}))
+}
+
+func setupTokenValidator(t *testing.T) (*authStore, *fakeValidator, func(t *testing.T)) {
+	as, tearDown := setupAuthStore(t)
+
+	_, err := as.RoleGrantPermission(&pb.AuthRoleGrantPermissionRequest{
+		Name: "role-test",
+		Perm: &authpb.Permission{PermType: authpb.READ, Key: []byte("foo"), RangeEnd: []byte("fop")},
+	})
+	require.NoError(t, err)
+	_, err = as.UserGrantRole(&pb.AuthUserGrantRoleRequest{User: "foo", Role: "role-test"})
+	require.NoError(t, err)
+
+	v := &fakeValidator{key: []byte("secret")}
+	as.SetTokenValidator(v)
+	return as, v, tearDown
+}
+
+func TestTokenValidatorPermissions(t *testing.T) {
+	as, v, tearDown := setupTokenValidator(t)
+	defer tearDown(t)
+
+	ai, err := as.AuthInfoFromCtx(tokenCtx(v.sign("foo", time.Now().Add(time.Hour))))
+	require.NoError(t, err)
+	require.NotNil(t, ai)
+	assert.Equal(t, "foo", ai.Username)
+
+	assert.NoError(t, as.IsRangePermitted(ai, []byte("foo"), nil))
+	assert.NoError(t, as.IsRangePermitted(ai, []byte("foo1"), []byte("foo2")))
+	assert.ErrorIs(t, as.IsRangePermitted(ai, []byte("bar"), nil), ErrPermissionDenied)
+	assert.ErrorIs(t, as.IsPutPermitted(ai, []byte("foo")), ErrPermissionDenied)
+
+	// the permissions follow the role of the user
+	_, err = as.RoleGrantPermission(&pb.AuthRoleGrantPermissionRequest{
+		Name: "role-test",
+		Perm: &authpb.Permission{PermType: authpb.READWRITE, Key: []byte("foo"), RangeEnd: []byte("fop")},
+	})
+	require.NoError(t, err)
+	ai, err = as.AuthInfoFromCtx(tokenCtx(v.sign("foo", time.Now().Add(time.Hour))))
+	require.NoError(t, err)
+	assert.NoError(t, as.IsPutPermitted(ai, []byte("foo")))
+}
+
+func TestTokenValidatorRejects(t *testing.T) {
+	as, v, tearDown := setupTokenValidator(t)
+	defer tearDown(t)
+
+	valid := v.sign("foo", time.Now().Add(time.Hour))
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"bad signature", valid[:len(valid)-1] + "0"},
+		{"malformed", "garbage"},
+		{"expired", v.sign("foo", time.Now().Add(-time.Second))},
+		{"unknown user", v.sign("nobody", time.Now().Add(time.Hour))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := as.AuthInfoFromCtx(tokenCtx(tt.token))
+			assert.ErrorIs(t, err, ErrInvalidAuthToken)
+		})
+	}
+
+	// a failing validator rejects every token
+	v.panic = true
+	_, err := as.AuthInfoFromCtx(tokenCtx(valid))
+	assert.ErrorIs(t, err, ErrInvalidAuthToken)
+}
+
+func TestTokenValidatorCache(t *testing.T) {
+	as, v, tearDown := setupTokenValidator(t)
+	defer tearDown(t)
+
+	token := v.sign("foo", time.Now().Add(500*time.Millisecond))
+	for i := 0; i < 3; i++ {
+		_, err := as.AuthInfoFromCtx(tokenCtx(token))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), v.calls.Load())
+
+	// an expired validation is not served from the cache
+	time.Sleep(time.Second)
+	_, err := as.AuthInfoFromCtx(tokenCtx(token))
+	assert.ErrorIs(t, err, ErrInvalidAuthToken)
+	assert.Equal(t, int32(2), v.calls.Load())
+
+	// deleting the user drops its cached tokens
+	token = v.sign("foo", time.Now().Add(time.Hour))
+	_, err = as.AuthInfoFromCtx(tokenCtx(token))
+	require.NoError(t, err)
+	_, err = as.UserDelete(&pb.AuthUserDeleteRequest{Name: "foo"})
+	require.NoError(t, err)
+	_, err = as.AuthInfoFromCtx(tokenCtx(token))
+	assert.ErrorIs(t, err, ErrInvalidAuthToken)
+}
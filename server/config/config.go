@@ -22,12 +22,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	"go.etcd.io/etcd/client/pkg/v3/types"
 	"go.etcd.io/etcd/pkg/v3/netutil"
+	"go.etcd.io/etcd/server/v3/auth"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3compactor"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3discovery"
 	"go.etcd.io/etcd/server/v3/storage/datadir"
 
@@ -48,6 +51,14 @@ type ServerConfig struct {
 	// DedicatedWALDir config will make the etcd to write the WAL to the WALDir
 	// rather than the dataDir/member/wal.
 	DedicatedWALDir string
+	// DedicatedSnapDir config will make the etcd to write the snapshots to it
+	// rather than the dataDir/member/snap. It must be on the filesystem of the
+	// backend, as a snapshot received from the leader is renamed into the
+	// backend.
+	DedicatedSnapDir string
+	// DedicatedBackendDir config will make the etcd to keep the backend in it
+	// rather than in the dataDir/member/snap.
+	DedicatedBackendDir string
 
 	SnapshotCount uint64
 
@@ -61,7 +72,12 @@ type ServerConfig struct {
 	MaxSnapFiles uint
 	MaxWALFiles  uint
 
+	// WALSlowFsyncThreshold is the duration after which a WAL fsync is
+	// logged and counted as slow.
+	WALSlowFsyncThreshold time.Duration
+
 	// BackendBatchInterval is the maximum time before commit the backend transaction.
+	// Intervals shorter than storage.MinimumBackendBatchInterval are raised to it.
 	BackendBatchInterval time.Duration
 	// BackendBatchLimit is the maximum operations before commit the backend transaction.
 	BackendBatchLimit int
@@ -69,6 +85,17 @@ type ServerConfig struct {
 	// BackendFreelistType is the type of the backend boltdb freelist.
 	BackendFreelistType bolt.FreelistType
 
+	// DefragInterval is the interval between scheduled online defragmentations.
+	DefragInterval time.Duration
+	// DefragThresholdMegabytes is the minimum number of megabytes of free
+	// space in the backend for a scheduled defragmentation to run.
+	DefragThresholdMegabytes uint
+	// DefragRateBytesPerSec limits the rate at which a scheduled
+	// defragmentation copies the backend. 0 means no limit.
+	DefragRateBytesPerSec uint
+	// DefragOnLeader allows scheduled defragmentations on the leader.
+	DefragOnLeader bool
+
 	InitialPeerURLsMap  types.URLsMap
 	InitialClusterToken string
 	NewCluster          bool
@@ -84,6 +111,16 @@ type ServerConfig struct {
 	TickMs        uint
 	ElectionTicks int
 
+	// TickClock drives the raft node ticker. If nil, the real clock is used.
+	// Tests may inject a clock they control to advance raft ticks
+	// deterministically instead of waiting for wall-clock time to pass.
+	TickClock clockwork.Clock
+
+	// InitialMemberIDs overrides, by member name, the IDs the members of a
+	// new cluster derive from their peer URLs and the cluster token. Tests use
+	// it to know the member IDs before the cluster starts.
+	InitialMemberIDs map[string]types.ID
+
 	// WaitClusterReadyTimeout is the maximum time to wait for the
 	// cluster to be ready on startup before serving client requests.
 	WaitClusterReadyTimeout time.Duration
@@ -117,24 +154,66 @@ type ServerConfig struct {
 	// See https://github.com/etcd-io/etcd/issues/9333 for more detail.
 	InitialElectionTickAdvance bool
 
+	// ElectionPriority is the priority of the member to become the leader,
+	// published with its attributes.
+	ElectionPriority uint32
+
 	BootstrapTimeout time.Duration
 
 	AutoCompactionRetention time.Duration
 	AutoCompactionMode      string
-	CompactionBatchLimit    int
-	CompactionSleepInterval time.Duration
-	QuotaBackendBytes       int64
-	MaxTxnOps               uint
+	// AutoCompactionPrefixRetention overrides AutoCompactionRetention for
+	// the keys under its prefixes.
+	AutoCompactionPrefixRetention []v3compactor.PrefixRetention
+	CompactionBatchLimit          int
+	CompactionSleepInterval       time.Duration
+	CompactionSleep               time.Duration
+	QuotaBackendBytes             int64
+	MaxTxnOps                     uint
+	// MaxTxnTotalOps limits the comparisons and operations of a txn, counting
+	// both branches and the nested txns together. Zero means three times
+	// MaxTxnOps.
+	MaxTxnTotalOps uint
+
+	// HotKeyTracking enables sampling key accesses to report the most read
+	// and most written keys.
+	HotKeyTracking bool
+
+	// ReadCacheBytes bounds the size of the in-memory cache of the key-value
+	// pairs read by point gets. 0 disables the cache.
+	ReadCacheBytes int64
 
 	// MaxRequestBytes is the maximum request size to send over raft.
 	MaxRequestBytes uint
 
+	// MaxValueBytes is the maximum size of a single value in a put or txn
+	// request. 0 means no limit other than MaxRequestBytes.
+	MaxValueBytes uint
+
+	// MaxApplyBacklog is the number of committed entries waiting to be
+	// applied above which new proposals are rejected with ErrTooManyRequests.
+	// 0 means the default backlog.
+	MaxApplyBacklog uint64
+
 	// MaxConcurrentStreams specifies the maximum number of concurrent
 	// streams that each client can open at a time.
 	MaxConcurrentStreams uint32
+	// MaxWatchStreamsPerConnection is the maximum number of watch streams
+	// that each client connection can open at a time. 0 means no limit.
+	MaxWatchStreamsPerConnection uint
 
 	WarningApplyDuration        time.Duration
 	WarningUnaryRequestDuration time.Duration
+	// WarningApplyDurationByRequest overrides WarningApplyDuration for the
+	// request types it maps, such as "Put" or "Txn".
+	WarningApplyDurationByRequest map[string]time.Duration
+
+	// WriteRateLimitByUser limits the writes and lease grants of the auth
+	// users it maps to the given number of requests per second. The empty user name limits
+	// the requests which are not authenticated.
+	WriteRateLimitByUser map[string]float64
+	// RateLimitReads makes WriteRateLimitByUser limit the reads too.
+	RateLimitReads bool
 
 	StrictReconfigCheck bool
 
@@ -144,6 +223,8 @@ type ServerConfig struct {
 	AuthToken  string
 	BcryptCost uint
 	TokenTTL   uint
+	// AuthTokenValidator validates auth tokens issued outside of etcd.
+	AuthTokenValidator auth.TokenValidator
 
 	// InitialCorruptCheck is true to check data corruption on boot
 	// before serving any peer/client traffic.
@@ -169,9 +250,15 @@ type ServerConfig struct {
 	LeaseCheckpointInterval time.Duration
 	// LeaseCheckpointPersist enables persisting remainingTTL to prevent indefinite auto-renewal of long lived leases. Always enabled in v3.6. Should be used to ensure smooth upgrade from v3.5 clusters with this feature enabled.
 	LeaseCheckpointPersist bool
+	// LeaseExpiryJitter is the window over which the revocation of leases expiring at the same time is spread.
+	LeaseExpiryJitter time.Duration
 
 	EnableGRPCGateway bool
 
+	// PprofAuth restricts the pprof endpoints to the users with the root
+	// role when auth is enabled.
+	PprofAuth bool
+
 	// ExperimentalEnableDistributedTracing enables distributed tracing using OpenTelemetry protocol.
 	ExperimentalEnableDistributedTracing bool
 	// ExperimentalTracerOptions are options for OpenTelemetry gRPC interceptor.
@@ -243,6 +330,56 @@ func (c *ServerConfig) VerifyJoinExisting() error {
 	return nil
 }
 
+// VerifyDirs sanity-checks the dedicated WAL, snapshot and backend
+// directories: none of them may contain the data directory or lie in the
+// member directory, other than at its default location, and the WAL directory
+// must not overlap the snapshot and backend directories.
+func (c *ServerConfig) VerifyDirs() error {
+	dataDir, err := filepath.Abs(c.DataDir)
+	if err != nil {
+		return err
+	}
+	memberDir := datadir.ToMemberDir(dataDir)
+	dirs := []struct {
+		name, dir, defaultDir string
+	}{
+		{"wal", c.DedicatedWALDir, datadir.ToWalDir(dataDir)},
+		{"snapshot", c.DedicatedSnapDir, datadir.ToSnapDir(dataDir)},
+		{"backend", c.DedicatedBackendDir, datadir.ToSnapDir(dataDir)},
+	}
+	absDirs := make([]string, len(dirs))
+	for i, d := range dirs {
+		absDirs[i] = d.defaultDir
+		if d.dir == "" {
+			continue
+		}
+		if absDirs[i], err = filepath.Abs(d.dir); err != nil {
+			return err
+		}
+		if absDirs[i] == d.defaultDir {
+			continue
+		}
+		if isSubDir(dataDir, absDirs[i]) {
+			return fmt.Errorf("%s dir %q must not contain data dir %q", d.name, d.dir, c.DataDir)
+		}
+		if isSubDir(absDirs[i], memberDir) {
+			return fmt.Errorf("%s dir %q must not be in member dir %q", d.name, d.dir, memberDir)
+		}
+	}
+	for i := 1; i < len(dirs); i++ {
+		if isSubDir(absDirs[0], absDirs[i]) || isSubDir(absDirs[i], absDirs[0]) {
+			return fmt.Errorf("wal dir %q must not overlap %s dir %q", absDirs[0], dirs[i].name, absDirs[i])
+		}
+	}
+	return nil
+}
+
+// isSubDir returns true if dir is base or one of its subdirectories.
+func isSubDir(dir, base string) bool {
+	rel, err := filepath.Rel(base, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // hasLocalMember checks that the cluster at least contains the local server.
 func (c *ServerConfig) hasLocalMember() error {
 	if urls := c.InitialPeerURLsMap[c.Name]; urls == nil {
@@ -312,7 +449,12 @@ func (c *ServerConfig) WALDir() string {
 	return datadir.ToWalDir(c.DataDir)
 }
 
-func (c *ServerConfig) SnapDir() string { return filepath.Join(c.MemberDir(), "snap") }
+func (c *ServerConfig) SnapDir() string {
+	if c.DedicatedSnapDir != "" {
+		return c.DedicatedSnapDir
+	}
+	return filepath.Join(c.MemberDir(), "snap")
+}
 
 func (c *ServerConfig) ShouldDiscover() bool {
 	return c.DiscoveryURL != "" || len(c.DiscoveryCfg.Endpoints) > 0
@@ -325,6 +467,16 @@ func (c *ServerConfig) ReqTimeout() time.Duration {
 	return 5*time.Second + 2*time.Duration(c.ElectionTicks*int(c.TickMs))*time.Millisecond
 }
 
+// WarningApplyDurationOf returns the time duration after which a warning is
+// generated if applying a request of the given type takes more time. Request
+// types without an override fall back to WarningApplyDuration.
+func (c *ServerConfig) WarningApplyDurationOf(requestType string) time.Duration {
+	if d, ok := c.WarningApplyDurationByRequest[requestType]; ok {
+		return d
+	}
+	return c.WarningApplyDuration
+}
+
 func (c *ServerConfig) ElectionTimeout() time.Duration {
 	return time.Duration(c.ElectionTicks*int(c.TickMs)) * time.Millisecond
 }
@@ -355,4 +507,9 @@ func (c *ServerConfig) BootstrapTimeoutEffective() time.Duration {
 	return time.Second
 }
 
-func (c *ServerConfig) BackendPath() string { return datadir.ToBackendFileName(c.DataDir) }
+func (c *ServerConfig) BackendPath() string {
+	if c.DedicatedBackendDir != "" {
+		return datadir.ToBackendFileNameInDir(c.DedicatedBackendDir)
+	}
+	return datadir.ToBackendFileName(c.DataDir)
+}
@@ -194,6 +194,74 @@ func TestWALDir(t *testing.T) {
 	}
 }
 
+func TestConfigVerifyDirs(t *testing.T) {
+	tests := []struct {
+		walDir     string
+		snapDir    string
+		backendDir string
+		ok         bool
+	}{
+		{"", "", "", true},
+		{"/var/lib/etcd-wal", "", "", true},
+		{"/wal", "", "", true},
+		{"/var/lib/etcd/wal", "", "", true},
+		{"/var/lib/etcd/member/wal", "", "", true},
+		{"/var/lib/etcd/member/wal/", "", "", true},
+		{"/var/lib/etcd/..wal", "", "", true},
+		{"/var/lib/etcd", "", "", false},
+		{"/var/lib/etcd/", "", "", false},
+		{"/var/lib", "", "", false},
+		{"/", "", "", false},
+		{"/var/lib/etcd/member", "", "", false},
+		{"/var/lib/etcd/member/snap", "", "", false},
+		{"/var/lib/etcd/member/snap/wal", "", "", false},
+
+		{"", "/var/lib/etcd-snap", "", true},
+		{"", "", "/var/lib/etcd-db", true},
+		{"/wal", "/snap", "/db", true},
+		{"", "/data", "/data", true},
+		{"", "/data", "/data/db", true},
+		{"", "/var/lib/etcd/member/snap", "/db", true},
+		{"", "/var/lib/etcd/snap", "", true},
+		{"", "/var/lib/etcd", "", false},
+		{"", "", "/var/lib", false},
+		{"", "/var/lib/etcd/member/snap2", "", false},
+		{"", "", "/var/lib/etcd/member/wal", false},
+		{"/data", "/data", "", false},
+		{"/data", "", "/data/db", false},
+		{"/data/wal", "/data", "", false},
+		{"/wal", "/snap", "/wal/db", false},
+	}
+	for _, tt := range tests {
+		cfg := ServerConfig{
+			DataDir:             "/var/lib/etcd",
+			DedicatedWALDir:     tt.walDir,
+			DedicatedSnapDir:    tt.snapDir,
+			DedicatedBackendDir: tt.backendDir,
+		}
+		if err := cfg.VerifyDirs(); (err == nil) != tt.ok {
+			t.Errorf("DedicatedWALDir=%q DedicatedSnapDir=%q DedicatedBackendDir=%q: VerifyDirs()=%v, want ok=%v", tt.walDir, tt.snapDir, tt.backendDir, err, tt.ok)
+		}
+	}
+}
+
+func TestSnapDirBackendPath(t *testing.T) {
+	cfg := ServerConfig{DataDir: "/var/lib/etcd"}
+	if got, want := cfg.SnapDir(), "/var/lib/etcd/member/snap"; got != want {
+		t.Errorf("SnapDir()=%q, want %q", got, want)
+	}
+	if got, want := cfg.BackendPath(), "/var/lib/etcd/member/snap/db"; got != want {
+		t.Errorf("BackendPath()=%q, want %q", got, want)
+	}
+	cfg.DedicatedSnapDir, cfg.DedicatedBackendDir = "/snap", "/db"
+	if got, want := cfg.SnapDir(), "/snap"; got != want {
+		t.Errorf("SnapDir()=%q, want %q", got, want)
+	}
+	if got, want := cfg.BackendPath(), "/db/db"; got != want {
+		t.Errorf("BackendPath()=%q, want %q", got, want)
+	}
+}
+
 func TestShouldDiscover(t *testing.T) {
 	tests := map[string]bool{
 		"":                              false,
@@ -36,6 +36,7 @@ import (
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/pkg/v3/flags"
 	"go.etcd.io/etcd/pkg/v3/netutil"
+	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/config"
 	"go.etcd.io/etcd/server/v3/etcdserver"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
@@ -58,6 +59,7 @@ const (
 	DefaultName                        = "default"
 	DefaultMaxSnapshots                = 5
 	DefaultMaxWALs                     = 5
+	DefaultWALSlowFsyncThreshold       = time.Second
 	DefaultMaxTxnOps                   = uint(128)
 	DefaultWarningApplyDuration        = 100 * time.Millisecond
 	DefaultWarningUnaryRequestDuration = 300 * time.Millisecond
@@ -67,6 +69,7 @@ const (
 	DefaultGRPCKeepAliveInterval       = 2 * time.Hour
 	DefaultGRPCKeepAliveTimeout        = 20 * time.Second
 	DefaultDowngradeCheckTime          = 5 * time.Second
+	DefaultLeaseCheckpointInterval     = 5 * time.Minute
 	DefaultWaitClusterReadyTimeout     = 5 * time.Second
 	DefaultAutoCompactionMode          = "periodic"
 
@@ -150,6 +153,11 @@ type Config struct {
 	Name   string `json:"name"`
 	Dir    string `json:"data-dir"`
 	WalDir string `json:"wal-dir"`
+	// SnapDir is the dedicated directory of the snapshots. It must be on the
+	// filesystem of the backend.
+	SnapDir string `json:"snapshot-dir"`
+	// BackendDir is the dedicated directory of the backend.
+	BackendDir string `json:"backend-dir"`
 
 	SnapshotCount uint64 `json:"snapshot-count"`
 
@@ -163,6 +171,10 @@ type Config struct {
 	MaxSnapFiles uint `json:"max-snapshots"`
 	MaxWalFiles  uint `json:"max-wals"`
 
+	// WALSlowFsyncThreshold is the duration after which a WAL fsync is logged
+	// and counted as slow.
+	WALSlowFsyncThreshold time.Duration `json:"wal-slow-fsync-threshold"`
+
 	// TickMs is the number of milliseconds between heartbeat ticks.
 	// TODO: decouple tickMs and heartbeat tick (current heartbeat tick = 1).
 	// make ticks a cluster wide configuration.
@@ -198,7 +210,13 @@ type Config struct {
 	// See https://github.com/etcd-io/etcd/issues/9333 for more detail.
 	InitialElectionTickAdvance bool `json:"initial-election-tick-advance"`
 
+	// ElectionPriority is the priority of the member to become the leader.
+	// The leader transfers its leadership to a healthy, up-to-date voting
+	// member with a higher priority than its own. 0 is the lowest priority.
+	ElectionPriority uint32 `json:"election-priority"`
+
 	// BackendBatchInterval is the maximum time before commit the backend transaction.
+	// Intervals shorter than 10ms are raised to 10ms, since every commit syncs the backend.
 	BackendBatchInterval time.Duration `json:"backend-batch-interval"`
 	// BackendBatchLimit is the maximum operations before commit the backend transaction.
 	BackendBatchLimit int `json:"backend-batch-limit"`
@@ -207,10 +225,39 @@ type Config struct {
 	QuotaBackendBytes   int64  `json:"quota-backend-bytes"`
 	MaxTxnOps           uint   `json:"max-txn-ops"`
 	MaxRequestBytes     uint   `json:"max-request-bytes"`
+	// MaxTxnTotalOps is the maximum number of comparisons and operations of
+	// a transaction, counting both branches and the nested transactions
+	// together. 0 means three times MaxTxnOps, which admits every
+	// transaction without nested transactions.
+	MaxTxnTotalOps uint `json:"max-txn-total-ops"`
+	// ReadCacheBytes is the maximum size in bytes of the in-memory cache of
+	// the key-value pairs read by point gets. 0 disables the cache.
+	ReadCacheBytes int64 `json:"read-cache-bytes"`
+	// MaxValueBytes is the maximum size in bytes of a single value in a put
+	// or txn request. 0 means no limit other than the max request size.
+	MaxValueBytes uint `json:"max-value-bytes"`
+	// MaxApplyBacklog is the number of committed entries waiting to be
+	// applied above which new writes are rejected to shed load.
+	MaxApplyBacklog uint64 `json:"max-apply-backlog"`
+
+	// DefragInterval is the interval between scheduled online defragmentations of the backend.
+	// If only DefragThresholdMegabytes is set, the free space is checked every minute.
+	DefragInterval time.Duration `json:"defrag-interval"`
+	// DefragThresholdMegabytes is the minimum number of megabytes of free space in the backend
+	// for a scheduled defragmentation to run.
+	DefragThresholdMegabytes uint `json:"defrag-threshold-megabytes"`
+	// DefragRateBytesPerSec limits the rate at which a scheduled defragmentation copies the backend.
+	// 0 means no limit.
+	DefragRateBytesPerSec uint `json:"defrag-rate-bytes-per-sec"`
+	// DefragOnLeader allows scheduled defragmentations to run on the leader.
+	DefragOnLeader bool `json:"defrag-on-leader"`
 
 	// MaxConcurrentStreams specifies the maximum number of concurrent
 	// streams that each client can open at a time.
 	MaxConcurrentStreams uint32 `json:"max-concurrent-streams"`
+	// MaxWatchStreamsPerConnection is the maximum number of watch streams
+	// that each client connection can open at a time. 0 means no limit.
+	MaxWatchStreamsPerConnection uint `json:"max-watch-streams-per-connection"`
 
 	ListenPeerUrls, ListenClientUrls, ListenClientHttpUrls []url.URL
 	AdvertisePeerUrls, AdvertiseClientUrls                 []url.URL
@@ -253,6 +300,24 @@ type Config struct {
 	// If no time unit is provided and compaction mode is 'periodic',
 	// the unit defaults to hour. For example, '5' translates into 5-hour.
 	AutoCompactionRetention string `json:"auto-compaction-retention"`
+	// ExperimentalAutoCompactionPrefixRetention maps key prefixes to the
+	// retention of their keys, which overrides AutoCompactionRetention. A
+	// retention with a time unit (e.g. '1h') keeps that duration of history,
+	// while a number (e.g. '10') keeps that many revisions, whatever the
+	// compaction mode. A key under several prefixes keeps the longest retention.
+	// Compactions retaining prefixes are not verified by the compaction hash
+	// check, and downgrading to v3.5 compacts every key to the shortest
+	// retention.
+	ExperimentalAutoCompactionPrefixRetention map[string]string `json:"experimental-auto-compaction-prefix-retention"`
+	// CompactionBatchInterval is the interval between the starts of the
+	// batches of a compaction. It replaces ExperimentalCompactionSleepInterval.
+	CompactionBatchInterval time.Duration `json:"compaction-batch-interval"`
+	// CompactionSleep is the pause after each batch of a compaction, which
+	// lets the reads waiting for the backend run at the cost of a longer
+	// compaction. It is skipped while another compaction waits behind the
+	// running one, so that the keyspace does not grow faster than compaction
+	// reclaims it.
+	CompactionSleep time.Duration `json:"compaction-sleep"`
 
 	// GRPCKeepAliveMinTime is the minimum interval that a client should
 	// wait before pinging server. When client pings "too fast", server
@@ -272,6 +337,10 @@ type Config struct {
 	// SocketOpts are socket options passed to listener config.
 	SocketOpts transport.SocketOpts `json:"socket-options"`
 
+	// LeaseExpiryJitter is the window over which the revocation of leases
+	// expiring at the same time is spread. 0 to disable.
+	LeaseExpiryJitter time.Duration `json:"lease-expiry-jitter"`
+
 	// PreVote is true to enable Raft Pre-Vote.
 	// If enabled, Raft runs an additional election phase
 	// to check whether it would get enough votes to win
@@ -319,6 +388,10 @@ type Config struct {
 	//	}
 	//	embed.StartEtcd(cfg)
 	ServiceRegister func(*grpc.Server) `json:"-"`
+	// AuthTokenValidator, if set, validates auth tokens issued outside of
+	// etcd, such as OIDC JWTs, and maps them to etcd users. It is only used
+	// for embedding etcd into other applications.
+	AuthTokenValidator auth.TokenValidator `json:"-"`
 
 	AuthToken  string `json:"auth-token"`
 	BcryptCost uint   `json:"bcrypt-cost"`
@@ -338,13 +411,22 @@ type Config struct {
 	// Deprecated in v3.6.
 	// TODO: Delete in v3.7
 	ExperimentalEnableLeaseCheckpointPersist bool `json:"experimental-enable-lease-checkpoint-persist"`
-	ExperimentalCompactionBatchLimit         int  `json:"experimental-compaction-batch-limit"`
+	// ExperimentalLeaseCheckpointInterval is the duration between the checkpoints of the remaining TTL of a lease.
+	// Requires experimental-enable-lease-checkpoint to be enabled.
+	ExperimentalLeaseCheckpointInterval time.Duration `json:"experimental-lease-checkpoint-interval"`
+	ExperimentalCompactionBatchLimit    int           `json:"experimental-compaction-batch-limit"`
 	// ExperimentalCompactionSleepInterval is the sleep interval between every etcd compaction loop.
+	// It is deprecated, please use CompactionBatchInterval instead.
 	ExperimentalCompactionSleepInterval     time.Duration `json:"experimental-compaction-sleep-interval"`
 	ExperimentalWatchProgressNotifyInterval time.Duration `json:"experimental-watch-progress-notify-interval"`
+	// ExperimentalHotKeyTracking enables sampling key accesses to report the most read and most written keys.
+	ExperimentalHotKeyTracking bool `json:"experimental-hot-key-tracking"`
 	// ExperimentalWarningApplyDuration is the time duration after which a warning is generated if applying request
 	// takes more time than this value.
 	ExperimentalWarningApplyDuration time.Duration `json:"experimental-warning-apply-duration"`
+	// ExperimentalWarningApplyDurationByRequest overrides ExperimentalWarningApplyDuration for the request
+	// types it maps, such as "Put" or "Txn". By default, every request type uses ExperimentalWarningApplyDuration.
+	ExperimentalWarningApplyDurationByRequest map[string]time.Duration `json:"experimental-warning-apply-duration-by-request"`
 	// ExperimentalBootstrapDefragThresholdMegabytes is the minimum number of megabytes needed to be freed for etcd server to
 	// consider running defrag during bootstrap. Needs to be set to non-zero value to take effect.
 	ExperimentalBootstrapDefragThresholdMegabytes uint `json:"experimental-bootstrap-defrag-threshold-megabytes"`
@@ -355,6 +437,11 @@ type Config struct {
 	ExperimentalWarningUnaryRequestDuration time.Duration `json:"experimental-warning-unary-request-duration"`
 	// ExperimentalMaxLearners sets a limit to the number of learner members that can exist in the cluster membership.
 	ExperimentalMaxLearners int `json:"experimental-max-learners"`
+	// ExperimentalWriteRateLimitByUser limits the writes and lease grants of the auth users it maps to the
+	// given number of requests per second. The empty user name limits the requests which are not authenticated.
+	ExperimentalWriteRateLimitByUser map[string]float64 `json:"experimental-write-rate-limit-by-user"`
+	// ExperimentalRateLimitReads makes ExperimentalWriteRateLimitByUser limit the reads of the users too.
+	ExperimentalRateLimitReads bool `json:"experimental-rate-limit-reads"`
 
 	// ForceNewCluster starts a new cluster even if previously started; unsafe.
 	ForceNewCluster bool `json:"force-new-cluster"`
@@ -364,6 +451,10 @@ type Config struct {
 	ListenMetricsUrls     []url.URL
 	ListenMetricsUrlsJSON string `json:"listen-metrics-urls"`
 
+	// ExperimentalEnablePprofAuth restricts the pprof endpoints to the users with the root role
+	// when auth is enabled. The requests authenticate with a token in the Authorization header.
+	ExperimentalEnablePprofAuth bool `json:"experimental-enable-pprof-auth"`
+
 	// ExperimentalEnableDistributedTracing indicates if experimental tracing using OpenTelemetry is enabled.
 	ExperimentalEnableDistributedTracing bool `json:"experimental-enable-distributed-tracing"`
 	// ExperimentalDistributedTracingAddress is the address of the OpenTelemetry Collector.
@@ -473,6 +564,8 @@ func NewConfig() *Config {
 		MaxSnapFiles: DefaultMaxSnapshots,
 		MaxWalFiles:  DefaultMaxWALs,
 
+		WALSlowFsyncThreshold: DefaultWALSlowFsyncThreshold,
+
 		Name: DefaultName,
 
 		SnapshotCount:          etcdserver.DefaultSnapshotCount,
@@ -480,6 +573,7 @@ func NewConfig() *Config {
 
 		MaxTxnOps:                        DefaultMaxTxnOps,
 		MaxRequestBytes:                  DefaultMaxRequestBytes,
+		MaxApplyBacklog:                  etcdserver.DefaultMaxApplyBacklog,
 		MaxConcurrentStreams:             DefaultMaxConcurrentStreams,
 		ExperimentalWarningApplyDuration: DefaultWarningApplyDuration,
 
@@ -527,6 +621,7 @@ func NewConfig() *Config {
 		EnableGRPCGateway:     true,
 
 		ExperimentalDowngradeCheckTime:           DefaultDowngradeCheckTime,
+		ExperimentalLeaseCheckpointInterval:      DefaultLeaseCheckpointInterval,
 		ExperimentalMemoryMlock:                  false,
 		ExperimentalTxnModeWriteWithSharedBuffer: true,
 		ExperimentalMaxLearners:                  membership.DefaultMaxLearners,
@@ -782,6 +877,15 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("unknown auto-compaction-mode %q", cfg.AutoCompactionMode)
 	}
 
+	if len(cfg.ExperimentalAutoCompactionPrefixRetention) > 0 {
+		if _, err := parsePrefixCompactionRetention(cfg.ExperimentalAutoCompactionPrefixRetention); err != nil {
+			return err
+		}
+		if retention, err := parseCompactionRetention(cfg.AutoCompactionMode, cfg.AutoCompactionRetention); err != nil || retention == 0 {
+			return errors.New("--experimental-auto-compaction-prefix-retention requires --auto-compaction-retention")
+		}
+	}
+
 	// Validate distributed tracing configuration but only if enabled.
 	if cfg.ExperimentalEnableDistributedTracing {
 		if err := validateTracingConfig(cfg.ExperimentalDistributedTracingSamplingRatePerMillion); err != nil {
@@ -797,6 +901,43 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("setting experimental-enable-lease-checkpoint-persist requires experimental-enable-lease-checkpoint")
 	}
 
+	if cfg.ExperimentalCompactionSleepInterval != 0 {
+		if cfg.CompactionBatchInterval != 0 {
+			return errors.New("both --experimental-compaction-sleep-interval and --compaction-batch-interval are set. Use only --compaction-batch-interval")
+		}
+		cfg.logger.Warn("--experimental-compaction-sleep-interval is deprecated, and will be decommissioned in v3.7. Use --compaction-batch-interval instead.")
+	}
+	if cfg.CompactionBatchInterval < 0 {
+		return fmt.Errorf("--compaction-batch-interval must be >=0 (set to %v)", cfg.CompactionBatchInterval)
+	}
+	if cfg.CompactionSleep < 0 {
+		return fmt.Errorf("--compaction-sleep must be >=0 (set to %v)", cfg.CompactionSleep)
+	}
+
+	if cfg.ExperimentalLeaseCheckpointInterval < 0 {
+		return fmt.Errorf("--experimental-lease-checkpoint-interval must be >=0 (set to %v)", cfg.ExperimentalLeaseCheckpointInterval)
+	}
+
+	if cfg.GRPCKeepAliveMinTime < 0 {
+		return fmt.Errorf("--grpc-keepalive-min-time must be >=0 (set to %v)", cfg.GRPCKeepAliveMinTime)
+	}
+	if cfg.GRPCKeepAliveInterval < 0 {
+		return fmt.Errorf("--grpc-keepalive-interval must be >=0 (set to %v)", cfg.GRPCKeepAliveInterval)
+	}
+	if cfg.GRPCKeepAliveTimeout < 0 {
+		return fmt.Errorf("--grpc-keepalive-timeout must be >=0 (set to %v)", cfg.GRPCKeepAliveTimeout)
+	}
+
+	for user, limit := range cfg.ExperimentalWriteRateLimitByUser {
+		if limit <= 0 {
+			return fmt.Errorf("--experimental-write-rate-limit-by-user must be >0 for user %q (set to %v)", user, limit)
+		}
+	}
+
+	if cfg.LeaseExpiryJitter < 0 {
+		return fmt.Errorf("--lease-expiry-jitter must be >=0 (set to %v)", cfg.LeaseExpiryJitter)
+	}
+
 	if cfg.ExperimentalCompactHashCheckTime <= 0 {
 		return fmt.Errorf("--experimental-compact-hash-check-time must be >0 (set to %v)", cfg.ExperimentalCompactHashCheckTime)
 	}
@@ -954,6 +1095,15 @@ func (cfg Config) InitialClusterFromName(name string) (ret string) {
 func (cfg Config) IsNewCluster() bool { return cfg.ClusterState == ClusterStateFlagNew }
 func (cfg Config) ElectionTicks() int { return int(cfg.ElectionMs / cfg.TickMs) }
 
+// compactionBatchInterval returns the interval between the batches of a
+// compaction, given by the deprecated flag if the new one is not set.
+func (cfg *Config) compactionBatchInterval() time.Duration {
+	if cfg.CompactionBatchInterval != 0 {
+		return cfg.CompactionBatchInterval
+	}
+	return cfg.ExperimentalCompactionSleepInterval
+}
+
 func (cfg Config) V2DeprecationEffective() config.V2DeprecationEnum {
 	if cfg.V2Deprecation == "" {
 		return config.V2_DEPR_DEFAULT
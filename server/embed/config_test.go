@@ -520,3 +520,66 @@ func TestUndefinedAutoCompactionModeValidate(t *testing.T) {
 	err := cfg.Validate()
 	require.Error(t, err)
 }
+
+func TestGRPCKeepAliveValidate(t *testing.T) {
+	tcs := []struct {
+		name        string
+		configFunc  func(cfg *Config)
+		expectError bool
+	}{
+		{
+			name:       "Default config should pass",
+			configFunc: func(cfg *Config) {},
+		},
+		{
+			name: "Disabled server pings should pass",
+			configFunc: func(cfg *Config) {
+				cfg.GRPCKeepAliveInterval = 0
+				cfg.GRPCKeepAliveTimeout = 0
+			},
+		},
+		{
+			name:        "Negative min time should fail",
+			configFunc:  func(cfg *Config) { cfg.GRPCKeepAliveMinTime = -time.Second },
+			expectError: true,
+		},
+		{
+			name:        "Negative interval should fail",
+			configFunc:  func(cfg *Config) { cfg.GRPCKeepAliveInterval = -time.Second },
+			expectError: true,
+		},
+		{
+			name:        "Negative timeout should fail",
+			configFunc:  func(cfg *Config) { cfg.GRPCKeepAliveTimeout = -time.Second },
+			expectError: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewConfig()
+			tc.configFunc(cfg)
+			err := cfg.Validate()
+			if (err != nil) != tc.expectError {
+				t.Errorf("config.Validate() = %q, expected error: %v", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestCompactionBatchIntervalValidate(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ExperimentalCompactionSleepInterval = time.Second
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, time.Second, cfg.compactionBatchInterval())
+
+	cfg.CompactionBatchInterval = 2 * time.Second
+	require.Error(t, cfg.Validate())
+
+	cfg = NewConfig()
+	cfg.CompactionBatchInterval = 2 * time.Second
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, 2*time.Second, cfg.compactionBatchInterval())
+
+	cfg.CompactionSleep = -time.Second
+	require.Error(t, cfg.Validate())
+}
@@ -41,6 +41,7 @@ import (
 	"go.etcd.io/etcd/server/v3/etcdserver"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/etcdhttp"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/rafthttp"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3compactor"
 	"go.etcd.io/etcd/server/v3/storage"
 	"go.etcd.io/etcd/server/v3/verify"
 
@@ -163,6 +164,10 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 	if err != nil {
 		return e, err
 	}
+	autoCompactionPrefixRetention, err := parsePrefixCompactionRetention(cfg.ExperimentalAutoCompactionPrefixRetention)
+	if err != nil {
+		return e, err
+	}
 
 	backendFreelistType := parseBackendFreelistType(cfg.BackendFreelistType)
 
@@ -172,10 +177,13 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		PeerURLs:                                 cfg.AdvertisePeerUrls,
 		DataDir:                                  cfg.Dir,
 		DedicatedWALDir:                          cfg.WalDir,
+		DedicatedSnapDir:                         cfg.SnapDir,
+		DedicatedBackendDir:                      cfg.BackendDir,
 		SnapshotCount:                            cfg.SnapshotCount,
 		SnapshotCatchUpEntries:                   cfg.SnapshotCatchUpEntries,
 		MaxSnapFiles:                             cfg.MaxSnapFiles,
 		MaxWALFiles:                              cfg.MaxWalFiles,
+		WALSlowFsyncThreshold:                    cfg.WALSlowFsyncThreshold,
 		InitialPeerURLsMap:                       urlsmap,
 		InitialClusterToken:                      token,
 		DiscoveryURL:                             cfg.Durl,
@@ -187,21 +195,33 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		ElectionTicks:                            cfg.ElectionTicks(),
 		WaitClusterReadyTimeout:                  cfg.ExperimentalWaitClusterReadyTimeout,
 		InitialElectionTickAdvance:               cfg.InitialElectionTickAdvance,
+		ElectionPriority:                         cfg.ElectionPriority,
 		AutoCompactionRetention:                  autoCompactionRetention,
 		AutoCompactionMode:                       cfg.AutoCompactionMode,
+		AutoCompactionPrefixRetention:            autoCompactionPrefixRetention,
 		QuotaBackendBytes:                        cfg.QuotaBackendBytes,
+		ReadCacheBytes:                           cfg.ReadCacheBytes,
 		BackendBatchLimit:                        cfg.BackendBatchLimit,
 		BackendFreelistType:                      backendFreelistType,
 		BackendBatchInterval:                     cfg.BackendBatchInterval,
+		DefragInterval:                           cfg.DefragInterval,
+		DefragThresholdMegabytes:                 cfg.DefragThresholdMegabytes,
+		DefragRateBytesPerSec:                    cfg.DefragRateBytesPerSec,
+		DefragOnLeader:                           cfg.DefragOnLeader,
 		MaxTxnOps:                                cfg.MaxTxnOps,
+		MaxTxnTotalOps:                           cfg.MaxTxnTotalOps,
 		MaxRequestBytes:                          cfg.MaxRequestBytes,
+		MaxValueBytes:                            cfg.MaxValueBytes,
+		MaxApplyBacklog:                          cfg.MaxApplyBacklog,
 		MaxConcurrentStreams:                     cfg.MaxConcurrentStreams,
+		MaxWatchStreamsPerConnection:             cfg.MaxWatchStreamsPerConnection,
 		SocketOpts:                               cfg.SocketOpts,
 		StrictReconfigCheck:                      cfg.StrictReconfigCheck,
 		ClientCertAuthEnabled:                    cfg.ClientTLSInfo.ClientCertAuth,
 		AuthToken:                                cfg.AuthToken,
 		BcryptCost:                               cfg.BcryptCost,
 		TokenTTL:                                 cfg.AuthTokenTTL,
+		AuthTokenValidator:                       cfg.AuthTokenValidator,
 		CORS:                                     cfg.CORS,
 		HostWhitelist:                            cfg.HostWhitelist,
 		InitialCorruptCheck:                      cfg.ExperimentalInitialCorruptCheck,
@@ -212,20 +232,28 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		Logger:                                   cfg.logger,
 		ForceNewCluster:                          cfg.ForceNewCluster,
 		EnableGRPCGateway:                        cfg.EnableGRPCGateway,
+		PprofAuth:                                cfg.ExperimentalEnablePprofAuth,
 		ExperimentalEnableDistributedTracing:     cfg.ExperimentalEnableDistributedTracing,
 		UnsafeNoFsync:                            cfg.UnsafeNoFsync,
 		EnableLeaseCheckpoint:                    cfg.ExperimentalEnableLeaseCheckpoint,
 		LeaseCheckpointPersist:                   cfg.ExperimentalEnableLeaseCheckpointPersist,
+		LeaseCheckpointInterval:                  cfg.ExperimentalLeaseCheckpointInterval,
+		LeaseExpiryJitter:                        cfg.LeaseExpiryJitter,
 		CompactionBatchLimit:                     cfg.ExperimentalCompactionBatchLimit,
-		CompactionSleepInterval:                  cfg.ExperimentalCompactionSleepInterval,
+		CompactionSleepInterval:                  cfg.compactionBatchInterval(),
+		CompactionSleep:                          cfg.CompactionSleep,
+		HotKeyTracking:                           cfg.ExperimentalHotKeyTracking,
 		WatchProgressNotifyInterval:              cfg.ExperimentalWatchProgressNotifyInterval,
 		DowngradeCheckTime:                       cfg.ExperimentalDowngradeCheckTime,
 		WarningApplyDuration:                     cfg.ExperimentalWarningApplyDuration,
+		WarningApplyDurationByRequest:            cfg.ExperimentalWarningApplyDurationByRequest,
 		WarningUnaryRequestDuration:              cfg.WarningUnaryRequestDuration,
 		ExperimentalMemoryMlock:                  cfg.ExperimentalMemoryMlock,
 		ExperimentalTxnModeWriteWithSharedBuffer: cfg.ExperimentalTxnModeWriteWithSharedBuffer,
 		ExperimentalBootstrapDefragThresholdMegabytes: cfg.ExperimentalBootstrapDefragThresholdMegabytes,
 		ExperimentalMaxLearners:                       cfg.ExperimentalMaxLearners,
+		WriteRateLimitByUser:                          cfg.ExperimentalWriteRateLimitByUser,
+		RateLimitReads:                                cfg.ExperimentalRateLimitReads,
 		V2Deprecation:                                 cfg.V2DeprecationEffective(),
 	}
 
@@ -324,12 +352,15 @@ func print(lg *zap.Logger, ec Config, sc config.ServerConfig, memberInitialized
 		zap.String("data-dir", sc.DataDir),
 		zap.String("wal-dir", ec.WalDir),
 		zap.String("wal-dir-dedicated", sc.DedicatedWALDir),
+		zap.String("snapshot-dir", sc.SnapDir()),
+		zap.String("backend-path", sc.BackendPath()),
 		zap.String("member-dir", sc.MemberDir()),
 		zap.Bool("force-new-cluster", sc.ForceNewCluster),
 		zap.String("heartbeat-interval", fmt.Sprintf("%v", time.Duration(sc.TickMs)*time.Millisecond)),
 		zap.String("election-timeout", fmt.Sprintf("%v", time.Duration(sc.ElectionTicks*int(sc.TickMs))*time.Millisecond)),
 		zap.String("wait-cluster-ready-timeout", sc.WaitClusterReadyTimeout.String()),
 		zap.Bool("initial-election-tick-advance", sc.InitialElectionTickAdvance),
+		zap.Uint32("election-priority", sc.ElectionPriority),
 		zap.Uint64("snapshot-count", sc.SnapshotCount),
 		zap.Uint("max-wals", sc.MaxWALFiles),
 		zap.Uint("max-snapshots", sc.MaxSnapFiles),
@@ -345,8 +376,12 @@ func print(lg *zap.Logger, ec Config, sc config.ServerConfig, memberInitialized
 		zap.String("initial-cluster-state", ec.ClusterState),
 		zap.String("initial-cluster-token", sc.InitialClusterToken),
 		zap.Int64("quota-backend-bytes", quota),
+		zap.Int64("read-cache-bytes", sc.ReadCacheBytes),
 		zap.Uint("max-request-bytes", sc.MaxRequestBytes),
+		zap.Uint("max-value-bytes", sc.MaxValueBytes),
+		zap.Uint64("max-apply-backlog", sc.MaxApplyBacklog),
 		zap.Uint32("max-concurrent-streams", sc.MaxConcurrentStreams),
+		zap.Uint("max-watch-streams-per-connection", sc.MaxWatchStreamsPerConnection),
 
 		zap.Bool("pre-vote", sc.PreVote),
 		zap.Bool("initial-corrupt-check", sc.InitialCorruptCheck),
@@ -356,6 +391,7 @@ func print(lg *zap.Logger, ec Config, sc config.ServerConfig, memberInitialized
 		zap.String("auto-compaction-mode", sc.AutoCompactionMode),
 		zap.Duration("auto-compaction-retention", sc.AutoCompactionRetention),
 		zap.String("auto-compaction-interval", sc.AutoCompactionRetention.String()),
+		zap.Any("auto-compaction-prefix-retention", sc.AutoCompactionPrefixRetention),
 		zap.String("discovery-url", sc.DiscoveryURL),
 		zap.String("discovery-proxy", sc.DiscoveryProxy),
 
@@ -399,6 +435,8 @@ func (e *Etcd) Close() {
 		verify.MustVerifyIfEnabled(verify.Config{
 			Logger:     lg,
 			DataDir:    e.cfg.Dir,
+			WALDir:     e.cfg.WalDir,
+			BackendDir: e.cfg.BackendDir,
 			ExactIndex: false,
 		})
 		lg.Sync()
@@ -899,3 +937,23 @@ func parseCompactionRetention(mode, retention string) (ret time.Duration, err er
 	}
 	return ret, nil
 }
+
+// parsePrefixCompactionRetention parses the retentions of the prefixes, which
+// are either a duration with a time unit or a number of revisions.
+func parsePrefixCompactionRetention(retentions map[string]string) ([]v3compactor.PrefixRetention, error) {
+	prefixes := make([]v3compactor.PrefixRetention, 0, len(retentions))
+	for prefix, retention := range retentions {
+		p := v3compactor.PrefixRetention{Prefix: prefix}
+		if revs, err := strconv.ParseInt(retention, 10, 64); err == nil {
+			p.Revisions = revs
+		} else if p.Period, err = time.ParseDuration(retention); err != nil {
+			return nil, fmt.Errorf("error parsing compaction retention of prefix %q: %v", prefix, err)
+		}
+		if p.Revisions <= 0 && p.Period <= 0 {
+			return nil, fmt.Errorf("compaction retention of prefix %q must be positive (set to %q)", prefix, retention)
+		}
+		prefixes = append(prefixes, p)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Prefix < prefixes[j].Prefix })
+	return prefixes, nil
+}
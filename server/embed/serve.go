@@ -25,6 +25,7 @@ import (
 	"time"
 
 	etcdservergw "go.etcd.io/etcd/api/v3/etcdserverpb/gw"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	"go.etcd.io/etcd/pkg/v3/debugutil"
 	"go.etcd.io/etcd/pkg/v3/httputil"
@@ -46,6 +47,7 @@ import (
 	"golang.org/x/net/http2"
 	"golang.org/x/net/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type serveCtx struct {
@@ -435,9 +437,44 @@ func (ac *accessController) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	if ac.s.Cfg.PprofAuth && strings.HasPrefix(req.URL.Path, debugutil.HTTPPrefixPProf) {
+		if code, err := ac.pprofPermitted(req); err != nil {
+			http.Error(rw, err.Error(), code)
+			return
+		}
+	}
+
 	ac.mux.ServeHTTP(rw, req)
 }
 
+// pprofPermitted checks that a pprof request carries the auth token of a user
+// with the root role in its Authorization header, as the gRPC gateway
+// expects, and returns the status to reject the request with otherwise. All
+// requests are permitted while auth is disabled.
+func (ac *accessController) pprofPermitted(req *http.Request) (int, error) {
+	as := ac.s.AuthStore()
+	if !as.IsAuthEnabled() {
+		return http.StatusOK, nil
+	}
+	ctx := req.Context()
+	if token := req.Header.Get("Authorization"); token != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(rpctypes.TokenFieldNameGRPC, token))
+	}
+	ai, err := as.AuthInfoFromCtx(ctx)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+	if err = as.IsAdminPermitted(ai); err != nil {
+		ac.lg.Warn(
+			"rejecting unauthorized pprof request",
+			zap.String("path", req.URL.Path),
+			zap.Error(err),
+		)
+		return http.StatusForbidden, err
+	}
+	return http.StatusOK, nil
+}
+
 // addCORSHeader adds the correct cors headers given an origin
 func addCORSHeader(w http.ResponseWriter, origin string) {
 	w.Header().Add("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.etcd.io/etcd/api/v3/version"
@@ -108,6 +110,8 @@ func newConfig() *config {
 	// member
 	fs.StringVar(&cfg.ec.Dir, "data-dir", cfg.ec.Dir, "Path to the data directory.")
 	fs.StringVar(&cfg.ec.WalDir, "wal-dir", cfg.ec.WalDir, "Path to the dedicated wal directory.")
+	fs.StringVar(&cfg.ec.SnapDir, "snapshot-dir", cfg.ec.SnapDir, "Path to the dedicated snapshot directory. It must be on the filesystem of the backend directory.")
+	fs.StringVar(&cfg.ec.BackendDir, "backend-dir", cfg.ec.BackendDir, "Path to the dedicated backend directory.")
 	fs.Var(
 		flags.NewUniqueURLsWithExceptions(embed.DefaultListenPeerURLs, ""),
 		"listen-peer-urls",
@@ -128,24 +132,36 @@ func newConfig() *config {
 	)
 	fs.UintVar(&cfg.ec.MaxSnapFiles, "max-snapshots", cfg.ec.MaxSnapFiles, "Maximum number of snapshot files to retain (0 is unlimited).")
 	fs.UintVar(&cfg.ec.MaxWalFiles, "max-wals", cfg.ec.MaxWalFiles, "Maximum number of wal files to retain (0 is unlimited).")
+	fs.DurationVar(&cfg.ec.WALSlowFsyncThreshold, "wal-slow-fsync-threshold", cfg.ec.WALSlowFsyncThreshold, "Duration after which a WAL fsync is logged and counted as slow.")
 	fs.StringVar(&cfg.ec.Name, "name", cfg.ec.Name, "Human-readable name for this member.")
 	fs.Uint64Var(&cfg.ec.SnapshotCount, "snapshot-count", cfg.ec.SnapshotCount, "Number of committed transactions to trigger a snapshot to disk.")
 	fs.UintVar(&cfg.ec.TickMs, "heartbeat-interval", cfg.ec.TickMs, "Time (in milliseconds) of a heartbeat interval.")
 	fs.UintVar(&cfg.ec.ElectionMs, "election-timeout", cfg.ec.ElectionMs, "Time (in milliseconds) for an election to timeout.")
 	fs.BoolVar(&cfg.ec.InitialElectionTickAdvance, "initial-election-tick-advance", cfg.ec.InitialElectionTickAdvance, "Whether to fast-forward initial election ticks on boot for faster election.")
+	fs.Var(flags.NewUint32Value(cfg.ec.ElectionPriority), "election-priority", "Priority of this member to become the leader; the leader hands its leadership over to a healthy up-to-date member with a higher priority.")
 	fs.Int64Var(&cfg.ec.QuotaBackendBytes, "quota-backend-bytes", cfg.ec.QuotaBackendBytes, "Raise alarms when backend size exceeds the given quota. 0 means use the default quota.")
+	fs.Int64Var(&cfg.ec.ReadCacheBytes, "read-cache-bytes", cfg.ec.ReadCacheBytes, "Maximum size in bytes of the in-memory cache of the key-value pairs read by point gets (0 disables the cache).")
 	fs.StringVar(&cfg.ec.BackendFreelistType, "backend-bbolt-freelist-type", cfg.ec.BackendFreelistType, "BackendFreelistType specifies the type of freelist that boltdb backend uses(array and map are supported types)")
-	fs.DurationVar(&cfg.ec.BackendBatchInterval, "backend-batch-interval", cfg.ec.BackendBatchInterval, "BackendBatchInterval is the maximum time before commit the backend transaction.")
+	fs.DurationVar(&cfg.ec.BackendBatchInterval, "backend-batch-interval", cfg.ec.BackendBatchInterval, "BackendBatchInterval is the maximum time before commit the backend transaction (raised to 10ms if shorter).")
 	fs.IntVar(&cfg.ec.BackendBatchLimit, "backend-batch-limit", cfg.ec.BackendBatchLimit, "BackendBatchLimit is the maximum operations before commit the backend transaction.")
+	fs.DurationVar(&cfg.ec.DefragInterval, "defrag-interval", cfg.ec.DefragInterval, "Interval between scheduled online defragmentations of the backend (0 to disable).")
+	fs.UintVar(&cfg.ec.DefragThresholdMegabytes, "defrag-threshold-megabytes", cfg.ec.DefragThresholdMegabytes, "Minimum free space in megabytes for a scheduled online defragmentation to run (0 to disable).")
+	fs.UintVar(&cfg.ec.DefragRateBytesPerSec, "defrag-rate-bytes-per-sec", cfg.ec.DefragRateBytesPerSec, "Maximum rate in bytes per second at which a scheduled online defragmentation copies the backend (0 means no limit).")
+	fs.BoolVar(&cfg.ec.DefragOnLeader, "defrag-on-leader", cfg.ec.DefragOnLeader, "Allow scheduled online defragmentations to run on the leader.")
 	fs.UintVar(&cfg.ec.MaxTxnOps, "max-txn-ops", cfg.ec.MaxTxnOps, "Maximum number of operations permitted in a transaction.")
+	fs.UintVar(&cfg.ec.MaxTxnTotalOps, "max-txn-total-ops", cfg.ec.MaxTxnTotalOps, "Maximum number of comparisons and operations permitted in a transaction, counting both branches and the nested transactions together (0 means three times --max-txn-ops).")
 	fs.UintVar(&cfg.ec.MaxRequestBytes, "max-request-bytes", cfg.ec.MaxRequestBytes, "Maximum client request size in bytes the server will accept.")
+	fs.UintVar(&cfg.ec.MaxValueBytes, "max-value-bytes", cfg.ec.MaxValueBytes, "Maximum size in bytes of a single value the server will accept (0 means no limit other than the max request size).")
+	fs.Uint64Var(&cfg.ec.MaxApplyBacklog, "max-apply-backlog", cfg.ec.MaxApplyBacklog, "Maximum number of committed entries waiting to be applied before the server rejects new writes.")
 	fs.DurationVar(&cfg.ec.GRPCKeepAliveMinTime, "grpc-keepalive-min-time", cfg.ec.GRPCKeepAliveMinTime, "Minimum interval duration that a client should wait before pinging server.")
 	fs.DurationVar(&cfg.ec.GRPCKeepAliveInterval, "grpc-keepalive-interval", cfg.ec.GRPCKeepAliveInterval, "Frequency duration of server-to-client ping to check if a connection is alive (0 to disable).")
 	fs.DurationVar(&cfg.ec.GRPCKeepAliveTimeout, "grpc-keepalive-timeout", cfg.ec.GRPCKeepAliveTimeout, "Additional duration of wait before closing a non-responsive connection (0 to disable).")
+	fs.DurationVar(&cfg.ec.LeaseExpiryJitter, "lease-expiry-jitter", cfg.ec.LeaseExpiryJitter, "Window over which the revocation of leases expiring at the same time is spread (0 to disable).")
 	fs.BoolVar(&cfg.ec.SocketOpts.ReusePort, "socket-reuse-port", cfg.ec.SocketOpts.ReusePort, "Enable to set socket option SO_REUSEPORT on listeners allowing rebinding of a port already in use.")
 	fs.BoolVar(&cfg.ec.SocketOpts.ReuseAddress, "socket-reuse-address", cfg.ec.SocketOpts.ReuseAddress, "Enable to set socket option SO_REUSEADDR on listeners allowing binding to an address in `TIME_WAIT` state.")
 
 	fs.Var(flags.NewUint32Value(cfg.ec.MaxConcurrentStreams), "max-concurrent-streams", "Maximum concurrent streams that each client can open at a time.")
+	fs.UintVar(&cfg.ec.MaxWatchStreamsPerConnection, "max-watch-streams-per-connection", cfg.ec.MaxWatchStreamsPerConnection, "Maximum watch streams that each client connection can open at a time (0 for no limit).")
 
 	// raft connection timeouts
 	fs.DurationVar(&rafthttp.ConnReadTimeout, "raft-read-timeout", rafthttp.DefaultConnReadTimeout, "Read timeout set on each rafthttp connection")
@@ -242,9 +258,13 @@ func newConfig() *config {
 
 	fs.StringVar(&cfg.ec.AutoCompactionRetention, "auto-compaction-retention", "0", "Auto compaction retention for mvcc key value store. 0 means disable auto compaction.")
 	fs.StringVar(&cfg.ec.AutoCompactionMode, "auto-compaction-mode", "periodic", "interpret 'auto-compaction-retention' one of: periodic|revision. 'periodic' for duration based retention, defaulting to hours if no time unit is provided (e.g. '5m'). 'revision' for revision number based retention.")
+	fs.DurationVar(&cfg.ec.CompactionBatchInterval, "compaction-batch-interval", cfg.ec.CompactionBatchInterval, "Interval between the starts of the batches of a compaction (10ms if 0).")
+	fs.DurationVar(&cfg.ec.CompactionSleep, "compaction-sleep", cfg.ec.CompactionSleep, "Pause after each batch of a compaction, letting reads run at the cost of a longer compaction. Skipped while another compaction waits.")
+	fs.Var(flags.NewStringsValue(""), "experimental-auto-compaction-prefix-retention", "Comma-separated list of prefix=retention pairs (e.g. '/config=1h,/metrics=10') overriding the auto compaction retention for the keys under these prefixes. A retention with a time unit keeps that duration of history, a number keeps that many revisions. A key under several prefixes keeps the longest retention. Compactions retaining prefixes are not verified by --experimental-compact-hash-check-enabled, and downgrading to v3.5 compacts every key to the shortest retention. Requires --auto-compaction-retention.")
 
 	// pprof profiler via HTTP
 	fs.BoolVar(&cfg.ec.EnablePprof, "enable-pprof", false, "Enable runtime profiling data via HTTP server. Address is at client URL + \"/debug/pprof/\"")
+	fs.BoolVar(&cfg.ec.ExperimentalEnablePprofAuth, "experimental-enable-pprof-auth", false, "Serve the runtime profiling data only to users with the root role when auth is enabled.")
 
 	// additional metrics
 	fs.StringVar(&cfg.ec.Metrics, "metrics", cfg.ec.Metrics, "Set level of detail for exported metrics, specify 'extensive' to include server side grpc histogram metrics")
@@ -273,17 +293,22 @@ func newConfig() *config {
 	fs.BoolVar(&cfg.ec.ExperimentalEnableLeaseCheckpoint, "experimental-enable-lease-checkpoint", false, "Enable leader to send regular checkpoints to other members to prevent reset of remaining TTL on leader change.")
 	// TODO: delete in v3.7
 	fs.BoolVar(&cfg.ec.ExperimentalEnableLeaseCheckpointPersist, "experimental-enable-lease-checkpoint-persist", false, "Enable persisting remainingTTL to prevent indefinite auto-renewal of long lived leases. Always enabled in v3.6. Should be used to ensure smooth upgrade from v3.5 clusters with this feature enabled. Requires experimental-enable-lease-checkpoint to be enabled.")
+	fs.DurationVar(&cfg.ec.ExperimentalLeaseCheckpointInterval, "experimental-lease-checkpoint-interval", cfg.ec.ExperimentalLeaseCheckpointInterval, "Duration between the checkpoints of the remaining TTL of a lease. Requires experimental-enable-lease-checkpoint to be enabled.")
 	fs.IntVar(&cfg.ec.ExperimentalCompactionBatchLimit, "experimental-compaction-batch-limit", cfg.ec.ExperimentalCompactionBatchLimit, "Sets the maximum revisions deleted in each compaction batch.")
-	fs.DurationVar(&cfg.ec.ExperimentalCompactionSleepInterval, "experimental-compaction-sleep-interval", cfg.ec.ExperimentalCompactionSleepInterval, "Sets the sleep interval between each compaction batch.")
+	fs.DurationVar(&cfg.ec.ExperimentalCompactionSleepInterval, "experimental-compaction-sleep-interval", cfg.ec.ExperimentalCompactionSleepInterval, "Sets the sleep interval between each compaction batch. It's deprecated, and will be decommissioned in v3.7. Use --compaction-batch-interval instead.")
+	fs.BoolVar(&cfg.ec.ExperimentalHotKeyTracking, "experimental-hot-key-tracking", cfg.ec.ExperimentalHotKeyTracking, "Enable sampling key accesses to report the most read and most written keys.")
 	fs.DurationVar(&cfg.ec.ExperimentalWatchProgressNotifyInterval, "experimental-watch-progress-notify-interval", cfg.ec.ExperimentalWatchProgressNotifyInterval, "Duration of periodic watch progress notifications.")
 	fs.DurationVar(&cfg.ec.ExperimentalDowngradeCheckTime, "experimental-downgrade-check-time", cfg.ec.ExperimentalDowngradeCheckTime, "Duration of time between two downgrade status checks.")
 	fs.DurationVar(&cfg.ec.ExperimentalWarningApplyDuration, "experimental-warning-apply-duration", cfg.ec.ExperimentalWarningApplyDuration, "Time duration after which a warning is generated if request takes more time.")
+	fs.Var(flags.NewStringsValue(""), "experimental-warning-apply-duration-by-request", "Comma-separated list of request type=duration pairs (e.g. 'Put=50ms,Txn=1s') overriding --experimental-warning-apply-duration for these request types.")
 	fs.DurationVar(&cfg.ec.WarningUnaryRequestDuration, "warning-unary-request-duration", cfg.ec.WarningUnaryRequestDuration, "Time duration after which a warning is generated if a unary request takes more time.")
 	fs.DurationVar(&cfg.ec.ExperimentalWarningUnaryRequestDuration, "experimental-warning-unary-request-duration", cfg.ec.ExperimentalWarningUnaryRequestDuration, "Time duration after which a warning is generated if a unary request takes more time. It's deprecated, and will be decommissioned in v3.7. Use --warning-unary-request-duration instead.")
 	fs.BoolVar(&cfg.ec.ExperimentalMemoryMlock, "experimental-memory-mlock", cfg.ec.ExperimentalMemoryMlock, "Enable to enforce etcd pages (in particular bbolt) to stay in RAM.")
 	fs.BoolVar(&cfg.ec.ExperimentalTxnModeWriteWithSharedBuffer, "experimental-txn-mode-write-with-shared-buffer", true, "Enable the write transaction to use a shared buffer in its readonly check operations.")
 	fs.UintVar(&cfg.ec.ExperimentalBootstrapDefragThresholdMegabytes, "experimental-bootstrap-defrag-threshold-megabytes", 0, "Enable the defrag during etcd server bootstrap on condition that it will free at least the provided threshold of disk space. Needs to be set to non-zero value to take effect.")
 	fs.IntVar(&cfg.ec.ExperimentalMaxLearners, "experimental-max-learners", membership.DefaultMaxLearners, "Sets the maximum number of learners that can be available in the cluster membership.")
+	fs.Var(flags.NewStringsValue(""), "experimental-write-rate-limit-by-user", "Comma-separated list of user=rate pairs (e.g. 'alice=100,bob=10') limiting the writes per second of these auth users. An empty user (e.g. '=50') limits unauthenticated requests.")
+	fs.BoolVar(&cfg.ec.ExperimentalRateLimitReads, "experimental-rate-limit-reads", false, "Enable --experimental-write-rate-limit-by-user to limit reads too.")
 	fs.DurationVar(&cfg.ec.ExperimentalWaitClusterReadyTimeout, "experimental-wait-cluster-ready-timeout", cfg.ec.ExperimentalWaitClusterReadyTimeout, "Maximum duration to wait for the cluster to be ready.")
 	fs.Uint64Var(&cfg.ec.SnapshotCatchUpEntries, "experimental-snapshot-catchup-entries", cfg.ec.SnapshotCatchUpEntries, "Number of entries for a slow follower to catch up after compacting the raft storage entries.")
 
@@ -400,7 +425,29 @@ func (cfg *config) configFromCmdLine() error {
 
 	cfg.ec.CipherSuites = flags.StringsFromFlag(cfg.cf.flagSet, "cipher-suites")
 
+	if flags.IsSet(cfg.cf.flagSet, "experimental-warning-apply-duration-by-request") {
+		cfg.ec.ExperimentalWarningApplyDurationByRequest, err = parseWarningApplyDurationByRequest(flags.StringsFromFlag(cfg.cf.flagSet, "experimental-warning-apply-duration-by-request"))
+		if err != nil {
+			return err
+		}
+	}
+
+	if flags.IsSet(cfg.cf.flagSet, "experimental-auto-compaction-prefix-retention") {
+		cfg.ec.ExperimentalAutoCompactionPrefixRetention, err = parseAutoCompactionPrefixRetention(flags.StringsFromFlag(cfg.cf.flagSet, "experimental-auto-compaction-prefix-retention"))
+		if err != nil {
+			return err
+		}
+	}
+
+	if flags.IsSet(cfg.cf.flagSet, "experimental-write-rate-limit-by-user") {
+		cfg.ec.ExperimentalWriteRateLimitByUser, err = parseWriteRateLimitByUser(flags.StringsFromFlag(cfg.cf.flagSet, "experimental-write-rate-limit-by-user"))
+		if err != nil {
+			return err
+		}
+	}
+
 	cfg.ec.MaxConcurrentStreams = flags.Uint32FromFlag(cfg.cf.flagSet, "max-concurrent-streams")
+	cfg.ec.ElectionPriority = flags.Uint32FromFlag(cfg.cf.flagSet, "election-priority")
 
 	cfg.ec.LogOutputs = flags.UniqueStringsFromFlag(cfg.cf.flagSet, "log-outputs")
 
@@ -439,6 +486,57 @@ func (cfg *config) validate() error {
 	return cfg.ec.Validate()
 }
 
+// parseWarningApplyDurationByRequest parses the request type=duration pairs of
+// --experimental-warning-apply-duration-by-request.
+func parseWarningApplyDurationByRequest(pairs []string) (map[string]time.Duration, error) {
+	durations := make(map[string]time.Duration, len(pairs))
+	for _, pair := range pairs {
+		requestType, duration, ok := strings.Cut(pair, "=")
+		if !ok || requestType == "" {
+			return nil, fmt.Errorf("invalid --experimental-warning-apply-duration-by-request entry %q, expecting type=duration", pair)
+		}
+		d, err := time.ParseDuration(duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --experimental-warning-apply-duration-by-request entry %q: %v", pair, err)
+		}
+		durations[requestType] = d
+	}
+	return durations, nil
+}
+
+// parseAutoCompactionPrefixRetention parses the prefix=retention pairs of
+// --experimental-auto-compaction-prefix-retention. The prefix ends at the last
+// '=', since a retention has none.
+func parseAutoCompactionPrefixRetention(pairs []string) (map[string]string, error) {
+	retentions := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		i := strings.LastIndex(pair, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid --experimental-auto-compaction-prefix-retention entry %q, expecting prefix=retention", pair)
+		}
+		retentions[pair[:i]] = pair[i+1:]
+	}
+	return retentions, nil
+}
+
+// parseWriteRateLimitByUser parses the user=rate pairs of
+// --experimental-write-rate-limit-by-user.
+func parseWriteRateLimitByUser(pairs []string) (map[string]float64, error) {
+	limits := make(map[string]float64, len(pairs))
+	for _, pair := range pairs {
+		user, limit, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --experimental-write-rate-limit-by-user entry %q, expecting user=rate", pair)
+		}
+		r, err := strconv.ParseFloat(limit, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --experimental-write-rate-limit-by-user entry %q: %v", pair, err)
+		}
+		limits[user] = r
+	}
+	return limits, nil
+}
+
 func (cfg *config) parseWarningUnaryRequestDuration() (time.Duration, error) {
 	if cfg.ec.ExperimentalWarningUnaryRequestDuration != 0 && cfg.ec.WarningUnaryRequestDuration != 0 {
 		return 0, errors.New(
@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"sigs.k8s.io/yaml"
 
@@ -380,6 +381,66 @@ func TestConfigFileElectionTimeout(t *testing.T) {
 	}
 }
 
+func TestConfigParsingWarningApplyDurationByRequest(t *testing.T) {
+	cfg := newConfig()
+	err := cfg.parse([]string{"--experimental-warning-apply-duration-by-request=Put=10ms,Txn=1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"Put": 10 * time.Millisecond, "Txn": time.Second}
+	if !reflect.DeepEqual(cfg.ec.ExperimentalWarningApplyDurationByRequest, want) {
+		t.Errorf("ExperimentalWarningApplyDurationByRequest = %v, want %v", cfg.ec.ExperimentalWarningApplyDurationByRequest, want)
+	}
+
+	for _, arg := range []string{"Put", "=10ms", "Put=fast"} {
+		cfg = newConfig()
+		if err = cfg.parse([]string{"--experimental-warning-apply-duration-by-request=" + arg}); err == nil {
+			t.Errorf("expected error parsing %q", arg)
+		}
+	}
+}
+
+func TestConfigParsingWriteRateLimitByUser(t *testing.T) {
+	cfg := newConfig()
+	err := cfg.parse([]string{"--experimental-write-rate-limit-by-user=alice=100,bob=0.5,=10", "--experimental-rate-limit-reads"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"alice": 100, "bob": 0.5, "": 10}
+	if !reflect.DeepEqual(cfg.ec.ExperimentalWriteRateLimitByUser, want) {
+		t.Errorf("ExperimentalWriteRateLimitByUser = %v, want %v", cfg.ec.ExperimentalWriteRateLimitByUser, want)
+	}
+	if !cfg.ec.ExperimentalRateLimitReads {
+		t.Error("expected ExperimentalRateLimitReads to be set")
+	}
+
+	for _, arg := range []string{"alice", "alice=fast", "alice=0", "alice=-1"} {
+		cfg = newConfig()
+		if err = cfg.parse([]string{"--experimental-write-rate-limit-by-user=" + arg}); err == nil {
+			t.Errorf("expected error parsing %q", arg)
+		}
+	}
+}
+
+func TestConfigParsingAutoCompactionPrefixRetention(t *testing.T) {
+	cfg := newConfig()
+	err := cfg.parse([]string{"--auto-compaction-retention=1h", "--experimental-auto-compaction-prefix-retention=/config/=24h,/a=b/=1000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"/config/": "24h", "/a=b/": "1000"}
+	if !reflect.DeepEqual(cfg.ec.ExperimentalAutoCompactionPrefixRetention, want) {
+		t.Errorf("ExperimentalAutoCompactionPrefixRetention = %v, want %v", cfg.ec.ExperimentalAutoCompactionPrefixRetention, want)
+	}
+
+	for _, arg := range []string{"/config/", "/config/=forever", "/config/=0", "/config/=-1h"} {
+		cfg = newConfig()
+		if err = cfg.parse([]string{"--auto-compaction-retention=1h", "--experimental-auto-compaction-prefix-retention=" + arg}); err == nil {
+			t.Errorf("expected error parsing %q", arg)
+		}
+	}
+}
+
 func TestFlagsPresentInHelp(t *testing.T) {
 	cfg := newConfig()
 	cfg.cf.flagSet.VisitAll(func(f *flag.Flag) {
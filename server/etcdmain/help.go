@@ -55,6 +55,10 @@ Member:
     Path to the data directory.
   --wal-dir ''
     Path to the dedicated wal directory.
+  --snapshot-dir ''
+    Path to the dedicated snapshot directory. It must be on the filesystem of the backend directory.
+  --backend-dir ''
+    Path to the dedicated backend directory.
   --snapshot-count '10000'
     Number of committed transactions to trigger a snapshot to disk.
   --heartbeat-interval '100'
@@ -63,6 +67,8 @@ Member:
     Time (in milliseconds) for an election to timeout. See tuning documentation for details.
   --initial-election-tick-advance 'true'
     Whether to fast-forward initial election ticks on boot for faster election.
+  --election-priority '0'
+    Priority of this member to become the leader; the leader hands its leadership over to a healthy up-to-date member with a higher priority.
   --listen-peer-urls 'http://localhost:2380'
     List of URLs to listen on for peer traffic.
   --listen-client-urls 'http://localhost:2379'
@@ -73,26 +79,48 @@ Member:
     Maximum number of snapshot files to retain (0 is unlimited).
   --max-wals '` + strconv.Itoa(embed.DefaultMaxWALs) + `'
     Maximum number of wal files to retain (0 is unlimited).
+  --wal-slow-fsync-threshold '1s'
+    Duration after which a WAL fsync is logged and counted as slow.
   --quota-backend-bytes '0'
     Raise alarms when backend size exceeds the given quota (0 defaults to low space quota).
+  --read-cache-bytes '0'
+    Maximum size in bytes of the in-memory cache of the key-value pairs read by point gets (0 disables the cache).
   --backend-bbolt-freelist-type 'map'
     BackendFreelistType specifies the type of freelist that boltdb backend uses(array and map are supported types).
   --backend-batch-interval ''
-    BackendBatchInterval is the maximum time before commit the backend transaction.
+    BackendBatchInterval is the maximum time before commit the backend transaction (raised to 10ms if shorter).
   --backend-batch-limit '0'
     BackendBatchLimit is the maximum operations before commit the backend transaction.
+  --defrag-interval '0s'
+    Interval between scheduled online defragmentations of the backend (0 to disable).
+  --defrag-threshold-megabytes '0'
+    Minimum free space in megabytes for a scheduled online defragmentation to run (0 to disable).
+  --defrag-rate-bytes-per-sec '0'
+    Maximum rate in bytes per second at which a scheduled online defragmentation copies the backend (0 means no limit).
+  --defrag-on-leader 'false'
+    Allow scheduled online defragmentations to run on the leader.
   --max-txn-ops '128'
     Maximum number of operations permitted in a transaction.
+  --max-txn-total-ops '0'
+    Maximum number of comparisons and operations permitted in a transaction, counting both branches and the nested transactions together (0 means three times --max-txn-ops).
   --max-request-bytes '1572864'
     Maximum client request size in bytes the server will accept.
+  --max-value-bytes '0'
+    Maximum size in bytes of a single value the server will accept (0 means no limit other than the max request size).
+  --max-apply-backlog '5000'
+    Maximum number of committed entries waiting to be applied before the server rejects new writes.
   --max-concurrent-streams 'math.MaxUint32'
     Maximum concurrent streams that each client can open at a time.
+  --max-watch-streams-per-connection '0'
+    Maximum watch streams that each client connection can open at a time (0 for no limit).
   --grpc-keepalive-min-time '5s'
     Minimum duration interval that a client should wait before pinging server.
   --grpc-keepalive-interval '2h'
     Frequency duration of server-to-client ping to check if a connection is alive (0 to disable).
   --grpc-keepalive-timeout '20s'
     Additional duration of wait before closing a non-responsive connection (0 to disable).
+  --lease-expiry-jitter '0s'
+    Window over which the revocation of leases expiring at the same time is spread (0 to disable).
   --socket-reuse-port 'false'
     Enable to set socket option SO_REUSEPORT on listeners allowing rebinding of a port already in use.
   --socket-reuse-address 'false'
@@ -162,6 +190,12 @@ Clustering:
     Auto compaction retention length. 0 means disable auto compaction.
   --auto-compaction-mode 'periodic'
     Interpret 'auto-compaction-retention' one of: periodic|revision. 'periodic' for duration based retention, defaulting to hours if no time unit is provided (e.g. '5m'). 'revision' for revision number based retention.
+  --compaction-batch-interval '0s'
+    Interval between the starts of the batches of a compaction (10ms if 0).
+  --compaction-sleep '0s'
+    Pause after each batch of a compaction, letting reads run at the cost of a longer compaction. Skipped while another compaction waits.
+  --experimental-auto-compaction-prefix-retention ''
+    Comma-separated list of prefix=retention pairs (e.g. '/config=1h,/metrics=10') overriding the auto compaction retention for the keys under these prefixes. A retention with a time unit keeps that duration of history, a number keeps that many revisions. A key under several prefixes keeps the longest retention. Compactions retaining prefixes are not verified by --experimental-compact-hash-check-enabled, and downgrading to v3.5 compacts every key to the shortest retention. Requires --auto-compaction-retention.
   --v2-deprecation '` + string(cconfig.V2_DEPR_DEFAULT) + `'
     Phase of v2store deprecation. Allows to opt-in for higher compatibility mode.
     Supported values:
@@ -279,12 +313,16 @@ Experimental feature:
     ExperimentalEnableLeaseCheckpoint enables primary lessor to persist lease remainingTTL to prevent indefinite auto-renewal of long lived leases.
   --experimental-compaction-batch-limit 1000
     ExperimentalCompactionBatchLimit sets the maximum revisions deleted in each compaction batch.
+  --experimental-hot-key-tracking 'false'
+    Enable sampling key accesses to report the most read and most written keys.
   --experimental-peer-skip-client-san-verification 'false'
     Skip verification of SAN field in client certificate for peer connections.
   --experimental-watch-progress-notify-interval '10m'
     Duration of periodical watch progress notification.
   --experimental-warning-apply-duration '100ms'
     Warning is generated if requests take more than this duration.
+  --experimental-warning-apply-duration-by-request ''
+    Comma-separated list of request type=duration pairs (e.g. 'Put=50ms,Txn=1s') overriding --experimental-warning-apply-duration for these request types.
   --experimental-txn-mode-write-with-shared-buffer 'true'
     Enable the write transaction to use a shared buffer in its readonly check operations.
   --experimental-bootstrap-defrag-threshold-megabytes
@@ -293,16 +331,24 @@ Experimental feature:
     Set time duration after which a warning is generated if a unary request takes more than this duration. It's deprecated, and will be decommissioned in v3.7. Use --warning-unary-request-duration instead.
   --experimental-max-learners '1'
     Set the max number of learner members allowed in the cluster membership.
+  --experimental-write-rate-limit-by-user ''
+    Comma-separated list of user=rate pairs (e.g. 'alice=100,bob=10') limiting the writes and lease grants per second of these auth users. An empty user (e.g. '=50') limits unauthenticated requests.
+  --experimental-rate-limit-reads 'false'
+    Enable --experimental-write-rate-limit-by-user to limit reads too.
   --experimental-wait-cluster-ready-timeout '5s'
     Set the maximum time duration to wait for the cluster to be ready.
   --experimental-snapshot-catch-up-entries '5000'
     Number of entries for a slow follower to catch up after compacting the raft storage entries.
   --experimental-compaction-sleep-interval
-    Sets the sleep interval between each compaction batch.
+    Sets the sleep interval between each compaction batch. It's deprecated, and will be decommissioned in v3.7. Use --compaction-batch-interval instead.
   --experimental-downgrade-check-time
     Duration of time between two downgrade status checks.
   --experimental-enable-lease-checkpoint-persist 'false'
     Enable persisting remainingTTL to prevent indefinite auto-renewal of long lived leases. Always enabled in v3.6. Should be used to ensure smooth upgrade from v3.5 clusters with this feature enabled. Requires experimental-enable-lease-checkpoint to be enabled.
+  --experimental-lease-checkpoint-interval '5m'
+    Duration between the checkpoints of the remaining TTL of a lease. Requires experimental-enable-lease-checkpoint to be enabled.
+  --experimental-enable-pprof-auth 'false'
+    Serve the runtime profiling data only to users with the root role when auth is enabled. Requests pass an auth token in the Authorization header.
   --experimental-memory-mlock
     Enable to enforce etcd pages (in particular bbolt) to stay in RAM.
   --experimental-snapshot-catchup-entries
@@ -34,17 +34,21 @@ import (
 const (
 	PathHealth      = "/health"
 	PathProxyHealth = "/proxy/health"
+	PathLivez       = "/livez"
 )
 
 type ServerHealth interface {
 	Alarms() []*pb.AlarmMember
 	Leader() types.ID
 	Range(context.Context, *pb.RangeRequest) (*pb.RangeResponse, error)
+	LinearizableReadNotify(context.Context) error
 	Config() config.ServerConfig
 }
 
 // HandleHealth registers metrics and health handlers. it checks health by using v3 range request
-// and its corresponding timeout.
+// and its corresponding timeout. Unless the check is serializable, it first confirms that the
+// member can reach quorum with a linearizable read index. It also registers the '/livez'
+// handler, which only checks that the process is serving.
 func HandleHealth(lg *zap.Logger, mux *http.ServeMux, srv ServerHealth) {
 	mux.Handle(PathHealth, NewHealthHandler(lg, func(excludedAlarms AlarmSet, serializable bool) Health {
 		if h := checkAlarms(lg, srv, excludedAlarms); h.Health != "true" {
@@ -53,8 +57,14 @@ func HandleHealth(lg *zap.Logger, mux *http.ServeMux, srv ServerHealth) {
 		if h := checkLeader(lg, srv, serializable); h.Health != "true" {
 			return h
 		}
-		return checkAPI(lg, srv, serializable)
+		if !serializable {
+			if h := checkQuorum(lg, srv); h.Health != "true" {
+				return h
+			}
+		}
+		return checkAPI(lg, srv)
 	}))
+	mux.Handle(PathLivez, newLivezHandler(lg))
 }
 
 // NewHealthHandler handles '/health' requests.
@@ -92,6 +102,22 @@ func NewHealthHandler(lg *zap.Logger, hfunc func(excludedAlarms AlarmSet, Serial
 	}
 }
 
+// newLivezHandler handles '/livez' requests, which succeed as long as the
+// process serves them.
+func newLivezHandler(lg *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			lg.Warn("/livez error", zap.Int("status-code", http.StatusMethodNotAllowed))
+			return
+		}
+		d, _ := json.Marshal(Health{Health: "true"})
+		w.WriteHeader(http.StatusOK)
+		w.Write(d)
+	}
+}
+
 var (
 	healthSuccess = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd",
@@ -179,11 +205,28 @@ func checkLeader(lg *zap.Logger, srv ServerHealth, serializable bool) Health {
 	return h
 }
 
-func checkAPI(lg *zap.Logger, srv ServerHealth, serializable bool) Health {
+// checkQuorum confirms with a read index that the member can reach quorum.
+// It waits at most an election timeout, so that a partitioned member is
+// reported unhealthy promptly.
+func checkQuorum(lg *zap.Logger, srv ServerHealth) Health {
+	h := Health{Health: "true"}
+	cfg := srv.Config()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ElectionTimeout())
+	err := srv.LinearizableReadNotify(ctx)
+	cancel()
+	if err != nil {
+		h.Health = "false"
+		h.Reason = fmt.Sprintf("QUORUM ERROR:%s", err)
+		lg.Warn("serving /health false; linearizable read index fails", zap.Error(err))
+	}
+	return h
+}
+
+func checkAPI(lg *zap.Logger, srv ServerHealth) Health {
 	h := Health{Health: "true"}
 	cfg := srv.Config()
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ReqTimeout())
-	_, err := srv.Range(ctx, &etcdserverpb.RangeRequest{KeysOnly: true, Limit: 1, Serializable: serializable})
+	_, err := srv.Range(ctx, &etcdserverpb.RangeRequest{KeysOnly: true, Limit: 1, Serializable: true})
 	cancel()
 	if err != nil && err != auth.ErrUserEmpty && err != auth.ErrPermissionDenied {
 		h.Health = "false"
@@ -42,14 +42,19 @@ func (s *fakeStats) StoreStats() []byte  { return nil }
 
 type fakeHealthServer struct {
 	fakeServer
-	health   string
-	apiError error
+	health      string
+	apiError    error
+	quorumError error
 }
 
 func (s *fakeHealthServer) Range(ctx context.Context, request *pb.RangeRequest) (*pb.RangeResponse, error) {
 	return nil, s.apiError
 }
 
+func (s *fakeHealthServer) LinearizableReadNotify(ctx context.Context) error {
+	return s.quorumError
+}
+
 func (s *fakeHealthServer) Config() config.ServerConfig {
 	return config.ServerConfig{}
 }
@@ -76,6 +81,7 @@ func TestHealthHandler(t *testing.T) {
 		alarms         []*pb.AlarmMember
 		healthCheckURL string
 		apiError       error
+		quorumError    error
 
 		expectStatusCode int
 		expectHealth     string
@@ -150,15 +156,44 @@ func TestHealthHandler(t *testing.T) {
 			expectStatusCode: http.StatusServiceUnavailable,
 			expectHealth:     "false",
 		},
+		{
+			name:             "Unhealthy if quorum is not reachable",
+			healthCheckURL:   "/health?serializable=false",
+			quorumError:      context.DeadlineExceeded,
+			expectStatusCode: http.StatusServiceUnavailable,
+			expectHealth:     "false",
+		},
+		{
+			name:             "Healthy if quorum is not reachable and serializable",
+			healthCheckURL:   "/health?serializable=true",
+			quorumError:      context.DeadlineExceeded,
+			expectStatusCode: http.StatusOK,
+			expectHealth:     "true",
+		},
+		{
+			name:             "Live if quorum is not reachable",
+			healthCheckURL:   "/livez",
+			quorumError:      context.DeadlineExceeded,
+			expectStatusCode: http.StatusOK,
+			expectHealth:     "true",
+		},
+		{
+			name:             "Live even if NOSPACE alarm is on",
+			alarms:           []*pb.AlarmMember{{MemberID: uint64(0), Alarm: pb.AlarmType_NOSPACE}},
+			healthCheckURL:   "/livez",
+			expectStatusCode: http.StatusOK,
+			expectHealth:     "true",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mux := http.NewServeMux()
 			HandleHealth(zaptest.NewLogger(t), mux, &fakeHealthServer{
-				fakeServer: fakeServer{alarms: tt.alarms},
-				health:     tt.expectHealth,
-				apiError:   tt.apiError,
+				fakeServer:  fakeServer{alarms: tt.alarms},
+				health:      tt.expectHealth,
+				apiError:    tt.apiError,
+				quorumError: tt.quorumError,
 			})
 			ts := httptest.NewServer(mux)
 			defer ts.Close()
@@ -65,6 +65,7 @@ func newPeerHandler(
 	if leaseHandler != nil {
 		mux.Handle(leasehttp.LeasePrefix, leaseHandler)
 		mux.Handle(leasehttp.LeaseInternalPrefix, leaseHandler)
+		mux.Handle(leasehttp.LeaseListPrefix, leaseHandler)
 	}
 	if downgradeEnabledHandler != nil {
 		mux.Handle(etcdserver.DowngradeEnabledPath, downgradeEnabledHandler)
@@ -141,7 +142,7 @@ func (h *peerMemberPromoteHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 		switch err {
 		case membership.ErrIDNotFound:
 			http.Error(w, err.Error(), http.StatusNotFound)
-		case membership.ErrMemberNotLearner:
+		case membership.ErrMemberNotLearner, membership.ErrMemberNotPromotable:
 			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		case errors.ErrLearnerNotReady:
 			http.Error(w, err.Error(), http.StatusPreconditionFailed)
@@ -70,6 +70,15 @@ type ConfigChangeContext struct {
 	IsPromote bool `json:"isPromote"`
 }
 
+// ReconfigureContext represents the context of a joint confChange, which adds
+// and removes several members at once.
+type ReconfigureContext struct {
+	// ID identifies the confChange, as raftpb.ConfChangeV2 has no ID of its own.
+	ID uint64 `json:"id"`
+	// Members are the added members, in the order of the changes adding them.
+	Members []Member `json:"members"`
+}
+
 type ShouldApplyV3 bool
 
 const (
@@ -81,8 +90,12 @@ const (
 // cluster with raft learner member.
 func NewClusterFromURLsMap(lg *zap.Logger, token string, urlsmap types.URLsMap, opts ...ClusterOption) (*RaftCluster, error) {
 	c := NewCluster(lg, opts...)
+	clOpts := newClusterOpts(opts...)
 	for name, urls := range urlsmap {
 		m := NewMember(name, urls, token, nil)
+		if id, ok := clOpts.memberIDs[name]; ok {
+			m.ID = id
+		}
 		if _, ok := c.members[m.ID]; ok {
 			return nil, fmt.Errorf("member exists with identical ID %v", m)
 		}
@@ -168,6 +181,27 @@ func (c *RaftCluster) MemberByName(name string) *Member {
 	return memb.Clone()
 }
 
+// MemberIDByName returns the ID of the member with the given name. Names are not
+// guaranteed to be unique, so it fails if more than one member has the name.
+func (c *RaftCluster) MemberIDByName(name string) (types.ID, error) {
+	c.Lock()
+	defer c.Unlock()
+	var id types.ID
+	for _, m := range c.members {
+		if m.Name != name {
+			continue
+		}
+		if id != 0 {
+			return 0, ErrNameAmbiguous
+		}
+		id = m.ID
+	}
+	if id == 0 {
+		return 0, ErrNameNotFound
+	}
+	return id, nil
+}
+
 func (c *RaftCluster) MemberIDs() []types.ID {
 	c.Lock()
 	defer c.Unlock()
@@ -324,6 +358,9 @@ func (c *RaftCluster) ValidateConfigurationChange(cc raftpb.ConfChange) error {
 			if !membersMap[id].IsLearner {
 				return ErrMemberNotLearner
 			}
+			if membersMap[id].NonPromotable {
+				return ErrMemberNotPromotable
+			}
 		} else { // adding a new member
 			if membersMap[id] != nil {
 				return ErrIDExists
@@ -384,6 +421,66 @@ func (c *RaftCluster) ValidateConfigurationChange(cc raftpb.ConfChange) error {
 	return nil
 }
 
+// ValidateConfigurationChangeV2 takes a proposed joint ConfChangeV2, which
+// adds the members of rc, and ensures that it is still valid. Each change is
+// validated against the members resulting from the previous ones, so that the
+// change as a whole leaves no duplicated member ID or peer URL.
+func (c *RaftCluster) ValidateConfigurationChangeV2(cc raftpb.ConfChangeV2, rc *ReconfigureContext) error {
+	// TODO: this must be switched to backend as well.
+	membersMap, removedMap := membersFromStore(c.lg, c.v2store)
+	added := 0
+	for _, ch := range cc.Changes {
+		id := types.ID(ch.NodeID)
+		if removedMap[id] {
+			return ErrIDRemoved
+		}
+		switch ch.Type {
+		case raftpb.ConfChangeAddNode, raftpb.ConfChangeAddLearnerNode:
+			if added >= len(rc.Members) || rc.Members[added].ID != id {
+				c.lg.Panic("got different member ID", zap.String("member-id-from-config-change-entry", id.String()))
+			}
+			m := &rc.Members[added]
+			added++
+			if membersMap[id] != nil {
+				return ErrIDExists
+			}
+
+			var members []*Member
+			urls := make(map[string]bool)
+			for _, mm := range membersMap {
+				members = append(members, mm)
+				for _, u := range mm.PeerURLs {
+					urls[u] = true
+				}
+			}
+			for _, u := range m.PeerURLs {
+				if urls[u] {
+					return ErrPeerURLexists
+				}
+			}
+
+			if m.IsLearner && ch.Type == raftpb.ConfChangeAddLearnerNode {
+				scaleUpLearners := true
+				if err := ValidateMaxLearnerConfig(c.maxLearners, members, scaleUpLearners); err != nil {
+					return err
+				}
+			}
+			membersMap[id] = m
+
+		case raftpb.ConfChangeRemoveNode:
+			if membersMap[id] == nil {
+				return ErrIDNotFound
+			}
+			delete(membersMap, id)
+			removedMap[id] = true
+
+		default:
+			c.lg.Panic("unsupported ConfChange type in joint ConfChange", zap.String("type", ch.Type.String()))
+		}
+	}
+	return nil
+}
+
 // AddMember adds a new Member into the cluster, and saves the given member's
 // raftAttributes into the store. The given member should have empty attributes.
 // A Member with a matching id must not exist.
@@ -504,6 +601,8 @@ func (c *RaftCluster) UpdateRaftAttributes(id types.ID, raftAttr RaftAttributes,
 	c.Lock()
 	defer c.Unlock()
 
+	// updating the peer URLs never makes a non-promotable member promotable
+	raftAttr.NonPromotable = c.members[id].NonPromotable
 	c.members[id].RaftAttributes = raftAttr
 	if c.v2store != nil {
 		mustUpdateMemberInStore(c.lg, c.v2store, c.members[id])
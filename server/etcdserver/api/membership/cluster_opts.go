@@ -14,10 +14,13 @@
 
 package membership
 
+import "go.etcd.io/etcd/client/pkg/v3/types"
+
 const DefaultMaxLearners = 1
 
 type ClusterOptions struct {
 	maxLearners int
+	memberIDs   map[string]types.ID
 }
 
 // ClusterOption are options which can be applied to the raft cluster.
@@ -41,3 +44,11 @@ func WithMaxLearners(max int) ClusterOption {
 		co.maxLearners = max
 	}
 }
+
+// WithMemberIDs sets the IDs, by member name, of the members of a cluster
+// created by NewClusterFromURLsMap instead of deriving them from their peer URLs.
+func WithMemberIDs(ids map[string]types.ID) ClusterOption {
+	return func(co *ClusterOptions) {
+		co.memberIDs = ids
+	}
+}
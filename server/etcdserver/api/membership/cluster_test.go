@@ -81,6 +81,33 @@ func TestClusterMemberByName(t *testing.T) {
 	}
 }
 
+func TestClusterMemberIDByName(t *testing.T) {
+	membs := []*Member{
+		newTestMember(1, nil, "node1", nil),
+		newTestMember(2, nil, "node2", nil),
+		newTestMember(3, nil, "node2", nil),
+	}
+	tests := []struct {
+		name string
+		wid  types.ID
+		werr error
+	}{
+		{"node1", 1, nil},
+		{"node2", 0, ErrNameAmbiguous},
+		{"node3", 0, ErrNameNotFound},
+	}
+	for i, tt := range tests {
+		c := newTestCluster(t, membs)
+		id, err := c.MemberIDByName(tt.name)
+		if err != tt.werr {
+			t.Errorf("#%d: err = %v, want %v", i, err, tt.werr)
+		}
+		if id != tt.wid {
+			t.Errorf("#%d: id = %v, want %v", i, id, tt.wid)
+		}
+	}
+}
+
 func TestClusterMemberIDs(t *testing.T) {
 	c := newTestCluster(t, []*Member{
 		newTestMember(1, nil, "", nil),
@@ -462,6 +489,94 @@ func TestClusterValidateConfigurationChange(t *testing.T) {
 	}
 }
 
+func TestClusterValidateConfigurationChangeV2(t *testing.T) {
+	cl := NewCluster(zaptest.NewLogger(t), WithMaxLearners(1))
+	cl.SetStore(v2store.New())
+	for i := 1; i <= 4; i++ {
+		attr := RaftAttributes{PeerURLs: []string{fmt.Sprintf("http://127.0.0.1:%d", i)}, IsLearner: i == 1}
+		cl.AddMember(&Member{ID: types.ID(i), RaftAttributes: attr}, true)
+	}
+	cl.RemoveMember(4, true)
+
+	member := func(id int, port int, isLearner bool) Member {
+		return Member{ID: types.ID(id), RaftAttributes: RaftAttributes{PeerURLs: []string{fmt.Sprintf("http://127.0.0.1:%d", port)}, IsLearner: isLearner}}
+	}
+	add := func(id uint64) raftpb.ConfChangeSingle {
+		return raftpb.ConfChangeSingle{Type: raftpb.ConfChangeAddNode, NodeID: id}
+	}
+	addLearner := func(id uint64) raftpb.ConfChangeSingle {
+		return raftpb.ConfChangeSingle{Type: raftpb.ConfChangeAddLearnerNode, NodeID: id}
+	}
+	remove := func(id uint64) raftpb.ConfChangeSingle {
+		return raftpb.ConfChangeSingle{Type: raftpb.ConfChangeRemoveNode, NodeID: id}
+	}
+	tests := []struct {
+		name    string
+		changes []raftpb.ConfChangeSingle
+		members []Member
+		werr    error
+	}{
+		{
+			name:    "swap two members",
+			changes: []raftpb.ConfChangeSingle{add(5), add(6), remove(2), remove(3)},
+			members: []Member{member(5, 5, false), member(6, 6, false)},
+		},
+		{
+			name:    "removed ID",
+			changes: []raftpb.ConfChangeSingle{add(4), remove(2)},
+			members: []Member{member(4, 5, false)},
+			werr:    ErrIDRemoved,
+		},
+		{
+			name:    "existing ID",
+			changes: []raftpb.ConfChangeSingle{add(5), add(2)},
+			members: []Member{member(5, 5, false), member(2, 6, false)},
+			werr:    ErrIDExists,
+		},
+		{
+			name:    "existing peer URL",
+			changes: []raftpb.ConfChangeSingle{add(5), remove(3)},
+			members: []Member{member(5, 2, false)},
+			werr:    ErrPeerURLexists,
+		},
+		{
+			name:    "peer URL added twice",
+			changes: []raftpb.ConfChangeSingle{add(5), add(6)},
+			members: []Member{member(5, 5, false), member(6, 5, false)},
+			werr:    ErrPeerURLexists,
+		},
+		{
+			name:    "ID removed twice",
+			changes: []raftpb.ConfChangeSingle{remove(2), remove(2)},
+			werr:    ErrIDRemoved,
+		},
+		{
+			name:    "unknown ID",
+			changes: []raftpb.ConfChangeSingle{add(5), remove(6)},
+			members: []Member{member(5, 5, false)},
+			werr:    ErrIDNotFound,
+		},
+		{
+			name:    "too many learners",
+			changes: []raftpb.ConfChangeSingle{addLearner(5), remove(2)},
+			members: []Member{member(5, 5, true)},
+			werr:    ErrTooManyLearners,
+		},
+		{
+			name:    "learner replaced",
+			changes: []raftpb.ConfChangeSingle{remove(1), addLearner(5)},
+			members: []Member{member(5, 5, true)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := raftpb.ConfChangeV2{Changes: tt.changes}
+			err := cl.ValidateConfigurationChangeV2(cc, &ReconfigureContext{Members: tt.members})
+			assert.Equal(t, tt.werr, err)
+		})
+	}
+}
+
 func TestClusterGenID(t *testing.T) {
 	cs := newTestCluster(t, []*Member{
 		newTestMember(1, nil, "", nil),
@@ -482,6 +597,27 @@ func TestClusterGenID(t *testing.T) {
 	}
 }
 
+func TestClusterFromURLsMapWithMemberIDs(t *testing.T) {
+	urlsmap, err := types.NewURLsMap("m0=http://127.0.0.1:2380,m1=http://127.0.0.1:2381,m2=http://127.0.0.1:2382")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := NewClusterFromURLsMap(zaptest.NewLogger(t), "token", urlsmap, WithMemberIDs(map[string]types.ID{"m0": 1, "m1": 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.ID(1), cl.MemberByName("m0").ID)
+	assert.Equal(t, types.ID(2), cl.MemberByName("m1").ID)
+	// members without an ID keep the one derived from their peer URLs
+	m2 := NewMember("m2", urlsmap["m2"], "token", nil)
+	assert.Equal(t, m2.ID, cl.MemberByName("m2").ID)
+
+	_, err = NewClusterFromURLsMap(zaptest.NewLogger(t), "token", urlsmap, WithMemberIDs(map[string]types.ID{"m0": 1, "m1": 1}))
+	assert.Error(t, err)
+	_, err = NewClusterFromURLsMap(zaptest.NewLogger(t), "token", urlsmap, WithMemberIDs(map[string]types.ID{"m0": 0}))
+	assert.Error(t, err)
+}
+
 func TestNodeToMemberBad(t *testing.T) {
 	tests := []*v2store.NodeExtern{
 		{Key: "/1234", Nodes: []*v2store.NodeExtern{
@@ -631,6 +767,31 @@ func TestClusterUpdateAttributes(t *testing.T) {
 	}
 }
 
+func TestClusterNonPromotableMember(t *testing.T) {
+	cl := NewCluster(zaptest.NewLogger(t))
+	st := v2store.New()
+	cl.SetStore(st)
+	cl.AddMember(newTestMember(1, []string{"http://127.0.0.1:1"}, "node1", nil), true)
+	m := newTestMemberAsLearner(2, []string{"http://127.0.0.1:2"}, "", nil)
+	m.NonPromotable = true
+	cl.AddMember(m, true)
+
+	ctx, err := json.Marshal(&ConfigChangeContext{Member: Member{ID: types.ID(2)}, IsPromote: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.ValidateConfigurationChange(raftpb.ConfChange{Type: raftpb.ConfChangeAddNode, NodeID: 2, Context: ctx})
+	assert.Equal(t, ErrMemberNotPromotable, err)
+
+	// updating the peer URLs keeps the member non-promotable
+	cl.UpdateRaftAttributes(types.ID(2), RaftAttributes{PeerURLs: []string{"http://127.0.0.1:3"}, IsLearner: true}, true)
+	assert.True(t, cl.Member(types.ID(2)).NonPromotable)
+
+	mst, _ := membersFromStore(cl.lg, st)
+	assert.True(t, mst[types.ID(2)].NonPromotable)
+	assert.False(t, mst[types.ID(1)].NonPromotable)
+}
+
 func TestNodeToMember(t *testing.T) {
 	n := &v2store.NodeExtern{Key: "/1234", Nodes: []*v2store.NodeExtern{
 		{Key: "/1234/attributes", Value: stringp(`{"name":"node1","clientURLs":null}`)},
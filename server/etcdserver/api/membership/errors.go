@@ -21,12 +21,16 @@ import (
 )
 
 var (
-	ErrIDRemoved        = errors.New("membership: ID removed")
-	ErrIDExists         = errors.New("membership: ID exists")
-	ErrIDNotFound       = errors.New("membership: ID not found")
-	ErrPeerURLexists    = errors.New("membership: peerURL exists")
-	ErrMemberNotLearner = errors.New("membership: can only promote a learner member")
-	ErrTooManyLearners  = errors.New("membership: too many learner members in cluster")
+	ErrIDRemoved           = errors.New("membership: ID removed")
+	ErrIDExists            = errors.New("membership: ID exists")
+	ErrIDNotFound          = errors.New("membership: ID not found")
+	ErrNameNotFound        = errors.New("membership: name not found")
+	ErrNameAmbiguous       = errors.New("membership: name matches more than one member")
+	ErrPeerURLexists       = errors.New("membership: peerURL exists")
+	ErrMemberNotLearner    = errors.New("membership: can only promote a learner member")
+	ErrMemberNotPromotable = errors.New("membership: member is not promotable")
+	ErrTooManyLearners     = errors.New("membership: too many learner members in cluster")
+	ErrNoChange            = errors.New("membership: no member to add or remove")
 )
 
 func isKeyNotFound(err error) bool {
@@ -32,12 +32,17 @@ type RaftAttributes struct {
 	PeerURLs []string `json:"peerURLs"`
 	// IsLearner indicates if the member is raft learner.
 	IsLearner bool `json:"isLearner,omitempty"`
+	// NonPromotable indicates if the member is a raft learner that can
+	// never be promoted to a voting member.
+	NonPromotable bool `json:"nonPromotable,omitempty"`
 }
 
 // Attributes represents all the non-raft related attributes of an etcd member.
 type Attributes struct {
 	Name       string   `json:"name,omitempty"`
 	ClientURLs []string `json:"clientURLs,omitempty"`
+	// ElectionPriority is the priority of the member to become the leader.
+	ElectionPriority uint32 `json:"electionPriority,omitempty"`
 }
 
 type Member struct {
@@ -94,10 +99,12 @@ func (m *Member) Clone() *Member {
 	mm := &Member{
 		ID: m.ID,
 		RaftAttributes: RaftAttributes{
-			IsLearner: m.IsLearner,
+			IsLearner:     m.IsLearner,
+			NonPromotable: m.NonPromotable,
 		},
 		Attributes: Attributes{
-			Name: m.Name,
+			Name:             m.Name,
+			ElectionPriority: m.ElectionPriority,
 		},
 	}
 	if m.PeerURLs != nil {
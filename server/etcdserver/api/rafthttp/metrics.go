@@ -71,6 +71,21 @@ var (
 		[]string{"From"},
 	)
 
+	droppedMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "network",
+		Name:      "raft_messages_dropped_total",
+		Help:      "The total number of raft messages dropped by reason.",
+	},
+		[]string{"Reason"},
+	)
+	// counters of droppedMessages by reason, to avoid looking up the labels
+	// for every message
+	droppedSendBufferFull = droppedMessages.WithLabelValues("send_buffer_full")
+	droppedRecvBufferFull = droppedMessages.WithLabelValues("receive_buffer_full")
+	droppedPeerPaused     = droppedMessages.WithLabelValues("peer_paused")
+	droppedUnknownPeer    = droppedMessages.WithLabelValues("unknown_peer")
+
 	snapshotSend = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "etcd",
 		Subsystem: "network",
@@ -172,6 +187,7 @@ func init() {
 	prometheus.MustRegister(receivedBytes)
 	prometheus.MustRegister(sentFailures)
 	prometheus.MustRegister(recvFailures)
+	prometheus.MustRegister(droppedMessages)
 
 	prometheus.MustRegister(snapshotSend)
 	prometheus.MustRegister(snapshotSendInflights)
@@ -239,6 +239,7 @@ func (p *peer) send(m raftpb.Message) {
 	p.mu.Unlock()
 
 	if paused {
+		droppedPeerPaused.Inc()
 		return
 	}
 
@@ -262,6 +263,7 @@ func (p *peer) send(m raftpb.Message) {
 			)
 		}
 		sentFailures.WithLabelValues(types.ID(m.To).String()).Inc()
+		droppedSendBufferFull.Inc()
 	}
 }
 
@@ -198,6 +198,7 @@ func (t *Transport) Send(msgs []raftpb.Message) {
 			continue
 		}
 
+		droppedUnknownPeer.Inc()
 		if t.Logger != nil {
 			t.Logger.Debug(
 				"ignored message send request; unknown remote peer target",
@@ -237,6 +237,7 @@ func (s *Snapshotter) checkSuffix(names []string) []string {
 
 // cleanupSnapdir removes any files that should not be in the snapshot directory:
 // - db.tmp prefixed files that can be orphaned by defragmentation
+// - snapshot-*.db.tmp files that can be orphaned by snapshots at a revision
 func (s *Snapshotter) cleanupSnapdir(filenames []string) (names []string, err error) {
 	names = make([]string, 0, len(filenames))
 	for _, filename := range filenames {
@@ -245,6 +246,11 @@ func (s *Snapshotter) cleanupSnapdir(filenames []string) (names []string, err er
 			if rmErr := os.Remove(filepath.Join(s.dir, filename)); rmErr != nil && !os.IsNotExist(rmErr) {
 				return names, fmt.Errorf("failed to remove orphaned .snap.db file %s: %v", filename, rmErr)
 			}
+		} else if strings.HasPrefix(filename, "snapshot-") && strings.HasSuffix(filename, ".db.tmp") {
+			s.lg.Info("found orphaned revision snapshot file; deleting", zap.String("path", filename))
+			if rmErr := os.Remove(filepath.Join(s.dir, filename)); rmErr != nil && !os.IsNotExist(rmErr) {
+				return names, fmt.Errorf("failed to remove orphaned revision snapshot file %s: %v", filename, rmErr)
+			}
 		} else {
 			names = append(names, filename)
 		}
@@ -148,6 +148,32 @@ func TestSnapNames(t *testing.T) {
 	}
 }
 
+func TestSnapNamesCleanupOrphans(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"1.snap", "db.tmp.123", "snapshot-456.db.tmp", "db"}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ss := New(zaptest.NewLogger(t), dir)
+	names, err := ss.snapNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := []string{"1.snap"}; !reflect.DeepEqual(names, w) {
+		t.Errorf("names = %v, want %v", names, w)
+	}
+	for _, name := range []string{"db.tmp.123", "snapshot-456.db.tmp"} {
+		if fileutil.Exist(filepath.Join(dir, name)) {
+			t.Errorf("expected orphaned %s to be deleted", name)
+		}
+	}
+	if !fileutil.Exist(filepath.Join(dir, "db")) {
+		t.Error("expected db to be kept")
+	}
+}
+
 func TestLoadNewestSnap(t *testing.T) {
 	dir := filepath.Join(os.TempDir(), "snapshot")
 	err := os.Mkdir(dir, 0700)
@@ -56,17 +56,23 @@ func New(
 	lg *zap.Logger,
 	mode string,
 	retention time.Duration,
+	prefixes []PrefixRetention,
 	rg RevGetter,
 	c Compactable,
 ) (Compactor, error) {
 	if lg == nil {
 		lg = zap.NewNop()
 	}
+	clock := clockwork.NewRealClock()
 	switch mode {
 	case ModePeriodic:
-		return newPeriodic(lg, clockwork.NewRealClock(), retention, rg, c), nil
+		pc := newPeriodic(lg, clock, retention, rg, c)
+		pc.prefixes = newPrefixRetentions(clock, prefixes)
+		return pc, nil
 	case ModeRevision:
-		return newRevision(lg, clockwork.NewRealClock(), int64(retention), rg, c), nil
+		rc := newRevision(lg, clock, int64(retention), rg, c)
+		rc.prefixes = newPrefixRetentions(clock, prefixes)
+		return rc, nil
 	default:
 		return nil, fmt.Errorf("unsupported compaction mode %s", mode)
 	}
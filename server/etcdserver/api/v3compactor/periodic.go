@@ -35,6 +35,8 @@ type Periodic struct {
 
 	rg RevGetter
 	c  Compactable
+	// prefixes is nil if no prefix is retained.
+	prefixes *prefixRetentions
 
 	revs   []int64
 	ctx    context.Context
@@ -107,6 +109,7 @@ func (pc *Periodic) Run() {
 		baseInterval := pc.period
 		for {
 			pc.revs = append(pc.revs, pc.rg.Rev())
+			pc.prefixes.record(pc.revs[len(pc.revs)-1])
 			if len(pc.revs) > retentions {
 				pc.revs = pc.revs[1:] // pc.revs[0] is always the rev at pc.period ago
 			}
@@ -138,7 +141,10 @@ func (pc *Periodic) Run() {
 				zap.Duration("compact-period", pc.period),
 			)
 			startTime := pc.clock.Now()
-			_, err := pc.c.Compact(pc.ctx, &pb.CompactionRequest{Revision: rev})
+			_, err := pc.c.Compact(pc.ctx, &pb.CompactionRequest{
+				Revision:         rev,
+				RetainedPrefixes: pc.prefixes.compactions(pc.revs[len(pc.revs)-1]),
+			})
 			if err == nil || err == mvcc.ErrCompacted {
 				pc.lg.Info(
 					"completed auto periodic compaction",
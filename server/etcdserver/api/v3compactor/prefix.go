@@ -0,0 +1,106 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3compactor
+
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// PrefixRetention is the retention of the keys with Prefix, which applies to
+// them instead of the retention of the compactor. Exactly one of Period and
+// Revisions is set. A key under several prefixes keeps the longest of their
+// retentions.
+type PrefixRetention struct {
+	Prefix string
+	// Period keeps the revisions written within the last Period.
+	Period time.Duration
+	// Revisions keeps the last Revisions revisions of the store.
+	Revisions int64
+}
+
+type revSample struct {
+	t   time.Time
+	rev int64
+}
+
+// prefixRetentions computes the revisions to compact the retained prefixes
+// at. The revisions of the periods are looked up in the revisions sampled
+// by the compactor, so they are as accurate as its sampling interval.
+type prefixRetentions struct {
+	clock    clockwork.Clock
+	prefixes []PrefixRetention
+	// maxPeriod is the longest period of the prefixes.
+	maxPeriod time.Duration
+	// samples are the sampled revisions, oldest first.
+	samples []revSample
+}
+
+func newPrefixRetentions(clock clockwork.Clock, prefixes []PrefixRetention) *prefixRetentions {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	pr := &prefixRetentions{clock: clock, prefixes: prefixes}
+	for _, p := range prefixes {
+		if p.Period > pr.maxPeriod {
+			pr.maxPeriod = p.Period
+		}
+	}
+	return pr
+}
+
+// record samples the current revision of the store. The samples older than
+// the longest period are dropped, except the newest of them.
+func (pr *prefixRetentions) record(rev int64) {
+	if pr == nil {
+		return
+	}
+	now := pr.clock.Now()
+	pr.samples = append(pr.samples, revSample{t: now, rev: rev})
+	i := 0
+	for i+1 < len(pr.samples) && now.Sub(pr.samples[i+1].t) >= pr.maxPeriod {
+		i++
+	}
+	pr.samples = pr.samples[i:]
+}
+
+// compactions returns the revisions to compact the retained prefixes at,
+// given the current revision rev. A prefix whose period has not elapsed yet
+// is compacted at revision 0, which compacts none of its keys.
+func (pr *prefixRetentions) compactions(rev int64) []*pb.PrefixCompaction {
+	if pr == nil {
+		return nil
+	}
+	now := pr.clock.Now()
+	compactions := make([]*pb.PrefixCompaction, 0, len(pr.prefixes))
+	for _, p := range pr.prefixes {
+		var crev int64
+		if p.Period > 0 {
+			for _, s := range pr.samples {
+				if now.Sub(s.t) < p.Period {
+					break
+				}
+				crev = s.rev
+			}
+		} else if rev > p.Revisions {
+			crev = rev - p.Revisions
+		}
+		compactions = append(compactions, &pb.PrefixCompaction{Prefix: []byte(p.Prefix), Revision: crev})
+	}
+	return compactions
+}
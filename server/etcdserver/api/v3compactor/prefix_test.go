@@ -0,0 +1,88 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3compactor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/testutil"
+
+	"github.com/jonboulle/clockwork"
+)
+
+func TestPrefixRetentions(t *testing.T) {
+	fc := clockwork.NewFakeClock()
+	pr := newPrefixRetentions(fc, []PrefixRetention{
+		{Prefix: "/config/", Period: time.Hour},
+		{Prefix: "/metrics/", Revisions: 10},
+	})
+
+	// the period of /config/ has not elapsed yet
+	pr.record(5)
+	want := []*pb.PrefixCompaction{
+		{Prefix: []byte("/config/"), Revision: 0},
+		{Prefix: []byte("/metrics/"), Revision: 0},
+	}
+	if got := pr.compactions(5); !reflect.DeepEqual(got, want) {
+		t.Errorf("compactions = %v, want %v", got, want)
+	}
+
+	for rev := int64(100); rev <= 700; rev += 100 {
+		fc.Advance(10 * time.Minute)
+		pr.record(rev)
+	}
+	// the revision an hour ago was sampled at revision 100
+	want = []*pb.PrefixCompaction{
+		{Prefix: []byte("/config/"), Revision: 100},
+		{Prefix: []byte("/metrics/"), Revision: 690},
+	}
+	if got := pr.compactions(700); !reflect.DeepEqual(got, want) {
+		t.Errorf("compactions = %v, want %v", got, want)
+	}
+	if len(pr.samples) != 7 {
+		t.Errorf("len(samples) = %d, want 7", len(pr.samples))
+	}
+}
+
+func TestRevisionRetainedPrefixes(t *testing.T) {
+	fc := clockwork.NewFakeClock()
+	rg := &fakeRevGetter{testutil.NewRecorderStream(), 99} // will be 100
+	compactable := &fakeCompactable{testutil.NewRecorderStream()}
+	tb := newRevision(zaptest.NewLogger(t), fc, 10, rg, compactable)
+	tb.prefixes = newPrefixRetentions(fc, []PrefixRetention{{Prefix: "/config/", Revisions: 50}})
+
+	tb.Run()
+	defer tb.Stop()
+
+	fc.BlockUntil(1)
+	fc.Advance(revInterval)
+	rg.Wait(1)
+	a, err := compactable.Wait(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &pb.CompactionRequest{
+		Revision:         90,
+		RetainedPrefixes: []*pb.PrefixCompaction{{Prefix: []byte("/config/"), Revision: 50}},
+	}
+	if !reflect.DeepEqual(a[0].Params[0], want) {
+		t.Errorf("compact request = %v, want %v", a[0].Params[0], want)
+	}
+}
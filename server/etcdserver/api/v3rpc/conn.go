@@ -0,0 +1,57 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+)
+
+// nextConnID is shared by the gRPC servers of all client listeners, so that
+// their connections get distinct IDs.
+var nextConnID uint64
+
+type connIDKey struct{}
+
+// connTagger tags the context of every client connection with a unique ID,
+// which the contexts of the streams of the connection inherit.
+type connTagger struct{}
+
+func (connTagger) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return context.WithValue(ctx, connIDKey{}, strconv.FormatUint(atomic.AddUint64(&nextConnID, 1), 10))
+}
+
+func (connTagger) HandleConn(context.Context, stats.ConnStats) {}
+
+func (connTagger) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context { return ctx }
+
+func (connTagger) HandleRPC(context.Context, stats.RPCStats) {}
+
+// connID returns the ID connTagger tagged the connection of a stream context
+// with. Streams served over HTTP do not go through connTagger, so their
+// connection is identified by the TCP address of the client instead.
+func connID(ctx context.Context) (string, bool) {
+	if id, ok := ctx.Value(connIDKey{}).(string); ok {
+		return id, true
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil && p.Addr.Network() == "tcp" {
+		return p.Addr.String(), true
+	}
+	return "", false
+}
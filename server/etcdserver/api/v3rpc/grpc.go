@@ -67,6 +67,9 @@ func Server(s *etcdserver.EtcdServer, tls *tls.Config, interceptor grpc.UnarySer
 	opts = append(opts, grpc.MaxRecvMsgSize(int(s.Cfg.MaxRequestBytes+grpcOverheadBytes)))
 	opts = append(opts, grpc.MaxSendMsgSize(maxSendBytes))
 	opts = append(opts, grpc.MaxConcurrentStreams(s.Cfg.MaxConcurrentStreams))
+	if s.Cfg.MaxWatchStreamsPerConnection > 0 {
+		opts = append(opts, grpc.StatsHandler(connTagger{}))
+	}
 
 	grpcServer := grpc.NewServer(append(opts, gopts...)...)
 
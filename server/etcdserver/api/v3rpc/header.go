@@ -21,18 +21,20 @@ import (
 )
 
 type header struct {
-	clusterID int64
-	memberID  int64
-	sg        apply.RaftStatusGetter
-	rev       func() int64
+	clusterID  int64
+	memberID   int64
+	sg         apply.RaftStatusGetter
+	rev        func() int64
+	compactRev func() int64
 }
 
 func newHeader(s *etcdserver.EtcdServer) header {
 	return header{
-		clusterID: int64(s.Cluster().ID()),
-		memberID:  int64(s.MemberId()),
-		sg:        s,
-		rev:       func() int64 { return s.KV().Rev() },
+		clusterID:  int64(s.Cluster().ID()),
+		memberID:   int64(s.MemberId()),
+		sg:         s,
+		rev:        func() int64 { return s.KV().Rev() },
+		compactRev: func() int64 { return s.KV().ReadFloorRev() },
 	}
 }
 
@@ -47,4 +49,5 @@ func (h *header) fill(rh *pb.ResponseHeader) {
 	if rh.Revision == 0 {
 		rh.Revision = h.rev()
 	}
+	rh.CompactRevision = h.compactRev()
 }
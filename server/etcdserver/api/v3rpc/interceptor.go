@@ -177,7 +177,8 @@ func logUnaryRequestStats(ctx context.Context, lg *zap.Logger, warnLatency time.
 	if enabledDebugLevel {
 		logGenericRequestStats(lg, startTime, duration, remote, responseType, reqCount, reqSize, respCount, respSize, reqContent)
 	} else if expensiveRequest {
-		logExpensiveRequestStats(lg, startTime, duration, remote, responseType, reqCount, reqSize, respCount, respSize, reqContent)
+		deadline, _ := ctx.Deadline()
+		logExpensiveRequestStats(lg, startTime, duration, deadline, remote, responseType, reqCount, reqSize, respCount, respSize, reqContent)
 	}
 }
 
@@ -196,11 +197,18 @@ func logGenericRequestStats(lg *zap.Logger, startTime time.Time, duration time.D
 	)
 }
 
-func logExpensiveRequestStats(lg *zap.Logger, startTime time.Time, duration time.Duration, remote string, responseType string,
+// logExpensiveRequestStats logs a slow request. If the client set a deadline,
+// the time that remained until it when the request started is logged too.
+func logExpensiveRequestStats(lg *zap.Logger, startTime time.Time, duration time.Duration, deadline time.Time, remote string, responseType string,
 	reqCount int64, reqSize int, respCount int64, respSize int, reqContent string) {
-	lg.Warn("request stats",
+	fields := []zap.Field{
 		zap.Time("start time", startTime),
 		zap.Duration("time spent", duration),
+	}
+	if !deadline.IsZero() {
+		fields = append(fields, zap.Duration("remaining deadline", deadline.Sub(startTime)))
+	}
+	fields = append(fields,
 		zap.String("remote", remote),
 		zap.String("response type", responseType),
 		zap.Int64("request count", reqCount),
@@ -209,6 +217,7 @@ func logExpensiveRequestStats(lg *zap.Logger, startTime time.Time, duration time
 		zap.Int("response size", respSize),
 		zap.String("request content", reqContent),
 	)
+	lg.Warn("request stats", fields...)
 }
 
 func newStreamInterceptor(s *etcdserver.EtcdServer) grpc.StreamServerInterceptor {
@@ -17,11 +17,15 @@ package v3rpc
 
 import (
 	"context"
+	"fmt"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/pkg/v3/adt"
 	"go.etcd.io/etcd/server/v3/etcdserver"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
 )
 
 type kvServer struct {
@@ -32,10 +36,21 @@ type kvServer struct {
 	// Txn.Success can have at most 128 operations,
 	// and Txn.Failure can have at most 128 operations.
 	maxTxnOps uint
+	// maxTxnTotalOps is the max comparisons and operations per txn, counting
+	// both branches and the nested txns together.
+	maxTxnTotalOps uint
+	// maxValueBytes is the max size of a value put by a put or txn request.
+	// Values are only limited by the max request size if it is zero.
+	maxValueBytes uint
 }
 
 func NewKVServer(s *etcdserver.EtcdServer) pb.KVServer {
-	return &kvServer{hdr: newHeader(s), kv: s, maxTxnOps: s.Cfg.MaxTxnOps}
+	maxTxnTotalOps := s.Cfg.MaxTxnTotalOps
+	if maxTxnTotalOps == 0 {
+		// enough for any txn without nested txns
+		maxTxnTotalOps = 3 * s.Cfg.MaxTxnOps
+	}
+	return &kvServer{hdr: newHeader(s), kv: s, maxTxnOps: s.Cfg.MaxTxnOps, maxTxnTotalOps: maxTxnTotalOps, maxValueBytes: s.Cfg.MaxValueBytes}
 }
 
 func (s *kvServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
@@ -56,6 +71,9 @@ func (s *kvServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse,
 	if err := checkPutRequest(r); err != nil {
 		return nil, err
 	}
+	if s.maxValueBytes > 0 && len(r.Value) > int(s.maxValueBytes) {
+		return nil, rpctypes.ErrGRPCValueTooLarge
+	}
 
 	resp, err := s.kv.Put(ctx, r)
 	if err != nil {
@@ -81,7 +99,7 @@ func (s *kvServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*
 }
 
 func (s *kvServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
-	if err := checkTxnRequest(r, int(s.maxTxnOps)); err != nil {
+	if err := checkTxnRequest(r, int(s.maxTxnOps), int(s.maxTxnTotalOps)); err != nil {
 		return nil, err
 	}
 	// check for forbidden put/del overlaps after checking request to avoid quadratic blowup
@@ -91,6 +109,11 @@ func (s *kvServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse,
 	if _, _, err := checkIntervals(r.Failure); err != nil {
 		return nil, err
 	}
+	if s.maxValueBytes > 0 {
+		if err := checkTxnValueSize(r, int(s.maxValueBytes)); err != nil {
+			return nil, err
+		}
+	}
 
 	resp, err := s.kv.Txn(ctx, r)
 	if err != nil {
@@ -101,6 +124,35 @@ func (s *kvServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse,
 	return resp, nil
 }
 
+func (s *kvServer) TxnStream(r *pb.TxnRequest, stream pb.KV_TxnStreamServer) error {
+	if err := checkTxnRequest(r, int(s.maxTxnOps), int(s.maxTxnTotalOps)); err != nil {
+		return err
+	}
+	// check for forbidden put/del overlaps after checking request to avoid quadratic blowup
+	if _, _, err := checkIntervals(r.Success); err != nil {
+		return err
+	}
+	if _, _, err := checkIntervals(r.Failure); err != nil {
+		return err
+	}
+	if s.maxValueBytes > 0 {
+		if err := checkTxnValueSize(r, int(s.maxValueBytes)); err != nil {
+			return err
+		}
+	}
+
+	err := s.kv.TxnStream(stream.Context(), r, func(resp *pb.TxnStreamResponse) error {
+		if resp.Header != nil {
+			s.hdr.fill(resp.Header)
+		}
+		return stream.Send(resp)
+	})
+	if err != nil {
+		return togRPCError(err)
+	}
+	return nil
+}
+
 func (s *kvServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error) {
 	resp, err := s.kv.Compact(ctx, r)
 	if err != nil {
@@ -111,6 +163,20 @@ func (s *kvServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.Co
 	return resp, nil
 }
 
+func (s *kvServer) CompactKey(ctx context.Context, r *pb.CompactKeyRequest) (*pb.CompactKeyResponse, error) {
+	if err := checkCompactKeyRequest(r); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.kv.CompactKey(ctx, r)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+
+	s.hdr.fill(resp.Header)
+	return resp, nil
+}
+
 func checkRangeRequest(r *pb.RangeRequest) error {
 	if len(r.Key) == 0 {
 		return rpctypes.ErrGRPCEmptyKey
@@ -131,12 +197,34 @@ func checkPutRequest(r *pb.PutRequest) error {
 	if len(r.Key) == 0 {
 		return rpctypes.ErrGRPCEmptyKey
 	}
-	if r.IgnoreValue && len(r.Value) != 0 {
+	if (r.IgnoreValue || len(r.CopyFrom) != 0) && len(r.Value) != 0 {
+		return rpctypes.ErrGRPCValueProvided
+	}
+	if r.IgnoreValue && len(r.CopyFrom) != 0 {
 		return rpctypes.ErrGRPCValueProvided
 	}
-	if r.IgnoreLease && r.Lease != 0 {
+	if r.CopyLease && (len(r.CopyFrom) == 0 || r.IgnoreLease || r.Ttl != 0 || r.Lease != 0) {
 		return rpctypes.ErrGRPCLeaseProvided
 	}
+	if (r.IgnoreLease || r.Ttl != 0) && r.Lease != 0 {
+		return rpctypes.ErrGRPCLeaseProvided
+	}
+	if r.IgnoreLease && r.Ttl != 0 {
+		return rpctypes.ErrGRPCKeyTTLIgnoreLease
+	}
+	if r.Ttl < 0 {
+		return rpctypes.ErrGRPCInvalidKeyTTL
+	}
+	return nil
+}
+
+func checkCompactKeyRequest(r *pb.CompactKeyRequest) error {
+	if len(r.Key) == 0 {
+		return rpctypes.ErrGRPCEmptyKey
+	}
+	if r.Keep < 1 {
+		return rpctypes.ErrGRPCInvalidKeep
+	}
 	return nil
 }
 
@@ -147,7 +235,32 @@ func checkDeleteRequest(r *pb.DeleteRangeRequest) error {
 	return nil
 }
 
-func checkTxnRequest(r *pb.TxnRequest, maxTxnOps int) error {
+func checkTxnRequest(r *pb.TxnRequest, maxTxnOps, maxTxnTotalOps int) error {
+	if maxTxnTotalOps > 0 && countTxnOps(r, maxTxnTotalOps) > maxTxnTotalOps {
+		return rpctypes.ErrGRPCTooManyOps
+	}
+	return checkTxnOps(r, maxTxnOps)
+}
+
+// countTxnOps returns the number of comparisons and operations of r, those of
+// nested txns included. It stops counting once the count is over maxOps, so
+// that deeply nested txns are not walked through.
+func countTxnOps(r *pb.TxnRequest, maxOps int) int {
+	n := len(r.Compare) + len(r.Success) + len(r.Failure)
+	for _, reqs := range [][]*pb.RequestOp{r.Success, r.Failure} {
+		for _, u := range reqs {
+			if n > maxOps {
+				return n
+			}
+			if tv, ok := u.Request.(*pb.RequestOp_RequestTxn); ok {
+				n += countTxnOps(tv.RequestTxn, maxOps-n)
+			}
+		}
+	}
+	return n
+}
+
+func checkTxnOps(r *pb.TxnRequest, maxTxnOps int) error {
 	opc := len(r.Compare)
 	if opc < len(r.Success) {
 		opc = len(r.Success)
@@ -178,6 +291,50 @@ func checkTxnRequest(r *pb.TxnRequest, maxTxnOps int) error {
 	return nil
 }
 
+// checkTxnValueSize returns ErrGRPCValueTooLarge if any put of the txn, including
+// puts of nested txns, has a value larger than maxValueBytes. The error details
+// hold the path of the first such op, e.g. "success[0].failure[1]".
+func checkTxnValueSize(r *pb.TxnRequest, maxValueBytes int) error {
+	path, size := oversizedTxnOp(r, maxValueBytes)
+	if path == "" {
+		return nil
+	}
+	st, err := status.Convert(rpctypes.ErrGRPCValueTooLarge).WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       path,
+			Description: fmt.Sprintf("value size %d exceeds max value bytes %d", size, maxValueBytes),
+		}},
+	})
+	if err != nil {
+		return rpctypes.ErrGRPCValueTooLarge
+	}
+	return st.Err()
+}
+
+// oversizedTxnOp returns the path and value size of the first put of the txn
+// with a value larger than maxValueBytes, or an empty path if there is none.
+func oversizedTxnOp(r *pb.TxnRequest, maxValueBytes int) (path string, size int) {
+	branches := []struct {
+		name string
+		ops  []*pb.RequestOp
+	}{{"success", r.Success}, {"failure", r.Failure}}
+	for _, b := range branches {
+		for i, op := range b.ops {
+			switch uv := op.Request.(type) {
+			case *pb.RequestOp_RequestPut:
+				if len(uv.RequestPut.Value) > maxValueBytes {
+					return fmt.Sprintf("%s[%d]", b.name, i), len(uv.RequestPut.Value)
+				}
+			case *pb.RequestOp_RequestTxn:
+				if p, size := oversizedTxnOp(uv.RequestTxn, maxValueBytes); p != "" {
+					return fmt.Sprintf("%s[%d].%s", b.name, i, p), size
+				}
+			}
+		}
+	}
+	return "", 0
+}
+
 // checkIntervals tests whether puts and deletes overlap for a list of ops. If
 // there is an overlap, returns an error. If no overlap, return put and delete
 // sets for recursive evaluation.
@@ -268,11 +425,14 @@ func checkRequestOp(u *pb.RequestOp, maxTxnOps int) error {
 	case *pb.RequestOp_RequestRange:
 		return checkRangeRequest(uv.RequestRange)
 	case *pb.RequestOp_RequestPut:
+		if uv.RequestPut.Ttl != 0 {
+			return rpctypes.ErrGRPCKeyTTLInTxn
+		}
 		return checkPutRequest(uv.RequestPut)
 	case *pb.RequestOp_RequestDeleteRange:
 		return checkDeleteRequest(uv.RequestDeleteRange)
 	case *pb.RequestOp_RequestTxn:
-		return checkTxnRequest(uv.RequestTxn, maxTxnOps)
+		return checkTxnOps(uv.RequestTxn, maxTxnOps)
 	default:
 		// empty op / nil entry
 		return rpctypes.ErrGRPCKeyNotFound
@@ -66,3 +66,155 @@ func getError(err error) string {
 
 	return err.Error()
 }
+
+func TestOversizedTxnOp(t *testing.T) {
+	put := func(size int) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("foo"), Value: make([]byte, size)}}}
+	}
+	txn := func(success []*pb.RequestOp, failure ...*pb.RequestOp) *pb.TxnRequest {
+		return &pb.TxnRequest{Success: success, Failure: failure}
+	}
+	nested := func(r *pb.TxnRequest) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: r}}
+	}
+
+	tests := []struct {
+		name     string
+		txn      *pb.TxnRequest
+		wantPath string
+		wantSize int
+	}{
+		{
+			name: "all values within limit",
+			txn:  txn([]*pb.RequestOp{put(10), put(5)}, put(10)),
+		},
+		{
+			name:     "oversized success op",
+			txn:      txn([]*pb.RequestOp{put(10), put(11)}),
+			wantPath: "success[1]",
+			wantSize: 11,
+		},
+		{
+			name:     "oversized failure op",
+			txn:      txn([]*pb.RequestOp{put(10)}, put(1), put(20)),
+			wantPath: "failure[1]",
+			wantSize: 20,
+		},
+		{
+			name:     "oversized nested op",
+			txn:      txn([]*pb.RequestOp{put(1), nested(txn(nil, put(1), put(12)))}),
+			wantPath: "success[1].failure[1]",
+			wantSize: 12,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path, size := oversizedTxnOp(tc.txn, 10)
+			if path != tc.wantPath || size != tc.wantSize {
+				t.Errorf("got path %q and size %d, want path %q and size %d", path, size, tc.wantPath, tc.wantSize)
+			}
+		})
+	}
+}
+
+func TestCheckTxnRequestTooManyOps(t *testing.T) {
+	put := &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("foo")}}}
+	cmp := &pb.Compare{Key: []byte("foo")}
+	ops := func(n int) []*pb.RequestOp {
+		reqs := make([]*pb.RequestOp, n)
+		for i := range reqs {
+			reqs[i] = put
+		}
+		return reqs
+	}
+	cmps := func(n int) []*pb.Compare {
+		cs := make([]*pb.Compare, n)
+		for i := range cs {
+			cs[i] = cmp
+		}
+		return cs
+	}
+	nested := func(r *pb.TxnRequest) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: r}}
+	}
+	// deep nests r in depth txns, each adding an operation
+	deep := func(depth int, r *pb.TxnRequest) *pb.TxnRequest {
+		for i := 0; i < depth; i++ {
+			r = &pb.TxnRequest{Success: []*pb.RequestOp{nested(r)}}
+		}
+		return r
+	}
+
+	const maxTxnOps, maxTxnTotalOps = 10, 10
+	tests := []struct {
+		name           string
+		txn            *pb.TxnRequest
+		maxTxnTotalOps int
+		wantErr        error
+	}{
+		{
+			name: "widest branch at the limit",
+			txn:  &pb.TxnRequest{Compare: cmps(10), Success: ops(10), Failure: ops(10)},
+		},
+		{
+			name:    "widest branch over the limit",
+			txn:     &pb.TxnRequest{Compare: cmps(2), Success: ops(11), Failure: ops(4)},
+			wantErr: rpctypes.ErrGRPCTooManyOps,
+		},
+		{
+			name: "nested at the branch limit",
+			txn:  &pb.TxnRequest{Success: []*pb.RequestOp{put, put, nested(&pb.TxnRequest{Failure: ops(7)})}},
+		},
+		{
+			name:    "nested over the branch limit",
+			txn:     &pb.TxnRequest{Success: []*pb.RequestOp{put, put, nested(&pb.TxnRequest{Failure: ops(8)})}},
+			wantErr: rpctypes.ErrGRPCTooManyOps,
+		},
+		{
+			name:           "branches at the total limit",
+			txn:            &pb.TxnRequest{Compare: cmps(2), Success: ops(4), Failure: ops(4)},
+			maxTxnTotalOps: maxTxnTotalOps,
+		},
+		{
+			name:           "branches over the total limit",
+			txn:            &pb.TxnRequest{Compare: cmps(2), Success: ops(4), Failure: ops(5)},
+			maxTxnTotalOps: maxTxnTotalOps,
+			wantErr:        rpctypes.ErrGRPCTooManyOps,
+		},
+		{
+			name:           "nested at the total limit",
+			txn:            &pb.TxnRequest{Compare: cmps(1), Success: []*pb.RequestOp{put, nested(&pb.TxnRequest{Compare: cmps(2), Failure: ops(5)})}},
+			maxTxnTotalOps: maxTxnTotalOps,
+		},
+		{
+			name:           "nested over the total limit",
+			txn:            &pb.TxnRequest{Compare: cmps(1), Success: []*pb.RequestOp{put, nested(&pb.TxnRequest{Compare: cmps(2), Failure: ops(6)})}},
+			maxTxnTotalOps: maxTxnTotalOps,
+			wantErr:        rpctypes.ErrGRPCTooManyOps,
+		},
+		{
+			name:           "deeply nested at the total limit",
+			txn:            deep(maxTxnTotalOps-1, &pb.TxnRequest{Success: ops(1)}),
+			maxTxnTotalOps: maxTxnTotalOps,
+		},
+		{
+			name:           "deeply nested over the total limit",
+			txn:            deep(maxTxnTotalOps, &pb.TxnRequest{Success: ops(1)}),
+			maxTxnTotalOps: maxTxnTotalOps,
+			wantErr:        rpctypes.ErrGRPCTooManyOps,
+		},
+		{
+			name:           "too deeply nested",
+			txn:            deep(100000, &pb.TxnRequest{}),
+			maxTxnTotalOps: maxTxnTotalOps,
+			wantErr:        rpctypes.ErrGRPCTooManyOps,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkTxnRequest(tc.txn, maxTxnOps, tc.maxTxnTotalOps); getError(err) != getError(tc.wantErr) {
+				t.Errorf("got error %q, want %q", getError(err), getError(tc.wantErr))
+			}
+		})
+	}
+}
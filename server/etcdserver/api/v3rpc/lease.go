@@ -50,6 +50,24 @@ func (ls *LeaseServer) LeaseGrant(ctx context.Context, cr *pb.LeaseGrantRequest)
 	return resp, nil
 }
 
+func (ls *LeaseServer) LeaseGrantBatch(ctx context.Context, cr *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	resp, err := ls.le.LeaseGrantBatch(ctx, cr)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	ls.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ls *LeaseServer) LeaseReattach(ctx context.Context, rr *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	resp, err := ls.le.LeaseReattach(ctx, rr)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	ls.hdr.fill(resp.Header)
+	return resp, nil
+}
+
 func (ls *LeaseServer) LeaseRevoke(ctx context.Context, rr *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
 	resp, err := ls.le.LeaseRevoke(ctx, rr)
 	if err != nil {
@@ -90,6 +108,15 @@ func (ls *LeaseServer) LeaseLeases(ctx context.Context, rr *pb.LeaseLeasesReques
 	return resp, nil
 }
 
+func (ls *LeaseServer) LeaseListDetailed(ctx context.Context, rr *pb.LeaseListDetailedRequest) (*pb.LeaseListDetailedResponse, error) {
+	resp, err := ls.le.LeaseListDetailed(ctx, rr)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	ls.hdr.fill(resp.Header)
+	return resp, nil
+}
+
 func (ls *LeaseServer) LeaseKeepAlive(stream pb.Lease_LeaseKeepAliveServer) (err error) {
 	errc := make(chan error, 1)
 	go func() {
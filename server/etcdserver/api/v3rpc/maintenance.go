@@ -18,6 +18,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"io"
+	"math/rand"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
@@ -25,7 +28,10 @@ import (
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/api/v3/version"
+	"go.etcd.io/etcd/client/pkg/v3/types"
 	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.etcd.io/etcd/server/v3/etcdserver/api"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
 	"go.etcd.io/etcd/server/v3/etcdserver/apply"
 	"go.etcd.io/etcd/server/v3/etcdserver/errors"
 	serverversion "go.etcd.io/etcd/server/v3/etcdserver/version"
@@ -60,14 +66,33 @@ type LeaderTransferrer interface {
 	MoveLeader(ctx context.Context, lead, target uint64) error
 }
 
+type RaftSnapshotter interface {
+	TriggerSnapshot(ctx context.Context) (index uint64, inProgress bool, err error)
+}
+
+type AuthImporter interface {
+	AuthExport(ctx context.Context, r *pb.AuthExportRequest) (*pb.AuthExportResponse, error)
+	AuthImport(ctx context.Context, r *pb.AuthImportRequest) (*pb.AuthImportResponse, error)
+}
+
+type PrefixQuotaSetter interface {
+	PrefixQuotaSet(ctx context.Context, r *pb.PrefixQuotaSetRequest) (*pb.PrefixQuotaSetResponse, error)
+}
+
+type IndexRebuilder interface {
+	RebuildIndex(ctx context.Context, force bool) (rev int64, anomalies []mvcc.Anomaly, err error)
+}
+
 type ClusterStatusGetter interface {
 	IsLearner() bool
+	LearnerProgress() uint64
 }
 
 type maintenanceServer struct {
 	lg     *zap.Logger
 	rg     apply.RaftStatusGetter
 	hasher mvcc.HashStorage
+	kg     KVGetter
 	bg     BackendGetter
 	a      Alarmer
 	lt     LeaderTransferrer
@@ -75,13 +100,33 @@ type maintenanceServer struct {
 	cs     ClusterStatusGetter
 	d      Downgrader
 	vs     serverversion.Server
+	rs     RaftSnapshotter
+	ai     AuthImporter
+	pq     PrefixQuotaSetter
+	ir     IndexRebuilder
+
+	cluster api.Cluster
+	// self is the member serving the requests as configured.
+	self *pb.Member
+
+	snapshots *snapshotCache
 }
 
 func NewMaintenanceServer(s *etcdserver.EtcdServer) pb.MaintenanceServer {
-	srv := &maintenanceServer{lg: s.Cfg.Logger, rg: s, hasher: s.KV().HashStorage(), bg: s, a: s, lt: s, hdr: newHeader(s), cs: s, d: s, vs: etcdserver.NewServerVersionAdapter(s)}
+	srv := &maintenanceServer{lg: s.Cfg.Logger, rg: s, hasher: s.KV().HashStorage(), kg: s, bg: s, a: s, lt: s, hdr: newHeader(s), cs: s, d: s, vs: etcdserver.NewServerVersionAdapter(s), rs: s, ai: s, pq: s, ir: s, cluster: s.Cluster(), snapshots: newSnapshotCache(filepath.Dir(s.Cfg.BackendPath()))}
+	srv.self = &pb.Member{
+		ID:         uint64(s.MemberId()),
+		Name:       s.Cfg.Name,
+		PeerURLs:   s.Cfg.PeerURLs.StringSlice(),
+		ClientURLs: s.Cfg.ClientURLs.StringSlice(),
+	}
 	if srv.lg == nil {
 		srv.lg = zap.NewNop()
 	}
+	go func() {
+		<-s.StoppingNotify()
+		srv.snapshots.stop(srv.lg)
+	}()
 	return &authMaintenanceServer{srv, &AuthAdmin{s}}
 }
 
@@ -99,39 +144,225 @@ func (ms *maintenanceServer) Defragment(ctx context.Context, sr *pb.DefragmentRe
 // big enough size to hold >1 OS pages in the buffer
 const snapshotSendBufferSize = 32 * 1024
 
+// snapshotCacheTTL is how long a snapshot is kept after its last download
+// stopped, so that an interrupted download can be resumed from the same image.
+var snapshotCacheTTL = time.Minute
+
+// cachedSnapshot is a point-in-time backend snapshot shared by the downloads
+// resuming it.
+type cachedSnapshot struct {
+	// id identifies the snapshot to the downloads resuming it.
+	id uint64
+	// rev is the revision of the snapshot.
+	rev            int64
+	snap           backend.Snapshot
+	storageVersion string
+
+	downloads   int
+	interrupted bool
+	expire      *time.Timer
+}
+
+// snapshotCache keeps the snapshots of interrupted downloads until they expire.
+type snapshotCache struct {
+	// dir is where the snapshots trimmed to a revision are written.
+	dir string
+
+	mu        sync.Mutex
+	snapshots map[uint64]*cachedSnapshot
+	// stopped is set once the server stops; the backend cannot be closed
+	// while snapshots of it are open.
+	stopped bool
+}
+
+func newSnapshotCache(dir string) *snapshotCache {
+	return &snapshotCache{dir: dir, snapshots: make(map[uint64]*cachedSnapshot)}
+}
+
+// acquire returns the snapshot to download for the request. A request without
+// offset always takes a new snapshot, at sr.AtRevision if it is set, while a
+// resumed download requires the snapshot identified by sr.SnapshotId to be
+// cached.
+func (c *snapshotCache) acquire(lg *zap.Logger, kg KVGetter, bg BackendGetter, sr *pb.SnapshotRequest) (*cachedSnapshot, error) {
+	if sr.Offset != 0 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if cs, ok := c.cached(sr.SnapshotId); ok {
+			return cs, nil
+		}
+		return nil, rpctypes.ErrGRPCSnapshotExpired
+	}
+
+	cs := &cachedSnapshot{storageVersion: readStorageVersion(bg)}
+	if sr.AtRevision != 0 {
+		// the snapshot is written outside the lock, since it copies the
+		// whole backend
+		switch {
+		case sr.AtRevision > kg.KV().Rev():
+			return nil, rpctypes.ErrGRPCFutureRev
+		case sr.AtRevision < kg.KV().FirstRev():
+			return nil, rpctypes.ErrGRPCCompacted
+		}
+		snap, err := newRevisionSnapshot(lg, c.dir, bg, sr.AtRevision)
+		if err != nil {
+			return nil, togRPCError(err)
+		}
+		cs.rev, cs.snap = sr.AtRevision, snap
+	} else {
+		// the backend snapshot contains at least every revision applied so far
+		cs.rev = kg.KV().Rev()
+		cs.snap = bg.Backend().Snapshot()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for cs.id == 0 || c.snapshots[cs.id] != nil {
+		cs.id = rand.Uint64()
+	}
+	c.snapshots[cs.id] = cs
+	cs.downloads++
+	return cs, nil
+}
+
+// cached returns the snapshot identified by id for a new download, if it is
+// cached.
+func (c *snapshotCache) cached(id uint64) (*cachedSnapshot, bool) {
+	cs, ok := c.snapshots[id]
+	if !ok {
+		return nil, false
+	}
+	if cs.expire != nil {
+		cs.expire.Stop()
+		cs.expire = nil
+	}
+	cs.downloads++
+	return cs, true
+}
+
+func readStorageVersion(bg BackendGetter) string {
+	if ver := schema.ReadStorageVersion(bg.Backend().ReadTx()); ver != nil {
+		return ver.String()
+	}
+	return ""
+}
+
+// release ends a download of cs. The snapshot is closed once no download uses
+// it, after snapshotCacheTTL if any download of it was interrupted.
+func (c *snapshotCache) release(lg *zap.Logger, cs *cachedSnapshot, completed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cs.downloads--
+	cs.interrupted = cs.interrupted || !completed
+	if cs.downloads > 0 {
+		return
+	}
+	if !cs.interrupted || c.stopped {
+		c.remove(lg, cs)
+		return
+	}
+	var expire *time.Timer
+	expire = time.AfterFunc(snapshotCacheTTL, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// the snapshot is in use again if the timer was reset
+		if cs.expire == expire {
+			c.remove(lg, cs)
+		}
+	})
+	cs.expire = expire
+}
+
+// stop closes the snapshots no download uses, and makes the others close as
+// soon as their downloads end.
+func (c *snapshotCache) stop(lg *zap.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	for _, cs := range c.snapshots {
+		if cs.downloads == 0 {
+			c.remove(lg, cs)
+		}
+	}
+}
+
+func (c *snapshotCache) remove(lg *zap.Logger, cs *cachedSnapshot) {
+	if c.snapshots[cs.id] != cs {
+		// already removed
+		return
+	}
+	delete(c.snapshots, cs.id)
+	if cs.expire != nil {
+		cs.expire.Stop()
+		cs.expire = nil
+	}
+	if err := cs.snap.Close(); err != nil {
+		lg.Warn("failed to close snapshot", zap.Error(err))
+	}
+}
+
 func (ms *maintenanceServer) Snapshot(sr *pb.SnapshotRequest, srv pb.Maintenance_SnapshotServer) error {
-	ver := schema.ReadStorageVersion(ms.bg.Backend().ReadTx())
-	storageVersion := ""
-	if ver != nil {
-		storageVersion = ver.String()
+	if sr.Offset < 0 {
+		return rpctypes.ErrGRPCSnapshotOffsetOutOfRange
+	}
+	cs, err := ms.snapshots.acquire(ms.lg, ms.kg, ms.bg, sr)
+	if err != nil {
+		return err
+	}
+	completed := false
+	defer func() {
+		ms.snapshots.release(ms.lg, cs, completed)
+	}()
+
+	total := cs.snap.Size()
+	if sr.Offset > total+sha256.Size {
+		return rpctypes.ErrGRPCSnapshotOffsetOutOfRange
 	}
-	snap := ms.bg.Backend().Snapshot()
-	pr, pw := io.Pipe()
 
-	defer pr.Close()
+	pr, pw := io.Pipe()
+	donec := make(chan struct{})
+	// the snapshot must not be released while it is being written
+	defer func() {
+		pr.Close()
+		<-donec
+	}()
 
 	go func() {
-		snap.WriteTo(pw)
-		if err := snap.Close(); err != nil {
-			ms.lg.Warn("failed to close snapshot", zap.Error(err))
-		}
-		pw.Close()
+		defer close(donec)
+		_, err := cs.snap.WriteTo(pw)
+		pw.CloseWithError(err)
 	}()
 
 	// record SHA digest of snapshot data
 	// used for integrity checks during snapshot restore operation
 	h := sha256.New()
 
-	sent := int64(0)
-	total := snap.Size()
+	// a resumed download skips the data it already received; the skipped
+	// data is still read to compute the digest of the whole snapshot
+	sent := sr.Offset
+	if sent > total {
+		sent = total
+	}
+	if _, err := io.CopyN(h, pr, sent); err != nil {
+		return togRPCError(err)
+	}
+
 	size := humanize.Bytes(uint64(total))
+	storageVersion := cs.storageVersion
 
 	start := time.Now()
 	ms.lg.Info("sending database snapshot to client",
 		zap.Int64("total-bytes", total),
+		zap.Int64("offset", sr.Offset),
+		zap.Int64("revision", cs.rev),
+		zap.Uint64("snapshot-id", cs.id),
 		zap.String("size", size),
 		zap.String("storage-version", storageVersion),
 	)
+	// the first header in the snapshot stream indicates the point in time of the snapshot
+	hdr := &pb.ResponseHeader{Revision: cs.rev}
+	ms.hdr.fill(hdr)
 	for total-sent > 0 {
 		// buffer just holds read bytes from stream
 		// response size is multiple of OS page size, fetched in boltdb
@@ -155,28 +386,40 @@ func (ms *maintenanceServer) Snapshot(sr *pb.SnapshotRequest, srv pb.Maintenance
 		// No, the client will still receive non-nil response
 		// until server closes the stream with EOF
 		resp := &pb.SnapshotResponse{
+			Header:         hdr,
 			RemainingBytes: uint64(total - sent),
 			Blob:           buf[:n],
 			Version:        storageVersion,
 		}
+		if hdr != nil {
+			resp.SnapshotId = cs.id
+		}
 		if err = srv.Send(resp); err != nil {
 			return togRPCError(err)
 		}
+		hdr = nil
 		h.Write(buf[:n])
 	}
 
 	// send SHA digest for integrity checks
 	// during snapshot restore operation
 	sha := h.Sum(nil)
+	if skip := sr.Offset - total; skip > 0 {
+		sha = sha[skip:]
+	}
 
 	ms.lg.Info("sending database sha256 checksum to client",
 		zap.Int64("total-bytes", total),
 		zap.Int("checksum-size", len(sha)),
 	)
-	hresp := &pb.SnapshotResponse{RemainingBytes: 0, Blob: sha, Version: storageVersion}
+	hresp := &pb.SnapshotResponse{Header: hdr, RemainingBytes: 0, Blob: sha, Version: storageVersion}
+	if hdr != nil {
+		hresp.SnapshotId = cs.id
+	}
 	if err := srv.Send(hresp); err != nil {
 		return togRPCError(err)
 	}
+	completed = true
 
 	ms.lg.Info("successfully sent database snapshot to client",
 		zap.Int64("total-bytes", total),
@@ -238,6 +481,7 @@ func (ms *maintenanceServer) Status(ctx context.Context, ar *pb.StatusRequest) (
 		DbSize:           ms.bg.Backend().Size(),
 		DbSizeInUse:      ms.bg.Backend().SizeInUse(),
 		IsLearner:        ms.cs.IsLearner(),
+		LearnerProgress:  ms.cs.LearnerProgress(),
 	}
 	if storageVersion := ms.vs.GetStorageVersion(); storageVersion != nil {
 		resp.StorageVersion = storageVersion.String()
@@ -272,6 +516,256 @@ func (ms *maintenanceServer) Downgrade(ctx context.Context, r *pb.DowngradeReque
 	return resp, nil
 }
 
+func (ms *maintenanceServer) CompactionStatus(ctx context.Context, r *pb.CompactionStatusRequest) (*pb.CompactionStatusResponse, error) {
+	status := ms.kg.KV().CompactionStatus()
+	resp := &pb.CompactionStatusResponse{
+		Header:          &pb.ResponseHeader{},
+		InProgress:      status.InProgress,
+		CompactRevision: status.CompactRevision,
+		ScannedRevision: status.ScannedRevision,
+		RemainingKeys:   status.RemainingKeys,
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) HotKeys(ctx context.Context, r *pb.HotKeysRequest) (*pb.HotKeysResponse, error) {
+	reads, writes, err := ms.kg.KV().HotKeys(int(r.Limit), r.Reset_)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	resp := &pb.HotKeysResponse{
+		Header: &pb.ResponseHeader{},
+		Reads:  toPBHotKeys(reads),
+		Writes: toPBHotKeys(writes),
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) HashKVByRange(ctx context.Context, r *pb.HashKVByRangeRequest) (*pb.HashKVResponse, error) {
+	if len(r.Key) == 0 {
+		return nil, rpctypes.ErrGRPCEmptyKey
+	}
+	h, rev, err := ms.hasher.HashByRevRange(r.Revision, r.Key, r.RangeEnd)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+
+	resp := &pb.HashKVResponse{
+		Header:          &pb.ResponseHeader{Revision: rev},
+		Hash:            h.Hash,
+		CompactRevision: h.CompactRevision,
+		HashRevision:    h.Revision,
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) SpaceReclaim(ctx context.Context, r *pb.SpaceReclaimRequest) (*pb.SpaceReclaimResponse, error) {
+	be := ms.bg.Backend()
+	be.SetSpaceReclaim(r.Enable)
+	enabled, reclaimed := be.SpaceReclaim()
+	resp := &pb.SpaceReclaimResponse{
+		Header:         &pb.ResponseHeader{},
+		Enabled:        enabled,
+		ReclaimedBytes: reclaimed,
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) TriggerSnapshot(ctx context.Context, r *pb.TriggerSnapshotRequest) (*pb.TriggerSnapshotResponse, error) {
+	index, inProgress, err := ms.rs.TriggerSnapshot(ctx)
+	if err != nil {
+		ms.lg.Warn("failed to trigger snapshot", zap.Error(err))
+		return nil, togRPCError(err)
+	}
+	if inProgress {
+		ms.lg.Info("skipped triggered snapshot since a snapshot is in progress", zap.Uint64("snapshot-index", index))
+	}
+	resp := &pb.TriggerSnapshotResponse{
+		Header:        &pb.ResponseHeader{},
+		SnapshotIndex: index,
+		InProgress:    inProgress,
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) AuthExport(ctx context.Context, r *pb.AuthExportRequest) (*pb.AuthExportResponse, error) {
+	resp, err := ms.ai.AuthExport(ctx, r)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) AuthImport(ctx context.Context, r *pb.AuthImportRequest) (*pb.AuthImportResponse, error) {
+	resp, err := ms.ai.AuthImport(ctx, r)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) MemberSelf(ctx context.Context, r *pb.MemberSelfRequest) (*pb.MemberSelfResponse, error) {
+	resp := &pb.MemberSelfResponse{
+		Header:    &pb.ResponseHeader{},
+		ClusterId: uint64(ms.cluster.ID()),
+	}
+	if m := ms.cluster.Member(types.ID(ms.self.ID)); m != nil {
+		resp.Member = membersToProtoMembers([]*membership.Member{m})[0]
+		resp.Joined = true
+		// the attributes of the member are only known to the cluster once
+		// it published them
+		if resp.Member.Name == "" {
+			resp.Member.Name = ms.self.Name
+		}
+		if len(resp.Member.ClientURLs) == 0 {
+			resp.Member.ClientURLs = ms.self.ClientURLs
+		}
+	} else {
+		resp.Member = &pb.Member{
+			ID:         ms.self.ID,
+			Name:       ms.self.Name,
+			PeerURLs:   ms.self.PeerURLs,
+			ClientURLs: ms.self.ClientURLs,
+		}
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) PrefixQuotaSet(ctx context.Context, r *pb.PrefixQuotaSetRequest) (*pb.PrefixQuotaSetResponse, error) {
+	if r.Quota == nil || len(r.Quota.Prefix) == 0 {
+		return nil, rpctypes.ErrGRPCEmptyKey
+	}
+	resp, err := ms.pq.PrefixQuotaSet(ctx, r)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) PrefixQuotaList(ctx context.Context, r *pb.PrefixQuotaListRequest) (*pb.PrefixQuotaListResponse, error) {
+	usages := ms.kg.KV().PrefixQuotas()
+	resp := &pb.PrefixQuotaListResponse{
+		Header: &pb.ResponseHeader{},
+		Quotas: make([]*pb.PrefixQuotaUsage, len(usages)),
+	}
+	for i, u := range usages {
+		resp.Quotas[i] = &pb.PrefixQuotaUsage{
+			Quota: &pb.PrefixQuota{Prefix: u.Prefix, MaxBytes: u.MaxBytes, MaxKeys: u.MaxKeys},
+			Bytes: u.Bytes,
+			Keys:  u.Keys,
+		}
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) VerifyBackend(ctx context.Context, r *pb.VerifyBackendRequest) (*pb.VerifyBackendResponse, error) {
+	ms.lg.Info("starting backend verification")
+	opts := mvcc.VerifyOptions{
+		BatchLimit:    int(r.BatchLimit),
+		BatchInterval: time.Duration(r.BatchIntervalMs) * time.Millisecond,
+	}
+	rev, anomalies, err := ms.kg.KV().Verify(ctx, opts)
+	if err != nil {
+		ms.lg.Warn("failed to verify backend", zap.Error(err))
+		return nil, togRPCError(err)
+	}
+	resp := &pb.VerifyBackendResponse{
+		Header:    &pb.ResponseHeader{},
+		Revision:  rev,
+		Anomalies: toPBAnomalies(anomalies),
+	}
+	if len(anomalies) != 0 {
+		ms.lg.Warn("found backend anomalies", zap.Int64("revision", rev), zap.Int("anomalies", len(anomalies)))
+	} else {
+		ms.lg.Info("finished backend verification", zap.Int64("revision", rev))
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) KeyspaceStats(ctx context.Context, r *pb.KeyspaceStatsRequest) (*pb.KeyspaceStatsResponse, error) {
+	stats, err := ms.kg.KV().KeyspaceStats(ctx, int(r.Depth), int(r.Limit))
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	resp := &pb.KeyspaceStatsResponse{
+		Header:     &pb.ResponseHeader{},
+		Revision:   stats.Revision,
+		Keys:       stats.Keys,
+		KeyBytes:   stats.KeyBytes,
+		ValueBytes: stats.ValueBytes,
+		Prefixes:   make([]*pb.KeyspacePrefix, len(stats.Prefixes)),
+	}
+	for i, p := range stats.Prefixes {
+		resp.Prefixes[i] = &pb.KeyspacePrefix{Prefix: p.Prefix, Keys: p.Keys, Bytes: p.Bytes}
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) RebuildIndex(ctx context.Context, r *pb.RebuildIndexRequest) (*pb.RebuildIndexResponse, error) {
+	ms.lg.Info("starting key index rebuild", zap.Bool("force", r.Force))
+	rev, before, err := ms.ir.RebuildIndex(ctx, r.Force)
+	if err != nil {
+		ms.lg.Warn("failed to rebuild key index", zap.Error(err))
+		return nil, togRPCError(err)
+	}
+	if len(before) != 0 {
+		ms.lg.Warn("replaced key index differed from backend", zap.Int64("revision", rev), zap.Int("anomalies", len(before)))
+	}
+	// the rebuilt index is checked like VerifyBackend, with the writes resumed
+	_, after, err := ms.kg.KV().Verify(ctx, mvcc.VerifyOptions{})
+	if err != nil {
+		ms.lg.Warn("failed to verify rebuilt key index", zap.Error(err))
+		return nil, togRPCError(err)
+	}
+	if len(after) != 0 {
+		ms.lg.Warn("found backend anomalies after key index rebuild", zap.Int64("revision", rev), zap.Int("anomalies", len(after)))
+	} else {
+		ms.lg.Info("finished key index rebuild", zap.Int64("revision", rev))
+	}
+	resp := &pb.RebuildIndexResponse{
+		Header:   &pb.ResponseHeader{},
+		Revision: rev,
+		Before:   toPBAnomalies(before),
+		After:    toPBAnomalies(after),
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func toPBAnomalies(anomalies []mvcc.Anomaly) []*pb.BackendAnomaly {
+	pbAnomalies := make([]*pb.BackendAnomaly, len(anomalies))
+	for i, a := range anomalies {
+		pbAnomalies[i] = &pb.BackendAnomaly{
+			Type:        pb.BackendAnomaly_AnomalyType(a.Type),
+			Key:         a.Key,
+			Revision:    a.Revision,
+			SubRevision: a.SubRevision,
+			Detail:      a.Detail,
+		}
+	}
+	return pbAnomalies
+}
+
+func toPBHotKeys(keys []mvcc.HotKey) []*pb.HotKey {
+	pbKeys := make([]*pb.HotKey, len(keys))
+	for i, k := range keys {
+		pbKeys[i] = &pb.HotKey{Key: k.Key, Count: k.Count}
+	}
+	return pbKeys
+}
+
 type authMaintenanceServer struct {
 	*maintenanceServer
 	*AuthAdmin
@@ -331,3 +825,96 @@ func (ams *authMaintenanceServer) Downgrade(ctx context.Context, r *pb.Downgrade
 
 	return ams.maintenanceServer.Downgrade(ctx, r)
 }
+
+func (ams *authMaintenanceServer) CompactionStatus(ctx context.Context, r *pb.CompactionStatusRequest) (*pb.CompactionStatusResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+
+	return ams.maintenanceServer.CompactionStatus(ctx, r)
+}
+
+func (ams *authMaintenanceServer) HotKeys(ctx context.Context, r *pb.HotKeysRequest) (*pb.HotKeysResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+
+	return ams.maintenanceServer.HotKeys(ctx, r)
+}
+
+func (ams *authMaintenanceServer) HashKVByRange(ctx context.Context, r *pb.HashKVByRangeRequest) (*pb.HashKVResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.HashKVByRange(ctx, r)
+}
+
+func (ams *authMaintenanceServer) SpaceReclaim(ctx context.Context, r *pb.SpaceReclaimRequest) (*pb.SpaceReclaimResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.SpaceReclaim(ctx, r)
+}
+
+func (ams *authMaintenanceServer) MemberSelf(ctx context.Context, r *pb.MemberSelfRequest) (*pb.MemberSelfResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.MemberSelf(ctx, r)
+}
+
+func (ams *authMaintenanceServer) TriggerSnapshot(ctx context.Context, r *pb.TriggerSnapshotRequest) (*pb.TriggerSnapshotResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.TriggerSnapshot(ctx, r)
+}
+
+func (ams *authMaintenanceServer) AuthExport(ctx context.Context, r *pb.AuthExportRequest) (*pb.AuthExportResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.AuthExport(ctx, r)
+}
+
+func (ams *authMaintenanceServer) AuthImport(ctx context.Context, r *pb.AuthImportRequest) (*pb.AuthImportResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.AuthImport(ctx, r)
+}
+
+func (ams *authMaintenanceServer) PrefixQuotaSet(ctx context.Context, r *pb.PrefixQuotaSetRequest) (*pb.PrefixQuotaSetResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.PrefixQuotaSet(ctx, r)
+}
+
+func (ams *authMaintenanceServer) PrefixQuotaList(ctx context.Context, r *pb.PrefixQuotaListRequest) (*pb.PrefixQuotaListResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.PrefixQuotaList(ctx, r)
+}
+
+func (ams *authMaintenanceServer) VerifyBackend(ctx context.Context, r *pb.VerifyBackendRequest) (*pb.VerifyBackendResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.VerifyBackend(ctx, r)
+}
+
+func (ams *authMaintenanceServer) KeyspaceStats(ctx context.Context, r *pb.KeyspaceStatsRequest) (*pb.KeyspaceStatsResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.KeyspaceStats(ctx, r)
+}
+
+func (ams *authMaintenanceServer) RebuildIndex(ctx context.Context, r *pb.RebuildIndexRequest) (*pb.RebuildIndexResponse, error) {
+	if err := ams.isPermitted(ctx); err != nil {
+		return nil, togRPCError(err)
+	}
+	return ams.maintenanceServer.RebuildIndex(ctx, r)
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
+)
+
+type fakeRaftStatus struct{}
+
+func (fakeRaftStatus) MemberId() types.ID     { return 1 }
+func (fakeRaftStatus) Leader() types.ID       { return 1 }
+func (fakeRaftStatus) CommittedIndex() uint64 { return 0 }
+func (fakeRaftStatus) AppliedIndex() uint64   { return 0 }
+func (fakeRaftStatus) Term() uint64           { return 1 }
+
+func TestMaintenanceMemberSelf(t *testing.T) {
+	peerURLs := types.MustNewURLs([]string{"http://127.0.0.1:2380"})
+	self := membership.NewMember("m1", peerURLs, "test", nil)
+	self.Name = ""
+	other := membership.NewMember("m2", types.MustNewURLs([]string{"http://127.0.0.1:2381"}), "test", nil)
+	other.Name = "m2"
+	other.ClientURLs = []string{"http://127.0.0.1:2379"}
+
+	newServer := func(membs ...*membership.Member) *maintenanceServer {
+		return &maintenanceServer{
+			hdr:     header{clusterID: 100, memberID: int64(self.ID), sg: fakeRaftStatus{}, rev: func() int64 { return 1 }, compactRev: func() int64 { return 0 }},
+			cluster: membership.NewClusterFromMembers(zaptest.NewLogger(t), 100, membs),
+			self: &pb.Member{
+				ID:         uint64(self.ID),
+				Name:       "m1",
+				PeerURLs:   peerURLs.StringSlice(),
+				ClientURLs: []string{"http://127.0.0.1:2379"},
+			},
+		}
+	}
+
+	// the member is in the membership, but did not publish its attributes yet
+	resp, err := newServer(self, other).MemberSelf(context.Background(), &pb.MemberSelfRequest{})
+	require.NoError(t, err)
+	assert.True(t, resp.Joined)
+	assert.Equal(t, uint64(100), resp.ClusterId)
+	assert.Equal(t, uint64(self.ID), resp.Member.ID)
+	assert.Equal(t, "m1", resp.Member.Name)
+	assert.Equal(t, peerURLs.StringSlice(), resp.Member.PeerURLs)
+	assert.Equal(t, []string{"http://127.0.0.1:2379"}, resp.Member.ClientURLs)
+
+	// the member has not joined the cluster yet
+	resp, err = newServer(other).MemberSelf(context.Background(), &pb.MemberSelfRequest{})
+	require.NoError(t, err)
+	assert.False(t, resp.Joined)
+	assert.Equal(t, uint64(100), resp.ClusterId)
+	assert.Equal(t, uint64(self.ID), resp.Member.ID)
+	assert.Equal(t, "m1", resp.Member.Name)
+	assert.Equal(t, peerURLs.StringSlice(), resp.Member.PeerURLs)
+}
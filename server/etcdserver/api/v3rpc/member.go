@@ -48,6 +48,7 @@ func (cs *ClusterServer) MemberAdd(ctx context.Context, r *pb.MemberAddRequest)
 	var m *membership.Member
 	if r.IsLearner {
 		m = membership.NewMemberAsLearner("", urls, "", &now)
+		m.NonPromotable = r.NonPromotable
 	} else {
 		m = membership.NewMember("", urls, "", &now)
 	}
@@ -59,16 +60,26 @@ func (cs *ClusterServer) MemberAdd(ctx context.Context, r *pb.MemberAddRequest)
 	return &pb.MemberAddResponse{
 		Header: cs.header(),
 		Member: &pb.Member{
-			ID:        uint64(m.ID),
-			PeerURLs:  m.PeerURLs,
-			IsLearner: m.IsLearner,
+			ID:            uint64(m.ID),
+			PeerURLs:      m.PeerURLs,
+			IsLearner:     m.IsLearner,
+			NonPromotable: m.NonPromotable,
 		},
 		Members: membersToProtoMembers(membs),
 	}, nil
 }
 
 func (cs *ClusterServer) MemberRemove(ctx context.Context, r *pb.MemberRemoveRequest) (*pb.MemberRemoveResponse, error) {
-	membs, err := cs.server.RemoveMember(ctx, r.ID)
+	var (
+		membs []*membership.Member
+		err   error
+	)
+	opts := etcdserver.RemoveMemberOptions{LeadershipTransfer: r.LeadershipTransfer, Force: r.Force}
+	if r.ID == 0 && r.Name != "" {
+		membs, err = cs.server.RemoveMemberByName(ctx, r.Name, opts)
+	} else {
+		membs, err = cs.server.RemoveMemberWithOptions(ctx, r.ID, opts)
+	}
 	if err != nil {
 		return nil, togRPCError(err)
 	}
@@ -105,6 +116,37 @@ func (cs *ClusterServer) MemberPromote(ctx context.Context, r *pb.MemberPromoteR
 	return &pb.MemberPromoteResponse{Header: cs.header(), Members: membersToProtoMembers(membs)}, nil
 }
 
+func (cs *ClusterServer) MemberReconfigure(ctx context.Context, r *pb.MemberReconfigureRequest) (*pb.MemberReconfigureResponse, error) {
+	now := time.Now()
+	adds := make([]membership.Member, len(r.Add))
+	added := make([]*pb.Member, len(r.Add))
+	for i, a := range r.Add {
+		urls, err := types.NewURLs(a.PeerURLs)
+		if err != nil {
+			return nil, rpctypes.ErrGRPCMemberBadURLs
+		}
+		var m *membership.Member
+		if a.IsLearner {
+			m = membership.NewMemberAsLearner("", urls, "", &now)
+			m.NonPromotable = a.NonPromotable
+		} else {
+			m = membership.NewMember("", urls, "", &now)
+		}
+		adds[i] = *m
+		added[i] = &pb.Member{
+			ID:            uint64(m.ID),
+			PeerURLs:      m.PeerURLs,
+			IsLearner:     m.IsLearner,
+			NonPromotable: m.NonPromotable,
+		}
+	}
+	membs, err := cs.server.ReconfigureMembers(ctx, adds, r.Remove)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	return &pb.MemberReconfigureResponse{Header: cs.header(), Added: added, Members: membersToProtoMembers(membs)}, nil
+}
+
 func (cs *ClusterServer) header() *pb.ResponseHeader {
 	return &pb.ResponseHeader{ClusterId: uint64(cs.cluster.ID()), MemberId: uint64(cs.server.MemberId()), RaftTerm: cs.server.Term()}
 }
@@ -113,11 +155,12 @@ func membersToProtoMembers(membs []*membership.Member) []*pb.Member {
 	protoMembs := make([]*pb.Member, len(membs))
 	for i := range membs {
 		protoMembs[i] = &pb.Member{
-			Name:       membs[i].Name,
-			ID:         uint64(membs[i].ID),
-			PeerURLs:   membs[i].PeerURLs,
-			ClientURLs: membs[i].ClientURLs,
-			IsLearner:  membs[i].IsLearner,
+			Name:          membs[i].Name,
+			ID:            uint64(membs[i].ID),
+			PeerURLs:      membs[i].PeerURLs,
+			ClientURLs:    membs[i].ClientURLs,
+			IsLearner:     membs[i].IsLearner,
+			NonPromotable: membs[i].NonPromotable,
 		}
 	}
 	return protoMembs
@@ -83,6 +83,20 @@ func (s *quotaLeaseServer) LeaseGrant(ctx context.Context, cr *pb.LeaseGrantRequ
 	return s.LeaseServer.LeaseGrant(ctx, cr)
 }
 
+func (s *quotaLeaseServer) LeaseGrantBatch(ctx context.Context, cr *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	if err := s.qa.check(ctx, cr); err != nil {
+		return nil, err
+	}
+	return s.LeaseServer.LeaseGrantBatch(ctx, cr)
+}
+
+func (s *quotaLeaseServer) LeaseReattach(ctx context.Context, rr *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	if err := s.qa.check(ctx, rr); err != nil {
+		return nil, err
+	}
+	return s.LeaseServer.LeaseReattach(ctx, rr)
+}
+
 func NewQuotaLeaseServer(s *etcdserver.EtcdServer) pb.LeaseServer {
 	return &quotaLeaseServer{
 		NewLeaseServer(s),
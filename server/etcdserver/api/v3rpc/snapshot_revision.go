@@ -0,0 +1,89 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/mvcc"
+)
+
+// fileSnapshot is a backend snapshot written to a file, which is removed when
+// the snapshot is closed.
+type fileSnapshot struct {
+	f    *os.File
+	size int64
+}
+
+func (s *fileSnapshot) Size() int64 { return s.size }
+
+func (s *fileSnapshot) WriteTo(w io.Writer) (int64, error) {
+	// downloads of the same snapshot read the file concurrently
+	return io.Copy(w, io.NewSectionReader(s.f, 0, s.size))
+}
+
+func (s *fileSnapshot) Close() error {
+	err := s.f.Close()
+	if rerr := os.Remove(s.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// newRevisionSnapshot writes a snapshot of the backend to a file in dir, and
+// trims it to the key-value store as of rev.
+func newRevisionSnapshot(lg *zap.Logger, dir string, bg BackendGetter, rev int64) (backend.Snapshot, error) {
+	f, err := os.CreateTemp(dir, "snapshot-*.db.tmp")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	snap := bg.Backend().Snapshot()
+	_, err = snap.WriteTo(f)
+	snap.Close()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	be := backend.NewDefaultBackend(lg, path)
+	err = mvcc.TrimAfterRevision(lg, be, rev)
+	if cerr := be.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	if f, err = os.Open(path); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return &fileSnapshot{f: f, size: fi.Size()}, nil
+}
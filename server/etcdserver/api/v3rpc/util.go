@@ -34,18 +34,27 @@ import (
 var toGRPCErrorMap = map[error]error{
 	membership.ErrIDRemoved:           rpctypes.ErrGRPCMemberNotFound,
 	membership.ErrIDNotFound:          rpctypes.ErrGRPCMemberNotFound,
+	membership.ErrNameNotFound:        rpctypes.ErrGRPCMemberNameNotFound,
+	membership.ErrNameAmbiguous:       rpctypes.ErrGRPCMemberNameAmbiguous,
 	membership.ErrIDExists:            rpctypes.ErrGRPCMemberExist,
 	membership.ErrPeerURLexists:       rpctypes.ErrGRPCPeerURLExist,
 	membership.ErrMemberNotLearner:    rpctypes.ErrGRPCMemberNotLearner,
+	membership.ErrMemberNotPromotable: rpctypes.ErrGRPCMemberNotPromotable,
 	membership.ErrTooManyLearners:     rpctypes.ErrGRPCTooManyLearners,
-	errors.ErrNotEnoughStartedMembers: rpctypes.ErrMemberNotEnoughStarted,
+	membership.ErrNoChange:            rpctypes.ErrGRPCMemberNoChange,
+	errors.ErrNotEnoughStartedMembers: rpctypes.ErrGRPCMemberNotEnoughStarted,
 	errors.ErrLearnerNotReady:         rpctypes.ErrGRPCLearnerNotReady,
 
 	mvcc.ErrCompacted:         rpctypes.ErrGRPCCompacted,
 	mvcc.ErrFutureRev:         rpctypes.ErrGRPCFutureRev,
+	mvcc.ErrInvalidKeep:       rpctypes.ErrGRPCInvalidKeep,
 	errors.ErrRequestTooLarge: rpctypes.ErrGRPCRequestTooLarge,
 	errors.ErrNoSpace:         rpctypes.ErrGRPCNoSpace,
-	errors.ErrTooManyRequests: rpctypes.ErrTooManyRequests,
+	errors.ErrTooManyRequests: rpctypes.ErrGRPCRequestTooManyRequests,
+	errors.ErrUserRateLimited: rpctypes.ErrGRPCUserRateLimited,
+
+	mvcc.ErrHotKeyTrackingDisabled: rpctypes.ErrGRPCHotKeyTrackingDisabled,
+	mvcc.ErrTooManyWatchStreams:    rpctypes.ErrGRPCTooManyWatchStreams,
 
 	errors.ErrNoLeader:                   rpctypes.ErrGRPCNoLeader,
 	errors.ErrNotLeader:                  rpctypes.ErrGRPCNotLeader,
@@ -55,10 +64,14 @@ var toGRPCErrorMap = map[error]error{
 	errors.ErrTimeoutDueToLeaderFail:     rpctypes.ErrGRPCTimeoutDueToLeaderFail,
 	errors.ErrTimeoutDueToConnectionLost: rpctypes.ErrGRPCTimeoutDueToConnectionLost,
 	errors.ErrTimeoutWaitAppliedIndex:    rpctypes.ErrGRPCTimeoutWaitAppliedIndex,
+	errors.ErrDeadlineExceeded:           rpctypes.ErrGRPCDeadlineExceeded,
 	errors.ErrUnhealthy:                  rpctypes.ErrGRPCUnhealthy,
 	errors.ErrKeyNotFound:                rpctypes.ErrGRPCKeyNotFound,
 	errors.ErrCorrupt:                    rpctypes.ErrGRPCCorrupt,
 	errors.ErrBadLeaderTransferee:        rpctypes.ErrGRPCBadLeaderTransferee,
+	errors.ErrRebuildIndexOnLeader:       rpctypes.ErrGRPCRebuildIndexOnLeader,
+	errors.ErrRebuildingIndex:            rpctypes.ErrGRPCRebuildingIndex,
+	errors.ErrInvalidPrefixQuota:         rpctypes.ErrGRPCInvalidPrefixQuota,
 
 	errors.ErrClusterVersionUnavailable:      rpctypes.ErrGRPCClusterVersionUnavailable,
 	errors.ErrWrongDowngradeVersionFormat:    rpctypes.ErrGRPCWrongDowngradeVersionFormat,
@@ -69,6 +82,7 @@ var toGRPCErrorMap = map[error]error{
 	lease.ErrLeaseNotFound:    rpctypes.ErrGRPCLeaseNotFound,
 	lease.ErrLeaseExists:      rpctypes.ErrGRPCLeaseExist,
 	lease.ErrLeaseTTLTooLarge: rpctypes.ErrGRPCLeaseTTLTooLarge,
+	lease.ErrLeaseTTLTooShort: rpctypes.ErrGRPCLeaseTTLTooShort,
 
 	auth.ErrRootUserNotExist:     rpctypes.ErrGRPCRootUserNotExist,
 	auth.ErrRootRoleNotExist:     rpctypes.ErrGRPCRootRoleNotExist,
@@ -98,6 +112,9 @@ func togRPCError(err error) error {
 	if err == context.Canceled || err == context.DeadlineExceeded {
 		return err
 	}
+	if qerr, ok := err.(*mvcc.QuotaExceededError); ok {
+		return rpctypes.ErrGRPCQuotaExceededForPrefix(qerr.Prefix)
+	}
 	grpcErr, ok := toGRPCErrorMap[err]
 	if !ok {
 		return status.Error(codes.Unknown, err.Error())
@@ -138,9 +155,10 @@ func isClientCtxErr(ctxErr error, err error) bool {
 }
 
 // in v3.4, learner is allowed to serve serializable read and endpoint status
+// in v3.6, learner is also allowed to serve compaction status and hot keys
 func isRPCSupportedForLearner(req interface{}) bool {
 	switch r := req.(type) {
-	case *pb.StatusRequest:
+	case *pb.StatusRequest, *pb.CompactionStatusRequest, *pb.HotKeysRequest:
 		return true
 	case *pb.RangeRequest:
 		return r.Serializable
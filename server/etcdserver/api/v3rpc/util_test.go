@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcderrors "go.etcd.io/etcd/server/v3/etcdserver/errors"
 	"go.etcd.io/etcd/server/v3/storage/mvcc"
 
 	"google.golang.org/grpc/codes"
@@ -48,3 +49,41 @@ func TestGRPCError(t *testing.T) {
 		}
 	}
 }
+
+func TestGRPCErrorReconfig(t *testing.T) {
+	tt := []struct {
+		err       error
+		expGRPC   error
+		expClient error
+		expCode   codes.Code
+	}{
+		{
+			err:       etcderrors.ErrNoLeader,
+			expGRPC:   rpctypes.ErrGRPCNoLeader,
+			expClient: rpctypes.ErrNoLeader,
+			expCode:   codes.Unavailable,
+		},
+		{
+			err:       etcderrors.ErrNotEnoughStartedMembers,
+			expGRPC:   rpctypes.ErrGRPCMemberNotEnoughStarted,
+			expClient: rpctypes.ErrMemberNotEnoughStarted,
+			expCode:   codes.FailedPrecondition,
+		},
+	}
+	for i, tc := range tt {
+		err := togRPCError(tc.err)
+		if !errors.Is(err, tc.expGRPC) {
+			t.Errorf("#%d: expected errors.Is(%v, %v)", i, err, tc.expGRPC)
+		}
+		if code := status.Code(err); code != tc.expCode {
+			t.Errorf("#%d: code = %v, expected %v", i, code, tc.expCode)
+		}
+		cerr := rpctypes.Error(err)
+		if !errors.Is(cerr, tc.expClient) {
+			t.Errorf("#%d: expected errors.Is(%v, %v)", i, cerr, tc.expClient)
+		}
+	}
+	if errors.Is(rpctypes.Error(togRPCError(etcderrors.ErrNoLeader)), rpctypes.ErrMemberNotEnoughStarted) {
+		t.Errorf("expected no leader error to be distinct from not enough started members error")
+	}
+}
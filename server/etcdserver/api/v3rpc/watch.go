@@ -15,6 +15,7 @@
 package v3rpc
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"math/rand"
@@ -135,15 +136,19 @@ type serverWatchStream struct {
 	watchStream mvcc.WatchStream
 	ctrlStream  chan *pb.WatchResponse
 
-	// mu protects progress, prevKV, fragment
+	// mu protects progress, prevKV, fragment, coalesce
 	mu sync.RWMutex
 	// tracks the watchID that stream might need to send progress to
 	// TODO: combine progress and prevKV into a single struct?
 	progress map[mvcc.WatchID]bool
 	// record watch IDs that need return previous key-value pair
 	prevKV map[mvcc.WatchID]bool
+	// records watch IDs that only get the put events changing a value
+	valueChanged map[mvcc.WatchID]bool
 	// records fragmented watch IDs
 	fragment map[mvcc.WatchID]bool
+	// records the coalesce windows of watch IDs
+	coalesce map[mvcc.WatchID]time.Duration
 
 	// indicates whether we have an outstanding global progress
 	// notification to send
@@ -157,6 +162,11 @@ type serverWatchStream struct {
 }
 
 func (ws *watchServer) Watch(stream pb.Watch_WatchServer) (err error) {
+	watchStream, err := ws.newWatchStream(stream.Context())
+	if err != nil {
+		ws.lg.Warn("rejected watch stream", zap.Error(err))
+		return togRPCError(err)
+	}
 	sws := serverWatchStream{
 		lg: ws.lg,
 
@@ -170,13 +180,15 @@ func (ws *watchServer) Watch(stream pb.Watch_WatchServer) (err error) {
 		ag:        ws.ag,
 
 		gRPCStream:  stream,
-		watchStream: ws.watchable.NewWatchStream(),
+		watchStream: watchStream,
 		// chan for sending control response like watcher created and canceled.
 		ctrlStream: make(chan *pb.WatchResponse, ctrlStreamBufLen),
 
-		progress: make(map[mvcc.WatchID]bool),
-		prevKV:   make(map[mvcc.WatchID]bool),
-		fragment: make(map[mvcc.WatchID]bool),
+		progress:     make(map[mvcc.WatchID]bool),
+		prevKV:       make(map[mvcc.WatchID]bool),
+		valueChanged: make(map[mvcc.WatchID]bool),
+		fragment:     make(map[mvcc.WatchID]bool),
+		coalesce:     make(map[mvcc.WatchID]time.Duration),
 
 		deferredProgress: false,
 
@@ -232,6 +244,89 @@ func (ws *watchServer) Watch(stream pb.Watch_WatchServer) (err error) {
 	return err
 }
 
+// replayBatchRevisions is the number of revisions read from the backend at a
+// time by Replay.
+const replayBatchRevisions = 1000
+
+func (ws *watchServer) Replay(r *pb.ReplayRequest, stream pb.Watch_ReplayServer) error {
+	key, end := normalizeWatchRange(r.Key, r.RangeEnd)
+	if err := ws.isReplayPermitted(stream.Context(), key, end); err != nil {
+		return togRPCError(err)
+	}
+
+	curRev := ws.watchable.Rev()
+	startRev, endRev := r.StartRevision, r.EndRevision
+	if startRev <= 0 {
+		startRev = 1
+	}
+	if endRev <= 0 || endRev > curRev {
+		endRev = curRev
+	}
+	header := func() *pb.ResponseHeader {
+		return &pb.ResponseHeader{
+			ClusterId:       uint64(ws.clusterID),
+			MemberId:        uint64(ws.memberID),
+			Revision:        curRev,
+			RaftTerm:        ws.sg.Term(),
+			CompactRevision: ws.watchable.ReadFloorRev(),
+		}
+	}
+
+	// the history is read in batches of revisions so that it is never held in
+	// memory all at once; a batch fails if it was compacted in the meantime.
+	// The first batch is always read so that a compacted startRev fails even
+	// if there is nothing to replay.
+	sent := false
+	for rev := startRev; rev <= endRev || rev == startRev; rev += replayBatchRevisions {
+		batchEnd := rev + replayBatchRevisions
+		if batchEnd > endRev+1 {
+			batchEnd = endRev + 1
+		}
+		evs, err := ws.watchable.Replay(key, end, rev, batchEnd)
+		if err != nil {
+			return togRPCError(err)
+		}
+		if len(evs) == 0 {
+			continue
+		}
+		resp := &pb.ReplayResponse{Header: header(), Events: make([]*mvccpb.Event, len(evs))}
+		for i := range evs {
+			resp.Events[i] = &evs[i]
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+		sent = true
+	}
+	if !sent {
+		// tell the client the revision replayed up to
+		return stream.Send(&pb.ReplayResponse{Header: header()})
+	}
+	return nil
+}
+
+func (ws *watchServer) isReplayPermitted(ctx context.Context, key, end []byte) error {
+	authInfo, err := ws.ag.AuthInfoFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+	if authInfo == nil {
+		// if auth is enabled, IsRangePermitted() can cause an error
+		authInfo = &auth.AuthInfo{}
+	}
+	return ws.ag.AuthStore().IsRangePermitted(authInfo, key, end)
+}
+
+// newWatchStream creates the watch stream of a gRPC stream, counting it
+// against the watch streams of its client connection if the connection can
+// be told apart from the others.
+func (ws *watchServer) newWatchStream(ctx context.Context) (mvcc.WatchStream, error) {
+	if conn, ok := connID(ctx); ok {
+		return ws.watchable.NewConnWatchStream(conn)
+	}
+	return ws.watchable.NewWatchStream(), nil
+}
+
 func (sws *serverWatchStream) isWatchPermitted(wcr *pb.WatchCreateRequest) error {
 	authInfo, err := sws.ag.AuthInfoFromCtx(sws.gRPCStream.Context())
 	if err != nil {
@@ -241,7 +336,34 @@ func (sws *serverWatchStream) isWatchPermitted(wcr *pb.WatchCreateRequest) error
 		// if auth is enabled, IsRangePermitted() can cause an error
 		authInfo = &auth.AuthInfo{}
 	}
-	return sws.ag.AuthStore().IsRangePermitted(authInfo, wcr.Key, wcr.RangeEnd)
+	if err = sws.ag.AuthStore().IsRangePermitted(authInfo, wcr.Key, wcr.RangeEnd); err != nil {
+		return err
+	}
+	for _, r := range wcr.Ranges {
+		if err = sws.ag.AuthStore().IsRangePermitted(authInfo, r.Key, r.RangeEnd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeWatchRange returns the range of a watch create request in the form
+// expected by mvcc.WatchStream.
+func normalizeWatchRange(key, end []byte) ([]byte, []byte) {
+	if len(key) == 0 {
+		// \x00 is the smallest key
+		key = []byte{0}
+	}
+	if len(end) == 0 {
+		// force nil since watchstream.Watch distinguishes
+		// between nil and []byte{} for single key / >=
+		end = nil
+	}
+	if len(end) == 1 && end[0] == 0 {
+		// support  >= key queries
+		end = []byte{}
+	}
+	return key, end
 }
 
 func (sws *serverWatchStream) recvLoop() error {
@@ -261,18 +383,9 @@ func (sws *serverWatchStream) recvLoop() error {
 			}
 
 			creq := uv.CreateRequest
-			if len(creq.Key) == 0 {
-				// \x00 is the smallest key
-				creq.Key = []byte{0}
-			}
-			if len(creq.RangeEnd) == 0 {
-				// force nil since watchstream.Watch distinguishes
-				// between nil and []byte{} for single key / >=
-				creq.RangeEnd = nil
-			}
-			if len(creq.RangeEnd) == 1 && creq.RangeEnd[0] == 0 {
-				// support  >= key queries
-				creq.RangeEnd = []byte{}
+			creq.Key, creq.RangeEnd = normalizeWatchRange(creq.Key, creq.RangeEnd)
+			for _, r := range creq.Ranges {
+				r.Key, r.RangeEnd = normalizeWatchRange(r.Key, r.RangeEnd)
 			}
 
 			err := sws.isWatchPermitted(creq)
@@ -315,7 +428,11 @@ func (sws *serverWatchStream) recvLoop() error {
 			if rev == 0 {
 				rev = wsrev + 1
 			}
-			id, err := sws.watchStream.Watch(mvcc.WatchID(creq.WatchId), creq.Key, creq.RangeEnd, rev, filters...)
+			var ranges []mvcc.WatchRange
+			for _, r := range creq.Ranges {
+				ranges = append(ranges, mvcc.WatchRange{Key: r.Key, End: r.RangeEnd, Filters: filtersFromTypes(r.Filters)})
+			}
+			id, err := sws.watchStream.WatchRanges(mvcc.WatchID(creq.WatchId), creq.Key, creq.RangeEnd, ranges, rev, creq.CaughtUpNotify, filters...)
 			if err == nil {
 				sws.mu.Lock()
 				if creq.ProgressNotify {
@@ -324,9 +441,15 @@ func (sws *serverWatchStream) recvLoop() error {
 				if creq.PrevKv {
 					sws.prevKV[id] = true
 				}
+				if creq.ValueFilter == pb.WatchCreateRequest_VALUE_CHANGED {
+					sws.valueChanged[id] = true
+				}
 				if creq.Fragment {
 					sws.fragment[id] = true
 				}
+				if creq.CoalesceWindowMs > 0 {
+					sws.coalesce[id] = coalesceWindow(creq.CoalesceWindowMs)
+				}
 				sws.mu.Unlock()
 			} else {
 				id = clientv3.InvalidWatchID
@@ -360,7 +483,9 @@ func (sws *serverWatchStream) recvLoop() error {
 					sws.mu.Lock()
 					delete(sws.progress, mvcc.WatchID(id))
 					delete(sws.prevKV, mvcc.WatchID(id))
+					delete(sws.valueChanged, mvcc.WatchID(id))
 					delete(sws.fragment, mvcc.WatchID(id))
+					delete(sws.coalesce, mvcc.WatchID(id))
 					sws.mu.Unlock()
 				}
 			}
@@ -393,6 +518,69 @@ func (sws *serverWatchStream) sendLoop() {
 	// watch responses pending on a watch id creation message
 	pending := make(map[mvcc.WatchID][]*pb.WatchResponse)
 
+	// events held for the watch ids created with a coalesce window
+	coalescers := make(map[mvcc.WatchID]*watchCoalescer)
+	// coalesceC fires at nextFlush, the end of the earliest coalesce window
+	var coalesceC <-chan time.Time
+	var nextFlush time.Time
+	scheduleFlush := func(deadline time.Time) {
+		if coalesceC == nil || deadline.Before(nextFlush) {
+			nextFlush = deadline
+			coalesceC = time.After(time.Until(deadline))
+		}
+	}
+
+	send := func(wr *pb.WatchResponse) bool {
+		wid := mvcc.WatchID(wr.WatchId)
+		sws.mu.RLock()
+		fragmented, ok := sws.fragment[wid]
+		sws.mu.RUnlock()
+
+		var serr error
+		if !fragmented && !ok {
+			serr = sws.gRPCStream.Send(wr)
+		} else {
+			serr = sendFragments(wr, sws.maxRequestBytes, sws.gRPCStream.Send)
+		}
+
+		if serr != nil {
+			if isClientCtxErr(sws.gRPCStream.Context().Err(), serr) {
+				sws.lg.Debug("failed to send watch response to gRPC stream", zap.Error(serr))
+			} else {
+				sws.lg.Warn("failed to send watch response to gRPC stream", zap.Error(serr))
+				streamFailures.WithLabelValues("send", "watch").Inc()
+			}
+			return false
+		}
+
+		sws.mu.Lock()
+		if len(wr.Events) > 0 && sws.progress[wid] {
+			// elide next progress update if sent a key update
+			sws.progress[wid] = false
+		}
+		if sws.deferredProgress {
+			if sws.watchStream.RequestProgressAll() {
+				sws.deferredProgress = false
+			}
+		}
+		sws.mu.Unlock()
+		return true
+	}
+
+	// flush sends the events held for the watch id, or for every watch id if
+	// it is clientv3.InvalidWatchID, so that no other response overtakes them.
+	flush := func(wid mvcc.WatchID) bool {
+		for id, c := range coalescers {
+			if wid != clientv3.InvalidWatchID && id != wid {
+				continue
+			}
+			if wr := c.flush(); wr != nil && !send(wr) {
+				return false
+			}
+		}
+		return true
+	}
+
 	interval := GetProgressReportInterval()
 	progressTicker := time.NewTicker(interval)
 
@@ -423,10 +611,11 @@ func (sws *serverWatchStream) sendLoop() {
 			events := make([]*mvccpb.Event, len(evs))
 			sws.mu.RLock()
 			needPrevKV := sws.prevKV[wresp.WatchID]
+			valueChanged := sws.valueChanged[wresp.WatchID]
 			sws.mu.RUnlock()
 			for i := range evs {
 				events[i] = &evs[i]
-				if needPrevKV && !IsCreateEvent(evs[i]) {
+				if (needPrevKV || valueChanged) && !IsCreateEvent(evs[i]) {
 					opt := mvcc.RangeOptions{Rev: evs[i].Kv.ModRevision - 1}
 					r, err := sws.watchable.Range(context.TODO(), evs[i].Kv.Key, nil, opt)
 					if err == nil && len(r.KVs) != 0 {
@@ -434,6 +623,14 @@ func (sws *serverWatchStream) sendLoop() {
 					}
 				}
 			}
+			if valueChanged {
+				// the events of the store carry no previous key-value, so the
+				// filter is only evaluated once they are looked up
+				events = filterValueUnchangedEvents(events, needPrevKV)
+				if len(events) == 0 && len(evs) != 0 && wresp.CompactRevision == 0 && !wresp.CaughtUp {
+					continue
+				}
+			}
 
 			canceled := wresp.CompactRevision != 0
 			wr := &pb.WatchResponse{
@@ -442,6 +639,7 @@ func (sws *serverWatchStream) sendLoop() {
 				Events:          events,
 				CompactRevision: wresp.CompactRevision,
 				Canceled:        canceled,
+				CaughtUp:        wresp.CaughtUp,
 			}
 
 			// Progress notifications can have WatchID -1
@@ -457,38 +655,32 @@ func (sws *serverWatchStream) sendLoop() {
 
 			mvcc.ReportEventReceived(len(evs))
 
-			sws.mu.RLock()
-			fragmented, ok := sws.fragment[wresp.WatchID]
-			sws.mu.RUnlock()
-
-			var serr error
-			if !fragmented && !ok {
-				serr = sws.gRPCStream.Send(wr)
-			} else {
-				serr = sendFragments(wr, sws.maxRequestBytes, sws.gRPCStream.Send)
-			}
-
-			if serr != nil {
-				if isClientCtxErr(sws.gRPCStream.Context().Err(), serr) {
-					sws.lg.Debug("failed to send watch response to gRPC stream", zap.Error(serr))
-				} else {
-					sws.lg.Warn("failed to send watch response to gRPC stream", zap.Error(serr))
-					streamFailures.WithLabelValues("send", "watch").Inc()
+			if len(evs) > 0 && !canceled {
+				sws.mu.RLock()
+				window := sws.coalesce[wresp.WatchID]
+				sws.mu.RUnlock()
+				if window > 0 {
+					c, ok := coalescers[wresp.WatchID]
+					if !ok {
+						c = newWatchCoalescer(window)
+						coalescers[wresp.WatchID] = c
+					}
+					if c.add(wr, time.Now()) {
+						scheduleFlush(c.deadline)
+					}
+					if c.full() && !send(c.flush()) {
+						return
+					}
+					continue
 				}
-				return
 			}
 
-			sws.mu.Lock()
-			if len(evs) > 0 && sws.progress[wresp.WatchID] {
-				// elide next progress update if sent a key update
-				sws.progress[wresp.WatchID] = false
+			if !flush(wresp.WatchID) || !send(wr) {
+				return
 			}
-			if sws.deferredProgress {
-				if sws.watchStream.RequestProgressAll() {
-					sws.deferredProgress = false
-				}
+			if canceled {
+				delete(coalescers, wresp.WatchID)
 			}
-			sws.mu.Unlock()
 
 		case c, ok := <-sws.ctrlStream:
 			if !ok {
@@ -512,6 +704,7 @@ func (sws *serverWatchStream) sendLoop() {
 
 			if c.Canceled && wid != clientv3.InvalidWatchID {
 				delete(ids, wid)
+				delete(coalescers, wid)
 				continue
 			}
 			if c.Created {
@@ -532,6 +725,21 @@ func (sws *serverWatchStream) sendLoop() {
 				delete(pending, wid)
 			}
 
+		case now := <-coalesceC:
+			coalesceC = nil
+			for _, c := range coalescers {
+				if c.wr == nil {
+					continue
+				}
+				if c.deadline.After(now) {
+					scheduleFlush(c.deadline)
+					continue
+				}
+				if !send(c.flush()) {
+					return
+				}
+			}
+
 		case <-progressTicker.C:
 			sws.mu.Lock()
 			for id, ok := range sws.progress {
@@ -599,10 +807,11 @@ func (sws *serverWatchStream) close() {
 
 func (sws *serverWatchStream) newResponseHeader(rev int64) *pb.ResponseHeader {
 	return &pb.ResponseHeader{
-		ClusterId: uint64(sws.clusterID),
-		MemberId:  uint64(sws.memberID),
-		Revision:  rev,
-		RaftTerm:  sws.sg.Term(),
+		ClusterId:       uint64(sws.clusterID),
+		MemberId:        uint64(sws.memberID),
+		Revision:        rev,
+		RaftTerm:        sws.sg.Term(),
+		CompactRevision: sws.watchable.ReadFloorRev(),
 	}
 }
 
@@ -614,10 +823,56 @@ func filterNoPut(e mvccpb.Event) bool {
 	return e.Type == mvccpb.PUT
 }
 
+func filterNotCreated(e mvccpb.Event) bool {
+	return e.Type == mvccpb.PUT && e.Kv.Version != 1
+}
+
+func filterValueNotPrefixed(prefix []byte) mvcc.FilterFunc {
+	return func(e mvccpb.Event) bool {
+		return e.Type == mvccpb.PUT && !bytes.HasPrefix(e.Kv.Value, prefix)
+	}
+}
+
+// filterValueUnchanged filters out the put events rewriting the value of their
+// previous key-value. Events without a previous key-value are kept.
+func filterValueUnchanged(e mvccpb.Event) bool {
+	return e.Type == mvccpb.PUT && e.PrevKv != nil && bytes.Equal(e.Kv.Value, e.PrevKv.Value)
+}
+
+// filterValueUnchangedEvents drops the events filtered by filterValueUnchanged,
+// and strips the previous key-values of the others unless keepPrevKV is set.
+func filterValueUnchangedEvents(events []*mvccpb.Event, keepPrevKV bool) []*mvccpb.Event {
+	kept := events[:0]
+	for _, ev := range events {
+		if filterValueUnchanged(*ev) {
+			continue
+		}
+		if !keepPrevKV {
+			ev.PrevKv = nil
+		}
+		kept = append(kept, ev)
+	}
+	return kept
+}
+
 // FiltersFromRequest returns "mvcc.FilterFunc" from a given watch create request.
 func FiltersFromRequest(creq *pb.WatchCreateRequest) []mvcc.FilterFunc {
-	filters := make([]mvcc.FilterFunc, 0, len(creq.Filters))
-	for _, ft := range creq.Filters {
+	filters := filtersFromTypes(creq.Filters)
+	switch creq.ValueFilter {
+	case pb.WatchCreateRequest_CREATE_ONLY:
+		filters = append(filters, filterNotCreated)
+	case pb.WatchCreateRequest_VALUE_PREFIX:
+		filters = append(filters, filterValueNotPrefixed(creq.ValuePrefix))
+	case pb.WatchCreateRequest_VALUE_CHANGED:
+		filters = append(filters, filterValueUnchanged)
+	default:
+	}
+	return filters
+}
+
+func filtersFromTypes(fts []pb.WatchCreateRequest_FilterType) []mvcc.FilterFunc {
+	filters := make([]mvcc.FilterFunc, 0, len(fts)+1)
+	for _, ft := range fts {
 		switch ft {
 		case pb.WatchCreateRequest_NOPUT:
 			filters = append(filters, filterNoPut)
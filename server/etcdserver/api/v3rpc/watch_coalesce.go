@@ -0,0 +1,111 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+const (
+	// maxCoalesceWindow is the longest coalesce window a watcher gets. Longer
+	// requested windows are clamped to it.
+	maxCoalesceWindow = 10 * time.Second
+	// maxCoalescedEvents is the number of events a coalescer holds before its
+	// window is flushed early, superseded puts included.
+	maxCoalescedEvents = 1000
+)
+
+// coalesceWindow returns the coalesce window of a watcher requesting ms
+// milliseconds, clamped to maxCoalesceWindow.
+func coalesceWindow(ms int64) time.Duration {
+	if ms > int64(maxCoalesceWindow/time.Millisecond) {
+		return maxCoalesceWindow
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// watchCoalescer holds the events of a watcher created with a coalesce window
+// until the window ends, keeping only the latest put of each key since its
+// last delete. Delete events are always kept.
+type watchCoalescer struct {
+	window time.Duration
+
+	// wr holds the events of the current window, nil if no window is open
+	wr *pb.WatchResponse
+	// deadline is the end of the current window
+	deadline time.Time
+	// puts maps a key to the index in wr.Events of its put held since its
+	// last delete
+	puts map[string]int
+}
+
+func newWatchCoalescer(window time.Duration) *watchCoalescer {
+	return &watchCoalescer{window: window}
+}
+
+// add holds the events of wr, superseding the puts held for the same keys.
+// It returns true if the events open a new window.
+func (c *watchCoalescer) add(wr *pb.WatchResponse, now time.Time) bool {
+	opened := c.wr == nil
+	if opened {
+		c.wr = &pb.WatchResponse{WatchId: wr.WatchId}
+		c.deadline = now.Add(c.window)
+		c.puts = make(map[string]int)
+	}
+	// the response reports the revision of the last events held
+	c.wr.Header = wr.Header
+	for _, ev := range wr.Events {
+		key := string(ev.Kv.Key)
+		if i, ok := c.puts[key]; ok {
+			// the previous key-value of the event is the one before the
+			// superseded put, which the watcher never receives
+			ev.PrevKv = c.wr.Events[i].PrevKv
+			c.wr.Events[i] = nil
+			delete(c.puts, key)
+		}
+		if ev.Type == mvccpb.PUT {
+			c.puts[key] = len(c.wr.Events)
+		}
+		c.wr.Events = append(c.wr.Events, ev)
+	}
+	return opened
+}
+
+// full returns true if the current window holds maxCoalescedEvents events
+// or more, so that it must be flushed before its deadline.
+func (c *watchCoalescer) full() bool {
+	return c.wr != nil && len(c.wr.Events) >= maxCoalescedEvents
+}
+
+// flush closes the current window and returns the response holding its
+// events, or nil if no window is open.
+func (c *watchCoalescer) flush() *pb.WatchResponse {
+	wr := c.wr
+	if wr == nil {
+		return nil
+	}
+	events := wr.Events[:0]
+	for _, ev := range wr.Events {
+		if ev != nil {
+			events = append(events, ev)
+		}
+	}
+	wr.Events = events
+	c.wr, c.puts = nil, nil
+	return wr
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestWatchCoalescer(t *testing.T) {
+	c := newWatchCoalescer(time.Second)
+	assert.Nil(t, c.flush())
+
+	resp := func(rev int64, evs ...*mvccpb.Event) *pb.WatchResponse {
+		return &pb.WatchResponse{Header: &pb.ResponseHeader{Revision: rev}, WatchId: 1, Events: evs}
+	}
+	put := func(key, val string, rev int64, prev string) *mvccpb.Event {
+		ev := &mvccpb.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val), ModRevision: rev}}
+		if prev != "" {
+			ev.PrevKv = &mvccpb.KeyValue{Key: []byte(key), Value: []byte(prev)}
+		}
+		return ev
+	}
+	del := func(key string, rev int64, prev string) *mvccpb.Event {
+		return &mvccpb.Event{
+			Type:   mvccpb.DELETE,
+			Kv:     &mvccpb.KeyValue{Key: []byte(key), ModRevision: rev},
+			PrevKv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(prev)},
+		}
+	}
+
+	now := time.Now()
+	assert.True(t, c.add(resp(2, put("a", "a1", 2, "a0")), now))
+	assert.Equal(t, now.Add(time.Second), c.deadline)
+	assert.False(t, c.add(resp(3, put("a", "a2", 3, "a1")), now.Add(time.Millisecond)))
+	assert.False(t, c.add(resp(5, put("b", "b1", 4, ""), put("a", "a3", 5, "a2")), now))
+	assert.False(t, c.add(resp(6, del("b", 6, "b1")), now))
+	assert.False(t, c.add(resp(8, put("b", "b2", 7, ""), del("a", 8, "a3")), now))
+	assert.False(t, c.add(resp(9, put("a", "a4", 9, "")), now))
+
+	wr := c.flush()
+	var got []string
+	for _, ev := range wr.Events {
+		var prev string
+		if ev.PrevKv != nil {
+			prev = string(ev.PrevKv.Value)
+		}
+		got = append(got, fmt.Sprintf("%s %s=%s@%d prev=%s", ev.Type, ev.Kv.Key, ev.Kv.Value, ev.Kv.ModRevision, prev))
+	}
+	// the deletes are kept and supersede the puts of their key before them
+	assert.Equal(t, []string{
+		"DELETE b=@6 prev=",
+		"PUT b=b2@7 prev=",
+		"DELETE a=@8 prev=a0",
+		"PUT a=a4@9 prev=",
+	}, got)
+	assert.Equal(t, int64(9), wr.Header.Revision)
+	assert.Equal(t, int64(1), wr.WatchId)
+
+	assert.Nil(t, c.flush())
+	assert.True(t, c.add(resp(10, put("a", "a5", 10, "a4")), now))
+}
+
+func TestWatchCoalescerFull(t *testing.T) {
+	c := newWatchCoalescer(time.Second)
+	assert.False(t, c.full())
+
+	now := time.Now()
+	for i := 0; i < maxCoalescedEvents-1; i++ {
+		ev := &mvccpb.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("a"), ModRevision: int64(i + 2)}}
+		c.add(&pb.WatchResponse{Header: &pb.ResponseHeader{Revision: int64(i + 2)}, Events: []*mvccpb.Event{ev}}, now)
+	}
+	// the superseded puts still count until the window is flushed
+	assert.False(t, c.full())
+	ev := &mvccpb.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("a"), ModRevision: maxCoalescedEvents + 1}}
+	c.add(&pb.WatchResponse{Header: &pb.ResponseHeader{Revision: maxCoalescedEvents + 1}, Events: []*mvccpb.Event{ev}}, now)
+	assert.True(t, c.full())
+
+	wr := c.flush()
+	assert.Len(t, wr.Events, 1)
+	assert.False(t, c.full())
+}
+
+func TestCoalesceWindow(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, coalesceWindow(100))
+	assert.Equal(t, maxCoalesceWindow, coalesceWindow(int64(maxCoalesceWindow/time.Millisecond)))
+	assert.Equal(t, maxCoalesceWindow, coalesceWindow(1<<62))
+}
@@ -76,8 +76,12 @@ type applierV3 interface {
 	DeleteRange(ctx context.Context, dr *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, *traceutil.Trace, error)
 	Txn(ctx context.Context, rt *pb.TxnRequest) (*pb.TxnResponse, *traceutil.Trace, error)
 	Compaction(compaction *pb.CompactionRequest) (*pb.CompactionResponse, <-chan struct{}, *traceutil.Trace, error)
+	CompactKey(r *pb.CompactKeyRequest) (*pb.CompactKeyResponse, *traceutil.Trace, error)
+	PrefixQuotaSet(r *pb.PrefixQuotaSetRequest) (*pb.PrefixQuotaSetResponse, error)
 
 	LeaseGrant(lc *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error)
+	LeaseGrantBatch(lc *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error)
+	LeaseReattach(lc *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error)
 	LeaseRevoke(lc *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error)
 
 	LeaseCheckpoint(lc *pb.LeaseCheckpointRequest) (*pb.LeaseCheckpointResponse, error)
@@ -103,6 +107,8 @@ type applierV3 interface {
 	RoleDelete(ua *pb.AuthRoleDeleteRequest) (*pb.AuthRoleDeleteResponse, error)
 	UserList(ua *pb.AuthUserListRequest) (*pb.AuthUserListResponse, error)
 	RoleList(ua *pb.AuthRoleListRequest) (*pb.AuthRoleListResponse, error)
+	AuthExport(ua *pb.AuthExportRequest) (*pb.AuthExportResponse, error)
+	AuthImport(ua *pb.AuthImportRequest) (*pb.AuthImportResponse, error)
 
 	// processing internal V3 raft request
 
@@ -181,7 +187,17 @@ func (a *applierV3backend) Compaction(compaction *pb.CompactionRequest) (*pb.Com
 		traceutil.Field{Key: "revision", Value: compaction.Revision},
 	)
 
-	ch, err := a.kv.Compact(trace, compaction.Revision)
+	var ch <-chan struct{}
+	var err error
+	if len(compaction.RetainedPrefixes) == 0 {
+		ch, err = a.kv.Compact(trace, compaction.Revision)
+	} else {
+		retained := make([]mvcc.PrefixRetention, len(compaction.RetainedPrefixes))
+		for i, p := range compaction.RetainedPrefixes {
+			retained[i] = mvcc.PrefixRetention{Prefix: p.Prefix, Rev: p.Revision}
+		}
+		ch, err = a.kv.CompactRetained(trace, compaction.Revision, retained)
+	}
 	if err != nil {
 		return nil, ch, nil, err
 	}
@@ -191,6 +207,28 @@ func (a *applierV3backend) Compaction(compaction *pb.CompactionRequest) (*pb.Com
 	return resp, ch, trace, err
 }
 
+func (a *applierV3backend) CompactKey(r *pb.CompactKeyRequest) (*pb.CompactKeyResponse, *traceutil.Trace, error) {
+	trace := traceutil.New("compact_key",
+		a.lg,
+		traceutil.Field{Key: "key", Value: string(r.Key)},
+		traceutil.Field{Key: "keep", Value: r.Keep},
+	)
+
+	rev, compactRev, err := a.kv.CompactKey(trace, r.Key, r.Keep)
+	if err != nil {
+		return nil, trace, err
+	}
+	resp := &pb.CompactKeyResponse{Header: a.newHeader(), CompactRevision: compactRev}
+	resp.Header.Revision = rev
+	return resp, trace, nil
+}
+
+func (a *applierV3backend) PrefixQuotaSet(r *pb.PrefixQuotaSetRequest) (*pb.PrefixQuotaSetResponse, error) {
+	q := r.Quota
+	a.kv.SetPrefixQuota(mvcc.PrefixQuota{Prefix: q.Prefix, MaxBytes: q.MaxBytes, MaxKeys: q.MaxKeys})
+	return &pb.PrefixQuotaSetResponse{Header: a.newHeader()}, nil
+}
+
 func (a *applierV3backend) LeaseGrant(lc *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
 	l, err := a.lessor.Grant(lease.LeaseID(lc.ID), lc.TTL)
 	resp := &pb.LeaseGrantResponse{}
@@ -202,6 +240,51 @@ func (a *applierV3backend) LeaseGrant(lc *pb.LeaseGrantRequest) (*pb.LeaseGrantR
 	return resp, err
 }
 
+func (a *applierV3backend) LeaseGrantBatch(lc *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	reqs := make([]lease.GrantRequest, len(lc.Leases))
+	for i, r := range lc.Leases {
+		reqs[i] = lease.GrantRequest{ID: lease.LeaseID(r.ID), TTL: r.TTL}
+	}
+	ls, err := a.lessor.GrantBatch(reqs)
+	resp := &pb.LeaseGrantBatchResponse{}
+	if err == nil {
+		resp.Leases = make([]*pb.GrantedLease, len(ls))
+		for i, l := range ls {
+			resp.Leases[i] = &pb.GrantedLease{ID: int64(l.ID), TTL: l.TTL()}
+		}
+		resp.Header = a.newHeader()
+	}
+	return resp, err
+}
+
+func (a *applierV3backend) LeaseReattach(lc *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	newID := lease.LeaseID(lc.NewID)
+
+	// the lessor moves the keys while the write txn is open, so that readers
+	// of the store never see the keys under the new lease before the rewrite
+	// of the keys is committed.
+	txn := a.kv.Write(traceutil.TODO())
+	items, err := a.lessor.Reattach(lease.LeaseID(lc.OldID), newID)
+	if err == nil {
+		for _, it := range items {
+			var rr *mvcc.RangeResult
+			rr, err = txn.Range(context.TODO(), []byte(it.Key), nil, mvcc.RangeOptions{})
+			if err != nil {
+				break
+			}
+			if len(rr.KVs) == 0 {
+				continue
+			}
+			txn.Put([]byte(it.Key), rr.KVs[0].Value, newID)
+		}
+	}
+	txn.End()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LeaseReattachResponse{Header: a.newHeader(), Count: int64(len(items))}, nil
+}
+
 func (a *applierV3backend) LeaseRevoke(lc *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
 	err := a.lessor.Revoke(lease.LeaseID(lc.ID))
 	return &pb.LeaseRevokeResponse{Header: a.newHeader()}, err
@@ -270,6 +353,14 @@ func (a *applierV3Capped) LeaseGrant(_ *pb.LeaseGrantRequest) (*pb.LeaseGrantRes
 	return nil, errors.ErrNoSpace
 }
 
+func (a *applierV3Capped) LeaseGrantBatch(_ *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	return nil, errors.ErrNoSpace
+}
+
+func (a *applierV3Capped) LeaseReattach(_ *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	return nil, errors.ErrNoSpace
+}
+
 func (a *applierV3backend) AuthEnable() (*pb.AuthEnableResponse, error) {
 	err := a.authStore.AuthEnable()
 	if err != nil {
@@ -402,6 +493,22 @@ func (a *applierV3backend) RoleList(r *pb.AuthRoleListRequest) (*pb.AuthRoleList
 	return resp, err
 }
 
+func (a *applierV3backend) AuthExport(r *pb.AuthExportRequest) (*pb.AuthExportResponse, error) {
+	resp, err := a.authStore.AuthExport(r)
+	if resp != nil {
+		resp.Header = a.newHeader()
+	}
+	return resp, err
+}
+
+func (a *applierV3backend) AuthImport(r *pb.AuthImportRequest) (*pb.AuthImportResponse, error) {
+	resp, err := a.authStore.AuthImport(r)
+	if resp != nil {
+		resp.Header = a.newHeader()
+	}
+	return resp, err
+}
+
 func (a *applierV3backend) ClusterVersionSet(r *membershippb.ClusterVersionSetRequest, shouldApplyV3 membership.ShouldApplyV3) {
 	prevVersion := a.cluster.Version()
 	newVersion := semver.Must(semver.NewVersion(r.Ver))
@@ -423,8 +530,9 @@ func (a *applierV3backend) ClusterMemberAttrSet(r *membershippb.ClusterMemberAtt
 	a.cluster.UpdateAttributes(
 		types.ID(r.Member_ID),
 		membership.Attributes{
-			Name:       r.MemberAttributes.Name,
-			ClientURLs: r.MemberAttributes.ClientUrls,
+			Name:             r.MemberAttributes.Name,
+			ClientURLs:       r.MemberAttributes.ClientUrls,
+			ElectionPriority: r.MemberAttributes.ElectionPriority,
 		},
 		shouldApplyV3,
 	)
@@ -474,6 +582,24 @@ func (a *quotaApplierV3) LeaseGrant(lc *pb.LeaseGrantRequest) (*pb.LeaseGrantRes
 	return resp, err
 }
 
+func (a *quotaApplierV3) LeaseGrantBatch(lc *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	ok := a.q.Available(lc)
+	resp, err := a.applierV3.LeaseGrantBatch(lc)
+	if err == nil && !ok {
+		err = errors.ErrNoSpace
+	}
+	return resp, err
+}
+
+func (a *quotaApplierV3) LeaseReattach(lc *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	ok := a.q.Available(lc)
+	resp, err := a.applierV3.LeaseReattach(lc)
+	if err == nil && !ok {
+		err = errors.ErrNoSpace
+	}
+	return resp, err
+}
+
 func (a *applierV3backend) newHeader() *pb.ResponseHeader {
 	return &pb.ResponseHeader{
 		ClusterId: uint64(a.cluster.ID()),
@@ -83,9 +83,21 @@ func (aa *authApplierV3) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResp
 			return nil, nil, err
 		}
 	}
+	if len(r.CopyFrom) != 0 {
+		if err := aa.as.IsRangePermitted(&aa.authInfo, r.CopyFrom, nil); err != nil {
+			return nil, nil, err
+		}
+	}
 	return aa.applierV3.Put(ctx, r)
 }
 
+func (aa *authApplierV3) CompactKey(r *pb.CompactKeyRequest) (*pb.CompactKeyResponse, *traceutil.Trace, error) {
+	if err := aa.as.IsPutPermitted(&aa.authInfo, r.Key); err != nil {
+		return nil, nil, err
+	}
+	return aa.applierV3.CompactKey(r)
+}
+
 func (aa *authApplierV3) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, *traceutil.Trace, error) {
 	if err := aa.as.IsRangePermitted(&aa.authInfo, r.Key, r.RangeEnd); err != nil {
 		return nil, nil, err
@@ -121,6 +133,18 @@ func (aa *authApplierV3) LeaseRevoke(lc *pb.LeaseRevokeRequest) (*pb.LeaseRevoke
 	return aa.applierV3.LeaseRevoke(lc)
 }
 
+func (aa *authApplierV3) LeaseReattach(lc *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	// the keys of the old lease are rewritten and attached to the new lease,
+	// which is only permitted if the user can write the keys of both leases.
+	if err := aa.checkLeasePuts(lease.LeaseID(lc.OldID)); err != nil {
+		return nil, err
+	}
+	if err := aa.checkLeasePuts(lease.LeaseID(lc.NewID)); err != nil {
+		return nil, err
+	}
+	return aa.applierV3.LeaseReattach(lc)
+}
+
 func (aa *authApplierV3) checkLeasePuts(leaseID lease.LeaseID) error {
 	l := aa.lessor.Lookup(leaseID)
 	if l != nil {
@@ -197,6 +221,12 @@ func needAdminPermission(r *pb.InternalRaftRequest) bool {
 		return true
 	case r.AuthRoleList != nil:
 		return true
+	case r.AuthExport != nil:
+		return true
+	case r.AuthImport != nil:
+		return true
+	case r.PrefixQuotaSet != nil:
+		return true
 	default:
 		return false
 	}
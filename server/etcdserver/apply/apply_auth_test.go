@@ -376,6 +376,16 @@ func TestAuthApplierV3_AdminPermission(t *testing.T) {
 				request:               &pb.InternalRaftRequest{AuthRoleRevokePermission: &pb.AuthRoleRevokePermissionRequest{}},
 				adminPermissionNeeded: true,
 			},
+			{
+				name:                  "AuthExport needs admin permission",
+				request:               &pb.InternalRaftRequest{AuthExport: &pb.AuthExportRequest{}},
+				adminPermissionNeeded: true,
+			},
+			{
+				name:                  "AuthImport needs admin permission",
+				request:               &pb.InternalRaftRequest{AuthImport: &pb.AuthImportRequest{}},
+				adminPermissionNeeded: true,
+			},
 		}
 	authApplier := defaultAuthApplierV3(t)
 	mustCreateRolesAndEnableAuth(t, authApplier)
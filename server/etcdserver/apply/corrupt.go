@@ -48,10 +48,26 @@ func (a *applierV3Corrupt) Compaction(_ *pb.CompactionRequest) (*pb.CompactionRe
 	return nil, nil, nil, errors.ErrCorrupt
 }
 
+func (a *applierV3Corrupt) CompactKey(_ *pb.CompactKeyRequest) (*pb.CompactKeyResponse, *traceutil.Trace, error) {
+	return nil, nil, errors.ErrCorrupt
+}
+
+func (a *applierV3Corrupt) PrefixQuotaSet(_ *pb.PrefixQuotaSetRequest) (*pb.PrefixQuotaSetResponse, error) {
+	return nil, errors.ErrCorrupt
+}
+
 func (a *applierV3Corrupt) LeaseGrant(_ *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
 	return nil, errors.ErrCorrupt
 }
 
+func (a *applierV3Corrupt) LeaseGrantBatch(_ *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	return nil, errors.ErrCorrupt
+}
+
+func (a *applierV3Corrupt) LeaseReattach(_ *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	return nil, errors.ErrCorrupt
+}
+
 func (a *applierV3Corrupt) LeaseRevoke(_ *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
 	return nil, errors.ErrCorrupt
 }
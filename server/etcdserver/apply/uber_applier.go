@@ -32,14 +32,15 @@ import (
 )
 
 type UberApplier interface {
-	Apply(r *pb.InternalRaftRequest, shouldApplyV3 membership.ShouldApplyV3) *Result
+	Apply(ctx context.Context, r *pb.InternalRaftRequest, shouldApplyV3 membership.ShouldApplyV3) *Result
 }
 
 type uberApplier struct {
 	lg *zap.Logger
 
-	alarmStore           *v3alarm.AlarmStore
-	warningApplyDuration time.Duration
+	alarmStore *v3alarm.AlarmStore
+	// warningApplyDuration returns the warning apply duration of a request type
+	warningApplyDuration func(requestType string) time.Duration
 
 	// This is the applier that is taking in consideration current alarms
 	applyV3 applierV3
@@ -59,7 +60,7 @@ func NewUberApplier(
 	raftStatus RaftStatusGetter,
 	snapshotServer SnapshotServer,
 	consistentIndex cindex.ConsistentIndexer,
-	warningApplyDuration time.Duration,
+	warningApplyDuration func(requestType string) time.Duration,
 	txnModeWriteWithSharedBuffer bool,
 	quotaBackendBytesCfg int64) UberApplier {
 	applyV3base_ := newApplierV3(lg, be, kv, alarmStore, authStore, lessor, cluster, raftStatus, snapshotServer, consistentIndex, txnModeWriteWithSharedBuffer, quotaBackendBytesCfg)
@@ -108,13 +109,13 @@ func (a *uberApplier) restoreAlarms() {
 	}
 }
 
-func (a *uberApplier) Apply(r *pb.InternalRaftRequest, shouldApplyV3 membership.ShouldApplyV3) *Result {
+func (a *uberApplier) Apply(ctx context.Context, r *pb.InternalRaftRequest, shouldApplyV3 membership.ShouldApplyV3) *Result {
 	// We first execute chain of Apply() calls down the hierarchy:
 	// (i.e. CorruptApplier -> CappedApplier -> Auth -> Quota -> Backend),
 	// then dispatch() unpacks the request to a specific method (like Put),
 	// that gets executed down the hierarchy again:
 	// i.e. CorruptApplier.Put(CappedApplier.Put(...(BackendApplier.Put(...)))).
-	return a.applyV3.Apply(context.TODO(), r, shouldApplyV3, a.dispatch)
+	return a.applyV3.Apply(ctx, r, shouldApplyV3, a.dispatch)
 }
 
 // dispatch translates the request (r) into appropriate call (like Put) on
@@ -125,7 +126,7 @@ func (a *uberApplier) dispatch(ctx context.Context, r *pb.InternalRaftRequest, s
 	defer func(start time.Time) {
 		success := ar.Err == nil || ar.Err == mvcc.ErrCompacted
 		txn.ApplySecObserve(v3Version, op, success, time.Since(start))
-		txn.WarnOfExpensiveRequest(a.lg, a.warningApplyDuration, start, &pb.InternalRaftStringer{Request: r}, ar.Resp, ar.Err)
+		txn.WarnOfExpensiveRequest(a.lg, a.warningApplyDuration(op), start, &pb.InternalRaftStringer{Request: r}, ar.Resp, ar.Err)
 		if !success {
 			txn.WarnOfFailedRequest(a.lg, start, &pb.InternalRaftStringer{Request: r}, ar.Resp, ar.Err)
 		}
@@ -166,9 +167,21 @@ func (a *uberApplier) dispatch(ctx context.Context, r *pb.InternalRaftRequest, s
 	case r.Compaction != nil:
 		op = "Compaction"
 		ar.Resp, ar.Physc, ar.Trace, ar.Err = a.applyV3.Compaction(r.Compaction)
+	case r.CompactKey != nil:
+		op = "CompactKey"
+		ar.Resp, ar.Trace, ar.Err = a.applyV3.CompactKey(r.CompactKey)
+	case r.PrefixQuotaSet != nil:
+		op = "PrefixQuotaSet"
+		ar.Resp, ar.Err = a.applyV3.PrefixQuotaSet(r.PrefixQuotaSet)
 	case r.LeaseGrant != nil:
 		op = "LeaseGrant"
 		ar.Resp, ar.Err = a.applyV3.LeaseGrant(r.LeaseGrant)
+	case r.LeaseGrantBatch != nil:
+		op = "LeaseGrantBatch"
+		ar.Resp, ar.Err = a.applyV3.LeaseGrantBatch(r.LeaseGrantBatch)
+	case r.LeaseReattach != nil:
+		op = "LeaseReattach"
+		ar.Resp, ar.Err = a.applyV3.LeaseReattach(r.LeaseReattach)
 	case r.LeaseRevoke != nil:
 		op = "LeaseRevoke"
 		ar.Resp, ar.Err = a.applyV3.LeaseRevoke(r.LeaseRevoke)
@@ -228,6 +241,12 @@ func (a *uberApplier) dispatch(ctx context.Context, r *pb.InternalRaftRequest, s
 	case r.AuthRoleList != nil:
 		op = "AuthRoleList"
 		ar.Resp, ar.Err = a.applyV3.RoleList(r.AuthRoleList)
+	case r.AuthExport != nil:
+		op = "AuthExport"
+		ar.Resp, ar.Err = a.applyV3.AuthExport(r.AuthExport)
+	case r.AuthImport != nil:
+		op = "AuthImport"
+		ar.Resp, ar.Err = a.applyV3.AuthImport(r.AuthImport)
 	default:
 		a.lg.Panic("not implemented apply", zap.Stringer("raft-request", r))
 	}
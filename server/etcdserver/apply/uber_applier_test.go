@@ -15,14 +15,19 @@
 package apply
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 	"golang.org/x/crypto/bcrypt"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
 	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3alarm"
@@ -37,7 +42,12 @@ import (
 const memberId = 111195
 
 func defaultUberApplier(t *testing.T) UberApplier {
-	lg := zaptest.NewLogger(t)
+	return newTestUberApplier(t, zaptest.NewLogger(t), nil, func(string) time.Duration { return 1 * time.Hour })
+}
+
+// newTestUberApplier creates an uber applier whose mvcc.KV is wrapped by
+// wrapKV, unless it is nil.
+func newTestUberApplier(t *testing.T, lg *zap.Logger, wrapKV func(mvcc.KV) mvcc.KV, warningApplyDuration func(string) time.Duration) UberApplier {
 	be, _ := betesting.NewDefaultTmpBackend(t)
 	t.Cleanup(func() {
 		betesting.Close(t, be)
@@ -46,7 +56,10 @@ func defaultUberApplier(t *testing.T) UberApplier {
 	cluster := membership.NewCluster(lg)
 	cluster.AddMember(&membership.Member{ID: memberId}, true)
 	lessor := lease.NewLessor(lg, be, cluster, lease.LessorConfig{})
-	kv := mvcc.NewStore(lg, be, lessor, mvcc.StoreConfig{})
+	var kv mvcc.KV = mvcc.NewStore(lg, be, lessor, mvcc.StoreConfig{})
+	if wrapKV != nil {
+		kv = wrapKV(kv)
+	}
 	alarmStore, err := v3alarm.NewAlarmStore(lg, schema.NewAlarmBackend(lg, be))
 	require.NoError(t, err)
 
@@ -70,7 +83,7 @@ func defaultUberApplier(t *testing.T) UberApplier {
 		&fakeRaftStatusGetter{},
 		&fakeSnapshotServer{},
 		consistentIndex,
-		1*time.Hour,
+		warningApplyDuration,
 		false,
 		16*1024*1024, //16MB
 	)
@@ -121,7 +134,7 @@ func TestUberApplier_Alarm_Corrupt(t *testing.T) {
 	}
 
 	ua := defaultUberApplier(t)
-	result := ua.Apply(&pb.InternalRaftRequest{
+	result := ua.Apply(context.TODO(), &pb.InternalRaftRequest{
 		Header: &pb.RequestHeader{},
 		Alarm: &pb.AlarmRequest{
 			Action:   pb.AlarmRequest_ACTIVATE,
@@ -134,7 +147,7 @@ func TestUberApplier_Alarm_Corrupt(t *testing.T) {
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			result = ua.Apply(tc.request, true)
+			result = ua.Apply(context.TODO(), tc.request, true)
 			require.NotNil(t, result)
 			require.Equalf(t, tc.expectError, result.Err, "Apply: got %v, expect: %v", result.Err, tc.expectError)
 		})
@@ -220,7 +233,7 @@ func TestUberApplier_Alarm_Quota(t *testing.T) {
 	}
 
 	ua := defaultUberApplier(t)
-	result := ua.Apply(&pb.InternalRaftRequest{
+	result := ua.Apply(context.TODO(), &pb.InternalRaftRequest{
 		Header: &pb.RequestHeader{},
 		Alarm: &pb.AlarmRequest{
 			Action:   pb.AlarmRequest_ACTIVATE,
@@ -233,7 +246,7 @@ func TestUberApplier_Alarm_Quota(t *testing.T) {
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			result = ua.Apply(tc.request, true)
+			result = ua.Apply(context.TODO(), tc.request, true)
 			require.NotNil(t, result)
 			require.Equalf(t, tc.expectError, result.Err, "Apply: got %v, expect: %v", result.Err, tc.expectError)
 		})
@@ -243,7 +256,7 @@ func TestUberApplier_Alarm_Quota(t *testing.T) {
 // TestUberApplier_Alarm_Deactivate tests the applier should be able to apply after alarm is deactivated
 func TestUberApplier_Alarm_Deactivate(t *testing.T) {
 	ua := defaultUberApplier(t)
-	result := ua.Apply(&pb.InternalRaftRequest{
+	result := ua.Apply(context.TODO(), &pb.InternalRaftRequest{
 		Header: &pb.RequestHeader{},
 		Alarm: &pb.AlarmRequest{
 			Action:   pb.AlarmRequest_ACTIVATE,
@@ -254,11 +267,11 @@ func TestUberApplier_Alarm_Deactivate(t *testing.T) {
 	require.NotNil(t, result)
 	require.Nil(t, result.Err)
 
-	result = ua.Apply(&pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte(key)}}, true)
+	result = ua.Apply(context.TODO(), &pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte(key)}}, true)
 	require.NotNil(t, result)
 	require.Equalf(t, errors.ErrNoSpace, result.Err, "Apply: got %v, expect: %v", result.Err, errors.ErrNoSpace)
 
-	result = ua.Apply(&pb.InternalRaftRequest{
+	result = ua.Apply(context.TODO(), &pb.InternalRaftRequest{
 		Header: &pb.RequestHeader{},
 		Alarm: &pb.AlarmRequest{
 			Action:   pb.AlarmRequest_DEACTIVATE,
@@ -269,7 +282,52 @@ func TestUberApplier_Alarm_Deactivate(t *testing.T) {
 	require.NotNil(t, result)
 	require.Nil(t, result.Err)
 
-	result = ua.Apply(&pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte(key)}}, true)
+	result = ua.Apply(context.TODO(), &pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte(key)}}, true)
 	require.NotNil(t, result)
 	require.Nil(t, result.Err)
 }
+
+// slowKV is a mvcc.KV whose write transactions take at least delay.
+type slowKV struct {
+	mvcc.KV
+	delay time.Duration
+}
+
+func (kv *slowKV) Write(trace *traceutil.Trace) mvcc.TxnWrite {
+	time.Sleep(kv.delay)
+	return kv.KV.Write(trace)
+}
+
+// TestUberApplier_WarningApplyDurationByRequest tests that slow applies are
+// warned of against the warning apply duration of their request type.
+func TestUberApplier_WarningApplyDurationByRequest(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	durations := map[string]time.Duration{
+		"Put": time.Millisecond,
+		"Txn": time.Hour,
+	}
+	warningApplyDuration := func(requestType string) time.Duration {
+		if d, ok := durations[requestType]; ok {
+			return d
+		}
+		return time.Hour
+	}
+	ua := newTestUberApplier(t, zap.New(core), func(kv mvcc.KV) mvcc.KV {
+		return &slowKV{KV: kv, delay: 20 * time.Millisecond}
+	}, warningApplyDuration)
+
+	put := &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar")}
+	result := ua.Apply(context.TODO(), &pb.InternalRaftRequest{Header: &pb.RequestHeader{ID: 1}, Put: put}, true)
+	require.NoError(t, result.Err)
+	result = ua.Apply(context.TODO(), &pb.InternalRaftRequest{Header: &pb.RequestHeader{ID: 2}, Txn: &pb.TxnRequest{
+		Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestPut{RequestPut: put}}},
+	}}, true)
+	require.NoError(t, result.Err)
+	result = ua.Apply(context.TODO(), &pb.InternalRaftRequest{Header: &pb.RequestHeader{ID: 3}, DeleteRange: &pb.DeleteRangeRequest{Key: []byte("foo")}}, true)
+	require.NoError(t, result.Err)
+
+	warnings := logs.FilterMessage("apply request took too long").All()
+	require.Len(t, warnings, 1)
+	assert.Equal(t, time.Millisecond, warnings[0].ContextMap()["expected-duration"])
+	assert.Contains(t, warnings[0].ContextMap()["request"], "put:")
+}
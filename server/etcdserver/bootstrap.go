@@ -21,11 +21,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/coreos/go-semver/semver"
 	"github.com/dustin/go-humanize"
+	"github.com/jonboulle/clockwork"
 	"go.uber.org/zap"
 
 	"go.etcd.io/etcd/api/v3/etcdserverpb"
@@ -44,6 +46,7 @@ import (
 	servererrors "go.etcd.io/etcd/server/v3/etcdserver/errors"
 	serverstorage "go.etcd.io/etcd/server/v3/storage"
 	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/datadir"
 	"go.etcd.io/etcd/server/v3/storage/schema"
 	"go.etcd.io/etcd/server/v3/storage/wal"
 	"go.etcd.io/etcd/server/v3/storage/wal/walpb"
@@ -62,6 +65,13 @@ func bootstrap(cfg config.ServerConfig) (b *bootstrappedServer, err error) {
 		)
 	}
 
+	if err = cfg.VerifyDirs(); err != nil {
+		return nil, err
+	}
+	if err = verifyMovedDirs(cfg); err != nil {
+		return nil, err
+	}
+
 	if terr := fileutil.TouchDirAll(cfg.Logger, cfg.DataDir); terr != nil {
 		return nil, fmt.Errorf("cannot access data directory: %v", terr)
 	}
@@ -70,6 +80,9 @@ func bootstrap(cfg config.ServerConfig) (b *bootstrappedServer, err error) {
 		return nil, fmt.Errorf("cannot access member directory: %v", terr)
 	}
 	ss := bootstrapSnapshot(cfg)
+	if err = bootstrapBackendDir(cfg); err != nil {
+		return nil, err
+	}
 	prt, err := rafthttp.NewRoundTripper(cfg.PeerTLSInfo, cfg.PeerDialTimeout())
 	if err != nil {
 		return nil, err
@@ -159,6 +172,7 @@ type bootstrapedCluster struct {
 type bootstrappedRaft struct {
 	lg        *zap.Logger
 	heartbeat time.Duration
+	clock     clockwork.Clock
 
 	peers   []raft.Peer
 	config  *raft.Config
@@ -177,6 +191,66 @@ func bootstrapStorage(cfg config.ServerConfig, st v2store.Store, be *bootstrappe
 	}, nil
 }
 
+// verifyMovedDirs refuses to start a member whose WAL, snapshots or backend
+// were left in the data dir when a dedicated dir was set: starting without
+// them would lose the state of the member.
+func verifyMovedDirs(cfg config.ServerConfig) error {
+	if cfg.DedicatedWALDir != "" && !wal.Exist(cfg.WALDir()) && wal.Exist(datadir.ToWalDir(cfg.DataDir)) {
+		return fmt.Errorf("found WAL in %q but not in wal dir %q: move the WAL files to the wal dir, or remove --wal-dir", datadir.ToWalDir(cfg.DataDir), cfg.DedicatedWALDir)
+	}
+	if cfg.DedicatedSnapDir != "" && !hasSnapFiles(cfg.SnapDir()) && hasSnapFiles(datadir.ToSnapDir(cfg.DataDir)) {
+		return fmt.Errorf("found snapshots in %q but not in snapshot dir %q: move the *.snap files to the snapshot dir, or remove --snapshot-dir", datadir.ToSnapDir(cfg.DataDir), cfg.DedicatedSnapDir)
+	}
+	if cfg.DedicatedBackendDir != "" && !fileutil.Exist(cfg.BackendPath()) {
+		// the backend may have been moved along with the snapshots
+		for _, bepath := range []string{datadir.ToBackendFileName(cfg.DataDir), datadir.ToBackendFileNameInDir(cfg.SnapDir())} {
+			if fileutil.Exist(bepath) {
+				return fmt.Errorf("found backend %q but not in backend dir %q: move it to the backend dir, or remove --backend-dir", bepath, cfg.DedicatedBackendDir)
+			}
+		}
+	}
+	return nil
+}
+
+func hasSnapFiles(dir string) bool {
+	names, err := filepath.Glob(filepath.Join(dir, "*.snap"))
+	return err == nil && len(names) != 0
+}
+
+// bootstrapBackendDir creates the dedicated backend dir, if any, removes the
+// temporary files orphaned in it, and checks that a snapshot received from the
+// leader can be renamed into it.
+func bootstrapBackendDir(cfg config.ServerConfig) error {
+	dir := filepath.Dir(cfg.BackendPath())
+	if cfg.DedicatedBackendDir == "" || dir == filepath.Clean(cfg.SnapDir()) {
+		return nil
+	}
+	if err := fileutil.TouchDirAll(cfg.Logger, dir); err != nil {
+		return fmt.Errorf("cannot access backend directory: %v", err)
+	}
+	if err := fileutil.RemoveMatchFile(cfg.Logger, dir, func(fileName string) bool {
+		return strings.HasPrefix(fileName, "db.tmp") || (strings.HasPrefix(fileName, "snapshot-") && strings.HasSuffix(fileName, ".db.tmp"))
+	}); err != nil {
+		cfg.Logger.Error(
+			"failed to remove temp file(s) in backend directory",
+			zap.String("path", dir),
+			zap.Error(err),
+		)
+	}
+
+	// the snapshotter removes the file if the rename is interrupted
+	f, err := os.CreateTemp(cfg.SnapDir(), "db.tmp.*")
+	if err != nil {
+		return fmt.Errorf("cannot write to snapshot directory: %v", err)
+	}
+	f.Close()
+	if err = os.Rename(f.Name(), filepath.Join(dir, filepath.Base(f.Name()))); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("cannot move snapshots from snapshot dir %q to backend dir %q, which must be on the same filesystem: %v", cfg.SnapDir(), dir, err)
+	}
+	return os.Remove(filepath.Join(dir, filepath.Base(f.Name())))
+}
+
 func bootstrapSnapshot(cfg config.ServerConfig) *snap.Snapshotter {
 	if err := fileutil.TouchDirAll(cfg.Logger, cfg.SnapDir()); err != nil {
 		cfg.Logger.Fatal(
@@ -282,7 +356,7 @@ func bootstrapExistingClusterNoWAL(cfg config.ServerConfig, prt http.RoundTrippe
 	if err := cfg.VerifyJoinExisting(); err != nil {
 		return nil, err
 	}
-	cl, err := membership.NewClusterFromURLsMap(cfg.Logger, cfg.InitialClusterToken, cfg.InitialPeerURLsMap, membership.WithMaxLearners(cfg.ExperimentalMaxLearners))
+	cl, err := membership.NewClusterFromURLsMap(cfg.Logger, cfg.InitialClusterToken, cfg.InitialPeerURLsMap, membership.WithMaxLearners(cfg.ExperimentalMaxLearners), membership.WithMemberIDs(cfg.InitialMemberIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -314,7 +388,7 @@ func bootstrapNewClusterNoWAL(cfg config.ServerConfig, prt http.RoundTripper) (*
 	if err := cfg.VerifyBootstrap(); err != nil {
 		return nil, err
 	}
-	cl, err := membership.NewClusterFromURLsMap(cfg.Logger, cfg.InitialClusterToken, cfg.InitialPeerURLsMap, membership.WithMaxLearners(cfg.ExperimentalMaxLearners))
+	cl, err := membership.NewClusterFromURLsMap(cfg.Logger, cfg.InitialClusterToken, cfg.InitialPeerURLsMap, membership.WithMaxLearners(cfg.ExperimentalMaxLearners), membership.WithMemberIDs(cfg.InitialMemberIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -342,7 +416,7 @@ func bootstrapNewClusterNoWAL(cfg config.ServerConfig, prt http.RoundTripper) (*
 		if config.CheckDuplicateURL(urlsmap) {
 			return nil, fmt.Errorf("discovery cluster %s has duplicate url", urlsmap)
 		}
-		if cl, err = membership.NewClusterFromURLsMap(cfg.Logger, cfg.InitialClusterToken, urlsmap, membership.WithMaxLearners(cfg.ExperimentalMaxLearners)); err != nil {
+		if cl, err = membership.NewClusterFromURLsMap(cfg.Logger, cfg.InitialClusterToken, urlsmap, membership.WithMaxLearners(cfg.ExperimentalMaxLearners), membership.WithMemberIDs(cfg.InitialMemberIDs)); err != nil {
 			return nil, err
 		}
 	}
@@ -499,6 +573,7 @@ func bootstrapRaftFromCluster(cfg config.ServerConfig, cl *membership.RaftCluste
 	return &bootstrappedRaft{
 		lg:        cfg.Logger,
 		heartbeat: time.Duration(cfg.TickMs) * time.Millisecond,
+		clock:     cfg.TickClock,
 		config:    raftConfig(cfg, uint64(member.ID), s),
 		peers:     peers,
 		storage:   s,
@@ -510,6 +585,7 @@ func bootstrapRaftFromWAL(cfg config.ServerConfig, bwal *bootstrappedWAL) *boots
 	return &bootstrappedRaft{
 		lg:        cfg.Logger,
 		heartbeat: time.Duration(cfg.TickMs) * time.Millisecond,
+		clock:     cfg.TickClock,
 		config:    raftConfig(cfg, uint64(bwal.meta.nodeID), s),
 		storage:   s,
 	}
@@ -545,6 +621,7 @@ func (b *bootstrappedRaft) newRaftNode(ss *snap.Snapshotter, wal *wal.WAL, cl *m
 			isIDRemoved: func(id uint64) bool { return cl.IsIDRemoved(types.ID(id)) },
 			Node:        n,
 			heartbeat:   b.heartbeat,
+			clock:       b.clock,
 			raftStorage: b.storage,
 			storage:     serverstorage.NewStorage(b.lg, wal, ss),
 		},
@@ -603,6 +680,7 @@ func openWALFromSnapshot(cfg config.ServerConfig, snapshot *raftpb.Snapshot) (*w
 		if cfg.UnsafeNoFsync {
 			w.SetUnsafeNoFsync()
 		}
+		w.SetSlowSyncThreshold(cfg.WALSlowFsyncThreshold)
 		wmetadata, st, ents, err := w.ReadAll()
 		if err != nil {
 			w.Close()
@@ -645,6 +723,7 @@ func bootstrapNewWAL(cfg config.ServerConfig, cl *bootstrapedCluster) *bootstrap
 	if cfg.UnsafeNoFsync {
 		w.SetUnsafeNoFsync()
 	}
+	w.SetSlowSyncThreshold(cfg.WALSlowFsyncThreshold)
 	return &bootstrappedWAL{
 		lg: cfg.Logger,
 		w:  w,
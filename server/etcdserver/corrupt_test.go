@@ -473,6 +473,10 @@ func (f *fakeHasher) HashByRev(rev int64) (hash mvcc.KeyValueHash, revision int6
 	return hashByRev.hash, hashByRev.revision, hashByRev.err
 }
 
+func (f *fakeHasher) HashByRevRange(rev int64, key, end []byte) (hash mvcc.KeyValueHash, revision int64, err error) {
+	panic("not implemented")
+}
+
 func (f *fakeHasher) Store(hash mvcc.KeyValueHash) {
 	f.actions = append(f.actions, fmt.Sprintf("Store(%v)", hash))
 	f.hashes = append(f.hashes, hash)
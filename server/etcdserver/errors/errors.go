@@ -28,6 +28,7 @@ var (
 	ErrTimeoutDueToConnectionLost  = errors.New("etcdserver: request timed out, possibly due to connection lost")
 	ErrTimeoutLeaderTransfer       = errors.New("etcdserver: request timed out, leader transfer took too long")
 	ErrTimeoutWaitAppliedIndex     = errors.New("etcdserver: request timed out, waiting for the applied index took too long")
+	ErrDeadlineExceeded            = errors.New("etcdserver: request deadline exceeded")
 	ErrLeaderChanged               = errors.New("etcdserver: leader changed")
 	ErrNotEnoughStartedMembers     = errors.New("etcdserver: re-configuration failed due to not enough started members")
 	ErrLearnerNotReady             = errors.New("etcdserver: can only promote a learner member which is in sync with leader")
@@ -36,12 +37,16 @@ var (
 	ErrRequestTooLarge             = errors.New("etcdserver: request is too large")
 	ErrNoSpace                     = errors.New("etcdserver: no space")
 	ErrTooManyRequests             = errors.New("etcdserver: too many requests")
+	ErrUserRateLimited             = errors.New("etcdserver: user request rate exceeded")
 	ErrUnhealthy                   = errors.New("etcdserver: unhealthy cluster")
 	ErrCorrupt                     = errors.New("etcdserver: corrupt cluster")
 	ErrBadLeaderTransferee         = errors.New("etcdserver: bad leader transferee")
 	ErrClusterVersionUnavailable   = errors.New("etcdserver: cluster version not found during downgrade")
 	ErrWrongDowngradeVersionFormat = errors.New("etcdserver: wrong downgrade target version format")
 	ErrKeyNotFound                 = errors.New("etcdserver: key not found")
+	ErrRebuildIndexOnLeader        = errors.New("etcdserver: refusing to rebuild the key index of the leader without force")
+	ErrRebuildingIndex             = errors.New("etcdserver: rebuilding the key index")
+	ErrInvalidPrefixQuota          = errors.New("etcdserver: prefix quota limits must not be negative")
 )
 
 type DiscoveryError struct {
@@ -0,0 +1,133 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/server/v3/lease"
+)
+
+// keyTTLLeases tracks the leases granted for the keys put with a TTL, so that
+// the keys put with the same TTL around the same time share a lease instead of
+// granting one each.
+type keyTTLLeases struct {
+	mu      sync.Mutex
+	buckets map[int64]keyTTLBucket
+}
+
+type keyTTLBucket struct {
+	id lease.LeaseID
+	// expiry is the earliest time the lease may expire
+	expiry time.Time
+}
+
+func newKeyTTLLeases() *keyTTLLeases {
+	return &keyTTLLeases{buckets: make(map[int64]keyTTLBucket)}
+}
+
+// keyTTLSlack returns how much longer than ttl the lease of a bucket is
+// granted for, which is how long the bucket is shared.
+func keyTTLSlack(ttl int64) int64 {
+	if ttl < 10 {
+		return 1
+	}
+	return ttl / 10
+}
+
+// get returns the lease of the bucket of ttl if it lives for at least ttl
+// from now.
+func (l *keyTTLLeases) get(ttl int64, now time.Time) (lease.LeaseID, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[ttl]
+	if !ok || now.Add(time.Duration(ttl)*time.Second).After(b.expiry) {
+		return lease.NoLease, false
+	}
+	return b.id, true
+}
+
+// put makes id the lease of the bucket of ttl, and forgets the buckets that
+// cannot be shared anymore.
+func (l *keyTTLLeases) put(ttl int64, id lease.LeaseID, expiry, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for t, b := range l.buckets {
+		if now.Add(time.Duration(t) * time.Second).After(b.expiry) {
+			delete(l.buckets, t)
+		}
+	}
+	l.buckets[ttl] = keyTTLBucket{id: id, expiry: expiry}
+}
+
+// forget drops the bucket of ttl if its lease is id.
+func (l *keyTTLLeases) forget(ttl int64, id lease.LeaseID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets[ttl].id == id {
+		delete(l.buckets, ttl)
+	}
+}
+
+// keyTTLLease returns a lease expiring the keys attached to it after at least
+// ttl seconds, granting it if no bucket lease can be shared.
+func (s *EtcdServer) keyTTLLease(ctx context.Context, ttl int64) (lease.LeaseID, error) {
+	now := time.Now()
+	if id, ok := s.keyTTLLeases.get(ttl, now); ok && s.lessor.Lookup(id) != nil {
+		return id, nil
+	}
+	resp, err := s.LeaseGrant(ctx, &pb.LeaseGrantRequest{TTL: ttl + keyTTLSlack(ttl)})
+	if err != nil {
+		return lease.NoLease, err
+	}
+	id := lease.LeaseID(resp.ID)
+	// the lease may be granted a longer TTL than requested, but never a
+	// shorter one
+	s.keyTTLLeases.put(ttl, id, now.Add(time.Duration(resp.TTL)*time.Second), now)
+	return id, nil
+}
+
+// putWithKeyTTL attaches the key of r to a lease expiring it after r.Ttl
+// seconds before proposing it, and returns the lease in the response.
+func (s *EtcdServer) putWithKeyTTL(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
+	ttl := r.Ttl
+	var (
+		resp *pb.PutResponse
+		err  error
+	)
+	// the bucket lease may be revoked between the lookup and the put, in
+	// which case a new one is granted once
+	for i := 0; i < 2; i++ {
+		var id lease.LeaseID
+		if id, err = s.keyTTLLease(ctx, ttl); err != nil {
+			return nil, err
+		}
+		pr := *r
+		pr.Lease, pr.Ttl = int64(id), 0
+		if resp, err = s.put(ctx, &pr); err == lease.ErrLeaseNotFound {
+			s.keyTTLLeases.forget(ttl, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp.Lease = int64(id)
+		return resp, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyTTLLeases(t *testing.T) {
+	l := newKeyTTLLeases()
+	now := time.Unix(0, 0)
+	if _, ok := l.get(30, now); ok {
+		t.Fatal("expected no lease before any was granted")
+	}
+
+	// a lease granted for 33s is shared by the keys put with a 30s TTL for 3s
+	l.put(30, 1, now.Add(33*time.Second), now)
+	for _, d := range []time.Duration{0, time.Second, 3 * time.Second} {
+		if id, ok := l.get(30, now.Add(d)); !ok || id != 1 {
+			t.Errorf("after %v: expected lease 1, got %v, %v", d, id, ok)
+		}
+	}
+	if _, ok := l.get(30, now.Add(3*time.Second+time.Millisecond)); ok {
+		t.Error("expected the lease not to be shared once it expires before the TTL")
+	}
+	if _, ok := l.get(10, now); ok {
+		t.Error("expected the lease not to be shared by another TTL")
+	}
+
+	// stale buckets are forgotten when a lease is granted
+	l.put(10, 2, now.Add(time.Hour), now.Add(time.Minute))
+	if _, ok := l.buckets[30]; ok {
+		t.Error("expected the stale bucket of 30s to be forgotten")
+	}
+
+	l.forget(10, 3)
+	if id, ok := l.get(10, now); !ok || id != 2 {
+		t.Errorf("expected forgetting another lease to keep lease 2, got %v, %v", id, ok)
+	}
+	l.forget(10, 2)
+	if id, ok := l.get(10, now); ok {
+		t.Errorf("expected no lease once forgotten, got %v", id)
+	}
+}
+
+func TestKeyTTLSlack(t *testing.T) {
+	tests := []struct {
+		ttl, slack int64
+	}{
+		{1, 1},
+		{9, 1},
+		{10, 1},
+		{30, 3},
+		{3600, 360},
+	}
+	for _, tt := range tests {
+		if slack := keyTTLSlack(tt.ttl); slack != tt.slack {
+			t.Errorf("ttl %d: expected slack %d, got %d", tt.ttl, tt.slack, slack)
+		}
+	}
+}
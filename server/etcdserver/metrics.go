@@ -18,6 +18,7 @@ import (
 	goruntime "runtime"
 	"time"
 
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/version"
 	"go.etcd.io/etcd/pkg/v3/runtime"
 
@@ -44,6 +45,12 @@ var (
 		Name:      "leader_changes_seen_total",
 		Help:      "The number of leader changes seen.",
 	})
+	raftStateChangesDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "raft_state_changes_dropped_total",
+		Help:      "The total number of raft state change notifications dropped because the observers fell behind.",
+	})
 	isLearner = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -64,6 +71,19 @@ var (
 		Name:      "learner_promote_successes",
 		Help:      "The total number of successful learner promotions while this member is leader.",
 	})
+	appendEntriesRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "append_entries_rejected_total",
+		Help:      "The total number of raft append entries requests rejected by this member, by reason.",
+	},
+		[]string{"Reason"},
+	)
+	// counters of appendEntriesRejected by reason, to avoid looking up the
+	// labels for every message
+	appendEntriesRejectedLogMismatch = appendEntriesRejected.WithLabelValues("log_mismatch")
+	appendEntriesRejectedStaleTerm   = appendEntriesRejected.WithLabelValues("stale_term")
+
 	heartbeatSendFailures = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -94,12 +114,36 @@ var (
 		Name:      "proposals_pending",
 		Help:      "The current number of pending proposals to commit.",
 	})
+	applyPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "apply_pending",
+		Help:      "The current number of batches of committed entries waiting to be applied.",
+	})
+	applyBacklog = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "apply_backlog",
+		Help:      "The current number of committed entries not yet applied.",
+	})
+	applyBacklogRejectedRequests = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "apply_backlog_rejected_requests_total",
+		Help:      "The total number of requests rejected because the apply backlog exceeded its limit.",
+	})
 	proposalsFailed = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
 		Name:      "proposals_failed_total",
 		Help:      "The total number of failed proposals seen.",
 	})
+	rateLimitedRequests = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "rate_limited_requests_total",
+		Help:      "The total number of requests rejected because their auth user exceeded its request rate.",
+	})
 	slowReadIndex = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -112,12 +156,28 @@ var (
 		Name:      "read_indexes_failed_total",
 		Help:      "The total number of failed read indexes seen.",
 	})
+	readIndexWaitSec = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "read_index_wait_duration_seconds",
+		Help:      "The latency distributions of linearizable reads waiting for the confirmation of their read index and for the local member to apply up to it, before reading the backend.",
+
+		// lowest bucket start of upper bound 0.0001 sec (0.1 ms) with factor 2
+		// highest bucket start of 0.0001 sec * 2^19 == 52.4288 sec
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 20),
+	})
 	leaseExpired = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd_debugging",
 		Subsystem: "server",
 		Name:      "lease_expired_total",
 		Help:      "The total number of expired leases.",
 	})
+	leaseRevokeRequested = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "server",
+		Name:      "lease_revoke_requested_total",
+		Help:      "The total number of leases revoked on request of a client, as opposed to expired leases.",
+	})
 
 	currentVersion = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "etcd",
@@ -141,6 +201,27 @@ var (
 	},
 		[]string{"server_id"})
 
+	applyEntrySec = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "apply_entry_duration_seconds",
+		Help:      "The latency distributions of applying committed raft entries by request type.",
+
+		// lowest bucket start of upper bound 0.0001 sec (0.1 ms) with factor 2
+		// highest bucket start of 0.0001 sec * 2^19 == 52.4288 sec
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 20),
+	},
+		[]string{"type"})
+	// observers of applyEntrySec by request type, to avoid looking up the
+	// labels for every entry
+	applyEntryPutSec    = applyEntrySec.WithLabelValues("put")
+	applyEntryDeleteSec = applyEntrySec.WithLabelValues("delete")
+	applyEntryTxnSec    = applyEntrySec.WithLabelValues("txn")
+	applyEntryLeaseSec  = applyEntrySec.WithLabelValues("lease")
+	applyEntryAuthSec   = applyEntrySec.WithLabelValues("auth")
+	applyEntryConfigSec = applyEntrySec.WithLabelValues("config")
+	applyEntryOtherSec  = applyEntrySec.WithLabelValues("other")
+
 	fdUsed = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "os",
 		Subsystem: "fd",
@@ -159,21 +240,30 @@ func init() {
 	prometheus.MustRegister(hasLeader)
 	prometheus.MustRegister(isLeader)
 	prometheus.MustRegister(leaderChanges)
+	prometheus.MustRegister(raftStateChangesDropped)
+	prometheus.MustRegister(appendEntriesRejected)
 	prometheus.MustRegister(heartbeatSendFailures)
 	prometheus.MustRegister(applySnapshotInProgress)
 	prometheus.MustRegister(proposalsCommitted)
 	prometheus.MustRegister(proposalsApplied)
 	prometheus.MustRegister(proposalsPending)
+	prometheus.MustRegister(applyPending)
+	prometheus.MustRegister(applyBacklog)
+	prometheus.MustRegister(applyBacklogRejectedRequests)
 	prometheus.MustRegister(proposalsFailed)
+	prometheus.MustRegister(rateLimitedRequests)
 	prometheus.MustRegister(slowReadIndex)
 	prometheus.MustRegister(readIndexFailed)
+	prometheus.MustRegister(readIndexWaitSec)
 	prometheus.MustRegister(leaseExpired)
+	prometheus.MustRegister(leaseRevokeRequested)
 	prometheus.MustRegister(currentVersion)
 	prometheus.MustRegister(currentGoVersion)
 	prometheus.MustRegister(serverID)
 	prometheus.MustRegister(isLearner)
 	prometheus.MustRegister(learnerPromoteSucceed)
 	prometheus.MustRegister(learnerPromoteFailed)
+	prometheus.MustRegister(applyEntrySec)
 	prometheus.MustRegister(fdUsed)
 	prometheus.MustRegister(fdLimit)
 
@@ -185,6 +275,32 @@ func init() {
 	}).Set(1)
 }
 
+// applyEntryObserver returns the observer of the apply latency of entries
+// carrying the given request.
+func applyEntryObserver(r *pb.InternalRaftRequest) prometheus.Observer {
+	switch {
+	case r.Put != nil:
+		return applyEntryPutSec
+	case r.DeleteRange != nil:
+		return applyEntryDeleteSec
+	case r.Txn != nil:
+		return applyEntryTxnSec
+	case r.LeaseGrant != nil, r.LeaseGrantBatch != nil, r.LeaseReattach != nil, r.LeaseRevoke != nil, r.LeaseCheckpoint != nil:
+		return applyEntryLeaseSec
+	case r.AuthEnable != nil, r.AuthDisable != nil, r.AuthStatus != nil, r.Authenticate != nil,
+		r.AuthUserAdd != nil, r.AuthUserDelete != nil, r.AuthUserGet != nil, r.AuthUserChangePassword != nil,
+		r.AuthUserGrantRole != nil, r.AuthUserRevokeRole != nil, r.AuthUserList != nil, r.AuthRoleList != nil,
+		r.AuthRoleAdd != nil, r.AuthRoleDelete != nil, r.AuthRoleGet != nil,
+		r.AuthRoleGrantPermission != nil, r.AuthRoleRevokePermission != nil,
+		r.AuthExport != nil, r.AuthImport != nil:
+		return applyEntryAuthSec
+	case r.ClusterVersionSet != nil, r.ClusterMemberAttrSet != nil, r.DowngradeInfoSet != nil:
+		return applyEntryConfigSec
+	default:
+		return applyEntryOtherSec
+	}
+}
+
 func monitorFileDescriptor(lg *zap.Logger, done <-chan struct{}) {
 	// This ticker will check File Descriptor Requirements ,and count all fds in used.
 	// And recorded some logs when in used >= limit/5*4. Just recorded message.
@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"go.uber.org/zap"
 
 	"go.etcd.io/raft/v3"
@@ -75,6 +76,7 @@ type toApply struct {
 	// raftAdvancedC notifies EtcdServer.apply that
 	// 'raftLog.applied' has advanced by r.Advance
 	// it should be used only when entries contain raftpb.EntryConfChange
+	// or raftpb.EntryConfChangeV2
 	raftAdvancedC <-chan struct{}
 }
 
@@ -94,7 +96,7 @@ type raftNode struct {
 	readStateC chan raft.ReadState
 
 	// utility
-	ticker *time.Ticker
+	ticker clockwork.Ticker
 	// contention detectors for raft heartbeat message
 	td *contention.TimeoutDetector
 
@@ -111,6 +113,8 @@ type raftNodeConfig struct {
 	raftStorage *raft.MemoryStorage
 	storage     serverstorage.Storage
 	heartbeat   time.Duration // for logging
+	// clock drives the ticker; defaults to the real clock.
+	clock clockwork.Clock
 	// transport specifies the transport to send and receive msgs to members.
 	// Sending messages MUST NOT block. It is okay to drop messages, since
 	// clients should timeout and reissue their messages.
@@ -144,10 +148,14 @@ func newRaftNode(cfg raftNodeConfig) *raftNode {
 		stopped:    make(chan struct{}),
 		done:       make(chan struct{}),
 	}
+	if r.clock == nil {
+		r.clock = clockwork.NewRealClock()
+	}
 	if r.heartbeat == 0 {
-		r.ticker = &time.Ticker{}
+		// a ticker on a clock that is never advanced never fires
+		r.ticker = clockwork.NewFakeClock().NewTicker(time.Second)
 	} else {
-		r.ticker = time.NewTicker(r.heartbeat)
+		r.ticker = r.clock.NewTicker(r.heartbeat)
 	}
 	return r
 }
@@ -167,12 +175,18 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 	go func() {
 		defer r.onStop()
 		islead := false
+		state := raft.StateFollower
+		hs, _, _ := r.raftStorage.InitialState()
+		term := hs.Term
 
 		for {
 			select {
-			case <-r.ticker.C:
+			case <-r.ticker.Chan():
 				r.tick()
 			case rd := <-r.Ready():
+				if !raft.IsEmptyHardState(rd.HardState) {
+					term = rd.HardState.Term
+				}
 				if rd.SoftState != nil {
 					newLeader := rd.SoftState.Lead != raft.None && rh.getLead() != rd.SoftState.Lead
 					if newLeader {
@@ -194,6 +208,11 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 					}
 					rh.updateLeadership(newLeader)
 					r.td.Reset()
+
+					if rd.RaftState != state {
+						rh.updateRaftState(state, rd.RaftState, term)
+						state = rd.RaftState
+					}
 				}
 
 				if len(rd.ReadStates) != 0 {
@@ -275,11 +294,13 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 
 				r.raftStorage.Append(rd.Entries)
 
-				confChanged := false
+				confChanged, confChangedV2 := false, false
 				for _, ent := range rd.CommittedEntries {
-					if ent.Type == raftpb.EntryConfChange {
+					switch ent.Type {
+					case raftpb.EntryConfChange:
 						confChanged = true
-						break
+					case raftpb.EntryConfChangeV2:
+						confChanged, confChangedV2 = true, true
 					}
 				}
 
@@ -314,6 +335,19 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 				} else {
 					// leader already processed 'MsgSnap' and signaled
 					notifyc <- struct{}{}
+
+					// The leader proposes to leave a joint configuration once
+					// raft is advanced past it, so the joint configuration
+					// changes must be applied before advancing. Otherwise the
+					// leader proposes to leave it again, since it does not know
+					// it is left until the change is applied.
+					if confChangedV2 {
+						select {
+						case notifyc <- struct{}{}:
+						case <-r.stopped:
+							return
+						}
+					}
 				}
 
 				// gofail: var raftBeforeAdvance struct{}
@@ -351,6 +385,11 @@ func (r *raftNode) processMessages(ms []raftpb.Message) []raftpb.Message {
 		}
 
 		if ms[i].Type == raftpb.MsgAppResp {
+			if ms[i].Reject {
+				// the log of the leader does not match ours at the index
+				// preceding its entries
+				appendEntriesRejectedLogMismatch.Inc()
+			}
 			if sentAppResp {
 				ms[i].To = 0
 			} else {
@@ -43,6 +43,14 @@ func TestGetIDs(t *testing.T) {
 	normalEntry := raftpb.Entry{Type: raftpb.EntryNormal}
 	updatecc := &raftpb.ConfChange{Type: raftpb.ConfChangeUpdateNode, NodeID: 2}
 	updateEntry := raftpb.Entry{Type: raftpb.EntryConfChange, Data: pbutil.MustMarshal(updatecc)}
+	swapcc := &raftpb.ConfChangeV2{Changes: []raftpb.ConfChangeSingle{
+		{Type: raftpb.ConfChangeAddNode, NodeID: 3},
+		{Type: raftpb.ConfChangeAddNode, NodeID: 4},
+		{Type: raftpb.ConfChangeRemoveNode, NodeID: 1},
+		{Type: raftpb.ConfChangeRemoveNode, NodeID: 2},
+	}}
+	swapEntry := raftpb.Entry{Type: raftpb.EntryConfChangeV2, Data: pbutil.MustMarshal(swapcc)}
+	leaveJointEntry := raftpb.Entry{Type: raftpb.EntryConfChangeV2}
 
 	tests := []struct {
 		confState *raftpb.ConfState
@@ -63,6 +71,8 @@ func TestGetIDs(t *testing.T) {
 			[]raftpb.Entry{addEntry, normalEntry, updateEntry}, []uint64{1, 2}},
 		{&raftpb.ConfState{Voters: []uint64{1}},
 			[]raftpb.Entry{addEntry, removeEntry, normalEntry}, []uint64{1}},
+		{&raftpb.ConfState{Voters: []uint64{1}},
+			[]raftpb.Entry{addEntry, swapEntry, leaveJointEntry}, []uint64{3, 4}},
 	}
 
 	for i, tt := range tests {
@@ -207,6 +217,7 @@ func TestConfigChangeBlocksApply(t *testing.T) {
 		getLead:          func() uint64 { return 0 },
 		updateLead:       func(uint64) {},
 		updateLeadership: func(bool) {},
+		updateRaftState:  func(from, to raft.StateType, term uint64) {},
 	})
 	defer srv.r.Stop()
 
@@ -0,0 +1,77 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"math"
+
+	"golang.org/x/time/rate"
+
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
+)
+
+// userRateLimiter limits the requests of auth users with a token bucket per
+// user. Requests which are not authenticated share the bucket of the empty
+// user name.
+type userRateLimiter struct {
+	limiters map[string]*rate.Limiter
+}
+
+// newUserRateLimiter returns a limiter for the given requests per second of
+// each user, or nil if no user is limited.
+func newUserRateLimiter(limits map[string]float64) *userRateLimiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	l := &userRateLimiter{limiters: make(map[string]*rate.Limiter, len(limits))}
+	for user, limit := range limits {
+		// allow a second worth of requests at once
+		l.limiters[user] = rate.NewLimiter(rate.Limit(limit), int(math.Ceil(limit)))
+	}
+	return l
+}
+
+// allow reports whether the user may send a request now. Users without a
+// limit are always allowed.
+func (l *userRateLimiter) allow(username string) bool {
+	if l == nil {
+		return true
+	}
+	lim, ok := l.limiters[username]
+	return !ok || lim.Allow()
+}
+
+// checkRateLimit returns ErrUserRateLimited if the auth user of the request
+// ran out of its request rate. It is checked before proposing, so that a
+// throttled user does not load raft.
+func (s *EtcdServer) checkRateLimit(ctx context.Context) error {
+	if s.rateLimiter == nil {
+		return nil
+	}
+	ai, err := s.AuthInfoFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+	var username string
+	if ai != nil {
+		username = ai.Username
+	}
+	if !s.rateLimiter.allow(username) {
+		rateLimitedRequests.Inc()
+		return errors.ErrUserRateLimited
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+)
+
+func TestUserRateLimiter(t *testing.T) {
+	if l := newUserRateLimiter(nil); l != nil || !l.allow("alice") {
+		t.Fatalf("expected no limiter to allow every request, got %v", l)
+	}
+
+	l := newUserRateLimiter(map[string]float64{"alice": 5, "": 0.5})
+	for i := 0; i < 5; i++ {
+		if !l.allow("alice") {
+			t.Fatalf("request %d of alice was limited within the burst", i)
+		}
+	}
+	if l.allow("alice") {
+		t.Error("expected alice to be limited after the burst")
+	}
+
+	// unauthenticated requests have a bucket of their own
+	if !l.allow("") {
+		t.Error("expected the first unauthenticated request to be allowed")
+	}
+	if l.allow("") {
+		t.Error("expected the second unauthenticated request to be limited")
+	}
+
+	for i := 0; i < 100; i++ {
+		if !l.allow("bob") {
+			t.Fatal("expected bob to be unlimited")
+		}
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/coreos/go-semver/semver"
 	humanize "github.com/dustin/go-humanize"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"go.etcd.io/etcd/pkg/v3/notify"
@@ -38,6 +39,7 @@ import (
 	"go.etcd.io/etcd/server/v3/config"
 	"go.etcd.io/etcd/server/v3/etcdserver/apply"
 	"go.etcd.io/etcd/server/v3/etcdserver/errors"
+	"go.etcd.io/etcd/server/v3/etcdserver/txn"
 
 	"go.etcd.io/raft/v3"
 	"go.etcd.io/raft/v3/raftpb"
@@ -83,6 +85,10 @@ const (
 	// follower to catch up.
 	DefaultSnapshotCatchUpEntries uint64 = 5000
 
+	// DefaultMaxApplyBacklog is the default number of committed entries
+	// waiting to be applied above which new proposals are rejected.
+	DefaultMaxApplyBacklog uint64 = maxGapBetweenApplyAndCommitIndex
+
 	StoreClusterPrefix = "/0"
 	StoreKeysPrefix    = "/1"
 
@@ -114,6 +120,10 @@ var (
 	// (since it will timeout).
 	monitorVersionInterval = rafthttp.ConnWriteTimeout - time.Second
 
+	// defragCheckInterval is the interval between checks of the free space
+	// in the backend if only a threshold is set for scheduled defragmentation.
+	defragCheckInterval = time.Minute
+
 	recommendedMaxRequestBytesString = humanize.Bytes(uint64(recommendedMaxRequestBytes))
 	storeMemberAttributeRegexp       = regexp.MustCompile(path.Join(membership.StoreMembersPrefix, "[[:xdigit:]]{1,16}", "attributes"))
 )
@@ -211,6 +221,10 @@ type EtcdServer struct {
 	committedIndex    uint64 // must use atomic operations to access; keep 64-bit aligned.
 	term              uint64 // must use atomic operations to access; keep 64-bit aligned.
 	lead              uint64 // must use atomic operations to access; keep 64-bit aligned.
+	// leaderCommittedIndex is the highest leader committed index advertised in append requests.
+	leaderCommittedIndex uint64 // must use atomic operations to access; keep 64-bit aligned.
+	// pendingApplies counts the batches of committed entries waiting to be applied.
+	pendingApplies int64 // must use atomic operations to access; keep 64-bit aligned.
 
 	consistIndex cindex.ConsistentIndexer // consistIndex is used to get/set/save consistentIndex
 	r            raftNode                 // uses 64-bit atomics; keep 64-bit aligned.
@@ -235,11 +249,23 @@ type EtcdServer struct {
 	stop chan struct{}
 	// stopping is closed by run goroutine on shutdown.
 	stopping chan struct{}
+	// proposalsStopped is closed when the server starts shutting down, failing
+	// pending and new proposals with ErrStopped.
+	proposalsStopped  chan struct{}
+	stopProposalsOnce sync.Once
 	// done is closed when all goroutines from start() complete.
 	done chan struct{}
 	// leaderChanged is used to notify the linearizable read loop to drop the old read requests.
 	leaderChanged *notify.Notifier
 
+	// raftStateC queues raft state transitions for the registered observers.
+	raftStateC           chan raftStateChange
+	raftStateObserversMu sync.RWMutex
+	raftStateObservers   []*RaftStateObserver
+
+	compactionObserversMu sync.RWMutex
+	compactionObservers   []CompactionObserver
+
 	errorc     chan error
 	memberId   types.ID
 	attributes membership.Attributes
@@ -252,6 +278,9 @@ type EtcdServer struct {
 	applyV2 ApplierV2
 
 	uberApply apply.UberApplier
+	// tracedProposals maps the IDs of proposed requests that are being traced
+	// to the span of their proposal, so that their apply is traced as its child.
+	tracedProposals sync.Map
 
 	applyWait wait.WaitTime
 
@@ -296,6 +325,19 @@ type EtcdServer struct {
 	// Should only be set within apply code path. Used to force snapshot after cluster version downgrade.
 	forceSnapshot     bool
 	corruptionChecker CorruptionChecker
+
+	// snapshotting is 1 while a raft snapshot is being saved; must use atomic operations to access.
+	snapshotting int32
+	// snapshotReqc receives the requests of TriggerSnapshot, which are served by the apply loop.
+	snapshotReqc chan chan snapshotResult
+	// rebuildingIndex is 1 while the key index is being rebuilt, during which the
+	// requests to propose are rejected; must use atomic operations to access.
+	rebuildingIndex int32
+
+	// rateLimiter limits the requests of each auth user, if configured.
+	rateLimiter *userRateLimiter
+	// keyTTLLeases tracks the leases shared by the keys put with a TTL.
+	keyTTLLeases *keyTTLLeases
 }
 
 // NewServer creates a new EtcdServer from the supplied configuration. The
@@ -326,7 +368,7 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 		snapshotter:           b.ss,
 		r:                     *b.raft.newRaftNode(b.ss, b.storage.wal.w, b.cluster.cl),
 		memberId:              b.cluster.nodeID,
-		attributes:            membership.Attributes{Name: cfg.Name, ClientURLs: cfg.ClientURLs.StringSlice()},
+		attributes:            membership.Attributes{Name: cfg.Name, ClientURLs: cfg.ClientURLs.StringSlice(), ElectionPriority: cfg.ElectionPriority},
 		cluster:               b.cluster.cl,
 		stats:                 sstats,
 		lstats:                lstats,
@@ -337,6 +379,9 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 		consistIndex:          b.storage.backend.ci,
 		firstCommitInTerm:     notify.NewNotifier(),
 		clusterVersionChanged: notify.NewNotifier(),
+		raftStateC:            make(chan raftStateChange, raftStateChangeBufferSize),
+		rateLimiter:           newUserRateLimiter(cfg.WriteRateLimitByUser),
+		keyTTLLeases:          newKeyTTLLeases(),
 	}
 	serverID.With(prometheus.Labels{"server_id": b.cluster.nodeID.String()}).Set(1)
 	srv.cluster.SetVersionChangedNotifier(srv.clusterVersionChanged)
@@ -353,6 +398,7 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 		CheckpointInterval:         cfg.LeaseCheckpointInterval,
 		CheckpointPersist:          cfg.LeaseCheckpointPersist,
 		ExpiredLeasesRetryInterval: srv.Cfg.ReqTimeout(),
+		ExpiryJitter:               cfg.LeaseExpiryJitter,
 	})
 
 	tp, err := auth.NewTokenProvider(cfg.Logger, cfg.AuthToken,
@@ -369,11 +415,20 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 	mvccStoreConfig := mvcc.StoreConfig{
 		CompactionBatchLimit:    cfg.CompactionBatchLimit,
 		CompactionSleepInterval: cfg.CompactionSleepInterval,
+		CompactionSleep:         cfg.CompactionSleep,
+		HotKeyTracking:          cfg.HotKeyTracking,
+		ReadCacheBytes:          cfg.ReadCacheBytes,
+		CompactionNotify:        srv.notifyCompactionObservers,
+		MaxWatchStreamsPerConn:  int(cfg.MaxWatchStreamsPerConnection),
 	}
 	srv.kv = mvcc.New(srv.Logger(), srv.be, srv.lessor, mvccStoreConfig)
 	srv.corruptionChecker = newCorruptionChecker(cfg.Logger, srv, srv.kv.HashStorage())
 
-	srv.authStore = auth.NewAuthStore(srv.Logger(), schema.NewAuthBackend(srv.Logger(), srv.be), tp, int(cfg.BcryptCost))
+	as := auth.NewAuthStore(srv.Logger(), schema.NewAuthBackend(srv.Logger(), srv.be), tp, int(cfg.BcryptCost))
+	if cfg.AuthTokenValidator != nil {
+		as.SetTokenValidator(cfg.AuthTokenValidator)
+	}
+	srv.authStore = as
 
 	newSrv := srv // since srv == nil in defer if srv is returned as nil
 	defer func() {
@@ -384,7 +439,7 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 		}
 	}()
 	if num := cfg.AutoCompactionRetention; num != 0 {
-		srv.compactor, err = v3compactor.New(cfg.Logger, cfg.AutoCompactionMode, num, srv.kv, srv)
+		srv.compactor, err = v3compactor.New(cfg.Logger, cfg.AutoCompactionMode, num, cfg.AutoCompactionPrefixRetention, srv.kv, srv)
 		if err != nil {
 			return nil, err
 		}
@@ -532,7 +587,10 @@ func (s *EtcdServer) Start() {
 	s.GoAttach(s.linearizableReadLoop)
 	s.GoAttach(s.monitorKVHash)
 	s.GoAttach(s.monitorCompactHash)
+	s.GoAttach(s.monitorDefrag)
 	s.GoAttach(s.monitorDowngrade)
+	s.GoAttach(s.monitorElectionPriority)
+	s.GoAttach(s.notifyRaftStateObservers)
 }
 
 // start prepares and starts server in a new goroutine. It is no longer safe to
@@ -563,6 +621,8 @@ func (s *EtcdServer) start() {
 	s.done = make(chan struct{})
 	s.stop = make(chan struct{})
 	s.stopping = make(chan struct{}, 1)
+	s.snapshotReqc = make(chan chan snapshotResult)
+	s.proposalsStopped = make(chan struct{})
 	s.ctx, s.cancel = context.WithCancel(context.Background())
 	s.readwaitc = make(chan struct{}, 1)
 	s.readNotifier = newNotifier()
@@ -706,6 +766,12 @@ func (s *EtcdServer) Process(ctx context.Context, m raftpb.Message) error {
 	}
 	if m.Type == raftpb.MsgApp {
 		s.stats.RecvAppendReq(types.ID(m.From).String(), m.Size())
+		s.setLeaderCommittedIndex(m.Commit)
+		if m.Term < s.getTerm() {
+			// raft ignores the entries of a leader deposed since, which
+			// steps down once it learns of the newer term from the response
+			appendEntriesRejectedStaleTerm.Inc()
+		}
 	}
 	return s.r.Step(ctx, m)
 }
@@ -735,6 +801,7 @@ type raftReadyHandler struct {
 	updateLead           func(lead uint64)
 	updateLeadership     func(newLeader bool)
 	updateCommittedIndex func(uint64)
+	updateRaftState      func(from, to raft.StateType, term uint64)
 }
 
 func (s *EtcdServer) run() {
@@ -766,6 +833,14 @@ func (s *EtcdServer) run() {
 	rh := &raftReadyHandler{
 		getLead:    func() (lead uint64) { return s.getLead() },
 		updateLead: func(lead uint64) { s.setLead(lead) },
+		updateRaftState: func(from, to raft.StateType, term uint64) {
+			select {
+			case s.raftStateC <- raftStateChange{from: from, to: to, term: term}:
+			default:
+				raftStateChangesDropped.Inc()
+				lg.Warn("dropped raft state change notification", zap.Stringer("from", from), zap.Stringer("to", to), zap.Uint64("term", term))
+			}
+		},
 		updateLeadership: func(newLeader bool) {
 			if !s.isLeader() {
 				if s.lessor != nil {
@@ -800,6 +875,7 @@ func (s *EtcdServer) run() {
 			cci := s.getCommittedIndex()
 			if ci > cci {
 				s.setCommittedIndex(ci)
+				s.updateApplyBacklog()
 			}
 		},
 	}
@@ -813,6 +889,7 @@ func (s *EtcdServer) run() {
 	}
 
 	defer func() {
+		s.StopProposals()
 		s.wgMu.Lock() // block concurrent waitgroup adds in GoAttach while stopping
 		close(s.stopping)
 		s.wgMu.Unlock()
@@ -841,7 +918,14 @@ func (s *EtcdServer) run() {
 	for {
 		select {
 		case ap := <-s.r.apply():
-			f := schedule.NewJob("server_applyAll", func(context.Context) { s.applyAll(&ep, &ap) })
+			applyPending.Set(float64(atomic.AddInt64(&s.pendingApplies, 1)))
+			f := schedule.NewJob("server_applyAll", func(context.Context) {
+				applyPending.Set(float64(atomic.AddInt64(&s.pendingApplies, -1)))
+				s.applyAll(&ep, &ap)
+			})
+			sched.Schedule(f)
+		case resultc := <-s.snapshotReqc:
+			f := schedule.NewJob("server_snapshotNow", func(context.Context) { resultc <- s.snapshotNow(&ep) })
 			sched.Schedule(f)
 		case leases := <-expiredLeaseC:
 			s.revokeExpiredLeases(leases)
@@ -874,7 +958,7 @@ func (s *EtcdServer) revokeExpiredLeases(leases []*lease.Lease) {
 			f := func(lid int64) {
 				s.GoAttach(func() {
 					ctx := s.authStore.WithRoot(s.ctx)
-					_, lerr := s.LeaseRevoke(ctx, &pb.LeaseRevokeRequest{ID: lid})
+					_, lerr := s.leaseRevoke(ctx, &pb.LeaseRevokeRequest{ID: lid})
 					if lerr == nil {
 						leaseExpired.Inc()
 					} else {
@@ -921,6 +1005,7 @@ func (s *EtcdServer) applyAll(ep *etcdProgress, apply *toApply) {
 	s.applyEntries(ep, apply)
 
 	proposalsApplied.Set(float64(ep.appliedi))
+	s.updateApplyBacklog()
 	s.applyWait.Trigger(ep.appliedi)
 
 	// wait for the raft routine to finish the disk writes before triggering a
@@ -1090,7 +1175,7 @@ func (s *EtcdServer) applySnapshot(ep *etcdProgress, toApply *toApply) {
 
 func (s *EtcdServer) NewUberApplier() apply.UberApplier {
 	return apply.NewUberApplier(s.lg, s.be, s.KV(), s.alarmStore, s.authStore, s.lessor, s.cluster, s, s, s.consistIndex,
-		s.Cfg.WarningApplyDuration, s.Cfg.ExperimentalTxnModeWriteWithSharedBuffer, s.Cfg.QuotaBackendBytes)
+		s.Cfg.WarningApplyDurationOf, s.Cfg.ExperimentalTxnModeWriteWithSharedBuffer, s.Cfg.QuotaBackendBytes)
 }
 
 func verifySnapshotIndex(snapshot raftpb.Snapshot, cindex uint64) {
@@ -1158,6 +1243,126 @@ func (s *EtcdServer) triggerSnapshot(ep *etcdProgress) {
 	ep.snapi = ep.appliedi
 }
 
+// snapshotResult is the outcome of a snapshot requested through TriggerSnapshot.
+type snapshotResult struct {
+	index      uint64
+	inProgress bool
+	// donec is closed once the snapshot is saved and the raft log compacted.
+	donec <-chan struct{}
+}
+
+// snapshotNow takes a snapshot at the applied index regardless of the snapshot count,
+// unless a previous snapshot is still being saved. It must run on the apply scheduler.
+func (s *EtcdServer) snapshotNow(ep *etcdProgress) snapshotResult {
+	if atomic.LoadInt32(&s.snapshotting) != 0 {
+		return snapshotResult{index: ep.snapi, inProgress: true}
+	}
+	if ep.appliedi == ep.snapi {
+		return snapshotResult{index: ep.snapi}
+	}
+	s.Logger().Info(
+		"triggering snapshot on request",
+		zap.String("local-member-id", s.MemberId().String()),
+		zap.Uint64("local-member-applied-index", ep.appliedi),
+		zap.Uint64("local-member-snapshot-index", ep.snapi),
+	)
+	donec := s.snapshot(ep.appliedi, ep.confState)
+	ep.snapi = ep.appliedi
+	return snapshotResult{index: ep.snapi, donec: donec}
+}
+
+// TriggerSnapshot makes the local member take a raft snapshot of its applied state
+// and compact its raft log, and returns the index of the snapshot. If a snapshot is
+// already being saved, it returns the index of the last snapshot with inProgress set.
+func (s *EtcdServer) TriggerSnapshot(ctx context.Context) (index uint64, inProgress bool, err error) {
+	resultc := make(chan snapshotResult, 1)
+	select {
+	case s.snapshotReqc <- resultc:
+	case <-ctx.Done():
+		return 0, false, ctx.Err()
+	case <-s.stopping:
+		return 0, false, errors.ErrStopped
+	}
+
+	var r snapshotResult
+	select {
+	case r = <-resultc:
+	case <-ctx.Done():
+		return 0, false, ctx.Err()
+	case <-s.stopping:
+		return 0, false, errors.ErrStopped
+	}
+	if r.donec != nil {
+		select {
+		case <-r.donec:
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
+		case <-s.stopping:
+			return 0, false, errors.ErrStopped
+		}
+	}
+	return r.index, r.inProgress, nil
+}
+
+// RebuildIndex rebuilds the key index of the local member from its backend, and
+// returns the revision it was rebuilt at along with the revisions the replaced
+// index was missing or had in excess. While the index is rebuilt, the member
+// rejects the requests to propose with ErrRebuildingIndex, while the writes
+// proposed through the other members are still committed and the entries the
+// member applies wait. It is refused on the leader unless force is set, in
+// which case the leadership is first transferred to another voting member; if
+// the transfer fails, the waiting applies stall the leader, which then loses
+// the leadership to an election.
+func (s *EtcdServer) RebuildIndex(ctx context.Context, force bool) (rev int64, anomalies []mvcc.Anomaly, err error) {
+	if s.isLeader() {
+		if !force {
+			return 0, nil, errors.ErrRebuildIndexOnLeader
+		}
+		if s.hasMultipleVotingMembers() {
+			if terr := s.transferLeadershipBeforeRebuild(ctx); terr != nil {
+				s.Logger().Warn(
+					"failed to transfer leadership before rebuilding the key index; an election will follow",
+					zap.String("local-member-id", s.MemberId().String()),
+					zap.Error(terr),
+				)
+			}
+		}
+	}
+	if !atomic.CompareAndSwapInt32(&s.rebuildingIndex, 0, 1) {
+		return 0, nil, errors.ErrRebuildingIndex
+	}
+	type rebuildResult struct {
+		rev       int64
+		anomalies []mvcc.Anomaly
+		err       error
+	}
+	resc := make(chan rebuildResult, 1)
+	// the writes are rejected until the rebuild is done, which may outlive ctx
+	go func() {
+		defer atomic.StoreInt32(&s.rebuildingIndex, 0)
+		rev, anomalies, err := s.KV().RebuildIndex(ctx)
+		resc <- rebuildResult{rev, anomalies, err}
+	}()
+	select {
+	case r := <-resc:
+		return r.rev, r.anomalies, r.err
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
+// transferLeadershipBeforeRebuild transfers the leadership of the local member
+// to the longest connected voting member.
+func (s *EtcdServer) transferLeadershipBeforeRebuild(ctx context.Context) error {
+	transferee, ok := longestConnected(s.r.transport, s.cluster.VotingMemberIDs())
+	if !ok {
+		return errors.ErrUnhealthy
+	}
+	tctx, cancel := context.WithTimeout(ctx, s.Cfg.ReqTimeout())
+	defer cancel()
+	return s.MoveLeader(tctx, s.Lead(), uint64(transferee))
+}
+
 func (s *EtcdServer) shouldSnapshot(ep *etcdProgress) bool {
 	return (s.forceSnapshot && ep.appliedi != ep.snapi) || (ep.appliedi-ep.snapi > s.Cfg.SnapshotCount)
 }
@@ -1258,12 +1463,26 @@ func (s *EtcdServer) HardStop() {
 // Do and Process cannot be called after Stop has been invoked.
 func (s *EtcdServer) Stop() {
 	lg := s.Logger()
+	s.StopProposals()
 	if err := s.TryTransferLeadershipOnShutdown(); err != nil {
 		lg.Warn("leadership transfer failed", zap.String("local-member-id", s.MemberId().String()), zap.Error(err))
 	}
 	s.HardStop()
 }
 
+// StopProposals fails pending and new proposals with ErrStopped, instead of
+// letting them wait for the request timeout while the server shuts down.
+// Stop and HardStop call it; it can be called earlier to release clients
+// before shutting down the client listeners.
+func (s *EtcdServer) StopProposals() {
+	s.stopProposalsOnce.Do(func() {
+		// servers that were never started have no proposals to stop
+		if s.proposalsStopped != nil {
+			close(s.proposalsStopped)
+		}
+	})
+}
+
 // ReadyNotify returns a channel that will be closed when the server
 // is ready to serve client requests
 func (s *EtcdServer) ReadyNotify() <-chan struct{} { return s.readych }
@@ -1343,6 +1562,16 @@ func (s *EtcdServer) mayAddMember(memb membership.Member) error {
 		return nil
 	}
 
+	if s.Leader() == types.ID(raft.None) {
+		lg.Warn(
+			"rejecting member add request; cluster has no leader",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.String("requested-member-add", fmt.Sprintf("%+v", memb)),
+			zap.Error(errors.ErrNoLeader),
+		)
+		return errors.ErrNoLeader
+	}
+
 	// protect quorum when adding voting member
 	if !memb.IsLearner && !s.cluster.IsReadyToAddVotingMember() {
 		lg.Warn(
@@ -1367,16 +1596,60 @@ func (s *EtcdServer) mayAddMember(memb membership.Member) error {
 	return nil
 }
 
+// RemoveMemberOptions changes how RemoveMemberWithOptions removes a member.
+type RemoveMemberOptions struct {
+	// LeadershipTransfer, if the member is the leader, first transfers the
+	// leadership to a healthy voting member, so that the removal does not
+	// leave the cluster without a leader until an election completes.
+	LeadershipTransfer bool
+	// Force skips the health checks of StrictReconfigCheck, so that a member
+	// whose host is gone for good can be removed while the other members are
+	// not all known to be healthy. The removal is still rejected if the
+	// remaining voting members connected to the local member do not form a
+	// quorum.
+	Force bool
+}
+
 func (s *EtcdServer) RemoveMember(ctx context.Context, id uint64) ([]*membership.Member, error) {
+	return s.removeMember(ctx, id, RemoveMemberOptions{})
+}
+
+// RemoveMemberWithOptions removes the given member like RemoveMember, changed
+// by opts.
+func (s *EtcdServer) RemoveMemberWithOptions(ctx context.Context, id uint64, opts RemoveMemberOptions) ([]*membership.Member, error) {
+	return s.removeMember(ctx, id, opts)
+}
+
+// RemoveMemberByName removes the member with the given name from the cluster
+// like RemoveMemberWithOptions.
+// It returns ErrNameNotFound if no member has the name, or ErrNameAmbiguous if
+// more than one member has it.
+func (s *EtcdServer) RemoveMemberByName(ctx context.Context, name string, opts RemoveMemberOptions) ([]*membership.Member, error) {
+	id, err := s.cluster.MemberIDByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.removeMember(ctx, uint64(id), opts)
+}
+
+func (s *EtcdServer) removeMember(ctx context.Context, id uint64, opts RemoveMemberOptions) ([]*membership.Member, error) {
 	if err := s.checkMembershipOperationPermission(ctx); err != nil {
 		return nil, err
 	}
 
-	// by default StrictReconfigCheck is enabled; reject removal if leads to quorum loss
-	if err := s.mayRemoveMember(types.ID(id)); err != nil {
+	if opts.Force {
+		if err := s.mayForceRemoveMember(types.ID(id)); err != nil {
+			return nil, err
+		}
+	} else if err := s.mayRemoveMember(types.ID(id)); err != nil {
+		// by default StrictReconfigCheck is enabled; reject removal if leads to quorum loss
 		return nil, err
 	}
 
+	if opts.LeadershipTransfer && s.Lead() == id {
+		s.transferLeadershipBeforeRemoval(ctx, types.ID(id), []types.ID{types.ID(id)})
+	}
+
 	cc := raftpb.ConfChange{
 		Type:   raftpb.ConfChangeRemoveNode,
 		NodeID: id,
@@ -1384,6 +1657,60 @@ func (s *EtcdServer) RemoveMember(ctx context.Context, id uint64) ([]*membership
 	return s.configure(ctx, cc)
 }
 
+// transferLeadershipBeforeRemoval transfers the leadership from the leader id,
+// which is about to be removed, to the voting member longest connected to the
+// local member among those not in removed, the members removed along with id,
+// or to the local member itself if it is kept and no other one is connected.
+// A failed transfer is only logged, since the removal still succeeds with an
+// election afterwards.
+func (s *EtcdServer) transferLeadershipBeforeRemoval(ctx context.Context, id types.ID, removed []types.ID) {
+	lg := s.Logger()
+	var candidates []types.ID
+	for _, vid := range s.cluster.VotingMemberIDs() {
+		isRemoved := false
+		for _, rid := range removed {
+			if vid == rid {
+				isRemoved = true
+				break
+			}
+		}
+		if !isRemoved {
+			candidates = append(candidates, vid)
+		}
+	}
+	transferee, ok := longestConnected(s.r.transport, candidates)
+	if !ok {
+		// the transport has no peer for the local member, which is a healthy
+		// transferee if it is kept, as when a member of two removes the leader
+		for _, cid := range candidates {
+			if cid == s.MemberId() {
+				transferee, ok = cid, true
+				break
+			}
+		}
+	}
+	if !ok {
+		lg.Warn(
+			"skipped leadership transfer before removing leader; no healthy transferee",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.String("requested-member-remove-id", id.String()),
+		)
+		return
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, s.Cfg.ReqTimeout())
+	defer cancel()
+	if err := s.MoveLeader(cctx, uint64(id), uint64(transferee)); err != nil {
+		lg.Warn(
+			"leadership transfer before removing leader failed",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.String("requested-member-remove-id", id.String()),
+			zap.String("transferee-member-id", transferee.String()),
+			zap.Error(err),
+		)
+	}
+}
+
 // PromoteMember promotes a learner node to a voting node.
 func (s *EtcdServer) PromoteMember(ctx context.Context, id uint64) ([]*membership.Member, error) {
 	// only raft leader has information on whether the to-be-promoted learner node is ready. If promoteMember call
@@ -1413,7 +1740,7 @@ func (s *EtcdServer) PromoteMember(ctx context.Context, id uint64) ([]*membershi
 				return resp, nil
 			}
 			// If member promotion failed, return early. Otherwise keep retry.
-			if err == errors.ErrLearnerNotReady || err == membership.ErrIDNotFound || err == membership.ErrMemberNotLearner {
+			if err == errors.ErrLearnerNotReady || err == membership.ErrIDNotFound || err == membership.ErrMemberNotLearner || err == membership.ErrMemberNotPromotable {
 				return nil, err
 			}
 		}
@@ -1465,6 +1792,15 @@ func (s *EtcdServer) promoteMember(ctx context.Context, id uint64) ([]*membershi
 
 func (s *EtcdServer) mayPromoteMember(id types.ID) error {
 	lg := s.Logger()
+	if m := s.cluster.Member(id); m != nil && m.NonPromotable {
+		lg.Warn(
+			"rejecting member promote request; member is not promotable",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.String("requested-member-promote-id", id.String()),
+			zap.Error(membership.ErrMemberNotPromotable),
+		)
+		return membership.ErrMemberNotPromotable
+	}
 	err := s.isLearnerReady(uint64(id))
 	if err != nil {
 		return err
@@ -1534,6 +1870,16 @@ func (s *EtcdServer) mayRemoveMember(id types.ID) error {
 	}
 
 	lg := s.Logger()
+	if s.Leader() == types.ID(raft.None) {
+		lg.Warn(
+			"rejecting member remove request; cluster has no leader",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.String("requested-member-remove", id.String()),
+			zap.Error(errors.ErrNoLeader),
+		)
+		return errors.ErrNoLeader
+	}
+
 	isLearner := s.cluster.IsMemberExist(id) && s.cluster.Member(id).IsLearner
 	// no need to check quorum when removing non-voting member
 	if isLearner {
@@ -1572,6 +1918,166 @@ func (s *EtcdServer) mayRemoveMember(id types.ID) error {
 	return nil
 }
 
+// mayForceRemoveMember rejects the forced removal of the member id if the
+// remaining voting members connected to the local member for HealthInterval,
+// including itself, would not form a quorum of the remaining voting members.
+// Unlike mayRemoveMember, it does not count the member id itself, whether it is
+// up or down, nor require the members to have published their attributes.
+func (s *EtcdServer) mayForceRemoveMember(id types.ID) error {
+	lg := s.Logger()
+	if s.Leader() == types.ID(raft.None) {
+		lg.Warn(
+			"rejecting forced member remove request; cluster has no leader",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.String("requested-member-remove", id.String()),
+			zap.Error(errors.ErrNoLeader),
+		)
+		return errors.ErrNoLeader
+	}
+
+	// removing a learner leaves the voting members as they are
+	var remaining []*membership.Member
+	for _, m := range s.cluster.VotingMembers() {
+		if m.ID != id {
+			remaining = append(remaining, m)
+		}
+	}
+	active := numConnectedSince(s.r.transport, time.Now().Add(-HealthInterval), s.MemberId(), remaining)
+	if active < 1+len(remaining)/2 {
+		lg.Warn(
+			"rejecting forced member remove request; remaining active members do not form a quorum",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.String("requested-member-remove", id.String()),
+			zap.Int("active-peers", active),
+			zap.Int("remaining-voting-members", len(remaining)),
+			zap.Error(errors.ErrUnhealthy),
+		)
+		return errors.ErrUnhealthy
+	}
+
+	lg.Warn(
+		"forcibly removing member; skipping member health checks",
+		zap.String("local-member-id", s.MemberId().String()),
+		zap.String("requested-member-remove", id.String()),
+		zap.Int("active-peers", active),
+		zap.Int("remaining-voting-members", len(remaining)),
+	)
+	return nil
+}
+
+// ReconfigureMembers adds the members adds and removes the members with the
+// IDs removes in a single configuration change. Raft applies the change
+// through a joint configuration, in which the decisions need a quorum of both
+// the current and the new voting members, so that no intermediate set of
+// voting members is ever used. The cluster leaves the joint configuration
+// right after the change is applied.
+//
+// The change is rejected if the voting members kept by the change and
+// connected to the local member would not form a quorum of both the current
+// and the new voting members, since the removed members stop once they apply
+// the change and the added members are not started yet. If the leader is
+// removed, the leadership is first transferred to a kept voting member.
+func (s *EtcdServer) ReconfigureMembers(ctx context.Context, adds []membership.Member, removes []uint64) ([]*membership.Member, error) {
+	if err := s.checkMembershipOperationPermission(ctx); err != nil {
+		return nil, err
+	}
+	if len(adds) == 0 && len(removes) == 0 {
+		return nil, membership.ErrNoChange
+	}
+
+	if err := s.mayReconfigureMembers(adds, removes); err != nil {
+		return nil, err
+	}
+
+	removedIDs := make([]types.ID, len(removes))
+	for i, id := range removes {
+		removedIDs[i] = types.ID(id)
+	}
+	for _, id := range removedIDs {
+		if uint64(id) == s.Lead() {
+			s.transferLeadershipBeforeRemoval(ctx, id, removedIDs)
+			break
+		}
+	}
+
+	rc := membership.ReconfigureContext{ID: s.reqIDGen.Next(), Members: adds}
+	b, err := json.Marshal(rc)
+	if err != nil {
+		return nil, err
+	}
+	cc := raftpb.ConfChangeV2{
+		Transition: raftpb.ConfChangeTransitionAuto,
+		Context:    b,
+	}
+	for _, m := range adds {
+		typ := raftpb.ConfChangeAddNode
+		if m.IsLearner {
+			typ = raftpb.ConfChangeAddLearnerNode
+		}
+		cc.Changes = append(cc.Changes, raftpb.ConfChangeSingle{Type: typ, NodeID: uint64(m.ID)})
+	}
+	for _, id := range removes {
+		cc.Changes = append(cc.Changes, raftpb.ConfChangeSingle{Type: raftpb.ConfChangeRemoveNode, NodeID: id})
+	}
+	return s.proposeConfChange(ctx, rc.ID, cc, zap.String("raft-conf-changes", raftpb.ConfChangesToString(cc.Changes)))
+}
+
+// mayReconfigureMembers rejects the joint configuration change adding adds and
+// removing removes if the kept voting members connected to the local member,
+// including itself, would not form a quorum of both the current and the new
+// voting members. The check applies whatever StrictReconfigCheck, which only
+// makes it require the members to have been connected for HealthInterval.
+func (s *EtcdServer) mayReconfigureMembers(adds []membership.Member, removes []uint64) error {
+	lg := s.Logger()
+	if s.Leader() == types.ID(raft.None) {
+		lg.Warn(
+			"rejecting member reconfigure request; cluster has no leader",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.Error(errors.ErrNoLeader),
+		)
+		return errors.ErrNoLeader
+	}
+
+	removed := make(map[types.ID]bool)
+	for _, id := range removes {
+		if !s.cluster.IsMemberExist(types.ID(id)) {
+			return membership.ErrIDNotFound
+		}
+		removed[types.ID(id)] = true
+	}
+	voters := s.cluster.VotingMembers()
+	var kept []*membership.Member
+	for _, m := range voters {
+		if !removed[m.ID] {
+			kept = append(kept, m)
+		}
+	}
+	newVoters := len(kept)
+	for _, m := range adds {
+		if !m.IsLearner {
+			newVoters++
+		}
+	}
+
+	since := time.Now()
+	if s.Cfg.StrictReconfigCheck {
+		since = since.Add(-HealthInterval)
+	}
+	active := numConnectedSince(s.r.transport, since, s.MemberId(), kept)
+	if active < 1+len(voters)/2 || active < 1+newVoters/2 {
+		lg.Warn(
+			"rejecting member reconfigure request; kept active members do not form a quorum of the joint configuration",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.Int("active-peers", active),
+			zap.Int("voting-members", len(voters)),
+			zap.Int("new-voting-members", newVoters),
+			zap.Error(errors.ErrUnhealthy),
+		)
+		return errors.ErrUnhealthy
+	}
+	return nil
+}
+
 func (s *EtcdServer) UpdateMember(ctx context.Context, memb membership.Member) ([]*membership.Member, error) {
 	b, merr := json.Marshal(memb)
 	if merr != nil {
@@ -1605,6 +2111,44 @@ func (s *EtcdServer) getAppliedIndex() uint64 {
 	return atomic.LoadUint64(&s.appliedIndex)
 }
 
+// applyBacklog returns the number of committed entries not yet applied.
+func (s *EtcdServer) applyBacklog() uint64 {
+	ai, ci := s.getAppliedIndex(), s.getCommittedIndex()
+	if ci < ai {
+		// the applied index of a restored snapshot may be ahead
+		return 0
+	}
+	return ci - ai
+}
+
+// updateApplyBacklog updates the apply backlog gauge. It is called as
+// entries are committed and applied, so the gauge drops back to zero once
+// the server catches up and goes idle.
+func (s *EtcdServer) updateApplyBacklog() {
+	applyBacklog.Set(float64(s.applyBacklog()))
+}
+
+func (s *EtcdServer) maxApplyBacklog() uint64 {
+	if s.Cfg.MaxApplyBacklog == 0 {
+		return DefaultMaxApplyBacklog
+	}
+	return s.Cfg.MaxApplyBacklog
+}
+
+// setLeaderCommittedIndex records v if it is higher than the recorded leader committed index.
+func (s *EtcdServer) setLeaderCommittedIndex(v uint64) {
+	for {
+		old := atomic.LoadUint64(&s.leaderCommittedIndex)
+		if v <= old || atomic.CompareAndSwapUint64(&s.leaderCommittedIndex, old, v) {
+			return
+		}
+	}
+}
+
+func (s *EtcdServer) getLeaderCommittedIndex() uint64 {
+	return atomic.LoadUint64(&s.leaderCommittedIndex)
+}
+
 func (s *EtcdServer) setTerm(v uint64) {
 	atomic.StoreUint64(&s.term, v)
 }
@@ -1655,13 +2199,23 @@ type confChangeResponse struct {
 // then waits for it to be applied to the server. It
 // will block until the change is performed or there is an error.
 func (s *EtcdServer) configure(ctx context.Context, cc raftpb.ConfChange) ([]*membership.Member, error) {
-	lg := s.Logger()
 	cc.ID = s.reqIDGen.Next()
-	ch := s.w.Register(cc.ID)
+	return s.proposeConfChange(ctx, cc.ID, cc,
+		zap.String("raft-conf-change", cc.Type.String()),
+		zap.String("raft-conf-change-node-id", types.ID(cc.NodeID).String()),
+	)
+}
+
+// proposeConfChange sends the configuration change cc, identified by id,
+// through consensus and waits for it to be applied like configure. fields
+// describe cc in the logs.
+func (s *EtcdServer) proposeConfChange(ctx context.Context, id uint64, cc raftpb.ConfChangeI, fields ...zap.Field) ([]*membership.Member, error) {
+	lg := s.Logger()
+	ch := s.w.Register(id)
 
 	start := time.Now()
 	if err := s.r.ProposeConfChange(ctx, cc); err != nil {
-		s.w.Trigger(cc.ID, nil)
+		s.w.Trigger(id, nil)
 		return nil, err
 	}
 
@@ -1678,14 +2232,12 @@ func (s *EtcdServer) configure(ctx context.Context, cc raftpb.ConfChange) ([]*me
 		<-resp.raftAdvanceC
 		lg.Info(
 			"applied a configuration change through raft",
-			zap.String("local-member-id", s.MemberId().String()),
-			zap.String("raft-conf-change", cc.Type.String()),
-			zap.String("raft-conf-change-node-id", types.ID(cc.NodeID).String()),
+			append([]zap.Field{zap.String("local-member-id", s.MemberId().String())}, fields...)...,
 		)
 		return resp.membs, resp.err
 
 	case <-ctx.Done():
-		s.w.Trigger(cc.ID, nil) // GC wait
+		s.w.Trigger(id, nil) // GC wait
 		return nil, s.parseProposeCtxErr(ctx.Err(), start)
 
 	case <-s.stopping:
@@ -1721,8 +2273,9 @@ func (s *EtcdServer) publishV3(timeout time.Duration) {
 	req := &membershippb.ClusterMemberAttrSetRequest{
 		Member_ID: uint64(s.MemberId()),
 		MemberAttributes: &membershippb.Attributes{
-			Name:       s.attributes.Name,
-			ClientUrls: s.attributes.ClientURLs,
+			Name:             s.attributes.Name,
+			ClientUrls:       s.attributes.ClientURLs,
+			ElectionPriority: s.attributes.ElectionPriority,
 		},
 	}
 	lg := s.Logger()
@@ -1839,18 +2392,37 @@ func (s *EtcdServer) apply(
 				shouldApplyV3 = membership.ApplyBoth
 			}
 
+			start := time.Now()
 			var cc raftpb.ConfChange
 			pbutil.MustUnmarshal(&cc, e.Data)
 			removedSelf, err := s.applyConfChange(cc, confState, shouldApplyV3)
+			applyEntryConfigSec.Observe(time.Since(start).Seconds())
 			s.setAppliedIndex(e.Index)
 			s.setTerm(e.Term)
 			shouldStop = shouldStop || removedSelf
 			s.w.Trigger(cc.ID, &confChangeResponse{s.cluster.Members(), raftAdvancedC, err})
 
+		case raftpb.EntryConfChangeV2:
+			shouldApplyV3 := membership.ApplyV2storeOnly
+			if e.Index > s.consistIndex.ConsistentIndex() {
+				s.consistIndex.SetConsistentApplyingIndex(e.Index, e.Term)
+				shouldApplyV3 = membership.ApplyBoth
+			}
+
+			start := time.Now()
+			var cc raftpb.ConfChangeV2
+			pbutil.MustUnmarshal(&cc, e.Data)
+			id, removedSelf, err := s.applyConfChangeV2(cc, confState, shouldApplyV3)
+			applyEntryConfigSec.Observe(time.Since(start).Seconds())
+			s.setAppliedIndex(e.Index)
+			s.setTerm(e.Term)
+			shouldStop = shouldStop || removedSelf
+			s.w.Trigger(id, &confChangeResponse{s.cluster.Members(), raftAdvancedC, err})
+
 		default:
 			lg := s.Logger()
 			lg.Panic(
-				"unknown entry type; must be either EntryNormal or EntryConfChange",
+				"unknown entry type; must be either EntryNormal, EntryConfChange or EntryConfChangeV2",
 				zap.String("type", e.Type.String()),
 			)
 		}
@@ -1861,6 +2433,7 @@ func (s *EtcdServer) apply(
 
 // applyEntryNormal applies an EntryNormal type raftpb request to the EtcdServer
 func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
+	start := time.Now()
 	shouldApplyV3 := membership.ApplyV2storeOnly
 	var ar *apply.Result
 	index := s.consistIndex.ConsistentIndex()
@@ -1896,6 +2469,9 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 	}
 
 	var raftReq pb.InternalRaftRequest
+	defer func() {
+		applyEntryObserver(&raftReq).Observe(time.Since(start).Seconds())
+	}()
 	if !pbutil.MaybeUnmarshal(&raftReq, e.Data) { // backward compatible
 		var r pb.Request
 		rp := &r
@@ -1925,7 +2501,9 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 		if !needResult && raftReq.Txn != nil {
 			removeNeedlessRangeReqs(raftReq.Txn)
 		}
-		ar = s.uberApply.Apply(&raftReq, shouldApplyV3)
+		ctx, span := s.startApplySpan(id)
+		ar = s.uberApply.Apply(ctx, &raftReq, shouldApplyV3)
+		span.End()
 	}
 
 	// do not re-toApply applied entries.
@@ -1961,6 +2539,17 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 	})
 }
 
+// startApplySpan starts the span of applying the request with the given ID
+// as a child of the span of its proposal, if the proposal is being traced by
+// this member.
+func (s *EtcdServer) startApplySpan(id uint64) (context.Context, trace.Span) {
+	ctx := context.TODO()
+	if span, ok := s.tracedProposals.LoadAndDelete(id); ok {
+		ctx = trace.ContextWithSpan(ctx, span.(trace.Span))
+	}
+	return txn.StartSpan(ctx, "apply")
+}
+
 func noSideEffect(r *pb.InternalRaftRequest) bool {
 	return r.Range != nil || r.AuthUserGet != nil || r.AuthRoleGet != nil || r.AuthStatus != nil
 }
@@ -1992,10 +2581,7 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 
 		// The txPostLock callback will not get called in this case,
 		// so we should set the consistent index directly.
-		if s.consistIndex != nil && membership.ApplyBoth == shouldApplyV3 {
-			applyingIndex, applyingTerm := s.consistIndex.ConsistentApplyingIndex()
-			s.consistIndex.SetConsistentIndex(applyingIndex, applyingTerm)
-		}
+		s.setConsistentIndexWithoutTx(shouldApplyV3)
 		return false, err
 	}
 
@@ -2062,8 +2648,78 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 	return false, nil
 }
 
+// applyConfChangeV2 applies a joint ConfChangeV2 to the server, or the
+// ConfChangeV2 leaving the joint configuration. It is only invoked with a
+// ConfChangeV2 that has already passed through Raft. It returns the ID of the
+// change, which is zero when leaving the joint configuration, and whether the
+// local member is removed.
+func (s *EtcdServer) applyConfChangeV2(cc raftpb.ConfChangeV2, confState *raftpb.ConfState, shouldApplyV3 membership.ShouldApplyV3) (uint64, bool, error) {
+	lg := s.Logger()
+	if cc.LeaveJoint() {
+		*confState = *s.r.ApplyConfChange(cc)
+		s.beHooks.SetConfState(confState)
+		// no member is changed, so no transaction sets the consistent index
+		s.setConsistentIndexWithoutTx(shouldApplyV3)
+		return 0, false, nil
+	}
+
+	rc := new(membership.ReconfigureContext)
+	if err := json.Unmarshal(cc.Context, rc); err != nil {
+		lg.Panic("failed to unmarshal reconfigure context", zap.Error(err))
+	}
+	if err := s.cluster.ValidateConfigurationChangeV2(cc, rc); err != nil {
+		s.r.ApplyConfChange(raftpb.ConfChange{NodeID: raft.None})
+		s.setConsistentIndexWithoutTx(shouldApplyV3)
+		return rc.ID, false, err
+	}
+
+	*confState = *s.r.ApplyConfChange(cc)
+	s.beHooks.SetConfState(confState)
+	removedSelf := false
+	added := 0
+	for _, ch := range cc.Changes {
+		id := types.ID(ch.NodeID)
+		switch ch.Type {
+		case raftpb.ConfChangeAddNode, raftpb.ConfChangeAddLearnerNode:
+			m := rc.Members[added]
+			added++
+			s.cluster.AddMember(&m, shouldApplyV3)
+			if id == s.MemberId() {
+				if ch.Type == raftpb.ConfChangeAddLearnerNode {
+					isLearner.Set(1)
+				} else {
+					isLearner.Set(0)
+				}
+			} else {
+				s.r.transport.AddPeer(id, m.PeerURLs)
+			}
+
+		case raftpb.ConfChangeRemoveNode:
+			s.cluster.RemoveMember(id, shouldApplyV3)
+			if id == s.MemberId() {
+				removedSelf = true
+			} else {
+				s.r.transport.RemovePeer(id)
+			}
+		}
+	}
+	return rc.ID, removedSelf, nil
+}
+
+// setConsistentIndexWithoutTx moves the consistent index to the entry being
+// applied, for the entries whose application writes nothing to the backend
+// and so does not get the txPostLock callback to do it.
+func (s *EtcdServer) setConsistentIndexWithoutTx(shouldApplyV3 membership.ShouldApplyV3) {
+	if s.consistIndex != nil && membership.ApplyBoth == shouldApplyV3 {
+		applyingIndex, applyingTerm := s.consistIndex.ConsistentApplyingIndex()
+		s.consistIndex.SetConsistentIndex(applyingIndex, applyingTerm)
+	}
+}
+
 // TODO: non-blocking snapshot
-func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
+// snapshot saves a snapshot at snapi in the background and compacts the raft log.
+// The returned channel is closed once the background work is finished.
+func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) <-chan struct{} {
 	d := GetMembershipInfoInV2Format(s.Logger(), s.cluster)
 	// commit kv to write metadata (for example: consistent index) to disk.
 	//
@@ -2076,7 +2732,13 @@ func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
 	// the go routine created below.
 	s.KV().Commit()
 
+	donec := make(chan struct{})
+	atomic.StoreInt32(&s.snapshotting, 1)
 	s.GoAttach(func() {
+		defer func() {
+			atomic.StoreInt32(&s.snapshotting, 0)
+			close(donec)
+		}()
 		lg := s.Logger()
 
 		// For backward compatibility, generate v2 snapshot from v3 state.
@@ -2135,6 +2797,7 @@ func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
 			zap.Uint64("compact-index", compacti),
 		)
 	})
+	return donec
 }
 
 // CutPeer drops messages to the specified peer.
@@ -2266,6 +2929,70 @@ func (s *EtcdServer) monitorCompactHash() {
 	}
 }
 
+// monitorDefrag defragments the backend incrementally on the configured
+// schedule, skipping the leader unless DefragOnLeader is set.
+func (s *EtcdServer) monitorDefrag() {
+	t, threshold := s.Cfg.DefragInterval, int64(s.Cfg.DefragThresholdMegabytes)*1024*1024
+	if t == 0 && threshold == 0 {
+		return
+	}
+	if t == 0 {
+		t = defragCheckInterval
+	}
+
+	lg := s.Logger()
+	lg.Info(
+		"enabled scheduled defragmentation",
+		zap.String("local-member-id", s.MemberId().String()),
+		zap.Duration("interval", t),
+		zap.Int64("threshold-bytes", threshold),
+		zap.Uint("rate-bytes-per-sec", s.Cfg.DefragRateBytesPerSec),
+	)
+	for {
+		select {
+		case <-time.After(t):
+		case <-s.stopping:
+			lg.Info("server has stopped; stopping defragmentation's monitor")
+			return
+		}
+		if s.isLeader() && !s.Cfg.DefragOnLeader {
+			continue
+		}
+		be := s.Backend()
+		if free := be.Size() - be.SizeInUse(); free < threshold {
+			continue
+		}
+		if err := s.defrag(be); err != nil {
+			lg.Warn("failed to defragment", zap.Error(err))
+		}
+	}
+}
+
+// defrag defragments the backend incrementally. The defragmentation is
+// aborted if the server stops, or if it becomes the leader unless
+// DefragOnLeader is set.
+func (s *EtcdServer) defrag(be backend.Backend) error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	if !s.Cfg.DefragOnLeader {
+		go func() {
+			for {
+				leaderChanged := s.leaderChanged.Receive()
+				if s.isLeader() {
+					cancel()
+					return
+				}
+				select {
+				case <-leaderChanged:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return be.IncrementalDefrag(ctx, int64(s.Cfg.DefragRateBytesPerSec))
+}
+
 func (s *EtcdServer) updateClusterVersionV2(ver string) {
 	lg := s.Logger()
 
@@ -2363,6 +3090,76 @@ func (s *EtcdServer) monitorDowngrade() {
 	}
 }
 
+// monitorElectionPriority checks once per election timeout, and whenever a new
+// term starts, whether the leader should hand its leadership over to a member
+// with a higher election priority.
+func (s *EtcdServer) monitorElectionPriority() {
+	t := time.Duration(s.Cfg.ElectionTicks) * time.Duration(s.Cfg.TickMs) * time.Millisecond
+	if t == 0 {
+		return
+	}
+	for {
+		select {
+		case <-s.firstCommitInTerm.Receive():
+		case <-time.After(t):
+		case <-s.stopping:
+			return
+		}
+
+		if !s.isLeader() {
+			continue
+		}
+		transferee, ok := s.preferredLeader()
+		if !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(s.ctx, s.Cfg.ReqTimeout())
+		err := s.MoveLeader(ctx, uint64(s.MemberId()), uint64(transferee))
+		cancel()
+		if err != nil {
+			s.Logger().Warn(
+				"leadership transfer to member with higher election priority failed",
+				zap.String("local-member-id", s.MemberId().String()),
+				zap.String("transferee-member-id", transferee.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// preferredLeader returns the voting member with the highest election
+// priority, if it is higher than the priority of the local member, the leader.
+// Only members that are connected and have caught up with the log are
+// considered. The transfer does not bypass the election: the transferee still
+// needs the votes of a quorum.
+func (s *EtcdServer) preferredLeader() (types.ID, bool) {
+	local := s.cluster.Member(s.MemberId())
+	if local == nil {
+		return 0, false
+	}
+	rs := s.raftStatus()
+	if rs.Progress == nil {
+		return 0, false
+	}
+	leaderMatch := rs.Progress[rs.ID].Match
+
+	var preferred types.ID
+	priority := local.ElectionPriority
+	for _, m := range s.cluster.VotingMembers() {
+		if m.ID == local.ID || m.ElectionPriority <= priority {
+			continue
+		}
+		if s.r.transport.ActiveSince(m.ID).IsZero() {
+			continue
+		}
+		if pr, ok := rs.Progress[uint64(m.ID)]; !ok || float64(pr.Match) < float64(leaderMatch)*readyPercent {
+			continue
+		}
+		preferred, priority = m.ID, m.ElectionPriority
+	}
+	return preferred, preferred != 0
+}
+
 func (s *EtcdServer) parseProposeCtxErr(err error, start time.Time) error {
 	switch err {
 	case context.Canceled:
@@ -2445,6 +3242,21 @@ func (s *EtcdServer) IsLearner() bool {
 	return s.cluster.IsLocalMemberLearner()
 }
 
+// LearnerProgress returns the gap between the leader committed index and the
+// applied index of the local member if it is raft learner, or 0 otherwise.
+// The leader committed index is the highest one advertised to the local member,
+// so after a leader change the gap lags until the new leader appends to it.
+func (s *EtcdServer) LearnerProgress() uint64 {
+	if !s.IsLearner() {
+		return 0
+	}
+	committed, applied := s.getLeaderCommittedIndex(), s.getAppliedIndex()
+	if committed <= applied {
+		return 0
+	}
+	return committed - applied
+}
+
 // IsMemberExist returns if the member with the given id exists in cluster.
 func (s *EtcdServer) IsMemberExist(id types.ID) bool {
 	return s.cluster.IsMemberExist(id)
@@ -2455,6 +3267,86 @@ func (s *EtcdServer) raftStatus() raft.Status {
 	return s.r.Node.Status()
 }
 
+// RaftState returns the current raft state of this etcd node.
+func (s *EtcdServer) RaftState() raft.StateType {
+	return s.raftStatus().RaftState
+}
+
+// raftStateChangeBufferSize is the number of raft state transitions queued for
+// the observers before further transitions are dropped.
+const raftStateChangeBufferSize = 128
+
+// RaftStateObserver is called on every transition of the local raft node
+// between the follower, candidate and leader states, with the term the node
+// is in after the transition.
+type RaftStateObserver func(from, to raft.StateType, term uint64)
+
+type raftStateChange struct {
+	from, to raft.StateType
+	term     uint64
+}
+
+// RegisterRaftStateObserver registers fn to be notified of raft state transitions,
+// until the returned function unregisters it. Observers are called sequentially,
+// off the raft loop; transitions happening while the observers fall behind are
+// dropped and counted.
+func (s *EtcdServer) RegisterRaftStateObserver(fn RaftStateObserver) (unregister func()) {
+	o := &fn
+	s.raftStateObserversMu.Lock()
+	defer s.raftStateObserversMu.Unlock()
+	s.raftStateObservers = append(s.raftStateObservers, o)
+	return func() {
+		s.raftStateObserversMu.Lock()
+		defer s.raftStateObserversMu.Unlock()
+		// the observers being notified keep the slice they were read from
+		observers := make([]*RaftStateObserver, 0, len(s.raftStateObservers))
+		for _, ro := range s.raftStateObservers {
+			if ro != o {
+				observers = append(observers, ro)
+			}
+		}
+		s.raftStateObservers = observers
+	}
+}
+
+func (s *EtcdServer) notifyRaftStateObservers() {
+	for {
+		select {
+		case c := <-s.raftStateC:
+			s.raftStateObserversMu.RLock()
+			observers := s.raftStateObservers
+			s.raftStateObserversMu.RUnlock()
+			for _, fn := range observers {
+				(*fn)(c.from, c.to, c.term)
+			}
+		case <-s.stopping:
+			return
+		}
+	}
+}
+
+// CompactionObserver is called with the revisions removed and retained by
+// each completed compaction of the key-value store.
+type CompactionObserver func(mvcc.CompactionResult)
+
+// RegisterCompactionObserver registers fn to be notified of completed compactions.
+// Observers are called sequentially from the compaction routine, which they
+// must not block.
+func (s *EtcdServer) RegisterCompactionObserver(fn CompactionObserver) {
+	s.compactionObserversMu.Lock()
+	defer s.compactionObserversMu.Unlock()
+	s.compactionObservers = append(s.compactionObservers, fn)
+}
+
+func (s *EtcdServer) notifyCompactionObservers(r mvcc.CompactionResult) {
+	s.compactionObserversMu.RLock()
+	observers := s.compactionObservers
+	s.compactionObserversMu.RUnlock()
+	for _, fn := range observers {
+		fn(r)
+	}
+}
+
 func (s *EtcdServer) Version() *serverversion.Manager {
 	return serverversion.NewManager(s.Logger(), NewServerVersionAdapter(s))
 }
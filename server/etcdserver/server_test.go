@@ -25,9 +25,11 @@ import (
 	"path/filepath"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
@@ -883,6 +885,34 @@ func TestDoProposalStopped(t *testing.T) {
 	}
 }
 
+// TestPutDeadlineExceeded tests that a request whose deadline has already
+// passed is rejected before it is proposed.
+func TestPutDeadlineExceeded(t *testing.T) {
+	n := newNodeRecorder()
+	wt := mockwait.NewRecorder()
+	srv := &EtcdServer{
+		lgMu:     new(sync.RWMutex),
+		lg:       zaptest.NewLogger(t),
+		Cfg:      config.ServerConfig{Logger: zaptest.NewLogger(t), TickMs: 1, SnapshotCatchUpEntries: DefaultSnapshotCatchUpEntries},
+		r:        *newRaftNode(raftNodeConfig{lg: zaptest.NewLogger(t), Node: n}),
+		w:        wt,
+		reqIDGen: idutil.NewGenerator(0, time.Time{}),
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err := srv.Put(ctx, &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar")})
+	if err != errors.ErrDeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, errors.ErrDeadlineExceeded)
+	}
+	if a := n.Action(); len(a) != 0 {
+		t.Errorf("node actions = %+v, want none", a)
+	}
+	if a := wt.Action(); len(a) != 0 {
+		t.Errorf("wait actions = %+v, want none", a)
+	}
+}
+
 // TestSync tests sync 1. is nonblocking 2. proposes SYNC request.
 func TestSync(t *testing.T) {
 	n := newNodeRecorder()
@@ -1076,6 +1106,32 @@ func TestSnapshot(t *testing.T) {
 	}
 }
 
+// TestSnapshotNowSkipped ensures a requested snapshot is not taken while
+// another snapshot is being saved, or when nothing was applied since the last one.
+func TestSnapshotNowSkipped(t *testing.T) {
+	srv := &EtcdServer{
+		lgMu: new(sync.RWMutex),
+		lg:   zaptest.NewLogger(t),
+	}
+
+	atomic.StoreInt32(&srv.snapshotting, 1)
+	ep := &etcdProgress{snapi: 5, appliedi: 10}
+	r := srv.snapshotNow(ep)
+	if !r.inProgress || r.index != 5 || r.donec != nil {
+		t.Errorf("result = %+v, want in progress at index 5", r)
+	}
+	if ep.snapi != 5 {
+		t.Errorf("snapi = %d, want 5", ep.snapi)
+	}
+
+	atomic.StoreInt32(&srv.snapshotting, 0)
+	ep = &etcdProgress{snapi: 10, appliedi: 10}
+	r = srv.snapshotNow(ep)
+	if r.inProgress || r.index != 10 || r.donec != nil {
+		t.Errorf("result = %+v, want index 10 without a new snapshot", r)
+	}
+}
+
 // TestSnapshotOrdering ensures raft persists snapshot onto disk before
 // snapshot db is applied.
 func TestSnapshotOrdering(t *testing.T) {
@@ -1336,6 +1392,191 @@ func TestConcurrentApplyAndSnapshotV3(t *testing.T) {
 	}
 }
 
+// slowApplierV2 blocks applying QGET requests until release is closed, as a
+// backend too slow to keep up with the committed entries would.
+type slowApplierV2 struct {
+	ApplierV2
+	release <-chan struct{}
+}
+
+func (a *slowApplierV2) QGet(r *RequestV2) Response {
+	<-a.release
+	return a.ApplierV2.QGet(r)
+}
+
+// TestApplyBacklogShedding tests that writes are rejected while more
+// committed entries than MaxApplyBacklog wait to be applied, that reads are
+// still served, and that the apply gauges follow the backlog.
+func TestApplyBacklogShedding(t *testing.T) {
+	lg := zaptest.NewLogger(t)
+	n := newNopReadyNode()
+	st := v2store.New()
+	cl := membership.NewCluster(lg)
+	cl.SetStore(st)
+
+	r := newRaftNode(raftNodeConfig{
+		lg:          lg,
+		Node:        n,
+		transport:   newNopTransporter(),
+		storage:     mockstorage.NewStorageRecorder(""),
+		raftStorage: raft.NewMemoryStorage(),
+	})
+	be, _ := betesting.NewDefaultTmpBackend(t)
+	ci := cindex.NewConsistentIndex(be)
+	s := &EtcdServer{
+		lgMu:         new(sync.RWMutex),
+		lg:           lg,
+		Cfg:          config.ServerConfig{Logger: lg, TickMs: 1, SnapshotCount: DefaultSnapshotCount, SnapshotCatchUpEntries: DefaultSnapshotCatchUpEntries, MaxApplyBacklog: 5},
+		r:            *r,
+		v2store:      st,
+		cluster:      cl,
+		reqIDGen:     idutil.NewGenerator(0, time.Time{}),
+		SyncTicker:   &time.Ticker{},
+		consistIndex: ci,
+		beHooks:      serverstorage.NewBackendHooks(lg, ci),
+		authStore:    auth.NewAuthStore(lg, schema.NewAuthBackend(lg, be), nil, 0),
+	}
+	release := make(chan struct{})
+	s.applyV2 = &slowApplierV2{ApplierV2: &applierV2store{store: st, cluster: cl}, release: release}
+	s.kv = mvcc.New(lg, be, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	s.be = be
+
+	s.start()
+	defer s.Stop()
+
+	waitGauges := func(pending, backlog float64) {
+		t.Helper()
+		for i := 0; ; i++ {
+			gp, gb := promtestutil.ToFloat64(applyPending), promtestutil.ToFloat64(applyBacklog)
+			if gp == pending && gb == backlog {
+				return
+			}
+			if i == 100 {
+				t.Fatalf("apply pending = %v, backlog = %v, want %v, %v", gp, gb, pending, backlog)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// commit two batches of entries; the first one blocks applying, and the
+	// second one waits behind it
+	idx := uint64(0)
+	for batch := 0; batch < 2; batch++ {
+		var ents []raftpb.Entry
+		for i := 0; i < 5; i++ {
+			idx++
+			req := &pb.Request{Method: "QGET", ID: idx}
+			ents = append(ents, raftpb.Entry{Index: idx, Data: pbutil.MustMarshal(req)})
+		}
+		n.readyc <- raft.Ready{Entries: ents, CommittedEntries: ents}
+	}
+	waitGauges(1, 10)
+
+	// flood writes; none of them is proposed while the backlog is too long
+	rejected := promtestutil.ToFloat64(applyBacklogRejectedRequests)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if _, err := s.Put(ctx, &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar")}); err != errors.ErrTooManyRequests {
+				t.Errorf("err = %v, want %v", err, errors.ErrTooManyRequests)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := promtestutil.ToFloat64(applyBacklogRejectedRequests) - rejected; got != 50 {
+		t.Errorf("rejected requests = %v, want 50", got)
+	}
+
+	// serializable reads are served from the backend as is
+	if _, err := s.Range(context.Background(), &pb.RangeRequest{Key: []byte("foo"), Serializable: true}); err != nil {
+		t.Errorf("range err = %v, want nil", err)
+	}
+
+	// the gauges drop once the backend catches up and the server goes idle
+	close(release)
+	waitGauges(0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Put(ctx, &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar")}); err == errors.ErrTooManyRequests {
+		t.Errorf("err = %v after the backlog was applied", err)
+	}
+}
+
+// blockingRebuildKV blocks rebuilding its index until release is closed.
+type blockingRebuildKV struct {
+	mvcc.WatchableKV
+	started chan struct{}
+	release <-chan struct{}
+}
+
+func (kv *blockingRebuildKV) RebuildIndex(ctx context.Context) (int64, []mvcc.Anomaly, error) {
+	close(kv.started)
+	<-kv.release
+	return kv.WatchableKV.RebuildIndex(ctx)
+}
+
+// TestRebuildIndex tests that RebuildIndex is refused on the leader unless
+// forced, and that writes are rejected while the index is rebuilt.
+func TestRebuildIndex(t *testing.T) {
+	lg := zaptest.NewLogger(t)
+	be, _ := betesting.NewDefaultTmpBackend(t)
+	release := make(chan struct{})
+	kv := &blockingRebuildKV{
+		WatchableKV: mvcc.New(lg, be, &lease.FakeLessor{}, mvcc.StoreConfig{}),
+		started:     make(chan struct{}),
+		release:     release,
+	}
+	defer be.Close()
+	defer kv.Close()
+	s := &EtcdServer{
+		lgMu:     new(sync.RWMutex),
+		lg:       lg,
+		Cfg:      config.ServerConfig{Logger: lg},
+		memberId: 1,
+		kv:       kv,
+	}
+	s.setLead(1)
+
+	if _, _, err := s.RebuildIndex(context.Background(), false); err != errors.ErrRebuildIndexOnLeader {
+		t.Fatalf("err = %v, want %v", err, errors.ErrRebuildIndexOnLeader)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := s.RebuildIndex(ctx, true)
+		errc <- err
+	}()
+	<-kv.started
+	if _, err := s.Put(context.Background(), &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar")}); err != errors.ErrRebuildingIndex {
+		t.Errorf("put err = %v, want %v", err, errors.ErrRebuildingIndex)
+	}
+	if _, _, err := s.RebuildIndex(context.Background(), true); err != errors.ErrRebuildingIndex {
+		t.Errorf("concurrent rebuild err = %v, want %v", err, errors.ErrRebuildingIndex)
+	}
+
+	// the rebuild outlives the request that started it
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	if atomic.LoadInt32(&s.rebuildingIndex) == 0 {
+		t.Error("expected the writes to be rejected until the index is rebuilt")
+	}
+
+	close(release)
+	for i := 0; atomic.LoadInt32(&s.rebuildingIndex) != 0; i++ {
+		if i == 100 {
+			t.Fatal("expected the writes to be accepted once the index is rebuilt")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 // TestAddMember tests AddMember can propose and perform node addition.
 func TestAddMember(t *testing.T) {
 	lg := zaptest.NewLogger(t)
@@ -1593,7 +1834,8 @@ func TestPublishV3Retry(t *testing.T) {
 			}
 		}
 	}()
-	srv.publishV3(10 * time.Nanosecond)
+	// the timeout must outlive the deadline check so that requests are proposed
+	srv.publishV3(10 * time.Millisecond)
 	ch <- struct{}{}
 	<-ch
 }
@@ -0,0 +1,39 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package txn
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "go.etcd.io/etcd/server/v3/etcdserver"
+
+// nonRecordingSpan is returned by StartSpan for requests that are not traced.
+var nonRecordingSpan = trace.SpanFromContext(context.Background())
+
+// StartSpan starts a span named name as a child of the span of ctx, using the
+// tracer provider of that span. With distributed tracing enabled, the gRPC
+// interceptors start a span for every request; otherwise, or if the request
+// was not sampled, ctx carries no recording span and StartSpan returns ctx
+// and a span that records nothing, without allocating.
+func StartSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.IsRecording() {
+		return ctx, nonRecordingSpan
+	}
+	return parent.TracerProvider().Tracer(tracerName).Start(ctx, name)
+}
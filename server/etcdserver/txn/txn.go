@@ -18,7 +18,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"math"
 	"sort"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -32,6 +34,8 @@ import (
 )
 
 func Put(ctx context.Context, lg *zap.Logger, lessor lease.Lessor, kv mvcc.KV, p *pb.PutRequest) (resp *pb.PutResponse, trace *traceutil.Trace, err error) {
+	ctx, span := StartSpan(ctx, "backend")
+	defer span.End()
 	trace = traceutil.Get(ctx)
 	// create put tracing if the trace in context is empty
 	if trace.IsEmpty() {
@@ -42,18 +46,40 @@ func Put(ctx context.Context, lg *zap.Logger, lessor lease.Lessor, kv mvcc.KV, p
 		)
 		ctx = context.WithValue(ctx, traceutil.TraceKey, trace)
 	}
+	var l *lease.Lease
 	leaseID := lease.LeaseID(p.Lease)
 	if leaseID != lease.NoLease {
-		if l := lessor.Lookup(leaseID); l == nil {
+		if l = lessor.Lookup(leaseID); l == nil {
 			return nil, nil, lease.ErrLeaseNotFound
 		}
 	}
 	txnWrite := kv.Write(trace)
 	defer txnWrite.End()
+	if err = kv.CheckPrefixQuotas(txnWrite, []mvcc.QuotaOp{putQuotaOp(p)}); err != nil {
+		return nil, nil, err
+	}
 	resp, err = put(ctx, txnWrite, p)
+	if err == nil && p.LeaseTtl && l != nil {
+		resp.LeaseTtl = remainingTTL(l)
+	}
 	return resp, trace, err
 }
 
+// remainingTTL returns the remaining TTL of the lease in seconds, rounded up
+// so that an unexpired lease never reports zero, or -1 if the lease has
+// expired but is not revoked yet. It returns zero if the expiry of the lease
+// is not tracked by this member because its lessor is not the primary.
+func remainingTTL(l *lease.Lease) int64 {
+	remaining := l.Remaining()
+	switch {
+	case remaining == time.Duration(math.MaxInt64):
+		return 0
+	case remaining <= 0:
+		return -1
+	}
+	return int64((remaining + time.Second - 1) / time.Second)
+}
+
 func put(ctx context.Context, txnWrite mvcc.TxnWrite, p *pb.PutRequest) (resp *pb.PutResponse, err error) {
 	trace := traceutil.Get(ctx)
 	resp = &pb.PutResponse{}
@@ -82,6 +108,24 @@ func put(ctx context.Context, txnWrite mvcc.TxnWrite, p *pb.PutRequest) (resp *p
 	if p.IgnoreLease {
 		leaseID = lease.LeaseID(rr.KVs[0].Lease)
 	}
+	if len(p.CopyFrom) != 0 {
+		// copy_from is read as of the start of the txn, before the writes
+		// preceding the put in the txn
+		var cr *mvcc.RangeResult
+		trace.StepWithFunction(func() {
+			cr, err = txnWrite.Range(context.TODO(), p.CopyFrom, nil, mvcc.RangeOptions{Rev: txnWrite.Rev()})
+		}, "get copied kv pair")
+		if err != nil {
+			return nil, err
+		}
+		if len(cr.KVs) == 0 {
+			return nil, errors.ErrKeyNotFound
+		}
+		val = cr.KVs[0].Value
+		if p.CopyLease {
+			leaseID = lease.LeaseID(cr.KVs[0].Lease)
+		}
+	}
 	if p.PrevKv {
 		if rr != nil && len(rr.KVs) != 0 {
 			resp.PrevKv = &rr.KVs[0]
@@ -94,6 +138,8 @@ func put(ctx context.Context, txnWrite mvcc.TxnWrite, p *pb.PutRequest) (resp *p
 }
 
 func DeleteRange(ctx context.Context, lg *zap.Logger, kv mvcc.KV, dr *pb.DeleteRangeRequest) (resp *pb.DeleteRangeResponse, trace *traceutil.Trace, err error) {
+	ctx, span := StartSpan(ctx, "backend")
+	defer span.End()
 	trace = traceutil.Get(ctx)
 	// create delete tracing if the trace in context is empty
 	if trace.IsEmpty() {
@@ -133,6 +179,8 @@ func deleteRange(ctx context.Context, txnWrite mvcc.TxnWrite, dr *pb.DeleteRange
 }
 
 func Range(ctx context.Context, lg *zap.Logger, kv mvcc.KV, r *pb.RangeRequest) (resp *pb.RangeResponse, trace *traceutil.Trace, err error) {
+	ctx, span := StartSpan(ctx, "backend")
+	defer span.End()
 	trace = traceutil.Get(ctx)
 	if trace.IsEmpty() {
 		trace = traceutil.New("range", lg)
@@ -166,6 +214,9 @@ func executeRange(ctx context.Context, lg *zap.Logger, txnRead mvcc.TxnRead, r *
 		Limit: limit,
 		Rev:   r.Revision,
 		Count: r.CountOnly,
+		// values are needed to sort by value
+		KeysOnly: r.KeysOnly && r.SortTarget != pb.RangeRequest_VALUE,
+		Reverse:  r.Reverse,
 	}
 
 	rr, err := txnRead.Range(ctx, r.Key, mkGteRange(r.RangeEnd), ro)
@@ -196,7 +247,7 @@ func executeRange(ctx context.Context, lg *zap.Logger, txnRead mvcc.TxnRead, r *
 		// sorted by keys in lexiographically ascending order,
 		// sort ASCEND by default only when target is not 'KEY'
 		sortOrder = pb.RangeRequest_ASCEND
-	} else if r.SortTarget == pb.RangeRequest_KEY && sortOrder == pb.RangeRequest_ASCEND {
+	} else if r.SortTarget == pb.RangeRequest_KEY && sortOrder == pb.RangeRequest_ASCEND && !r.Reverse {
 		// Since current mvcc.Range implementation returns results
 		// sorted by keys in lexiographically ascending order,
 		// don't re-sort when target is 'KEY' and order is ASCEND
@@ -245,12 +296,14 @@ func executeRange(ctx context.Context, lg *zap.Logger, txnRead mvcc.TxnRead, r *
 }
 
 func Txn(ctx context.Context, lg *zap.Logger, rt *pb.TxnRequest, txnModeWriteWithSharedBuffer bool, kv mvcc.KV, lessor lease.Lessor) (*pb.TxnResponse, *traceutil.Trace, error) {
+	ctx, span := StartSpan(ctx, "backend")
+	defer span.End()
 	trace := traceutil.Get(ctx)
 	if trace.IsEmpty() {
 		trace = traceutil.New("transaction", lg)
 		ctx = context.WithValue(ctx, traceutil.TraceKey, trace)
 	}
-	isWrite := !IsTxnReadonly(rt)
+	isWrite := !IsTxnReadonly(rt) && !rt.DryRun
 	// When the transaction contains write operations, we use ReadTx instead of
 	// ConcurrentReadTx to avoid extra overhead of copying buffer.
 	var mode mvcc.ReadTxMode
@@ -271,6 +324,10 @@ func Txn(ctx context.Context, lg *zap.Logger, rt *pb.TxnRequest, txnModeWriteWit
 		trace.AddField(traceutil.Field{Key: "read_only", Value: false})
 	}
 	_, err := checkTxn(txnRead, rt, lessor, txnPath)
+	if err == nil && isWrite {
+		ops, _ := txnQuotaOps(nil, rt, txnPath)
+		err = kv.CheckPrefixQuotas(txnRead, ops)
+	}
 	if err != nil {
 		txnRead.End()
 		return nil, nil, err
@@ -284,6 +341,8 @@ func Txn(ctx context.Context, lg *zap.Logger, rt *pb.TxnRequest, txnModeWriteWit
 	if isWrite {
 		txnRead.End()
 		txnWrite = kv.Write(trace)
+	} else if rt.DryRun {
+		txnWrite = mvcc.NewDryRunTxnWrite(txnRead)
 	} else {
 		txnWrite = mvcc.NewReadOnlyTxnWrite(txnRead)
 	}
@@ -297,6 +356,118 @@ func Txn(ctx context.Context, lg *zap.Logger, rt *pb.TxnRequest, txnModeWriteWit
 	return txnResp, trace, err
 }
 
+// TxnStream executes the read only txn rt. It calls send first with the header
+// and whether the compare of rt succeeded, then with the response of each
+// request of the executed branch, in request order. Each response is built
+// right before it is sent, so the responses of rt are never all held in memory.
+//
+// The store is not read while send runs: the compare of rt is evaluated in a
+// read txn closed before the header is sent, and each request is read in a
+// read txn of its own at the revision of the header. A slow receiver therefore
+// does not hold up the writes or the compactions of kv, but the responses
+// following a compaction past the header revision fail with ErrCompacted.
+func TxnStream(ctx context.Context, lg *zap.Logger, rt *pb.TxnRequest, kv mvcc.KV, lessor lease.Lessor, send func(*pb.TxnStreamResponse) error) error {
+	ctx, span := StartSpan(ctx, "backend")
+	defer span.End()
+	trace := traceutil.Get(ctx)
+	if trace.IsEmpty() {
+		trace = traceutil.New("transaction", lg)
+		ctx = context.WithValue(ctx, traceutil.TraceKey, trace)
+	}
+
+	txnRead := kv.Read(mvcc.ConcurrentReadTxMode, trace)
+	var txnPath []bool
+	trace.StepWithFunction(
+		func() {
+			txnPath = compareToPath(txnRead, rt)
+		},
+		"compare",
+	)
+	_, err := checkTxn(txnRead, rt, lessor, txnPath)
+	rev := txnRead.Rev()
+	txnRead.End()
+	if err != nil {
+		return err
+	}
+	trace.Step("check requests")
+
+	err = send(&pb.TxnStreamResponse{
+		Header:    &pb.ResponseHeader{Revision: rev},
+		Succeeded: txnPath[0],
+	})
+	if err != nil {
+		return err
+	}
+
+	reqs := rt.Success
+	if !txnPath[0] {
+		reqs = rt.Failure
+	}
+	txnPath = txnPath[1:]
+	for _, req := range reqs {
+		var resp *pb.ResponseOp
+		resp, txnPath, err = readTxnOp(ctx, lg, kv, rev, req, txnPath)
+		if err != nil {
+			return err
+		}
+		if err = send(&pb.TxnStreamResponse{Response: resp}); err != nil {
+			return err
+		}
+	}
+	trace.AddField(traceutil.Field{Key: "number_of_response", Value: len(reqs)})
+	return nil
+}
+
+// readTxnOp reads the response of the read only request req of a streamed txn
+// at rev, in a read txn ended before returning. It returns the compare results
+// of txnPath left after the nested txns of req.
+func readTxnOp(ctx context.Context, lg *zap.Logger, kv mvcc.KV, rev int64, req *pb.RequestOp, txnPath []bool) (*pb.ResponseOp, []bool, error) {
+	txnRead := &revTxnRead{TxnRead: kv.Read(mvcc.ConcurrentReadTxMode, traceutil.Get(ctx)), rev: rev}
+	defer txnRead.End()
+
+	switch tv := req.Request.(type) {
+	case *pb.RequestOp_RequestRange:
+		rr, err := executeRange(ctx, lg, txnRead, tv.RequestRange)
+		if err != nil {
+			return nil, nil, fmt.Errorf("applyTxn: failed Range: %w", err)
+		}
+		return &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: rr}}, txnPath, nil
+	case *pb.RequestOp_RequestTxn:
+		tresp, _ := newTxnResp(tv.RequestTxn, txnPath)
+		txns, err := executeTxn(ctx, lg, mvcc.NewReadOnlyTxnWrite(txnRead), tv.RequestTxn, txnPath, tresp)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: tresp}}, txnPath[txns+1:], nil
+	default:
+		// empty union
+		return nil, txnPath, nil
+	}
+}
+
+// revTxnRead reads at rev through a read txn opened at a later revision, as
+// a read txn opened at rev would have.
+type revTxnRead struct {
+	mvcc.TxnRead
+	rev int64
+}
+
+func (tr *revTxnRead) Rev() int64 { return tr.rev }
+
+func (tr *revTxnRead) Range(ctx context.Context, key, end []byte, ro mvcc.RangeOptions) (*mvcc.RangeResult, error) {
+	if ro.Rev > tr.rev {
+		return &mvcc.RangeResult{KVs: nil, Count: -1, Rev: tr.rev}, mvcc.ErrFutureRev
+	}
+	if ro.Rev <= 0 {
+		ro.Rev = tr.rev
+	}
+	r, err := tr.TxnRead.Range(ctx, key, end, ro)
+	if r != nil {
+		r.Rev = tr.rev
+	}
+	return r, err
+}
+
 func txn(ctx context.Context, lg *zap.Logger, txnWrite mvcc.TxnWrite, rt *pb.TxnRequest, isWrite bool, txnPath []bool) (*pb.TxnResponse, error) {
 	txnResp, _ := newTxnResp(rt, txnPath)
 	_, err := executeTxn(ctx, lg, txnWrite, rt, txnPath, txnResp)
@@ -415,6 +586,15 @@ func checkPut(rv mvcc.ReadView, lessor lease.Lessor, req *pb.PutRequest) error {
 			return errors.ErrKeyNotFound
 		}
 	}
+	if len(req.CopyFrom) != 0 {
+		rr, err := rv.Range(context.TODO(), req.CopyFrom, nil, mvcc.RangeOptions{})
+		if err != nil {
+			return err
+		}
+		if rr == nil || len(rr.KVs) == 0 {
+			return errors.ErrKeyNotFound
+		}
+	}
 	if lease.LeaseID(req.Lease) != lease.NoLease {
 		if l := lessor.Lookup(lease.LeaseID(req.Lease)); l == nil {
 			return lease.ErrLeaseNotFound
@@ -464,6 +644,37 @@ func checkTxn(rv mvcc.ReadView, rt *pb.TxnRequest, lessor lease.Lessor, txnPath
 	return txnCount, nil
 }
 
+func putQuotaOp(req *pb.PutRequest) mvcc.QuotaOp {
+	return mvcc.QuotaOp{Key: req.Key, Value: req.Value, IgnoreValue: req.IgnoreValue, CopyFrom: req.CopyFrom}
+}
+
+// txnQuotaOps appends the writes on the path of rt to ops. Like checkTxn, it
+// also returns the number of txns nested in rt on the path.
+func txnQuotaOps(ops []mvcc.QuotaOp, rt *pb.TxnRequest, txnPath []bool) ([]mvcc.QuotaOp, int) {
+	txnCount := 0
+	reqs := rt.Success
+	if !txnPath[0] {
+		reqs = rt.Failure
+	}
+	for _, req := range reqs {
+		switch tv := req.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			ops = append(ops, putQuotaOp(tv.RequestPut))
+		case *pb.RequestOp_RequestDeleteRange:
+			dr := tv.RequestDeleteRange
+			ops = append(ops, mvcc.QuotaOp{Key: dr.Key, End: mkGteRange(dr.RangeEnd), Delete: true})
+		case *pb.RequestOp_RequestTxn:
+			var txns int
+			ops, txns = txnQuotaOps(ops, tv.RequestTxn, txnPath[1:])
+			txnCount += txns + 1
+			txnPath = txnPath[txns+1:]
+		default:
+			// empty union
+		}
+	}
+	return ops, txnCount
+}
+
 // mkGteRange determines if the range end is a >= range. This works around grpc
 // sending empty byte strings as nil; >= is encoded in the range end as '\0'.
 // If it is a GTE range, then []byte{} is returned to indicate the empty byte
@@ -633,29 +844,40 @@ func compareKV(c *pb.Compare, ckv mvccpb.KeyValue) bool {
 	return true
 }
 
+// IsTxnSerializable returns true if every operation of the transaction,
+// including those of nested transactions, is a serializable range.
 func IsTxnSerializable(r *pb.TxnRequest) bool {
-	for _, u := range r.Success {
-		if r := u.GetRequestRange(); r == nil || !r.Serializable {
-			return false
-		}
-	}
-	for _, u := range r.Failure {
-		if r := u.GetRequestRange(); r == nil || !r.Serializable {
-			return false
+	for _, reqs := range [][]*pb.RequestOp{r.Success, r.Failure} {
+		for _, u := range reqs {
+			if tv := u.GetRequestTxn(); tv != nil {
+				if !IsTxnSerializable(tv) {
+					return false
+				}
+				continue
+			}
+			if r := u.GetRequestRange(); r == nil || !r.Serializable {
+				return false
+			}
 		}
 	}
 	return true
 }
 
+// IsTxnReadonly returns true if the transaction, including its nested
+// transactions, contains only range operations and can be served without
+// going through raft.
 func IsTxnReadonly(r *pb.TxnRequest) bool {
-	for _, u := range r.Success {
-		if r := u.GetRequestRange(); r == nil {
-			return false
-		}
-	}
-	for _, u := range r.Failure {
-		if r := u.GetRequestRange(); r == nil {
-			return false
+	for _, reqs := range [][]*pb.RequestOp{r.Success, r.Failure} {
+		for _, u := range reqs {
+			if tv := u.GetRequestTxn(); tv != nil {
+				if !IsTxnReadonly(tv) {
+					return false
+				}
+				continue
+			}
+			if r := u.GetRequestRange(); r == nil {
+				return false
+			}
 		}
 	}
 	return true
@@ -693,6 +915,11 @@ func checkTxnReqsPermission(as auth.AuthStore, ai *auth.AuthInfo, reqs []*pb.Req
 			if err := as.IsPutPermitted(ai, tv.RequestPut.Key); err != nil {
 				return err
 			}
+			if len(tv.RequestPut.CopyFrom) != 0 {
+				if err := as.IsRangePermitted(ai, tv.RequestPut.CopyFrom, nil); err != nil {
+					return err
+				}
+			}
 
 		case *pb.RequestOp_RequestDeleteRange:
 			if tv.RequestDeleteRange == nil {
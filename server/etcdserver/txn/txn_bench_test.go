@@ -0,0 +1,57 @@
+// Copyright 2024 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package txn
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+	"go.etcd.io/etcd/server/v3/storage/mvcc"
+)
+
+func BenchmarkDeleteRange100000(b *testing.B)       { benchmarkDeleteRange(b, 100000, false) }
+func BenchmarkDeleteRangePrevKv100000(b *testing.B) { benchmarkDeleteRange(b, 100000, true) }
+
+func benchmarkDeleteRange(b *testing.B, keys int, prevKv bool) {
+	be, _ := betesting.NewDefaultTmpBackend(b)
+	defer betesting.Close(b, be)
+	s := mvcc.NewStore(zap.NewNop(), be, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	defer s.Close()
+
+	val := make([]byte, 1024)
+	req := &pb.DeleteRangeRequest{Key: []byte("foo"), RangeEnd: []byte("fop"), PrevKv: prevKv}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for j := 0; j < keys; j++ {
+			s.Put([]byte(fmt.Sprintf("foo%08d", j)), val, lease.NoLease)
+		}
+		b.StartTimer()
+
+		resp, _, err := DeleteRange(context.TODO(), zap.NewNop(), s, req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if resp.Deleted != int64(keys) {
+			b.Fatalf("deleted = %d, want %d", resp.Deleted, keys)
+		}
+	}
+}
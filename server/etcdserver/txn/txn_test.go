@@ -16,6 +16,7 @@ package txn
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -26,6 +27,7 @@ import (
 
 	"go.etcd.io/etcd/api/v3/authpb"
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/lease"
 	"go.etcd.io/etcd/server/v3/storage/backend"
@@ -165,6 +167,31 @@ var putTestCases = []testCase{
 			},
 		},
 	},
+	{
+		name: "Put with copy from without copied key should fail",
+		op: &pb.RequestOp{
+			Request: &pb.RequestOp_RequestPut{
+				RequestPut: &pb.PutRequest{
+					Key:      []byte("copy"),
+					CopyFrom: []byte("copied"),
+				},
+			},
+		},
+		expectError: "etcdserver: key not found",
+	},
+	{
+		name:  "Put with copy from with copied key should succeed",
+		setup: testSetup{key: []byte("copied")},
+		op: &pb.RequestOp{
+			Request: &pb.RequestOp_RequestPut{
+				RequestPut: &pb.PutRequest{
+					Key:       []byte("copy"),
+					CopyFrom:  []byte("copied"),
+					CopyLease: true,
+				},
+			},
+		},
+	},
 }
 
 func TestCheckTxn(t *testing.T) {
@@ -338,6 +365,168 @@ func TestReadonlyTxnError(t *testing.T) {
 	}
 }
 
+func TestTxnStream(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+	s := mvcc.NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	defer s.Close()
+
+	var ops []*pb.RequestOp
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("foo%04d", i))
+		s.Put(key, []byte(strings.Repeat("v", 100)), lease.NoLease)
+		ops = append(ops, &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: key}}})
+	}
+	nested := &pb.TxnRequest{
+		Compare: []*pb.Compare{{Key: []byte("foo0000"), Target: pb.Compare_VERSION, Result: pb.Compare_EQUAL, TargetUnion: &pb.Compare_Version{Version: 2}}},
+		Success: ops[:1],
+		Failure: ops[1:2],
+	}
+	ops = append(ops, &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: nested}})
+
+	tcs := []struct {
+		name string
+		cmp  *pb.Compare
+	}{
+		{
+			name: "success",
+			cmp:  &pb.Compare{Key: []byte("foo0000"), Target: pb.Compare_VERSION, Result: pb.Compare_EQUAL, TargetUnion: &pb.Compare_Version{Version: 1}},
+		},
+		{
+			name: "failure",
+			cmp:  &pb.Compare{Key: []byte("foo0000"), Target: pb.Compare_VERSION, Result: pb.Compare_GREATER, TargetUnion: &pb.Compare_Version{Version: 1}},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := &pb.TxnRequest{Compare: []*pb.Compare{tc.cmp}, Success: ops, Failure: ops[1:]}
+			want, _, err := Txn(context.TODO(), zaptest.NewLogger(t), rt, false, s, &lease.FakeLessor{})
+			require.NoError(t, err)
+
+			got := &pb.TxnResponse{}
+			maxSize := 0
+			err = TxnStream(context.TODO(), zaptest.NewLogger(t), rt, s, &lease.FakeLessor{}, func(resp *pb.TxnStreamResponse) error {
+				if resp.Header != nil {
+					require.Nil(t, got.Header, "header sent twice")
+					got.Header, got.Succeeded = resp.Header, resp.Succeeded
+				} else {
+					got.Responses = append(got.Responses, resp.Response)
+				}
+				if resp.Size() > maxSize {
+					maxSize = resp.Size()
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+			// no message holds more than a single operation response
+			assert.Less(t, maxSize*100, want.Size())
+		})
+	}
+}
+
+// TestTxnStreamConsistent ensures the responses of a streamed txn are read at
+// the revision of its header, even if the keys are written while they are sent.
+func TestTxnStreamConsistent(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+	s := mvcc.NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	defer s.Close()
+
+	s.Put([]byte("a"), []byte("a1"), lease.NoLease)
+	s.Put([]byte("b"), []byte("b1"), lease.NoLease)
+	rangeOp := func(key string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte(key)}}}
+	}
+	rt := &pb.TxnRequest{Success: []*pb.RequestOp{rangeOp("a"), rangeOp("b")}}
+
+	var header *pb.ResponseHeader
+	var values []string
+	err := TxnStream(context.TODO(), zaptest.NewLogger(t), rt, s, &lease.FakeLessor{}, func(resp *pb.TxnStreamResponse) error {
+		if resp.Header != nil {
+			header = resp.Header
+		} else {
+			rr := resp.Response.GetResponseRange()
+			assert.Equal(t, header.Revision, rr.Header.Revision)
+			for _, kv := range rr.Kvs {
+				values = append(values, string(kv.Value))
+			}
+		}
+		// the keys are written between the responses
+		s.Put([]byte("b"), []byte(fmt.Sprintf("b%d", len(values)+2)), lease.NoLease)
+		s.DeleteRange([]byte("a"), nil)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), header.Revision)
+	assert.Equal(t, []string{"a1", "b1"}, values)
+}
+
+// TestTxnStreamStalledReceiver ensures a receiver that stops receiving the
+// responses of a streamed txn does not hold up the compactions of the store.
+func TestTxnStreamStalledReceiver(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+	s := mvcc.NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	defer s.Close()
+
+	var ops []*pb.RequestOp
+	for i := 0; i < 10; i++ {
+		key := []byte(fmt.Sprintf("foo%d", i))
+		s.Put(key, []byte("v1"), lease.NoLease)
+		ops = append(ops, &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: key}}})
+	}
+	rev := s.Rev()
+
+	stalledc, resumec := make(chan struct{}), make(chan struct{})
+	errc := make(chan error, 1)
+	var resps []*pb.ResponseOp
+	go func() {
+		errc <- TxnStream(context.TODO(), zaptest.NewLogger(t), &pb.TxnRequest{Success: ops}, s, &lease.FakeLessor{}, func(resp *pb.TxnStreamResponse) error {
+			if resp.Response == nil {
+				return nil
+			}
+			resps = append(resps, resp.Response)
+			if len(resps) == 1 {
+				close(stalledc)
+				<-resumec
+			}
+			return nil
+		})
+	}()
+	<-stalledc
+
+	compactc := make(chan error, 1)
+	go func() {
+		for i := 0; i < 10; i++ {
+			s.Put([]byte(fmt.Sprintf("foo%d", i)), []byte("v2"), lease.NoLease)
+		}
+		donec, err := s.Compact(traceutil.TODO(), rev)
+		if err == nil {
+			<-donec
+		}
+		compactc <- err
+	}()
+	select {
+	case err := <-compactc:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("compaction blocked by the stalled receiver")
+	}
+	close(resumec)
+
+	require.NoError(t, <-errc)
+	require.Len(t, resps, len(ops))
+	for i, resp := range resps {
+		rr := resp.GetResponseRange()
+		assert.Equal(t, rev, rr.Header.Revision)
+		if assert.Len(t, rr.Kvs, 1) {
+			assert.Equal(t, fmt.Sprintf("foo%d", i), string(rr.Kvs[0].Key))
+			assert.Equal(t, "v1", string(rr.Kvs[0].Value))
+		}
+	}
+}
+
 func TestWriteTxnPanic(t *testing.T) {
 	b, _ := betesting.NewDefaultTmpBackend(t)
 	defer betesting.Close(t, b)
@@ -372,6 +561,55 @@ func TestWriteTxnPanic(t *testing.T) {
 	assert.Panics(t, func() { Txn(ctx, zaptest.NewLogger(t), txn, false, s, &lease.FakeLessor{}) }, "Expected panic in Txn with writes")
 }
 
+// TestTxnPutCopyFrom ensures a put copies the value and the lease its key to
+// copy has before the txn, regardless of the writes of the txn preceding it.
+func TestTxnPutCopyFrom(t *testing.T) {
+	s, lessor := setup(t, testSetup{lease: 1})
+	s.Put([]byte("src"), []byte("v1"), 1)
+
+	rt := &pb.TxnRequest{Success: []*pb.RequestOp{
+		{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("src"), Value: []byte("v2")}}},
+		{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("dst1"), CopyFrom: []byte("src"), CopyLease: true}}},
+		{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("dst2"), CopyFrom: []byte("src")}}},
+	}}
+	_, _, err := Txn(context.TODO(), zaptest.NewLogger(t), rt, false, s, lessor)
+	require.NoError(t, err)
+
+	for key, wlease := range map[string]int64{"dst1": 1, "dst2": 0} {
+		rr, err := s.Range(context.TODO(), []byte(key), nil, mvcc.RangeOptions{})
+		require.NoError(t, err)
+		require.Len(t, rr.KVs, 1)
+		assert.Equal(t, "v1", string(rr.KVs[0].Value))
+		assert.Equal(t, wlease, rr.KVs[0].Lease)
+	}
+}
+
+func TestDeleteRangePrevKv(t *testing.T) {
+	for _, prevKv := range []bool{false, true} {
+		t.Run(fmt.Sprintf("PrevKv=%v", prevKv), func(t *testing.T) {
+			b, _ := betesting.NewDefaultTmpBackend(t)
+			defer betesting.Close(t, b)
+			s := mvcc.NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+			defer s.Close()
+
+			var wkvs []*mvccpb.KeyValue
+			for i := 0; i < 3; i++ {
+				key, val := []byte(fmt.Sprintf("foo%d", i)), []byte(fmt.Sprintf("bar%d", i))
+				rev := s.Put(key, val, lease.NoLease)
+				wkvs = append(wkvs, &mvccpb.KeyValue{Key: key, Value: val, CreateRevision: rev, ModRevision: rev, Version: 1})
+			}
+			if !prevKv {
+				wkvs = nil
+			}
+
+			resp, _, err := DeleteRange(context.TODO(), zaptest.NewLogger(t), s, &pb.DeleteRangeRequest{Key: []byte("foo"), RangeEnd: []byte("fop"), PrevKv: prevKv})
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), resp.Deleted)
+			assert.Equal(t, wkvs, resp.PrevKvs)
+		})
+	}
+}
+
 func TestCheckTxnAuth(t *testing.T) {
 	be, _ := betesting.NewDefaultTmpBackend(t)
 	defer betesting.Close(t, be)
@@ -651,3 +889,139 @@ var (
 		},
 	}
 )
+
+func TestIsTxnReadonly(t *testing.T) {
+	rangeOp := func(serializable bool) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte("foo"), Serializable: serializable}}}
+	}
+	putOp := &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("foo")}}}
+	txnOp := func(success []*pb.RequestOp, failure ...*pb.RequestOp) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: &pb.TxnRequest{Success: success, Failure: failure}}}
+	}
+
+	tcs := []struct {
+		name             string
+		req              *pb.TxnRequest
+		wantReadonly     bool
+		wantSerializable bool
+	}{
+		{
+			name:             "serializable ranges",
+			req:              &pb.TxnRequest{Success: []*pb.RequestOp{rangeOp(true)}, Failure: []*pb.RequestOp{rangeOp(true)}},
+			wantReadonly:     true,
+			wantSerializable: true,
+		},
+		{
+			name:         "linearizable range",
+			req:          &pb.TxnRequest{Success: []*pb.RequestOp{rangeOp(true)}, Failure: []*pb.RequestOp{rangeOp(false)}},
+			wantReadonly: true,
+		},
+		{
+			name: "put",
+			req:  &pb.TxnRequest{Success: []*pb.RequestOp{rangeOp(true)}, Failure: []*pb.RequestOp{putOp}},
+		},
+		{
+			name:             "nested serializable ranges",
+			req:              &pb.TxnRequest{Success: []*pb.RequestOp{txnOp([]*pb.RequestOp{rangeOp(true)}), rangeOp(true)}, Failure: []*pb.RequestOp{txnOp(nil, txnOp([]*pb.RequestOp{rangeOp(true)}))}},
+			wantReadonly:     true,
+			wantSerializable: true,
+		},
+		{
+			name:         "nested linearizable range",
+			req:          &pb.TxnRequest{Success: []*pb.RequestOp{txnOp([]*pb.RequestOp{rangeOp(true)}, rangeOp(false))}},
+			wantReadonly: true,
+		},
+		{
+			name: "nested put",
+			req:  &pb.TxnRequest{Success: []*pb.RequestOp{rangeOp(true)}, Failure: []*pb.RequestOp{txnOp([]*pb.RequestOp{rangeOp(true)}, txnOp([]*pb.RequestOp{putOp}))}},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantReadonly, IsTxnReadonly(tc.req))
+			assert.Equal(t, tc.wantSerializable, IsTxnSerializable(tc.req))
+		})
+	}
+}
+
+func TestReadonlyNestedTxnFailedCompare(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+	s := mvcc.NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	defer s.Close()
+
+	rev := s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+
+	rangeOp := func(key string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte(key)}}}
+	}
+	rt := &pb.TxnRequest{
+		Compare: []*pb.Compare{{Key: []byte("foo"), Target: pb.Compare_VALUE, Result: pb.Compare_EQUAL, TargetUnion: &pb.Compare_Value{Value: []byte("baz")}}},
+		Success: []*pb.RequestOp{rangeOp("abc")},
+		Failure: []*pb.RequestOp{
+			rangeOp("foo"),
+			{Request: &pb.RequestOp_RequestTxn{RequestTxn: &pb.TxnRequest{Success: []*pb.RequestOp{rangeOp("foo")}}}},
+		},
+	}
+	require.True(t, IsTxnReadonly(rt))
+
+	resp, _, err := Txn(context.TODO(), zaptest.NewLogger(t), rt, false, s, &lease.FakeLessor{})
+	require.NoError(t, err)
+	assert.False(t, resp.Succeeded)
+	assert.Equal(t, rev, resp.Header.Revision)
+	require.Len(t, resp.Responses, 2)
+	assert.Equal(t, []byte("bar"), resp.Responses[0].GetResponseRange().Kvs[0].Value)
+	nested := resp.Responses[1].GetResponseTxn()
+	require.NotNil(t, nested)
+	assert.True(t, nested.Succeeded)
+	assert.Equal(t, []byte("bar"), nested.Responses[0].GetResponseRange().Kvs[0].Value)
+}
+
+func TestDryRunTxn(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+	s := mvcc.NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	defer s.Close()
+
+	s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+	rev := s.Put([]byte("foo2"), []byte("bar"), lease.NoLease)
+
+	rangeOp := func(key string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte(key)}}}
+	}
+	putOp := func(key string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte(key), Value: []byte("baz")}}}
+	}
+	rt := &pb.TxnRequest{
+		Compare: []*pb.Compare{{Key: []byte("foo"), Target: pb.Compare_VALUE, Result: pb.Compare_EQUAL, TargetUnion: &pb.Compare_Value{Value: []byte("bar")}}},
+		Success: []*pb.RequestOp{
+			putOp("foo"),
+			{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("foo"), RangeEnd: []byte("fop")}}},
+			{Request: &pb.RequestOp_RequestTxn{RequestTxn: &pb.TxnRequest{Success: []*pb.RequestOp{putOp("abc"), rangeOp("foo2")}}}},
+		},
+		Failure: []*pb.RequestOp{rangeOp("abc")},
+		DryRun:  true,
+	}
+	require.False(t, IsTxnReadonly(rt))
+
+	resp, _, err := Txn(context.TODO(), zaptest.NewLogger(t), rt, false, s, &lease.FakeLessor{})
+	require.NoError(t, err)
+	assert.True(t, resp.Succeeded)
+	assert.Equal(t, rev, resp.Header.Revision)
+	require.Len(t, resp.Responses, 3)
+	assert.Equal(t, rev, resp.Responses[0].GetResponsePut().Header.Revision)
+	assert.Equal(t, int64(2), resp.Responses[1].GetResponseDeleteRange().Deleted)
+	nested := resp.Responses[2].GetResponseTxn()
+	require.NotNil(t, nested)
+	assert.True(t, nested.Succeeded)
+	assert.Equal(t, []byte("bar"), nested.Responses[1].GetResponseRange().Kvs[0].Value)
+
+	// nothing was written
+	assert.Equal(t, rev, s.Rev())
+	rr, err := s.Range(context.TODO(), []byte("a"), []byte("z"), mvcc.RangeOptions{})
+	require.NoError(t, err)
+	require.Len(t, rr.KVs, 2)
+	for _, kv := range rr.KVs {
+		assert.Equal(t, []byte("bar"), kv.Value)
+	}
+}
@@ -84,6 +84,9 @@ func longestConnected(tp rafthttp.Transporter, membs []types.ID) (types.ID, bool
 type notifier struct {
 	c   chan struct{}
 	err error
+	// forwardedTo is the leader the read index was requested from, or 0 if
+	// the read index was confirmed locally by the leader.
+	forwardedTo types.ID
 }
 
 func newNotifier() *notifier {
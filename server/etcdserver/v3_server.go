@@ -20,10 +20,16 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/version"
+	"go.etcd.io/etcd/client/pkg/v3/types"
 	"go.etcd.io/etcd/pkg/v3/traceutil"
 	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
@@ -34,10 +40,6 @@ import (
 	"go.etcd.io/etcd/server/v3/lease/leasehttp"
 	"go.etcd.io/etcd/server/v3/storage/mvcc"
 	"go.etcd.io/raft/v3"
-
-	"github.com/gogo/protobuf/proto"
-	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -59,7 +61,9 @@ type RaftKV interface {
 	Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error)
 	DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error)
 	Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error)
+	TxnStream(ctx context.Context, r *pb.TxnRequest, send func(*pb.TxnStreamResponse) error) error
 	Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error)
+	CompactKey(ctx context.Context, r *pb.CompactKeyRequest) (*pb.CompactKeyResponse, error)
 }
 
 type Lessor interface {
@@ -67,6 +71,10 @@ type Lessor interface {
 	LeaseGrant(ctx context.Context, r *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error)
 	// LeaseRevoke sends LeaseRevoke request to raft and toApply it after committed.
 	LeaseRevoke(ctx context.Context, r *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error)
+	// LeaseGrantBatch sends LeaseGrantBatch request to raft and toApply it after committed.
+	LeaseGrantBatch(ctx context.Context, r *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error)
+	// LeaseReattach sends LeaseReattach request to raft and toApply it after committed.
+	LeaseReattach(ctx context.Context, r *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error)
 
 	// LeaseRenew renews the lease with given ID. The renewed TTL is returned. Or an error
 	// is returned.
@@ -77,6 +85,9 @@ type Lessor interface {
 
 	// LeaseLeases lists all leases.
 	LeaseLeases(ctx context.Context, r *pb.LeaseLeasesRequest) (*pb.LeaseLeasesResponse, error)
+
+	// LeaseListDetailed lists all leases with their TTLs and the number of keys attached to them.
+	LeaseListDetailed(ctx context.Context, r *pb.LeaseListDetailedRequest) (*pb.LeaseListDetailedResponse, error)
 }
 
 type Authenticator interface {
@@ -110,7 +121,7 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 	var resp *pb.RangeResponse
 	var err error
 	defer func(start time.Time) {
-		txn.WarnOfExpensiveReadOnlyRangeRequest(s.Logger(), s.Cfg.WarningApplyDuration, start, r, resp, err)
+		txn.WarnOfExpensiveReadOnlyRangeRequest(s.Logger(), s.Cfg.WarningApplyDurationOf("Range"), start, r, resp, err)
 		if resp != nil {
 			trace.AddField(
 				traceutil.Field{Key: "response_count", Value: len(resp.Kvs)},
@@ -120,8 +131,14 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 		trace.LogIfLong(traceThreshold)
 	}(time.Now())
 
+	if s.Cfg.RateLimitReads {
+		if err = s.checkRateLimit(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var forwardedTo types.ID
 	if !r.Serializable {
-		err = s.linearizableReadNotify(ctx)
+		forwardedTo, err = s.linearizableRead(ctx)
 		trace.Step("agreement among raft nodes before linearized reading")
 		if err != nil {
 			return nil, err
@@ -136,19 +153,53 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 		err = serr
 		return nil, err
 	}
+	if resp != nil {
+		resp.Header.ForwardedTo = uint64(forwardedTo)
+	}
 	return resp, err
 }
 
 func (s *EtcdServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
+	if err := s.checkRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	ctx = context.WithValue(ctx, traceutil.StartTimeKey, time.Now())
+	if r.Ttl != 0 {
+		return s.putWithKeyTTL(ctx, r)
+	}
+	return s.put(ctx, r)
+}
+
+func (s *EtcdServer) put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{Put: r})
 	if err != nil {
 		return nil, err
 	}
-	return resp.(*pb.PutResponse), nil
+	presp := resp.(*pb.PutResponse)
+	if r.LeaseTtl && r.Lease != 0 && presp.LeaseTtl == 0 {
+		// only the primary lessor tracks lease expiries; ask the leader
+		ttl, err := s.leaseTimeToLive(ctx, &pb.LeaseTimeToLiveRequest{ID: r.Lease})
+		switch {
+		case err == nil:
+			presp.LeaseTtl = ttl.TTL
+			if presp.LeaseTtl == 0 {
+				// TimeToLive rounds down, but the lease is not revoked yet
+				presp.LeaseTtl = 1
+			}
+		case err == lease.ErrLeaseNotFound:
+			// revoked after the put was applied
+			presp.LeaseTtl = -1
+		default:
+			return nil, err
+		}
+	}
+	return presp, nil
 }
 
 func (s *EtcdServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	if err := s.checkRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{DeleteRange: r})
 	if err != nil {
 		return nil, err
@@ -157,14 +208,22 @@ func (s *EtcdServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest)
 }
 
 func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
-	if txn.IsTxnReadonly(r) {
+	// a dry run writes nothing, so it is served like a read-only txn
+	if r.DryRun || txn.IsTxnReadonly(r) {
+		if s.Cfg.RateLimitReads {
+			if err := s.checkRateLimit(ctx); err != nil {
+				return nil, err
+			}
+		}
 		trace := traceutil.New("transaction",
 			s.Logger(),
 			traceutil.Field{Key: "read_only", Value: true},
 		)
 		ctx = context.WithValue(ctx, traceutil.TraceKey, trace)
+		var forwardedTo types.ID
 		if !txn.IsTxnSerializable(r) {
-			err := s.linearizableReadNotify(ctx)
+			var err error
+			forwardedTo, err = s.linearizableRead(ctx)
 			trace.Step("agreement among raft nodes before linearized reading")
 			if err != nil {
 				return nil, err
@@ -177,7 +236,7 @@ func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse
 		}
 
 		defer func(start time.Time) {
-			txn.WarnOfExpensiveReadOnlyTxnRequest(s.Logger(), s.Cfg.WarningApplyDuration, start, r, resp, err)
+			txn.WarnOfExpensiveReadOnlyTxnRequest(s.Logger(), s.Cfg.WarningApplyDurationOf("Txn"), start, r, resp, err)
 			trace.LogIfLong(traceThreshold)
 		}(time.Now())
 
@@ -187,9 +246,15 @@ func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse
 		if serr := s.doSerialize(ctx, chk, get); serr != nil {
 			return nil, serr
 		}
+		if resp != nil {
+			resp.Header.ForwardedTo = uint64(forwardedTo)
+		}
 		return resp, err
 	}
 
+	if err := s.checkRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	ctx = context.WithValue(ctx, traceutil.StartTimeKey, time.Now())
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{Txn: r})
 	if err != nil {
@@ -198,6 +263,81 @@ func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse
 	return resp.(*pb.TxnResponse), nil
 }
 
+// TxnStream executes a txn like Txn, but hands the responses of the executed
+// operations to send one at a time. Read-only txns are streamed from the
+// backend as they are read, each operation in a read txn of its own at the
+// revision of the header, so that the responses are consistent without holding
+// the backend while they are sent. Txns with writes are applied through raft
+// like Txn, and their complete response is split up afterwards.
+func (s *EtcdServer) TxnStream(ctx context.Context, r *pb.TxnRequest, send func(*pb.TxnStreamResponse) error) error {
+	if !txn.IsTxnReadonly(r) {
+		resp, err := s.Txn(ctx, r)
+		if err != nil {
+			return err
+		}
+		return sendTxnResponse(resp, send)
+	}
+
+	if s.Cfg.RateLimitReads {
+		if err := s.checkRateLimit(ctx); err != nil {
+			return err
+		}
+	}
+	trace := traceutil.New("transaction stream",
+		s.Logger(),
+		traceutil.Field{Key: "read_only", Value: true},
+	)
+	ctx = context.WithValue(ctx, traceutil.TraceKey, trace)
+	var forwardedTo types.ID
+	if !txn.IsTxnSerializable(r) {
+		var err error
+		forwardedTo, err = s.linearizableRead(ctx)
+		trace.Step("agreement among raft nodes before linearized reading")
+		if err != nil {
+			return err
+		}
+	}
+	var authInfo *auth.AuthInfo
+	chk := func(ai *auth.AuthInfo) error {
+		authInfo = ai
+		return txn.CheckTxnAuth(s.authStore, ai, r)
+	}
+	defer trace.LogIfLong(traceThreshold)
+
+	var err error
+	get := func() {
+		err = txn.TxnStream(ctx, s.Logger(), r, s.KV(), s.lessor, func(resp *pb.TxnStreamResponse) error {
+			// responses are sent while the txn is read, so stale credentials
+			// have to be caught before each response instead of once at the end
+			if authInfo.Revision != 0 && authInfo.Revision != s.authStore.Revision() {
+				return auth.ErrAuthOldRevision
+			}
+			if resp.Header != nil {
+				resp.Header.ForwardedTo = uint64(forwardedTo)
+			}
+			return send(resp)
+		})
+	}
+	if serr := s.doSerialize(ctx, chk, get); serr != nil {
+		return serr
+	}
+	return err
+}
+
+// sendTxnResponse hands a complete txn response to send in the order of a
+// streamed one: first the header, then the response of every operation.
+func sendTxnResponse(resp *pb.TxnResponse, send func(*pb.TxnStreamResponse) error) error {
+	if err := send(&pb.TxnStreamResponse{Header: resp.Header, Succeeded: resp.Succeeded}); err != nil {
+		return err
+	}
+	for _, r := range resp.Responses {
+		if err := send(&pb.TxnStreamResponse{Response: r}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *EtcdServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error) {
 	startTime := time.Now()
 	result, err := s.processInternalRaftRequestOnce(ctx, pb.InternalRaftRequest{Compaction: r})
@@ -243,7 +383,29 @@ func (s *EtcdServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.
 	return resp, nil
 }
 
+func (s *EtcdServer) CompactKey(ctx context.Context, r *pb.CompactKeyRequest) (*pb.CompactKeyResponse, error) {
+	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{CompactKey: r})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.CompactKeyResponse), nil
+}
+
+func (s *EtcdServer) PrefixQuotaSet(ctx context.Context, r *pb.PrefixQuotaSetRequest) (*pb.PrefixQuotaSetResponse, error) {
+	if r.Quota.GetMaxBytes() < 0 || r.Quota.GetMaxKeys() < 0 {
+		return nil, errors.ErrInvalidPrefixQuota
+	}
+	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{PrefixQuotaSet: r})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.PrefixQuotaSetResponse), nil
+}
+
 func (s *EtcdServer) LeaseGrant(ctx context.Context, r *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	if err := s.checkRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	// no id given? choose one
 	for r.ID == int64(lease.NoLease) {
 		// only use positive int64 id's
@@ -256,6 +418,24 @@ func (s *EtcdServer) LeaseGrant(ctx context.Context, r *pb.LeaseGrantRequest) (*
 	return resp.(*pb.LeaseGrantResponse), nil
 }
 
+func (s *EtcdServer) LeaseGrantBatch(ctx context.Context, r *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	if err := s.checkRateLimit(ctx); err != nil {
+		return nil, err
+	}
+	for _, lr := range r.Leases {
+		// no id given? choose one
+		for lr.ID == int64(lease.NoLease) {
+			// only use positive int64 id's
+			lr.ID = int64(s.reqIDGen.Next() & ((1 << 63) - 1))
+		}
+	}
+	resp, err := s.raftRequestOnce(ctx, pb.InternalRaftRequest{LeaseGrantBatch: r})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.LeaseGrantBatchResponse), nil
+}
+
 func (s *EtcdServer) waitAppliedIndex() error {
 	select {
 	case <-s.ApplyWait():
@@ -269,6 +449,16 @@ func (s *EtcdServer) waitAppliedIndex() error {
 }
 
 func (s *EtcdServer) LeaseRevoke(ctx context.Context, r *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	resp, err := s.leaseRevoke(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	leaseRevokeRequested.Inc()
+	return resp, nil
+}
+
+// leaseRevoke revokes a lease, on request of a client or because it expired.
+func (s *EtcdServer) leaseRevoke(ctx context.Context, r *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
 	resp, err := s.raftRequestOnce(ctx, pb.InternalRaftRequest{LeaseRevoke: r})
 	if err != nil {
 		return nil, err
@@ -276,6 +466,31 @@ func (s *EtcdServer) LeaseRevoke(ctx context.Context, r *pb.LeaseRevokeRequest)
 	return resp.(*pb.LeaseRevokeResponse), nil
 }
 
+func (s *EtcdServer) LeaseReattach(ctx context.Context, r *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	// only the leader knows the remaining TTLs of the leases, so they are
+	// checked before proposing; an expired lease is waiting to be revoked.
+	oldl, err := s.leaseTimeToLive(ctx, &pb.LeaseTimeToLiveRequest{ID: r.OldID})
+	if err != nil {
+		return nil, err
+	}
+	newl, err := s.leaseTimeToLive(ctx, &pb.LeaseTimeToLiveRequest{ID: r.NewID})
+	if err != nil {
+		return nil, err
+	}
+	if oldl.TTL <= 0 || newl.TTL <= 0 {
+		return nil, lease.ErrLeaseNotFound
+	}
+	if !r.AllowShorterTTL && newl.TTL < oldl.TTL {
+		return nil, lease.ErrLeaseTTLTooShort
+	}
+
+	resp, err := s.raftRequestOnce(ctx, pb.InternalRaftRequest{LeaseReattach: r})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.LeaseReattachResponse), nil
+}
+
 func (s *EtcdServer) LeaseRenew(ctx context.Context, id lease.LeaseID) (int64, error) {
 	if s.isLeader() {
 		if err := s.waitAppliedIndex(); err != nil {
@@ -435,6 +650,67 @@ func (s *EtcdServer) LeaseLeases(_ context.Context, _ *pb.LeaseLeasesRequest) (*
 	return &pb.LeaseLeasesResponse{Header: s.newHeader(), Leases: lss}, nil
 }
 
+// LeaseListDetailed lists the leases of the leader, whose remaining TTLs are the
+// only ones that are up to date. Listing the keys attached to the leases
+// requires admin permission since they may span the whole key space.
+func (s *EtcdServer) LeaseListDetailed(ctx context.Context, r *pb.LeaseListDetailedRequest) (*pb.LeaseListDetailedResponse, error) {
+	if r.Keys {
+		if err := s.checkLeaseListKeys(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.isLeader() {
+		if err := s.waitAppliedIndex(); err != nil {
+			return nil, err
+		}
+		ls := s.lessor.Leases()
+		details := make([]*pb.LeaseDetail, len(ls))
+		for i := range ls {
+			details[i] = ls[i].Detail(r.Keys)
+		}
+		return &pb.LeaseListDetailedResponse{Header: s.newHeader(), Leases: details}, nil
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, s.Cfg.ReqTimeout())
+	defer cancel()
+
+	// forward to leader
+	for cctx.Err() == nil {
+		leader, err := s.waitLeader(cctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, url := range leader.PeerURLs {
+			lurl := url + leasehttp.LeaseListPrefix
+			resp, err := leasehttp.ListDetailedHTTP(cctx, r.Keys, lurl, s.peerRt)
+			if err == nil {
+				resp.LeaseListDetailedResponse.Header = s.newHeader()
+				return resp.LeaseListDetailedResponse, nil
+			}
+		}
+	}
+
+	if cctx.Err() == context.DeadlineExceeded {
+		return nil, errors.ErrTimeout
+	}
+	return nil, errors.ErrCanceled
+}
+
+func (s *EtcdServer) checkLeaseListKeys(ctx context.Context) error {
+	if !s.AuthStore().IsAuthEnabled() {
+		return nil
+	}
+	authInfo, err := s.AuthInfoFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+	if authInfo == nil {
+		return auth.ErrUserEmpty
+	}
+	return s.AuthStore().IsAdminPermitted(authInfo)
+}
+
 func (s *EtcdServer) waitLeader(ctx context.Context) (*membership.Member, error) {
 	leader := s.cluster.Member(s.Leader())
 	for leader == nil {
@@ -663,6 +939,22 @@ func (s *EtcdServer) RoleDelete(ctx context.Context, r *pb.AuthRoleDeleteRequest
 	return resp.(*pb.AuthRoleDeleteResponse), nil
 }
 
+func (s *EtcdServer) AuthExport(ctx context.Context, r *pb.AuthExportRequest) (*pb.AuthExportResponse, error) {
+	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{AuthExport: r})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.AuthExportResponse), nil
+}
+
+func (s *EtcdServer) AuthImport(ctx context.Context, r *pb.AuthImportRequest) (*pb.AuthImportResponse, error) {
+	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{AuthImport: r})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.AuthImportResponse), nil
+}
+
 func (s *EtcdServer) raftRequestOnce(ctx context.Context, r pb.InternalRaftRequest) (proto.Message, error) {
 	result, err := s.processInternalRaftRequestOnce(ctx, r)
 	if err != nil {
@@ -713,12 +1005,23 @@ func (s *EtcdServer) doSerialize(ctx context.Context, chk func(*auth.AuthInfo) e
 }
 
 func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.InternalRaftRequest) (*apply2.Result, error) {
-	ai := s.getAppliedIndex()
-	ci := s.getCommittedIndex()
-	if ci > ai+maxGapBetweenApplyAndCommitIndex {
+	// do not propose requests the client has already given up on
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return nil, errors.ErrDeadlineExceeded
+	}
+
+	// shed writes while the backend cannot keep up with applying them;
+	// reads are not proposed, so they are still served
+	if s.applyBacklog() > s.maxApplyBacklog() {
+		applyBacklogRejectedRequests.Inc()
 		return nil, errors.ErrTooManyRequests
 	}
 
+	// reject the writes while the key index is rebuilt, see RebuildIndex
+	if atomic.LoadInt32(&s.rebuildingIndex) != 0 {
+		return nil, errors.ErrRebuildingIndex
+	}
+
 	r.Header = &pb.RequestHeader{
 		ID: s.reqIDGen.Next(),
 	}
@@ -744,10 +1047,23 @@ func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.In
 		return nil, errors.ErrRequestTooLarge
 	}
 
+	select {
+	case <-s.proposalsStopped:
+		return nil, errors.ErrStopped
+	default:
+	}
+
 	id := r.ID
 	if id == 0 {
 		id = r.Header.ID
 	}
+	ctx, span := txn.StartSpan(ctx, "propose")
+	defer span.End()
+	if span.IsRecording() {
+		s.tracedProposals.Store(id, span)
+		defer s.tracedProposals.Delete(id)
+	}
+
 	ch := s.w.Register(id)
 
 	cctx, cancel := context.WithTimeout(ctx, s.Cfg.ReqTimeout())
@@ -770,6 +1086,10 @@ func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.In
 		proposalsFailed.Inc()
 		s.w.Trigger(id, nil) // GC wait
 		return nil, s.parseProposeCtxErr(cctx.Err(), start)
+	case <-s.proposalsStopped:
+		proposalsFailed.Inc()
+		s.w.Trigger(id, nil) // GC wait
+		return nil, errors.ErrStopped
 	case <-s.done:
 		return nil, errors.ErrStopped
 	}
@@ -823,6 +1143,9 @@ func (s *EtcdServer) linearizableReadLoop() {
 				return
 			}
 		}
+		if lead := types.ID(s.getLead()); lead != s.MemberId() {
+			nr.forwardedTo = lead
+		}
 		// unblock all l-reads requested at indices before confirmedIndex
 		nr.notify(nil)
 		trace.Step("applied index is now lower than readState.Index")
@@ -937,6 +1260,18 @@ func (s *EtcdServer) LinearizableReadNotify(ctx context.Context) error {
 }
 
 func (s *EtcdServer) linearizableReadNotify(ctx context.Context) error {
+	_, err := s.linearizableRead(ctx)
+	return err
+}
+
+// linearizableRead waits until the local member is up to date for a
+// linearizable read, and returns the leader the read index was requested
+// from if the local member is a follower.
+func (s *EtcdServer) linearizableRead(ctx context.Context) (types.ID, error) {
+	_, span := txn.StartSpan(ctx, "read index")
+	defer span.End()
+	start := time.Now()
+
 	s.readMu.RLock()
 	nc := s.readNotifier
 	s.readMu.RUnlock()
@@ -950,11 +1285,14 @@ func (s *EtcdServer) linearizableReadNotify(ctx context.Context) error {
 	// wait for read state notification
 	select {
 	case <-nc.c:
-		return nc.err
+		if nc.err == nil {
+			readIndexWaitSec.Observe(time.Since(start).Seconds())
+		}
+		return nc.forwardedTo, nc.err
 	case <-ctx.Done():
-		return ctx.Err()
+		return 0, ctx.Err()
 	case <-s.done:
-		return errors.ErrStopped
+		return 0, errors.ErrStopped
 	}
 }
 
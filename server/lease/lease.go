@@ -19,6 +19,7 @@ import (
 	"sync"
 	"time"
 
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/server/v3/lease/leasepb"
 	"go.etcd.io/etcd/server/v3/storage/backend"
 	"go.etcd.io/etcd/server/v3/storage/schema"
@@ -53,10 +54,14 @@ func (l *Lease) expired() bool {
 }
 
 func (l *Lease) persistTo(b backend.Backend) {
-	lpb := leasepb.Lease{ID: int64(l.ID), TTL: l.ttl, RemainingTTL: l.remainingTTL}
 	tx := b.BatchTx()
 	tx.LockInsideApply()
 	defer tx.Unlock()
+	l.unsafePersistTo(tx)
+}
+
+func (l *Lease) unsafePersistTo(tx backend.BatchTx) {
+	lpb := leasepb.Lease{ID: int64(l.ID), TTL: l.ttl, RemainingTTL: l.remainingTTL}
 	schema.MustUnsafePutLease(tx, &lpb)
 }
 
@@ -106,6 +111,33 @@ func (l *Lease) Keys() []string {
 	return keys
 }
 
+// KeyCount returns the number of keys attached to the lease.
+func (l *Lease) KeyCount() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.itemSet)
+}
+
+// Detail returns the TTLs of the lease and the number of keys attached to it,
+// along with the keys themselves if keys is set.
+func (l *Lease) Detail(keys bool) *pb.LeaseDetail {
+	d := &pb.LeaseDetail{
+		ID:         int64(l.ID),
+		TTL:        int64(l.Remaining().Seconds()),
+		GrantedTTL: l.TTL(),
+		KeyCount:   int64(l.KeyCount()),
+	}
+	if keys {
+		ks := l.Keys()
+		d.KeyCount = int64(len(ks))
+		d.Keys = make([][]byte, len(ks))
+		for i := range ks {
+			d.Keys[i] = []byte(ks[i])
+		}
+	}
+	return d
+}
+
 // Remaining returns the remaining time of the lease.
 func (l *Lease) Remaining() time.Duration {
 	l.expiryMu.RLock()
@@ -32,6 +32,7 @@ import (
 var (
 	LeasePrefix         = "/leases"
 	LeaseInternalPrefix = "/leases/internal"
+	LeaseListPrefix     = "/leases/list"
 	applyTimeout        = time.Second
 	ErrLeaseHTTPTimeout = errors.New("waiting for node to catch up its applied index has timed out")
 )
@@ -132,6 +133,36 @@ func (h *leaseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+	case LeaseListPrefix:
+		lreq := leasepb.LeaseInternalRequest{}
+		if lerr := lreq.Unmarshal(b); lerr != nil || lreq.LeaseListDetailedRequest == nil {
+			http.Error(w, "error unmarshalling request", http.StatusBadRequest)
+			return
+		}
+		select {
+		case <-h.waitch():
+		case <-time.After(applyTimeout):
+			http.Error(w, ErrLeaseHTTPTimeout.Error(), http.StatusRequestTimeout)
+			return
+		}
+		ls := h.l.Leases()
+		details := make([]*pb.LeaseDetail, len(ls))
+		for i := range ls {
+			details[i] = ls[i].Detail(lreq.LeaseListDetailedRequest.Keys)
+		}
+		// TODO: fill out ResponseHeader
+		resp := &leasepb.LeaseInternalResponse{
+			LeaseListDetailedResponse: &pb.LeaseListDetailedResponse{
+				Header: &pb.ResponseHeader{},
+				Leases: details,
+			},
+		}
+		v, err = resp.Marshal()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 	default:
 		http.Error(w, fmt.Sprintf("unknown request path %q", r.URL.Path), http.StatusBadRequest)
 		return
@@ -241,6 +272,50 @@ func TimeToLiveHTTP(ctx context.Context, id lease.LeaseID, keys bool, url string
 	return lresp, nil
 }
 
+// ListDetailedHTTP lists the leases of the given primary server with their
+// TTLs and the number of keys attached to them.
+func ListDetailedHTTP(ctx context.Context, keys bool, url string, rt http.RoundTripper) (*leasepb.LeaseInternalResponse, error) {
+	lreq, err := (&leasepb.LeaseInternalRequest{
+		LeaseListDetailedRequest: &pb.LeaseListDetailedRequest{Keys: keys},
+	}).Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(lreq))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/protobuf")
+
+	req = req.WithContext(ctx)
+
+	cc := &http.Client{Transport: rt}
+	resp, err := cc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusRequestTimeout {
+		return nil, ErrLeaseHTTPTimeout
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lease: unknown error(%s)", string(b))
+	}
+
+	lresp := &leasepb.LeaseInternalResponse{}
+	if err := lresp.Unmarshal(b); err != nil {
+		return nil, fmt.Errorf(`lease: %v. data = "%s"`, err, string(b))
+	}
+	if lresp.LeaseListDetailedResponse == nil {
+		return nil, fmt.Errorf("lease: missing lease list")
+	}
+	return lresp, nil
+}
+
 func readResponse(resp *http.Response) (b []byte, err error) {
 	b, err = io.ReadAll(resp.Body)
 	httputil.GracefulClose(resp)
@@ -16,8 +16,10 @@ package leasehttp
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
@@ -78,6 +80,56 @@ func TestTimeToLiveHTTP(t *testing.T) {
 	}
 }
 
+func TestListDetailedHTTP(t *testing.T) {
+	lg := zaptest.NewLogger(t)
+	be, _ := betesting.NewTmpBackend(t, time.Hour, 10000)
+	defer betesting.Close(t, be)
+
+	le := lease.NewLessor(lg, be, nil, lease.LessorConfig{MinLeaseTTL: int64(5)})
+	le.Promote(time.Second)
+	for id, keys := range map[lease.LeaseID]int{1: 0, 2: 3} {
+		if _, err := le.Grant(id, int64(5)); err != nil {
+			t.Fatalf("failed to create lease: %v", err)
+		}
+		for i := 0; i < keys; i++ {
+			if err := le.Attach(id, []lease.LeaseItem{{Key: fmt.Sprintf("foo%d", i)}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	ts := httptest.NewServer(NewHandler(le, waitReady))
+	defer ts.Close()
+
+	resp, err := ListDetailedHTTP(context.TODO(), false, ts.URL+LeaseListPrefix, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[int64]int64)
+	for _, d := range resp.LeaseListDetailedResponse.Leases {
+		if d.GrantedTTL != 5 {
+			t.Fatalf("granted TTL expected 5, got %d", d.GrantedTTL)
+		}
+		if len(d.Keys) != 0 {
+			t.Fatalf("expected no keys, got %q", d.Keys)
+		}
+		counts[d.ID] = d.KeyCount
+	}
+	if !reflect.DeepEqual(counts, map[int64]int64{1: 0, 2: 3}) {
+		t.Fatalf("unexpected key counts %v", counts)
+	}
+
+	resp, err = ListDetailedHTTP(context.TODO(), true, ts.URL+LeaseListPrefix, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range resp.LeaseListDetailedResponse.Leases {
+		if int64(len(d.Keys)) != d.KeyCount {
+			t.Fatalf("expected %d keys, got %q", d.KeyCount, d.Keys)
+		}
+	}
+}
+
 func TestRenewHTTPTimeout(t *testing.T) {
 	testApplyTimeout(t, func(l *lease.Lease, serverURL string) error {
 		_, err := RenewHTTP(context.TODO(), l.ID, serverURL+LeasePrefix, http.DefaultTransport)
@@ -92,6 +144,13 @@ func TestTimeToLiveHTTPTimeout(t *testing.T) {
 	})
 }
 
+func TestListDetailedHTTPTimeout(t *testing.T) {
+	testApplyTimeout(t, func(l *lease.Lease, serverURL string) error {
+		_, err := ListDetailedHTTP(context.TODO(), false, serverURL+LeaseListPrefix, http.DefaultTransport)
+		return err
+	})
+}
+
 func testApplyTimeout(t *testing.T, f func(*lease.Lease, string) error) {
 	lg := zaptest.NewLogger(t)
 	be, _ := betesting.NewTmpBackend(t, time.Hour, 10000)
@@ -68,10 +68,11 @@ func (m *Lease) XXX_DiscardUnknown() {
 var xxx_messageInfo_Lease proto.InternalMessageInfo
 
 type LeaseInternalRequest struct {
-	LeaseTimeToLiveRequest *etcdserverpb.LeaseTimeToLiveRequest `protobuf:"bytes,1,opt,name=LeaseTimeToLiveRequest,proto3" json:"LeaseTimeToLiveRequest,omitempty"`
-	XXX_NoUnkeyedLiteral   struct{}                             `json:"-"`
-	XXX_unrecognized       []byte                               `json:"-"`
-	XXX_sizecache          int32                                `json:"-"`
+	LeaseTimeToLiveRequest   *etcdserverpb.LeaseTimeToLiveRequest   `protobuf:"bytes,1,opt,name=LeaseTimeToLiveRequest,proto3" json:"LeaseTimeToLiveRequest,omitempty"`
+	LeaseListDetailedRequest *etcdserverpb.LeaseListDetailedRequest `protobuf:"bytes,2,opt,name=LeaseListDetailedRequest,proto3" json:"LeaseListDetailedRequest,omitempty"`
+	XXX_NoUnkeyedLiteral     struct{}                               `json:"-"`
+	XXX_unrecognized         []byte                                 `json:"-"`
+	XXX_sizecache            int32                                  `json:"-"`
 }
 
 func (m *LeaseInternalRequest) Reset()         { *m = LeaseInternalRequest{} }
@@ -108,10 +109,11 @@ func (m *LeaseInternalRequest) XXX_DiscardUnknown() {
 var xxx_messageInfo_LeaseInternalRequest proto.InternalMessageInfo
 
 type LeaseInternalResponse struct {
-	LeaseTimeToLiveResponse *etcdserverpb.LeaseTimeToLiveResponse `protobuf:"bytes,1,opt,name=LeaseTimeToLiveResponse,proto3" json:"LeaseTimeToLiveResponse,omitempty"`
-	XXX_NoUnkeyedLiteral    struct{}                              `json:"-"`
-	XXX_unrecognized        []byte                                `json:"-"`
-	XXX_sizecache           int32                                 `json:"-"`
+	LeaseTimeToLiveResponse   *etcdserverpb.LeaseTimeToLiveResponse   `protobuf:"bytes,1,opt,name=LeaseTimeToLiveResponse,proto3" json:"LeaseTimeToLiveResponse,omitempty"`
+	LeaseListDetailedResponse *etcdserverpb.LeaseListDetailedResponse `protobuf:"bytes,2,opt,name=LeaseListDetailedResponse,proto3" json:"LeaseListDetailedResponse,omitempty"`
+	XXX_NoUnkeyedLiteral      struct{}                                `json:"-"`
+	XXX_unrecognized          []byte                                  `json:"-"`
+	XXX_sizecache             int32                                   `json:"-"`
 }
 
 func (m *LeaseInternalResponse) Reset()         { *m = LeaseInternalResponse{} }
@@ -156,23 +158,26 @@ func init() {
 func init() { proto.RegisterFile("lease.proto", fileDescriptor_3dd57e402472b33a) }
 
 var fileDescriptor_3dd57e402472b33a = []byte{
-	// 256 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xce, 0x49, 0x4d, 0x2c,
-	0x4e, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x07, 0x73, 0x0a, 0x92, 0xa4, 0x44, 0xd2,
-	0xf3, 0xd3, 0xf3, 0xc1, 0x62, 0xfa, 0x20, 0x16, 0x44, 0x5a, 0x4a, 0x3e, 0xb5, 0x24, 0x39, 0x45,
-	0x3f, 0xb1, 0x20, 0x53, 0x1f, 0xc4, 0x28, 0x4e, 0x2d, 0x2a, 0x4b, 0x2d, 0x2a, 0x48, 0xd2, 0x2f,
-	0x2a, 0x48, 0x86, 0x28, 0x50, 0xf2, 0xe5, 0x62, 0xf5, 0x01, 0x99, 0x20, 0xc4, 0xc7, 0xc5, 0xe4,
-	0xe9, 0x22, 0xc1, 0xa8, 0xc0, 0xa8, 0xc1, 0x1c, 0xc4, 0xe4, 0xe9, 0x22, 0x24, 0xc0, 0xc5, 0x1c,
-	0x12, 0xe2, 0x23, 0xc1, 0x04, 0x16, 0x00, 0x31, 0x85, 0x94, 0xb8, 0x78, 0x82, 0x52, 0x73, 0x13,
-	0x33, 0xf3, 0x32, 0xf3, 0xd2, 0x41, 0x52, 0xcc, 0x60, 0x29, 0x14, 0x31, 0xa5, 0x12, 0x2e, 0x11,
-	0xb0, 0x71, 0x9e, 0x79, 0x25, 0xa9, 0x45, 0x79, 0x89, 0x39, 0x41, 0xa9, 0x85, 0xa5, 0xa9, 0xc5,
-	0x25, 0x42, 0x31, 0x5c, 0x62, 0x60, 0xf1, 0x90, 0xcc, 0xdc, 0xd4, 0x90, 0x7c, 0x9f, 0xcc, 0xb2,
-	0x54, 0xa8, 0x0c, 0xd8, 0x46, 0x6e, 0x23, 0x15, 0x3d, 0x64, 0xf7, 0xe9, 0x61, 0x57, 0x1b, 0x84,
-	0xc3, 0x0c, 0xa5, 0x0a, 0x2e, 0x51, 0x34, 0x5b, 0x8b, 0x0b, 0xf2, 0xf3, 0x8a, 0x53, 0x85, 0xe2,
-	0xb9, 0xc4, 0x31, 0xb4, 0x40, 0xa4, 0xa0, 0xf6, 0xaa, 0x12, 0xb0, 0x17, 0xa2, 0x38, 0x08, 0x97,
-	0x29, 0x4e, 0x12, 0x27, 0x1e, 0xca, 0x31, 0x5c, 0x78, 0x28, 0xc7, 0x70, 0xe2, 0x91, 0x1c, 0xe3,
-	0x85, 0x47, 0x72, 0x8c, 0x0f, 0x1e, 0xc9, 0x31, 0xce, 0x78, 0x2c, 0xc7, 0x90, 0xc4, 0x06, 0x0e,
-	0x5f, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0x7b, 0x8a, 0x94, 0xb9, 0xae, 0x01, 0x00, 0x00,
+	// 301 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x92, 0xcd, 0x4a, 0xc3, 0x40,
+	0x14, 0x85, 0x3b, 0x29, 0x2a, 0xdc, 0x8a, 0xc8, 0x50, 0x35, 0x76, 0x31, 0x4a, 0xf0, 0x6f, 0xd5,
+	0x80, 0xbe, 0x81, 0x64, 0x13, 0x88, 0x9b, 0x21, 0x4b, 0x41, 0x92, 0xf6, 0x12, 0x06, 0xd2, 0xcc,
+	0x98, 0x19, 0xfb, 0x2c, 0x3e, 0x52, 0x97, 0x5d, 0xba, 0xd3, 0xc6, 0x17, 0x91, 0x4c, 0x22, 0xf8,
+	0x93, 0xd0, 0xdd, 0x9d, 0x73, 0x0e, 0xdf, 0xbd, 0x07, 0x06, 0x46, 0x39, 0x26, 0x1a, 0xa7, 0xaa,
+	0x94, 0x46, 0xd2, 0x3d, 0xfb, 0x50, 0xe9, 0x64, 0x9c, 0xc9, 0x4c, 0x5a, 0xcd, 0xaf, 0xa7, 0xc6,
+	0x9e, 0x9c, 0xa1, 0x99, 0xcd, 0xfd, 0x44, 0x09, 0xbf, 0x1e, 0x34, 0x96, 0x4b, 0x2c, 0x55, 0xea,
+	0x97, 0x6a, 0xd6, 0x04, 0xbc, 0x07, 0xd8, 0x89, 0x6a, 0x02, 0x3d, 0x00, 0x27, 0x0c, 0x5c, 0x72,
+	0x4e, 0x6e, 0x86, 0xdc, 0x09, 0x03, 0x7a, 0x08, 0xc3, 0x38, 0x8e, 0x5c, 0xc7, 0x0a, 0xf5, 0x48,
+	0x3d, 0xd8, 0xe7, 0xb8, 0x48, 0x44, 0x21, 0x8a, 0xac, 0xb6, 0x86, 0xd6, 0xfa, 0xa5, 0x79, 0x6f,
+	0x04, 0xc6, 0x96, 0x17, 0x16, 0x06, 0xcb, 0x22, 0xc9, 0x39, 0x3e, 0xbf, 0xa0, 0x36, 0xf4, 0x11,
+	0x8e, 0xad, 0x1e, 0x8b, 0x05, 0xc6, 0x32, 0x12, 0x4b, 0x6c, 0x1d, 0xbb, 0x72, 0x74, 0x7b, 0x31,
+	0xfd, 0x79, 0xe0, 0xb4, 0x3b, 0xcb, 0x7b, 0x18, 0x34, 0x05, 0xd7, 0x3a, 0x91, 0xd0, 0x26, 0x40,
+	0x93, 0x88, 0x1c, 0xe7, 0xdf, 0x7c, 0xc7, 0xf2, 0xaf, 0x3a, 0xf8, 0x1d, 0x69, 0xde, 0xcb, 0xf1,
+	0xde, 0x09, 0x1c, 0xfd, 0xa9, 0xa6, 0x95, 0x2c, 0x34, 0xd2, 0x27, 0x38, 0xf9, 0x77, 0x57, 0x63,
+	0xb5, 0xe5, 0x2e, 0xb7, 0x94, 0x6b, 0xc2, 0xbc, 0x8f, 0x42, 0x11, 0x4e, 0x3b, 0xce, 0x6a, 0x57,
+	0x34, 0xfd, 0xae, 0xb7, 0xf6, 0x6b, 0x97, 0xf4, 0x93, 0xee, 0xdd, 0xd5, 0x86, 0x0d, 0xd6, 0x1b,
+	0x36, 0x58, 0x55, 0x8c, 0xac, 0x2b, 0x46, 0x3e, 0x2a, 0x46, 0x5e, 0x3f, 0xd9, 0x20, 0xdd, 0xb5,
+	0x9f, 0xe5, 0xee, 0x6b, 0x00, 0xcc, 0x3b, 0xca, 0x4e, 0x7b, 0x02, 0x00, 0x00,
 }
 
 func (m *Lease) Marshal() (dAtA []byte, err error) {
@@ -241,6 +246,18 @@ func (m *LeaseInternalRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.LeaseListDetailedRequest != nil {
+		{
+			size, err := m.LeaseListDetailedRequest.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintLease(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
 	if m.LeaseTimeToLiveRequest != nil {
 		{
 			size, err := m.LeaseTimeToLiveRequest.MarshalToSizedBuffer(dAtA[:i])
@@ -280,6 +297,18 @@ func (m *LeaseInternalResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.LeaseListDetailedResponse != nil {
+		{
+			size, err := m.LeaseListDetailedResponse.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintLease(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
 	if m.LeaseTimeToLiveResponse != nil {
 		{
 			size, err := m.LeaseTimeToLiveResponse.MarshalToSizedBuffer(dAtA[:i])
@@ -337,6 +366,10 @@ func (m *LeaseInternalRequest) Size() (n int) {
 		l = m.LeaseTimeToLiveRequest.Size()
 		n += 1 + l + sovLease(uint64(l))
 	}
+	if m.LeaseListDetailedRequest != nil {
+		l = m.LeaseListDetailedRequest.Size()
+		n += 1 + l + sovLease(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -353,6 +386,10 @@ func (m *LeaseInternalResponse) Size() (n int) {
 		l = m.LeaseTimeToLiveResponse.Size()
 		n += 1 + l + sovLease(uint64(l))
 	}
+	if m.LeaseListDetailedResponse != nil {
+		l = m.LeaseListDetailedResponse.Size()
+		n += 1 + l + sovLease(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -538,6 +575,42 @@ func (m *LeaseInternalRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaseListDetailedRequest", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowLease
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthLease
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthLease
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.LeaseListDetailedRequest == nil {
+				m.LeaseListDetailedRequest = &etcdserverpb.LeaseListDetailedRequest{}
+			}
+			if err := m.LeaseListDetailedRequest.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipLease(dAtA[iNdEx:])
@@ -625,6 +698,42 @@ func (m *LeaseInternalResponse) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaseListDetailedResponse", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowLease
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthLease
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthLease
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.LeaseListDetailedResponse == nil {
+				m.LeaseListDetailedResponse = &etcdserverpb.LeaseListDetailedResponse{}
+			}
+			if err := m.LeaseListDetailedResponse.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipLease(dAtA[iNdEx:])
@@ -19,6 +19,7 @@ import (
 	"context"
 	"errors"
 	"math"
+	"math/rand"
 	"sort"
 	"sync"
 	"time"
@@ -51,6 +52,10 @@ var (
 	// the default interval of lease checkpoint
 	defaultLeaseCheckpointInterval = 5 * time.Minute
 
+	// the minimum interval of lease checkpoint, so that short intervals do not
+	// flood the consensus log with checkpoints; configurable for tests
+	minLeaseCheckpointInterval = time.Second
+
 	// maximum number of lease checkpoints to batch into a single consensus log entry
 	maxLeaseCheckpointBatchSize = 1000
 
@@ -61,6 +66,7 @@ var (
 	ErrLeaseNotFound    = errors.New("lease not found")
 	ErrLeaseExists      = errors.New("lease already exists")
 	ErrLeaseTTLTooLarge = errors.New("too large lease TTL")
+	ErrLeaseTTLTooShort = errors.New("lease TTL shorter than the remaining TTL of the old lease")
 )
 
 // TxnDelete is a TxnWrite that only permits deletes. Defined here
@@ -79,6 +85,12 @@ type Checkpointer func(ctx context.Context, lc *pb.LeaseCheckpointRequest)
 
 type LeaseID int64
 
+// GrantRequest is a lease to grant in a batch.
+type GrantRequest struct {
+	ID  LeaseID
+	TTL int64
+}
+
 // Lessor owns leases. It can grant, revoke, renew and modify leases for lessee.
 type Lessor interface {
 	// SetRangeDeleter lets the lessor create TxnDeletes to the store.
@@ -90,6 +102,9 @@ type Lessor interface {
 
 	// Grant grants a lease that expires at least after TTL seconds.
 	Grant(id LeaseID, ttl int64) (*Lease, error)
+	// GrantBatch grants the given leases at once. If any of the leases cannot
+	// be granted, none of them is and an error is returned.
+	GrantBatch(reqs []GrantRequest) ([]*Lease, error)
 	// Revoke revokes a lease with given ID. The item attached to the
 	// given lease will be removed. If the ID does not exist, an error
 	// will be returned.
@@ -111,6 +126,11 @@ type Lessor interface {
 	// If the lease does not exist, an error will be returned.
 	Detach(id LeaseID, items []LeaseItem) error
 
+	// Reattach moves all items attached to the lease oldID to the lease newID
+	// at once and returns the moved items sorted by key. If either lease does
+	// not exist, an error will be returned.
+	Reattach(oldID, newID LeaseID) ([]LeaseItem, error)
+
 	// Promote promotes the lessor to be the primary lessor. Primary lessor manages
 	// the expiration and renew of leases.
 	// Newly promoted lessor renew the TTL of all lease to extend + previous TTL.
@@ -184,6 +204,8 @@ type lessor struct {
 	expiredLeaseRetryInterval time.Duration
 	// whether lessor should always persist remaining TTL (always enabled in v3.6).
 	checkpointPersist bool
+	// the window over which the revocation of leases expiring at the same time is spread
+	expiryJitter time.Duration
 	// cluster is used to adapt lessor logic based on cluster version
 	cluster cluster
 }
@@ -198,6 +220,11 @@ type LessorConfig struct {
 	CheckpointInterval         time.Duration
 	ExpiredLeasesRetryInterval time.Duration
 	CheckpointPersist          bool
+	// ExpiryJitter delays the revocation of each expired lease by a random
+	// duration within the window, so that leases granted with the same TTL
+	// at the same time are not all revoked at once. Only granted leases are
+	// jittered; renewed leases expire at their deadline. 0 disables jitter.
+	ExpiryJitter time.Duration
 }
 
 func NewLessor(lg *zap.Logger, b backend.Backend, cluster cluster, cfg LessorConfig) Lessor {
@@ -210,6 +237,14 @@ func newLessor(lg *zap.Logger, b backend.Backend, cluster cluster, cfg LessorCon
 	if checkpointInterval == 0 {
 		checkpointInterval = defaultLeaseCheckpointInterval
 	}
+	if checkpointInterval < minLeaseCheckpointInterval {
+		lg.Warn(
+			"lease checkpoint interval is too short; using the minimum",
+			zap.Duration("given", checkpointInterval),
+			zap.Duration("minimum", minLeaseCheckpointInterval),
+		)
+		checkpointInterval = minLeaseCheckpointInterval
+	}
 	if expiredLeaseRetryInterval == 0 {
 		expiredLeaseRetryInterval = defaultExpiredleaseRetryInterval
 	}
@@ -223,6 +258,7 @@ func newLessor(lg *zap.Logger, b backend.Backend, cluster cluster, cfg LessorCon
 		checkpointInterval:        checkpointInterval,
 		expiredLeaseRetryInterval: expiredLeaseRetryInterval,
 		checkpointPersist:         cfg.CheckpointPersist,
+		expiryJitter:              cfg.ExpiryJitter,
 		// expiredC is a small buffered chan to avoid unnecessary blocking.
 		expiredC: make(chan []*Lease, 16),
 		stopC:    make(chan struct{}),
@@ -269,48 +305,75 @@ func (le *lessor) SetCheckpointer(cp Checkpointer) {
 }
 
 func (le *lessor) Grant(id LeaseID, ttl int64) (*Lease, error) {
-	if id == NoLease {
-		return nil, ErrLeaseNotFound
+	ls, err := le.GrantBatch([]GrantRequest{{ID: id, TTL: ttl}})
+	if err != nil {
+		return nil, err
 	}
+	return ls[0], nil
+}
 
-	if ttl > MaxLeaseTTL {
-		return nil, ErrLeaseTTLTooLarge
-	}
+func (le *lessor) GrantBatch(reqs []GrantRequest) ([]*Lease, error) {
+	ls := make([]*Lease, len(reqs))
+	for i, r := range reqs {
+		if r.ID == NoLease {
+			return nil, ErrLeaseNotFound
+		}
+
+		if r.TTL > MaxLeaseTTL {
+			return nil, ErrLeaseTTLTooLarge
+		}
 
-	// TODO: when lessor is under high load, it should give out lease
-	// with longer TTL to reduce renew load.
-	l := NewLease(id, ttl)
+		// TODO: when lessor is under high load, it should give out lease
+		// with longer TTL to reduce renew load.
+		l := NewLease(r.ID, r.TTL)
 
-	if l.ttl < le.minLeaseTTL {
-		l.ttl = le.minLeaseTTL
+		if l.ttl < le.minLeaseTTL {
+			l.ttl = le.minLeaseTTL
+		}
+		ls[i] = l
 	}
 
 	le.mu.Lock()
 	defer le.mu.Unlock()
 
-	if _, ok := le.leaseMap[id]; ok {
-		return nil, ErrLeaseExists
+	// check all IDs before granting any lease
+	ids := make(map[LeaseID]struct{}, len(ls))
+	for _, l := range ls {
+		if _, ok := le.leaseMap[l.ID]; ok {
+			return nil, ErrLeaseExists
+		}
+		if _, ok := ids[l.ID]; ok {
+			return nil, ErrLeaseExists
+		}
+		ids[l.ID] = struct{}{}
 	}
 
-	if le.isPrimary() {
-		l.refresh(0)
-	} else {
-		l.forever()
-	}
+	tx := le.b.BatchTx()
+	tx.LockInsideApply()
+	for _, l := range ls {
+		if le.isPrimary() {
+			l.refresh(0)
+		} else {
+			l.forever()
+		}
 
-	le.leaseMap[id] = l
-	l.persistTo(le.b)
+		le.leaseMap[l.ID] = l
+		l.unsafePersistTo(tx)
 
-	leaseTotalTTLs.Observe(float64(l.ttl))
-	leaseGranted.Inc()
+		leaseTotalTTLs.Observe(float64(l.ttl))
+		leaseGranted.Inc()
+	}
+	tx.Unlock()
+	leaseActive.Set(float64(len(le.leaseMap)))
 
 	if le.isPrimary() {
-		item := &LeaseWithTime{id: l.ID, time: l.expiry}
-		le.leaseExpiredNotifier.RegisterOrUpdate(item)
-		le.scheduleCheckpointIfNeeded(l)
+		for _, l := range ls {
+			le.leaseExpiredNotifier.RegisterOrUpdate(le.jitteredExpiryItem(l))
+			le.scheduleCheckpointIfNeeded(l)
+		}
 	}
 
-	return l, nil
+	return ls, nil
 }
 
 func (le *lessor) Revoke(id LeaseID) error {
@@ -326,6 +389,7 @@ func (le *lessor) Revoke(id LeaseID) error {
 	// it may lead to deadlock with Grant or Checkpoint operations, which
 	// acquire the le.mu firstly and then the batchTx lock.
 	delete(le.leaseMap, id)
+	leaseActive.Set(float64(len(le.leaseMap)))
 
 	defer close(l.revokec)
 	// unlock before doing external work
@@ -363,8 +427,10 @@ func (le *lessor) Checkpoint(id LeaseID, remainingTTL int64) error {
 	if l, ok := le.leaseMap[id]; ok {
 		// when checkpointing, we only update the remainingTTL, Promote is responsible for applying this to lease expiry
 		l.remainingTTL = remainingTTL
+		leaseCheckpointed.Inc()
 		if le.shouldPersistCheckpoints() {
 			l.persistTo(le.b)
+			leaseCheckpointPersisted.Inc()
 		}
 		if le.isPrimary() {
 			// schedule the next checkpoint as needed
@@ -429,8 +495,7 @@ func (le *lessor) Renew(id LeaseID) (int64, error) {
 
 	le.mu.Lock()
 	l.refresh(0)
-	item := &LeaseWithTime{id: l.ID, time: l.expiry}
-	le.leaseExpiredNotifier.RegisterOrUpdate(item)
+	le.leaseExpiredNotifier.RegisterOrUpdate(&LeaseWithTime{id: l.ID, time: l.expiry})
 	le.mu.Unlock()
 
 	leaseRenewed.Inc()
@@ -467,9 +532,12 @@ func (le *lessor) Promote(extend time.Duration) {
 
 	// refresh the expiries of all leases.
 	for _, l := range le.leaseMap {
+		if l.remainingTTL > 0 && l.remainingTTL < l.ttl {
+			// the checkpointed remaining TTL is kept instead of the TTL
+			leaseCheckpointRestored.Inc()
+		}
 		l.refresh(extend)
-		item := &LeaseWithTime{id: l.ID, time: l.expiry}
-		le.leaseExpiredNotifier.RegisterOrUpdate(item)
+		le.leaseExpiredNotifier.RegisterOrUpdate(&LeaseWithTime{id: l.ID, time: l.expiry})
 		le.scheduleCheckpointIfNeeded(l)
 	}
 
@@ -507,8 +575,7 @@ func (le *lessor) Promote(extend time.Duration) {
 		delay := time.Duration(rateDelay)
 		nextWindow = baseWindow + delay
 		l.refresh(delay + extend)
-		item := &LeaseWithTime{id: l.ID, time: l.expiry}
-		le.leaseExpiredNotifier.RegisterOrUpdate(item)
+		le.leaseExpiredNotifier.RegisterOrUpdate(&LeaseWithTime{id: l.ID, time: l.expiry})
 		le.scheduleCheckpointIfNeeded(l)
 	}
 }
@@ -579,6 +646,40 @@ func (le *lessor) Detach(id LeaseID, items []LeaseItem) error {
 	return nil
 }
 
+// Reattach moves the items of the lease oldID to the lease newID. The items
+// are sorted so that the keys are rewritten in the same order among all
+// members.
+func (le *lessor) Reattach(oldID, newID LeaseID) ([]LeaseItem, error) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	ol, nl := le.leaseMap[oldID], le.leaseMap[newID]
+	if ol == nil || nl == nil {
+		return nil, ErrLeaseNotFound
+	}
+	if oldID == newID {
+		return nil, nil
+	}
+
+	ol.mu.Lock()
+	items := make([]LeaseItem, 0, len(ol.itemSet))
+	for it := range ol.itemSet {
+		items = append(items, it)
+	}
+	ol.itemSet = make(map[LeaseItem]struct{})
+	ol.mu.Unlock()
+
+	nl.mu.Lock()
+	for _, it := range items {
+		nl.itemSet[it] = struct{}{}
+		le.itemMap[it] = newID
+	}
+	nl.mu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	return items, nil
+}
+
 func (le *lessor) Recover(b backend.Backend, rd RangeDeleter) {
 	le.mu.Lock()
 	defer le.mu.Unlock()
@@ -617,6 +718,20 @@ func (le *lessor) runLoop() {
 	}
 }
 
+// jitteredExpiryItem returns the expiration notifier item of a newly granted
+// lease. With expiry jitter configured, the lease is checked for expiry at a
+// random point within the jitter window past its deadline, which spreads the
+// revocation of leases granted together over consecutive expiry scans. The lease
+// deadline itself is left untouched, so renewing or revoking the lease is never
+// affected. Renewed and promoted leases are checked at their deadline.
+func (le *lessor) jitteredExpiryItem(l *Lease) *LeaseWithTime {
+	t := l.expiry
+	if le.expiryJitter > 0 {
+		t = t.Add(time.Duration(rand.Int63n(int64(le.expiryJitter))))
+	}
+	return &LeaseWithTime{id: l.ID, time: t}
+}
+
 // revokeExpiredLeases finds all leases past their expiry and sends them to expired channel for
 // to be revoked.
 func (le *lessor) revokeExpiredLeases() {
@@ -810,6 +925,7 @@ func (le *lessor) initAndRecover() {
 	}
 	le.leaseExpiredNotifier.Init()
 	heap.Init(&le.leaseCheckpointHeap)
+	leaseActive.Set(float64(len(le.leaseMap)))
 
 	le.b.ForceCommit()
 }
@@ -829,6 +945,13 @@ func (fl *FakeLessor) Grant(id LeaseID, ttl int64) (*Lease, error) {
 	return nil, nil
 }
 
+func (fl *FakeLessor) GrantBatch(reqs []GrantRequest) ([]*Lease, error) {
+	for _, r := range reqs {
+		fl.LeaseSet[r.ID] = struct{}{}
+	}
+	return nil, nil
+}
+
 func (fl *FakeLessor) Revoke(id LeaseID) error { return nil }
 
 func (fl *FakeLessor) Checkpoint(id LeaseID, remainingTTL int64) error { return nil }
@@ -838,6 +961,8 @@ func (fl *FakeLessor) Attach(id LeaseID, items []LeaseItem) error { return nil }
 func (fl *FakeLessor) GetLease(item LeaseItem) LeaseID            { return 0 }
 func (fl *FakeLessor) Detach(id LeaseID, items []LeaseItem) error { return nil }
 
+func (fl *FakeLessor) Reattach(oldID, newID LeaseID) ([]LeaseItem, error) { return nil, nil }
+
 func (fl *FakeLessor) Promote(extend time.Duration) {}
 
 func (fl *FakeLessor) Demote() {}
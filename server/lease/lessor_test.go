@@ -104,6 +104,108 @@ func TestLessorGrant(t *testing.T) {
 	}
 }
 
+// TestLessorGrantBatch ensures Lessor grants all leases of a batch, or none
+// of them if any lease of the batch cannot be granted.
+func TestLessorGrantBatch(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	le := newLessor(lg, be, clusterLatest(), LessorConfig{MinLeaseTTL: minLeaseTTL})
+	defer le.Stop()
+	le.Promote(0)
+
+	ls, err := le.GrantBatch([]GrantRequest{{ID: 1, TTL: 1}, {ID: 2, TTL: 20}})
+	if err != nil {
+		t.Fatalf("could not grant leases 1 and 2 (%v)", err)
+	}
+	if len(ls) != 2 || ls[0].ID != 1 || ls[0].ttl != minLeaseTTL || ls[1].ID != 2 || ls[1].ttl != 20 {
+		t.Fatalf("leases = %+v, want leases 1 and 2 with ttls %d and 20", ls, minLeaseTTL)
+	}
+
+	tests := []struct {
+		name string
+		reqs []GrantRequest
+		werr error
+	}{
+		{"existing ID", []GrantRequest{{ID: 3, TTL: 10}, {ID: 1, TTL: 10}}, ErrLeaseExists},
+		{"duplicate ID", []GrantRequest{{ID: 3, TTL: 10}, {ID: 3, TTL: 10}}, ErrLeaseExists},
+		{"no ID", []GrantRequest{{ID: 3, TTL: 10}, {ID: NoLease, TTL: 10}}, ErrLeaseNotFound},
+		{"TTL too large", []GrantRequest{{ID: 3, TTL: 10}, {ID: 4, TTL: MaxLeaseTTL + 1}}, ErrLeaseTTLTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err = le.GrantBatch(tt.reqs); err != tt.werr {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.werr)
+		}
+	}
+
+	if n := len(le.Leases()); n != 2 {
+		t.Errorf("len(leases) = %d, want 2", n)
+	}
+	tx := be.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	if lpb := schema.MustUnsafeGetLease(tx, 3); lpb != nil {
+		t.Errorf("lpb = %v, want nil", lpb)
+	}
+}
+
+func TestLessorReattach(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	le := newLessor(lg, be, clusterLatest(), LessorConfig{MinLeaseTTL: minLeaseTTL})
+	defer le.Stop()
+
+	for _, id := range []LeaseID{1, 2} {
+		if _, err := le.Grant(id, 100); err != nil {
+			t.Fatalf("could not grant lease %d (%v)", id, err)
+		}
+	}
+	items := []LeaseItem{{Key: "foo2"}, {Key: "foo1"}, {Key: "foo3"}}
+	if err := le.Attach(1, items); err != nil {
+		t.Fatal(err)
+	}
+	if err := le.Attach(2, []LeaseItem{{Key: "bar"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := le.Reattach(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wmoved := []LeaseItem{{Key: "foo1"}, {Key: "foo2"}, {Key: "foo3"}}
+	if !reflect.DeepEqual(moved, wmoved) {
+		t.Errorf("moved = %v, want %v", moved, wmoved)
+	}
+	if keys := le.Lookup(1).Keys(); len(keys) != 0 {
+		t.Errorf("keys of lease 1 = %v, want none", keys)
+	}
+	keys := le.Lookup(2).Keys()
+	sort.Strings(keys)
+	if wkeys := []string{"bar", "foo1", "foo2", "foo3"}; !reflect.DeepEqual(keys, wkeys) {
+		t.Errorf("keys of lease 2 = %v, want %v", keys, wkeys)
+	}
+	for _, it := range items {
+		if id := le.GetLease(it); id != 2 {
+			t.Errorf("lease of %q = %d, want 2", it.Key, id)
+		}
+	}
+
+	if _, err = le.Reattach(1, 3); err != ErrLeaseNotFound {
+		t.Errorf("err = %v, want %v", err, ErrLeaseNotFound)
+	}
+	if _, err = le.Reattach(3, 2); err != ErrLeaseNotFound {
+		t.Errorf("err = %v, want %v", err, ErrLeaseNotFound)
+	}
+	if moved, err = le.Reattach(2, 2); err != nil || len(moved) != 0 {
+		t.Errorf("moved, err = %v, %v, want none, nil", moved, err)
+	}
+}
+
 // TestLeaseConcurrentKeys ensures Lease.Keys method calls are guarded
 // from concurrent map writes on 'itemSet'.
 func TestLeaseConcurrentKeys(t *testing.T) {
@@ -357,6 +459,127 @@ func TestLessorRenewExtendPileup(t *testing.T) {
 	}
 }
 
+// TestLessorExpiryJitter ensures Lessor spreads the revocation of leases
+// expiring at the same time over the jitter window.
+func TestLessorExpiryJitter(t *testing.T) {
+	oldRevokeRate := leaseRevokeRate
+	defer func() { leaseRevokeRate = oldRevokeRate }()
+	leaseRevokeRate = 100000
+
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	jitter := 2 * time.Second
+	le := newLessor(lg, be, clusterLatest(), LessorConfig{MinLeaseTTL: 1, ExpiryJitter: jitter})
+	defer le.Stop()
+	le.Promote(0)
+
+	n := 10000
+	ttl := time.Second
+	start := time.Now()
+	for i := 1; i <= n; i++ {
+		if _, err := le.Grant(LeaseID(i), int64(ttl.Seconds())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a lease that keeps being renewed must never be revoked
+	keepAlive, err := le.Grant(LeaseID(n+1), int64(ttl.Seconds()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renewTicker := time.NewTicker(ttl / 4)
+	defer renewTicker.Stop()
+	timeout := time.After(ttl + jitter + 5*time.Second)
+	expired := make(map[LeaseID]struct{})
+	var batches []int
+	for len(expired) < n {
+		select {
+		case ls := <-le.ExpiredLeasesC():
+			since := time.Since(start)
+			if since < ttl {
+				t.Fatalf("leases revoked after %v, before their TTL %v", since, ttl)
+			}
+			if since > ttl+jitter+time.Second {
+				t.Errorf("leases revoked after %v, past their TTL %v and jitter window %v", since, ttl, jitter)
+			}
+			batch := 0
+			for _, l := range ls {
+				if l.ID == keepAlive.ID {
+					t.Fatalf("kept-alive lease %d revoked", l.ID)
+				}
+				if _, ok := expired[l.ID]; !ok {
+					expired[l.ID] = struct{}{}
+					batch++
+				}
+			}
+			batches = append(batches, batch)
+		case <-renewTicker.C:
+			if _, err := le.Renew(keepAlive.ID); err != nil {
+				t.Fatal(err)
+			}
+		case <-timeout:
+			t.Fatalf("expected %d expired leases, got %d", n, len(expired))
+		}
+	}
+	// scanned every 500ms, the jitter window is covered by at least 4 scans
+	if len(batches) < 4 {
+		t.Fatalf("expected revocations to be spread over at least 4 batches, got %v", batches)
+	}
+	for _, b := range batches {
+		if b > n/2 {
+			t.Errorf("expected at most %d leases revoked at once, got %v", n/2, batches)
+			break
+		}
+	}
+}
+
+// TestLessorExpiryJitterGrantOnly ensures only granted leases are jittered, and
+// renewed or promoted leases are checked for expiry at their deadline.
+func TestLessorExpiryJitterGrantOnly(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	le := newLessor(lg, be, clusterLatest(), LessorConfig{MinLeaseTTL: 1, ExpiryJitter: time.Hour})
+	defer le.Stop()
+	le.Promote(0)
+
+	itemTime := func(id LeaseID) time.Time {
+		le.mu.RLock()
+		defer le.mu.RUnlock()
+		return le.leaseExpiredNotifier.m[id].time
+	}
+
+	// with a jitter window of an hour, the lease is jittered with high probability
+	var l *Lease
+	for i := 1; ; i++ {
+		var err error
+		if l, err = le.Grant(LeaseID(i), 10); err != nil {
+			t.Fatal(err)
+		}
+		if itemTime(l.ID).After(l.expiry) {
+			break
+		}
+	}
+
+	if _, err := le.Renew(l.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := itemTime(l.ID); !got.Equal(l.expiry) {
+		t.Errorf("expected renewed lease checked at its deadline %v, got %v", l.expiry, got)
+	}
+
+	le.Demote()
+	le.Promote(0)
+	if got := itemTime(l.ID); !got.Equal(l.expiry) {
+		t.Errorf("expected promoted lease checked at its deadline %v, got %v", l.expiry, got)
+	}
+}
+
 func TestLessorDetach(t *testing.T) {
 	lg := zap.NewNop()
 	dir, be := NewTestBackend(t)
@@ -580,6 +803,19 @@ func TestLessorCheckpointScheduling(t *testing.T) {
 	}
 }
 
+func TestLessorCheckpointIntervalMinimum(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	le := newLessor(lg, be, clusterLatest(), LessorConfig{MinLeaseTTL: minLeaseTTL, CheckpointInterval: time.Millisecond})
+	defer le.Stop()
+	if le.checkpointInterval != minLeaseCheckpointInterval {
+		t.Fatalf("expected checkpoint interval %v, got %v", minLeaseCheckpointInterval, le.checkpointInterval)
+	}
+}
+
 func TestLessorCheckpointsRestoredOnPromote(t *testing.T) {
 	lg := zap.NewNop()
 	dir, be := NewTestBackend(t)
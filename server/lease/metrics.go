@@ -40,6 +40,34 @@ var (
 		Help:      "The number of renewed leases seen by the leader.",
 	})
 
+	leaseCheckpointed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "checkpointed_total",
+		Help:      "The total number of lease checkpoints applied.",
+	})
+
+	leaseCheckpointPersisted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "checkpoint_persisted_total",
+		Help:      "The total number of lease checkpoints persisted.",
+	})
+
+	leaseCheckpointRestored = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "checkpoint_restored_total",
+		Help:      "The total number of leases whose remaining TTL was shortened to their last checkpoint when the member became the leader.",
+	})
+
+	leaseActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "active",
+		Help:      "The number of leases currently granted and not revoked.",
+	})
+
 	leaseTotalTTLs = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: "etcd_debugging",
@@ -55,5 +83,9 @@ func init() {
 	prometheus.MustRegister(leaseGranted)
 	prometheus.MustRegister(leaseRevoked)
 	prometheus.MustRegister(leaseRenewed)
+	prometheus.MustRegister(leaseCheckpointed)
+	prometheus.MustRegister(leaseCheckpointPersisted)
+	prometheus.MustRegister(leaseCheckpointRestored)
+	prometheus.MustRegister(leaseActive)
 	prometheus.MustRegister(leaseTotalTTLs)
 }
@@ -16,6 +16,7 @@ package adapter
 
 import (
 	"context"
+	"io"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 
@@ -44,6 +45,40 @@ func (s *kvs2kvc) Txn(ctx context.Context, in *pb.TxnRequest, opts ...grpc.CallO
 	return s.kvs.Txn(ctx, in)
 }
 
+func (s *kvs2kvc) TxnStream(ctx context.Context, in *pb.TxnRequest, opts ...grpc.CallOption) (pb.KV_TxnStreamClient, error) {
+	cs := newPipeStream(ctx, func(ss chanServerStream) error {
+		if err := s.kvs.TxnStream(in, &ts2tcServerStream{ss}); err != nil {
+			return err
+		}
+		// the pipe only cancels the stream once the handler returns; end it
+		// the way a grpc stream does
+		return io.EOF
+	})
+	return &ts2tcClientStream{cs}, nil
+}
+
 func (s *kvs2kvc) Compact(ctx context.Context, in *pb.CompactionRequest, opts ...grpc.CallOption) (*pb.CompactionResponse, error) {
 	return s.kvs.Compact(ctx, in)
 }
+
+func (s *kvs2kvc) CompactKey(ctx context.Context, in *pb.CompactKeyRequest, opts ...grpc.CallOption) (*pb.CompactKeyResponse, error) {
+	return s.kvs.CompactKey(ctx, in)
+}
+
+// ts2tcClientStream implements KV_TxnStreamClient
+type ts2tcClientStream struct{ chanClientStream }
+
+// ts2tcServerStream implements KV_TxnStreamServer
+type ts2tcServerStream struct{ chanServerStream }
+
+func (s *ts2tcClientStream) Recv() (*pb.TxnStreamResponse, error) {
+	var v interface{}
+	if err := s.RecvMsg(&v); err != nil {
+		return nil, err
+	}
+	return v.(*pb.TxnStreamResponse), nil
+}
+
+func (s *ts2tcServerStream) Send(resp *pb.TxnStreamResponse) error {
+	return s.SendMsg(resp)
+}
@@ -34,6 +34,14 @@ func (c *ls2lc) LeaseGrant(ctx context.Context, in *pb.LeaseGrantRequest, opts .
 	return c.leaseServer.LeaseGrant(ctx, in)
 }
 
+func (c *ls2lc) LeaseGrantBatch(ctx context.Context, in *pb.LeaseGrantBatchRequest, opts ...grpc.CallOption) (*pb.LeaseGrantBatchResponse, error) {
+	return c.leaseServer.LeaseGrantBatch(ctx, in)
+}
+
+func (c *ls2lc) LeaseReattach(ctx context.Context, in *pb.LeaseReattachRequest, opts ...grpc.CallOption) (*pb.LeaseReattachResponse, error) {
+	return c.leaseServer.LeaseReattach(ctx, in)
+}
+
 func (c *ls2lc) LeaseRevoke(ctx context.Context, in *pb.LeaseRevokeRequest, opts ...grpc.CallOption) (*pb.LeaseRevokeResponse, error) {
 	return c.leaseServer.LeaseRevoke(ctx, in)
 }
@@ -53,6 +61,10 @@ func (c *ls2lc) LeaseLeases(ctx context.Context, in *pb.LeaseLeasesRequest, opts
 	return c.leaseServer.LeaseLeases(ctx, in)
 }
 
+func (c *ls2lc) LeaseListDetailed(ctx context.Context, in *pb.LeaseListDetailedRequest, opts ...grpc.CallOption) (*pb.LeaseListDetailedResponse, error) {
+	return c.leaseServer.LeaseListDetailed(ctx, in)
+}
+
 // ls2lcClientStream implements Lease_LeaseKeepAliveClient
 type ls2lcClientStream struct{ chanClientStream }
 
@@ -56,6 +56,58 @@ func (s *mts2mtc) Downgrade(ctx context.Context, r *pb.DowngradeRequest, opts ..
 	return s.mts.Downgrade(ctx, r)
 }
 
+func (s *mts2mtc) CompactionStatus(ctx context.Context, r *pb.CompactionStatusRequest, opts ...grpc.CallOption) (*pb.CompactionStatusResponse, error) {
+	return s.mts.CompactionStatus(ctx, r)
+}
+
+func (s *mts2mtc) HotKeys(ctx context.Context, r *pb.HotKeysRequest, opts ...grpc.CallOption) (*pb.HotKeysResponse, error) {
+	return s.mts.HotKeys(ctx, r)
+}
+
+func (s *mts2mtc) HashKVByRange(ctx context.Context, r *pb.HashKVByRangeRequest, opts ...grpc.CallOption) (*pb.HashKVResponse, error) {
+	return s.mts.HashKVByRange(ctx, r)
+}
+
+func (s *mts2mtc) SpaceReclaim(ctx context.Context, r *pb.SpaceReclaimRequest, opts ...grpc.CallOption) (*pb.SpaceReclaimResponse, error) {
+	return s.mts.SpaceReclaim(ctx, r)
+}
+
+func (s *mts2mtc) MemberSelf(ctx context.Context, r *pb.MemberSelfRequest, opts ...grpc.CallOption) (*pb.MemberSelfResponse, error) {
+	return s.mts.MemberSelf(ctx, r)
+}
+
+func (s *mts2mtc) TriggerSnapshot(ctx context.Context, r *pb.TriggerSnapshotRequest, opts ...grpc.CallOption) (*pb.TriggerSnapshotResponse, error) {
+	return s.mts.TriggerSnapshot(ctx, r)
+}
+
+func (s *mts2mtc) AuthExport(ctx context.Context, r *pb.AuthExportRequest, opts ...grpc.CallOption) (*pb.AuthExportResponse, error) {
+	return s.mts.AuthExport(ctx, r)
+}
+
+func (s *mts2mtc) AuthImport(ctx context.Context, r *pb.AuthImportRequest, opts ...grpc.CallOption) (*pb.AuthImportResponse, error) {
+	return s.mts.AuthImport(ctx, r)
+}
+
+func (s *mts2mtc) PrefixQuotaSet(ctx context.Context, r *pb.PrefixQuotaSetRequest, opts ...grpc.CallOption) (*pb.PrefixQuotaSetResponse, error) {
+	return s.mts.PrefixQuotaSet(ctx, r)
+}
+
+func (s *mts2mtc) PrefixQuotaList(ctx context.Context, r *pb.PrefixQuotaListRequest, opts ...grpc.CallOption) (*pb.PrefixQuotaListResponse, error) {
+	return s.mts.PrefixQuotaList(ctx, r)
+}
+
+func (s *mts2mtc) VerifyBackend(ctx context.Context, r *pb.VerifyBackendRequest, opts ...grpc.CallOption) (*pb.VerifyBackendResponse, error) {
+	return s.mts.VerifyBackend(ctx, r)
+}
+
+func (s *mts2mtc) KeyspaceStats(ctx context.Context, r *pb.KeyspaceStatsRequest, opts ...grpc.CallOption) (*pb.KeyspaceStatsResponse, error) {
+	return s.mts.KeyspaceStats(ctx, r)
+}
+
+func (s *mts2mtc) RebuildIndex(ctx context.Context, r *pb.RebuildIndexRequest, opts ...grpc.CallOption) (*pb.RebuildIndexResponse, error) {
+	return s.mts.RebuildIndex(ctx, r)
+}
+
 func (s *mts2mtc) Snapshot(ctx context.Context, in *pb.SnapshotRequest, opts ...grpc.CallOption) (pb.Maintenance_SnapshotClient, error) {
 	cs := newPipeStream(ctx, func(ss chanServerStream) error {
 		return s.mts.Snapshot(in, &ss2scServerStream{ss})
@@ -17,6 +17,7 @@ package adapter
 import (
 	"context"
 	"errors"
+	"io"
 
 	"google.golang.org/grpc"
 
@@ -65,3 +66,33 @@ func (s *ws2wcServerStream) Recv() (*pb.WatchRequest, error) {
 	}
 	return v.(*pb.WatchRequest), nil
 }
+
+func (s *ws2wc) Replay(ctx context.Context, in *pb.ReplayRequest, opts ...grpc.CallOption) (pb.Watch_ReplayClient, error) {
+	cs := newPipeStream(ctx, func(ss chanServerStream) error {
+		if err := s.wserv.Replay(in, &rs2rcServerStream{ss}); err != nil {
+			return err
+		}
+		// the pipe only cancels the stream once the handler returns; end it
+		// the way a grpc stream does
+		return io.EOF
+	})
+	return &rs2rcClientStream{cs}, nil
+}
+
+// rs2rcClientStream implements Watch_ReplayClient
+type rs2rcClientStream struct{ chanClientStream }
+
+// rs2rcServerStream implements Watch_ReplayServer
+type rs2rcServerStream struct{ chanServerStream }
+
+func (s *rs2rcClientStream) Recv() (*pb.ReplayResponse, error) {
+	var v interface{}
+	if err := s.RecvMsg(&v); err != nil {
+		return nil, err
+	}
+	return v.(*pb.ReplayResponse), nil
+}
+
+func (s *rs2rcServerStream) Send(resp *pb.ReplayResponse) error {
+	return s.SendMsg(resp)
+}
@@ -16,6 +16,7 @@ package grpcproxy
 
 import (
 	"context"
+	"io"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -100,12 +101,22 @@ func (p *kvProxy) txnToCache(reqs []*pb.RequestOp, resps []*pb.ResponseOp) {
 }
 
 func (p *kvProxy) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
-	op := TxnRequestToOp(r)
-	opResp, err := p.kv.Do(ctx, op)
-	if err != nil {
-		return nil, err
+	var resp *clientv3.TxnResponse
+	if r.DryRun {
+		// an Op cannot be a dry run
+		cmps, thenOps, elseOps := TxnRequestToOp(r).Txn()
+		var err error
+		resp, err = p.kv.Txn(ctx).If(cmps...).Then(thenOps...).Else(elseOps...).WithDryRun().Commit()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		opResp, err := p.kv.Do(ctx, TxnRequestToOp(r))
+		if err != nil {
+			return nil, err
+		}
+		resp = opResp.Txn()
 	}
-	resp := opResp.Txn()
 
 	// txn may claim an outdated key is updated; be safe and invalidate
 	for _, cmp := range r.Compare {
@@ -123,6 +134,47 @@ func (p *kvProxy) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, e
 	return (*pb.TxnResponse)(resp), nil
 }
 
+func (p *kvProxy) TxnStream(r *pb.TxnRequest, stream pb.KV_TxnStreamServer) error {
+	cmps, thenOps, elseOps := TxnRequestToOp(r).Txn()
+	txn := p.kv.Txn(stream.Context()).If(cmps...).Then(thenOps...).Else(elseOps...)
+	if r.DryRun {
+		txn = txn.WithDryRun()
+	}
+	ts, err := clientv3.CommitStream(txn)
+	if err != nil {
+		return err
+	}
+
+	// txn may claim an outdated key is updated; be safe and invalidate
+	for _, cmp := range r.Compare {
+		p.cache.Invalidate(cmp.Key, cmp.RangeEnd)
+	}
+	reqs := r.Success
+	if !ts.Succeeded {
+		reqs = r.Failure
+	}
+	if err = stream.Send(&pb.TxnStreamResponse{Header: ts.Header, Succeeded: ts.Succeeded}); err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		resp, err := ts.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// update any fetched keys
+		p.txnToCache(reqs[i:i+1], []*pb.ResponseOp{resp})
+		if err = stream.Send(&pb.TxnStreamResponse{Response: resp}); err != nil {
+			return err
+		}
+	}
+
+	cacheKeys.Set(float64(p.cache.Size()))
+	return nil
+}
+
 func (p *kvProxy) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error) {
 	var opts []clientv3.CompactOption
 	if r.Physical {
@@ -139,6 +191,14 @@ func (p *kvProxy) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.Com
 	return (*pb.CompactionResponse)(resp), err
 }
 
+func (p *kvProxy) CompactKey(ctx context.Context, r *pb.CompactKeyRequest) (*pb.CompactKeyResponse, error) {
+	p.cache.Invalidate(r.Key, nil)
+	cacheKeys.Set(float64(p.cache.Size()))
+
+	resp, err := p.kv.Do(ctx, clientv3.OpCompactKey(string(r.Key), r.Keep))
+	return (*pb.CompactKeyResponse)(resp.CompactKey()), err
+}
+
 func requestOpToOp(union *pb.RequestOp) clientv3.Op {
 	switch tv := union.Request.(type) {
 	case *pb.RequestOp_RequestRange:
@@ -182,6 +242,9 @@ func RangeRequestToOp(r *pb.RangeRequest) clientv3.Op {
 	if r.KeysOnly {
 		opts = append(opts, clientv3.WithKeysOnly())
 	}
+	if r.Reverse {
+		opts = append(opts, clientv3.WithReverse())
+	}
 	if r.Serializable {
 		opts = append(opts, clientv3.WithSerializable())
 	}
@@ -201,6 +264,15 @@ func PutRequestToOp(r *pb.PutRequest) clientv3.Op {
 	if r.PrevKv {
 		opts = append(opts, clientv3.WithPrevKV())
 	}
+	if r.Ttl != 0 {
+		opts = append(opts, clientv3.WithTTL(r.Ttl))
+	}
+	if len(r.CopyFrom) != 0 {
+		opts = append(opts, clientv3.WithCopyFrom(string(r.CopyFrom)))
+	}
+	if r.CopyLease {
+		opts = append(opts, clientv3.WithCopyLease())
+	}
 	return clientv3.OpPut(string(r.Key), string(r.Value), opts...)
 }
 
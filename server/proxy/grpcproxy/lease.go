@@ -82,6 +82,24 @@ func (lp *leaseProxy) LeaseGrant(ctx context.Context, cr *pb.LeaseGrantRequest)
 	return rp, nil
 }
 
+func (lp *leaseProxy) LeaseGrantBatch(ctx context.Context, cr *pb.LeaseGrantBatchRequest) (*pb.LeaseGrantBatchResponse, error) {
+	rp, err := lp.leaseClient.LeaseGrantBatch(ctx, cr, grpc.WaitForReady(true))
+	if err != nil {
+		return nil, err
+	}
+	lp.leader.gotLeader()
+	return rp, nil
+}
+
+func (lp *leaseProxy) LeaseReattach(ctx context.Context, rr *pb.LeaseReattachRequest) (*pb.LeaseReattachResponse, error) {
+	rp, err := lp.leaseClient.LeaseReattach(ctx, rr, grpc.WaitForReady(true))
+	if err != nil {
+		return nil, err
+	}
+	lp.leader.gotLeader()
+	return rp, nil
+}
+
 func (lp *leaseProxy) LeaseRevoke(ctx context.Context, rr *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
 	r, err := lp.lessor.Revoke(ctx, clientv3.LeaseID(rr.ID))
 	if err != nil {
@@ -130,6 +148,10 @@ func (lp *leaseProxy) LeaseLeases(ctx context.Context, rr *pb.LeaseLeasesRequest
 	return rp, err
 }
 
+func (lp *leaseProxy) LeaseListDetailed(ctx context.Context, rr *pb.LeaseListDetailedRequest) (*pb.LeaseListDetailedResponse, error) {
+	return lp.leaseClient.LeaseListDetailed(ctx, rr)
+}
+
 func (lp *leaseProxy) LeaseKeepAlive(stream pb.Lease_LeaseKeepAliveServer) error {
 	lp.mu.Lock()
 	select {
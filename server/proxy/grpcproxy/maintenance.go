@@ -85,3 +85,55 @@ func (mp *maintenanceProxy) MoveLeader(ctx context.Context, r *pb.MoveLeaderRequ
 func (mp *maintenanceProxy) Downgrade(ctx context.Context, r *pb.DowngradeRequest) (*pb.DowngradeResponse, error) {
 	return mp.maintenanceClient.Downgrade(ctx, r)
 }
+
+func (mp *maintenanceProxy) CompactionStatus(ctx context.Context, r *pb.CompactionStatusRequest) (*pb.CompactionStatusResponse, error) {
+	return mp.maintenanceClient.CompactionStatus(ctx, r)
+}
+
+func (mp *maintenanceProxy) HotKeys(ctx context.Context, r *pb.HotKeysRequest) (*pb.HotKeysResponse, error) {
+	return mp.maintenanceClient.HotKeys(ctx, r)
+}
+
+func (mp *maintenanceProxy) HashKVByRange(ctx context.Context, r *pb.HashKVByRangeRequest) (*pb.HashKVResponse, error) {
+	return mp.maintenanceClient.HashKVByRange(ctx, r)
+}
+
+func (mp *maintenanceProxy) SpaceReclaim(ctx context.Context, r *pb.SpaceReclaimRequest) (*pb.SpaceReclaimResponse, error) {
+	return mp.maintenanceClient.SpaceReclaim(ctx, r)
+}
+
+func (mp *maintenanceProxy) MemberSelf(ctx context.Context, r *pb.MemberSelfRequest) (*pb.MemberSelfResponse, error) {
+	return mp.maintenanceClient.MemberSelf(ctx, r)
+}
+
+func (mp *maintenanceProxy) TriggerSnapshot(ctx context.Context, r *pb.TriggerSnapshotRequest) (*pb.TriggerSnapshotResponse, error) {
+	return mp.maintenanceClient.TriggerSnapshot(ctx, r)
+}
+
+func (mp *maintenanceProxy) AuthExport(ctx context.Context, r *pb.AuthExportRequest) (*pb.AuthExportResponse, error) {
+	return mp.maintenanceClient.AuthExport(ctx, r)
+}
+
+func (mp *maintenanceProxy) AuthImport(ctx context.Context, r *pb.AuthImportRequest) (*pb.AuthImportResponse, error) {
+	return mp.maintenanceClient.AuthImport(ctx, r)
+}
+
+func (mp *maintenanceProxy) PrefixQuotaSet(ctx context.Context, r *pb.PrefixQuotaSetRequest) (*pb.PrefixQuotaSetResponse, error) {
+	return mp.maintenanceClient.PrefixQuotaSet(ctx, r)
+}
+
+func (mp *maintenanceProxy) PrefixQuotaList(ctx context.Context, r *pb.PrefixQuotaListRequest) (*pb.PrefixQuotaListResponse, error) {
+	return mp.maintenanceClient.PrefixQuotaList(ctx, r)
+}
+
+func (mp *maintenanceProxy) VerifyBackend(ctx context.Context, r *pb.VerifyBackendRequest) (*pb.VerifyBackendResponse, error) {
+	return mp.maintenanceClient.VerifyBackend(ctx, r)
+}
+
+func (mp *maintenanceProxy) KeyspaceStats(ctx context.Context, r *pb.KeyspaceStatsRequest) (*pb.KeyspaceStatsResponse, error) {
+	return mp.maintenanceClient.KeyspaceStats(ctx, r)
+}
+
+func (mp *maintenanceProxy) RebuildIndex(ctx context.Context, r *pb.RebuildIndexRequest) (*pb.RebuildIndexResponse, error) {
+	return mp.maintenanceClient.RebuildIndex(ctx, r)
+}
@@ -24,6 +24,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3rpc"
@@ -164,6 +165,28 @@ func (wp *watchProxy) Watch(stream pb.Watch_WatchServer) (err error) {
 	}
 }
 
+// Replay forwards the replay to the server; unlike watches, replays are not
+// coalesced.
+func (wp *watchProxy) Replay(r *pb.ReplayRequest, stream pb.Watch_ReplayServer) error {
+	var opts []clientv3.OpOption
+	if len(r.RangeEnd) != 0 {
+		opts = append(opts, clientv3.WithRange(string(r.RangeEnd)))
+	}
+	for wr := range clientv3.Replay(stream.Context(), wp.cw, string(r.Key), r.StartRevision, r.EndRevision, opts...) {
+		if err := wr.Err(); err != nil {
+			return err
+		}
+		resp := &pb.ReplayResponse{Header: &wr.Header, Events: make([]*mvccpb.Event, len(wr.Events))}
+		for i, ev := range wr.Events {
+			resp.Events[i] = (*mvccpb.Event)(ev)
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return stream.Context().Err()
+}
+
 // watchProxyStream forwards etcd watch events to a proxied client stream.
 type watchProxyStream struct {
 	ranges *watchRanges
@@ -235,6 +258,18 @@ func (wps *watchProxyStream) recvLoop() error {
 		case *pb.WatchRequest_CreateRequest:
 			cr := uv.CreateRequest
 
+			if len(cr.Ranges) != 0 {
+				// watchers are shared by range, so a watcher cannot have several
+				wps.watchCh <- &pb.WatchResponse{
+					Header:       &pb.ResponseHeader{},
+					WatchId:      clientv3.InvalidWatchID,
+					Created:      true,
+					Canceled:     true,
+					CancelReason: "grpcproxy: watching several ranges in one watch is not supported",
+				}
+				continue
+			}
+
 			if err := wps.checkPermissionForWatch(cr.Key, cr.RangeEnd); err != nil {
 				wps.watchCh <- &pb.WatchResponse{
 					Header:       &pb.ResponseHeader{},
@@ -28,6 +28,12 @@ import (
 	"go.uber.org/zap"
 )
 
+// MinimumBackendBatchInterval is the shortest backend batch interval. Each
+// commit of the batch transaction syncs the backend file, so a shorter
+// interval would make a write heavy member sync the file continuously; at
+// this interval a member syncs it at most 100 times per second.
+const MinimumBackendBatchInterval = 10 * time.Millisecond
+
 func newBackend(cfg config.ServerConfig, hooks backend.Hooks) backend.Backend {
 	bcfg := backend.DefaultBackendConfig(cfg.Logger)
 	bcfg.Path = cfg.BackendPath()
@@ -40,8 +46,18 @@ func newBackend(cfg config.ServerConfig, hooks backend.Hooks) backend.Backend {
 	}
 	if cfg.BackendBatchInterval != 0 {
 		bcfg.BatchInterval = cfg.BackendBatchInterval
+		if bcfg.BatchInterval < MinimumBackendBatchInterval {
+			bcfg.BatchInterval = MinimumBackendBatchInterval
+			if cfg.Logger != nil {
+				cfg.Logger.Warn(
+					"backend batch interval is too short; using the minimum",
+					zap.Duration("given", cfg.BackendBatchInterval),
+					zap.Duration("minimum", MinimumBackendBatchInterval),
+				)
+			}
+		}
 		if cfg.Logger != nil {
-			cfg.Logger.Info("setting backend batch interval", zap.Duration("batch interval", cfg.BackendBatchInterval))
+			cfg.Logger.Info("setting backend batch interval", zap.Duration("batch interval", bcfg.BatchInterval))
 		}
 	}
 	bcfg.BackendFreelistType = cfg.BackendFreelistType
@@ -15,6 +15,8 @@
 package backend
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -43,6 +45,10 @@ var (
 
 	// minSnapshotWarningTimeout is the minimum threshold to trigger a long running snapshot warning.
 	minSnapshotWarningTimeout = 30 * time.Second
+
+	// ErrCheckNoSpace is returned by Check if the file system does not have
+	// room for the copy of the database to check.
+	ErrCheckNoSpace = errors.New("backend: not enough disk space to check the database")
 )
 
 type Backend interface {
@@ -54,6 +60,15 @@ type Backend interface {
 
 	Snapshot() Snapshot
 	Hash(ignores func(bucketName, keyName []byte) bool) (uint32, error)
+	// Check verifies the consistency of the bbolt database and returns the
+	// inconsistencies found, at most maxErrors of them if it is positive.
+	// The database is first copied next to it, and the copy is checked, so
+	// that the check holds no lock nor transaction of the backend while it
+	// walks the pages. The copy is not throttled, as it holds a read
+	// transaction that blocks the commits growing the database, and fails
+	// with ErrCheckNoSpace unless the file system has room for twice the
+	// database. It returns the error of ctx once ctx is done.
+	Check(ctx context.Context, maxErrors int) ([]error, error)
 	// Size returns the current size of the backend physically allocated.
 	// The backend can hold DB space that is not utilized at the moment,
 	// since it can conduct pre-allocation or spare unused space for recycling.
@@ -66,6 +81,17 @@ type Backend interface {
 	// OpenReadTxN returns the number of currently open read transactions in the backend.
 	OpenReadTxN() int64
 	Defrag() error
+	// IncrementalDefrag defragments the backend without blocking reads and
+	// writes while the database is copied, copying at most bytesPerSec bytes
+	// per second if it is positive.
+	IncrementalDefrag(ctx context.Context, bytesPerSec int64) error
+	// SetSpaceReclaim enables or disables the incremental space reclaim,
+	// which rewrites the backend in small steps between commits so that
+	// pages freed by deletions are reused without a defragmentation.
+	SetSpaceReclaim(enabled bool)
+	// SpaceReclaim reports whether the space reclaim is enabled and the
+	// number of bytes it freed since the backend was opened.
+	SpaceReclaim() (enabled bool, reclaimedBytes int64)
 	ForceCommit()
 	Close() error
 
@@ -107,6 +133,11 @@ type backend struct {
 	bopts *bolt.Options
 	db    *bolt.DB
 
+	// defragMu serializes defragmentations.
+	defragMu sync.Mutex
+
+	reclaimer spaceReclaimer
+
 	batchInterval time.Duration
 	batchLimit    int
 	batchTx       *batchTxBuffered
@@ -204,6 +235,7 @@ func newBackend(bcfg BackendConfig) *backend {
 				},
 				buckets: make(map[BucketID]*bolt.Bucket),
 				txWg:    new(sync.WaitGroup),
+				txRefs:  new(atomic.Int64),
 				txMu:    new(sync.RWMutex),
 			},
 		},
@@ -252,6 +284,7 @@ func (b *backend) ConcurrentReadTx() ReadTx {
 	defer b.readTx.RUnlock()
 	// prevent boltdb read Tx from been rolled back until store read Tx is done. Needs to be called when holding readTx.RLock().
 	b.readTx.txWg.Add(1)
+	b.readTx.txRefs.Add(1)
 
 	// TODO: might want to copy the read buffer lazily - create copy when A) end of a write transaction B) end of a batch interval.
 
@@ -318,6 +351,7 @@ func (b *backend) ConcurrentReadTx() ReadTx {
 			tx:      b.readTx.tx,
 			buckets: b.readTx.buckets,
 			txWg:    b.readTx.txWg,
+			txRefs:  b.readTx.txRefs,
 		},
 	}
 }
@@ -402,6 +436,73 @@ func (b *backend) Hash(ignores func(bucketName, keyName []byte) bool) (uint32, e
 	return h.Sum32(), nil
 }
 
+func (b *backend) Check(ctx context.Context, maxErrors int) ([]error, error) {
+	dir := filepath.Dir(b.db.Path())
+	// leave room for the database to grow as much as its size once copied
+	free, err := diskFree(dir)
+	if err != nil {
+		return nil, err
+	}
+	if free >= 0 && free < 2*b.Size() {
+		return nil, ErrCheckNoSpace
+	}
+	f, err := os.CreateTemp(dir, "db.tmp.*")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	b.mu.RLock()
+	tx, err := b.db.Begin(false)
+	b.mu.RUnlock()
+	if err == nil {
+		_, err = tx.WriteTo(f)
+		tx.Rollback()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	// the walk of bbolt cannot be interrupted, so it finishes in the
+	// background if ctx is done
+	donec := make(chan []error, 1)
+	go func() {
+		defer os.Remove(path)
+		donec <- checkDB(path, maxErrors)
+	}()
+	select {
+	case errs := <-donec:
+		return errs, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// checkDB checks the consistency of the bbolt database at path.
+func checkDB(path string, maxErrors int) (errs []error) {
+	db, err := bolt.Open(path, 0400, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return []error{err}
+	}
+	defer db.Close()
+	db.View(func(tx *bolt.Tx) error {
+		// the errors are drained to let the check finish
+		for err := range tx.Check() {
+			if maxErrors <= 0 || len(errs) < maxErrors {
+				errs = append(errs, err)
+			}
+		}
+		return nil
+	})
+	return errs
+}
+
 func (b *backend) Size() int64 {
 	return atomic.LoadInt64(&b.size)
 }
@@ -424,6 +525,9 @@ func (b *backend) run() {
 		if b.batchTx.safePending() != 0 {
 			b.batchTx.Commit()
 		}
+		if b.reclaimer.enabled.Load() {
+			b.reclaimStep()
+		}
 		t.Reset(b.batchInterval)
 	}
 }
@@ -446,6 +550,13 @@ func (b *backend) Defrag() error {
 }
 
 func (b *backend) defrag() error {
+	b.defragMu.Lock()
+	defer b.defragMu.Unlock()
+	return b.unsafeDefrag()
+}
+
+// unsafeDefrag defragments the backend. It must be called holding defragMu.
+func (b *backend) unsafeDefrag() error {
 	now := time.Now()
 	isDefragActive.Set(1)
 	defer isDefragActive.Set(0)
@@ -468,24 +579,7 @@ func (b *backend) defrag() error {
 
 	b.batchTx.tx = nil
 
-	// Create a temporary file to ensure we start with a clean slate.
-	// Snapshotter.cleanupSnapdir cleans up any of these that are found during startup.
-	dir := filepath.Dir(b.db.Path())
-	temp, err := os.CreateTemp(dir, "db.tmp.*")
-	if err != nil {
-		return err
-	}
-	options := bolt.Options{}
-	if boltOpenOptions != nil {
-		options = *boltOpenOptions
-	}
-	options.OpenFile = func(_ string, _ int, _ os.FileMode) (file *os.File, err error) {
-		return temp, nil
-	}
-	// Don't load tmp db into memory regardless of opening options
-	options.Mlock = false
-	tdbp := temp.Name()
-	tmpdb, err := bolt.Open(tdbp, 0600, &options)
+	tmpdb, err := b.openTmpDB()
 	if err != nil {
 		return err
 	}
@@ -505,14 +599,69 @@ func (b *backend) defrag() error {
 	// gofail: var defragBeforeCopy struct{}
 	err = defragdb(b.db, tmpdb, defragLimit)
 	if err != nil {
-		tmpdb.Close()
-		if rmErr := os.RemoveAll(tmpdb.Path()); rmErr != nil {
-			b.lg.Error("failed to remove db.tmp after defragmentation completed", zap.Error(rmErr))
-		}
+		b.removeTmpDB(tmpdb)
 		return err
 	}
 
-	err = b.db.Close()
+	b.unsafeReplaceDB(tmpdb)
+
+	took := time.Since(now)
+	defragSec.Observe(took.Seconds())
+
+	size2, sizeInUse2 := b.Size(), b.SizeInUse()
+	if b.lg != nil {
+		b.lg.Info(
+			"finished defragmenting directory",
+			zap.String("path", dbp),
+			zap.Int64("current-db-size-bytes-diff", size2-size1),
+			zap.Int64("current-db-size-bytes", size2),
+			zap.String("current-db-size", humanize.Bytes(uint64(size2))),
+			zap.Int64("current-db-size-in-use-bytes-diff", sizeInUse2-sizeInUse1),
+			zap.Int64("current-db-size-in-use-bytes", sizeInUse2),
+			zap.String("current-db-size-in-use", humanize.Bytes(uint64(sizeInUse2))),
+			zap.Duration("took", took),
+		)
+	}
+	return nil
+}
+
+// openTmpDB opens an empty database next to the backend database to copy
+// it into.
+func (b *backend) openTmpDB() (*bolt.DB, error) {
+	// Create a temporary file to ensure we start with a clean slate.
+	// Snapshotter.cleanupSnapdir cleans up any of these that are found during startup.
+	dir := filepath.Dir(b.db.Path())
+	temp, err := os.CreateTemp(dir, "db.tmp.*")
+	if err != nil {
+		return nil, err
+	}
+	options := bolt.Options{}
+	if boltOpenOptions != nil {
+		options = *boltOpenOptions
+	}
+	options.OpenFile = func(_ string, _ int, _ os.FileMode) (file *os.File, err error) {
+		return temp, nil
+	}
+	// Don't load tmp db into memory regardless of opening options
+	options.Mlock = false
+	return bolt.Open(temp.Name(), 0600, &options)
+}
+
+func (b *backend) removeTmpDB(tmpdb *bolt.DB) {
+	// the path is reset by Close
+	tdbp := tmpdb.Path()
+	tmpdb.Close()
+	if rmErr := os.RemoveAll(tdbp); rmErr != nil {
+		b.lg.Error("failed to remove db.tmp after defragmentation completed", zap.Error(rmErr))
+	}
+}
+
+// unsafeReplaceDB replaces the backend database with tmpdb and begins new
+// transactions on it. It must be called holding all the backend locks, after
+// the batch tx is committed and stopped.
+func (b *backend) unsafeReplaceDB(tmpdb *bolt.DB) {
+	dbp, tdbp := b.db.Path(), tmpdb.Path()
+	err := b.db.Close()
 	if err != nil {
 		b.lg.Fatal("failed to close database", zap.Error(err))
 	}
@@ -539,25 +688,6 @@ func (b *backend) defrag() error {
 	db := b.readTx.tx.DB()
 	atomic.StoreInt64(&b.size, size)
 	atomic.StoreInt64(&b.sizeInUse, size-(int64(db.Stats().FreePageN)*int64(db.Info().PageSize)))
-
-	took := time.Since(now)
-	defragSec.Observe(took.Seconds())
-
-	size2, sizeInUse2 := b.Size(), b.SizeInUse()
-	if b.lg != nil {
-		b.lg.Info(
-			"finished defragmenting directory",
-			zap.String("path", dbp),
-			zap.Int64("current-db-size-bytes-diff", size2-size1),
-			zap.Int64("current-db-size-bytes", size2),
-			zap.String("current-db-size", humanize.Bytes(uint64(size2))),
-			zap.Int64("current-db-size-in-use-bytes-diff", sizeInUse2-sizeInUse1),
-			zap.Int64("current-db-size-in-use-bytes", sizeInUse2),
-			zap.String("current-db-size-in-use", humanize.Bytes(uint64(sizeInUse2))),
-			zap.Duration("took", took),
-		)
-	}
-	return nil
 }
 
 func defragdb(odb, tmpdb *bolt.DB, limit int) error {
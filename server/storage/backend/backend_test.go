@@ -15,9 +15,13 @@
 package backend_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -193,6 +197,377 @@ func TestBackendDefrag(t *testing.T) {
 	b.ForceCommit()
 }
 
+// TestBackendIncrementalDefrag ensures writes made while the database is
+// copied by an incremental defragmentation are not lost.
+func TestBackendIncrementalDefrag(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	for i := 0; i < 20000; i++ {
+		tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("foo_%d", i)), []byte("bar"))
+	}
+	tx.Unlock()
+	b.ForceCommit()
+
+	want := make(map[string]string)
+	tx.Lock()
+	for i := 0; i < 20000; i++ {
+		key := fmt.Sprintf("foo_%d", i)
+		if i%10 == 0 {
+			want[key] = "bar"
+			continue
+		}
+		tx.UnsafeDelete(schema.Test, []byte(key))
+	}
+	tx.Unlock()
+	b.ForceCommit()
+	size := b.Size()
+
+	donec := make(chan error)
+	go func() {
+		// copy ~300KB at 500KB/s
+		donec <- b.IncrementalDefrag(context.Background(), 500*1024)
+	}()
+	for i := 0; ; i++ {
+		select {
+		case err := <-donec:
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 {
+				t.Fatal("expected writes during defragmentation")
+			}
+			verifyTestBucket(t, b, want)
+			if nsize := b.Size(); nsize >= size {
+				t.Errorf("new size = %v, want < %d", nsize, size)
+			}
+			return
+		default:
+		}
+		tx.Lock()
+		key := fmt.Sprintf("foo_%d", (i*7)%20000)
+		if _, ok := want[key]; ok {
+			tx.UnsafeDelete(schema.Test, []byte(key))
+			delete(want, key)
+		} else {
+			tx.UnsafePut(schema.Test, []byte(key), []byte(fmt.Sprintf("baz_%d", i)))
+			want[key] = fmt.Sprintf("baz_%d", i)
+		}
+		tx.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestBackendIncrementalDefragGrowDB ensures commits growing the database
+// are not blocked while an incremental defragmentation copies it.
+func TestBackendIncrementalDefragGrowDB(t *testing.T) {
+	bcfg := backend.DefaultBackendConfig(zaptest.NewLogger(t))
+	bcfg.MmapSize = 1024 * 1024
+	b, _ := betesting.NewTmpBackendFromCfg(t, bcfg)
+	defer betesting.Close(t, b)
+
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	for i := 0; i < 10000; i++ {
+		tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("foo_%d", i)), []byte("bar"))
+	}
+	tx.Unlock()
+	b.ForceCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	donec := make(chan error)
+	go func() {
+		// copy ~100KB at 10KB/s
+		donec <- b.IncrementalDefrag(ctx, 10*1024)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// grow the database beyond its mapping
+	start := time.Now()
+	value := make([]byte, 1024*1024)
+	for i := 0; i < 64; i++ {
+		tx.Lock()
+		tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("big_%d", i)), value)
+		tx.Unlock()
+		b.ForceCommit()
+	}
+	took := time.Since(start)
+	cancel()
+	if err := <-donec; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	if took > 3*time.Second {
+		t.Errorf("commits took %v while defragmenting, want < 3s", took)
+	}
+}
+
+// TestBackendIncrementalDefragOverflow ensures an incremental
+// defragmentation falls back to a blocking one when too many keys are
+// written while the database is copied.
+func TestBackendIncrementalDefragOverflow(t *testing.T) {
+	defer backend.SetDefragMaxTrackedKeysForTest(10)()
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	want := make(map[string]string)
+	value := strings.Repeat("bar", 100)
+	for i := 0; i < 10000; i++ {
+		if i%10 == 0 {
+			want[fmt.Sprintf("foo_%d", i)] = value
+		}
+		tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("foo_%d", i)), []byte(value))
+	}
+	tx.Unlock()
+	b.ForceCommit()
+	tx.Lock()
+	for i := 0; i < 10000; i++ {
+		if i%10 != 0 {
+			tx.UnsafeDelete(schema.Test, []byte(fmt.Sprintf("foo_%d", i)))
+		}
+	}
+	tx.Unlock()
+	b.ForceCommit()
+	size := b.Size()
+
+	donec := make(chan error)
+	go func() {
+		// copy ~300KB at 100KB/s
+		donec <- b.IncrementalDefrag(context.Background(), 100*1024)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	tx.Lock()
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("new_%d", i)
+		tx.UnsafePut(schema.Test, []byte(key), []byte("baz"))
+		want[key] = "baz"
+	}
+	tx.Unlock()
+
+	select {
+	case err := <-donec:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("defragmentation did not fall back to a blocking one")
+	}
+	verifyTestBucket(t, b, want)
+	if nsize := b.Size(); nsize >= size {
+		t.Errorf("new size = %v, want < %d", nsize, size)
+	}
+}
+
+func TestBackendIncrementalDefragCanceled(t *testing.T) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	want := make(map[string]string)
+	for i := 0; i < 10000; i++ {
+		want[fmt.Sprintf("foo_%d", i)] = "bar"
+		tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("foo_%d", i)), []byte("bar"))
+	}
+	tx.Unlock()
+	b.ForceCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if err := b.IncrementalDefrag(ctx, 1024); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+
+	tmps, err := filepath.Glob(filepath.Join(filepath.Dir(tmpPath), "db.tmp.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmps) != 0 {
+		t.Errorf("temporary databases %v were not removed", tmps)
+	}
+	tx.Lock()
+	tx.UnsafePut(schema.Test, []byte("more"), []byte("bar"))
+	tx.Unlock()
+	b.ForceCommit()
+	want["more"] = "bar"
+	verifyTestBucket(t, b, want)
+}
+
+func TestBackendCheck(t *testing.T) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	for i := 0; i < 10000; i++ {
+		tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("foo_%d", i)), []byte("bar"))
+	}
+	tx.Unlock()
+	b.ForceCommit()
+
+	errs, err := b.Check(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("errs = %v, want none", errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = b.Check(ctx, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	tmps, err := filepath.Glob(filepath.Join(filepath.Dir(tmpPath), "db.tmp.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmps) != 0 {
+		t.Errorf("temporary databases %v were not removed", tmps)
+	}
+}
+
+func verifyTestBucket(t *testing.T, b backend.Backend, want map[string]string) {
+	got := make(map[string]string)
+	rtx := b.ReadTx()
+	rtx.RLock()
+	defer rtx.RUnlock()
+	if err := rtx.UnsafeForEach(schema.Test, func(k, v []byte) error {
+		got[string(k)] = string(v)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %d keys, want %d", len(got), len(want))
+	}
+}
+
+func TestBackendSpaceReclaimBoundsSize(t *testing.T) {
+	b, _ := betesting.NewTmpBackend(t, 10*time.Millisecond, 10000)
+	defer betesting.Close(t, b)
+
+	b.SetSpaceReclaim(true)
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	tx.Unlock()
+
+	value := strings.Repeat("v", 512)
+	want := make(map[string]string)
+	var sizes []int64
+	for round := 0; round < 30; round++ {
+		// replace the keys of the previous round, while the reclaim
+		// rewrites the backend concurrently
+		for i := 0; i < 2000; i++ {
+			tx.Lock()
+			if round > 0 {
+				key := fmt.Sprintf("%02d_%04d", round-1, i)
+				tx.UnsafeDelete(schema.Test, []byte(key))
+				delete(want, key)
+			}
+			key := fmt.Sprintf("%02d_%04d", round, i)
+			tx.UnsafePut(schema.Test, []byte(key), []byte(value))
+			want[key] = value
+			tx.Unlock()
+		}
+		b.ForceCommit()
+		sizes = append(sizes, b.Size())
+	}
+
+	verifyTestBucket(t, b, want)
+	verifyFreelist(t, b)
+	// the rounds write many times the size of the backend, which levels off
+	// after the first rounds instead of growing with them
+	assert.Lessf(t, sizes[len(sizes)-1], sizes[9]*5/4, "sizes by round %v", sizes)
+}
+
+func TestBackendSpaceReclaimMergesSparsePages(t *testing.T) {
+	b, _ := betesting.NewTmpBackend(t, 10*time.Millisecond, 10000)
+	defer betesting.Close(t, b)
+
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	for i := 0; i < 2000; i++ {
+		tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("foo_%04d", i)), []byte(strings.Repeat("v", 1024)))
+	}
+	tx.Unlock()
+	b.ForceCommit()
+
+	// shrinking the values leaves sparse pages behind, which bbolt only
+	// merges once keys are deleted from them
+	want := make(map[string]string)
+	tx.Lock()
+	for i := 0; i < 2000; i++ {
+		tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("foo_%04d", i)), []byte("bar"))
+		want[fmt.Sprintf("foo_%04d", i)] = "bar"
+	}
+	tx.Unlock()
+	b.ForceCommit()
+
+	b.SetSpaceReclaim(true)
+	assert.Eventually(t, func() bool {
+		_, reclaimed := b.SpaceReclaim()
+		return reclaimed > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	b.SetSpaceReclaim(false)
+	enabled, _ := b.SpaceReclaim()
+	assert.False(t, enabled)
+
+	b.ForceCommit()
+	verifyTestBucket(t, b, want)
+	verifyFreelist(t, b)
+}
+
+// TestBackendSpaceReclaimClosesReadTx ensures that while space reclaim is
+// enabled, commits close the read tx of the previous batch interval right
+// away, unless a read still uses it, which commits do not wait for.
+func TestBackendSpaceReclaimClosesReadTx(t *testing.T) {
+	b, _ := betesting.NewTmpBackend(t, time.Hour, 10000)
+	defer betesting.Close(t, b)
+	db := backend.DbFromBackendForTest(b)
+
+	b.SetSpaceReclaim(true)
+	b.ForceCommit()
+	// only the read tx of the new batch interval is open
+	assert.Equal(t, 1, db.Stats().OpenTxN)
+
+	rtx := b.ConcurrentReadTx()
+	rtx.RLock()
+	b.ForceCommit()
+	assert.Equal(t, 2, db.Stats().OpenTxN)
+	rtx.RUnlock()
+	assert.Eventually(t, func() bool { return db.Stats().OpenTxN == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+// verifyFreelist checks the consistency of the pages of the backend,
+// including that no page is both in use and free.
+func verifyFreelist(t *testing.T, b backend.Backend) {
+	// hold off commits, which may be allocating pages
+	tx := b.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	err := backend.DbFromBackendForTest(b).View(func(tx *bolt.Tx) error {
+		for err := range tx.Check() {
+			t.Error(err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestBackendWriteback ensures writes are stored to the read txn on write txn unlock.
 func TestBackendWriteback(t *testing.T) {
 	b, _ := betesting.NewDefaultTmpBackend(t)
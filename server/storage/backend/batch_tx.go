@@ -74,6 +74,9 @@ type batchTx struct {
 	backend *backend
 
 	pending int
+
+	// defragTracker tracks the writes during an incremental defragmentation.
+	defragTracker *defragTracker
 }
 
 // Lock is supposed to be called only by the unit test.
@@ -112,6 +115,7 @@ func (t *batchTx) Unlock() {
 }
 
 func (t *batchTx) UnsafeCreateBucket(bucket Bucket) {
+	t.defragTracker.trackBucket(bucket)
 	_, err := t.tx.CreateBucket(bucket.Name())
 	if err != nil && err != bolt.ErrBucketExists {
 		t.backend.lg.Fatal(
@@ -124,6 +128,7 @@ func (t *batchTx) UnsafeCreateBucket(bucket Bucket) {
 }
 
 func (t *batchTx) UnsafeDeleteBucket(bucket Bucket) {
+	t.defragTracker.trackBucket(bucket)
 	err := t.tx.DeleteBucket(bucket.Name())
 	if err != nil && err != bolt.ErrBucketNotFound {
 		t.backend.lg.Fatal(
@@ -159,6 +164,7 @@ func (t *batchTx) unsafePut(bucketType Bucket, key []byte, value []byte, seq boo
 		// this can delay the page split and reduce space usage.
 		bucket.FillPercent = 0.9
 	}
+	t.defragTracker.trackKey(bucketType, key)
 	if err := bucket.Put(key, value); err != nil {
 		t.backend.lg.Fatal(
 			"failed to write to a bucket",
@@ -214,6 +220,7 @@ func (t *batchTx) UnsafeDelete(bucketType Bucket, key []byte) {
 			zap.Stack("stack"),
 		)
 	}
+	t.defragTracker.trackKey(bucketType, key)
 	err := bucket.Delete(key)
 	if err != nil {
 		t.backend.lg.Fatal(
@@ -344,14 +351,23 @@ func (t *batchTxBuffered) unsafeCommit(stop bool) {
 	}
 
 	if t.backend.readTx.tx != nil {
-		// wait all store read transactions using the current boltdb tx to finish,
-		// then close the boltdb tx
-		go func(tx *bolt.Tx, wg *sync.WaitGroup) {
-			wg.Wait()
-			if err := tx.Rollback(); err != nil {
+		if t.backend.reclaimer.enabled.Load() && t.backend.readTx.txRefs.Load() == 0 {
+			// no read uses the boltdb tx, and none can start while the
+			// readTx lock is held, so close it right away for the commit to
+			// reuse the pages freed by the previous one
+			if err := t.backend.readTx.tx.Rollback(); err != nil {
 				t.backend.lg.Fatal("failed to rollback tx", zap.Error(err))
 			}
-		}(t.backend.readTx.tx, t.backend.readTx.txWg)
+		} else {
+			// wait all store read transactions using the current boltdb tx to finish,
+			// then close the boltdb tx
+			go func(tx *bolt.Tx, wg *sync.WaitGroup) {
+				wg.Wait()
+				if err := tx.Rollback(); err != nil {
+					t.backend.lg.Fatal("failed to rollback tx", zap.Error(err))
+				}
+			}(t.backend.readTx.tx, t.backend.readTx.txWg)
+		}
 		t.backend.readTx.reset()
 	}
 
@@ -0,0 +1,382 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// ErrBackendClosed is returned by IncrementalDefrag if the backend is
+	// closed before the defragmentation completes.
+	ErrBackendClosed = errors.New("backend: closed")
+
+	// defragChunkBytes is the number of bytes an incremental
+	// defragmentation copies in each read transaction, before it waits on
+	// the rate limit.
+	defragChunkBytes = 64 * 1024
+
+	// defragMaxTrackedKeys is the number of keys written during an
+	// incremental defragmentation above which it stops tracking them and
+	// falls back to a blocking defragmentation.
+	defragMaxTrackedKeys = 100000
+)
+
+// defragTracker records the keys written to the backend while an
+// incremental defragmentation copies it, so that they are copied again
+// before the copy replaces the backend database.
+type defragTracker struct {
+	// keys holds the written keys by bucket name.
+	keys map[string]map[string]struct{}
+	// buckets holds the names of the buckets created or deleted, which are
+	// copied again entirely.
+	buckets map[string]struct{}
+	// n is the number of tracked keys.
+	n int
+	// overflowed is set once more than defragMaxTrackedKeys are written,
+	// after which the writes are no longer tracked.
+	overflowed bool
+}
+
+func newDefragTracker() *defragTracker {
+	return &defragTracker{
+		keys:    make(map[string]map[string]struct{}),
+		buckets: make(map[string]struct{}),
+	}
+}
+
+func (t *defragTracker) trackKey(bucket Bucket, key []byte) {
+	if t == nil || t.overflowed {
+		return
+	}
+	keys, ok := t.keys[string(bucket.Name())]
+	if !ok {
+		keys = make(map[string]struct{})
+		t.keys[string(bucket.Name())] = keys
+	}
+	if _, ok = keys[string(key)]; ok {
+		return
+	}
+	keys[string(key)] = struct{}{}
+	if t.n++; t.n > defragMaxTrackedKeys {
+		t.overflowed = true
+		t.keys, t.buckets = nil, nil
+	}
+}
+
+func (t *defragTracker) trackBucket(bucket Bucket) {
+	if t == nil || t.overflowed {
+		return
+	}
+	t.buckets[string(bucket.Name())] = struct{}{}
+}
+
+// apply copies the tracked keys and buckets from tx into tmpdb.
+func (t *defragTracker) apply(tx *bolt.Tx, tmpdb *bolt.DB) error {
+	return tmpdb.Update(func(tmptx *bolt.Tx) error {
+		for name := range t.buckets {
+			if err := tmptx.DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+			b := tx.Bucket([]byte(name))
+			if b == nil {
+				continue
+			}
+			tmpb, err := tmptx.CreateBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+			tmpb.FillPercent = 0.9
+			if err = b.ForEach(tmpb.Put); err != nil {
+				return err
+			}
+		}
+		for name, keys := range t.keys {
+			if _, ok := t.buckets[name]; ok {
+				continue
+			}
+			b := tx.Bucket([]byte(name))
+			if b == nil {
+				continue
+			}
+			tmpb, err := tmptx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+			for k := range keys {
+				if v := b.Get([]byte(k)); v != nil {
+					err = tmpb.Put([]byte(k), v)
+				} else {
+					err = tmpb.Delete([]byte(k))
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// defragCursor is the position of the copy of an incremental
+// defragmentation: the last key copied from the bucket being copied.
+type defragCursor struct {
+	bucket []byte
+	key    []byte
+	done   bool
+}
+
+// copyChunk copies the keys after cur from tx into tmpdb, until about
+// defragChunkBytes are copied or the end of the database is reached, and
+// advances cur. It returns the number of bytes copied.
+func copyChunk(tx *bolt.Tx, tmpdb *bolt.DB, cur *defragCursor) (n int, err error) {
+	err = tmpdb.Update(func(tmptx *bolt.Tx) error {
+		bc := tx.Cursor()
+		var name []byte
+		if cur.bucket == nil {
+			name, _ = bc.First()
+		} else {
+			name, _ = bc.Seek(cur.bucket)
+		}
+		for ; name != nil; name, _ = bc.Next() {
+			if string(name) != string(cur.bucket) {
+				// the previous bucket was copied entirely, or deleted
+				cur.bucket, cur.key = append([]byte(nil), name...), nil
+			}
+			b := tx.Bucket(name)
+			if b == nil {
+				return fmt.Errorf("backend: cannot defrag bucket %s", string(name))
+			}
+			tmpb, err := tmptx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+			tmpb.FillPercent = 0.9 // for bucket2seq write in for each
+
+			c := b.Cursor()
+			var k, v []byte
+			if cur.key == nil {
+				k, v = c.First()
+			} else if k, v = c.Seek(cur.key); k != nil && string(k) == string(cur.key) {
+				k, v = c.Next()
+			}
+			for ; k != nil; k, v = c.Next() {
+				if err = tmpb.Put(k, v); err != nil {
+					return err
+				}
+				cur.key = append(cur.key[:0], k...)
+				if n += len(k) + len(v); n >= defragChunkBytes {
+					return nil
+				}
+			}
+		}
+		cur.done = true
+		return nil
+	})
+	return n, err
+}
+
+// IncrementalDefrag defragments the backend like Defrag, but without
+// blocking reads and writes while the database is copied. The copy reads at
+// most bytesPerSec bytes per second, or is not limited if bytesPerSec is not
+// positive, in short read transactions so that it does not block the commits
+// growing the database. Only the keys written in the meantime are copied
+// again while holding the backend locks, before the copy replaces the
+// database. If more than defragMaxTrackedKeys keys are written in the
+// meantime, the copy is discarded and the backend defragmented by Defrag.
+//
+// The defragmentation is aborted and the copy discarded if ctx is done or
+// the backend is closed before the copy completes.
+func (b *backend) IncrementalDefrag(ctx context.Context, bytesPerSec int64) error {
+	b.defragMu.Lock()
+	defer b.defragMu.Unlock()
+
+	now := time.Now()
+	isDefragActive.Set(1)
+	defer isDefragActive.Set(0)
+
+	tmpdb, err := b.openTmpDB()
+	if err != nil {
+		return err
+	}
+
+	// commit pending writes so that they are visible to the copy, and track
+	// the writes after it
+	tracker := newDefragTracker()
+	b.batchTx.LockOutsideApply()
+	select {
+	case <-b.stopc:
+		b.batchTx.Unlock()
+		b.removeTmpDB(tmpdb)
+		return ErrBackendClosed
+	default:
+	}
+	b.batchTx.commit(false)
+	b.batchTx.defragTracker = tracker
+	b.batchTx.Unlock()
+
+	replaced := false
+	defer func() {
+		if !replaced {
+			b.batchTx.LockOutsideApply()
+			b.batchTx.defragTracker = nil
+			b.batchTx.Unlock()
+			b.removeTmpDB(tmpdb)
+		}
+	}()
+
+	dbp := b.db.Path()
+	size1, sizeInUse1 := b.Size(), b.SizeInUse()
+	if b.lg != nil {
+		b.lg.Info(
+			"defragmenting incrementally",
+			zap.String("path", dbp),
+			zap.Int64("current-db-size-bytes", size1),
+			zap.String("current-db-size", humanize.Bytes(uint64(size1))),
+			zap.Int64("current-db-size-in-use-bytes", sizeInUse1),
+			zap.String("current-db-size-in-use", humanize.Bytes(uint64(sizeInUse1))),
+			zap.Int64("rate-bytes-per-sec", bytesPerSec),
+		)
+	}
+	throttle := b.defragThrottle(ctx, bytesPerSec)
+	cur := &defragCursor{}
+	for !cur.done {
+		b.mu.RLock()
+		tx, err := b.db.Begin(false)
+		b.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		n, err := copyChunk(tx, tmpdb, cur)
+		tx.Rollback()
+		if err != nil {
+			return err
+		}
+		if err = throttle(n); err != nil {
+			return err
+		}
+
+		b.batchTx.LockOutsideApply()
+		overflowed := tracker.overflowed
+		b.batchTx.Unlock()
+		if overflowed {
+			if b.lg != nil {
+				b.lg.Warn(
+					"too many writes during incremental defragmentation, defragmenting with blocking",
+					zap.String("path", dbp),
+					zap.Int("max-tracked-keys", defragMaxTrackedKeys),
+				)
+			}
+			b.batchTx.LockOutsideApply()
+			b.batchTx.defragTracker = nil
+			b.batchTx.Unlock()
+			b.removeTmpDB(tmpdb)
+			replaced = true
+			return b.unsafeDefrag()
+		}
+	}
+
+	// block reads and writes while catching up with the tracked writes
+	b.batchTx.LockOutsideApply()
+	defer b.batchTx.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.readTx.Lock()
+	defer b.readTx.Unlock()
+
+	select {
+	case <-b.stopc:
+		return ErrBackendClosed
+	default:
+	}
+
+	b.batchTx.unsafeCommit(true)
+	b.batchTx.defragTracker = nil
+
+	err = b.db.View(func(tx *bolt.Tx) error { return tracker.apply(tx, tmpdb) })
+	if err != nil {
+		// keep using the current database
+		b.batchTx.tx = b.unsafeBegin(true)
+		b.readTx.reset()
+		b.readTx.tx = b.unsafeBegin(false)
+		return err
+	}
+	b.unsafeReplaceDB(tmpdb)
+	replaced = true
+
+	took := time.Since(now)
+	defragSec.Observe(took.Seconds())
+
+	size2, sizeInUse2 := b.Size(), b.SizeInUse()
+	if b.lg != nil {
+		b.lg.Info(
+			"finished defragmenting directory incrementally",
+			zap.String("path", dbp),
+			zap.Int64("current-db-size-bytes-diff", size2-size1),
+			zap.Int64("current-db-size-bytes", size2),
+			zap.String("current-db-size", humanize.Bytes(uint64(size2))),
+			zap.Int64("current-db-size-in-use-bytes-diff", sizeInUse2-sizeInUse1),
+			zap.Int64("current-db-size-in-use-bytes", sizeInUse2),
+			zap.String("current-db-size-in-use", humanize.Bytes(uint64(sizeInUse2))),
+			zap.Int("recopied-keys", tracker.n),
+			zap.Duration("took", took),
+		)
+	}
+	return nil
+}
+
+// defragThrottle returns the throttle of the copy of an incremental
+// defragmentation, which waits for the rate limit after each chunk of n
+// bytes and aborts the copy if ctx is done or the backend is closed.
+func (b *backend) defragThrottle(ctx context.Context, bytesPerSec int64) func(n int) error {
+	var limiter *rate.Limiter
+	if bytesPerSec > 0 {
+		burst := defragChunkBytes
+		if int64(burst) > bytesPerSec {
+			burst = int(bytesPerSec)
+		}
+		limiter = rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+	}
+	return func(n int) error {
+		select {
+		case <-b.stopc:
+			return ErrBackendClosed
+		default:
+		}
+		if limiter == nil {
+			return ctx.Err()
+		}
+		for n > 0 {
+			wait := n
+			if wait > limiter.Burst() {
+				wait = limiter.Burst()
+			}
+			if err := limiter.WaitN(ctx, wait); err != nil {
+				return err
+			}
+			n -= wait
+		}
+		return ctx.Err()
+	}
+}
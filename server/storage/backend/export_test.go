@@ -27,3 +27,9 @@ func DefragLimitForTest() int {
 func CommitsForTest(b Backend) int64 {
 	return b.(*backend).Commits()
 }
+
+func SetDefragMaxTrackedKeysForTest(n int) func() {
+	old := defragMaxTrackedKeys
+	defragMaxTrackedKeys = n
+	return func() { defragMaxTrackedKeys = old }
+}
@@ -84,6 +84,13 @@ var (
 		Buckets: prometheus.ExponentialBuckets(.01, 2, 17),
 	})
 
+	spaceReclaimedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "disk",
+		Name:      "backend_space_reclaimed_bytes_total",
+		Help:      "The total number of bytes freed by the backend space reclaim.",
+	})
+
 	isDefragActive = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "etcd",
 		Subsystem: "disk",
@@ -99,5 +106,6 @@ func init() {
 	prometheus.MustRegister(writeSec)
 	prometheus.MustRegister(defragSec)
 	prometheus.MustRegister(snapshotTransferSec)
+	prometheus.MustRegister(spaceReclaimedBytes)
 	prometheus.MustRegister(isDefragActive)
 }
@@ -17,6 +17,7 @@ package backend
 import (
 	"math"
 	"sync"
+	"sync/atomic"
 
 	bolt "go.etcd.io/bbolt"
 )
@@ -49,6 +50,8 @@ type baseReadTx struct {
 	buckets map[BucketID]*bolt.Bucket
 	// txWg protects tx from being rolled back at the end of a batch interval until all reads using this tx are done.
 	txWg *sync.WaitGroup
+	// txRefs is the number of the reads using this tx that are not done.
+	txRefs *atomic.Int64
 }
 
 func (baseReadTx *baseReadTx) UnsafeForEach(bucket Bucket, visitor func(k, v []byte) error) error {
@@ -135,6 +138,7 @@ func (rt *readTx) reset() {
 	rt.buckets = make(map[BucketID]*bolt.Bucket)
 	rt.tx = nil
 	rt.txWg = new(sync.WaitGroup)
+	rt.txRefs = new(atomic.Int64)
 }
 
 type concurrentReadTx struct {
@@ -148,4 +152,7 @@ func (rt *concurrentReadTx) Unlock() {}
 func (rt *concurrentReadTx) RLock() {}
 
 // RUnlock signals the end of concurrentReadTx.
-func (rt *concurrentReadTx) RUnlock() { rt.txWg.Done() }
+func (rt *concurrentReadTx) RUnlock() {
+	rt.txRefs.Add(-1)
+	rt.txWg.Done()
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// reclaimBatchLimit is the maximum number of keys a space reclaim step
+	// rewrites in one commit.
+	reclaimBatchLimit = 256
+
+	// reclaimPassInterval is the minimum time between the starts of two
+	// passes of the space reclaim over the backend.
+	reclaimPassInterval = time.Minute
+)
+
+// spaceReclaimer reclaims the space freed in the backend while space reclaim
+// is enabled.
+//
+// bbolt can only reuse a freed page once no read transaction older than the
+// commit that freed it is open. Commits close the read transaction of the
+// previous batch interval in the background, so the pages freed by a commit
+// are usually still pinned when the next one allocates, and the file grows
+// instead. While space reclaim is enabled, commits close that read
+// transaction right away if no read uses it, without waiting for the reads
+// otherwise.
+//
+// The reclaimer also rewrites the backend a few keys at a time. bbolt never
+// writes a modified node back to its page; it allocates new pages, preferring
+// the lowest free ones, and frees the old ones. Deleting a key also makes
+// bbolt merge its node into a sibling if the node fell below a quarter of a
+// page. Rewriting every key in place thus moves the data into the free pages
+// left behind by deletions and merges sparse nodes, without the copy of a full
+// defragmentation. The file is never truncated, but the freed pages are reused
+// instead of the file growing.
+type spaceReclaimer struct {
+	enabled atomic.Bool
+	// reclaimed is the number of bytes freed by the reclaim steps.
+	reclaimed atomic.Int64
+
+	// mu guards the position of the next step.
+	mu sync.Mutex
+	// bucket and key are the bucket and key the next step starts at.
+	bucket []byte
+	key    []byte
+	// nextPass is the earliest time the next pass may start.
+	nextPass time.Time
+}
+
+// rewrite rewrites up to limit keys of tx starting at the position of the
+// last step and returns the number of keys rewritten. After the last key of
+// the last bucket, the next step starts over from the first bucket once
+// reclaimPassInterval passed since the start of the last pass.
+func (r *spaceReclaimer) rewrite(tx *bolt.Tx, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.bucket == nil && time.Now().Before(r.nextPass) {
+		return 0, nil
+	}
+	if r.bucket == nil {
+		r.nextPass = time.Now().Add(reclaimPassInterval)
+	}
+
+	type kv struct{ k, v []byte }
+	n := 0
+	c := tx.Cursor()
+	name, _ := c.Seek(r.bucket)
+	for ; name != nil && n < limit; name, _ = c.Next() {
+		b := tx.Bucket(name)
+		if b == nil {
+			continue
+		}
+		var start []byte
+		if bytes.Equal(name, r.bucket) {
+			start = r.key
+		}
+
+		// collect the keys first, the cursor is invalidated by writes
+		var kvs []kv
+		bc := b.Cursor()
+		k, v := bc.First()
+		if start != nil {
+			k, v = bc.Seek(start)
+		}
+		for ; k != nil && n+len(kvs) < limit; k, v = bc.Next() {
+			if v == nil {
+				// nested buckets are not used by etcd
+				continue
+			}
+			kvs = append(kvs, kv{append([]byte(nil), k...), append([]byte(nil), v...)})
+		}
+		for _, e := range kvs {
+			if err := b.Delete(e.k); err != nil {
+				return n, err
+			}
+			if err := b.Put(e.k, e.v); err != nil {
+				return n, err
+			}
+		}
+		n += len(kvs)
+
+		if k != nil {
+			r.bucket, r.key = append([]byte(nil), name...), append([]byte(nil), k...)
+			return n, nil
+		}
+		// continue after the bucket
+		r.bucket, r.key = append(append([]byte(nil), name...), 0), nil
+	}
+	if name == nil {
+		// a full pass is done
+		r.bucket, r.key = nil, nil
+	}
+	return n, nil
+}
+
+// SetSpaceReclaim enables or disables the incremental space reclaim.
+func (b *backend) SetSpaceReclaim(enabled bool) {
+	if b.reclaimer.enabled.Swap(enabled) != enabled {
+		b.lg.Info("backend space reclaim", zap.Bool("enabled", enabled))
+	}
+}
+
+// SpaceReclaim reports whether the space reclaim is enabled and the number of
+// bytes it freed since the backend was opened.
+func (b *backend) SpaceReclaim() (enabled bool, reclaimedBytes int64) {
+	return b.reclaimer.enabled.Load(), b.reclaimer.reclaimed.Load()
+}
+
+// reclaimStep rewrites the next batch of keys in a commit of its own, so
+// that the pages it frees can be told apart from those of other writes.
+// It holds the batch tx lock, like any other write, so concurrent writes
+// wait for the step and the readers are not affected: the rewritten keys
+// keep their values.
+func (b *backend) reclaimStep() {
+	t := b.batchTx
+	t.lock()
+	defer t.Unlock()
+
+	t.commit(false)
+	before := freeAndPendingPages(b.db)
+	n, err := b.reclaimer.rewrite(t.tx, reclaimBatchLimit)
+	if err != nil {
+		b.lg.Fatal("failed to reclaim backend space", zap.Error(err))
+	}
+	if n == 0 {
+		return
+	}
+	t.pending += n
+	t.commit(false)
+
+	if freed := freeAndPendingPages(b.db) - before; freed > 0 {
+		reclaimed := int64(freed) * int64(b.db.Info().PageSize)
+		b.reclaimer.reclaimed.Add(reclaimed)
+		spaceReclaimedBytes.Add(float64(reclaimed))
+	}
+}
+
+// freeAndPendingPages returns the number of pages freed in db, including the
+// pending ones which are still read by open transactions.
+func freeAndPendingPages(db *bolt.DB) int {
+	stats := db.Stats()
+	return stats.FreePageN + stats.PendingPageN
+}
@@ -24,7 +24,13 @@ const (
 )
 
 func ToBackendFileName(dataDir string) string {
-	return filepath.Join(ToSnapDir(dataDir), backendFileSegment)
+	return ToBackendFileNameInDir(ToSnapDir(dataDir))
+}
+
+// ToBackendFileNameInDir returns the path of the backend file kept in
+// backendDir rather than in the snapshot directory.
+func ToBackendFileNameInDir(backendDir string) string {
+	return filepath.Join(backendDir, backendFileSegment)
 }
 
 func ToSnapDir(dataDir string) string {
@@ -39,6 +39,24 @@ func unsafeHashByRev(tx backend.UnsafeReader, compactRevision, revision int64, k
 	return h.Hash(), err
 }
 
+// unsafeHashRevisions is like unsafeHashByRev, but only reads and hashes the
+// given revisions, sorted in ascending order, instead of the whole backend.
+func unsafeHashRevisions(tx backend.UnsafeReader, compactRev, rev int64, keep map[revision]struct{}, revs []revision) KeyValueHash {
+	h := newKVHasher(compactRev, rev, keep)
+	start, end := newRevBytes(), newRevBytes()
+	for _, r := range revs {
+		// a revision is stored as is, or marked as a tombstone or a key
+		// compaction marker
+		revToBytes(r, start)
+		revToBytes(revision{main: r.main, sub: r.sub + 1}, end)
+		ks, vs := tx.UnsafeRange(schema.Key, start, end, 0)
+		for i := range ks {
+			h.WriteKeyValue(ks[i], vs[i])
+		}
+	}
+	return h.Hash()
+}
+
 type kvHasher struct {
 	hash            hash.Hash32
 	compactRevision int64
@@ -97,6 +115,12 @@ type HashStorage interface {
 	// HashByRev computes the hash of all MVCC revisions up to a given revision.
 	HashByRev(rev int64) (hash KeyValueHash, currentRev int64, err error)
 
+	// HashByRevRange computes the hash of the MVCC revisions of the keys in
+	// the range [key, end) up to a given revision. The range is interpreted
+	// as in Range. Only the revisions of the range, found in the index, are
+	// read from the backend.
+	HashByRevRange(rev int64, key, end []byte) (hash KeyValueHash, currentRev int64, err error)
+
 	// Store adds hash value in local cache, allowing it to be returned by HashByRev.
 	Store(valueHash KeyValueHash)
 
@@ -139,6 +163,10 @@ func (s *hashStorage) HashByRev(rev int64) (KeyValueHash, int64, error) {
 	return s.store.hashByRev(rev)
 }
 
+func (s *hashStorage) HashByRevRange(rev int64, key, end []byte) (KeyValueHash, int64, error) {
+	return s.store.hashByRevRange(rev, key, end)
+}
+
 func (s *hashStorage) Store(hash KeyValueHash) {
 	s.lg.Info("storing new hash",
 		zap.Uint32("hash", hash.Hash),
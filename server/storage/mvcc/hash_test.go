@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
 	"go.etcd.io/etcd/pkg/v3/traceutil"
@@ -130,6 +131,80 @@ func testHashByRev(t *testing.T, s *store, rev int64) KeyValueHash {
 	return hash
 }
 
+func TestHashByRevRange(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	putKVs(s, 1, 100)
+	rev := s.Rev()
+	full, _, err := s.hashByRev(rev)
+	require.NoError(t, err)
+
+	all, _, err := s.hashByRevRange(rev, []byte{0}, []byte{0})
+	require.NoError(t, err)
+	assert.Equal(t, full, all, "hash of the whole keyspace should match HashByRev")
+
+	first, _, err := s.hashByRevRange(rev, []byte("a"), []byte("d"))
+	require.NoError(t, err)
+	second, _, err := s.hashByRevRange(rev, []byte("d"), []byte{0})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Hash, second.Hash)
+	assert.NotEqual(t, full.Hash, first.Hash)
+
+	single, _, err := s.hashByRevRange(rev, []byte("alice"), nil)
+	require.NoError(t, err)
+	prefix, _, err := s.hashByRevRange(rev, []byte("alice"), []byte("alicf"))
+	require.NoError(t, err)
+	assert.Equal(t, single, prefix)
+
+	s.Put([]byte("bob"), []byte("new"), 0)
+	after, _, err := s.hashByRevRange(rev, []byte("a"), []byte("d"))
+	require.NoError(t, err)
+	assert.Equal(t, first, after, "hash at a past revision should not change")
+
+	_, err = s.Compact(traceutil.TODO(), rev)
+	require.NoError(t, err)
+	_, _, err = s.hashByRevRange(rev-1, []byte("a"), []byte("d"))
+	assert.Equal(t, ErrCompacted, err)
+	_, _, err = s.hashByRevRange(s.Rev()+1, []byte("a"), []byte("d"))
+	assert.Equal(t, ErrFutureRev, err)
+}
+
+// TestHashByRevRangeKeyCompaction ensures that the hash of a range read from
+// the index covers the tombstones and the key compaction markers, as the hash
+// of the whole keyspace does, also after a restore.
+func TestHashByRevRangeKeyCompaction(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	for i := 0; i < 3; i++ {
+		s.Put([]byte("foo"), []byte(fmt.Sprint(i)), 0)
+	}
+	s.Put([]byte("zoo"), []byte("bar"), 0)
+	s.DeleteRange([]byte("zoo"), nil)
+	_, _, err := s.CompactKey(traceutil.TODO(), []byte("foo"), 1)
+	require.NoError(t, err)
+	// the read transactions see the removed revisions until they are committed
+	s.b.ForceCommit()
+	rev := s.Rev()
+
+	full, _, err := s.hashByRev(rev)
+	require.NoError(t, err)
+	all, _, err := s.hashByRevRange(rev, []byte{0}, []byte{0})
+	require.NoError(t, err)
+	assert.Equal(t, full, all, "hash of the whole keyspace should match HashByRev")
+	foo, _, err := s.hashByRevRange(rev, []byte("foo"), nil)
+	require.NoError(t, err)
+
+	ns := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer ns.Close()
+	nfoo, _, err := ns.hashByRevRange(rev, []byte("foo"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, foo, nfoo, "hash of a range should not change on restore")
+}
+
 // TestCompactionHash tests compaction hash
 // TODO: Change this to fuzz test
 func TestCompactionHash(t *testing.T) {
@@ -0,0 +1,189 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+var (
+	// hotKeyCapacity is the maximum number of keys tracked for reads and for writes.
+	hotKeyCapacity = 1024
+	// hotKeySampleInterval is the average number of accesses per sampled access.
+	hotKeySampleInterval = 8
+)
+
+// maxHotKeyLen is the maximum length of a tracked key. Longer keys are
+// tracked by their prefix of this length.
+const maxHotKeyLen = 256
+
+// HotKey is a key, or key prefix, together with its estimated access count.
+type HotKey struct {
+	Key   []byte
+	Count int64
+}
+
+// hotKeyTracker estimates the most read and most written keys of the store
+// from a sample of accesses. Its memory usage is bounded by hotKeyCapacity
+// regardless of the size of the keyspace.
+type hotKeyTracker struct {
+	sampleInterval int
+
+	mu     sync.Mutex
+	reads  *hotKeyCounter
+	writes *hotKeyCounter
+}
+
+func newHotKeyTracker(capacity, sampleInterval int) *hotKeyTracker {
+	return &hotKeyTracker{
+		sampleInterval: sampleInterval,
+		reads:          newHotKeyCounter(capacity),
+		writes:         newHotKeyCounter(capacity),
+	}
+}
+
+func (t *hotKeyTracker) recordRead(key []byte) {
+	if t == nil || !t.sample() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reads.add(key, int64(t.sampleInterval))
+}
+
+func (t *hotKeyTracker) recordWrite(key []byte) {
+	if t == nil || !t.sample() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writes.add(key, int64(t.sampleInterval))
+}
+
+func (t *hotKeyTracker) sample() bool {
+	return t.sampleInterval <= 1 || rand.Intn(t.sampleInterval) == 0
+}
+
+// top returns up to limit most read and most written keys in descending order
+// of their counts. If reset is true, all counts are discarded afterwards.
+func (t *hotKeyTracker) top(limit int, reset bool) (reads, writes []HotKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	reads, writes = t.reads.top(limit), t.writes.top(limit)
+	if reset {
+		t.reads.reset()
+		t.writes.reset()
+	}
+	return reads, writes
+}
+
+// hotKeyCounter implements the Space-Saving algorithm: it counts at most
+// capacity keys, and a new key replaces the key with the lowest count,
+// inheriting that count. The count of a key is therefore overestimated by
+// at most the lowest count at the time the key was last admitted.
+type hotKeyCounter struct {
+	capacity int
+	entries  map[string]*hotKeyEntry
+	minHeap  hotKeyHeap
+}
+
+type hotKeyEntry struct {
+	key   string
+	count int64
+	// index is the position of the entry in the heap.
+	index int
+}
+
+func newHotKeyCounter(capacity int) *hotKeyCounter {
+	return &hotKeyCounter{
+		capacity: capacity,
+		entries:  make(map[string]*hotKeyEntry, capacity),
+	}
+}
+
+func (c *hotKeyCounter) add(key []byte, n int64) {
+	if len(key) > maxHotKeyLen {
+		key = key[:maxHotKeyLen]
+	}
+	if e, ok := c.entries[string(key)]; ok {
+		e.count += n
+		heap.Fix(&c.minHeap, e.index)
+		return
+	}
+	if len(c.entries) < c.capacity {
+		e := &hotKeyEntry{key: string(key), count: n}
+		c.entries[e.key] = e
+		heap.Push(&c.minHeap, e)
+		return
+	}
+	e := c.minHeap[0]
+	delete(c.entries, e.key)
+	e.key = string(key)
+	e.count += n
+	c.entries[e.key] = e
+	heap.Fix(&c.minHeap, e.index)
+}
+
+func (c *hotKeyCounter) top(limit int) []HotKey {
+	keys := make([]HotKey, 0, len(c.minHeap))
+	for _, e := range c.minHeap {
+		keys = append(keys, HotKey{Key: []byte(e.key), Count: e.count})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return string(keys[i].Key) < string(keys[j].Key)
+	})
+	if limit > 0 && limit < len(keys) {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+func (c *hotKeyCounter) reset() {
+	c.entries = make(map[string]*hotKeyEntry, c.capacity)
+	c.minHeap = nil
+}
+
+// hotKeyHeap is a min-heap of entries ordered by count.
+type hotKeyHeap []*hotKeyEntry
+
+func (h hotKeyHeap) Len() int           { return len(h) }
+func (h hotKeyHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotKeyHeap) Push(x interface{}) {
+	e := x.(*hotKeyEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *hotKeyHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestHotKeys(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{HotKeyTracking: true})
+	defer cleanup(s, b)
+
+	// hammer a few keys with different weights amid background traffic on many cold keys
+	for i := 0; i < 2000; i++ {
+		s.Put([]byte(fmt.Sprintf("cold%d", i)), []byte("bar"), lease.NoLease)
+		s.Range(context.TODO(), []byte(fmt.Sprintf("cold%d", i)), nil, RangeOptions{})
+		for j, key := range []string{"hot0", "hot1", "hot2"} {
+			if i%(1<<j) == 0 {
+				s.Put([]byte(key), []byte("bar"), lease.NoLease)
+			}
+			if i%(1<<(2-j)) == 0 {
+				s.Range(context.TODO(), []byte(key), nil, RangeOptions{})
+			}
+		}
+	}
+
+	reads, writes, err := s.HotKeys(3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hotKeyNames(writes), "hot0,hot1,hot2"; got != want {
+		t.Errorf("most written keys = %s, want %s", got, want)
+	}
+	if got, want := hotKeyNames(reads), "hot2,hot1,hot0"; got != want {
+		t.Errorf("most read keys = %s, want %s", got, want)
+	}
+	// 2000 writes with 1 in 8 sampled is ~250 samples
+	if writes[0].Count < 1000 || writes[0].Count > 4000 {
+		t.Errorf("count of %q = %d, want about 2000", writes[0].Key, writes[0].Count)
+	}
+
+	if _, _, err = s.HotKeys(3, true); err != nil {
+		t.Fatal(err)
+	}
+	reads, writes, err = s.HotKeys(0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reads) != 0 || len(writes) != 0 {
+		t.Errorf("hot keys = %v, %v after reset, want none", reads, writes)
+	}
+}
+
+func TestHotKeysDisabled(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+	if _, _, err := s.HotKeys(0, false); err != ErrHotKeyTrackingDisabled {
+		t.Errorf("err = %v, want %v", err, ErrHotKeyTrackingDisabled)
+	}
+}
+
+func TestHotKeyCounterBounded(t *testing.T) {
+	c := newHotKeyCounter(10)
+	for i := 0; i < 10000; i++ {
+		c.add([]byte(fmt.Sprintf("key%d", i)), 1)
+		c.add([]byte("hot"), 1)
+	}
+	c.add([]byte(strings.Repeat("a", 2*maxHotKeyLen)), 1)
+
+	if len(c.entries) != 10 || len(c.minHeap) != 10 {
+		t.Fatalf("tracked %d entries and %d heap entries, want %d", len(c.entries), len(c.minHeap), 10)
+	}
+	for key := range c.entries {
+		if len(key) > maxHotKeyLen {
+			t.Errorf("tracked key of length %d, want at most %d", len(key), maxHotKeyLen)
+		}
+	}
+	top := c.top(1)
+	if len(top) != 1 || string(top[0].Key) != "hot" || top[0].Count != 10000 {
+		t.Errorf("top = %+v, want hot with count 10000", top)
+	}
+}
+
+func hotKeyNames(keys []HotKey) string {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, string(k.Key))
+	}
+	return strings.Join(names, ",")
+}
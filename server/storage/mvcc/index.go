@@ -24,12 +24,21 @@ import (
 type index interface {
 	Get(key []byte, atRev int64) (rev, created revision, ver int64, err error)
 	Range(key, end []byte, atRev int64) ([][]byte, []revision)
-	Revisions(key, end []byte, atRev int64, limit int) ([]revision, int)
+	Keys(key, end []byte, atRev int64) [][]byte
+	Revisions(key, end []byte, atRev int64, limit int, reverse bool) ([]revision, int)
+	KeyRevisions(key, end []byte, atRev int64, limit int, reverse bool) ([]keyRevision, int)
 	CountRevisions(key, end []byte, atRev int64) int
-	Put(key []byte, rev revision)
+	Put(key []byte, rev revision, lease int64)
 	Tombstone(key []byte, rev revision) error
 	Compact(rev int64) map[revision]struct{}
+	CompactFloors(floor func(key []byte) int64) map[revision]struct{}
 	Keep(rev int64) map[revision]struct{}
+	NthRevision(key []byte, n int) (revision, bool)
+	HasRevision(key []byte, rev revision) bool
+	Histories(key []byte, limit int) (keys [][]byte, revs [][]revision)
+	History(key, end []byte, atRev int64) []revision
+	KeysFrom(key []byte, atRev int64, limit int) []keyRevision
+	CompactKey(key []byte, compactRev, atRev int64) (removed, tombstones []revision)
 	Equal(b index) bool
 
 	Insert(ki *keyIndex)
@@ -51,7 +60,7 @@ func newTreeIndex(lg *zap.Logger) index {
 	}
 }
 
-func (ti *treeIndex) Put(key []byte, rev revision) {
+func (ti *treeIndex) Put(key []byte, rev revision, lease int64) {
 	keyi := &keyIndex{key: key}
 
 	ti.Lock()
@@ -59,10 +68,12 @@ func (ti *treeIndex) Put(key []byte, rev revision) {
 	okeyi, ok := ti.tree.Get(keyi)
 	if !ok {
 		keyi.put(ti.lg, rev.main, rev.sub)
+		keyi.lease = lease
 		ti.tree.ReplaceOrInsert(keyi)
 		return
 	}
 	okeyi.put(ti.lg, rev.main, rev.sub)
+	okeyi.lease = lease
 }
 
 func (ti *treeIndex) Get(key []byte, atRev int64) (modified, created revision, ver int64, err error) {
@@ -106,10 +117,35 @@ func (ti *treeIndex) unsafeVisit(key, end []byte, f func(ki *keyIndex) bool) {
 	})
 }
 
+// unsafeVisitReverse is like unsafeVisit, but visits the keys in descending
+// order, from end(excluded) to key(included).
+func (ti *treeIndex) unsafeVisitReverse(key, end []byte, f func(ki *keyIndex) bool) {
+	keyi, endi := &keyIndex{key: key}, &keyIndex{key: end}
+
+	visit := func(item *keyIndex) bool {
+		if item.Less(keyi) {
+			return false
+		}
+		return f(item)
+	}
+	if len(endi.key) == 0 {
+		ti.tree.Descend(visit)
+		return
+	}
+	ti.tree.DescendLessOrEqual(endi, func(item *keyIndex) bool {
+		if !item.Less(endi) {
+			// end is excluded
+			return true
+		}
+		return visit(item)
+	})
+}
+
 // Revisions returns limited number of revisions from key(included) to end(excluded)
-// at the given rev. The returned slice is sorted in the order of key. There is no limit if limit <= 0.
+// at the given rev. The returned slice is sorted in the order of key, or in the
+// reverse order of key if reverse is set. There is no limit if limit <= 0.
 // The second return parameter isn't capped by the limit and reflects the total number of revisions.
-func (ti *treeIndex) Revisions(key, end []byte, atRev int64, limit int) (revs []revision, total int) {
+func (ti *treeIndex) Revisions(key, end []byte, atRev int64, limit int, reverse bool) (revs []revision, total int) {
 	ti.RLock()
 	defer ti.RUnlock()
 
@@ -120,7 +156,11 @@ func (ti *treeIndex) Revisions(key, end []byte, atRev int64, limit int) (revs []
 		}
 		return []revision{rev}, 1
 	}
-	ti.unsafeVisit(key, end, func(ki *keyIndex) bool {
+	visit := ti.unsafeVisit
+	if reverse {
+		visit = ti.unsafeVisitReverse
+	}
+	visit(key, end, func(ki *keyIndex) bool {
 		if rev, _, _, err := ki.get(ti.lg, atRev); err == nil {
 			if limit <= 0 || len(revs) < limit {
 				revs = append(revs, rev)
@@ -132,6 +172,61 @@ func (ti *treeIndex) Revisions(key, end []byte, atRev int64, limit int) (revs []
 	return revs, total
 }
 
+// keyRevision describes a key as of a given revision.
+type keyRevision struct {
+	key      []byte
+	modified revision
+	created  revision
+	ver      int64
+	// lease is the lease of the key at modified, and is only known if
+	// modified is the latest revision of the key.
+	lease  int64
+	latest bool
+}
+
+// KeyRevisions is like Revisions, but returns the keys together with their
+// revisions and versions, so keys can be listed without reading their values.
+func (ti *treeIndex) KeyRevisions(key, end []byte, atRev int64, limit int, reverse bool) (keyRevs []keyRevision, total int) {
+	ti.RLock()
+	defer ti.RUnlock()
+
+	if end == nil {
+		keyi := ti.keyIndex(&keyIndex{key: key})
+		if keyi == nil {
+			return nil, 0
+		}
+		modified, created, ver, err := keyi.get(ti.lg, atRev)
+		if err != nil {
+			return nil, 0
+		}
+		return []keyRevision{newKeyRevision(keyi, modified, created, ver)}, 1
+	}
+	visit := ti.unsafeVisit
+	if reverse {
+		visit = ti.unsafeVisitReverse
+	}
+	visit(key, end, func(ki *keyIndex) bool {
+		if modified, created, ver, err := ki.get(ti.lg, atRev); err == nil {
+			if limit <= 0 || len(keyRevs) < limit {
+				keyRevs = append(keyRevs, newKeyRevision(ki, modified, created, ver))
+			}
+			total++
+		}
+		return true
+	})
+	return keyRevs, total
+}
+
+// newKeyRevision copies the key out of the index, so that the returned key
+// is not shared with the tree.
+func newKeyRevision(ki *keyIndex, modified, created revision, ver int64) keyRevision {
+	kr := keyRevision{key: append([]byte(nil), ki.key...), modified: modified, created: created, ver: ver}
+	if modified == ki.modified {
+		kr.lease, kr.latest = ki.lease, true
+	}
+	return kr
+}
+
 // CountRevisions returns the number of revisions
 // from key(included) to end(excluded) at the given rev.
 func (ti *treeIndex) CountRevisions(key, end []byte, atRev int64) int {
@@ -176,6 +271,27 @@ func (ti *treeIndex) Range(key, end []byte, atRev int64) (keys [][]byte, revs []
 	return keys, revs
 }
 
+// Keys returns the keys in the given range that exist at atRev, without
+// collecting their revisions.
+func (ti *treeIndex) Keys(key, end []byte, atRev int64) (keys [][]byte) {
+	ti.RLock()
+	defer ti.RUnlock()
+
+	if end == nil {
+		if _, _, _, err := ti.unsafeGet(key, atRev); err != nil {
+			return nil
+		}
+		return [][]byte{key}
+	}
+	ti.unsafeVisit(key, end, func(ki *keyIndex) bool {
+		if _, _, _, err := ki.get(ti.lg, atRev); err == nil {
+			keys = append(keys, ki.key)
+		}
+		return true
+	})
+	return keys
+}
+
 func (ti *treeIndex) Tombstone(key []byte, rev revision) error {
 	keyi := &keyIndex{key: key}
 
@@ -190,8 +306,20 @@ func (ti *treeIndex) Tombstone(key []byte, rev revision) error {
 }
 
 func (ti *treeIndex) Compact(rev int64) map[revision]struct{} {
-	available := make(map[revision]struct{})
 	ti.lg.Info("compact tree index", zap.Int64("revision", rev))
+	return ti.compact(func([]byte) int64 { return rev })
+}
+
+// CompactFloors compacts every key at the revision floor returns for it. Like
+// Compact, it only returns the revisions kept at or below the floor of each
+// key, so that their number is bounded by the number of keys.
+func (ti *treeIndex) CompactFloors(floor func(key []byte) int64) map[revision]struct{} {
+	ti.lg.Info("compact tree index with prefix retentions")
+	return ti.compact(floor)
+}
+
+func (ti *treeIndex) compact(floor func(key []byte) int64) map[revision]struct{} {
+	available := make(map[revision]struct{})
 	ti.Lock()
 	clone := ti.tree.Clone()
 	ti.Unlock()
@@ -200,7 +328,7 @@ func (ti *treeIndex) Compact(rev int64) map[revision]struct{} {
 		// Lock is needed here to prevent modification to the keyIndex while
 		// compaction is going on or revision added to empty before deletion
 		ti.Lock()
-		keyi.compact(ti.lg, rev, available)
+		keyi.compact(ti.lg, floor(keyi.key), available)
 		if keyi.isEmpty() {
 			_, ok := ti.tree.Delete(keyi)
 			if !ok {
@@ -225,6 +353,100 @@ func (ti *treeIndex) Keep(rev int64) map[revision]struct{} {
 	return available
 }
 
+// NthRevision returns the n-th most recent revision of the key.
+func (ti *treeIndex) NthRevision(key []byte, n int) (revision, bool) {
+	ti.RLock()
+	defer ti.RUnlock()
+	keyi := ti.keyIndex(&keyIndex{key: key})
+	if keyi == nil {
+		return revision{}, false
+	}
+	return keyi.nthRevision(n)
+}
+
+// HasRevision returns whether rev is a revision of the key, tombstones included.
+func (ti *treeIndex) HasRevision(key []byte, rev revision) bool {
+	ti.RLock()
+	defer ti.RUnlock()
+	keyi := ti.keyIndex(&keyIndex{key: key})
+	if keyi == nil {
+		return false
+	}
+	return keyi.hasRevision(rev)
+}
+
+// Histories returns up to limit keys from key on, in order, along with all
+// their revisions, tombstones included.
+func (ti *treeIndex) Histories(key []byte, limit int) (keys [][]byte, revs [][]revision) {
+	ti.RLock()
+	defer ti.RUnlock()
+	ti.tree.AscendGreaterOrEqual(&keyIndex{key: key}, func(ki *keyIndex) bool {
+		if len(keys) == limit {
+			return false
+		}
+		keys = append(keys, ki.key)
+		revs = append(revs, ki.revisions())
+		return true
+	})
+	return keys, revs
+}
+
+// History returns the revisions up to atRev of the keys from key(included)
+// to end(excluded), tombstones included, in the order of key.
+func (ti *treeIndex) History(key, end []byte, atRev int64) (revs []revision) {
+	ti.RLock()
+	defer ti.RUnlock()
+	visit := func(ki *keyIndex) bool {
+		for _, g := range ki.generations {
+			for _, rev := range g.revs {
+				if rev.main <= atRev {
+					revs = append(revs, rev)
+				}
+			}
+		}
+		return true
+	}
+	if end == nil {
+		if keyi := ti.keyIndex(&keyIndex{key: key}); keyi != nil {
+			visit(keyi)
+		}
+		return revs
+	}
+	ti.unsafeVisit(key, end, visit)
+	return revs
+}
+
+// KeysFrom returns up to limit keys from key on that exist at atRev, in
+// order, along with their revisions. Unlike KeyRevisions, it stops visiting
+// the index once it has limit keys.
+func (ti *treeIndex) KeysFrom(key []byte, atRev int64, limit int) (keyRevs []keyRevision) {
+	ti.RLock()
+	defer ti.RUnlock()
+	ti.tree.AscendGreaterOrEqual(&keyIndex{key: key}, func(ki *keyIndex) bool {
+		if len(keyRevs) == limit {
+			return false
+		}
+		if modified, created, ver, err := ki.get(ti.lg, atRev); err == nil {
+			keyRevs = append(keyRevs, keyRevision{key: ki.key, modified: modified, created: created, ver: ver})
+		}
+		return true
+	})
+	return keyRevs
+}
+
+// CompactKey removes the revisions of the key in the range (compactRev, atRev)
+// from the index. It returns the removed revisions, separating those that
+// point to tombstones.
+func (ti *treeIndex) CompactKey(key []byte, compactRev, atRev int64) (removed, tombstones []revision) {
+	ti.Lock()
+	defer ti.Unlock()
+	keyi := ti.keyIndex(&keyIndex{key: key})
+	if keyi == nil {
+		return nil, nil
+	}
+	return keyi.compactKey(compactRev, atRev)
+}
+
 func (ti *treeIndex) Equal(bi index) bool {
 	b := bi.(*treeIndex)
 
@@ -24,8 +24,8 @@ import (
 
 func TestIndexGet(t *testing.T) {
 	ti := newTreeIndex(zaptest.NewLogger(t))
-	ti.Put([]byte("foo"), revision{main: 2})
-	ti.Put([]byte("foo"), revision{main: 4})
+	ti.Put([]byte("foo"), revision{main: 2}, 0)
+	ti.Put([]byte("foo"), revision{main: 4}, 0)
 	ti.Tombstone([]byte("foo"), revision{main: 6})
 
 	tests := []struct {
@@ -67,7 +67,7 @@ func TestIndexRange(t *testing.T) {
 
 	ti := newTreeIndex(zaptest.NewLogger(t))
 	for i := range allKeys {
-		ti.Put(allKeys[i], allRevs[i])
+		ti.Put(allKeys[i], allRevs[i], 0)
 	}
 
 	atRev := int64(3)
@@ -117,12 +117,15 @@ func TestIndexRange(t *testing.T) {
 		if !reflect.DeepEqual(revs, tt.wrevs) {
 			t.Errorf("#%d: revs = %+v, want %+v", i, revs, tt.wrevs)
 		}
+		if keys := ti.Keys(tt.key, tt.end, atRev); !reflect.DeepEqual(keys, tt.wkeys) {
+			t.Errorf("#%d: Keys = %+v, want %+v", i, keys, tt.wkeys)
+		}
 	}
 }
 
 func TestIndexTombstone(t *testing.T) {
 	ti := newTreeIndex(zaptest.NewLogger(t))
-	ti.Put([]byte("foo"), revision{main: 1})
+	ti.Put([]byte("foo"), revision{main: 1}, 0)
 
 	err := ti.Tombstone([]byte("foo"), revision{main: 2})
 	if err != nil {
@@ -145,7 +148,7 @@ func TestIndexRevision(t *testing.T) {
 
 	ti := newTreeIndex(zaptest.NewLogger(t))
 	for i := range allKeys {
-		ti.Put(allKeys[i], allRevs[i])
+		ti.Put(allKeys[i], allRevs[i], 0)
 	}
 
 	tests := []struct {
@@ -222,7 +225,7 @@ func TestIndexRevision(t *testing.T) {
 		},
 	}
 	for i, tt := range tests {
-		revs, _ := ti.Revisions(tt.key, tt.end, tt.atRev, tt.limit)
+		revs, _ := ti.Revisions(tt.key, tt.end, tt.atRev, tt.limit, false)
 		if !reflect.DeepEqual(revs, tt.wrevs) {
 			t.Errorf("#%d limit %d: revs = %+v, want %+v", i, tt.limit, revs, tt.wrevs)
 		}
@@ -233,6 +236,60 @@ func TestIndexRevision(t *testing.T) {
 	}
 }
 
+func TestIndexRevisionReverse(t *testing.T) {
+	allKeys := [][]byte{[]byte("foo"), []byte("foo1"), []byte("foo2"), []byte("foo2"), []byte("foo1"), []byte("foo")}
+	allRevs := []revision{{main: 1}, {main: 2}, {main: 3}, {main: 4}, {main: 5}, {main: 6}}
+
+	ti := newTreeIndex(zaptest.NewLogger(t))
+	for i := range allKeys {
+		ti.Put(allKeys[i], allRevs[i], 0)
+	}
+
+	tests := []struct {
+		key, end []byte
+		atRev    int64
+		limit    int
+		wkeys    []string
+		wrevs    []revision
+		wcounts  int
+	}{
+		{
+			[]byte("foo"), []byte("fop"), 6, 0, []string{"foo2", "foo1", "foo"}, []revision{{main: 4}, {main: 5}, {main: 6}}, 3,
+		},
+		{
+			[]byte("foo"), []byte("fop"), 6, 2, []string{"foo2", "foo1"}, []revision{{main: 4}, {main: 5}}, 3,
+		},
+		// the end is excluded
+		{
+			[]byte("foo"), []byte("foo2"), 6, 0, []string{"foo1", "foo"}, []revision{{main: 5}, {main: 6}}, 2,
+		},
+		// an empty end is unbounded
+		{
+			[]byte("foo1"), []byte{}, 6, 1, []string{"foo2"}, []revision{{main: 4}}, 2,
+		},
+		{
+			[]byte("fop"), []byte{}, 6, 0, nil, nil, 0,
+		},
+		{
+			[]byte("foo"), []byte("fop"), 3, 0, []string{"foo2", "foo1", "foo"}, []revision{{main: 3}, {main: 2}, {main: 1}}, 3,
+		},
+	}
+	for i, tt := range tests {
+		revs, total := ti.Revisions(tt.key, tt.end, tt.atRev, tt.limit, true)
+		if !reflect.DeepEqual(revs, tt.wrevs) || total != tt.wcounts {
+			t.Errorf("#%d: revs = %+v (total %d), want %+v (total %d)", i, revs, total, tt.wrevs, tt.wcounts)
+		}
+		keyRevs, total := ti.KeyRevisions(tt.key, tt.end, tt.atRev, tt.limit, true)
+		var keys []string
+		for _, kr := range keyRevs {
+			keys = append(keys, string(kr.key))
+		}
+		if !reflect.DeepEqual(keys, tt.wkeys) || total != tt.wcounts {
+			t.Errorf("#%d: keys = %v (total %d), want %v (total %d)", i, keys, total, tt.wkeys, tt.wcounts)
+		}
+	}
+}
+
 func TestIndexCompactAndKeep(t *testing.T) {
 	maxRev := int64(20)
 	tests := []struct {
@@ -261,7 +318,7 @@ func TestIndexCompactAndKeep(t *testing.T) {
 		if tt.remove {
 			ti.Tombstone(tt.key, tt.rev)
 		} else {
-			ti.Put(tt.key, tt.rev)
+			ti.Put(tt.key, tt.rev, 0)
 		}
 	}
 	for i := int64(1); i < maxRev; i++ {
@@ -294,7 +351,7 @@ func TestIndexCompactAndKeep(t *testing.T) {
 			if tt.remove {
 				ti.Tombstone(tt.key, tt.rev)
 			} else {
-				ti.Put(tt.key, tt.rev)
+				ti.Put(tt.key, tt.rev, 0)
 			}
 		}
 		am := ti.Compact(i)
@@ -320,6 +377,44 @@ func TestIndexCompactAndKeep(t *testing.T) {
 	}
 }
 
+func TestIndexCompactFloors(t *testing.T) {
+	ti := newTreeIndex(zaptest.NewLogger(t))
+	for i := int64(1); i <= 10; i++ {
+		ti.Put([]byte("a"), revision{main: 2*i - 1}, 0)
+		ti.Put([]byte("b"), revision{main: 2 * i}, 0)
+	}
+
+	// "a" is retained from revision 15 on, "b" is compacted at 4.
+	floors := map[string]int64{"a": 15, "b": 4}
+	keep := ti.CompactFloors(func(key []byte) int64 { return floors[string(key)] })
+	wkeep := map[revision]struct{}{{main: 15}: {}, {main: 4}: {}}
+	if !reflect.DeepEqual(keep, wkeep) {
+		t.Errorf("keep = %v, want %v", keep, wkeep)
+	}
+
+	if _, _, _, err := ti.Get([]byte("a"), 13); err != ErrRevisionNotFound {
+		t.Errorf("Get(a, 13) error = %v, want %v", err, ErrRevisionNotFound)
+	}
+	for _, tt := range []struct {
+		key  string
+		rev  int64
+		want int64
+	}{
+		{"a", 15, 15},
+		{"a", 17, 17},
+		{"b", 4, 4},
+		{"b", 10, 10},
+	} {
+		modified, _, _, err := ti.Get([]byte(tt.key), tt.rev)
+		if err != nil {
+			t.Fatalf("Get(%q, %d) error = %v", tt.key, tt.rev, err)
+		}
+		if modified.main != tt.want {
+			t.Errorf("Get(%q, %d) = %d, want %d", tt.key, tt.rev, modified.main, tt.want)
+		}
+	}
+}
+
 func restore(ti *treeIndex, key []byte, created, modified revision, ver int64) {
 	keyi := &keyIndex{key: key}
 
@@ -75,6 +75,7 @@ type keyIndex struct {
 	key         []byte
 	modified    revision // the main rev of the last modification
 	generations []generation
+	lease       int64 // the lease attached at modified
 }
 
 // put puts a revision to the keyIndex.
@@ -131,6 +132,7 @@ func (ki *keyIndex) tombstone(lg *zap.Logger, main int64, sub int64) error {
 		return ErrRevisionNotFound
 	}
 	ki.put(lg, main, sub)
+	ki.lease = 0
 	ki.generations = append(ki.generations, generation{})
 	keysGauge.Dec()
 	return nil
@@ -248,6 +250,54 @@ func (ki *keyIndex) keep(atRev int64, available map[revision]struct{}) {
 	}
 }
 
+// nthRevision returns the n-th most recent revision of the key, counting
+// tombstones. It returns false if the key has less than n revisions.
+func (ki *keyIndex) nthRevision(n int) (revision, bool) {
+	for gi := len(ki.generations) - 1; gi >= 0; gi-- {
+		revs := ki.generations[gi].revs
+		if n <= len(revs) {
+			return revs[len(revs)-n], true
+		}
+		n -= len(revs)
+	}
+	return revision{}, false
+}
+
+// compactKey removes the revisions of the key whose main revision is in the
+// range (compactRev, atRev), and returns them. A generation that loses all
+// of its revisions is removed. The tombstone of a generation that keeps
+// revisions at or below compactRev is kept, so that the generation still
+// ends.
+func (ki *keyIndex) compactKey(compactRev, atRev int64) (removed []revision, tombstones []revision) {
+	gens := ki.generations[:0]
+	for gi, g := range ki.generations {
+		last := gi == len(ki.generations)-1
+		revs := g.revs[:0]
+		for i, rev := range g.revs {
+			if rev.main <= compactRev || rev.main >= atRev {
+				revs = append(revs, rev)
+				continue
+			}
+			if !last && i == len(g.revs)-1 {
+				if len(revs) != 0 {
+					revs = append(revs, rev)
+					continue
+				}
+				tombstones = append(tombstones, rev)
+				continue
+			}
+			removed = append(removed, rev)
+		}
+		g.revs = revs
+		if len(revs) == 0 && !last {
+			continue
+		}
+		gens = append(gens, g)
+	}
+	ki.generations = gens
+	return removed, tombstones
+}
+
 func (ki *keyIndex) doCompact(atRev int64, available map[revision]struct{}) (genIdx int, revIndex int) {
 	// walk until reaching the first revision smaller or equal to "atRev",
 	// and add the revision to the available map
@@ -274,6 +324,27 @@ func (ki *keyIndex) doCompact(atRev int64, available map[revision]struct{}) (gen
 	return genIdx, revIndex
 }
 
+// hasRevision returns whether rev is in a generation of the keyIndex.
+func (ki *keyIndex) hasRevision(rev revision) bool {
+	for _, g := range ki.generations {
+		for _, r := range g.revs {
+			if r == rev {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// revisions returns all the revisions of the keyIndex in ascending order.
+func (ki *keyIndex) revisions() []revision {
+	var revs []revision
+	for _, g := range ki.generations {
+		revs = append(revs, g.revs...)
+	}
+	return revs
+}
+
 func (ki *keyIndex) isEmpty() bool {
 	return len(ki.generations) == 1 && ki.generations[0].isEmpty()
 }
@@ -312,7 +383,7 @@ func (ki *keyIndex) equal(b *keyIndex) bool {
 	if !bytes.Equal(ki.key, b.key) {
 		return false
 	}
-	if ki.modified != b.modified {
+	if ki.modified != b.modified || ki.lease != b.lease {
 		return false
 	}
 	if len(ki.generations) != len(b.generations) {
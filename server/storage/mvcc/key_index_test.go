@@ -518,7 +518,7 @@ func cloneKeyIndex(ki *keyIndex) *keyIndex {
 	for i, gen := range ki.generations {
 		generations[i] = *cloneGeneration(&gen)
 	}
-	return &keyIndex{ki.key, ki.modified, generations}
+	return &keyIndex{ki.key, ki.modified, generations, ki.lease}
 }
 
 func cloneGeneration(g *generation) *generation {
@@ -557,6 +557,49 @@ func TestKeyIndexCompactOnFurtherRev(t *testing.T) {
 	}
 }
 
+func TestKeyIndexCompactKey(t *testing.T) {
+	tests := []struct {
+		compactRev, atRev int64
+
+		wgens       []generation
+		wremoved    []revision
+		wtombstones []revision
+	}{
+		{
+			compactRev: 0, atRev: 14,
+			wgens: []generation{
+				{created: revision{14, 0}, ver: 3, revs: []revision{{14, 0}, {14, 1}, {16, 0}}},
+				{},
+			},
+			wremoved:    []revision{{2, 0}, {4, 0}, {8, 0}, {10, 0}},
+			wtombstones: []revision{{6, 0}, {12, 0}},
+		},
+		{
+			compactRev: 8, atRev: 16,
+			wgens: []generation{
+				{created: revision{2, 0}, ver: 3, revs: []revision{{2, 0}, {4, 0}, {6, 0}}},
+				{created: revision{8, 0}, ver: 3, revs: []revision{{8, 0}, {12, 0}}},
+				{created: revision{14, 0}, ver: 3, revs: []revision{{16, 0}}},
+				{},
+			},
+			wremoved: []revision{{10, 0}, {14, 0}, {14, 1}},
+		},
+	}
+	for i, tt := range tests {
+		ki := newTestKeyIndex(zaptest.NewLogger(t))
+		removed, tombstones := ki.compactKey(tt.compactRev, tt.atRev)
+		if !reflect.DeepEqual(ki.generations, tt.wgens) {
+			t.Errorf("#%d: generations = %+v, want %+v", i, ki.generations, tt.wgens)
+		}
+		if !reflect.DeepEqual(removed, tt.wremoved) {
+			t.Errorf("#%d: removed = %+v, want %+v", i, removed, tt.wremoved)
+		}
+		if !reflect.DeepEqual(tombstones, tt.wtombstones) {
+			t.Errorf("#%d: tombstones = %+v, want %+v", i, tombstones, tt.wtombstones)
+		}
+	}
+}
+
 func TestKeyIndexIsEmpty(t *testing.T) {
 	tests := []struct {
 		ki *keyIndex
@@ -27,6 +27,13 @@ type RangeOptions struct {
 	Limit int64
 	Rev   int64
 	Count bool
+	// KeysOnly omits the values of the returned key-value pairs, so that they
+	// are read from the index. The backend is only read for the leases of
+	// keys that were modified after the requested revision.
+	KeysOnly bool
+	// Reverse returns the key-value pairs in the descending order of their
+	// keys, walking the index backward.
+	Reverse bool
 }
 
 type RangeResult struct {
@@ -100,6 +107,25 @@ func (trw *txnReadWrite) Changes() []mvccpb.KeyValue { return nil }
 
 func NewReadOnlyTxnWrite(txn TxnRead) TxnWrite { return &txnReadWrite{txn} }
 
+// txnDryRunWrite coerces a read txn to a write whose write operations change
+// nothing: they report the result they would have at the current revision.
+type txnDryRunWrite struct{ TxnRead }
+
+func (tdw *txnDryRunWrite) DeleteRange(key, end []byte) (n, rev int64) {
+	r, err := tdw.Range(context.TODO(), key, end, RangeOptions{Count: true})
+	if err != nil {
+		return 0, tdw.Rev()
+	}
+	return int64(r.Count), tdw.Rev()
+}
+func (tdw *txnDryRunWrite) Put(key, value []byte, lease lease.LeaseID) (rev int64) { return tdw.Rev() }
+func (tdw *txnDryRunWrite) Changes() []mvccpb.KeyValue                             { return nil }
+
+// NewDryRunTxnWrite returns a write txn that reads through txn, while its Put
+// and DeleteRange only report the revision and the number of deleted keys they
+// would have without writing anything.
+func NewDryRunTxnWrite(txn TxnRead) TxnWrite { return &txnDryRunWrite{txn} }
+
 type ReadTxMode uint32
 
 const (
@@ -125,6 +151,69 @@ type KV interface {
 	// Compact frees all superseded keys with revisions less than rev.
 	Compact(trace *traceutil.Trace, rev int64) (<-chan struct{}, error)
 
+	// CompactRetained is like Compact, except that the keys under the
+	// prefixes of retained are compacted at the revision of their prefix.
+	CompactRetained(trace *traceutil.Trace, rev int64, retained []PrefixRetention) (<-chan struct{}, error)
+
+	// CompactKey frees the revisions of key older than its keep most recent
+	// ones. It returns the current revision and the oldest revision of key
+	// that can be read.
+	CompactKey(trace *traceutil.Trace, key []byte, keep int64) (rev, compactRev int64, err error)
+
+	// CompactRev returns the revision of the last compaction, or 0 if the store
+	// was never compacted. Reads at a lower revision fail with ErrCompacted.
+	CompactRev() int64
+
+	// ReadFloorRev returns the lowest revision at which no read or watch
+	// fails with ErrCompacted, or 0 if the store was never compacted. It is
+	// above CompactRev if prefix retentions or key compactions removed the
+	// revisions of some keys further.
+	ReadFloorRev() int64
+
+	// CompactionStatus returns the progress of the latest requested compaction.
+	CompactionStatus() CompactionStatus
+
+	// Replay returns the events on the keys in [key, end), interpreted as in
+	// Watch, with a revision in [startRev, endRev). It fails with ErrCompacted
+	// if revisions of the range from startRev on were compacted, and with
+	// ErrFutureRev if endRev is after the revision following the current one.
+	Replay(key, end []byte, startRev, endRev int64) ([]mvccpb.Event, error)
+
+	// Verify checks the consistency of the backend and that the key index
+	// matches it, returning the revision it checked at and the anomalies
+	// found. The scan is throttled by opts and stops once ctx is done.
+	Verify(ctx context.Context, opts VerifyOptions) (rev int64, anomalies []Anomaly, err error)
+
+	// RebuildIndex replaces the key index by one rebuilt from the backend,
+	// returning the revision it was rebuilt at and the revisions the
+	// replaced index was missing or had in excess. The reads and the writes
+	// wait until the index is replaced. It returns once the rebuild is done,
+	// or skipped because ctx was done before it started.
+	RebuildIndex(ctx context.Context) (rev int64, anomalies []Anomaly, err error)
+
+	// KeyspaceStats returns the number and the size of the keys, along with
+	// up to limit largest prefixes of the keys at depth. The result is
+	// cached for a while, so it may lag behind the store.
+	KeyspaceStats(ctx context.Context, depth, limit int) (KeyspaceStats, error)
+
+	// HotKeys returns up to limit most read and most written keys, sorted by
+	// their estimated access counts. If reset is true, the counts start over.
+	// It returns ErrHotKeyTrackingDisabled if hot key tracking is disabled.
+	HotKeys(limit int, reset bool) (reads, writes []HotKey, err error)
+
+	// SetPrefixQuota sets the quota of the keys with q.Prefix, replacing the
+	// previous quota of the prefix. A quota without limits removes it.
+	SetPrefixQuota(q PrefixQuota)
+
+	// PrefixQuotas returns the prefix quotas sorted by prefix, along with
+	// the usage of their prefixes.
+	PrefixQuotas() []PrefixQuotaUsage
+
+	// CheckPrefixQuotas returns a *QuotaExceededError if applying the
+	// non-overlapping ops to rv would take the keys under a prefix over
+	// its quota.
+	CheckPrefixQuotas(rv ReadView, ops []QuotaOp) error
+
 	// Commit commits outstanding txns into the underlying backend.
 	Commit()
 
@@ -144,4 +233,22 @@ type Watchable interface {
 	// NewWatchStream returns a WatchStream that can be used to
 	// watch events happened or happening on the KV.
 	NewWatchStream() WatchStream
+
+	// NewConnWatchStream returns a WatchStream like NewWatchStream, counted
+	// against the watch streams of the client connection conn until it is
+	// closed. It fails with ErrTooManyWatchStreams if conn already has
+	// StoreConfig.MaxWatchStreamsPerConn streams open.
+	NewConnWatchStream(conn string) (WatchStream, error)
+}
+
+// CompactionStatus describes the progress of a compaction.
+type CompactionStatus struct {
+	// InProgress is true if a compaction is scheduled or running.
+	InProgress bool
+	// CompactRevision is the revision the compaction compacts up to.
+	CompactRevision int64
+	// ScannedRevision is the revision of the last key scanned by the compaction.
+	ScannedRevision int64
+	// RemainingKeys is an estimate of the number of keys left to scan.
+	RemainingKeys int64
 }
@@ -16,6 +16,7 @@ package mvcc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
@@ -256,6 +257,145 @@ func testKVRangeLimit(t *testing.T, f rangeFunc) {
 	}
 }
 
+func TestKVRangeKeysOnly(t *testing.T)    { testKVRangeKeysOnly(t, normalRangeFunc) }
+func TestKVTxnRangeKeysOnly(t *testing.T) { testKVRangeKeysOnly(t, txnRangeFunc) }
+
+// testKVRangeKeysOnly ensures keys only ranges return the same keys, revisions,
+// versions, leases and counts as full ranges, without values.
+func testKVRangeKeysOnly(t *testing.T, f rangeFunc) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	s.Put([]byte("foo"), []byte("bar"), 1)
+	s.Put([]byte("foo1"), []byte("bar1"), lease.NoLease)
+	s.Put([]byte("foo2"), []byte("bar2"), 2)
+	s.Put([]byte("foo1"), []byte("bar11"), lease.NoLease)
+	s.DeleteRange([]byte("foo2"), nil)
+	s.Put([]byte("foo2"), []byte("bar22"), lease.NoLease)
+	s.Put([]byte("foo3"), []byte("bar3"), lease.NoLease)
+
+	tests := []struct {
+		key, end []byte
+		ro       RangeOptions
+	}{
+		{[]byte("foo"), nil, RangeOptions{}},
+		{[]byte("foo1"), nil, RangeOptions{Rev: 3}},
+		{[]byte("foo"), []byte("foo3"), RangeOptions{}},
+		{[]byte("foo"), []byte("foo4"), RangeOptions{Limit: 2}},
+		{[]byte("foo"), []byte("foo4"), RangeOptions{Rev: 4}},
+		{[]byte("foo"), []byte("foo4"), RangeOptions{Rev: 6}},
+		{[]byte("bar"), []byte("baz"), RangeOptions{}},
+	}
+	for i, tt := range tests {
+		wr, err := f(s, tt.key, tt.end, tt.ro)
+		if err != nil {
+			t.Fatalf("#%d: range error (%v)", i, err)
+		}
+		for j := range wr.KVs {
+			wr.KVs[j].Value = nil
+		}
+
+		ro := tt.ro
+		ro.KeysOnly = true
+		r, err := f(s, tt.key, tt.end, ro)
+		if err != nil {
+			t.Fatalf("#%d: keys only range error (%v)", i, err)
+		}
+		if len(r.KVs) != len(wr.KVs) || (len(r.KVs) > 0 && !reflect.DeepEqual(r.KVs, wr.KVs)) {
+			t.Errorf("#%d: kvs = %+v, want %+v", i, r.KVs, wr.KVs)
+		}
+		if r.Count != wr.Count {
+			t.Errorf("#%d: count = %d, want %d", i, r.Count, wr.Count)
+		}
+		if r.Rev != wr.Rev {
+			t.Errorf("#%d: rev = %d, want %d", i, r.Rev, wr.Rev)
+		}
+		// the returned keys must not share memory with the index
+		for j := range r.KVs {
+			r.KVs[j].Key[0] = 'x'
+		}
+	}
+
+	// the leases are restored into the index
+	ns := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer ns.Close()
+	r, err := f(ns, []byte("foo"), []byte("foo4"), RangeOptions{KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wkeys, wleases := []string{"foo", "foo1", "foo2", "foo3"}, []int64{1, 0, 0, 0}
+	if len(r.KVs) != len(wkeys) {
+		t.Fatalf("len(kvs) = %d, want %d", len(r.KVs), len(wkeys))
+	}
+	for i, kv := range r.KVs {
+		if string(kv.Key) != wkeys[i] || kv.Lease != wleases[i] {
+			t.Errorf("#%d: key = %q, lease = %d, want %q, %d", i, kv.Key, kv.Lease, wkeys[i], wleases[i])
+		}
+	}
+}
+
+func TestKVRangeCountOnly(t *testing.T)    { testKVRangeCountOnly(t, normalRangeFunc) }
+func TestKVTxnRangeCountOnly(t *testing.T) { testKVRangeCountOnly(t, txnRangeFunc) }
+
+// testKVRangeCountOnly ensures count only ranges return the counts of full
+// ranges without any key, and fail the same way at compacted revisions.
+func testKVRangeCountOnly(t *testing.T, f rangeFunc) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+	s.Put([]byte("foo1"), []byte("bar1"), lease.NoLease)
+	s.Put([]byte("foo2"), []byte("bar2"), lease.NoLease)
+	s.Put([]byte("foo1"), []byte("bar11"), lease.NoLease)
+	s.DeleteRange([]byte("foo2"), nil)
+	s.Put([]byte("foo2"), []byte("bar22"), lease.NoLease)
+	s.Put([]byte("foo3"), []byte("bar3"), lease.NoLease)
+	if _, err := s.Compact(traceutil.TODO(), 4); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, end []byte
+		ro       RangeOptions
+	}{
+		{[]byte("foo"), nil, RangeOptions{}},
+		{[]byte("foo2"), nil, RangeOptions{Rev: 5}},
+		{[]byte("foo2"), nil, RangeOptions{Rev: 6}},
+		{[]byte("foo"), []byte("foo3"), RangeOptions{}},
+		{[]byte("foo"), []byte("foo4"), RangeOptions{Limit: 2}},
+		{[]byte("foo"), []byte("foo4"), RangeOptions{Rev: 4}},
+		{[]byte("foo"), []byte("foo4"), RangeOptions{Rev: 6}},
+		{[]byte("foo"), []byte{}, RangeOptions{}},
+		{[]byte("bar"), []byte("baz"), RangeOptions{}},
+		// compacted
+		{[]byte("foo"), []byte("foo4"), RangeOptions{Rev: 3}},
+	}
+	for i, tt := range tests {
+		wr, werr := f(s, tt.key, tt.end, tt.ro)
+
+		ro := tt.ro
+		ro.Count = true
+		r, err := f(s, tt.key, tt.end, ro)
+		if !errors.Is(err, werr) {
+			t.Fatalf("#%d: count only range error = %v, want %v", i, err, werr)
+		}
+		if werr != nil {
+			continue
+		}
+		if len(r.KVs) != 0 {
+			t.Errorf("#%d: kvs = %+v, want none", i, r.KVs)
+		}
+		if r.Count != wr.Count {
+			t.Errorf("#%d: count = %d, want %d", i, r.Count, wr.Count)
+		}
+		if r.Rev != wr.Rev {
+			t.Errorf("#%d: rev = %d, want %d", i, r.Rev, wr.Rev)
+		}
+	}
+}
+
 func TestKVPutMultipleTimes(t *testing.T)    { testKVPutMultipleTimes(t, normalPutFunc) }
 func TestKVTxnPutMultipleTimes(t *testing.T) { testKVPutMultipleTimes(t, txnPutFunc) }
 
@@ -361,6 +501,42 @@ func testKVDeleteMultipleTimes(t *testing.T, f deleteRangeFunc) {
 	}
 }
 
+func TestKVDeleteRangeAfterCompact(t *testing.T) {
+	testKVDeleteRangeAfterCompact(t, normalDeleteRangeFunc)
+}
+func TestKVTxnDeleteRangeAfterCompact(t *testing.T) {
+	testKVDeleteRangeAfterCompact(t, txnDeleteRangeFunc)
+}
+
+// testKVDeleteRangeAfterCompact ensures the deleted count only includes the keys
+// alive at the deletion, wherever the compaction revision falls.
+func testKVDeleteRangeAfterCompact(t *testing.T, f deleteRangeFunc) {
+	// keys are put at rev 2-11, updated at rev 12-16 and deleted at rev 17-18
+	for compactRev := int64(1); compactRev <= 18; compactRev++ {
+		b, _ := betesting.NewDefaultTmpBackend(t)
+		s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+
+		for i := 0; i < 10; i++ {
+			s.Put([]byte(fmt.Sprintf("foo%d", i)), []byte("bar"), lease.NoLease)
+		}
+		for i := 0; i < 5; i++ {
+			s.Put([]byte(fmt.Sprintf("foo%d", i)), []byte("baz"), lease.NoLease)
+		}
+		s.DeleteRange([]byte("foo5"), nil)
+		s.DeleteRange([]byte("foo6"), nil)
+
+		if _, err := s.Compact(traceutil.TODO(), compactRev); err != nil {
+			t.Fatalf("compact at %d: %v", compactRev, err)
+		}
+		n, rev := f(s, []byte("foo"), []byte("fop"))
+		if n != 8 || rev != 19 {
+			t.Errorf("compact at %d: n = %d, rev = %d, want (%d, %d)", compactRev, n, rev, 8, 19)
+		}
+
+		cleanup(s, b)
+	}
+}
+
 func TestKVPutWithSameLease(t *testing.T)    { testKVPutWithSameLease(t, normalPutFunc) }
 func TestKVTxnPutWithSameLease(t *testing.T) { testKVPutWithSameLease(t, txnPutFunc) }
 
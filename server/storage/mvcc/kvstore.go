@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -35,6 +36,10 @@ import (
 var (
 	ErrCompacted = errors.New("mvcc: required revision has been compacted")
 	ErrFutureRev = errors.New("mvcc: required revision is a future revision")
+	// ErrInvalidKeep is returned by CompactKey if it is asked to keep no revision.
+	ErrInvalidKeep = errors.New("mvcc: number of revisions to keep must be positive")
+
+	ErrHotKeyTrackingDisabled = errors.New("mvcc: hot key tracking is disabled")
 )
 
 const (
@@ -44,6 +49,7 @@ const (
 	markedRevBytesLen      = revBytesLen + 1
 	markBytePosition       = markedRevBytesLen - 1
 	markTombstone     byte = 't'
+	markKeyCompaction byte = 'c'
 )
 
 var restoreChunkKeys = 10000 // non-const for testing
@@ -53,6 +59,35 @@ var minimumBatchInterval = 10 * time.Millisecond
 type StoreConfig struct {
 	CompactionBatchLimit    int
 	CompactionSleepInterval time.Duration
+	// CompactionSleep is the pause after each compaction batch, which lets
+	// the reads waiting for the backend run. It is skipped while another
+	// compaction waits behind the running one.
+	CompactionSleep time.Duration
+	// HotKeyTracking enables sampling accesses to keep track of the most read
+	// and most written keys.
+	HotKeyTracking bool
+	// CompactionNotify, if set, is called with the result of each completed
+	// compaction.
+	CompactionNotify func(CompactionResult)
+	// MaxWatchStreamsPerConn limits the watch streams of each connection
+	// created by NewConnWatchStream. Zero means no limit.
+	MaxWatchStreamsPerConn int
+	// ReadCacheBytes bounds the size of the cache of the key-value pairs
+	// read by point gets. Zero disables the cache.
+	ReadCacheBytes int64
+}
+
+// CompactionResult reports the revisions removed and retained by a compaction.
+type CompactionResult struct {
+	// Revision is the compaction revision.
+	Revision int64
+	// RemovedRevisions is the number of revisions removed from the backend.
+	RemovedRevisions int64
+	// RetainedRevisions is the number of revisions above the previous
+	// compaction revision and at or below Revision that are retained, which
+	// are the latest revisions of the live keys. The revisions retained by an
+	// earlier compaction are not counted again.
+	RetainedRevisions int64
 }
 
 type store struct {
@@ -66,6 +101,9 @@ type store struct {
 
 	b       backend.Backend
 	kvindex index
+	// indexMu serializes the compactions of kvindex with its replacement by
+	// RebuildIndex, which also holds mu.
+	indexMu sync.Mutex
 
 	le lease.Lessor
 
@@ -77,6 +115,37 @@ type store struct {
 	currentRev int64
 	// compactMainRev is the main revision of the last compaction.
 	compactMainRev int64
+	// retention holds the compaction floors of the keys if a compaction with
+	// prefix retentions compacted some keys above compactMainRev. It is empty
+	// otherwise.
+	retention compactFloors
+
+	// keyCompactions tracks the keys compacted by CompactKey. It is
+	// changed holding mu and revMu, and read holding either.
+	keyCompactions keyCompactions
+
+	// verifyMu protects compacting and verifiers.
+	verifyMu sync.Mutex
+	// compacting holds the revisions removed by the compaction in progress.
+	compacting *compactedRevisions
+	// verifiers are the scans of Verify in progress.
+	verifiers map[*verifier]struct{}
+
+	// compactStatusMu protects compactStatus.
+	compactStatusMu sync.Mutex
+	// compactStatus is the progress of the latest requested compaction.
+	compactStatus CompactionStatus
+
+	// hotKeys is nil if hot key tracking is disabled.
+	hotKeys *hotKeyTracker
+	// readCache is nil if the read cache is disabled.
+	readCache *readCache
+
+	// quotas tracks the usage of the prefixes with a quota.
+	quotas prefixQuotas
+
+	// keyspaceStats caches the results of KeyspaceStats.
+	keyspaceStats keyspaceStatsCache
 
 	fifoSched schedule.Scheduler
 
@@ -108,12 +177,20 @@ func NewStore(lg *zap.Logger, b backend.Backend, le lease.Lessor, cfg StoreConfi
 		currentRev:     1,
 		compactMainRev: -1,
 
+		keyCompactions: newKeyCompactions(),
+
 		fifoSched: schedule.NewFIFOScheduler(lg),
 
 		stopc: make(chan struct{}),
 
 		lg: lg,
 	}
+	if cfg.HotKeyTracking {
+		s.hotKeys = newHotKeyTracker(hotKeyCapacity, hotKeySampleInterval)
+	}
+	if cfg.ReadCacheBytes > 0 {
+		s.readCache = newReadCache(cfg.ReadCacheBytes)
+	}
 	s.hashes = newHashStorage(lg, s)
 	s.ReadView = &readView{s}
 	s.WriteView = &writeView{s}
@@ -168,7 +245,36 @@ func (s *store) hash() (hash uint32, revision int64, err error) {
 	return h, s.currentRev, err
 }
 
+func (s *store) CompactRev() int64 {
+	s.revMu.RLock()
+	defer s.revMu.RUnlock()
+	if s.compactMainRev < 0 {
+		return 0
+	}
+	return s.compactMainRev
+}
+
+func (s *store) ReadFloorRev() int64 {
+	s.revMu.RLock()
+	defer s.revMu.RUnlock()
+	rev := s.compactMainRev
+	if !s.retention.uniform() && s.retention.max() > rev {
+		rev = s.retention.max()
+	}
+	if s.keyCompactions.maxRev > rev {
+		rev = s.keyCompactions.maxRev
+	}
+	if rev < 0 {
+		return 0
+	}
+	return rev
+}
+
 func (s *store) hashByRev(rev int64) (hash KeyValueHash, currentRev int64, err error) {
+	return s.hashByRevRange(rev, nil, nil)
+}
+
+func (s *store) hashByRevRange(rev int64, key, end []byte) (hash KeyValueHash, currentRev int64, err error) {
 	var compactRev int64
 	start := time.Now()
 
@@ -177,7 +283,9 @@ func (s *store) hashByRev(rev int64) (hash KeyValueHash, currentRev int64, err e
 	compactRev, currentRev = s.compactMainRev, s.currentRev
 	s.revMu.RUnlock()
 
-	if rev > 0 && rev < compactRev {
+	// a key compaction after rev changed the revisions up to rev, and a
+	// compaction with prefix retentions keeps different revisions than Keep
+	if rev > 0 && (rev < compactRev || rev < s.keyCompactions.lastRev || (!s.retention.uniform() && rev < s.retention.max())) {
 		s.mu.RUnlock()
 		return KeyValueHash{}, 0, ErrCompacted
 	} else if rev > 0 && rev > currentRev {
@@ -188,33 +296,75 @@ func (s *store) hashByRev(rev int64) (hash KeyValueHash, currentRev int64, err e
 		rev = currentRev
 	}
 	keep := s.kvindex.Keep(rev)
+	var revs []revision
+	if key != nil {
+		revs = s.rangeHashRevisions(rev, key, end)
+	}
 
 	tx := s.b.ReadTx()
 	tx.RLock()
 	defer tx.RUnlock()
 	s.mu.RUnlock()
-	hash, err = unsafeHashByRev(tx, compactRev, rev, keep)
+	if key != nil {
+		hash = unsafeHashRevisions(tx, compactRev, rev, keep, revs)
+	} else {
+		hash, err = unsafeHashByRev(tx, compactRev, rev, keep)
+	}
 	hashRevSec.Observe(time.Since(start).Seconds())
 	return hash, currentRev, err
 }
 
-func (s *store) updateCompactRev(rev int64) (<-chan struct{}, int64, error) {
+// rangeHashRevisions returns the revisions up to rev of the keys in the range
+// [key, end), interpreted as in Range, along with the revisions of the markers
+// of their key compactions, in ascending order.
+func (s *store) rangeHashRevisions(rev int64, key, end []byte) []revision {
+	switch {
+	case len(end) == 0:
+		end = nil
+	case len(end) == 1 && end[0] == 0:
+		end = []byte{}
+	}
+	revs := s.kvindex.History(key, end, rev)
+	s.keyCompactions.ascendRange(key, end, func(c keyCompaction) bool {
+		for _, m := range c.markers {
+			if m <= rev {
+				revs = append(revs, revision{main: m})
+			}
+		}
+		return true
+	})
+	sort.Slice(revs, func(i, j int) bool { return revs[j].GreaterThan(revs[i]) })
+	return revs
+}
+
+func (s *store) updateCompactRev(req compactFloors) (<-chan struct{}, int64, error) {
 	s.revMu.Lock()
-	if rev <= s.compactMainRev {
+	prev := s.floors()
+	next := prev.merge(req)
+	if next.equal(prev) {
 		ch := make(chan struct{})
 		f := schedule.NewJob("kvstore_updateCompactRev_compactBarrier", func(ctx context.Context) { s.compactBarrier(ctx, ch) })
 		s.fifoSched.Schedule(f)
 		s.revMu.Unlock()
 		return ch, 0, ErrCompacted
 	}
-	if rev > s.currentRev {
+	if req.max() > s.currentRev {
 		s.revMu.Unlock()
 		return nil, 0, ErrFutureRev
 	}
 	compactMainRev := s.compactMainRev
-	s.compactMainRev = rev
+	s.compactMainRev = next.min()
+	if next.uniform() {
+		s.retention = compactFloors{}
+	} else {
+		s.retention = next
+	}
+	s.keyCompactions.prune(next)
 
-	SetScheduledCompact(s.b.BatchTx(), rev)
+	SetScheduledCompact(s.b.BatchTx(), s.compactMainRev)
+	if !prev.uniform() || !next.uniform() {
+		setCompactFloors(s.b.BatchTx(), s.retention)
+	}
 	// ensure that desired compaction is persisted
 	// gofail: var compactBeforeCommitScheduledCompact struct{}
 	s.b.ForceCommit()
@@ -235,14 +385,18 @@ func (s *store) checkPrevCompactionCompleted() bool {
 	return scheduledCompact == finishedCompact && scheduledCompactFound == finishedCompactFound
 }
 
-func (s *store) compact(trace *traceutil.Trace, rev, prevCompactRev int64, prevCompactionCompleted bool) (<-chan struct{}, error) {
+func (s *store) compact(trace *traceutil.Trace, floors compactFloors, prevCompactRev int64, prevCompactionCompleted bool) (<-chan struct{}, error) {
 	ch := make(chan struct{})
+	rev := floors.max()
+	s.setCompactionStatus(CompactionStatus{InProgress: true, CompactRevision: rev})
 	j := schedule.NewJob("kvstore_compact", func(ctx context.Context) {
 		if ctx.Err() != nil {
+			s.clearCompactionStatus(rev)
 			s.compactBarrier(ctx, ch)
 			return
 		}
-		hash, err := s.scheduleCompaction(rev, prevCompactRev)
+		hash, err := s.scheduleCompaction(floors, prevCompactRev)
+		s.clearCompactionStatus(rev)
 		if err != nil {
 			s.lg.Warn("Failed compaction", zap.Error(err))
 			s.compactBarrier(context.TODO(), ch)
@@ -250,8 +404,12 @@ func (s *store) compact(trace *traceutil.Trace, rev, prevCompactRev int64, prevC
 		}
 		// Only store the hash value if the previous hash is completed, i.e. this compaction
 		// hashes every revision from last compaction. For more details, see #15919.
-		if prevCompactionCompleted {
+		// A compaction with prefix retentions keeps other revisions than a
+		// compaction at its revision, so its hash cannot be compared.
+		if prevCompactionCompleted && floors.uniform() {
 			s.hashes.Store(hash)
+		} else if !floors.uniform() {
+			s.lg.Info("compaction retained prefixes, skip storing compaction hash value")
 		} else {
 			s.lg.Info("previous compaction was interrupted, skip storing compaction hash value")
 		}
@@ -263,28 +421,82 @@ func (s *store) compact(trace *traceutil.Trace, rev, prevCompactRev int64, prevC
 	return ch, nil
 }
 
-func (s *store) compactLockfree(rev int64) (<-chan struct{}, error) {
+func (s *store) compactLockfree(floors compactFloors) (<-chan struct{}, error) {
 	prevCompactionCompleted := s.checkPrevCompactionCompleted()
-	ch, prevCompactRev, err := s.updateCompactRev(rev)
+	ch, prevCompactRev, err := s.updateCompactRev(floors)
 	if err != nil {
 		return ch, err
 	}
 
-	return s.compact(traceutil.TODO(), rev, prevCompactRev, prevCompactionCompleted)
+	s.revMu.RLock()
+	floors = s.floors()
+	s.revMu.RUnlock()
+	return s.compact(traceutil.TODO(), floors, prevCompactRev, prevCompactionCompleted)
 }
 
 func (s *store) Compact(trace *traceutil.Trace, rev int64) (<-chan struct{}, error) {
+	return s.compactFloors(trace, uniformFloors(rev))
+}
+
+func (s *store) compactFloors(trace *traceutil.Trace, req compactFloors) (<-chan struct{}, error) {
 	s.mu.Lock()
 	prevCompactionCompleted := s.checkPrevCompactionCompleted()
-	ch, prevCompactRev, err := s.updateCompactRev(rev)
+	ch, prevCompactRev, err := s.updateCompactRev(req)
 	trace.Step("check and update compact revision")
 	if err != nil {
 		s.mu.Unlock()
 		return ch, err
 	}
+	s.revMu.RLock()
+	floors := s.floors()
+	s.revMu.RUnlock()
 	s.mu.Unlock()
 
-	return s.compact(trace, rev, prevCompactRev, prevCompactionCompleted)
+	return s.compact(trace, floors, prevCompactRev, prevCompactionCompleted)
+}
+
+func (s *store) CompactionStatus() CompactionStatus {
+	s.compactStatusMu.Lock()
+	defer s.compactStatusMu.Unlock()
+	return s.compactStatus
+}
+
+// setCompactionStatus records the status of the latest requested compaction,
+// superseding the status of any compaction requested before.
+func (s *store) setCompactionStatus(status CompactionStatus) {
+	s.compactStatusMu.Lock()
+	defer s.compactStatusMu.Unlock()
+	s.compactStatus = status
+}
+
+// updateCompactionStatus records the progress of the compaction up to rev,
+// unless a later compaction has been requested since.
+func (s *store) updateCompactionStatus(rev, scannedRev, remainingKeys int64) {
+	s.compactStatusMu.Lock()
+	defer s.compactStatusMu.Unlock()
+	if s.compactStatus.CompactRevision != rev {
+		return
+	}
+	s.compactStatus.ScannedRevision = scannedRev
+	s.compactStatus.RemainingKeys = remainingKeys
+}
+
+// clearCompactionStatus resets the status once the compaction up to rev is
+// done, unless a later compaction has been requested since.
+func (s *store) clearCompactionStatus(rev int64) {
+	s.compactStatusMu.Lock()
+	defer s.compactStatusMu.Unlock()
+	if s.compactStatus.CompactRevision == rev {
+		s.compactStatus = CompactionStatus{}
+	}
+}
+
+func (s *store) HotKeys(limit int, reset bool) (reads, writes []HotKey, err error) {
+	if s.hotKeys == nil {
+		return nil, nil, ErrHotKeyTrackingDisabled
+	}
+	reads, writes = s.hotKeys.top(limit, reset)
+	return reads, writes, nil
 }
 
 func (s *store) Commit() {
@@ -302,12 +514,15 @@ func (s *store) Restore(b backend.Backend) error {
 
 	s.b = b
 	s.kvindex = newTreeIndex(s.lg)
+	s.readCache.reset()
 
 	{
 		// During restore the metrics might report 'special' values
 		s.revMu.Lock()
+		s.keyCompactions = newKeyCompactions()
 		s.currentRev = 1
 		s.compactMainRev = -1
+		s.retention = compactFloors{}
 		s.revMu.Unlock()
 	}
 
@@ -343,6 +558,14 @@ func (s *store) restore() error {
 		s.revMu.Unlock()
 	}
 	scheduledCompact, _ := UnsafeReadScheduledCompact(tx)
+	retention, err := unsafeReadCompactFloors(tx)
+	if err != nil {
+		tx.RUnlock()
+		return err
+	}
+	if scheduledCompact <= s.compactMainRev {
+		scheduledCompact = 0
+	}
 	// index keys concurrently as they're loaded in from tx
 	keysGauge.Set(0)
 	rkvc, revc := restoreIntoIndex(s.lg, s.kvindex)
@@ -353,7 +576,7 @@ func (s *store) restore() error {
 		}
 		// rkvc blocks if the total pending keys exceeds the restore
 		// chunk size to keep keys from consuming too much memory.
-		restoreChunk(s.lg, rkvc, keys, vals, keyToLease)
+		restoreChunk(s.lg, rkvc, keys, vals, keyToLease, &s.keyCompactions)
 		if len(keys) < restoreChunkKeys {
 			// partial set implies final set
 			break
@@ -375,11 +598,20 @@ func (s *store) restore() error {
 		if s.currentRev < s.compactMainRev {
 			s.currentRev = s.compactMainRev
 		}
+		// the retention of a scheduled compaction is set when it is resumed.
+		// A finished compaction may have been interrupted before removing
+		// the revisions below the floors of the retained prefixes; they
+		// cannot be read, and the next compaction removes them.
+		if scheduledCompact == 0 && len(retention.revs) != 0 {
+			s.retention = retention
+		}
+		s.keyCompactions.prune(s.floors())
 		s.revMu.Unlock()
 	}
 
-	if scheduledCompact <= s.compactMainRev {
-		scheduledCompact = 0
+	if err := s.unsafeRestorePrefixQuotas(tx, s.currentRev); err != nil {
+		tx.RUnlock()
+		return err
 	}
 
 	for key, lid := range keyToLease {
@@ -402,7 +634,11 @@ func (s *store) restore() error {
 	s.lg.Info("kvstore restored", zap.Int64("current-rev", s.currentRev))
 
 	if scheduledCompact != 0 {
-		if _, err := s.compactLockfree(scheduledCompact); err != nil {
+		floors := uniformFloors(scheduledCompact)
+		if len(retention.revs) != 0 {
+			floors = retention
+		}
+		if _, err := s.compactLockfree(floors); err != nil {
 			s.lg.Warn("compaction encountered error", zap.Error(err))
 		}
 
@@ -450,6 +686,9 @@ func restoreIntoIndex(lg *zap.Logger, idx index) (chan<- revKeyValue, <-chan int
 			}
 			rev := bytesToRev(rkv.key)
 			currentRev = rev.main
+			if isKeyCompactionMarker(rkv.key) {
+				continue
+			}
 			if ok {
 				if isTombstone(rkv.key) {
 					if err := ki.tombstone(lg, rev.main, rev.sub); err != nil {
@@ -458,8 +697,12 @@ func restoreIntoIndex(lg *zap.Logger, idx index) (chan<- revKeyValue, <-chan int
 					continue
 				}
 				ki.put(lg, rev.main, rev.sub)
+				ki.lease = rkv.kv.Lease
+				// the versions of a key skip the revisions removed by CompactKey
+				ki.generations[len(ki.generations)-1].ver = rkv.kv.Version
 			} else if !isTombstone(rkv.key) {
 				ki.restore(lg, revision{rkv.kv.CreateRevision, 0}, rev, rkv.kv.Version)
+				ki.lease = rkv.kv.Lease
 				idx.Insert(ki)
 				kiCache[rkv.kstr] = ki
 			}
@@ -468,14 +711,16 @@ func restoreIntoIndex(lg *zap.Logger, idx index) (chan<- revKeyValue, <-chan int
 	return rkvc, revc
 }
 
-func restoreChunk(lg *zap.Logger, kvc chan<- revKeyValue, keys, vals [][]byte, keyToLease map[string]lease.LeaseID) {
+func restoreChunk(lg *zap.Logger, kvc chan<- revKeyValue, keys, vals [][]byte, keyToLease map[string]lease.LeaseID, kc *keyCompactions) {
 	for i, key := range keys {
 		rkv := revKeyValue{key: key}
 		if err := rkv.kv.Unmarshal(vals[i]); err != nil {
 			lg.Fatal("failed to unmarshal mvccpb.KeyValue", zap.Error(err))
 		}
 		rkv.kstr = string(rkv.kv.Key)
-		if isTombstone(key) {
+		if isKeyCompactionMarker(key) {
+			kc.set(rkv.kstr, rkv.kv.CreateRevision, rkv.kv.ModRevision, bytesToRev(key).main)
+		} else if isTombstone(key) {
 			delete(keyToLease, rkv.kstr)
 		} else if lid := lease.LeaseID(rkv.kv.Lease); lid != lease.NoLease {
 			keyToLease[rkv.kstr] = lid
@@ -536,6 +781,23 @@ func isTombstone(b []byte) bool {
 	return len(b) == markedRevBytesLen && b[markBytePosition] == markTombstone
 }
 
+// appendMarkKeyCompaction appends key compaction mark to normal revision bytes.
+func appendMarkKeyCompaction(lg *zap.Logger, b []byte) []byte {
+	if len(b) != revBytesLen {
+		lg.Panic(
+			"cannot append key compaction mark to non-normal revision bytes",
+			zap.Int("expected-revision-bytes-size", revBytesLen),
+			zap.Int("given-revision-bytes-size", len(b)),
+		)
+	}
+	return append(b, markKeyCompaction)
+}
+
+// isKeyCompactionMarker checks whether the revision bytes is a key compaction marker.
+func isKeyCompactionMarker(b []byte) bool {
+	return len(b) == markedRevBytesLen && b[markBytePosition] == markKeyCompaction
+}
+
 func (s *store) HashStorage() HashStorage {
 	return s.hashes
 }
@@ -74,6 +74,91 @@ func benchmarkStoreRange(b *testing.B, n int) {
 	}
 }
 
+func BenchmarkStoreRangeHotKey(b *testing.B)       { benchmarkStoreRangeHotKey(b, 0) }
+func BenchmarkStoreRangeHotKeyCached(b *testing.B) { benchmarkStoreRangeHotKey(b, 1<<20) }
+
+// benchmarkStoreRangeHotKey gets a few keys with 1KiB values in parallel,
+// which the read cache serves without reading the backend.
+func benchmarkStoreRangeHotKey(b *testing.B, readCacheBytes int64) {
+	be, _ := betesting.NewDefaultTmpBackend(b)
+	s := NewStore(zaptest.NewLogger(b), be, &lease.FakeLessor{}, StoreConfig{ReadCacheBytes: readCacheBytes})
+	defer cleanup(s, be)
+
+	keys, val := createBytesSlice(64, 8), createBytesSlice(1024, 1)
+	for i := range keys {
+		s.Put(keys[i], val[0], lease.NoLease)
+	}
+	// Force into boltdb tx instead of backend read tx.
+	s.Commit()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Range(context.TODO(), keys[i%len(keys)], nil, RangeOptions{})
+		}
+	})
+}
+
+func BenchmarkStoreRangeReverseLimit(b *testing.B) { benchmarkStoreRangeLastPage(b, true) }
+func BenchmarkStoreRangeAll(b *testing.B)          { benchmarkStoreRangeLastPage(b, false) }
+
+// benchmarkStoreRangeLastPage reads the last 100 of 10k keys, either walking
+// the index backward or reading the entire range, as sorting by key in
+// descending order does.
+func benchmarkStoreRangeLastPage(b *testing.B, reverse bool) {
+	be, _ := betesting.NewDefaultTmpBackend(b)
+	s := NewStore(zaptest.NewLogger(b), be, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, be)
+
+	keys, val := createBytesSlice(64, 10000), createBytesSlice(64, 1)
+	for i := range keys {
+		s.Put(keys[i], val[0], lease.NoLease)
+	}
+	// Force into boltdb tx instead of backend read tx.
+	s.Commit()
+
+	ro := RangeOptions{}
+	if reverse {
+		ro = RangeOptions{Limit: 100, Reverse: true}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Range(context.TODO(), []byte{}, []byte{}, ro)
+	}
+}
+
+func BenchmarkStoreRangeKeysOnly(b *testing.B) { benchmarkStoreRangeLargeValues(b, true) }
+func BenchmarkStoreRangeFull(b *testing.B)     { benchmarkStoreRangeLargeValues(b, false) }
+
+// benchmarkStoreRangeLargeValues lists 100k keys with 1KiB values, which keys
+// only ranges do without reading the values from the backend.
+func benchmarkStoreRangeLargeValues(b *testing.B, keysOnly bool) {
+	be, _ := betesting.NewDefaultTmpBackend(b)
+	s := NewStore(zaptest.NewLogger(b), be, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, be)
+
+	keys, val := createBytesSlice(64, 100000), createBytesSlice(1024, 1)
+	for i := range keys {
+		s.Put(keys[i], val[0], lease.NoLease)
+	}
+	// Force into boltdb tx instead of backend read tx.
+	s.Commit()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := s.Range(context.TODO(), []byte{}, []byte{}, RangeOptions{KeysOnly: keysOnly})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(r.KVs) != len(keys) {
+			b.Fatalf("got %d keys, want %d", len(r.KVs), len(keys))
+		}
+	}
+}
+
 func BenchmarkConsistentIndex(b *testing.B) {
 	be, _ := betesting.NewDefaultTmpBackend(b)
 	ci := cindex.NewConsistentIndex(be)
@@ -21,30 +21,48 @@ import (
 
 	"go.uber.org/zap"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/server/v3/storage/schema"
 )
 
-func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyValueHash, error) {
+func (s *store) scheduleCompaction(floors compactFloors, prevCompactRev int64) (KeyValueHash, error) {
+	// the revisions are scanned up to the highest floor, while the store is
+	// compacted at the lowest one
+	compactMainRev, scanRev := floors.min(), floors.max()
 	totalStart := time.Now()
-	keep := s.kvindex.Compact(compactMainRev)
+	var keep map[revision]struct{}
+	s.indexMu.Lock()
+	if floors.uniform() {
+		keep = s.kvindex.Compact(compactMainRev)
+	} else {
+		keep = s.kvindex.CompactFloors(floors.at)
+	}
+	s.startCompaction(&compactedRevisions{floors: floors, keep: keep})
+	s.indexMu.Unlock()
 	indexCompactionPauseMs.Observe(float64(time.Since(totalStart) / time.Millisecond))
+	defer s.finishCompaction()
+	// the compacted revisions are no longer resolved by the index
+	s.readCache.reset()
 
 	totalStart = time.Now()
 	defer func() { dbCompactionTotalMs.Observe(float64(time.Since(totalStart) / time.Millisecond)) }()
-	keyCompactions := 0
-	defer func() { dbCompactionKeysCounter.Add(float64(keyCompactions)) }()
 	defer func() { dbCompactionLast.Set(float64(time.Now().Unix())) }()
 
 	end := make([]byte, 8)
-	binary.BigEndian.PutUint64(end, uint64(compactMainRev+1))
+	binary.BigEndian.PutUint64(end, uint64(scanRev+1))
 
 	batchNum := s.cfg.CompactionBatchLimit
 	batchTicker := time.NewTicker(s.cfg.CompactionSleepInterval)
 	defer batchTicker.Stop()
 	h := newKVHasher(prevCompactRev, compactMainRev, keep)
 	last := make([]byte, 8+1+8)
+	var firstRev, scannedKeys, removed, retained int64
 	for {
 		var rev revision
+		// removals are counted as they are applied, so that the revisions
+		// removed before an interruption are not counted again by the
+		// resumed or a later overlapping compaction
+		batchRemoved := 0
 
 		start := time.Now()
 
@@ -53,9 +71,13 @@ func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyVal
 		keys, values := tx.UnsafeRange(schema.Key, last, end, int64(batchNum))
 		for i := range keys {
 			rev = bytesToRev(keys[i])
-			if _, ok := keep[rev]; !ok {
+			if _, ok := keep[rev]; !ok && !aboveFloor(floors, rev, values[i]) {
 				tx.UnsafeDelete(schema.Key, keys[i])
-				keyCompactions++
+				batchRemoved++
+			} else if rev.main > prevCompactRev && !aboveFloor(floors, rev, values[i]) {
+				// the revisions kept by the previous compaction were
+				// already reported as retained by it
+				retained++
 			}
 			h.WriteKeyValue(keys[i], values[i])
 		}
@@ -64,6 +86,8 @@ func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyVal
 			// gofail: var compactBeforeSetFinishedCompact struct{}
 			UnsafeSetFinishedCompact(tx, compactMainRev)
 			tx.Unlock()
+			removed += int64(batchRemoved)
+			dbCompactionKeysCounter.Add(float64(batchRemoved))
 			// gofail: var compactAfterSetFinishedCompact struct{}
 			hash := h.Hash()
 			s.lg.Info(
@@ -71,11 +95,23 @@ func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyVal
 				zap.Int64("compact-revision", compactMainRev),
 				zap.Duration("took", time.Since(totalStart)),
 				zap.Uint32("hash", hash.Hash),
+				zap.Int64("removed-revisions", removed),
+				zap.Int64("retained-revisions", retained),
 			)
+			if s.cfg.CompactionNotify != nil {
+				s.cfg.CompactionNotify(CompactionResult{Revision: compactMainRev, RemovedRevisions: removed, RetainedRevisions: retained})
+			}
 			return hash, nil
 		}
 
 		tx.Unlock()
+		removed += int64(batchRemoved)
+		dbCompactionKeysCounter.Add(float64(batchRemoved))
+		if firstRev == 0 {
+			firstRev = bytesToRev(keys[0]).main
+		}
+		scannedKeys += int64(len(keys))
+		s.updateCompactionStatus(scanRev, rev.main, estimateRemainingKeys(firstRev, rev.main, scanRev, scannedKeys))
 		// update last
 		revToBytes(revision{main: rev.main, sub: rev.sub + 1}, last)
 		// Immediately commit the compaction deletes instead of letting them accumulate in the write buffer
@@ -84,6 +120,16 @@ func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyVal
 		// gofail: var compactAfterCommitBatch struct{}
 		dbCompactionPauseMs.Observe(float64(time.Since(start) / time.Millisecond))
 
+		// the running compaction is pending in the scheduler; another pending
+		// job means the compactions fall behind, so that pausing would let the
+		// keyspace grow faster than it is reclaimed
+		if s.cfg.CompactionSleep > 0 && s.fifoSched.Pending() <= 1 {
+			select {
+			case <-time.After(s.cfg.CompactionSleep):
+			case <-s.stopc:
+				return KeyValueHash{}, fmt.Errorf("interrupted due to stop signal")
+			}
+		}
 		select {
 		case <-batchTicker.C:
 		case <-s.stopc:
@@ -91,3 +137,24 @@ func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyVal
 		}
 	}
 }
+
+// aboveFloor returns whether rev, whose key-value pair is v, is above the
+// floor of its key, so that a compaction at floors keeps it. The key is only
+// decoded for the revisions above the lowest floor. A key-value pair that
+// cannot be decoded is kept.
+func aboveFloor(floors compactFloors, rev revision, v []byte) bool {
+	if floors.uniform() || rev.main <= floors.min() {
+		return false
+	}
+	var kv mvccpb.KeyValue
+	if err := kv.Unmarshal(v); err != nil {
+		return true
+	}
+	return rev.main > floors.at(kv.Key)
+}
+
+// estimateRemainingKeys extrapolates the number of keys left to scan up to
+// compactRev from the density of keys scanned between firstRev and scannedRev.
+func estimateRemainingKeys(firstRev, scannedRev, compactRev, scannedKeys int64) int64 {
+	return (compactRev - scannedRev) * scannedKeys / (scannedRev - firstRev + 1)
+}
@@ -16,14 +16,20 @@ package mvcc
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"go.uber.org/zap/zaptest"
 
+	"go.etcd.io/etcd/pkg/v3/schedule"
 	"go.etcd.io/etcd/pkg/v3/traceutil"
 	"go.etcd.io/etcd/server/v3/lease"
+	"go.etcd.io/etcd/server/v3/storage/backend"
 	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
 	"go.etcd.io/etcd/server/v3/storage/schema"
 )
@@ -83,7 +89,7 @@ func TestScheduleCompaction(t *testing.T) {
 		}
 		tx.Unlock()
 
-		_, err := s.scheduleCompaction(tt.rev, 0)
+		_, err := s.scheduleCompaction(uniformFloors(tt.rev), 0)
 		if err != nil {
 			t.Error(err)
 		}
@@ -147,3 +153,292 @@ func TestCompactAllAndRestore(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCompactionStatus(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{CompactionBatchLimit: 10})
+	defer cleanup(s, b)
+
+	if status := s.CompactionStatus(); status != (CompactionStatus{}) {
+		t.Fatalf("status = %+v before compaction, want zero", status)
+	}
+
+	for i := 0; i < 1000; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%d", i%100)), []byte("bar"), lease.NoLease)
+	}
+
+	// the second compaction supersedes the first one
+	done1, err := s.Compact(traceutil.TODO(), 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, err := s.Compact(traceutil.TODO(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lastScanned int64
+	var sawProgress bool
+	timeout := time.After(10 * time.Second)
+	for {
+		status := s.CompactionStatus()
+		if !status.InProgress {
+			break
+		}
+		if status.CompactRevision != 1000 {
+			t.Fatalf("status = %+v, want compact revision %d", status, 1000)
+		}
+		if status.ScannedRevision < lastScanned || status.ScannedRevision > 1000 {
+			t.Fatalf("status = %+v, want scanned revision in [%d, %d]", status, lastScanned, 1000)
+		}
+		if status.ScannedRevision > 0 && status.ScannedRevision < 1000 && status.RemainingKeys > 0 {
+			sawProgress = true
+		}
+		lastScanned = status.ScannedRevision
+
+		select {
+		case <-timeout:
+			t.Fatal("timeout waiting for compaction to finish")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if !sawProgress {
+		t.Error("expected to observe compaction progress")
+	}
+
+	<-done1
+	<-done2
+	if status := s.CompactionStatus(); status != (CompactionStatus{}) {
+		t.Errorf("status = %+v after compaction, want zero", status)
+	}
+}
+
+func TestCompactionNotify(t *testing.T) {
+	var results []CompactionResult
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{
+		CompactionBatchLimit: 7,
+		CompactionNotify:     func(r CompactionResult) { results = append(results, r) },
+	})
+	defer cleanup(s, b)
+
+	// 10 keys with 5 revisions each at revisions 2 to 51, and only foo0 at
+	// revisions 52 to 101
+	for i := 0; i < 100; i++ {
+		key := "foo0"
+		if i < 50 {
+			key = fmt.Sprintf("foo%d", i%10)
+		}
+		s.Put([]byte(key), []byte("bar"), lease.NoLease)
+	}
+	removedBefore := readCounterInt(dbCompactionKeysCounter)
+
+	// the second compaction scans the revisions retained by the first one
+	// again, and only reports the revision of foo0 it retains
+	if _, err := s.Compact(traceutil.TODO(), 51); err != nil {
+		t.Fatal(err)
+	}
+	done, err := s.Compact(traceutil.TODO(), 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for compaction to finish")
+	}
+
+	wresults := []CompactionResult{
+		{Revision: 51, RemovedRevisions: 40, RetainedRevisions: 10},
+		{Revision: 101, RemovedRevisions: 50, RetainedRevisions: 1},
+	}
+	if !reflect.DeepEqual(results, wresults) {
+		t.Errorf("results = %+v, want %+v", results, wresults)
+	}
+	if removed := readCounterInt(dbCompactionKeysCounter) - removedBefore; removed != 90 {
+		t.Errorf("compacted keys counter increased by %d, want %d", removed, 90)
+	}
+}
+
+// TestCompactionNotifyResumed ensures a compaction resumed on restart reports
+// the revisions it retains above the last finished compaction only.
+func TestCompactionNotifyResumed(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s0 := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{CompactionBatchLimit: 7})
+
+	// 10 keys with 5 revisions each at revisions 2 to 51, and only foo0 at
+	// revisions 52 to 101
+	for i := 0; i < 100; i++ {
+		key := "foo0"
+		if i < 50 {
+			key = fmt.Sprintf("foo%d", i%10)
+		}
+		s0.Put([]byte(key), []byte("bar"), lease.NoLease)
+	}
+	done, err := s0.Compact(traceutil.TODO(), 51)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	// write the scheduled compaction, but not do it
+	tx := s0.b.BatchTx()
+	tx.Lock()
+	UnsafeSetScheduledCompact(tx, 101)
+	tx.Unlock()
+	s0.b.ForceCommit()
+	s0.Close()
+
+	resultc := make(chan CompactionResult, 1)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{
+		CompactionBatchLimit: 7,
+		CompactionNotify:     func(r CompactionResult) { resultc <- r },
+	})
+	defer cleanup(s, b)
+
+	select {
+	case r := <-resultc:
+		if w := (CompactionResult{Revision: 101, RemovedRevisions: 50, RetainedRevisions: 1}); r != w {
+			t.Errorf("result = %+v, want %+v", r, w)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the resumed compaction to finish")
+	}
+}
+
+// TestCompactionSleep checks that a compaction pausing after each batch holds
+// the backend, which the reads then wait for, a much smaller share of its
+// duration than a compaction without pauses, and that a compaction does not
+// pause while another compaction waits behind it.
+func TestCompactionSleep(t *testing.T) {
+	noSleep := compactionHoldShare(t, 0)
+	withSleep := compactionHoldShare(t, 20*time.Millisecond)
+	if withSleep > noSleep/4 {
+		t.Errorf("compaction with sleep holds the backend %.2f of its duration, want at most a quarter of %.2f without sleep", withSleep, noSleep)
+	}
+
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{
+		CompactionBatchLimit:    100,
+		CompactionSleepInterval: time.Microsecond,
+		CompactionSleep:         time.Hour,
+	})
+	defer cleanup(s, b)
+	for i := 0; i < 1000; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%d", i%10)), []byte("bar"), lease.NoLease)
+	}
+
+	// the compaction up to 501 does not pause while the one up to 1001 is
+	// pending, which then pauses as it runs alone; both are queued before
+	// the first one runs
+	unblock := make(chan struct{})
+	s.fifoSched.Schedule(schedule.NewJob("block", func(ctx context.Context) { <-unblock }))
+	done, err := s.Compact(traceutil.TODO(), 501)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Compact(traceutil.TODO(), 1001); err != nil {
+		t.Fatal(err)
+	}
+	close(unblock)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for compaction to finish")
+	}
+}
+
+// compactionHoldShare returns the share of the duration of a compaction
+// pausing for sleep after each batch during which it holds the batch tx lock
+// or commits, so that the reads wait for it.
+func compactionHoldShare(t *testing.T, sleep time.Duration) float64 {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	hb := &holdRecordingBackend{Backend: b}
+	s := NewStore(zaptest.NewLogger(t), hb, &lease.FakeLessor{}, StoreConfig{
+		CompactionBatchLimit:    100,
+		CompactionSleepInterval: time.Microsecond,
+		CompactionSleep:         sleep,
+	})
+	defer cleanup(s, b)
+
+	// 10 keys at revisions 2 to 5001
+	for i := 0; i < 5000; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%d", i%10)), []byte("bar"), lease.NoLease)
+	}
+	hb.reset()
+	start := time.Now()
+	done, err := s.Compact(traceutil.TODO(), 5001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for compaction to finish")
+	}
+	return float64(hb.held()) / float64(time.Since(start))
+}
+
+// holdRecordingBackend records the time the batch tx lock is held and the
+// time spent in forced commits.
+type holdRecordingBackend struct {
+	backend.Backend
+	mu   sync.Mutex
+	hold time.Duration
+}
+
+func (b *holdRecordingBackend) BatchTx() backend.BatchTx {
+	return &holdRecordingBatchTx{BatchTx: b.Backend.BatchTx(), b: b}
+}
+
+func (b *holdRecordingBackend) ForceCommit() {
+	start := time.Now()
+	b.Backend.ForceCommit()
+	b.add(time.Since(start))
+}
+
+func (b *holdRecordingBackend) add(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hold += d
+}
+
+func (b *holdRecordingBackend) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hold = 0
+}
+
+func (b *holdRecordingBackend) held() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.hold
+}
+
+type holdRecordingBatchTx struct {
+	backend.BatchTx
+	b        *holdRecordingBackend
+	lockedAt time.Time
+}
+
+func (tx *holdRecordingBatchTx) LockOutsideApply() {
+	tx.BatchTx.LockOutsideApply()
+	tx.lockedAt = time.Now()
+}
+
+func (tx *holdRecordingBatchTx) Unlock() {
+	if !tx.lockedAt.IsZero() {
+		tx.b.add(time.Since(tx.lockedAt))
+		tx.lockedAt = time.Time{}
+	}
+	tx.BatchTx.Unlock()
+}
+
+func readCounterInt(c prometheus.Counter) int {
+	ch := make(chan prometheus.Metric, 1)
+	c.Collect(ch)
+	m := <-ch
+	mm := &dto.Metric{}
+	m.Write(mm)
+	return int(mm.GetCounter().GetValue())
+}
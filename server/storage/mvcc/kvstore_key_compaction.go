@@ -0,0 +1,245 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"sort"
+
+	"github.com/google/btree"
+	"go.uber.org/zap"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+)
+
+// keyCompactions tracks the keys whose history was compacted by CompactKey.
+type keyCompactions struct {
+	// tree holds the compacted keys in key order.
+	tree *btree.BTreeG[keyCompaction]
+	// maxRev is the highest compaction revision of the keys. The reads and
+	// the watches from this revision on see no compacted key.
+	maxRev int64
+	// lastRev is the revision of the last key compaction, or 0 once a
+	// compaction passed it.
+	lastRev int64
+}
+
+// keyCompaction is the compaction of the history of a key. The revisions of
+// the key in the range [fromRev, compactRev) are removed.
+type keyCompaction struct {
+	key        string
+	fromRev    int64
+	compactRev int64
+	// markers are the revisions of the markers of the key compactions that
+	// are still in the backend, in ascending order.
+	markers []int64
+}
+
+// newKeyCompactions returns an empty keyCompactions. Its tree is created by
+// the first key compaction.
+func newKeyCompactions() keyCompactions {
+	return keyCompactions{}
+}
+
+// get returns the compaction revision of key, or 0 if it was never compacted.
+func (kc *keyCompactions) get(key []byte) int64 {
+	if kc.tree == nil {
+		return 0
+	}
+	c, _ := kc.tree.Get(keyCompaction{key: string(key)})
+	return c.compactRev
+}
+
+// set records the compaction of key at compactRev by the key compaction at
+// rev, which removed the revisions from fromRev on.
+func (kc *keyCompactions) set(key string, fromRev, compactRev, rev int64) {
+	if kc.tree == nil {
+		kc.tree = btree.NewG(32, func(a, b keyCompaction) bool { return a.key < b.key })
+	}
+	c, ok := kc.tree.Get(keyCompaction{key: key})
+	if ok && c.fromRev < fromRev {
+		fromRev = c.fromRev
+	}
+	markers := append(c.markers[:len(c.markers):len(c.markers)], rev)
+	kc.tree.ReplaceOrInsert(keyCompaction{key: key, fromRev: fromRev, compactRev: compactRev, markers: markers})
+	if compactRev > kc.maxRev {
+		kc.maxRev = compactRev
+	}
+	kc.lastRev = rev
+}
+
+// prune forgets the key compactions superseded by a compaction at floors,
+// and the markers it removes from the backend.
+func (kc *keyCompactions) prune(floors compactFloors) {
+	// hashing below the floors fails anyway
+	if kc.lastRev <= floors.min() {
+		kc.lastRev = 0
+	}
+	if kc.tree == nil {
+		return
+	}
+	var changed []keyCompaction
+	kc.maxRev = 0
+	scanRev := floors.max()
+	kc.tree.Ascend(func(c keyCompaction) bool {
+		i := sort.Search(len(c.markers), func(i int) bool { return c.markers[i] > scanRev })
+		c.markers = c.markers[i:]
+		if c.compactRev != 0 && c.compactRev <= floors.at([]byte(c.key)) {
+			// the markers left are only kept for hashing
+			c.fromRev, c.compactRev = 0, 0
+			changed = append(changed, c)
+		} else if i != 0 {
+			changed = append(changed, c)
+		}
+		if c.compactRev > kc.maxRev {
+			kc.maxRev = c.compactRev
+		}
+		return true
+	})
+	for _, c := range changed {
+		if c.compactRev == 0 && len(c.markers) == 0 {
+			kc.tree.Delete(c)
+			continue
+		}
+		kc.tree.ReplaceOrInsert(c)
+	}
+}
+
+// ascendRange calls f on the key compactions of the keys in the range
+// [key, end), interpreted as in Range, until f returns false.
+func (kc *keyCompactions) ascendRange(key, end []byte, f func(c keyCompaction) bool) {
+	if kc.tree == nil {
+		return
+	}
+	switch {
+	case end == nil:
+		if c, ok := kc.tree.Get(keyCompaction{key: string(key)}); ok {
+			f(c)
+		}
+	case len(end) == 0:
+		kc.tree.AscendGreaterOrEqual(keyCompaction{key: string(key)}, f)
+	default:
+		kc.tree.AscendRange(keyCompaction{key: string(key)}, keyCompaction{key: string(end)}, f)
+	}
+}
+
+// compacted returns whether a key in the range [key, end) has a revision
+// removed at rev, so that the range cannot be read at rev.
+func (kc *keyCompactions) compacted(key, end []byte, rev int64) bool {
+	if rev >= kc.maxRev {
+		return false
+	}
+	compacted := false
+	kc.ascendRange(key, end, func(c keyCompaction) bool {
+		compacted = c.fromRev <= rev && rev < c.compactRev
+		return !compacted
+	})
+	return compacted
+}
+
+// compactRev returns the highest compaction revision above rev of the keys
+// in the range [key, end), or 0 if there is none. The events of the range
+// from rev on are incomplete below it.
+func (kc *keyCompactions) compactRev(key, end []byte, rev int64) int64 {
+	if rev >= kc.maxRev {
+		return 0
+	}
+	var compactRev int64
+	kc.ascendRange(key, end, func(c keyCompaction) bool {
+		if c.compactRev > rev && c.compactRev > compactRev {
+			compactRev = c.compactRev
+		}
+		return compactRev < kc.maxRev
+	})
+	return compactRev
+}
+
+// CompactKey removes the revisions of key older than its keep most recent
+// ones. Reading the key at a removed revision fails with ErrCompacted.
+//
+// The revisions are removed at once, and a marker is written at a new
+// revision of the store so that members hashing the same revision agree.
+// The marker generates no event. Revisions at or below the compaction
+// revision are left to the global compaction. It returns the current
+// revision and the oldest revision key can be read at, or 0 if key was
+// never compacted.
+func (s *store) CompactKey(trace *traceutil.Trace, key []byte, keep int64) (rev, compactRev int64, err error) {
+	if keep < 1 {
+		return 0, 0, ErrInvalidKeep
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revMu.RLock()
+	compactMainRev, rev := s.floors().at(key), s.currentRev
+	s.revMu.RUnlock()
+
+	compactRev = s.keyCompactions.get(key)
+	nth, ok := s.kvindex.NthRevision(key, int(keep))
+	if !ok || nth.main <= compactMainRev || nth.main <= compactRev {
+		return rev, compactRev, nil
+	}
+	compactRev = nth.main
+	rev++
+
+	removed, tombstones := s.kvindex.CompactKey(key, compactMainRev, compactRev)
+	trace.Step("compact key in in-memory index tree")
+	// the key is read at the removed revisions from the oldest one on
+	fromRev := compactRev
+	for _, r := range removed {
+		if r.main < fromRev {
+			fromRev = r.main
+		}
+	}
+
+	tx := s.b.BatchTx()
+	tx.LockInsideApply()
+	for _, r := range removed {
+		rbytes := newRevBytes()
+		revToBytes(r, rbytes)
+		tx.UnsafeDelete(schema.Key, rbytes)
+	}
+	for _, r := range tombstones {
+		rbytes := newRevBytes()
+		revToBytes(r, rbytes)
+		tx.UnsafeDelete(schema.Key, appendMarkTombstone(s.lg, rbytes))
+	}
+
+	ibytes := newRevBytes()
+	revToBytes(revision{main: rev}, ibytes)
+	ibytes = appendMarkKeyCompaction(s.lg, ibytes)
+	kv := mvccpb.KeyValue{Key: key, CreateRevision: fromRev, ModRevision: compactRev}
+	d, err := kv.Marshal()
+	if err != nil {
+		s.lg.Fatal(
+			"failed to marshal mvccpb.KeyValue",
+			zap.Error(err),
+		)
+	}
+	tx.UnsafeSeqPut(schema.Key, ibytes, d)
+
+	// hold revMu lock to prevent new read txns from opening until writeback.
+	s.revMu.Lock()
+	s.currentRev = rev
+	s.keyCompactions.set(string(key), fromRev, compactRev, rev)
+	tx.Unlock()
+	s.revMu.Unlock()
+
+	dbCompactionKeysCounter.Add(float64(len(removed) + len(tombstones)))
+	trace.Step("compact key in backend")
+	return rev, compactRev, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestStoreCompactKey(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	s.Put([]byte("bar"), []byte("bar"), lease.NoLease)
+	for i := 0; i < 10; i++ {
+		s.Put([]byte("foo"), []byte(fmt.Sprintf("v%d", i)), lease.NoLease)
+	}
+	// foo is written at revisions 3 to 12
+
+	w := s.NewWatchStream()
+	defer w.Close()
+	w.Watch(0, []byte("foo"), nil, 0)
+
+	rev, compactRev, err := s.CompactKey(traceutil.TODO(), []byte("foo"), 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), rev)
+	assert.Equal(t, int64(10), compactRev)
+	assert.Equal(t, int64(0), s.CompactRev())
+	assert.Equal(t, int64(10), s.ReadFloorRev())
+
+	for r := int64(3); r < 10; r++ {
+		_, err := s.Range(context.TODO(), []byte("foo"), nil, RangeOptions{Rev: r})
+		assert.ErrorIs(t, err, ErrCompacted, "range at revision %d", r)
+	}
+	for r := int64(10); r <= 12; r++ {
+		res, err := s.Range(context.TODO(), []byte("foo"), nil, RangeOptions{Rev: r})
+		require.NoError(t, err, "range at revision %d", r)
+		require.Len(t, res.KVs, 1)
+		assert.Equal(t, r, res.KVs[0].ModRevision)
+	}
+	res, err := s.Range(context.TODO(), []byte("bar"), nil, RangeOptions{Rev: 2})
+	require.NoError(t, err)
+	assert.Len(t, res.KVs, 1)
+	_, err = s.Range(context.TODO(), []byte("a"), []byte{}, RangeOptions{Rev: 5})
+	assert.ErrorIs(t, err, ErrCompacted)
+	// foo has no revision removed at revision 2
+	res, err = s.Range(context.TODO(), []byte("a"), []byte{}, RangeOptions{Rev: 2})
+	require.NoError(t, err)
+	require.Len(t, res.KVs, 1)
+	assert.Equal(t, []byte("bar"), res.KVs[0].Key)
+
+	// a watcher resuming below the compaction revision of the key is
+	// cancelled with it, like after a compaction
+	for _, startRev := range []int64{2, 5} {
+		id, err := w.Watch(0, []byte("a"), []byte{}, startRev)
+		require.NoError(t, err)
+		select {
+		case resp := <-w.Chan():
+			assert.Equal(t, id, resp.WatchID)
+			assert.Equal(t, int64(10), resp.CompactRevision)
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to receive the compaction of the watcher in 5 seconds")
+		}
+	}
+
+	// keeping more revisions than are left does nothing
+	rev, compactRev, err = s.CompactKey(traceutil.TODO(), []byte("foo"), 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), rev)
+	assert.Equal(t, int64(10), compactRev)
+
+	_, _, err = s.HashStorage().HashByRev(12)
+	assert.ErrorIs(t, err, ErrCompacted)
+	_, _, err = s.HashStorage().HashByRev(13)
+	require.NoError(t, err)
+
+	// a watcher syncing from before the key compaction sees no delete
+	w.Watch(0, []byte("foo"), nil, 13)
+	s.Put([]byte("foo"), []byte("v10"), lease.NoLease)
+	for i := 0; i < 2; i++ {
+		select {
+		case resp := <-w.Chan():
+			require.Len(t, resp.Events, 1)
+			ev := resp.Events[0]
+			assert.Equal(t, mvccpb.PUT, ev.Type)
+			assert.Equal(t, int64(14), ev.Kv.ModRevision)
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to receive event in 5 seconds")
+		}
+	}
+	select {
+	case resp := <-w.Chan():
+		t.Fatalf("unexpected watch response %+v", resp)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRestoreCompactKey(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s0 := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+
+	for i := 0; i < 5; i++ {
+		s0.Put([]byte("foo"), []byte(fmt.Sprintf("v%d", i)), lease.NoLease)
+	}
+	s0.DeleteRange([]byte("foo"), nil)
+	for i := 0; i < 5; i++ {
+		s0.Put([]byte("foo"), []byte(fmt.Sprintf("w%d", i)), lease.NoLease)
+	}
+	// foo is written at revisions 2 to 6, deleted at 7, and written at 8 to 12
+	_, _, err := s0.CompactKey(traceutil.TODO(), []byte("foo"), 2)
+	require.NoError(t, err)
+	s0.Commit()
+	s0.Close()
+
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+	assert.Equal(t, int64(13), s.Rev())
+
+	_, err = s.Range(context.TODO(), []byte("foo"), nil, RangeOptions{Rev: 10})
+	assert.ErrorIs(t, err, ErrCompacted)
+	res, err := s.Range(context.TODO(), []byte("foo"), nil, RangeOptions{Rev: 11, KeysOnly: true})
+	require.NoError(t, err)
+	require.Len(t, res.KVs, 1)
+	assert.Equal(t, int64(4), res.KVs[0].Version)
+	assert.Equal(t, int64(8), res.KVs[0].CreateRevision)
+
+	// the compaction supersedes the key compaction
+	_, err = s.Compact(traceutil.TODO(), 12)
+	require.NoError(t, err)
+	res, err = s.Range(context.TODO(), []byte("foo"), nil, RangeOptions{Rev: 12})
+	require.NoError(t, err)
+	require.Len(t, res.KVs, 1)
+	assert.Equal(t, int64(5), res.KVs[0].Version)
+}
+
+func TestKeyCompactionsPrune(t *testing.T) {
+	kc := newKeyCompactions()
+	kc.set("a", 3, 10, 13)
+	kc.set("b", 5, 20, 22)
+	assert.True(t, kc.compacted([]byte("a"), []byte("c"), 15))
+	assert.Equal(t, int64(20), kc.compactRev([]byte("a"), []byte("c"), 8))
+
+	kc.prune(uniformFloors(12))
+	assert.Equal(t, int64(0), kc.get([]byte("a")))
+	assert.Equal(t, int64(20), kc.get([]byte("b")))
+	assert.Equal(t, int64(22), kc.lastRev)
+	assert.False(t, kc.compacted([]byte("a"), nil, 5))
+
+	kc.prune(uniformFloors(22))
+	assert.Equal(t, int64(0), kc.get([]byte("b")))
+	assert.Equal(t, int64(0), kc.lastRev)
+	assert.Equal(t, int64(0), kc.maxRev)
+}
+
+func TestKeyCompactionsPruneMarkers(t *testing.T) {
+	kc := newKeyCompactions()
+	kc.set("a", 3, 10, 13)
+	kc.set("a", 11, 14, 15)
+
+	markers := func() (ms []int64) {
+		kc.ascendRange([]byte("a"), nil, func(c keyCompaction) bool {
+			ms = c.markers
+			return true
+		})
+		return ms
+	}
+	assert.Equal(t, []int64{13, 15}, markers())
+
+	// the marker at 15 outlives the compaction it records
+	kc.prune(uniformFloors(14))
+	assert.Equal(t, int64(0), kc.get([]byte("a")))
+	assert.Equal(t, []int64{15}, markers())
+
+	kc.prune(uniformFloors(15))
+	assert.Equal(t, 0, kc.tree.Len())
+}
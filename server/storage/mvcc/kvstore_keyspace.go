@@ -0,0 +1,290 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+)
+
+var (
+	// keyspaceStatsTTL is how long the keyspace stats are served from the
+	// cache before the keyspace is walked again.
+	keyspaceStatsTTL = 30 * time.Second
+	// keyspaceStatsBatchLimit is the number of keys read between two
+	// releases of the locks during the walk of the keyspace.
+	keyspaceStatsBatchLimit = 1000
+	// keyspaceStatsMaxTracked is the number of prefixes counted during the
+	// walk of the keyspace above which the smallest ones are dropped.
+	keyspaceStatsMaxTracked = 10 * MaxKeyspacePrefixes
+)
+
+const (
+	// MaxKeyspacePrefixes is the maximum number of prefixes reported by
+	// KeyspaceStats.
+	MaxKeyspacePrefixes = 100
+	// MaxKeyspaceDepth is the maximum depth of the prefixes reported by
+	// KeyspaceStats.
+	MaxKeyspaceDepth = 16
+
+	defaultKeyspacePrefixes = 10
+	defaultKeyspaceDepth    = 1
+)
+
+// KeyspaceStats summarizes the keys of the store.
+type KeyspaceStats struct {
+	// Revision is the revision the keys were counted at.
+	Revision int64
+	// Keys is the number of keys.
+	Keys int64
+	// KeyBytes and ValueBytes are the total sizes of the keys and values.
+	KeyBytes   int64
+	ValueBytes int64
+	// Prefixes are the largest prefixes by the size of their keys and
+	// values, largest first.
+	Prefixes []PrefixStats
+}
+
+// PrefixStats is the number and the size of the keys with a prefix.
+type PrefixStats struct {
+	Prefix []byte
+	Keys   int64
+	// Bytes is the size of the keys and their values.
+	Bytes int64
+}
+
+// keyspaceStatsCache holds the keyspace stats of every requested depth for
+// keyspaceStatsTTL.
+type keyspaceStatsCache struct {
+	mu      sync.Mutex
+	entries map[int]*keyspaceStatsEntry
+}
+
+// keyspaceStatsEntry holds the result of a walk of the keyspace. Concurrent
+// requests share the walk by waiting for done; the other fields are only set
+// before done is closed.
+type keyspaceStatsEntry struct {
+	done     chan struct{}
+	stats    KeyspaceStats
+	err      error
+	computed time.Time
+}
+
+// stale reports whether the walk of e is over and e cannot be served, as it
+// failed or expired.
+func (e *keyspaceStatsEntry) stale() bool {
+	select {
+	case <-e.done:
+		return e.err != nil || time.Since(e.computed) >= keyspaceStatsTTL
+	default:
+		return false
+	}
+}
+
+// KeyspaceStats returns the number and the size of the keys, along with up to
+// limit largest prefixes. The prefix of a key is the key up to its depth-th
+// '/', a leading '/' aside; the keys with fewer separators count toward the
+// prefix up to their last one. Zero or negative values of limit and depth use
+// the defaults of 10 prefixes and a depth of 1, and larger values than
+// MaxKeyspacePrefixes and MaxKeyspaceDepth are capped.
+//
+// The keyspace is walked in batches, between which the locks are released, and
+// the result is cached for a while, so it may lag behind the store; Revision
+// tells which revision it reflects. Concurrent requests for the same depth
+// share a walk. Only a bounded number of prefixes is counted during a walk, the
+// smallest being dropped, so a large prefix whose keys are interleaved with
+// those of many others may be undercounted. It returns the error of ctx once
+// ctx is done.
+func (s *store) KeyspaceStats(ctx context.Context, depth, limit int) (KeyspaceStats, error) {
+	if depth <= 0 {
+		depth = defaultKeyspaceDepth
+	} else if depth > MaxKeyspaceDepth {
+		depth = MaxKeyspaceDepth
+	}
+	if limit <= 0 {
+		limit = defaultKeyspacePrefixes
+	} else if limit > MaxKeyspacePrefixes {
+		limit = MaxKeyspacePrefixes
+	}
+
+	e, err := s.keyspaceStatsEntry(ctx, depth)
+	if err != nil {
+		return KeyspaceStats{}, err
+	}
+	stats := e.stats
+	if len(stats.Prefixes) > limit {
+		stats.Prefixes = stats.Prefixes[:limit]
+	}
+	stats.Prefixes = append([]PrefixStats(nil), stats.Prefixes...)
+	return stats, nil
+}
+
+// keyspaceStatsEntry returns the cached stats of depth once they are computed,
+// walking the keyspace if no other request is. The cache mutex is not held
+// during the walk.
+func (s *store) keyspaceStatsEntry(ctx context.Context, depth int) (*keyspaceStatsEntry, error) {
+	c := &s.keyspaceStats
+	for {
+		c.mu.Lock()
+		e, ok := c.entries[depth]
+		if !ok || e.stale() {
+			e = &keyspaceStatsEntry{done: make(chan struct{})}
+			if c.entries == nil {
+				c.entries = make(map[int]*keyspaceStatsEntry)
+			}
+			c.entries[depth] = e
+			c.mu.Unlock()
+
+			e.stats, e.err = s.walkKeyspace(ctx, depth)
+			e.computed = time.Now()
+			close(e.done)
+			return e, e.err
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if e.err == nil {
+			return e, nil
+		}
+		// the walk of another request failed, e.g. as it was canceled
+	}
+}
+
+// walkKeyspace counts the keys of the store at the current revision, grouping
+// them by their prefixes at depth.
+func (s *store) walkKeyspace(ctx context.Context, depth int) (KeyspaceStats, error) {
+	s.revMu.RLock()
+	rev := s.currentRev
+	s.revMu.RUnlock()
+
+	stats := KeyspaceStats{Revision: rev}
+	prefixes := make(map[string]*PrefixStats)
+	from := []byte{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return KeyspaceStats{}, err
+		}
+		s.mu.RLock()
+		keyRevs := s.kvindex.KeysFrom(from, rev, keyspaceStatsBatchLimit)
+		tx := s.b.ReadTx()
+		tx.RLock()
+		for _, kr := range keyRevs {
+			vsize, ok := unsafeValueSize(tx, kr.modified)
+			if !ok {
+				// compacted since rev
+				continue
+			}
+			ksize := int64(len(kr.key))
+			stats.Keys++
+			stats.KeyBytes += ksize
+			stats.ValueBytes += vsize
+
+			prefix := keyspacePrefix(kr.key, depth)
+			p, ok := prefixes[string(prefix)]
+			if !ok {
+				p = &PrefixStats{Prefix: bytes.Clone(prefix)}
+				prefixes[string(prefix)] = p
+			}
+			p.Keys++
+			p.Bytes += ksize + vsize
+		}
+		tx.RUnlock()
+		s.mu.RUnlock()
+
+		if len(prefixes) > keyspaceStatsMaxTracked {
+			prefixes = largestPrefixes(prefixes, MaxKeyspacePrefixes)
+		}
+
+		if len(keyRevs) < keyspaceStatsBatchLimit {
+			break
+		}
+		from = append(append([]byte{}, keyRevs[len(keyRevs)-1].key...), 0)
+	}
+
+	stats.Prefixes = sortPrefixes(prefixes)
+	if len(stats.Prefixes) > MaxKeyspacePrefixes {
+		stats.Prefixes = stats.Prefixes[:MaxKeyspacePrefixes]
+	}
+	return stats, nil
+}
+
+// sortPrefixes returns the stats of prefixes, largest first.
+func sortPrefixes(prefixes map[string]*PrefixStats) []PrefixStats {
+	sorted := make([]PrefixStats, 0, len(prefixes))
+	for _, p := range prefixes {
+		sorted = append(sorted, *p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return bytes.Compare(a.Prefix, b.Prefix) < 0
+	})
+	return sorted
+}
+
+// largestPrefixes returns the n largest of prefixes.
+func largestPrefixes(prefixes map[string]*PrefixStats, n int) map[string]*PrefixStats {
+	largest := make(map[string]*PrefixStats, n)
+	for _, p := range sortPrefixes(prefixes)[:n] {
+		p := p
+		largest[string(p.Prefix)] = &p
+	}
+	return largest
+}
+
+// keyspacePrefix returns the prefix of key up to and including its depth-th
+// '/', not counting a leading one, or up to its last '/' if it has fewer.
+func keyspacePrefix(key []byte, depth int) []byte {
+	end := 0
+	if len(key) > 0 && key[0] == '/' {
+		end = 1
+	}
+	for i := end; i < len(key) && depth > 0; i++ {
+		if key[i] == '/' {
+			end = i + 1
+			depth--
+		}
+	}
+	return key[:end]
+}
+
+// unsafeValueSize returns the size of the value of the key-value pair written
+// at rev, and false if the backend does not hold it anymore.
+func unsafeValueSize(tx backend.UnsafeReader, rev revision) (int64, bool) {
+	revBytes := newRevBytes()
+	revToBytes(rev, revBytes)
+	_, vs := tx.UnsafeRange(schema.Key, revBytes, nil, 0)
+	if len(vs) != 1 {
+		return 0, false
+	}
+	var kv mvccpb.KeyValue
+	if err := kv.Unmarshal(vs[0]); err != nil {
+		return 0, false
+	}
+	return int64(len(kv.Value)), true
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestKeyspacePrefix(t *testing.T) {
+	tests := []struct {
+		key    string
+		depth  int
+		prefix string
+	}{
+		{"/registry/pods/ns/p", 1, "/registry/"},
+		{"/registry/pods/ns/p", 2, "/registry/pods/"},
+		{"/registry/pods/ns/p", 16, "/registry/pods/ns/"},
+		{"a/b", 1, "a/"},
+		{"/a", 1, "/"},
+		{"a", 1, ""},
+		{"//a", 1, "//"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.prefix, string(keyspacePrefix([]byte(tt.key), tt.depth)), "%q at depth %d", tt.key, tt.depth)
+	}
+}
+
+func TestStoreKeyspaceStats(t *testing.T) {
+	defer func(limit int, ttl time.Duration) {
+		keyspaceStatsBatchLimit, keyspaceStatsTTL = limit, ttl
+	}(keyspaceStatsBatchLimit, keyspaceStatsTTL)
+	// small batches go through the releases of the locks
+	keyspaceStatsBatchLimit = 3
+	keyspaceStatsTTL = time.Hour
+
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	// /a/ has the most keys, /b/ the most bytes
+	for i := 0; i < 6; i++ {
+		s.Put([]byte(fmt.Sprintf("/a/%d", i)), []byte("v"), lease.NoLease)
+	}
+	for i := 0; i < 2; i++ {
+		s.Put([]byte(fmt.Sprintf("/b/x/%d", i)), []byte(strings.Repeat("v", 100)), lease.NoLease)
+	}
+	s.Put([]byte("c"), []byte("vv"), lease.NoLease)
+	// overwritten and deleted keys only count once, or not at all
+	s.Put([]byte("/a/0"), []byte("v"), lease.NoLease)
+	s.Put([]byte("/d/0"), []byte("v"), lease.NoLease)
+	s.DeleteRange([]byte("/d/0"), nil)
+
+	stats, err := s.KeyspaceStats(context.Background(), 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, s.Rev(), stats.Revision)
+	assert.Equal(t, int64(9), stats.Keys)
+	assert.Equal(t, int64(6*4+2*6+1), stats.KeyBytes)
+	assert.Equal(t, int64(6*1+2*100+2), stats.ValueBytes)
+	assert.Equal(t, []PrefixStats{
+		{Prefix: []byte("/b/"), Keys: 2, Bytes: 2 * 106},
+		{Prefix: []byte("/a/"), Keys: 6, Bytes: 6 * 5},
+		{Prefix: []byte(""), Keys: 1, Bytes: 3},
+	}, stats.Prefixes)
+
+	stats, err = s.KeyspaceStats(context.Background(), 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []PrefixStats{{Prefix: []byte("/b/x/"), Keys: 2, Bytes: 2 * 106}}, stats.Prefixes)
+
+	// the stats are cached until the TTL expires
+	s.Put([]byte("/e/0"), []byte(strings.Repeat("v", 1000)), lease.NoLease)
+	cached, err := s.KeyspaceStats(context.Background(), 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), cached.Keys)
+
+	keyspaceStatsTTL = 0
+	stats, err = s.KeyspaceStats(context.Background(), 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), stats.Keys)
+	assert.Equal(t, []PrefixStats{{Prefix: []byte("/e/"), Keys: 1, Bytes: 1004}}, stats.Prefixes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.KeyspaceStats(ctx, 0, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStoreKeyspaceStatsMaxTracked(t *testing.T) {
+	defer func(limit, tracked int) {
+		keyspaceStatsBatchLimit, keyspaceStatsMaxTracked = limit, tracked
+	}(keyspaceStatsBatchLimit, keyspaceStatsMaxTracked)
+	keyspaceStatsBatchLimit = 10
+	keyspaceStatsMaxTracked = MaxKeyspacePrefixes + 10
+
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	// the prefixes grow with their keys, so the first ones are dropped
+	n := 3 * MaxKeyspacePrefixes
+	for i := 0; i < n; i++ {
+		s.Put([]byte(fmt.Sprintf("/%04d/k", i)), []byte(strings.Repeat("v", i)), lease.NoLease)
+	}
+
+	stats, err := s.KeyspaceStats(context.Background(), 0, MaxKeyspacePrefixes)
+	require.NoError(t, err)
+	assert.Equal(t, int64(n), stats.Keys)
+	require.Len(t, stats.Prefixes, MaxKeyspacePrefixes)
+	for i, p := range stats.Prefixes {
+		j := n - 1 - i
+		assert.Equal(t, PrefixStats{Prefix: []byte(fmt.Sprintf("/%04d/", j)), Keys: 1, Bytes: int64(7 + j)}, p)
+	}
+}
+
+func TestStoreKeyspaceStatsSharedWalk(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+	s.Put([]byte("/a/0"), []byte("v"), lease.NoLease)
+
+	// block the walk of the first request on the store lock
+	s.mu.Lock()
+	donec := make(chan error, 1)
+	go func() {
+		_, err := s.KeyspaceStats(context.Background(), 1, 0)
+		donec <- err
+	}()
+	require.Eventually(t, func() bool {
+		s.keyspaceStats.mu.Lock()
+		defer s.keyspaceStats.mu.Unlock()
+		_, ok := s.keyspaceStats.entries[1]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the other requests do not wait on the cache for the walk
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := s.KeyspaceStats(ctx, 1, 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	s.mu.Unlock()
+	require.NoError(t, <-donec)
+	stats, err := s.KeyspaceStats(context.Background(), 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Keys)
+}
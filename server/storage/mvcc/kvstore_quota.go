@@ -0,0 +1,395 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.etcd.io/etcd/server/v3/storage/backend"
+)
+
+// PrefixQuota limits the keys with Prefix. MaxBytes limits the total size of
+// their keys and values, and MaxKeys their number. Zero means no limit.
+type PrefixQuota struct {
+	Prefix   []byte
+	MaxBytes int64
+	MaxKeys  int64
+}
+
+// PrefixQuotaUsage is a prefix quota along with the current usage of its
+// prefix.
+type PrefixQuotaUsage struct {
+	PrefixQuota
+	// Bytes is the total size of the keys and values with the prefix.
+	Bytes int64
+	// Keys is the number of keys with the prefix.
+	Keys int64
+}
+
+// QuotaOp is a write checked against the prefix quotas by
+// CheckPrefixQuotas.
+type QuotaOp struct {
+	Key   []byte
+	Value []byte
+	// Delete deletes the keys in the range [Key, End), interpreted as in
+	// DeleteRange, rather than putting Value.
+	Delete bool
+	End    []byte
+	// IgnoreValue puts the current value of Key rather than Value.
+	IgnoreValue bool
+	// CopyFrom puts the current value of CopyFrom rather than Value.
+	CopyFrom []byte
+}
+
+// QuotaExceededError is returned for writes that would take the keys with
+// Prefix over the quota of Prefix.
+type QuotaExceededError struct {
+	Prefix []byte
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("mvcc: quota of prefix %q exceeded", e.Prefix)
+}
+
+// prefixQuotas tracks the usage of the prefixes with a quota. The usages are
+// updated by the write txns as they put and delete keys, along with the size
+// of every key with a quota, so that neither the writes nor their quota
+// checks read the values of the keys.
+type prefixQuotas struct {
+	mu sync.RWMutex
+	// usages is sorted by prefix.
+	usages []PrefixQuotaUsage
+	// sizes maps the keys with a prefix with a quota to the size of their
+	// key and value.
+	sizes map[string]int64
+}
+
+func (pq *prefixQuotas) list() []PrefixQuotaUsage {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	if len(pq.usages) == 0 {
+		return nil
+	}
+	return append([]PrefixQuotaUsage(nil), pq.usages...)
+}
+
+// size returns the size of key, if key exists and has a prefix with a quota.
+func (pq *prefixQuotas) size(key []byte) (int64, bool) {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	n, ok := pq.sizes[string(key)]
+	return n, ok
+}
+
+// put records that key was put with a value of size bytes, along with its
+// key.
+func (pq *prefixQuotas) put(key []byte, size int64) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if !covers(pq.usages, key) {
+		return
+	}
+	keys := int64(1)
+	delta := size
+	if old, ok := pq.sizes[string(key)]; ok {
+		keys, delta = 0, size-old
+	}
+	pq.sizes[string(key)] = size
+	pq.add(key, keys, delta)
+}
+
+// delete records that key was deleted.
+func (pq *prefixQuotas) delete(key []byte) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if old, ok := pq.sizes[string(key)]; ok {
+		delete(pq.sizes, string(key))
+		pq.add(key, -1, -old)
+	}
+}
+
+// add adds keys and size to the usage of the prefixes of key.
+func (pq *prefixQuotas) add(key []byte, keys, size int64) {
+	for i := range pq.usages {
+		if bytes.HasPrefix(key, pq.usages[i].Prefix) {
+			pq.usages[i].Keys += keys
+			pq.usages[i].Bytes += size
+		}
+	}
+}
+
+// set replaces the quota of the prefix of u, removing it if u has no limits.
+// sizes holds the size of the keys with the prefix.
+func (pq *prefixQuotas) set(u PrefixQuotaUsage, sizes map[string]int64) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	i := sort.Search(len(pq.usages), func(i int) bool { return bytes.Compare(pq.usages[i].Prefix, u.Prefix) >= 0 })
+	found := i < len(pq.usages) && bytes.Equal(pq.usages[i].Prefix, u.Prefix)
+	switch {
+	case u.MaxBytes == 0 && u.MaxKeys == 0:
+		if found {
+			pq.usages = append(pq.usages[:i], pq.usages[i+1:]...)
+			for k := range pq.sizes {
+				if !covers(pq.usages, []byte(k)) {
+					delete(pq.sizes, k)
+				}
+			}
+		}
+		return
+	case found:
+		pq.usages[i] = u
+	default:
+		pq.usages = append(pq.usages, PrefixQuotaUsage{})
+		copy(pq.usages[i+1:], pq.usages[i:])
+		pq.usages[i] = u
+	}
+	if pq.sizes == nil {
+		pq.sizes = make(map[string]int64, len(sizes))
+	}
+	for k, n := range sizes {
+		pq.sizes[k] = n
+	}
+}
+
+func (pq *prefixQuotas) reset(usages []PrefixQuotaUsage, sizes map[string]int64) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	pq.usages = usages
+	pq.sizes = sizes
+}
+
+// covers reports whether key has a prefix in usages.
+func covers(usages []PrefixQuotaUsage, key []byte) bool {
+	for _, u := range usages {
+		if bytes.HasPrefix(key, u.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPrefixQuota sets the quota of the keys with q.Prefix, replacing the
+// previous quota of the prefix. A quota without limits removes the quota of
+// the prefix.
+func (s *store) SetPrefixQuota(q PrefixQuota) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.b.BatchTx()
+	tx.LockInsideApply()
+	defer tx.Unlock()
+	u := PrefixQuotaUsage{PrefixQuota: PrefixQuota{Prefix: bytes.Clone(q.Prefix), MaxBytes: q.MaxBytes, MaxKeys: q.MaxKeys}}
+	var sizes map[string]int64
+	if q.MaxBytes != 0 || q.MaxKeys != 0 {
+		s.revMu.RLock()
+		rev := s.currentRev
+		s.revMu.RUnlock()
+		sizes = make(map[string]int64)
+		u.Keys, u.Bytes = s.unsafePrefixUsage(tx, q.Prefix, rev, sizes)
+	}
+	s.quotas.set(u, sizes)
+
+	var quotas []PrefixQuota
+	for _, u := range s.quotas.list() {
+		quotas = append(quotas, u.PrefixQuota)
+	}
+	unsafeSetPrefixQuotas(tx, quotas)
+}
+
+// PrefixQuotas returns the prefix quotas sorted by prefix, along with the
+// usage of their prefixes.
+func (s *store) PrefixQuotas() []PrefixQuotaUsage {
+	return s.quotas.list()
+}
+
+// CheckPrefixQuotas returns a *QuotaExceededError if applying ops to rv would
+// take the keys under a prefix over its quota. The ops must not overlap, as in
+// a txn. Writes that do not grow the usage of a prefix are accepted even if
+// the prefix is over its quota, so that the keys of a prefix whose quota was
+// lowered can still be updated and deleted.
+func (s *store) CheckPrefixQuotas(rv ReadView, ops []QuotaOp) error {
+	usages := s.quotas.list()
+	if len(usages) == 0 {
+		return nil
+	}
+	keys := make([]int64, len(usages))
+	sizes := make([]int64, len(usages))
+	for _, op := range ops {
+		if op.Delete {
+			// only the index and the tracked sizes are consulted
+			for i, u := range usages {
+				key, end, ok := intersectPrefix(op.Key, op.End, u.Prefix)
+				if !ok {
+					continue
+				}
+				for _, k := range s.kvindex.Keys(key, end, rv.Rev()) {
+					if n, ok := s.quotas.size(k); ok {
+						keys[i]--
+						sizes[i] -= n
+					}
+				}
+			}
+			continue
+		}
+		if !covers(usages, op.Key) {
+			continue
+		}
+		k, n, err := s.putQuotaDelta(rv, op)
+		if err != nil {
+			return err
+		}
+		for i, u := range usages {
+			if bytes.HasPrefix(op.Key, u.Prefix) {
+				keys[i] += k
+				sizes[i] += n
+			}
+		}
+	}
+	for i, u := range usages {
+		if (u.MaxKeys > 0 && keys[i] > 0 && u.Keys+keys[i] > u.MaxKeys) ||
+			(u.MaxBytes > 0 && sizes[i] > 0 && u.Bytes+sizes[i] > u.MaxBytes) {
+			return &QuotaExceededError{Prefix: u.Prefix}
+		}
+	}
+	return nil
+}
+
+// putQuotaDelta returns the change to the number and the size of the keys
+// made by the put op of a key with a quota. Only a put copying a key without
+// a quota reads its value.
+func (s *store) putQuotaDelta(rv ReadView, op QuotaOp) (keys, size int64, err error) {
+	size = int64(len(op.Key) + len(op.Value))
+	if len(op.CopyFrom) != 0 {
+		if n, ok := s.quotas.size(op.CopyFrom); ok {
+			size = int64(len(op.Key)) + n - int64(len(op.CopyFrom))
+		} else {
+			cr, err := rv.Range(context.TODO(), op.CopyFrom, nil, RangeOptions{})
+			if err != nil {
+				return 0, 0, err
+			}
+			size = int64(len(op.Key))
+			if len(cr.KVs) != 0 {
+				size += int64(len(cr.KVs[0].Value))
+			}
+		}
+	}
+	old, ok := s.quotas.size(op.Key)
+	switch {
+	case !ok:
+		return 1, size, nil
+	case op.IgnoreValue:
+		return 0, 0, nil
+	}
+	return 0, size - old, nil
+}
+
+// prefixRange returns the range of the keys with prefix, as in Range.
+func prefixRange(prefix []byte) (key, end []byte) {
+	if end = prefixEnd(prefix); end == nil {
+		end = []byte{}
+	}
+	return prefix, end
+}
+
+// intersectPrefix returns the part of the range [key, end), interpreted as in
+// Range, with prefix. It returns false if they do not intersect.
+func intersectPrefix(key, end, prefix []byte) ([]byte, []byte, bool) {
+	if end == nil {
+		return key, nil, bytes.HasPrefix(key, prefix)
+	}
+	pkey, pend := prefixRange(prefix)
+	if bytes.Compare(pkey, key) > 0 {
+		key = pkey
+	}
+	if len(end) == 0 || (len(pend) != 0 && bytes.Compare(pend, end) < 0) {
+		end = pend
+	}
+	if len(end) != 0 && bytes.Compare(key, end) >= 0 {
+		return nil, nil, false
+	}
+	return key, end, true
+}
+
+// unsafePrefixUsage returns the number and the size of the keys with prefix
+// at rev, and records the size of every key in sizes.
+func (s *store) unsafePrefixUsage(tx backend.UnsafeReader, prefix []byte, rev int64, sizes map[string]int64) (keys, size int64) {
+	key, end := prefixRange(prefix)
+	revs, _ := s.kvindex.Revisions(key, end, rev, 0, false)
+	for _, r := range revs {
+		kv := s.unsafeKV(tx, r)
+		n := int64(len(kv.Key) + len(kv.Value))
+		sizes[string(kv.Key)] = n
+		size += n
+	}
+	return int64(len(revs)), size
+}
+
+// unsafeRestorePrefixQuotas restores the prefix quotas and recounts the usage
+// of their prefixes at rev.
+func (s *store) unsafeRestorePrefixQuotas(tx backend.UnsafeReader, rev int64) error {
+	quotas, err := unsafeReadPrefixQuotas(tx)
+	if err != nil {
+		return err
+	}
+	var usages []PrefixQuotaUsage
+	sizes := make(map[string]int64)
+	for _, q := range quotas {
+		u := PrefixQuotaUsage{PrefixQuota: q}
+		u.Keys, u.Bytes = s.unsafePrefixUsage(tx, q.Prefix, rev, sizes)
+		usages = append(usages, u)
+	}
+	s.quotas.reset(usages, sizes)
+	return nil
+}
+
+// marshalPrefixQuotas encodes every quota as its byte and key limits followed
+// by the length and the bytes of its prefix.
+func marshalPrefixQuotas(quotas []PrefixQuota) []byte {
+	var buf []byte
+	for _, q := range quotas {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(q.MaxBytes))
+		buf = binary.BigEndian.AppendUint64(buf, uint64(q.MaxKeys))
+		buf = binary.AppendUvarint(buf, uint64(len(q.Prefix)))
+		buf = append(buf, q.Prefix...)
+	}
+	return buf
+}
+
+func unmarshalPrefixQuotas(buf []byte) ([]PrefixQuota, error) {
+	var quotas []PrefixQuota
+	for len(buf) > 0 {
+		if len(buf) < 16 {
+			return nil, fmt.Errorf("truncated prefix quota limits")
+		}
+		q := PrefixQuota{
+			MaxBytes: int64(binary.BigEndian.Uint64(buf)),
+			MaxKeys:  int64(binary.BigEndian.Uint64(buf[8:])),
+		}
+		buf = buf[16:]
+		n, l := binary.Uvarint(buf)
+		if l <= 0 || uint64(len(buf)-l) < n {
+			return nil, fmt.Errorf("truncated prefix quota prefix")
+		}
+		q.Prefix = bytes.Clone(buf[l : l+int(n)])
+		quotas = append(quotas, q)
+		buf = buf[l+int(n):]
+	}
+	return quotas, nil
+}
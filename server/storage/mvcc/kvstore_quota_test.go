@@ -0,0 +1,136 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestIntersectPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		key, end, prefix string
+		openEnd          bool
+
+		wantKey, wantEnd string
+		wantOK           bool
+	}{
+		{key: "/a/x", prefix: "/a/", wantKey: "/a/x", wantOK: true},
+		{key: "/b", prefix: "/a/"},
+		{key: "/", end: "0", prefix: "/a/", wantKey: "/a/", wantEnd: "/a0", wantOK: true},
+		{key: "/a/m", end: "/b", prefix: "/a/", wantKey: "/a/m", wantEnd: "/a0", wantOK: true},
+		{key: "/", end: "/a/m", prefix: "/a/", wantKey: "/a/", wantEnd: "/a/m", wantOK: true},
+		{key: "/b", end: "/c", prefix: "/a/"},
+		{key: "/", end: "/a/", prefix: "/a/"},
+		{key: "/", openEnd: true, prefix: "/a/", wantKey: "/a/", wantEnd: "/a0", wantOK: true},
+	} {
+		var end []byte
+		if tt.end != "" {
+			end = []byte(tt.end)
+		} else if tt.openEnd {
+			end = []byte{}
+		}
+		key, end, ok := intersectPrefix([]byte(tt.key), end, []byte(tt.prefix))
+		require.Equal(t, tt.wantOK, ok, "%q-%q with prefix %q", tt.key, tt.end, tt.prefix)
+		if ok {
+			assert.Equal(t, tt.wantKey, string(key))
+			assert.Equal(t, tt.wantEnd, string(end))
+		}
+	}
+}
+
+func TestStorePrefixQuota(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer b.Close()
+
+	s.Put([]byte("/a/1"), []byte("12345"), lease.NoLease)
+	s.Put([]byte("/b/1"), []byte("12345"), lease.NoLease)
+	s.SetPrefixQuota(PrefixQuota{Prefix: []byte("/a/"), MaxKeys: 3, MaxBytes: 40})
+	s.SetPrefixQuota(PrefixQuota{Prefix: []byte("/c/"), MaxKeys: 1})
+
+	usage := func(s *store) PrefixQuotaUsage {
+		quotas := s.PrefixQuotas()
+		require.Len(t, quotas, 2)
+		return quotas[0]
+	}
+	check := func(ops ...QuotaOp) error {
+		txn := s.Read(ConcurrentReadTxMode, traceutil.TODO())
+		defer txn.End()
+		return s.CheckPrefixQuotas(txn, ops)
+	}
+	put := func(key, value string) QuotaOp { return QuotaOp{Key: []byte(key), Value: []byte(value)} }
+
+	u := usage(s)
+	assert.Equal(t, []byte("/a/"), u.Prefix)
+	assert.Equal(t, int64(1), u.Keys)
+	assert.Equal(t, int64(9), u.Bytes)
+
+	s.Put([]byte("/a/2"), []byte("12345"), lease.NoLease)
+	s.Put([]byte("/a/1"), []byte("1234567890"), lease.NoLease)
+	u = usage(s)
+	assert.Equal(t, int64(2), u.Keys)
+	assert.Equal(t, int64(23), u.Bytes)
+
+	assert.NoError(t, check(put("/a/3", "1")))
+	assert.NoError(t, check(put("/b/3", "a value longer than the byte limit of /a/")))
+	// a third key fits, but not its value
+	var qerr *QuotaExceededError
+	require.ErrorAs(t, check(put("/a/3", "12345678901234")), &qerr)
+	assert.Equal(t, []byte("/a/"), qerr.Prefix)
+	// ops of a txn are checked together
+	require.ErrorAs(t, check(put("/a/3", "1"), put("/a/4", "1")), &qerr)
+	require.ErrorAs(t, check(put("/c/1", "1"), put("/c/2", "1")), &qerr)
+	assert.Equal(t, []byte("/c/"), qerr.Prefix)
+	// deleting a key makes room for another one
+	assert.NoError(t, check(QuotaOp{Key: []byte("/a/2"), Delete: true}, put("/a/3", "1"), put("/a/4", "1")))
+	assert.NoError(t, check(QuotaOp{Key: []byte("/"), End: []byte{}, Delete: true}, put("/a/3", "1"), put("/a/4", "1")))
+	assert.NoError(t, check(QuotaOp{Key: []byte("/a/1"), IgnoreValue: true}))
+	// a copy counts the value it copies
+	assert.NoError(t, check(QuotaOp{Key: []byte("/a/3"), CopyFrom: []byte("/a/2")}))
+	require.ErrorAs(t, check(QuotaOp{Key: []byte("/a/3"), CopyFrom: []byte("/a/1")}, put("/a/4", "")), &qerr)
+	assert.NoError(t, check(QuotaOp{Key: []byte("/a/3"), CopyFrom: []byte("/b/1")}))
+	// only the keys with a quota are tracked
+	assert.Equal(t, map[string]int64{"/a/1": 14, "/a/2": 9}, s.quotas.sizes)
+
+	// deletes free the quota
+	s.DeleteRange([]byte("/a/1"), nil)
+	u = usage(s)
+	assert.Equal(t, int64(1), u.Keys)
+	assert.Equal(t, int64(9), u.Bytes)
+
+	// the quotas survive a restart, and the usage is recounted
+	require.NoError(t, s.Close())
+	s = NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	u = usage(s)
+	assert.Equal(t, PrefixQuota{Prefix: []byte("/a/"), MaxKeys: 3, MaxBytes: 40}, u.PrefixQuota)
+	assert.Equal(t, int64(1), u.Keys)
+	assert.Equal(t, int64(9), u.Bytes)
+
+	// a quota without limits is removed, along with the sizes of its keys
+	s.SetPrefixQuota(PrefixQuota{Prefix: []byte("/c/")})
+	require.Len(t, s.PrefixQuotas(), 1)
+	s.SetPrefixQuota(PrefixQuota{Prefix: []byte("/a/")})
+	assert.Empty(t, s.PrefixQuotas())
+	assert.Empty(t, s.quotas.sizes)
+	require.NoError(t, s.Close())
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go.etcd.io/etcd/server/v3/storage/schema"
+)
+
+// RebuildIndex replaces the key index by one rebuilt from the backend, as on
+// restore. It returns the revision of the store and the differences of the
+// replaced index with the backend: the revisions of the backend it was
+// missing as AnomalyMissingInIndex, and the revisions it had that the backend
+// does not hold as AnomalyMissingInBackend.
+//
+// The index is rebuilt with the store locked, so that the reads and the
+// writes wait until it is replaced. It does not wait for the compactions: the
+// rebuilt index is compacted like the replaced one by the compaction in
+// progress, if any, which goes on removing the revisions it does not keep
+// from the backend. The index is left untouched if ctx is done before the
+// rebuild starts, or if a key-value pair of the backend cannot be decoded.
+func (s *store) RebuildIndex(ctx context.Context) (int64, []Anomaly, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	start := time.Now()
+	// the last batch of a compaction is not committed, so its deletes are
+	// not seen by the read txs yet
+	s.b.ForceCommit()
+	idx := newTreeIndex(s.lg)
+	if err := s.unsafeRestoreIndex(idx); err != nil {
+		return 0, nil, err
+	}
+	s.revMu.RLock()
+	rev := s.currentRev
+	s.revMu.RUnlock()
+
+	s.indexMu.Lock()
+	// the backend may still hold revisions the compaction in progress
+	// removed from the replaced index
+	s.verifyMu.Lock()
+	if c := s.compacting; c != nil {
+		if c.floors.uniform() {
+			idx.Compact(c.floors.min())
+		} else {
+			idx.CompactFloors(c.floors.at)
+		}
+	}
+	s.verifyMu.Unlock()
+	anomalies := diffIndex(s.kvindex, idx)
+	s.kvindex = idx
+	s.indexMu.Unlock()
+	// the cached revisions were resolved by the replaced index
+	s.readCache.reset()
+
+	s.lg.Info(
+		"rebuilt key index",
+		zap.Int64("current-rev", rev),
+		zap.Int("anomalies", len(anomalies)),
+		zap.Duration("took", time.Since(start)),
+	)
+	return rev, anomalies, nil
+}
+
+// unsafeRestoreIndex restores the revisions of the keys in the backend into
+// idx. Unlike restore, it fails on a key-value pair that cannot be decoded.
+// The store must be locked.
+func (s *store) unsafeRestoreIndex(idx index) error {
+	min, max := newRevBytes(), newRevBytes()
+	revToBytes(revision{main: 1}, min)
+	revToBytes(revision{main: math.MaxInt64, sub: math.MaxInt64}, max)
+
+	tx := s.b.ReadTx()
+	tx.RLock()
+	defer tx.RUnlock()
+
+	rkvc, revc := restoreIntoIndex(s.lg, idx)
+	defer func() {
+		close(rkvc)
+		<-revc
+	}()
+	for {
+		keys, vals := tx.UnsafeRange(schema.Key, min, max, int64(restoreChunkKeys))
+		for i, key := range keys {
+			rkv := revKeyValue{key: key}
+			if err := rkv.kv.Unmarshal(vals[i]); err != nil {
+				return fmt.Errorf("cannot decode the key-value pair at revision %d: %w", bytesToRev(key).main, err)
+			}
+			rkv.kstr = string(rkv.kv.Key)
+			rkvc <- rkv
+		}
+		if len(keys) < restoreChunkKeys {
+			return nil
+		}
+		newMin := bytesToRev(keys[len(keys)-1][:revBytesLen])
+		newMin.sub++
+		revToBytes(newMin, min)
+	}
+}
+
+// diffIndex returns the revisions of rebuilt missing from old as
+// AnomalyMissingInIndex, and the revisions of old missing from rebuilt as
+// AnomalyMissingInBackend, in the order of their revisions.
+func diffIndex(old, rebuilt index) []Anomaly {
+	var anomalies []Anomaly
+	diff := func(from, to index, t AnomalyType, detail string) {
+		key := []byte{}
+		for {
+			keys, revs := from.Histories(key, defaultVerifyBatchLimit)
+			for i, k := range keys {
+				for _, rev := range revs[i] {
+					if !to.HasRevision(k, rev) {
+						anomalies = append(anomalies, Anomaly{Type: t, Key: k, Revision: rev.main, SubRevision: rev.sub, Detail: detail})
+					}
+				}
+			}
+			if len(keys) < defaultVerifyBatchLimit {
+				return
+			}
+			key = append(append([]byte{}, keys[len(keys)-1]...), 0)
+		}
+	}
+	diff(rebuilt, old, AnomalyMissingInIndex, "revision is missing from the key index")
+	diff(old, rebuilt, AnomalyMissingInBackend, "revision is missing from the backend")
+	sortAnomalies(anomalies)
+	return anomalies
+}
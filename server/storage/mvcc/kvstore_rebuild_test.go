@@ -0,0 +1,148 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestStoreRebuildIndex(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+	// ref holds the same keys, with an index that is not corrupted
+	refb, _ := betesting.NewDefaultTmpBackend(t)
+	ref := NewStore(zaptest.NewLogger(t), refb, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(ref, refb)
+
+	for _, st := range []*store{s, ref} {
+		for i := 0; i < 10; i++ {
+			st.Put([]byte(fmt.Sprintf("key%d", i)), []byte("v"), lease.NoLease) // rev 2-11
+		}
+		st.Put([]byte("foo"), []byte("1"), lease.NoLease) // rev 12
+		st.Put([]byte("foo"), []byte("2"), lease.NoLease) // rev 13
+		st.DeleteRange([]byte("key3"), nil)               // rev 14
+		st.Put([]byte("foo"), []byte("3"), lease.NoLease) // rev 15
+		donec, err := st.Compact(traceutil.TODO(), 5)
+		require.NoError(t, err)
+		<-donec
+		st.Put([]byte("foo"), []byte("4"), lease.NoLease) // rev 16
+	}
+
+	rev, anomalies, err := s.RebuildIndex(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(16), rev)
+	assert.Empty(t, anomalies)
+
+	corruptIndex(t, s, []byte("foo"), func(ki *keyIndex) {
+		g := &ki.generations[len(ki.generations)-1]
+		require.Equal(t, revision{main: 13}, g.revs[1])
+		g.revs = append(g.revs[:1], g.revs[2:]...)
+	})
+	// key2 now resolves to the revision of key4
+	corruptIndex(t, s, []byte("key2"), func(ki *keyIndex) {
+		g := &ki.generations[len(ki.generations)-1]
+		g.revs = append(g.revs, revision{main: 6})
+		ki.modified = revision{main: 6}
+	})
+	r, err := s.Range(context.Background(), []byte("key2"), nil, RangeOptions{})
+	require.NoError(t, err)
+	require.Len(t, r.KVs, 1)
+	assert.Equal(t, "key4", string(r.KVs[0].Key))
+	hash, _, err := s.hashByRev(0)
+	require.NoError(t, err)
+	refHash, _, err := ref.hashByRev(0)
+	require.NoError(t, err)
+	assert.NotEqual(t, refHash, hash)
+
+	rev, anomalies, err = s.RebuildIndex(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(16), rev)
+	assert.Equal(t, []Anomaly{{
+		Type:     AnomalyMissingInBackend,
+		Key:      []byte("key2"),
+		Revision: 6,
+		Detail:   "revision is missing from the backend",
+	}, {
+		Type:     AnomalyMissingInIndex,
+		Key:      []byte("foo"),
+		Revision: 13,
+		Detail:   "revision is missing from the key index",
+	}}, anomalies)
+
+	_, anomalies, err = s.Verify(context.Background(), VerifyOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, anomalies)
+	hash, _, err = s.hashByRev(0)
+	require.NoError(t, err)
+	assert.Equal(t, refHash, hash)
+	for _, key := range []string{"key2", "foo"} {
+		r, err = s.Range(context.Background(), []byte(key), nil, RangeOptions{Rev: 13})
+		require.NoError(t, err)
+		refr, err := ref.Range(context.Background(), []byte(key), nil, RangeOptions{Rev: 13})
+		require.NoError(t, err)
+		assert.Equal(t, refr.KVs, r.KVs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = s.RebuildIndex(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestStoreRebuildIndexDuringCompaction ensures the index is rebuilt without
+// waiting for the compaction in progress, and is compacted like the replaced
+// one.
+func TestStoreRebuildIndexDuringCompaction(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{
+		CompactionBatchLimit:    10,
+		CompactionSleepInterval: 10 * time.Millisecond,
+	})
+	defer cleanup(s, b)
+
+	for i := 0; i < 1000; i++ {
+		s.Put([]byte(fmt.Sprintf("key%d", i%10)), []byte("v"), lease.NoLease)
+	}
+	donec, err := s.Compact(traceutil.TODO(), 900)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	rev, anomalies, err := s.RebuildIndex(context.Background())
+	require.NoError(t, err)
+	select {
+	case <-donec:
+		t.Fatal("the compaction is done, want the index rebuilt while it is in progress")
+	default:
+	}
+	assert.Equal(t, int64(1001), rev)
+	assert.Empty(t, anomalies)
+
+	<-donec
+	_, anomalies, err = s.Verify(context.Background(), VerifyOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, anomalies)
+}
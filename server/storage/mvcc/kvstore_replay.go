@@ -0,0 +1,53 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+)
+
+func (s *store) Replay(key, end []byte, startRev, endRev int64) ([]mvccpb.Event, error) {
+	s.mu.RLock()
+	s.revMu.RLock()
+	compactRev, curRev := s.floors().rangeMax(key, end), s.currentRev
+	s.revMu.RUnlock()
+
+	if endRev > curRev+1 {
+		s.mu.RUnlock()
+		return nil, ErrFutureRev
+	}
+	if startRev < compactRev || s.keyCompactions.compactRev(key, end, startRev) != 0 {
+		s.mu.RUnlock()
+		return nil, ErrCompacted
+	}
+	if startRev >= endRev {
+		s.mu.RUnlock()
+		return nil, nil
+	}
+
+	minBytes, maxBytes := newRevBytes(), newRevBytes()
+	revToBytes(revision{main: startRev}, minBytes)
+	revToBytes(revision{main: endRev}, maxBytes)
+
+	// holding the read tx keeps a compaction after the checks above from
+	// removing the revisions before they are read
+	tx := s.b.ReadTx()
+	tx.RLock()
+	defer tx.RUnlock()
+	s.mu.RUnlock()
+	revs, vs := tx.UnsafeRange(schema.Key, minBytes, maxBytes, 0)
+	return kvsToEvents(s.lg, func(k string) bool { return inRange([]byte(k), key, end) }, revs, vs), nil
+}
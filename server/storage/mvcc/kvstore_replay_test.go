@@ -0,0 +1,92 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestStoreReplay(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	s.Put([]byte("foo"), []byte("1"), lease.NoLease)  // rev 2
+	s.Put([]byte("bar"), []byte("1"), lease.NoLease)  // rev 3
+	s.Put([]byte("foo"), []byte("2"), lease.NoLease)  // rev 4
+	s.DeleteRange([]byte("foo"), nil)                 // rev 5
+	s.Put([]byte("foo1"), []byte("1"), lease.NoLease) // rev 6
+	s.Put([]byte("foo"), []byte("3"), lease.NoLease)  // rev 7
+
+	type event struct {
+		typ      mvccpb.Event_EventType
+		key, val string
+		rev      int64
+	}
+	replay := func(key, end []byte, startRev, endRev int64) ([]event, error) {
+		evs, err := s.Replay(key, end, startRev, endRev)
+		var got []event
+		for _, ev := range evs {
+			got = append(got, event{ev.Type, string(ev.Kv.Key), string(ev.Kv.Value), ev.Kv.ModRevision})
+		}
+		return got, err
+	}
+
+	got, err := replay([]byte("foo"), nil, 3, 7)
+	require.NoError(t, err)
+	assert.Equal(t, []event{
+		{mvccpb.PUT, "foo", "2", 4},
+		{mvccpb.DELETE, "foo", "", 5},
+	}, got)
+
+	got, err = replay([]byte("foo"), []byte("fop"), 4, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []event{
+		{mvccpb.PUT, "foo", "2", 4},
+		{mvccpb.DELETE, "foo", "", 5},
+		{mvccpb.PUT, "foo1", "1", 6},
+		{mvccpb.PUT, "foo", "3", 7},
+	}, got)
+
+	got, err = replay([]byte("a"), []byte{}, 2, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []event{
+		{mvccpb.PUT, "foo", "1", 2},
+		{mvccpb.PUT, "bar", "1", 3},
+	}, got)
+
+	_, err = replay([]byte("foo"), nil, 2, 9)
+	assert.ErrorIs(t, err, ErrFutureRev)
+
+	_, err = s.Compact(traceutil.TODO(), 4)
+	require.NoError(t, err)
+	_, err = replay([]byte("foo"), nil, 3, 8)
+	assert.ErrorIs(t, err, ErrCompacted)
+	got, err = replay([]byte("foo"), nil, 4, 6)
+	require.NoError(t, err)
+	assert.Equal(t, []event{
+		{mvccpb.PUT, "foo", "2", 4},
+		{mvccpb.DELETE, "foo", "", 5},
+	}, got)
+}
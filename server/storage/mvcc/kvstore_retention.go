@@ -0,0 +1,233 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+)
+
+// PrefixRetention makes a compaction compact the keys with Prefix at Rev
+// rather than at the compaction revision.
+type PrefixRetention struct {
+	Prefix []byte
+	Rev    int64
+}
+
+// compactFloors maps every key to the oldest revision it can be read at. It
+// is piecewise constant: the keys from starts[i] up to starts[i+1] are
+// compacted at revs[i]. starts[0] is always the empty key, and a compaction
+// without prefix retentions has a single floor.
+type compactFloors struct {
+	starts [][]byte
+	revs   []int64
+}
+
+func uniformFloors(rev int64) compactFloors {
+	return compactFloors{starts: [][]byte{{}}, revs: []int64{rev}}
+}
+
+// newCompactFloors returns the floors of a compaction at rev that compacts
+// the keys under each prefix of retained at its own revision. A key under
+// overlapping prefixes is compacted at the lowest of their revisions, so that
+// the longest retention wins.
+func newCompactFloors(rev int64, retained []PrefixRetention) compactFloors {
+	bounds := [][]byte{{}}
+	for _, r := range retained {
+		bounds = append(bounds, r.Prefix)
+		if end := prefixEnd(r.Prefix); end != nil {
+			bounds = append(bounds, end)
+		}
+	}
+	// no boundary falls inside the range between two consecutive bounds, so
+	// all of its keys are under the same prefixes as its first key
+	var f compactFloors
+	for _, start := range sortedBounds(bounds) {
+		floor, matched := rev, false
+		for _, r := range retained {
+			if bytes.HasPrefix(start, r.Prefix) && (!matched || r.Rev < floor) {
+				floor, matched = r.Rev, true
+			}
+		}
+		f.append(start, floor)
+	}
+	return f
+}
+
+// prefixEnd returns the end of the range of the keys with prefix, or nil if
+// the range is open-ended.
+func prefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func sortedBounds(bounds [][]byte) [][]byte {
+	sort.Slice(bounds, func(i, j int) bool { return bytes.Compare(bounds[i], bounds[j]) < 0 })
+	sorted := bounds[:1]
+	for _, b := range bounds[1:] {
+		if !bytes.Equal(b, sorted[len(sorted)-1]) {
+			sorted = append(sorted, b)
+		}
+	}
+	return sorted
+}
+
+// append sets the floor of the keys from start on, merging it with the
+// previous floor if they are equal.
+func (f *compactFloors) append(start []byte, rev int64) {
+	if len(f.revs) > 0 && f.revs[len(f.revs)-1] == rev {
+		return
+	}
+	f.starts = append(f.starts, start)
+	f.revs = append(f.revs, rev)
+}
+
+func (f compactFloors) uniform() bool {
+	return len(f.revs) <= 1
+}
+
+// at returns the floor of key.
+func (f compactFloors) at(key []byte) int64 {
+	i := sort.Search(len(f.starts), func(i int) bool { return bytes.Compare(f.starts[i], key) > 0 })
+	return f.revs[i-1]
+}
+
+// rangeMax returns the highest floor of the keys in the range [key, end),
+// interpreted as in Range.
+func (f compactFloors) rangeMax(key, end []byte) int64 {
+	i := sort.Search(len(f.starts), func(i int) bool { return bytes.Compare(f.starts[i], key) > 0 })
+	max := f.revs[i-1]
+	if end == nil {
+		return max
+	}
+	for ; i < len(f.starts) && (len(end) == 0 || bytes.Compare(f.starts[i], end) < 0); i++ {
+		if f.revs[i] > max {
+			max = f.revs[i]
+		}
+	}
+	return max
+}
+
+func (f compactFloors) min() int64 {
+	min := f.revs[0]
+	for _, rev := range f.revs[1:] {
+		if rev < min {
+			min = rev
+		}
+	}
+	return min
+}
+
+func (f compactFloors) max() int64 {
+	max := f.revs[0]
+	for _, rev := range f.revs[1:] {
+		if rev > max {
+			max = rev
+		}
+	}
+	return max
+}
+
+// merge returns the higher of the floors of f and g for every key.
+func (f compactFloors) merge(g compactFloors) compactFloors {
+	var m compactFloors
+	for _, start := range sortedBounds(append(append([][]byte{}, f.starts...), g.starts...)) {
+		rev := f.at(start)
+		if grev := g.at(start); grev > rev {
+			rev = grev
+		}
+		m.append(start, rev)
+	}
+	return m
+}
+
+func (f compactFloors) equal(g compactFloors) bool {
+	if len(f.revs) != len(g.revs) {
+		return false
+	}
+	for i := range f.revs {
+		if f.revs[i] != g.revs[i] || !bytes.Equal(f.starts[i], g.starts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// marshal encodes every floor as its revision followed by the length and the
+// bytes of its first key.
+func (f compactFloors) marshal() []byte {
+	var buf []byte
+	for i := range f.revs {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(f.revs[i]))
+		buf = binary.AppendUvarint(buf, uint64(len(f.starts[i])))
+		buf = append(buf, f.starts[i]...)
+	}
+	return buf
+}
+
+func unmarshalCompactFloors(buf []byte) (compactFloors, error) {
+	var f compactFloors
+	for len(buf) > 0 {
+		if len(buf) < 8 {
+			return compactFloors{}, fmt.Errorf("truncated compaction floor revision")
+		}
+		rev := int64(binary.BigEndian.Uint64(buf))
+		buf = buf[8:]
+		n, l := binary.Uvarint(buf)
+		if l <= 0 || uint64(len(buf)-l) < n {
+			return compactFloors{}, fmt.Errorf("truncated compaction floor key")
+		}
+		f.starts = append(f.starts, bytes.Clone(buf[l:l+int(n)]))
+		f.revs = append(f.revs, rev)
+		buf = buf[l+int(n):]
+	}
+	if len(f.starts) == 0 || len(f.starts[0]) != 0 {
+		return compactFloors{}, fmt.Errorf("compaction floors do not start at the empty key")
+	}
+	return f, nil
+}
+
+// floors returns the compaction floors of the store. It must be called with
+// revMu held.
+func (s *store) floors() compactFloors {
+	if len(s.retention.revs) != 0 {
+		return s.retention
+	}
+	return uniformFloors(s.compactMainRev)
+}
+
+// CompactRetained compacts the store at rev like Compact, except for the
+// keys under the prefixes of retained, which are compacted at the revision of
+// their prefix instead. Where prefixes overlap, the lowest revision wins. The
+// revisions of a key can be read down to the revision it is compacted at, so
+// reading below it fails with ErrCompacted even though other keys may still
+// be read there.
+//
+// The compaction revision of a key never decreases: a key compacted at a
+// higher revision by an earlier compaction stays compacted there. It fails
+// with ErrCompacted if no key is compacted further.
+func (s *store) CompactRetained(trace *traceutil.Trace, rev int64, retained []PrefixRetention) (<-chan struct{}, error) {
+	return s.compactFloors(trace, newCompactFloors(rev, retained))
+}
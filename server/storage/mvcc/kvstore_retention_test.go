@@ -0,0 +1,204 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestCompactFloors(t *testing.T) {
+	f := newCompactFloors(10, []PrefixRetention{
+		{Prefix: []byte("/a"), Rev: 8},
+		{Prefix: []byte("/a/b"), Rev: 5},
+		{Prefix: []byte("/a/b/c"), Rev: 9},
+		{Prefix: []byte("/d"), Rev: 20},
+	})
+
+	for key, want := range map[string]int64{
+		"":       10,
+		"/":      10,
+		"/a":     8,
+		"/a/x":   8,
+		"/a/b":   5,
+		"/a/b/c": 5, // the longest retention wins
+		"/a/bb":  5,
+		"/b":     10,
+		"/d":     20,
+		"/d/x":   20,
+		"/e":     10,
+	} {
+		assert.Equal(t, want, f.at([]byte(key)), "floor of %q", key)
+	}
+	assert.Equal(t, int64(5), f.min())
+	assert.Equal(t, int64(20), f.max())
+
+	assert.Equal(t, int64(5), f.rangeMax([]byte("/a/b"), nil))
+	assert.Equal(t, int64(5), f.rangeMax([]byte("/a/b"), []byte("/a/c")))
+	assert.Equal(t, int64(8), f.rangeMax([]byte("/a"), []byte("/b")))
+	assert.Equal(t, int64(10), f.rangeMax([]byte("/a"), []byte("/c")))
+	assert.Equal(t, int64(20), f.rangeMax([]byte("/a"), []byte{}))
+
+	// a key is never compacted below its previous floor
+	m := f.merge(uniformFloors(9))
+	assert.Equal(t, int64(9), m.at([]byte("/a/b")))
+	assert.Equal(t, int64(10), m.at([]byte("/b")))
+	assert.Equal(t, int64(20), m.at([]byte("/d")))
+	assert.True(t, f.merge(uniformFloors(30)).equal(uniformFloors(30)))
+
+	u, err := unmarshalCompactFloors(f.marshal())
+	require.NoError(t, err)
+	assert.True(t, u.equal(f))
+	_, err = unmarshalCompactFloors(f.marshal()[:5])
+	assert.Error(t, err)
+}
+
+func TestStoreCompactRetained(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer b.Close()
+
+	for i := 0; i < 10; i++ {
+		v := []byte(fmt.Sprintf("v%d", i))
+		s.Put([]byte("/config/a"), v, lease.NoLease)
+		s.Put([]byte("/metrics/a"), v, lease.NoLease)
+		s.Put([]byte("/other"), v, lease.NoLease)
+	}
+	// /config/a is written at revisions 2, 5, ..., 29, /metrics/a at 3, 6,
+	// ..., 30 and /other at 4, 7, ..., 31
+
+	done, err := s.CompactRetained(traceutil.TODO(), 25, []PrefixRetention{
+		{Prefix: []byte("/config/"), Rev: 5},
+		{Prefix: []byte("/metrics/"), Rev: 28},
+	})
+	require.NoError(t, err)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for compaction to finish")
+	}
+
+	checkRetained := func(s *store) {
+		for _, tt := range []struct {
+			key, end  string
+			rev       int64
+			compacted bool
+		}{
+			{key: "/config/a", rev: 4, compacted: true},
+			{key: "/config/a", rev: 5},
+			{key: "/metrics/a", rev: 27, compacted: true},
+			{key: "/metrics/a", rev: 28},
+			{key: "/other", rev: 24, compacted: true},
+			{key: "/other", rev: 25},
+			{key: "/config/", end: "/config0", rev: 5},
+			{key: "/", end: "0", rev: 27, compacted: true},
+			{key: "/", end: "0", rev: 28},
+		} {
+			var end []byte
+			if tt.end != "" {
+				end = []byte(tt.end)
+			}
+			r, err := s.Range(context.TODO(), []byte(tt.key), end, RangeOptions{Rev: tt.rev})
+			if tt.compacted {
+				assert.ErrorIs(t, err, ErrCompacted, "range %q-%q at revision %d", tt.key, tt.end, tt.rev)
+				continue
+			}
+			require.NoError(t, err, "range %q-%q at revision %d", tt.key, tt.end, tt.rev)
+			require.NotEmpty(t, r.KVs)
+		}
+		// the revisions of a key above its floor are kept even though the
+		// backend is compacted up to the highest floor
+		for rev, want := range map[int64]string{7: "v1", 11: "v3", 26: "v8"} {
+			r, err := s.Range(context.TODO(), []byte("/config/a"), nil, RangeOptions{Rev: rev})
+			require.NoError(t, err)
+			assert.Equal(t, want, string(r.KVs[0].Value), "value at revision %d", rev)
+		}
+		r, err := s.Range(context.TODO(), []byte("/other"), nil, RangeOptions{Rev: 27})
+		require.NoError(t, err)
+		assert.Equal(t, "v7", string(r.KVs[0].Value))
+	}
+	checkRetained(s)
+
+	// nothing is compacted further
+	_, err = s.CompactRetained(traceutil.TODO(), 25, []PrefixRetention{
+		{Prefix: []byte("/config/"), Rev: 5},
+		{Prefix: []byte("/metrics/"), Rev: 20},
+	})
+	assert.ErrorIs(t, err, ErrCompacted)
+
+	// the retention survives a restart
+	require.NoError(t, s.Close())
+	s = NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	checkRetained(s)
+
+	// a compaction without retention compacts every key at the same revision
+	done, err = s.Compact(traceutil.TODO(), 29)
+	require.NoError(t, err)
+	<-done
+	_, err = s.Range(context.TODO(), []byte("/config/a"), nil, RangeOptions{Rev: 28})
+	assert.ErrorIs(t, err, ErrCompacted)
+	_, err = s.Range(context.TODO(), []byte("/metrics/a"), nil, RangeOptions{Rev: 29})
+	assert.NoError(t, err)
+	assert.True(t, s.retention.uniform())
+	require.NoError(t, s.Close())
+}
+
+func TestWatchableStoreCompactRetained(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	for i := 0; i < 10; i++ {
+		s.Put([]byte("/config/a"), []byte("v"), lease.NoLease)
+		s.Put([]byte("/metrics/a"), []byte("v"), lease.NoLease)
+	}
+	// /config/a is written at revisions 2, 4, ..., 20 and /metrics/a at 3,
+	// 5, ..., 21
+	done, err := s.CompactRetained(traceutil.TODO(), 15, []PrefixRetention{{Prefix: []byte("/config/"), Rev: 4}})
+	require.NoError(t, err)
+	<-done
+
+	w := s.NewWatchStream()
+	defer w.Close()
+	metricsID, err := w.Watch(0, []byte("/metrics/a"), nil, 10)
+	require.NoError(t, err)
+	_, err = w.Watch(0, []byte("/config/a"), nil, 10)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case resp := <-w.Chan():
+			if resp.WatchID == metricsID {
+				assert.Equal(t, int64(15), resp.CompactRevision)
+				continue
+			}
+			assert.Zero(t, resp.CompactRevision)
+			require.NotEmpty(t, resp.Events)
+			assert.Equal(t, int64(10), resp.Events[0].Kv.ModRevision)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for watch response")
+		}
+	}
+}
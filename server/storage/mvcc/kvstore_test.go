@@ -398,6 +398,7 @@ func TestStoreRestore(t *testing.T) {
 	}
 	b.tx.rangeRespc <- rangeResp{[][]byte{schema.FinishedCompactKeyName}, [][]byte{newTestRevBytes(revision{3, 0})}}
 	b.tx.rangeRespc <- rangeResp{[][]byte{schema.ScheduledCompactKeyName}, [][]byte{newTestRevBytes(revision{3, 0})}}
+	b.tx.rangeRespc <- rangeResp{nil, nil}
 
 	b.tx.rangeRespc <- rangeResp{[][]byte{putkey, delkey}, [][]byte{putkvb, delkvb}}
 	b.tx.rangeRespc <- rangeResp{nil, nil}
@@ -413,7 +414,9 @@ func TestStoreRestore(t *testing.T) {
 	wact := []testutil.Action{
 		{Name: "range", Params: []interface{}{schema.Meta, schema.FinishedCompactKeyName, []byte(nil), int64(0)}},
 		{Name: "range", Params: []interface{}{schema.Meta, schema.ScheduledCompactKeyName, []byte(nil), int64(0)}},
+		{Name: "range", Params: []interface{}{schema.Meta, schema.MetaCompactRetentionName, []byte(nil), int64(0)}},
 		{Name: "range", Params: []interface{}{schema.Key, newTestRevBytes(revision{1, 0}), newTestRevBytes(revision{math.MaxInt64, math.MaxInt64}), int64(restoreChunkKeys)}},
+		{Name: "range", Params: []interface{}{schema.Meta, schema.MetaPrefixQuotasName, []byte(nil), int64(0)}},
 	}
 	if g := b.tx.Action(); !reflect.DeepEqual(g, wact) {
 		t.Errorf("tx actions = %+v, want %+v", g, wact)
@@ -977,12 +980,16 @@ func (b *fakeBackend) BatchTx() backend.BatchTx
 func (b *fakeBackend) ReadTx() backend.ReadTx                                     { return b.tx }
 func (b *fakeBackend) ConcurrentReadTx() backend.ReadTx                           { return b.tx }
 func (b *fakeBackend) Hash(func(bucketName, keyName []byte) bool) (uint32, error) { return 0, nil }
+func (b *fakeBackend) Check(context.Context, int) ([]error, error)                { return nil, nil }
 func (b *fakeBackend) Size() int64                                                { return 0 }
 func (b *fakeBackend) SizeInUse() int64                                           { return 0 }
 func (b *fakeBackend) OpenReadTxN() int64                                         { return 0 }
 func (b *fakeBackend) Snapshot() backend.Snapshot                                 { return nil }
 func (b *fakeBackend) ForceCommit()                                               {}
 func (b *fakeBackend) Defrag() error                                              { return nil }
+func (b *fakeBackend) IncrementalDefrag(context.Context, int64) error             { return nil }
+func (b *fakeBackend) SetSpaceReclaim(bool)                                       {}
+func (b *fakeBackend) SpaceReclaim() (bool, int64)                                { return false, 0 }
 func (b *fakeBackend) Close() error                                               { return nil }
 func (b *fakeBackend) SetTxPostLockInsideApplyHook(func())                        {}
 
@@ -1010,7 +1017,7 @@ type fakeIndex struct {
 	indexCompactRespc     chan map[revision]struct{}
 }
 
-func (i *fakeIndex) Revisions(key, end []byte, atRev int64, limit int) ([]revision, int) {
+func (i *fakeIndex) Revisions(key, end []byte, atRev int64, limit int, reverse bool) ([]revision, int) {
 	_, rev := i.Range(key, end, atRev)
 	if len(rev) >= limit {
 		rev = rev[:limit]
@@ -1018,6 +1025,18 @@ func (i *fakeIndex) Revisions(key, end []byte, atRev int64, limit int) ([]revisi
 	return rev, len(rev)
 }
 
+func (i *fakeIndex) KeyRevisions(key, end []byte, atRev int64, limit int, reverse bool) ([]keyRevision, int) {
+	keys, revs := i.Range(key, end, atRev)
+	if len(revs) >= limit {
+		keys, revs = keys[:limit], revs[:limit]
+	}
+	keyRevs := make([]keyRevision, len(revs))
+	for j := range revs {
+		keyRevs[j] = keyRevision{key: keys[j], modified: revs[j]}
+	}
+	return keyRevs, len(keyRevs)
+}
+
 func (i *fakeIndex) CountRevisions(key, end []byte, atRev int64) int {
 	_, rev := i.Range(key, end, atRev)
 	return len(rev)
@@ -1033,7 +1052,11 @@ func (i *fakeIndex) Range(key, end []byte, atRev int64) ([][]byte, []revision) {
 	r := <-i.indexRangeRespc
 	return r.keys, r.revs
 }
-func (i *fakeIndex) Put(key []byte, rev revision) {
+func (i *fakeIndex) Keys(key, end []byte, atRev int64) [][]byte {
+	keys, _ := i.Range(key, end, atRev)
+	return keys
+}
+func (i *fakeIndex) Put(key []byte, rev revision, lease int64) {
 	i.Recorder.Record(testutil.Action{Name: "put", Params: []interface{}{key, rev}})
 }
 func (i *fakeIndex) Tombstone(key []byte, rev revision) error {
@@ -1049,10 +1072,38 @@ func (i *fakeIndex) Compact(rev int64) map[revision]struct{} {
 	i.Recorder.Record(testutil.Action{Name: "compact", Params: []interface{}{rev}})
 	return <-i.indexCompactRespc
 }
+func (i *fakeIndex) CompactFloors(floor func(key []byte) int64) map[revision]struct{} {
+	i.Recorder.Record(testutil.Action{Name: "compactFloors"})
+	return <-i.indexCompactRespc
+}
 func (i *fakeIndex) Keep(rev int64) map[revision]struct{} {
 	i.Recorder.Record(testutil.Action{Name: "keep", Params: []interface{}{rev}})
 	return <-i.indexCompactRespc
 }
+func (i *fakeIndex) NthRevision(key []byte, n int) (revision, bool) {
+	i.Recorder.Record(testutil.Action{Name: "nthRevision", Params: []interface{}{key, n}})
+	return revision{}, false
+}
+func (i *fakeIndex) HasRevision(key []byte, rev revision) bool {
+	i.Recorder.Record(testutil.Action{Name: "hasRevision", Params: []interface{}{key, rev}})
+	return false
+}
+func (i *fakeIndex) Histories(key []byte, limit int) ([][]byte, [][]revision) {
+	i.Recorder.Record(testutil.Action{Name: "histories", Params: []interface{}{key, limit}})
+	return nil, nil
+}
+func (i *fakeIndex) History(key, end []byte, atRev int64) []revision {
+	i.Recorder.Record(testutil.Action{Name: "history", Params: []interface{}{key, end, atRev}})
+	return nil
+}
+func (i *fakeIndex) KeysFrom(key []byte, atRev int64, limit int) []keyRevision {
+	i.Recorder.Record(testutil.Action{Name: "keysFrom", Params: []interface{}{key, atRev, limit}})
+	return nil
+}
+func (i *fakeIndex) CompactKey(key []byte, compactRev, atRev int64) (removed, tombstones []revision) {
+	i.Recorder.Record(testutil.Action{Name: "compactKey", Params: []interface{}{key, compactRev, atRev}})
+	return nil, nil
+}
 func (i *fakeIndex) Equal(b index) bool { return false }
 
 func (i *fakeIndex) Insert(ki *keyIndex) {
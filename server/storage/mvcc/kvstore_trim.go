@@ -0,0 +1,68 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"math"
+
+	"go.uber.org/zap"
+
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+)
+
+// TrimAfterRevision removes the revisions after rev from the key-value store
+// in be, so that the store restored from be is the store as of rev. It returns
+// ErrCompacted if any key of the store in be is compacted after rev. Only the key-value
+// store is trimmed; leases, auth and membership are kept as they are.
+func TrimAfterRevision(lg *zap.Logger, be backend.Backend, rev int64) error {
+	tx := be.BatchTx()
+	tx.LockOutsideApply()
+	defer tx.Unlock()
+
+	if scheduled, found := UnsafeReadScheduledCompact(tx); found && scheduled > rev {
+		return ErrCompacted
+	}
+	// the scheduled compaction is at the lowest floor of a compaction with
+	// prefix retentions, which compacted the other keys further
+	floors, err := unsafeReadCompactFloors(tx)
+	if err != nil {
+		return err
+	}
+	if len(floors.revs) != 0 && floors.max() > rev {
+		return ErrCompacted
+	}
+
+	min, max := newRevBytes(), newRevBytes()
+	revToBytes(revision{main: rev + 1}, min)
+	revToBytes(revision{main: math.MaxInt64, sub: math.MaxInt64}, max)
+	removed := 0
+	for {
+		keys, _ := tx.UnsafeRange(schema.Key, min, max, int64(restoreChunkKeys))
+		for _, key := range keys {
+			tx.UnsafeDelete(schema.Key, key)
+		}
+		removed += len(keys)
+		if len(keys) < restoreChunkKeys {
+			break
+		}
+	}
+	lg.Info(
+		"trimmed key-value store revisions",
+		zap.Int64("revision", rev),
+		zap.Int("removed-key-revisions", removed),
+	)
+	return nil
+}
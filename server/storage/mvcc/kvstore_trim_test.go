@@ -0,0 +1,83 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestTrimAfterRevision(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+
+	for i := 0; i < 10; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%d", i%3)), []byte(fmt.Sprintf("bar%d", i)), lease.NoLease)
+	}
+	s.DeleteRange([]byte("foo0"), nil)
+	done, err := s.Compact(traceutil.TODO(), 3)
+	require.NoError(t, err)
+	<-done
+	// revisions after 8 are trimmed, including the delete at 12
+	s.Put([]byte("foo3"), []byte("baz"), lease.NoLease)
+	hash, _, err := s.HashStorage().HashByRev(8)
+	require.NoError(t, err)
+	want, err := s.Range(context.TODO(), []byte("foo"), []byte("fop"), RangeOptions{Rev: 8})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	require.ErrorIs(t, TrimAfterRevision(zaptest.NewLogger(t), b, 2), ErrCompacted)
+	require.NoError(t, TrimAfterRevision(zaptest.NewLogger(t), b, 8))
+
+	s = NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+	assert.Equal(t, int64(8), s.Rev())
+	got, err := s.Range(context.TODO(), []byte("foo"), []byte("fop"), RangeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, want.KVs, got.KVs)
+	trimmedHash, _, err := s.HashStorage().HashByRev(8)
+	require.NoError(t, err)
+	assert.Equal(t, hash, trimmedHash)
+}
+
+// TestTrimAfterRevisionRetained ensures the revisions below the floor of a
+// key compacted further than a retained prefix cannot be trimmed to.
+func TestTrimAfterRevisionRetained(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+
+	for i := 0; i < 10; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%d", i%3)), []byte(fmt.Sprintf("bar%d", i)), lease.NoLease)
+		s.Put([]byte(fmt.Sprintf("keep%d", i%3)), []byte(fmt.Sprintf("bar%d", i)), lease.NoLease)
+	}
+	// the keys are compacted at 15, but those under keep at 5
+	done, err := s.CompactRetained(traceutil.TODO(), 15, []PrefixRetention{{Prefix: []byte("keep"), Rev: 5}})
+	require.NoError(t, err)
+	<-done
+	require.NoError(t, s.Close())
+
+	require.ErrorIs(t, TrimAfterRevision(zaptest.NewLogger(t), b, 10), ErrCompacted)
+	require.NoError(t, TrimAfterRevision(zaptest.NewLogger(t), b, 15))
+}
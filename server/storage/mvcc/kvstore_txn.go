@@ -70,6 +70,7 @@ func (tr *storeTxnCommon) Range(ctx context.Context, key, end []byte, ro RangeOp
 }
 
 func (tr *storeTxnCommon) rangeKeys(ctx context.Context, key, end []byte, curRev int64, ro RangeOptions) (*RangeResult, error) {
+	tr.s.hotKeys.recordRead(key)
 	rev := ro.Rev
 	if rev > curRev {
 		return &RangeResult{KVs: nil, Count: -1, Rev: curRev}, ErrFutureRev
@@ -80,12 +81,31 @@ func (tr *storeTxnCommon) rangeKeys(ctx context.Context, key, end []byte, curRev
 	if rev < tr.s.compactMainRev {
 		return &RangeResult{KVs: nil, Count: -1, Rev: 0}, ErrCompacted
 	}
+	if !tr.s.retention.uniform() && rev < tr.s.retention.rangeMax(key, end) {
+		return &RangeResult{KVs: nil, Count: -1, Rev: 0}, ErrCompacted
+	}
+	if tr.s.keyCompactions.compacted(key, end, rev) {
+		return &RangeResult{KVs: nil, Count: -1, Rev: 0}, ErrCompacted
+	}
 	if ro.Count {
 		total := tr.s.kvindex.CountRevisions(key, end, rev)
 		tr.trace.Step("count revisions from in-memory index tree")
 		return &RangeResult{KVs: nil, Count: total, Rev: curRev}, nil
 	}
-	revpairs, total := tr.s.kvindex.Revisions(key, end, rev, int(ro.Limit))
+	if ro.KeysOnly {
+		keyRevs, total := tr.s.kvindex.KeyRevisions(key, end, rev, int(ro.Limit), ro.Reverse)
+		tr.trace.Step("range keys from in-memory index tree")
+		kvs := make([]mvccpb.KeyValue, len(keyRevs))
+		for i, kr := range keyRevs {
+			kvs[i] = mvccpb.KeyValue{Key: kr.key, CreateRevision: kr.created.main, ModRevision: kr.modified.main, Version: kr.ver, Lease: kr.lease}
+			if !kr.latest {
+				// the index only knows the lease of the latest revision of a key
+				kvs[i].Lease = tr.s.unsafeKV(tr.tx, kr.modified).Lease
+			}
+		}
+		return &RangeResult{KVs: kvs, Count: total, Rev: curRev}, nil
+	}
+	revpairs, total := tr.s.kvindex.Revisions(key, end, rev, int(ro.Limit), ro.Reverse)
 	tr.trace.Step("range keys from in-memory index tree")
 	if len(revpairs) == 0 {
 		return &RangeResult{KVs: nil, Count: total, Rev: curRev}, nil
@@ -104,6 +124,12 @@ func (tr *storeTxnCommon) rangeKeys(ctx context.Context, key, end []byte, curRev
 			return nil, fmt.Errorf("rangeKeys: context cancelled: %w", ctx.Err())
 		default:
 		}
+		if end == nil {
+			if kv, ok := tr.s.readCache.get(key, revpair); ok {
+				kvs[i] = kv
+				continue
+			}
+		}
 		revToBytes(revpair, revBytes)
 		_, vs := tr.tx.UnsafeRange(schema.Key, revBytes, nil, 0)
 		if len(vs) != 1 {
@@ -126,6 +152,9 @@ func (tr *storeTxnCommon) rangeKeys(ctx context.Context, key, end []byte, curRev
 				zap.Error(err),
 			)
 		}
+		if end == nil {
+			tr.s.readCache.add(revpair, kvs[i])
+		}
 	}
 	tr.trace.Step("range keys from bolt db")
 	return &RangeResult{KVs: kvs, Count: total, Rev: curRev}, nil
@@ -168,6 +197,7 @@ func (tw *storeTxnWrite) Range(ctx context.Context, key, end []byte, ro RangeOpt
 }
 
 func (tw *storeTxnWrite) DeleteRange(key, end []byte) (int64, int64) {
+	tw.s.hotKeys.recordWrite(key)
 	if n := tw.deleteRange(key, end); n != 0 || len(tw.changes) > 0 {
 		return n, tw.beginRev + 1
 	}
@@ -175,6 +205,7 @@ func (tw *storeTxnWrite) DeleteRange(key, end []byte) (int64, int64) {
 }
 
 func (tw *storeTxnWrite) Put(key, value []byte, lease lease.LeaseID) int64 {
+	tw.s.hotKeys.recordWrite(key)
 	tw.put(key, value, lease)
 	return tw.beginRev + 1
 }
@@ -206,6 +237,7 @@ func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID) {
 		oldLease = tw.s.le.GetLease(lease.LeaseItem{Key: string(key)})
 		tw.trace.Step("get key's previous created_revision and leaseID")
 	}
+	tw.s.quotas.put(key, int64(len(key)+len(value)))
 	ibytes := newRevBytes()
 	idxRev := revision{main: rev, sub: int64(len(tw.changes))}
 	revToBytes(idxRev, ibytes)
@@ -230,7 +262,7 @@ func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID) {
 
 	tw.trace.Step("marshal mvccpb.KeyValue")
 	tw.tx.UnsafeSeqPut(schema.Key, ibytes, d)
-	tw.s.kvindex.Put(key, idxRev)
+	tw.s.kvindex.Put(key, idxRev, int64(leaseID))
 	tw.changes = append(tw.changes, kv)
 	tw.trace.Step("store kv pair into bolt db")
 
@@ -268,7 +300,8 @@ func (tw *storeTxnWrite) deleteRange(key, end []byte) int64 {
 	if len(tw.changes) > 0 {
 		rrev++
 	}
-	keys, _ := tw.s.kvindex.Range(key, end, rrev)
+	// only the index is consulted, values are not read from the backend.
+	keys := tw.s.kvindex.Keys(key, end, rrev)
 	if len(keys) == 0 {
 		return 0
 	}
@@ -279,6 +312,8 @@ func (tw *storeTxnWrite) deleteRange(key, end []byte) int64 {
 }
 
 func (tw *storeTxnWrite) delete(key []byte) {
+	tw.s.quotas.delete(key)
+
 	ibytes := newRevBytes()
 	idxRev := revision{main: tw.beginRev + 1, sub: int64(len(tw.changes))}
 	revToBytes(idxRev, ibytes)
@@ -321,3 +356,22 @@ func (tw *storeTxnWrite) delete(key []byte) {
 }
 
 func (tw *storeTxnWrite) Changes() []mvccpb.KeyValue { return tw.changes }
+
+// unsafeKV reads the key-value pair of the given revision from the backend.
+func (s *store) unsafeKV(tx backend.UnsafeReader, rev revision) mvccpb.KeyValue {
+	revBytes := newRevBytes()
+	revToBytes(rev, revBytes)
+	_, vs := tx.UnsafeRange(schema.Key, revBytes, nil, 0)
+	if len(vs) != 1 {
+		s.lg.Fatal(
+			"failed to find the key-value pair of a revision",
+			zap.Int64("revision-main", rev.main),
+			zap.Int64("revision-sub", rev.sub),
+		)
+	}
+	var kv mvccpb.KeyValue
+	if err := kv.Unmarshal(vs[0]); err != nil {
+		s.lg.Fatal("failed to unmarshal mvccpb.KeyValue", zap.Error(err))
+	}
+	return kv
+}
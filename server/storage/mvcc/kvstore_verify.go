@@ -0,0 +1,345 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+)
+
+var (
+	defaultVerifyBatchLimit    = 1000
+	defaultVerifyBatchInterval = 10 * time.Millisecond
+	// maxVerifyBoltErrors bounds the inconsistencies of the bbolt database
+	// reported by Verify, as a damaged database may have a lot of them.
+	maxVerifyBoltErrors = 100
+)
+
+// AnomalyType is the type of an inconsistency found by Verify.
+type AnomalyType int
+
+const (
+	// AnomalyBolt is an inconsistency of the bbolt database.
+	AnomalyBolt AnomalyType = iota
+	// AnomalyCorruptValue is a key-value pair of the backend that cannot be
+	// decoded, or that is not stored at its modification revision.
+	AnomalyCorruptValue
+	// AnomalyMissingInIndex is a revision of a key in the backend that is
+	// missing from the key index.
+	AnomalyMissingInIndex
+	// AnomalyMissingInBackend is a revision of a key in the key index that is
+	// missing from the backend.
+	AnomalyMissingInBackend
+	// AnomalyKeyMismatch is a revision of a key in the key index that holds
+	// another key in the backend.
+	AnomalyKeyMismatch
+)
+
+// Anomaly is an inconsistency found by Verify.
+type Anomaly struct {
+	Type AnomalyType
+	// Key, Revision and SubRevision locate the anomaly. They are unset for
+	// AnomalyBolt.
+	Key         []byte
+	Revision    int64
+	SubRevision int64
+	Detail      string
+}
+
+// VerifyOptions throttles Verify.
+type VerifyOptions struct {
+	// BatchLimit is the number of revisions checked between two pauses.
+	// Zero uses the default of 1000.
+	BatchLimit int
+	// BatchInterval is the pause between two batches. Zero uses the default
+	// of 10ms.
+	BatchInterval time.Duration
+}
+
+// Verify checks the consistency of the bbolt database, then that the key
+// index holds the same revisions of the keys as the backend. It returns the
+// revision the key index is checked at and the anomalies found.
+//
+// The bbolt database is checked on a copy, which needs the free space of
+// twice the database next to it. The key index is scanned in batches,
+// between which Verify pauses and releases the locks so that it does not
+// hold up the reads and writes. It returns the error of ctx once ctx is
+// done.
+func (s *store) Verify(ctx context.Context, opts VerifyOptions) (int64, []Anomaly, error) {
+	if opts.BatchLimit <= 0 {
+		opts.BatchLimit = defaultVerifyBatchLimit
+	}
+	if opts.BatchInterval <= 0 {
+		opts.BatchInterval = defaultVerifyBatchInterval
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	var anomalies []Anomaly
+	// the bbolt database is checked without the store locks
+	boltErrs, err := s.b.Check(ctx, maxVerifyBoltErrors)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, err := range boltErrs {
+		anomalies = append(anomalies, Anomaly{Type: AnomalyBolt, Detail: err.Error()})
+	}
+	boltN := len(anomalies)
+
+	v := &verifier{s: s, opts: opts}
+	s.startVerify(v)
+	defer s.finishVerify(v)
+	s.mu.RLock()
+	s.revMu.RLock()
+	v.rev = s.currentRev
+	s.revMu.RUnlock()
+	s.mu.RUnlock()
+
+	if err := v.verifyBackend(ctx); err != nil {
+		return 0, nil, err
+	}
+	if err := v.verifyIndex(ctx); err != nil {
+		return 0, nil, err
+	}
+
+	// a compaction running along the scan removes revisions from the index
+	// and the backend at different times, so the anomalies at the revisions
+	// it removed are dropped
+	s.mu.RLock()
+	for _, a := range v.anomalies {
+		if !v.compacted(a) && !s.keyCompactions.compacted(a.Key, nil, a.Revision) {
+			anomalies = append(anomalies, a)
+		}
+	}
+	s.mu.RUnlock()
+
+	sortAnomalies(anomalies[boltN:])
+	return v.rev, anomalies, nil
+}
+
+// sortAnomalies sorts the anomalies in the order of their revisions.
+func sortAnomalies(anomalies []Anomaly) {
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if a.Revision != b.Revision {
+			return a.Revision < b.Revision
+		}
+		return a.SubRevision < b.SubRevision
+	})
+}
+
+// compactedRevisions are the revisions removed from the backend by a
+// compaction: those up to the floor of their key that it does not keep.
+type compactedRevisions struct {
+	floors compactFloors
+	keep   map[revision]struct{}
+}
+
+func (c *compactedRevisions) removed(key []byte, rev revision) bool {
+	_, ok := c.keep[rev]
+	return rev.main <= c.floors.at(key) && !ok
+}
+
+// startCompaction records the revisions removed by the compaction starting,
+// for the scans of Verify in progress and the ones starting before it
+// finishes.
+func (s *store) startCompaction(c *compactedRevisions) {
+	s.verifyMu.Lock()
+	defer s.verifyMu.Unlock()
+	s.compacting = c
+	for v := range s.verifiers {
+		v.compactions = append(v.compactions, c)
+	}
+}
+
+func (s *store) finishCompaction() {
+	s.verifyMu.Lock()
+	defer s.verifyMu.Unlock()
+	s.compacting = nil
+}
+
+func (s *store) startVerify(v *verifier) {
+	s.verifyMu.Lock()
+	defer s.verifyMu.Unlock()
+	if s.verifiers == nil {
+		s.verifiers = make(map[*verifier]struct{})
+	}
+	s.verifiers[v] = struct{}{}
+	if s.compacting != nil {
+		v.compactions = append(v.compactions, s.compacting)
+	}
+}
+
+func (s *store) finishVerify(v *verifier) {
+	s.verifyMu.Lock()
+	defer s.verifyMu.Unlock()
+	delete(s.verifiers, v)
+}
+
+// verifier cross-validates the key index and the backend up to rev.
+type verifier struct {
+	s    *store
+	rev  int64
+	opts VerifyOptions
+
+	anomalies []Anomaly
+	// compactions are the compactions running along the scan. They are
+	// appended by the compactions, under the verifyMu of the store.
+	compactions []*compactedRevisions
+}
+
+// compacted returns whether the revision of a is removed by a compaction
+// running along the scan.
+func (v *verifier) compacted(a Anomaly) bool {
+	if a.Type == AnomalyBolt {
+		return false
+	}
+	v.s.verifyMu.Lock()
+	defer v.s.verifyMu.Unlock()
+	for _, c := range v.compactions {
+		if c.removed(a.Key, revision{main: a.Revision, sub: a.SubRevision}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *verifier) add(t AnomalyType, key []byte, rev revision, detail string) {
+	v.anomalies = append(v.anomalies, Anomaly{Type: t, Key: key, Revision: rev.main, SubRevision: rev.sub, Detail: detail})
+}
+
+// throttle pauses the scan between two batches.
+func (v *verifier) throttle(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(v.opts.BatchInterval):
+		return nil
+	}
+}
+
+// verifyBackend checks that the revisions of the keys in the backend are in
+// the key index.
+func (v *verifier) verifyBackend(ctx context.Context) error {
+	min, max := newRevBytes(), newRevBytes()
+	// the batches are ranges of revisions, as the ranges of a read tx with a
+	// limit may skip the revisions not committed yet
+	read := false
+	for start := int64(1); start <= v.rev; start += int64(v.opts.BatchLimit) {
+		// compacted ranges are skipped without pausing
+		if read {
+			if err := v.throttle(ctx); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := start + int64(v.opts.BatchLimit)
+		if end > v.rev+1 {
+			end = v.rev + 1
+		}
+		revToBytes(revision{main: start}, min)
+		revToBytes(revision{main: end}, max)
+
+		v.s.mu.RLock()
+		tx := v.s.b.ReadTx()
+		tx.RLock()
+		keys, vals := tx.UnsafeRange(schema.Key, min, max, 0)
+		for i, key := range keys {
+			v.verifyBackendKeyValue(key, vals[i])
+		}
+		tx.RUnlock()
+		v.s.mu.RUnlock()
+		read = len(keys) > 0
+	}
+	return nil
+}
+
+func (v *verifier) verifyBackendKeyValue(key, val []byte) {
+	if isKeyCompactionMarker(key) {
+		return
+	}
+	rev := bytesToRev(key[:revBytesLen])
+	var kv mvccpb.KeyValue
+	if err := kv.Unmarshal(val); err != nil {
+		v.add(AnomalyCorruptValue, nil, rev, fmt.Sprintf("cannot decode key-value pair: %v", err))
+		return
+	}
+	// tombstones only record the key
+	if !isTombstone(key) && kv.ModRevision != rev.main {
+		v.add(AnomalyCorruptValue, kv.Key, rev, fmt.Sprintf("key-value pair has modification revision %d", kv.ModRevision))
+		return
+	}
+	if !v.s.kvindex.HasRevision(kv.Key, rev) {
+		v.add(AnomalyMissingInIndex, kv.Key, rev, "revision is missing from the key index")
+	}
+}
+
+// verifyIndex checks that the revisions of the keys in the key index are in
+// the backend.
+func (v *verifier) verifyIndex(ctx context.Context) error {
+	from := []byte{}
+	for {
+		v.s.mu.RLock()
+		keys, revs := v.s.kvindex.Histories(from, v.opts.BatchLimit)
+		tx := v.s.b.ReadTx()
+		tx.RLock()
+		for i, key := range keys {
+			for _, rev := range revs[i] {
+				if rev.main <= v.rev {
+					v.verifyIndexRevision(tx, key, rev)
+				}
+			}
+		}
+		tx.RUnlock()
+		v.s.mu.RUnlock()
+
+		if len(keys) < v.opts.BatchLimit {
+			return nil
+		}
+		if err := v.throttle(ctx); err != nil {
+			return err
+		}
+		from = append(append([]byte{}, keys[len(keys)-1]...), 0)
+	}
+}
+
+func (v *verifier) verifyIndexRevision(tx backend.UnsafeReader, key []byte, rev revision) {
+	min, max := newRevBytes(), newRevBytes()
+	revToBytes(rev, min)
+	revToBytes(revision{main: rev.main, sub: rev.sub + 1}, max)
+	// the range also covers the revision marked as a tombstone
+	_, vals := tx.UnsafeRange(schema.Key, min, max, 1)
+	if len(vals) == 0 {
+		v.add(AnomalyMissingInBackend, key, rev, "revision is missing from the backend")
+		return
+	}
+	var kv mvccpb.KeyValue
+	if err := kv.Unmarshal(vals[0]); err != nil {
+		// reported by verifyBackend
+		return
+	}
+	if !bytes.Equal(kv.Key, key) {
+		v.add(AnomalyKeyMismatch, key, rev, fmt.Sprintf("revision holds key %q in the backend", kv.Key))
+	}
+}
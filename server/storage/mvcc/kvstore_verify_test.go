@@ -0,0 +1,137 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+// corruptIndex is a fault-injection hook that lets f alter the index entry of
+// key in place, as a bug or a memory corruption would.
+func corruptIndex(t *testing.T, s *store, key []byte, f func(ki *keyIndex)) {
+	ki := s.kvindex.KeyIndex(&keyIndex{key: key})
+	require.NotNil(t, ki)
+	f(ki)
+}
+
+func TestStoreVerify(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	for i := 0; i < 10; i++ {
+		s.Put([]byte(fmt.Sprintf("key%d", i)), []byte("v"), lease.NoLease) // rev 2-11
+	}
+	s.Put([]byte("foo"), []byte("1"), lease.NoLease) // rev 12
+	s.Put([]byte("foo"), []byte("2"), lease.NoLease) // rev 13
+	s.DeleteRange([]byte("key3"), nil)               // rev 14
+	s.Put([]byte("foo"), []byte("3"), lease.NoLease) // rev 15
+	_, err := s.Compact(traceutil.TODO(), 5)
+	require.NoError(t, err)
+	s.Put([]byte("foo"), []byte("4"), lease.NoLease) // rev 16
+
+	// small batches go through the pauses of the scan
+	opts := VerifyOptions{BatchLimit: 3}
+	rev, anomalies, err := s.Verify(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(16), rev)
+	assert.Empty(t, anomalies)
+
+	corruptIndex(t, s, []byte("foo"), func(ki *keyIndex) {
+		g := &ki.generations[len(ki.generations)-1]
+		require.Equal(t, revision{main: 13}, g.revs[1])
+		g.revs = append(g.revs[:1], g.revs[2:]...)
+	})
+	rev, anomalies, err = s.Verify(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(16), rev)
+	assert.Equal(t, []Anomaly{{
+		Type:     AnomalyMissingInIndex,
+		Key:      []byte("foo"),
+		Revision: 13,
+		Detail:   "revision is missing from the key index",
+	}}, anomalies)
+
+	// the compaction at 5 keeps the revision 2 of key0
+	corruptIndex(t, s, []byte("key0"), func(ki *keyIndex) {
+		g := &ki.generations[0]
+		require.Equal(t, []revision{{main: 2}}, g.revs)
+		g.revs = []revision{{main: 17}}
+	})
+	_, anomalies, err = s.Verify(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, []Anomaly{{
+		Type:     AnomalyMissingInIndex,
+		Key:      []byte("key0"),
+		Revision: 2,
+		Detail:   "revision is missing from the key index",
+	}, {
+		Type:     AnomalyMissingInIndex,
+		Key:      []byte("foo"),
+		Revision: 13,
+		Detail:   "revision is missing from the key index",
+	}}, anomalies)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = s.Verify(ctx, opts)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStoreVerifyCompaction(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b)
+
+	for i := 0; i < 100; i++ {
+		s.Put([]byte(fmt.Sprintf("key%d", i%10)), []byte("v"), lease.NoLease)
+	}
+
+	// the index is compacted before the backend, so the scan sees the
+	// revisions removed from the index only
+	c := &compactedRevisions{floors: uniformFloors(s.Rev()), keep: s.kvindex.Compact(s.Rev())}
+	s.startCompaction(c)
+	_, anomalies, err := s.Verify(context.Background(), VerifyOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, anomalies)
+	s.finishCompaction()
+
+	// the kept revisions are still verified
+	corruptIndex(t, s, []byte("key0"), func(ki *keyIndex) {
+		g := &ki.generations[0]
+		require.Equal(t, []revision{{main: 92}}, g.revs)
+		g.revs = []revision{{main: 102}}
+	})
+	s.startCompaction(c)
+	_, anomalies, err = s.Verify(context.Background(), VerifyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Anomaly{{
+		Type:     AnomalyMissingInIndex,
+		Key:      []byte("key0"),
+		Revision: 92,
+		Detail:   "revision is missing from the key index",
+	}}, anomalies)
+	s.finishCompaction()
+}
@@ -258,6 +258,22 @@ var (
 	reportCompactRevMu sync.RWMutex
 	reportCompactRev   = func() float64 { return 0 }
 
+	readCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "read_cache_hits_total",
+			Help:      "Total number of point gets served from the read cache.",
+		})
+
+	readCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "read_cache_misses_total",
+			Help:      "Total number of point gets not found in the read cache.",
+		})
+
 	totalPutSizeGauge = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Namespace: "etcd_debugging",
@@ -290,6 +306,8 @@ func init() {
 	prometheus.MustRegister(hashRevSec)
 	prometheus.MustRegister(currentRev)
 	prometheus.MustRegister(compactRev)
+	prometheus.MustRegister(readCacheHits)
+	prometheus.MustRegister(readCacheMisses)
 	prometheus.MustRegister(totalPutSizeGauge)
 }
 
@@ -0,0 +1,127 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"container/list"
+	"sync"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// readCache is a least recently used cache of the key-value pairs read by
+// point gets, bounded by the total size of the cached pairs. An entry is the
+// key-value pair of a key written at a revision, so a read only uses it if the
+// index resolves the key to that same revision: a stale entry is never
+// returned, it is only replaced. Writes and compactions drop the entries they
+// make stale to free their memory early.
+type readCache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	bytes   int64
+	entries map[string]*list.Element
+	// lru holds *readCacheEntry, the most recently used first.
+	lru *list.List
+}
+
+type readCacheEntry struct {
+	rev revision
+	kv  mvccpb.KeyValue
+}
+
+func (e *readCacheEntry) size() int64 { return int64(e.kv.Size()) }
+
+func newReadCache(maxBytes int64) *readCache {
+	return &readCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// get returns the key-value pair of key written at rev, if cached.
+func (c *readCache) get(key []byte, rev revision) (mvccpb.KeyValue, bool) {
+	if c == nil {
+		return mvccpb.KeyValue{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[string(key)]
+	if !ok || el.Value.(*readCacheEntry).rev != rev {
+		readCacheMisses.Inc()
+		return mvccpb.KeyValue{}, false
+	}
+	readCacheHits.Inc()
+	c.lru.MoveToFront(el)
+	return el.Value.(*readCacheEntry).kv, true
+}
+
+// add caches kv, the key-value pair written at rev, unless a later revision
+// of the key is cached already.
+func (c *readCache) add(rev revision, kv mvccpb.KeyValue) {
+	if c == nil {
+		return
+	}
+	e := &readCacheEntry{rev: rev, kv: kv}
+	if e.size() > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[string(kv.Key)]; ok {
+		if cached := el.Value.(*readCacheEntry).rev; cached == rev || cached.GreaterThan(rev) {
+			return
+		}
+		c.remove(el)
+	}
+	c.entries[string(kv.Key)] = c.lru.PushFront(e)
+	c.bytes += e.size()
+	for c.bytes > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// invalidate drops the entries of the keys of evs.
+func (c *readCache) invalidate(evs []mvccpb.Event) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ev := range evs {
+		if el, ok := c.entries[string(ev.Kv.Key)]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// reset drops every entry.
+func (c *readCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bytes = 0
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+func (c *readCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*readCacheEntry)
+	delete(c.entries, string(e.kv.Key))
+	c.bytes -= e.size()
+}
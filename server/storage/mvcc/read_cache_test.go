@@ -0,0 +1,174 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+)
+
+func TestReadCache(t *testing.T) {
+	kv := func(key, value string) mvccpb.KeyValue {
+		return mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}
+	}
+	size := int64((&readCacheEntry{kv: kv("a", "1")}).size())
+	c := newReadCache(2 * size)
+
+	c.add(revision{main: 2}, kv("a", "1"))
+	c.add(revision{main: 3}, kv("b", "1"))
+	_, ok := c.get([]byte("a"), revision{main: 2})
+	require.True(t, ok)
+	// another revision of a cached key is not a hit
+	_, ok = c.get([]byte("a"), revision{main: 4})
+	require.False(t, ok)
+
+	// the least recently used entry is evicted
+	c.add(revision{main: 4}, kv("c", "1"))
+	_, ok = c.get([]byte("b"), revision{main: 3})
+	require.False(t, ok)
+	_, ok = c.get([]byte("a"), revision{main: 2})
+	require.True(t, ok)
+
+	// an earlier revision does not replace a later one
+	c.add(revision{main: 5}, kv("a", "2"))
+	c.add(revision{main: 2}, kv("a", "1"))
+	got, ok := c.get([]byte("a"), revision{main: 5})
+	require.True(t, ok)
+	assert.Equal(t, "2", string(got.Value))
+
+	c.invalidate([]mvccpb.Event{{Kv: &mvccpb.KeyValue{Key: []byte("a")}}})
+	_, ok = c.get([]byte("a"), revision{main: 5})
+	require.False(t, ok)
+	assert.Equal(t, size, c.bytes)
+
+	// entries larger than the cache are not cached
+	c.add(revision{main: 6}, kv("d", "a value larger than the whole cache"))
+	_, ok = c.get([]byte("d"), revision{main: 6})
+	require.False(t, ok)
+}
+
+func TestStoreReadCache(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{ReadCacheBytes: 1024})
+	defer cleanup(s, b)
+
+	get := func(key string) *RangeResult {
+		r, err := s.Range(context.TODO(), []byte(key), nil, RangeOptions{})
+		require.NoError(t, err)
+		return r
+	}
+	cached := func(key string) bool {
+		s.readCache.mu.Lock()
+		defer s.readCache.mu.Unlock()
+		_, ok := s.readCache.entries[key]
+		return ok
+	}
+
+	s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+	require.Equal(t, "bar", string(get("foo").KVs[0].Value))
+	require.True(t, cached("foo"))
+	require.Equal(t, "bar", string(get("foo").KVs[0].Value))
+
+	// writes invalidate the key
+	s.Put([]byte("foo"), []byte("baz"), lease.NoLease)
+	require.False(t, cached("foo"))
+	require.Equal(t, "baz", string(get("foo").KVs[0].Value))
+
+	// historical reads are not confused with the cached revision
+	r, err := s.Range(context.TODO(), []byte("foo"), nil, RangeOptions{Rev: 2})
+	require.NoError(t, err)
+	require.Equal(t, "bar", string(r.KVs[0].Value))
+	require.Equal(t, "baz", string(get("foo").KVs[0].Value))
+
+	// deletes invalidate the key
+	s.DeleteRange([]byte("foo"), nil)
+	require.False(t, cached("foo"))
+	require.Empty(t, get("foo").KVs)
+
+	// compactions drop every entry
+	s.Put([]byte("foo"), []byte("qux"), lease.NoLease)
+	get("foo")
+	require.True(t, cached("foo"))
+	done, err := s.Compact(traceutil.TODO(), s.Rev())
+	require.NoError(t, err)
+	<-done
+	require.False(t, cached("foo"))
+	require.Equal(t, "qux", string(get("foo").KVs[0].Value))
+}
+
+// TestStoreReadCacheConcurrentWrites ensures the point gets of a key written
+// concurrently always return the value written at the returned revision, and
+// never go back to an earlier revision.
+func TestStoreReadCacheConcurrentWrites(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{ReadCacheBytes: 1024})
+	defer cleanup(s, b)
+
+	// the value of every put is the revision it is written at
+	s.Put([]byte("foo"), []byte("2"), lease.NoLease)
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		for rev := 3; rev < 1000; rev++ {
+			s.Put([]byte("foo"), []byte(strconv.Itoa(rev)), lease.NoLease)
+			if rev%100 == 0 {
+				s.Compact(traceutil.TODO(), int64(rev))
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var last int64
+			for {
+				select {
+				case <-donec:
+					return
+				default:
+				}
+				r, err := s.Range(context.TODO(), []byte("foo"), nil, RangeOptions{})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				kv := r.KVs[0]
+				if string(kv.Value) != strconv.FormatInt(kv.ModRevision, 10) {
+					t.Errorf("got value %q at revision %d", kv.Value, kv.ModRevision)
+					return
+				}
+				if kv.ModRevision < last {
+					t.Errorf("got revision %d after revision %d", kv.ModRevision, last)
+					return
+				}
+				last = kv.ModRevision
+			}
+		}()
+	}
+	wg.Wait()
+}
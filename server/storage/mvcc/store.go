@@ -58,3 +58,39 @@ func UnsafeSetFinishedCompact(tx backend.UnsafeWriter, value int64) {
 	revToBytes(revision{main: value}, rbytes)
 	tx.UnsafePut(schema.Meta, schema.FinishedCompactKeyName, rbytes)
 }
+
+// unsafeReadCompactFloors reads the compaction floors of a compaction with
+// prefix retentions. The floors are empty if the last compaction had none.
+func unsafeReadCompactFloors(tx backend.UnsafeReader) (compactFloors, error) {
+	_, floorsBytes := tx.UnsafeRange(schema.Meta, schema.MetaCompactRetentionName, nil, 0)
+	if len(floorsBytes) == 0 || len(floorsBytes[0]) == 0 {
+		return compactFloors{}, nil
+	}
+	return unmarshalCompactFloors(floorsBytes[0])
+}
+
+func setCompactFloors(tx backend.BatchTx, floors compactFloors) {
+	tx.LockInsideApply()
+	defer tx.Unlock()
+	if len(floors.revs) == 0 {
+		tx.UnsafeDelete(schema.Meta, schema.MetaCompactRetentionName)
+		return
+	}
+	tx.UnsafePut(schema.Meta, schema.MetaCompactRetentionName, floors.marshal())
+}
+
+func unsafeReadPrefixQuotas(tx backend.UnsafeReader) ([]PrefixQuota, error) {
+	_, quotasBytes := tx.UnsafeRange(schema.Meta, schema.MetaPrefixQuotasName, nil, 0)
+	if len(quotasBytes) == 0 {
+		return nil, nil
+	}
+	return unmarshalPrefixQuotas(quotasBytes[0])
+}
+
+func unsafeSetPrefixQuotas(tx backend.UnsafeWriter, quotas []PrefixQuota) {
+	if len(quotas) == 0 {
+		tx.UnsafeDelete(schema.Meta, schema.MetaPrefixQuotasName)
+		return
+	}
+	tx.UnsafePut(schema.Meta, schema.MetaPrefixQuotasName, marshalPrefixQuotas(quotas))
+}
@@ -15,6 +15,7 @@
 package testutil
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -146,3 +147,46 @@ func CorruptBBolt(fpath string) error {
 		return nil
 	})
 }
+
+// CorruptBBoltRange changes the values of the keys in the range [key, end) in
+// the backend at fpath, leaving the other keys intact. An empty end corrupts
+// the single key.
+func CorruptBBoltRange(fpath string, key, end []byte) error {
+	db, derr := bbolt.Open(fpath, os.ModePerm, &bbolt.Options{})
+	if derr != nil {
+		return derr
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("key"))
+		if b == nil {
+			return errors.New("got nil bucket for 'key'")
+		}
+		var vals [][]byte
+		var keys [][]byte
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var kv mvccpb.KeyValue
+			if uerr := kv.Unmarshal(v); uerr != nil {
+				return uerr
+			}
+			if bytes.Compare(kv.Key, key) < 0 || (len(end) == 0 && !bytes.Equal(kv.Key, key)) || (len(end) != 0 && bytes.Compare(kv.Key, end) >= 0) {
+				continue
+			}
+			kv.Value = append(kv.Value, '!')
+			v2, v2err := kv.Marshal()
+			if v2err != nil {
+				return v2err
+			}
+			keys = append(keys, k)
+			vals = append(vals, v2)
+		}
+		for i := range keys {
+			if perr := b.Put(keys[i], vals[i]); perr != nil {
+				return perr
+			}
+		}
+		return nil
+	})
+}
@@ -15,6 +15,7 @@
 package mvcc
 
 import (
+	"bytes"
 	"sync"
 	"time"
 
@@ -40,7 +41,7 @@ var (
 )
 
 type watchable interface {
-	watch(key, end []byte, startRev int64, id WatchID, ch chan<- WatchResponse, fcs ...FilterFunc) (*watcher, cancelFunc)
+	watch(key, end []byte, ranges []WatchRange, startRev int64, id WatchID, ch chan<- WatchResponse, notifyCaughtUp bool, fcs ...FilterFunc) (*watcher, cancelFunc)
 	progress(w *watcher)
 	progressAll(watchers map[WatchID]*watcher) bool
 	rev() int64
@@ -66,6 +67,11 @@ type watchableStore struct {
 
 	stopc chan struct{}
 	wg    sync.WaitGroup
+
+	// connStreamsMu protects connStreams, the number of watch streams open
+	// on each connection.
+	connStreamsMu sync.Mutex
+	connStreams   map[string]int
 }
 
 // cancelFunc updates unsynced and synced maps when running
@@ -86,6 +92,8 @@ func newWatchableStore(lg *zap.Logger, b backend.Backend, le lease.Lessor, cfg S
 		unsynced: newWatcherGroup(),
 		synced:   newWatcherGroup(),
 		stopc:    make(chan struct{}),
+
+		connStreams: make(map[string]int),
 	}
 	s.store.ReadView = &readView{s}
 	s.store.WriteView = &writeView{s}
@@ -115,18 +123,46 @@ func (s *watchableStore) NewWatchStream() WatchStream {
 	}
 }
 
-func (s *watchableStore) watch(key, end []byte, startRev int64, id WatchID, ch chan<- WatchResponse, fcs ...FilterFunc) (*watcher, cancelFunc) {
+func (s *watchableStore) NewConnWatchStream(conn string) (WatchStream, error) {
+	limit := s.store.cfg.MaxWatchStreamsPerConn
+	if limit <= 0 {
+		return s.NewWatchStream(), nil
+	}
+
+	s.connStreamsMu.Lock()
+	if s.connStreams[conn] >= limit {
+		s.connStreamsMu.Unlock()
+		return nil, ErrTooManyWatchStreams
+	}
+	s.connStreams[conn]++
+	s.connStreamsMu.Unlock()
+
+	ws := s.NewWatchStream().(*watchStream)
+	ws.release = func() {
+		s.connStreamsMu.Lock()
+		defer s.connStreamsMu.Unlock()
+		if s.connStreams[conn]--; s.connStreams[conn] == 0 {
+			delete(s.connStreams, conn)
+		}
+	}
+	return ws, nil
+}
+
+func (s *watchableStore) watch(key, end []byte, ranges []WatchRange, startRev int64, id WatchID, ch chan<- WatchResponse, notifyCaughtUp bool, fcs ...FilterFunc) (*watcher, cancelFunc) {
 	wa := &watcher{
-		key:    key,
-		end:    end,
-		minRev: startRev,
-		id:     id,
-		ch:     ch,
-		fcs:    fcs,
+		key:            key,
+		end:            end,
+		ranges:         ranges,
+		minRev:         startRev,
+		id:             id,
+		ch:             ch,
+		fcs:            fcs,
+		notifyCaughtUp: notifyCaughtUp,
 	}
 
 	s.mu.Lock()
 	s.revMu.RLock()
+	wa.caughtUpRev = s.store.currentRev
 	synced := startRev > s.store.currentRev || startRev == 0
 	if synced {
 		wa.minRev = s.store.currentRev + 1
@@ -134,6 +170,7 @@ func (s *watchableStore) watch(key, end []byte, startRev int64, id WatchID, ch c
 			wa.minRev = startRev
 		}
 		s.synced.add(wa)
+		s.sendCaughtUp(wa)
 	} else {
 		slowWatcherGauge.Inc()
 		s.unsynced.add(wa)
@@ -284,7 +321,9 @@ func (s *watchableStore) moveVictims() (moved int) {
 		for w, eb := range wb {
 			// watcher has observed the store up to, but not including, w.minRev
 			rev := w.minRev - 1
-			if w.send(WatchResponse{WatchID: w.id, Events: eb.evs, Revision: rev}) {
+			// only a pending caught up notification leaves a victim without events
+			caughtUp := len(eb.evs) == 0
+			if w.send(WatchResponse{WatchID: w.id, Events: eb.evs, Revision: rev, CaughtUp: caughtUp}) {
 				pendingEventsGauge.Add(float64(len(eb.evs)))
 			} else {
 				if newVictim == nil {
@@ -306,6 +345,9 @@ func (s *watchableStore) moveVictims() (moved int) {
 				continue
 			}
 			w.victim = false
+			if len(eb.evs) == 0 {
+				w.notifyCaughtUp = false
+			}
 			if eb.moreRev != 0 {
 				w.minRev = eb.moreRev
 			}
@@ -314,6 +356,7 @@ func (s *watchableStore) moveVictims() (moved int) {
 			} else {
 				slowWatcherGauge.Dec()
 				s.synced.add(w)
+				s.sendCaughtUp(w)
 			}
 		}
 		s.store.revMu.RUnlock()
@@ -349,9 +392,9 @@ func (s *watchableStore) syncWatchers() int {
 	// find min revision index, and these revisions can be used to
 	// query the backend store of key-value pairs
 	curRev := s.store.currentRev
-	compactionRev := s.store.compactMainRev
+	floors := s.store.floors()
 
-	wg, minRev := s.unsynced.choose(maxWatchersPerSync, curRev, compactionRev)
+	wg, minRev := s.unsynced.choose(maxWatchersPerSync, curRev, floors, &s.store.keyCompactions)
 	minBytes, maxBytes := newRevBytes(), newRevBytes()
 	revToBytes(revision{main: minRev}, minBytes)
 	revToBytes(revision{main: curRev + 1}, maxBytes)
@@ -361,7 +404,7 @@ func (s *watchableStore) syncWatchers() int {
 	tx := s.store.b.ReadTx()
 	tx.RLock()
 	revs, vs := tx.UnsafeRange(schema.Key, minBytes, maxBytes, 0)
-	evs := kvsToEvents(s.store.lg, wg, revs, vs)
+	evs := kvsToEvents(s.store.lg, wg.contains, revs, vs)
 	// Must unlock after kvsToEvents, because vs (come from boltdb memory) is not deep copy.
 	// We can only unlock after Unmarshal, which will do deep copy.
 	// Otherwise we will trigger SIGSEGV during boltdb re-mmap.
@@ -377,6 +420,7 @@ func (s *watchableStore) syncWatchers() int {
 			// bring un-notified watcher to synced
 			s.synced.add(w)
 			s.unsynced.delete(w)
+			s.sendCaughtUp(w)
 			continue
 		}
 
@@ -400,6 +444,7 @@ func (s *watchableStore) syncWatchers() int {
 			s.synced.add(w)
 		}
 		s.unsynced.delete(w)
+		s.sendCaughtUp(w)
 	}
 	s.addVictim(victims)
 
@@ -413,14 +458,18 @@ func (s *watchableStore) syncWatchers() int {
 }
 
 // kvsToEvents gets all events for the watchers from all key-value pairs
-func kvsToEvents(lg *zap.Logger, wg *watcherGroup, revs, vals [][]byte) (evs []mvccpb.Event) {
+func kvsToEvents(lg *zap.Logger, contains func(key string) bool, revs, vals [][]byte) (evs []mvccpb.Event) {
 	for i, v := range vals {
+		// key compaction markers are not events
+		if isKeyCompactionMarker(revs[i]) {
+			continue
+		}
 		var kv mvccpb.KeyValue
 		if err := kv.Unmarshal(v); err != nil {
 			lg.Panic("failed to unmarshal mvccpb.KeyValue", zap.Error(err))
 		}
 
-		if !wg.contains(string(kv.Key)) {
+		if !contains(string(kv.Key)) {
 			continue
 		}
 
@@ -438,6 +487,7 @@ func kvsToEvents(lg *zap.Logger, wg *watcherGroup, revs, vals [][]byte) (evs []m
 // notify notifies the fact that given event at the given rev just happened to
 // watchers that watch on the key of the event.
 func (s *watchableStore) notify(rev int64, evs []mvccpb.Event) {
+	s.store.readCache.invalidate(evs)
 	victim := make(watcherBatch)
 	for w, eb := range newWatcherBatch(&s.synced, evs) {
 		if eb.revs != 1 {
@@ -463,6 +513,23 @@ func (s *watchableStore) notify(rev int64, evs []mvccpb.Event) {
 	s.addVictim(victim)
 }
 
+// sendCaughtUp sends the caught up notification to a synced watcher waiting
+// for it. If the watcher channel is full, the watcher becomes a victim so that
+// syncVictimsLoop retries the notification.
+func (s *watchableStore) sendCaughtUp(w *watcher) {
+	if w.victim || !w.notifyCaughtUp || w.minRev <= w.caughtUpRev {
+		return
+	}
+	if w.send(WatchResponse{WatchID: w.id, Revision: w.minRev - 1, CaughtUp: true}) {
+		w.notifyCaughtUp = false
+		return
+	}
+	w.victim = true
+	s.synced.delete(w)
+	slowWatcherGauge.Inc()
+	s.addVictim(watcherBatch{w: &eventBatch{}})
+}
+
 func (s *watchableStore) addVictim(victim watcherBatch) {
 	if len(victim) == 0 {
 		return
@@ -513,6 +580,8 @@ type watcher struct {
 	// end indicates the end of the range to watch.
 	// If end is set, the watcher is on a range.
 	end []byte
+	// ranges are the ranges watched in addition to [key, end).
+	ranges []WatchRange
 
 	// victim is set when ch is blocked and undergoing victim processing
 	victim bool
@@ -530,7 +599,14 @@ type watcher struct {
 
 	// minRev is the minimum revision update the watcher will accept
 	minRev int64
-	id     WatchID
+
+	// notifyCaughtUp is set while the watcher waits to be notified that it has
+	// delivered all events up to caughtUpRev, the store revision at the time
+	// the watcher was created.
+	notifyCaughtUp bool
+	caughtUpRev    int64
+
+	id WatchID
 
 	fcs []FilterFunc
 	// a chan to send out the watch response.
@@ -541,17 +617,21 @@ type watcher struct {
 func (w *watcher) send(wr WatchResponse) bool {
 	progressEvent := len(wr.Events) == 0
 
+	if len(w.ranges) != 0 {
+		ne := make([]mvccpb.Event, 0, len(wr.Events))
+		for _, ev := range wr.Events {
+			if i, ok := w.rangeIndex(ev); ok {
+				ev.RangeIndex = int32(i)
+				ne = append(ne, ev)
+			}
+		}
+		wr.Events = ne
+	}
+
 	if len(w.fcs) != 0 {
 		ne := make([]mvccpb.Event, 0, len(wr.Events))
 		for i := range wr.Events {
-			filtered := false
-			for _, filter := range w.fcs {
-				if filter(wr.Events[i]) {
-					filtered = true
-					break
-				}
-			}
-			if !filtered {
+			if !filtered(wr.Events[i], w.fcs) {
 				ne = append(ne, wr.Events[i])
 			}
 		}
@@ -569,3 +649,55 @@ func (w *watcher) send(wr WatchResponse) bool {
 		return false
 	}
 }
+
+// rangeIndex returns the index of the first range of the watcher that matches
+// the key of ev and whose filters keep ev: 0 for [key, end), and i for the
+// i-th additional range.
+func (w *watcher) rangeIndex(ev mvccpb.Event) (int, bool) {
+	if inRange(ev.Kv.Key, w.key, w.end) {
+		return 0, true
+	}
+	for i, r := range w.ranges {
+		if inRange(ev.Kv.Key, r.Key, r.End) && !filtered(ev, r.Filters) {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// compactRev returns the highest compaction floor of the ranges of the
+// watcher, or the compaction revision of a key of the ranges compacted above
+// the minimum revision of the watcher if it is higher.
+func (w *watcher) compactRev(floors compactFloors, kc *keyCompactions) int64 {
+	rev := floors.rangeMax(w.key, w.end)
+	if r := kc.compactRev(w.key, w.end, w.minRev); r > rev {
+		rev = r
+	}
+	for _, r := range w.ranges {
+		if r := floors.rangeMax(r.Key, r.End); r > rev {
+			rev = r
+		}
+		if r := kc.compactRev(r.Key, r.End, w.minRev); r > rev {
+			rev = r
+		}
+	}
+	return rev
+}
+
+// inRange reports whether k is in the range [key, end), interpreted as in
+// Watch.
+func inRange(k, key, end []byte) bool {
+	if end == nil {
+		return bytes.Equal(k, key)
+	}
+	return bytes.Compare(k, key) >= 0 && (len(end) == 0 || bytes.Compare(k, end) < 0)
+}
+
+func filtered(ev mvccpb.Event, fcs []FilterFunc) bool {
+	for _, filter := range fcs {
+		if filter(ev) {
+			return true
+		}
+	}
+	return false
+}
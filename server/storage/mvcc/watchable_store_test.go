@@ -49,6 +49,40 @@ func TestWatch(t *testing.T) {
 	}
 }
 
+func TestNewConnWatchStreamLimit(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{MaxWatchStreamsPerConn: 2})
+	defer cleanup(s, b)
+
+	var streams []WatchStream
+	for i := 0; i < 2; i++ {
+		w, err := s.NewConnWatchStream("a")
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		streams = append(streams, w)
+	}
+	if _, err := s.NewConnWatchStream("a"); err != ErrTooManyWatchStreams {
+		t.Fatalf("err = %v, want %v", err, ErrTooManyWatchStreams)
+	}
+	// the limit applies to each connection separately
+	w, err := s.NewConnWatchStream("b")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w.Close()
+
+	streams[0].Close()
+	if w, err = s.NewConnWatchStream("a"); err != nil {
+		t.Fatalf("unexpected error %v after closing a stream", err)
+	}
+	w.Close()
+	streams[1].Close()
+	if n := len(s.connStreams); n != 0 {
+		t.Errorf("len(connStreams) = %d, want 0 after closing all streams", n)
+	}
+}
+
 func TestNewWatcherCancel(t *testing.T) {
 	b, _ := betesting.NewDefaultTmpBackend(t)
 	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
@@ -423,6 +457,100 @@ func TestWatchBatchUnsynced(t *testing.T) {
 	}
 }
 
+// TestWatchCaughtUpNotifyUnsynced ensures a watcher created in the past is
+// notified exactly once that it caught up, after its backlog was delivered.
+func TestWatchCaughtUpNotifyUnsynced(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+
+	oldMaxRevs := watchBatchMaxRevs
+	defer func() {
+		watchBatchMaxRevs = oldMaxRevs
+		cleanup(s, b)
+	}()
+	batches := 3
+	watchBatchMaxRevs = 4
+
+	v := []byte("foo")
+	for i := 0; i < watchBatchMaxRevs*batches; i++ {
+		s.Put(v, v, lease.NoLease)
+	}
+	createRev := s.Rev()
+
+	w := s.NewWatchStream()
+	defer w.Close()
+
+	w.WatchWithCaughtUpNotify(0, v, nil, 1)
+	for i := 0; i < batches; i++ {
+		resp := <-w.Chan()
+		if resp.CaughtUp {
+			t.Fatalf("#%d: unexpected caught up notification before the backlog was delivered", i)
+		}
+		if len(resp.Events) != watchBatchMaxRevs {
+			t.Fatalf("len(events) = %d, want %d", len(resp.Events), watchBatchMaxRevs)
+		}
+	}
+
+	resp := <-w.Chan()
+	if !resp.CaughtUp || len(resp.Events) != 0 {
+		t.Fatalf("expected caught up notification without events, got %+v", resp)
+	}
+	if resp.Revision != createRev {
+		t.Errorf("revision = %d, want %d", resp.Revision, createRev)
+	}
+
+	s.Put(v, v, lease.NoLease)
+	resp = <-w.Chan()
+	if resp.CaughtUp || len(resp.Events) != 1 {
+		t.Fatalf("expected a single event, got %+v", resp)
+	}
+	select {
+	case resp = <-w.Chan():
+		t.Fatalf("unexpected response %+v", resp)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// TestWatchCaughtUpNotifySynced ensures a watcher created at the current
+// revision is notified right away, and that the notification is retried
+// if the watch channel is full.
+func TestWatchCaughtUpNotifySynced(t *testing.T) {
+	oldChanBufLen := chanBufLen
+
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+
+	defer func() {
+		cleanup(s, b)
+		chanBufLen = oldChanBufLen
+	}()
+	chanBufLen = 1
+
+	testKey, testValue := []byte("foo"), []byte("bar")
+	s.Put(testKey, testValue, lease.NoLease)
+
+	w := s.NewWatchStream()
+	defer w.Close()
+
+	// fill the watch channel so the notification cannot be sent at creation
+	id, _ := w.Watch(0, testKey, nil, 0)
+	s.Put(testKey, testValue, lease.NoLease)
+	createRev := s.Rev()
+	caughtUpID, _ := w.WatchWithCaughtUpNotify(0, testKey, nil, 0)
+
+	resp := <-w.Chan()
+	if resp.WatchID != id || len(resp.Events) != 1 {
+		t.Fatalf("expected the event of watcher %d, got %+v", id, resp)
+	}
+	resp = <-w.Chan()
+	if resp.WatchID != caughtUpID || !resp.CaughtUp {
+		t.Fatalf("expected caught up notification of watcher %d, got %+v", caughtUpID, resp)
+	}
+	if resp.Revision != createRev {
+		t.Errorf("revision = %d, want %d", resp.Revision, createRev)
+	}
+}
+
 func TestNewMapwatcherToEventMap(t *testing.T) {
 	k0, k1, k2 := []byte("foo0"), []byte("foo1"), []byte("foo2")
 	v0, v1, v2 := []byte("bar0"), []byte("bar1"), []byte("bar2")
@@ -27,6 +27,9 @@ var (
 	ErrWatcherNotExist    = errors.New("mvcc: watcher does not exist")
 	ErrEmptyWatcherRange  = errors.New("mvcc: watcher range is empty")
 	ErrWatcherDuplicateID = errors.New("mvcc: duplicate watch ID provided on the WatchStream")
+	// ErrTooManyWatchStreams is returned by NewConnWatchStream if the
+	// connection already has the maximum number of watch streams open.
+	ErrTooManyWatchStreams = errors.New("mvcc: too many watch streams on connection")
 )
 
 type WatchID int64
@@ -34,6 +37,14 @@ type WatchID int64
 // FilterFunc returns true if the given event should be filtered out.
 type FilterFunc func(e mvccpb.Event) bool
 
+// WatchRange is a key range watched by a watcher on several ranges, along
+// with the filters of the events of the range.
+type WatchRange struct {
+	// Key and End are the range [Key, End), interpreted as in Watch.
+	Key, End []byte
+	Filters  []FilterFunc
+}
+
 type WatchStream interface {
 	// Watch creates a watcher. The watcher watches the events happening or
 	// happened on the given key or range [key, end) from the given startRev.
@@ -47,6 +58,18 @@ type WatchStream interface {
 	// an auto-generated watch ID is returned.
 	Watch(id WatchID, key, end []byte, startRev int64, fcs ...FilterFunc) (WatchID, error)
 
+	// WatchWithCaughtUpNotify creates a watcher like Watch. Once the watcher has
+	// delivered all events up to the current revision of the store, it also
+	// receives a single response with no events and CaughtUp set.
+	WatchWithCaughtUpNotify(id WatchID, key, end []byte, startRev int64, fcs ...FilterFunc) (WatchID, error)
+
+	// WatchRanges creates a watcher like Watch, or like WatchWithCaughtUpNotify
+	// if notifyCaughtUp is set, that also watches the given ranges. Every event
+	// is delivered once, with RangeIndex set to the index of the first range
+	// matching its key whose filters keep it: 0 for [key, end), and i for
+	// ranges[i-1]. The filters fcs apply to the events of every range.
+	WatchRanges(id WatchID, key, end []byte, ranges []WatchRange, startRev int64, notifyCaughtUp bool, fcs ...FilterFunc) (WatchID, error)
+
 	// Chan returns a chan. All watch response will be sent to the returned chan.
 	Chan() <-chan WatchResponse
 
@@ -92,6 +115,11 @@ type WatchResponse struct {
 
 	// CompactRevision is set when the watcher is cancelled due to compaction.
 	CompactRevision int64
+
+	// CaughtUp is set when the watcher has delivered all events up to the
+	// store revision at the time it was created. The response contains no
+	// events, and Revision is the revision the watcher has observed up to.
+	CaughtUp bool
 }
 
 // watchStream contains a collection of watchers that share
@@ -106,15 +134,39 @@ type watchStream struct {
 	closed   bool
 	cancels  map[WatchID]cancelFunc
 	watchers map[WatchID]*watcher
+	// release, if set, is called on Close to stop counting the stream
+	// against its connection.
+	release func()
 }
 
 // Watch creates a new watcher in the stream and returns its WatchID.
 func (ws *watchStream) Watch(id WatchID, key, end []byte, startRev int64, fcs ...FilterFunc) (WatchID, error) {
+	return ws.watch(id, key, end, nil, startRev, false, fcs...)
+}
+
+// WatchWithCaughtUpNotify creates a new watcher in the stream that is notified
+// once it caught up with the store, and returns its WatchID.
+func (ws *watchStream) WatchWithCaughtUpNotify(id WatchID, key, end []byte, startRev int64, fcs ...FilterFunc) (WatchID, error) {
+	return ws.watch(id, key, end, nil, startRev, true, fcs...)
+}
+
+// WatchRanges creates a new watcher in the stream on several ranges and
+// returns its WatchID.
+func (ws *watchStream) WatchRanges(id WatchID, key, end []byte, ranges []WatchRange, startRev int64, notifyCaughtUp bool, fcs ...FilterFunc) (WatchID, error) {
+	return ws.watch(id, key, end, ranges, startRev, notifyCaughtUp, fcs...)
+}
+
+func (ws *watchStream) watch(id WatchID, key, end []byte, ranges []WatchRange, startRev int64, notifyCaughtUp bool, fcs ...FilterFunc) (WatchID, error) {
 	// prevent wrong range where key >= end lexicographically
 	// watch request with 'WithFromKey' has empty-byte range end
 	if len(end) != 0 && bytes.Compare(key, end) != -1 {
 		return -1, ErrEmptyWatcherRange
 	}
+	for _, r := range ranges {
+		if len(r.End) != 0 && bytes.Compare(r.Key, r.End) != -1 {
+			return -1, ErrEmptyWatcherRange
+		}
+	}
 
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
@@ -132,7 +184,7 @@ func (ws *watchStream) Watch(id WatchID, key, end []byte, startRev int64, fcs ..
 		return -1, ErrWatcherDuplicateID
 	}
 
-	w, c := ws.watchable.watch(key, end, startRev, id, ws.ch, fcs...)
+	w, c := ws.watchable.watch(key, end, ranges, startRev, id, ws.ch, notifyCaughtUp, fcs...)
 
 	ws.cancels[id] = c
 	ws.watchers[id] = w
@@ -178,6 +230,9 @@ func (ws *watchStream) Close() {
 	ws.closed = true
 	close(ws.ch)
 	watchStreamGauge.Dec()
+	if ws.release != nil {
+		ws.release()
+	}
 }
 
 func (ws *watchStream) Rev() int64 {
@@ -106,7 +106,8 @@ func (w watcherSet) add(wa *watcher) {
 
 func (w watcherSet) union(ws watcherSet) {
 	for wa := range ws {
-		w.add(wa)
+		// a watcher on several ranges may be in both sets
+		w[wa] = struct{}{}
 	}
 }
 
@@ -119,17 +120,21 @@ func (w watcherSet) delete(wa *watcher) {
 
 type watcherSetByKey map[string]watcherSet
 
-func (w watcherSetByKey) add(wa *watcher) {
-	set := w[string(wa.key)]
+func (w watcherSetByKey) add(wa *watcher, key []byte) {
+	set := w[string(key)]
 	if set == nil {
 		set = make(watcherSet)
-		w[string(wa.key)] = set
+		w[string(key)] = set
+	}
+	if _, ok := set[wa]; ok {
+		// another range of the watcher on the same key
+		return
 	}
 	set.add(wa)
 }
 
-func (w watcherSetByKey) delete(wa *watcher) bool {
-	k := string(wa.key)
+func (w watcherSetByKey) delete(wa *watcher, key []byte) bool {
+	k := string(key)
 	if v, ok := w[k]; ok {
 		if _, ok := v[wa]; ok {
 			delete(v, wa)
@@ -164,15 +169,26 @@ func newWatcherGroup() watcherGroup {
 // add puts a watcher in the group.
 func (wg *watcherGroup) add(wa *watcher) {
 	wg.watchers.add(wa)
-	if wa.end == nil {
-		wg.keyWatchers.add(wa)
+	wg.addRange(wa, wa.key, wa.end)
+	for _, r := range wa.ranges {
+		wg.addRange(wa, r.Key, r.End)
+	}
+}
+
+func (wg *watcherGroup) addRange(wa *watcher, key, end []byte) {
+	if end == nil {
+		wg.keyWatchers.add(wa, key)
 		return
 	}
 
 	// interval already registered?
-	ivl := adt.NewStringAffineInterval(string(wa.key), string(wa.end))
+	ivl := adt.NewStringAffineInterval(string(key), string(end))
 	if iv := wg.ranges.Find(ivl); iv != nil {
-		iv.Val.(watcherSet).add(wa)
+		ws := iv.Val.(watcherSet)
+		if _, ok := ws[wa]; !ok {
+			// not another range of the watcher on the same interval
+			ws.add(wa)
+		}
 		return
 	}
 
@@ -197,12 +213,19 @@ func (wg *watcherGroup) delete(wa *watcher) bool {
 		return false
 	}
 	wg.watchers.delete(wa)
-	if wa.end == nil {
-		wg.keyWatchers.delete(wa)
-		return true
+	ok := wg.deleteRange(wa, wa.key, wa.end)
+	for _, r := range wa.ranges {
+		wg.deleteRange(wa, r.Key, r.End)
+	}
+	return ok
+}
+
+func (wg *watcherGroup) deleteRange(wa *watcher, key, end []byte) bool {
+	if end == nil {
+		return wg.keyWatchers.delete(wa, key)
 	}
 
-	ivl := adt.NewStringAffineInterval(string(wa.key), string(wa.end))
+	ivl := adt.NewStringAffineInterval(string(key), string(end))
 	iv := wg.ranges.Find(ivl)
 	if iv == nil {
 		return false
@@ -221,9 +244,9 @@ func (wg *watcherGroup) delete(wa *watcher) bool {
 }
 
 // choose selects watchers from the watcher group to update
-func (wg *watcherGroup) choose(maxWatchers int, curRev, compactRev int64) (*watcherGroup, int64) {
+func (wg *watcherGroup) choose(maxWatchers int, curRev int64, floors compactFloors, kc *keyCompactions) (*watcherGroup, int64) {
 	if len(wg.watchers) < maxWatchers {
-		return wg, wg.chooseAll(curRev, compactRev)
+		return wg, wg.chooseAll(curRev, floors, kc)
 	}
 	ret := newWatcherGroup()
 	for w := range wg.watchers {
@@ -233,10 +256,10 @@ func (wg *watcherGroup) choose(maxWatchers int, curRev, compactRev int64) (*watc
 		maxWatchers--
 		ret.add(w)
 	}
-	return &ret, ret.chooseAll(curRev, compactRev)
+	return &ret, ret.chooseAll(curRev, floors, kc)
 }
 
-func (wg *watcherGroup) chooseAll(curRev, compactRev int64) int64 {
+func (wg *watcherGroup) chooseAll(curRev int64, floors compactFloors, kc *keyCompactions) int64 {
 	minRev := int64(math.MaxInt64)
 	for w := range wg.watchers {
 		if w.minRev > curRev {
@@ -250,7 +273,7 @@ func (wg *watcherGroup) chooseAll(curRev, compactRev int64) int64 {
 			// mark 'restore' done, since it's chosen
 			w.restore = false
 		}
-		if w.minRev < compactRev {
+		if compactRev := w.compactRev(floors, kc); w.minRev < compactRev {
 			select {
 			case w.ch <- WatchResponse{WatchID: w.id, CompactRevision: compactRev}:
 				w.compacted = true
@@ -428,3 +428,91 @@ func TestWatcherWatchWithFilter(t *testing.T) {
 		t.Fatal("failed to receive delete request")
 	}
 }
+
+// TestWatcherWatchRanges tests that a watcher on several ranges receives the
+// events of all of them once, tagged with the range they matched.
+func TestWatcherWatchRanges(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := WatchableKV(newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{}))
+	defer cleanup(s, b)
+
+	filterDelete := func(e mvccpb.Event) bool {
+		return e.Type == mvccpb.DELETE
+	}
+	ranges := []WatchRange{
+		{Key: []byte("b/"), End: []byte("b0")},
+		// overlaps with the first range, whose events are tagged 0
+		{Key: []byte("a/"), End: []byte("b")},
+		{Key: []byte("c/"), End: []byte("c0"), Filters: []FilterFunc{filterDelete}},
+		// catches the deletes filtered out of the previous range
+		{Key: []byte("c/x")},
+	}
+	rev := s.Put([]byte("z"), []byte("v"), lease.NoLease)
+
+	type event struct {
+		typ mvccpb.Event_EventType
+		key string
+		idx int32
+	}
+	wevs := []event{
+		{mvccpb.PUT, "a/1", 0},
+		{mvccpb.PUT, "b/1", 1},
+		{mvccpb.PUT, "c/1", 3},
+		{mvccpb.PUT, "c/x", 3},
+		{mvccpb.DELETE, "c/1", -1},
+		{mvccpb.DELETE, "c/x", 4},
+	}
+	write := func() {
+		for _, ev := range wevs {
+			if ev.typ == mvccpb.PUT {
+				s.Put([]byte(ev.key), []byte("v"), lease.NoLease)
+			} else {
+				s.DeleteRange([]byte(ev.key), nil)
+			}
+		}
+	}
+	write()
+
+	// synced and unsynced watchers tag their events alike
+	for _, startRev := range []int64{rev + 1, 0} {
+		w := s.NewWatchStream()
+		id, err := w.WatchRanges(0, []byte("a/"), []byte("a0"), ranges, startRev, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if startRev == 0 {
+			write()
+		}
+
+		var gevs []event
+		for len(gevs) < len(wevs)-1 {
+			select {
+			case resp := <-w.Chan():
+				if resp.WatchID != id {
+					t.Fatalf("watch ID = %d, want %d", resp.WatchID, id)
+				}
+				for _, ev := range resp.Events {
+					gevs = append(gevs, event{ev.Type, string(ev.Kv.Key), ev.RangeIndex})
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for events, got %v", gevs)
+			}
+		}
+		var want []event
+		for _, ev := range wevs {
+			if ev.idx >= 0 {
+				want = append(want, ev)
+			}
+		}
+		if !reflect.DeepEqual(gevs, want) {
+			t.Errorf("events from rev %d = %v, want %v", startRev, gevs, want)
+		}
+		w.Close()
+	}
+
+	w := s.NewWatchStream()
+	defer w.Close()
+	if _, err := w.WatchRanges(0, []byte("a"), nil, []WatchRange{{Key: []byte("c"), End: []byte("b")}}, 0, false); err != ErrEmptyWatcherRange {
+		t.Errorf("err = %v, want %v", err, ErrEmptyWatcherRange)
+	}
+}
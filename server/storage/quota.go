@@ -141,6 +141,11 @@ func (b *BackendQuota) Cost(v interface{}) int {
 		return costTxn(r)
 	case *pb.LeaseGrantRequest:
 		return leaseOverhead
+	case *pb.LeaseGrantBatchRequest:
+		return leaseOverhead * len(r.Leases)
+	case *pb.LeaseReattachRequest:
+		// the rewritten keys are not known before the request is applied
+		return kvOverhead
 	default:
 		panic("unexpected cost")
 	}
@@ -157,6 +162,10 @@ func costTxnReq(u *pb.RequestOp) int {
 }
 
 func costTxn(r *pb.TxnRequest) int {
+	if r.DryRun {
+		// a dry run writes nothing
+		return 0
+	}
 	sizeSuccess := 0
 	for _, u := range r.Success {
 		sizeSuccess += costTxnReq(u)
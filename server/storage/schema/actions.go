@@ -15,6 +15,9 @@
 package schema
 
 import (
+	"encoding/binary"
+	"fmt"
+
 	"go.uber.org/zap"
 
 	"go.etcd.io/etcd/server/v3/storage/backend"
@@ -49,6 +52,68 @@ func (a deleteKeyAction) unsafeDo(tx backend.UnsafeReadWriter) (action, error) {
 	return revert, nil
 }
 
+// refuseFieldAction fails with Reason if the field is set.
+type refuseFieldAction struct {
+	Bucket    backend.Bucket
+	FieldName []byte
+	Reason    string
+}
+
+func (a refuseFieldAction) unsafeDo(tx backend.UnsafeReadWriter) (action, error) {
+	if _, vs := tx.UnsafeRange(a.Bucket, a.FieldName, nil, 1); len(vs) != 0 {
+		return nil, fmt.Errorf("%s", a.Reason)
+	}
+	return noopAction{}, nil
+}
+
+type noopAction struct{}
+
+func (a noopAction) unsafeDo(tx backend.UnsafeReadWriter) (action, error) {
+	return a, nil
+}
+
+// scheduleCompactAtFloorsAction raises the scheduled compaction revision to
+// the highest compaction floor of the prefixes retained by the last
+// compaction, if any.
+type scheduleCompactAtFloorsAction struct{}
+
+func (a scheduleCompactAtFloorsAction) unsafeDo(tx backend.UnsafeReadWriter) (action, error) {
+	_, vs := tx.UnsafeRange(Meta, MetaCompactRetentionName, nil, 0)
+	if len(vs) == 0 {
+		return noopAction{}, nil
+	}
+	// the floors are encoded by mvcc as a list of an 8 bytes revision
+	// followed by the uvarint length of the first key of the floor and the
+	// key itself
+	var max int64
+	for buf := vs[0]; len(buf) > 0; {
+		if len(buf) < 8 {
+			return nil, fmt.Errorf("truncated compaction floor revision")
+		}
+		if rev := int64(binary.BigEndian.Uint64(buf)); rev > max {
+			max = rev
+		}
+		buf = buf[8:]
+		n, l := binary.Uvarint(buf)
+		if l <= 0 || uint64(len(buf)-l) < n {
+			return nil, fmt.Errorf("truncated compaction floor key")
+		}
+		buf = buf[l+int(n):]
+	}
+	_, scheduled := tx.UnsafeRange(Meta, ScheduledCompactKeyName, nil, 0)
+	if len(scheduled) != 0 && int64(binary.BigEndian.Uint64(scheduled[0])) >= max {
+		return noopAction{}, nil
+	}
+	revert := restoreFieldValueAction(tx, Meta, ScheduledCompactKeyName)
+	// a revision is encoded as its 8 bytes main revision, '_' and its 8
+	// bytes sub revision
+	rbytes := make([]byte, 17)
+	binary.BigEndian.PutUint64(rbytes, uint64(max))
+	rbytes[8] = '_'
+	tx.UnsafePut(Meta, ScheduledCompactKeyName, rbytes)
+	return revert, nil
+}
+
 func restoreFieldValueAction(tx backend.UnsafeReader, bucket backend.Bucket, fieldName []byte) action {
 	_, vs := tx.UnsafeRange(bucket, fieldName, nil, 1)
 	if len(vs) == 1 {
@@ -66,6 +131,21 @@ func restoreFieldValueAction(tx backend.UnsafeReader, bucket backend.Bucket, fie
 
 type ActionList []action
 
+// unsafeDo executes the actions as a single action, whose revert action
+// reverts them in reversed order.
+func (as ActionList) unsafeDo(tx backend.UnsafeReadWriter) (action, error) {
+	reverts := make(ActionList, 0, len(as))
+	for _, a := range as {
+		revert, err := a.unsafeDo(tx)
+		if err != nil {
+			reverts.unsafeDo(tx)
+			return nil, err
+		}
+		reverts = append(ActionList{revert}, reverts...)
+	}
+	return reverts, nil
+}
+
 // unsafeExecute executes actions one by one. If one of actions returns error,
 // it will revert them.
 func (as ActionList) unsafeExecute(lg *zap.Logger, tx backend.UnsafeReadWriter) error {
@@ -81,6 +81,11 @@ var (
 	ClusterDowngradeKeyName      = []byte("downgrade")
 	// Since v3.6
 	MetaStorageVersionName = []byte("storageVersion")
+	// MetaCompactRetentionName holds the compaction floors of the prefixes
+	// retained by the last compaction.
+	MetaCompactRetentionName = []byte("compactRetention")
+	// MetaPrefixQuotasName holds the quotas of the prefixes with a quota.
+	MetaPrefixQuotasName = []byte("prefixQuotas")
 	// Before adding new meta key please update server/etcdserver/version
 )
 
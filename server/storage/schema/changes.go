@@ -36,6 +36,49 @@ func addNewField(bucket backend.Bucket, fieldName []byte, fieldValue []byte) sch
 	}
 }
 
+// addOptionalField represents a field that may be written by the new version
+// but is not set when upgrading. Downgrade will remove the field.
+func addOptionalField(bucket backend.Bucket, fieldName []byte) schemaChange {
+	return simpleSchemaChange{
+		upgrade: noopAction{},
+		downgrade: deleteKeyAction{
+			Bucket:    bucket,
+			FieldName: fieldName,
+		},
+	}
+}
+
+// addCompactRetention represents the compaction floors of the prefixes
+// retained by the last compaction, which may be written by the new version.
+// Older versions compact every key at the same revision, so downgrade
+// schedules a compaction at the highest floor before removing the floors.
+func addCompactRetention() schemaChange {
+	return simpleSchemaChange{
+		upgrade: noopAction{},
+		downgrade: ActionList{
+			scheduleCompactAtFloorsAction{},
+			deleteKeyAction{
+				Bucket:    Meta,
+				FieldName: MetaCompactRetentionName,
+			},
+		},
+	}
+}
+
+// addPrefixQuotas represents the prefix quotas, which may be written by the
+// new version. Older versions do not enforce them, so downgrade is refused
+// while a quota is set rather than silently dropping the quotas.
+func addPrefixQuotas() schemaChange {
+	return simpleSchemaChange{
+		upgrade: noopAction{},
+		downgrade: refuseFieldAction{
+			Bucket:    Meta,
+			FieldName: MetaPrefixQuotasName,
+			Reason:    "prefix quotas are set, remove them before downgrading",
+		},
+	}
+}
+
 type simpleSchemaChange struct {
 	upgrade   action
 	downgrade action
@@ -33,6 +33,10 @@ func TestUpgradeDowngrade(t *testing.T) {
 			change:                  addNewField(Meta, []byte("/test"), []byte("1")),
 			expectStateAfterUpgrade: map[string]string{"/test": "1"},
 		},
+		{
+			name:   "addOptionalField",
+			change: addOptionalField(Meta, []byte("/test")),
+		},
 	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
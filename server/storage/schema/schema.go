@@ -130,6 +130,8 @@ var (
 	schemaChanges = map[semver.Version][]schemaChange{
 		version.V3_6: {
 			addNewField(Meta, MetaStorageVersionName, emptyStorageVersion),
+			addCompactRetention(),
+			addPrefixQuotas(),
 		},
 	}
 	// emptyStorageVersion is used for v3.6 Step for the first time, in all other version StoragetVersion should be set by migrator.
@@ -15,6 +15,7 @@
 package schema
 
 import (
+	"encoding/binary"
 	"testing"
 	"time"
 
@@ -294,6 +295,90 @@ func TestMigrateIsReversible(t *testing.T) {
 	}
 }
 
+func TestDowngradeRemovesV3_6Fields(t *testing.T) {
+	lg := zap.NewNop()
+	dataPath := setupBackendData(t, version.V3_6, func(tx backend.UnsafeReadWriter) {
+		MustUnsafeSaveConfStateToBackend(lg, tx, &raftpb.ConfState{})
+		UnsafeUpdateConsistentIndex(tx, 1, 1)
+		UnsafeSetStorageVersion(tx, &version.V3_6)
+		// the keys are compacted at 20, but the keys under /a at 10
+		tx.UnsafePut(Meta, ScheduledCompactKeyName, revBytes(10))
+		tx.UnsafePut(Meta, FinishedCompactKeyName, revBytes(10))
+		floors := binary.BigEndian.AppendUint64(nil, 20)
+		floors = binary.AppendUvarint(floors, 0)
+		floors = binary.BigEndian.AppendUint64(floors, 10)
+		floors = binary.AppendUvarint(floors, 2)
+		floors = append(floors, "/a"...)
+		floors = binary.BigEndian.AppendUint64(floors, 20)
+		floors = binary.AppendUvarint(floors, 2)
+		floors = append(floors, "/b"...)
+		tx.UnsafePut(Meta, MetaCompactRetentionName, floors)
+	})
+	w, _ := waltesting.NewTmpWAL(t, nil)
+	defer w.Close()
+	walVersion, err := wal.ReadWALVersion(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	be := backend.NewDefaultBackend(lg, dataPath)
+	defer be.Close()
+	tx := be.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	if err = UnsafeMigrate(lg, tx, walVersion, version.V3_5); err != nil {
+		t.Fatalf("Migrate(lg, tx, %q) returned error %+v", version.V3_5, err)
+	}
+	// v3.5 compacts the keys under /a at 20 as well
+	assertBucketState(t, tx, Meta, map[string]string{
+		"confState":           `{"auto_leave":false}`,
+		"consistent_index":    "\x00\x00\x00\x00\x00\x00\x00\x01",
+		"term":                "\x00\x00\x00\x00\x00\x00\x00\x01",
+		"scheduledCompactRev": string(revBytes(20)),
+		"finishedCompactRev":  string(revBytes(10)),
+	})
+}
+
+func TestDowngradeRefusedWithPrefixQuotas(t *testing.T) {
+	lg := zap.NewNop()
+	dataPath := setupBackendData(t, version.V3_6, func(tx backend.UnsafeReadWriter) {
+		MustUnsafeSaveConfStateToBackend(lg, tx, &raftpb.ConfState{})
+		UnsafeUpdateConsistentIndex(tx, 1, 1)
+		UnsafeSetStorageVersion(tx, &version.V3_6)
+		tx.UnsafePut(Meta, MetaPrefixQuotasName, []byte("quotas"))
+	})
+	w, _ := waltesting.NewTmpWAL(t, nil)
+	defer w.Close()
+	walVersion, err := wal.ReadWALVersion(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	be := backend.NewDefaultBackend(lg, dataPath)
+	defer be.Close()
+	tx := be.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	if err = UnsafeMigrate(lg, tx, walVersion, version.V3_5); err == nil {
+		t.Fatalf("Migrate(lg, tx, %q) expected an error", version.V3_5)
+	}
+	// the quotas and the storage version are kept
+	ver := UnsafeReadStorageVersion(tx)
+	if ver == nil || !ver.Equal(version.V3_6) {
+		t.Errorf("unexpected storage version %v", ver)
+	}
+	_, vs := tx.UnsafeRange(Meta, MetaPrefixQuotasName, nil, 0)
+	if len(vs) != 1 || string(vs[0]) != "quotas" {
+		t.Errorf("unexpected prefix quotas %q", vs)
+	}
+}
+
+func revBytes(rev int64) []byte {
+	b := binary.BigEndian.AppendUint64(nil, uint64(rev))
+	b = append(b, '_')
+	return binary.BigEndian.AppendUint64(b, 0)
+}
+
 func setupBackendData(t *testing.T, ver semver.Version, overrideKeys func(tx backend.UnsafeReadWriter)) string {
 	t.Helper()
 	be, tmpPath := betesting.NewTmpBackend(t, time.Microsecond, 10)
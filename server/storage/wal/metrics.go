@@ -28,6 +28,24 @@ var (
 		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
 	})
 
+	walFsyncEntries = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "disk",
+		Name:      "wal_fsync_entries",
+		Help:      "The distributions of the number of entries flushed by each fsync called by WAL.",
+
+		// lowest bucket start of upper bound 1 with factor 2
+		// highest bucket start of 1 * 2^13 == 8192
+		Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+	})
+
+	walSlowFsyncs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "disk",
+		Name:      "wal_slow_fsync_total",
+		Help:      "The total number of fsyncs called by WAL that took longer than the slow fsync threshold.",
+	})
+
 	walWriteBytes = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "etcd",
 		Subsystem: "disk",
@@ -38,5 +56,7 @@ var (
 
 func init() {
 	prometheus.MustRegister(walFsyncSec)
+	prometheus.MustRegister(walFsyncEntries)
+	prometheus.MustRegister(walSlowFsyncs)
 	prometheus.MustRegister(walWriteBytes)
 }
@@ -138,7 +138,8 @@ func visitEntryData(entryType raftpb.EntryType, data []byte, visitor Visitor) er
 			return nil
 		}
 		msg = proto.MessageReflect(&confChange)
-		return visitor(msg.Descriptor().FullName(), &version.V3_4)
+		// etcd applies EntryConfChangeV2, proposed by MemberReconfigure, since v3.6
+		return visitor(msg.Descriptor().FullName(), &version.V3_6)
 	default:
 		panic("unhandled")
 	}
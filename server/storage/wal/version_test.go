@@ -79,14 +79,14 @@ func TestEtcdVersionFromEntry(t *testing.T) {
 			expect: &version.V3_0,
 		},
 		{
-			name: "Using ConfigChangeV2 implies v3.4",
+			name: "Using ConfigChangeV2 implies v3.6",
 			input: raftpb.Entry{
 				Term:  1,
 				Index: 2,
 				Type:  raftpb.EntryConfChangeV2,
 				Data:  confChangeV2Data,
 			},
-			expect: &version.V3_4,
+			expect: &version.V3_6,
 		},
 	}
 	for _, tc := range tcs {
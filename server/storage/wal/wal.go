@@ -42,9 +42,9 @@ const (
 	CrcType
 	SnapshotType
 
-	// warnSyncDuration is the amount of time allotted to an fsync before
-	// logging a warning
-	warnSyncDuration = time.Second
+	// DefaultSlowSyncThreshold is the default amount of time allotted to an
+	// fsync before logging a warning and counting it as slow
+	DefaultSlowSyncThreshold = time.Second
 )
 
 var (
@@ -86,9 +86,13 @@ type WAL struct {
 
 	unsafeNoSync bool // if set, do not fsync
 
-	mu      sync.Mutex
-	enti    uint64   // index of the last entry saved to the wal
-	encoder *encoder // encoder to encode records
+	// slowSyncThreshold is the duration after which an fsync is slow
+	slowSyncThreshold time.Duration
+
+	mu       sync.Mutex
+	enti     uint64   // index of the last entry saved to the wal
+	unsynced int      // number of entries saved since the last fsync
+	encoder  *encoder // encoder to encode records
 
 	locks []*fileutil.LockedFile // the locked files the WAL holds (the name is increasing)
 	fp    *filePipeline
@@ -245,6 +249,12 @@ func (w *WAL) SetUnsafeNoFsync() {
 	w.unsafeNoSync = true
 }
 
+// SetSlowSyncThreshold sets the duration after which an fsync is logged and
+// counted as slow. A non-positive threshold restores DefaultSlowSyncThreshold.
+func (w *WAL) SetSlowSyncThreshold(d time.Duration) {
+	w.slowSyncThreshold = d
+}
+
 func (w *WAL) cleanupWAL(lg *zap.Logger) {
 	var err error
 	if err = w.Close(); err != nil {
@@ -804,7 +814,12 @@ func (w *WAL) sync() error {
 		}
 	}
 
+	walFsyncEntries.Observe(float64(w.unsynced))
+	w.unsynced = 0
+
 	if w.unsafeNoSync {
+		// report the skipped fsync as instantaneous
+		walFsyncSec.Observe(0)
 		return nil
 	}
 
@@ -812,12 +827,17 @@ func (w *WAL) sync() error {
 	err := fileutil.Fdatasync(w.tail().File)
 
 	took := time.Since(start)
-	if took > warnSyncDuration {
+	threshold := w.slowSyncThreshold
+	if threshold <= 0 {
+		threshold = DefaultSlowSyncThreshold
+	}
+	if took > threshold {
 		w.lg.Warn(
 			"slow fdatasync",
 			zap.Duration("took", took),
-			zap.Duration("expected-duration", warnSyncDuration),
+			zap.Duration("expected-duration", threshold),
 		)
+		walSlowFsyncs.Inc()
 	}
 	walFsyncSec.Observe(took.Seconds())
 
@@ -910,6 +930,7 @@ func (w *WAL) saveEntry(e *raftpb.Entry) error {
 		return err
 	}
 	w.enti = e.Index
+	w.unsynced++
 	return nil
 }
 
@@ -28,7 +28,10 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
@@ -374,6 +377,68 @@ func TestSaveWithCut(t *testing.T) {
 	}
 }
 
+func TestSaveFsyncMetrics(t *testing.T) {
+	tests := []struct {
+		name         string
+		unsafeNoSync bool
+	}{
+		{"fsync", false},
+		{"unsafe no fsync", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := Create(zaptest.NewLogger(t), t.TempDir(), []byte("metadata"))
+			require.NoError(t, err)
+			defer w.Close()
+			if tt.unsafeNoSync {
+				w.SetUnsafeNoFsync()
+			}
+			// count every fsync as slow
+			w.SetSlowSyncThreshold(time.Nanosecond)
+
+			sec, ents, slow := readHistogram(walFsyncSec), readHistogram(walFsyncEntries), readCounter(walSlowFsyncs)
+			index := uint64(1)
+			for i := 0; i < 3; i++ {
+				st := raftpb.HardState{Term: 1, Commit: index}
+				var es []raftpb.Entry
+				for j := 0; j < 4; j++ {
+					es = append(es, raftpb.Entry{Index: index, Term: 1, Data: []byte("data")})
+					index++
+				}
+				require.NoError(t, w.Save(st, es))
+			}
+
+			sec2, ents2, slow2 := readHistogram(walFsyncSec), readHistogram(walFsyncEntries), readCounter(walSlowFsyncs)
+			assert.Equal(t, uint64(3), sec2.GetSampleCount()-sec.GetSampleCount())
+			assert.Equal(t, uint64(3), ents2.GetSampleCount()-ents.GetSampleCount())
+			assert.Equal(t, float64(12), ents2.GetSampleSum()-ents.GetSampleSum())
+			if tt.unsafeNoSync {
+				assert.Equal(t, sec.GetSampleSum(), sec2.GetSampleSum())
+				assert.Equal(t, slow, slow2)
+			} else {
+				assert.Greater(t, sec2.GetSampleSum(), sec.GetSampleSum())
+				assert.Equal(t, float64(3), slow2-slow)
+			}
+		})
+	}
+}
+
+func readHistogram(h prometheus.Histogram) *dto.Histogram {
+	m := &dto.Metric{}
+	if err := h.Write(m); err != nil {
+		panic(err)
+	}
+	return m.GetHistogram()
+}
+
+func readCounter(c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		panic(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
 func TestRecover(t *testing.T) {
 	cases := []struct {
 		name string
@@ -36,6 +36,12 @@ type Config struct {
 	// DataDir is a root directory where the data being verified are stored.
 	DataDir string
 
+	// WALDir is the directory of the WAL. Defaults to the WAL directory under DataDir.
+	WALDir string
+
+	// BackendDir is the directory of the backend. Defaults to the snapshot directory under DataDir.
+	BackendDir string
+
 	// ExactIndex requires consistent_index in backend exactly match the last committed WAL entry.
 	// Usually backend's consistent_index needs to be <= WAL.commit, but for backups the match
 	// is expected to be exact.
@@ -55,7 +61,11 @@ func Verify(cfg Config) error {
 		lg = zap.NewNop()
 	}
 
-	if !fileutil.Exist(datadir.ToBackendFileName(cfg.DataDir)) {
+	bepath := datadir.ToBackendFileName(cfg.DataDir)
+	if cfg.BackendDir != "" {
+		bepath = datadir.ToBackendFileNameInDir(cfg.BackendDir)
+	}
+	if !fileutil.Exist(bepath) {
 		lg.Info("verification skipped due to non exist db file")
 		return nil
 	}
@@ -76,7 +86,7 @@ func Verify(cfg Config) error {
 		}
 	}()
 
-	be := backend.NewDefaultBackend(lg, datadir.ToBackendFileName(cfg.DataDir))
+	be := backend.NewDefaultBackend(lg, bepath)
 	defer be.Close()
 
 	snapshot, hardstate, err := validateWal(cfg)
@@ -134,7 +144,10 @@ func validateConsistentIndex(cfg Config, hardstate *raftpb.HardState, snapshot *
 }
 
 func validateWal(cfg Config) (*walpb.Snapshot, *raftpb.HardState, error) {
-	walDir := datadir.ToWalDir(cfg.DataDir)
+	walDir := cfg.WALDir
+	if walDir == "" {
+		walDir = datadir.ToWalDir(cfg.DataDir)
+	}
 
 	walSnaps, err := wal2.ValidSnapshotEntries(cfg.Logger, walDir)
 	if err != nil {
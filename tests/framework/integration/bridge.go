@@ -31,10 +31,12 @@ type bridge struct {
 	l      net.Listener
 	conns  map[*bridgeConn]struct{}
 
-	stopc      chan struct{}
-	pausec     chan struct{}
-	blackholec chan struct{}
-	wg         sync.WaitGroup
+	stopc         chan struct{}
+	pausec        chan struct{}
+	blackholec    chan struct{}
+	dropInboundc  chan struct{}
+	dropOutboundc chan struct{}
+	wg            sync.WaitGroup
 
 	mu sync.Mutex
 }
@@ -42,12 +44,14 @@ type bridge struct {
 func newBridge(dialer Dialer, listener net.Listener) (*bridge, error) {
 	b := &bridge{
 		// bridge "port" is ("%05d%05d0", port, pid) since go1.8 expects the port to be a number
-		dialer:     dialer,
-		l:          listener,
-		conns:      make(map[*bridgeConn]struct{}),
-		stopc:      make(chan struct{}),
-		pausec:     make(chan struct{}),
-		blackholec: make(chan struct{}),
+		dialer:        dialer,
+		l:             listener,
+		conns:         make(map[*bridgeConn]struct{}),
+		stopc:         make(chan struct{}),
+		pausec:        make(chan struct{}),
+		blackholec:    make(chan struct{}),
+		dropInboundc:  make(chan struct{}),
+		dropOutboundc: make(chan struct{}),
 	}
 	close(b.pausec)
 	b.wg.Add(1)
@@ -120,13 +124,15 @@ func (b *bridge) serveListen() {
 
 		outc, oerr := b.dialer.Dial()
 		if oerr != nil {
+			// the bridged server may be stopped; keep listening for its restart
 			inc.Close()
-			return
+			continue
 		}
 
-		bc := &bridgeConn{inc, outc, make(chan struct{})}
+		bc := &bridgeConn{in: inc, out: outc, donec: make(chan struct{})}
 		b.wg.Add(1)
 		b.mu.Lock()
+		bc.dropInboundc, bc.dropOutboundc = b.dropInboundc, b.dropOutboundc
 		b.conns[bc] = struct{}{}
 		go b.serveConn(bc)
 		b.mu.Unlock()
@@ -146,12 +152,12 @@ func (b *bridge) serveConn(bc *bridgeConn) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
-		b.ioCopy(bc.out, bc.in)
+		b.ioCopy(bc.out, bc.in, bc.dropInboundc)
 		bc.close()
 		wg.Done()
 	}()
 	go func() {
-		b.ioCopy(bc.in, bc.out)
+		b.ioCopy(bc.in, bc.out, bc.dropOutboundc)
 		bc.close()
 		wg.Done()
 	}()
@@ -162,6 +168,11 @@ type bridgeConn struct {
 	in    net.Conn
 	out   net.Conn
 	donec chan struct{}
+
+	// dropInboundc and dropOutboundc are the bridge drop channels
+	// at the time the connection was accepted.
+	dropInboundc  chan struct{}
+	dropOutboundc chan struct{}
 }
 
 func (bc *bridgeConn) Close() {
@@ -190,14 +201,54 @@ func (b *bridge) Unblackhole() {
 	b.mu.Unlock()
 }
 
+// DropInbound drops the traffic sent to the bridged server, while the traffic
+// it sends back is still delivered. Restore undoes it.
+func (b *bridge) DropInbound() {
+	b.mu.Lock()
+	select {
+	case <-b.dropInboundc:
+	default:
+		close(b.dropInboundc)
+	}
+	b.mu.Unlock()
+}
+
+// DropOutbound drops the traffic sent by the bridged server, while the traffic
+// sent to it is still delivered. Restore undoes it.
+func (b *bridge) DropOutbound() {
+	b.mu.Lock()
+	select {
+	case <-b.dropOutboundc:
+	default:
+		close(b.dropOutboundc)
+	}
+	b.mu.Unlock()
+}
+
+// Restore stops dropping traffic in either direction. Like Unblackhole, it closes
+// the existing connections, since they may have lost part of their stream.
+func (b *bridge) Restore() {
+	b.mu.Lock()
+	for bc := range b.conns {
+		bc.Close()
+	}
+	b.conns = make(map[*bridgeConn]struct{})
+	b.dropInboundc = make(chan struct{})
+	b.dropOutboundc = make(chan struct{})
+	b.mu.Unlock()
+}
+
 // ref. https://github.com/golang/go/blob/master/src/io/io.go copyBuffer
-func (b *bridge) ioCopy(dst io.Writer, src io.Reader) (err error) {
+func (b *bridge) ioCopy(dst io.Writer, src io.Reader, dropc <-chan struct{}) (err error) {
 	buf := make([]byte, 32*1024)
 	for {
 		select {
 		case <-b.blackholec:
 			io.Copy(io.Discard, src)
 			return nil
+		case <-dropc:
+			io.Copy(io.Discard, src)
+			return nil
 		default:
 		}
 		nr, er := src.Read(buf)
@@ -0,0 +1,98 @@
+// Copyright 2024 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// manualClock is a clock whose tickers only fire when advanced by the test.
+// Unlike clockwork's fake tickers, which drop expirations nobody has received
+// yet, every tick is handed over synchronously, so advancing the clock n
+// ticks delivers exactly n ticks to each running raft node.
+type manualClock struct {
+	clockwork.FakeClock
+	tick time.Duration
+
+	mu      sync.Mutex
+	tickers map[*manualTicker]struct{}
+}
+
+func newManualClock(tick time.Duration) *manualClock {
+	return &manualClock{
+		FakeClock: clockwork.NewFakeClock(),
+		tick:      tick,
+		tickers:   make(map[*manualTicker]struct{}),
+	}
+}
+
+func (c *manualClock) NewTicker(time.Duration) clockwork.Ticker {
+	t := &manualTicker{
+		clock: c,
+		c:     make(chan time.Time),
+		stopc: make(chan struct{}),
+	}
+	c.mu.Lock()
+	c.tickers[t] = struct{}{}
+	c.mu.Unlock()
+	return t
+}
+
+// advance moves the clock forward by n ticks, blocking until every live
+// ticker has received each of them.
+func (c *manualClock) advance(n int) {
+	for i := 0; i < n; i++ {
+		c.FakeClock.Advance(c.tick)
+		now := c.Now()
+
+		c.mu.Lock()
+		tickers := make([]*manualTicker, 0, len(c.tickers))
+		for t := range c.tickers {
+			tickers = append(tickers, t)
+		}
+		c.mu.Unlock()
+
+		for _, t := range tickers {
+			select {
+			case t.c <- now:
+			case <-t.stopc:
+			}
+		}
+	}
+}
+
+type manualTicker struct {
+	clock    *manualClock
+	c        chan time.Time
+	stopc    chan struct{}
+	stopOnce sync.Once
+}
+
+func (t *manualTicker) Chan() <-chan time.Time { return t.c }
+
+// Reset is a no-op; the ticker fires whenever its clock advances.
+func (t *manualTicker) Reset(time.Duration) {}
+
+func (t *manualTicker) Stop() {
+	t.stopOnce.Do(func() {
+		t.clock.mu.Lock()
+		delete(t.clock.tickers, t)
+		t.clock.mu.Unlock()
+		close(t.stopc)
+	})
+}
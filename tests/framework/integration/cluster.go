@@ -65,6 +65,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/soheilhy/cmux"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc"
@@ -147,7 +148,9 @@ type ClusterConfig struct {
 	QuotaBackendBytes int64
 
 	MaxTxnOps              uint
+	MaxTxnTotalOps         uint
 	MaxRequestBytes        uint
+	MaxValueBytes          uint
 	SnapshotCount          uint64
 	SnapshotCatchUpEntries uint64
 
@@ -163,17 +166,61 @@ type ClusterConfig struct {
 	// UseBridge adds bridge between client and grpc server. Should be used in tests that
 	// want to manipulate connection or require connection not breaking despite server stop/restart.
 	UseBridge bool
+	// UsePeerBridge adds bridge in front of each member peer listener. Should be used
+	// in tests that want to manipulate raft traffic between members.
+	UsePeerBridge bool
 	// UseTCP configures server listen on tcp socket. If disabled unix socket is used.
 	UseTCP bool
+	// ManualClock stops raft nodes from ticking on their own. Tests drive the
+	// ticks explicitly with Cluster.AdvanceTicks or Member.AdvanceTicks; the first
+	// member is ticked into leadership when the cluster launches.
+	ManualClock bool
+	// DeterministicIDs gives the initial members sequential IDs, the ID of
+	// the i-th member being DeterministicMemberID(i), instead of the IDs
+	// derived from their peer URLs. Members added at runtime get the IDs
+	// assigned by the MemberAdd API.
+	DeterministicIDs bool
+	// ElectionPriorities are the election priorities of the members, by
+	// member number. Members without an entry have priority 0.
+	ElectionPriorities []uint32
 
 	EnableLeaseCheckpoint   bool
 	LeaseCheckpointInterval time.Duration
 	LeaseCheckpointPersist  bool
 
 	WatchProgressNotifyInterval time.Duration
+	MaxWatchStreamsPerConn      uint
 	ExperimentalMaxLearners     int
 	DisableStrictReconfigCheck  bool
 	CorruptCheckTime            time.Duration
+
+	CompactionBatchLimit    int
+	CompactionSleepInterval time.Duration
+
+	HotKeyTracking bool
+	ReadCacheBytes int64
+
+	BackendBatchInterval time.Duration
+
+	// TracerOptions enables distributed tracing with the given options.
+	TracerOptions []otelgrpc.Option
+
+	DefragInterval        time.Duration
+	DefragRateBytesPerSec uint
+	DefragOnLeader        bool
+
+	WriteRateLimitByUser map[string]float64
+	RateLimitReads       bool
+
+	// DedicatedWALDir puts the WAL of each member in a directory of its own,
+	// outside of its data directory.
+	DedicatedWALDir bool
+	// DedicatedSnapDir puts the snapshots of each member in a directory of its
+	// own, outside of its data directory.
+	DedicatedSnapDir bool
+	// DedicatedBackendDir puts the backend of each member in a directory of its
+	// own, outside of its data directory.
+	DedicatedBackendDir bool
 }
 
 type Cluster struct {
@@ -201,21 +248,49 @@ func (c *Cluster) fillClusterForMembers() error {
 	addrs := make([]string, 0)
 	for _, m := range c.Members {
 		scheme := SchemeFromTLSInfo(m.PeerTLSInfo)
-		for _, l := range m.PeerListeners {
-			addrs = append(addrs, fmt.Sprintf("%s=%s://%s", m.Name, scheme, l.Addr().String()))
+		for _, u := range m.PeerURLs {
+			addrs = append(addrs, fmt.Sprintf("%s=%s://%s", m.Name, scheme, u.Host))
 		}
 	}
 	clusterStr := strings.Join(addrs, ",")
+	var ids map[string]types.ID
+	if c.Cfg.DeterministicIDs {
+		ids = make(map[string]types.ID, len(c.Members))
+		for _, m := range c.Members {
+			ids[m.Name] = DeterministicMemberID(m.MemberNumber)
+		}
+	}
 	var err error
 	for _, m := range c.Members {
 		m.InitialPeerURLsMap, err = types.NewURLsMap(clusterStr)
 		if err != nil {
 			return err
 		}
+		m.InitialMemberIDs = ids
 	}
 	return nil
 }
 
+// DeterministicMemberID returns the ID of the member with the given member
+// number in a cluster created with DeterministicIDs. The IDs start at 1 as
+// raft reserves 0. The IDs assigned by MemberAdd are hashes of the peer URLs
+// and the time, so they do not collide with these in practice, and a
+// colliding ID is rejected by MemberAdd anyway.
+func DeterministicMemberID(memberNumber int) types.ID {
+	return types.ID(memberNumber + 1)
+}
+
+// AdvanceTicks delivers n raft ticks to every member of a cluster created
+// with ManualClock, one tick at a time to all members in turn. Stopped
+// members do not receive ticks.
+func (c *Cluster) AdvanceTicks(n int) {
+	for i := 0; i < n; i++ {
+		for _, m := range c.Members {
+			m.AdvanceTicks(1)
+		}
+	}
+}
+
 func (c *Cluster) Launch(t testutil.TB) {
 	t.Logf("Launching new cluster...")
 	errc := make(chan error)
@@ -232,6 +307,9 @@ func (c *Cluster) Launch(t testutil.TB) {
 			t.Fatalf("error setting up member: %v", err)
 		}
 	}
+	if c.Cfg.ManualClock {
+		c.Members[0].campaign(t)
+	}
 	// wait Cluster to be stable to receive future client requests
 	c.WaitMembersMatch(t, c.ProtoMembers())
 	c.waitVersion()
@@ -247,8 +325,8 @@ func (c *Cluster) ProtoMembers() []*pb.Member {
 		pScheme := SchemeFromTLSInfo(m.PeerTLSInfo)
 		cScheme := SchemeFromTLSInfo(m.ClientTLSInfo)
 		cm := &pb.Member{Name: m.Name}
-		for _, ln := range m.PeerListeners {
-			cm.PeerURLs = append(cm.PeerURLs, pScheme+"://"+ln.Addr().String())
+		for _, u := range m.PeerURLs {
+			cm.PeerURLs = append(cm.PeerURLs, pScheme+"://"+u.Host)
 		}
 		for _, ln := range m.ClientListeners {
 			cm.ClientURLs = append(cm.ClientURLs, cScheme+"://"+ln.Addr().String())
@@ -261,6 +339,10 @@ func (c *Cluster) ProtoMembers() []*pb.Member {
 func (c *Cluster) mustNewMember(t testutil.TB) *Member {
 	memberNumber := c.LastMemberNum
 	c.LastMemberNum++
+	var electionPriority uint32
+	if memberNumber < len(c.Cfg.ElectionPriorities) {
+		electionPriority = c.Cfg.ElectionPriorities[memberNumber]
+	}
 
 	m := MustNewMember(t,
 		MemberConfig{
@@ -272,7 +354,9 @@ func (c *Cluster) mustNewMember(t testutil.TB) *Member {
 			ClientTLS:                   c.Cfg.ClientTLS,
 			QuotaBackendBytes:           c.Cfg.QuotaBackendBytes,
 			MaxTxnOps:                   c.Cfg.MaxTxnOps,
+			MaxTxnTotalOps:              c.Cfg.MaxTxnTotalOps,
 			MaxRequestBytes:             c.Cfg.MaxRequestBytes,
+			MaxValueBytes:               c.Cfg.MaxValueBytes,
 			SnapshotCount:               c.Cfg.SnapshotCount,
 			SnapshotCatchUpEntries:      c.Cfg.SnapshotCatchUpEntries,
 			GrpcKeepAliveMinTime:        c.Cfg.GRPCKeepAliveMinTime,
@@ -282,14 +366,32 @@ func (c *Cluster) mustNewMember(t testutil.TB) *Member {
 			ClientMaxCallRecvMsgSize:    c.Cfg.ClientMaxCallRecvMsgSize,
 			UseIP:                       c.Cfg.UseIP,
 			UseBridge:                   c.Cfg.UseBridge,
+			UsePeerBridge:               c.Cfg.UsePeerBridge,
 			UseTCP:                      c.Cfg.UseTCP,
+			ManualClock:                 c.Cfg.ManualClock,
 			EnableLeaseCheckpoint:       c.Cfg.EnableLeaseCheckpoint,
 			LeaseCheckpointInterval:     c.Cfg.LeaseCheckpointInterval,
 			LeaseCheckpointPersist:      c.Cfg.LeaseCheckpointPersist,
 			WatchProgressNotifyInterval: c.Cfg.WatchProgressNotifyInterval,
+			MaxWatchStreamsPerConn:      c.Cfg.MaxWatchStreamsPerConn,
 			ExperimentalMaxLearners:     c.Cfg.ExperimentalMaxLearners,
 			DisableStrictReconfigCheck:  c.Cfg.DisableStrictReconfigCheck,
 			CorruptCheckTime:            c.Cfg.CorruptCheckTime,
+			CompactionBatchLimit:        c.Cfg.CompactionBatchLimit,
+			CompactionSleepInterval:     c.Cfg.CompactionSleepInterval,
+			HotKeyTracking:              c.Cfg.HotKeyTracking,
+			ReadCacheBytes:              c.Cfg.ReadCacheBytes,
+			ElectionPriority:            electionPriority,
+			BackendBatchInterval:        c.Cfg.BackendBatchInterval,
+			TracerOptions:               c.Cfg.TracerOptions,
+			DefragInterval:              c.Cfg.DefragInterval,
+			DefragRateBytesPerSec:       c.Cfg.DefragRateBytesPerSec,
+			DefragOnLeader:              c.Cfg.DefragOnLeader,
+			WriteRateLimitByUser:        c.Cfg.WriteRateLimitByUser,
+			RateLimitReads:              c.Cfg.RateLimitReads,
+			DedicatedWALDir:             c.Cfg.DedicatedWALDir,
+			DedicatedSnapDir:            c.Cfg.DedicatedSnapDir,
+			DedicatedBackendDir:         c.Cfg.DedicatedBackendDir,
 		})
 	m.DiscoveryURL = c.Cfg.DiscoveryURL
 	return m
@@ -304,7 +406,7 @@ func (c *Cluster) addMember(t testutil.TB) types.URLs {
 	// send add request to the Cluster
 	var err error
 	for i := 0; i < len(c.Members); i++ {
-		peerURL := scheme + "://" + m.PeerListeners[0].Addr().String()
+		peerURL := scheme + "://" + m.PeerURLs[0].Host
 		if err = c.AddMemberByURL(t, c.Members[i].Client, peerURL); err == nil {
 			break
 		}
@@ -356,6 +458,88 @@ func (c *Cluster) RemoveMember(t testutil.TB, cc *clientv3.Client, id uint64) er
 	if err != nil {
 		return err
 	}
+	c.WaitMemberRemoved(t, id)
+	return nil
+}
+
+// RemoveMemberByName removes the member with the given name via the v3 MemberRemove
+// API, which fails if no member or more than one member has the name.
+func (c *Cluster) RemoveMemberByName(t testutil.TB, cc *clientv3.Client, name string) error {
+	var id uint64
+	for _, m := range c.Members {
+		if m.Name == name {
+			id = uint64(m.Server.MemberId())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	_, err := cc.MemberRemoveByName(ctx, name)
+	cancel()
+	if err != nil {
+		return err
+	}
+	c.WaitMemberRemoved(t, id)
+	return nil
+}
+
+// ReconfigureMembers adds n voting members and removes the members with the
+// given ids via a single v3 MemberReconfigure request, then launches the added
+// members, terminates the removed ones once they stop by themselves and waits
+// for the members to agree on the membership. It returns the added members.
+func (c *Cluster) ReconfigureMembers(t testutil.TB, cc *clientv3.Client, n int, removes []uint64) []*Member {
+	scheme := SchemeFromTLSInfo(c.Cfg.PeerTLS)
+	added := make([]*Member, n)
+	adds := make([][]string, n)
+	for i := range added {
+		added[i] = c.mustNewMember(t)
+		adds[i] = []string{scheme + "://" + added[i].PeerURLs[0].Host}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	_, err := cc.MemberReconfigure(ctx, adds, removes)
+	cancel()
+	if err != nil {
+		t.Fatalf("reconfigure members failed: %v", err)
+	}
+
+	removed := make(map[uint64]bool)
+	for _, id := range removes {
+		removed[id] = true
+	}
+	var kept []*Member
+	for _, m := range c.Members {
+		if !removed[uint64(m.Server.MemberId())] {
+			kept = append(kept, m)
+			continue
+		}
+		m.Client.Close()
+		select {
+		case <-m.Server.StopNotify():
+			m.Terminate(t)
+		case <-time.After(time.Second + time.Duration(ElectionTicks)*framecfg.TickDuration + time.Second + rafthttp.ConnWriteTimeout):
+			t.Fatalf("failed to remove member %s in time", m.Server.MemberId())
+		}
+	}
+
+	initialPeerURLsMap := types.URLsMap{}
+	for _, m := range append(kept, added...) {
+		initialPeerURLsMap[m.Name] = m.PeerURLs
+	}
+	for _, m := range added {
+		m.InitialPeerURLsMap = initialPeerURLsMap
+		m.NewCluster = false
+		if err := m.Launch(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Members = append(kept, added...)
+	c.WaitMembersMatch(t, c.ProtoMembers())
+	return added
+}
+
+// WaitMemberRemoved terminates the removed member with the given id once it stops
+// by itself, and waits for the remaining members to agree on the membership.
+func (c *Cluster) WaitMemberRemoved(t testutil.TB, id uint64) {
 	newMembers := make([]*Member, 0)
 	for _, m := range c.Members {
 		if uint64(m.Server.MemberId()) != id {
@@ -376,7 +560,6 @@ func (c *Cluster) RemoveMember(t testutil.TB, cc *clientv3.Client, id uint64) er
 
 	c.Members = newMembers
 	c.WaitMembersMatch(t, c.ProtoMembers())
-	return nil
 }
 
 func (c *Cluster) WaitMembersMatch(t testutil.TB, membs []*pb.Member) {
@@ -412,16 +595,16 @@ func (c *Cluster) WaitLeader(t testing.TB) int {
 }
 
 // WaitMembersForLeader waits until given members agree on the same leader,
-// and returns its 'index' in the 'membs' list
+// and returns its 'index' in the 'membs' list. Stopped members are ignored.
+// It fails the test naming the members that never converged, or all reported
+// leaders if members disagree, when leader is not established in 30s.
 func (c *Cluster) WaitMembersForLeader(t testing.TB, membs []*Member) int {
 	t.Logf("WaitMembersForLeader")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	l := 0
-	for l = c.waitMembersForLeader(ctx, t, membs); l < 0; {
-		if ctx.Err() != nil {
-			t.Fatalf("WaitLeader FAILED: %v", ctx.Err())
-		}
+	l, err := c.waitMembersForLeader(ctx, t, membs)
+	if err != nil {
+		t.Fatalf("WaitLeader FAILED: %v", err)
 	}
 	t.Logf("WaitMembersForLeader succeeded. Cluster leader index: %v", l)
 
@@ -437,54 +620,85 @@ func (c *Cluster) WaitMembersForLeader(t testing.TB, membs []*Member) int {
 	return l
 }
 
-// WaitMembersForLeader waits until given members agree on the same leader,
-// and returns its 'index' in the 'membs' list
-func (c *Cluster) waitMembersForLeader(ctx context.Context, t testing.TB, membs []*Member) int {
-	possibleLead := make(map[uint64]bool)
-	var lead uint64
-	for _, m := range membs {
-		possibleLead[uint64(m.Server.MemberId())] = true
-	}
+// waitMembersForLeader polls the status of given members until they agree on
+// the same leader, and returns its 'index' in the 'membs' list.
+func (c *Cluster) waitMembersForLeader(ctx context.Context, t testing.TB, membs []*Member) (int, error) {
 	cc, err := c.ClusterClient(t)
 	if err != nil {
-		t.Fatal(err)
+		return -1, err
 	}
 	// ensure leader is up via linearizable get
 	for {
-		ctx, cancel := context.WithTimeout(ctx, 10*framecfg.TickDuration+time.Second)
-		_, err := cc.Get(ctx, "0")
+		gctx, cancel := context.WithTimeout(ctx, 10*framecfg.TickDuration+time.Second)
+		_, err := cc.Get(gctx, "0")
 		cancel()
 		if err == nil || strings.Contains(err.Error(), "Key not found") {
 			break
 		}
+		if ctx.Err() != nil {
+			return -1, fmt.Errorf("no leader serving linearizable requests: %v", err)
+		}
 	}
 
-	for lead == 0 || !possibleLead[lead] {
-		lead = 0
-		for _, m := range membs {
-			select {
-			case <-m.Server.StopNotify():
-				continue
-			default:
-			}
-			if lead != 0 && lead != m.Server.Lead() {
-				lead = 0
-				time.Sleep(10 * framecfg.TickDuration)
-				break
+	for {
+		leads := memberLeaders(ctx, membs)
+		if len(leads) == 1 {
+			for lead := range leads {
+				for i, m := range membs {
+					if lead != 0 && uint64(m.Server.MemberId()) == lead {
+						t.Logf("waitMembersForLeader found leader. Member: %v lead: %x", i, lead)
+						return i, nil
+					}
+				}
 			}
-			lead = m.Server.Lead()
+		}
+		select {
+		case <-ctx.Done():
+			return -1, fmt.Errorf("%v: %s", ctx.Err(), describeMemberLeaders(leads))
+		case <-time.After(framecfg.TickDuration):
 		}
 	}
+}
 
-	for i, m := range membs {
-		if uint64(m.Server.MemberId()) == lead {
-			t.Logf("waitMembersForLeader found leader. Member: %v lead: %x", i, lead)
-			return i
+// memberLeaders returns the names of the given running members grouped by the
+// leader they report via the status RPC. Members without leader are grouped under 0.
+// The server is asked directly if member has no client or its connection is
+// unavailable (e.g. blackholed bridge).
+func memberLeaders(ctx context.Context, membs []*Member) map[uint64][]string {
+	leads := make(map[uint64][]string)
+	for _, m := range membs {
+		select {
+		case <-m.Server.StopNotify():
+			continue
+		default:
 		}
+		lead := m.Server.Lead()
+		if m.Client != nil {
+			sctx, cancel := context.WithTimeout(ctx, 10*framecfg.TickDuration)
+			resp, err := pb.NewMaintenanceClient(m.Client.ActiveConnection()).Status(sctx, &pb.StatusRequest{})
+			cancel()
+			if err == nil {
+				lead = resp.Leader
+			}
+		}
+		leads[lead] = append(leads[lead], m.Name)
 	}
+	return leads
+}
 
-	t.Logf("waitMembersForLeader failed (-1)")
-	return -1
+func describeMemberLeaders(leads map[uint64][]string) string {
+	if len(leads[0]) > 0 {
+		return fmt.Sprintf("members never converged on a leader: %s", strings.Join(leads[0], ", "))
+	}
+	var reported []string
+	for lead, names := range leads {
+		reported = append(reported, fmt.Sprintf("%s report leader %x", strings.Join(names, ", "), lead))
+	}
+	sort.Strings(reported)
+	if len(reported) > 1 {
+		return fmt.Sprintf("members disagree on leader: %s", strings.Join(reported, "; "))
+	}
+	return fmt.Sprintf("leader is not one of the given members: %s", strings.Join(reported, "; "))
 }
 
 func (c *Cluster) WaitNoLeader() { c.WaitMembersNoLeader(c.Members) }
@@ -565,6 +779,8 @@ type Member struct {
 	GrpcServer     *grpc.Server
 	GrpcURL        string
 	GrpcBridge     *bridge
+	peerBridge     *bridge
+	clock          *manualClock
 
 	// ServerClient is a clientv3 that directly calls the etcdserver.
 	ServerClient *clientv3.Client
@@ -576,6 +792,7 @@ type Member struct {
 	ClientMaxCallRecvMsgSize int
 	UseIP                    bool
 	UseBridge                bool
+	UsePeerBridge            bool
 	UseTCP                   bool
 
 	IsLearner bool
@@ -598,7 +815,9 @@ type MemberConfig struct {
 	AuthTokenTTL                uint
 	QuotaBackendBytes           int64
 	MaxTxnOps                   uint
+	MaxTxnTotalOps              uint
 	MaxRequestBytes             uint
+	MaxValueBytes               uint
 	SnapshotCount               uint64
 	SnapshotCatchUpEntries      uint64
 	GrpcKeepAliveMinTime        time.Duration
@@ -608,14 +827,32 @@ type MemberConfig struct {
 	ClientMaxCallRecvMsgSize    int
 	UseIP                       bool
 	UseBridge                   bool
+	UsePeerBridge               bool
 	UseTCP                      bool
+	ManualClock                 bool
 	EnableLeaseCheckpoint       bool
 	LeaseCheckpointInterval     time.Duration
 	LeaseCheckpointPersist      bool
 	WatchProgressNotifyInterval time.Duration
+	MaxWatchStreamsPerConn      uint
 	ExperimentalMaxLearners     int
 	DisableStrictReconfigCheck  bool
 	CorruptCheckTime            time.Duration
+	CompactionBatchLimit        int
+	CompactionSleepInterval     time.Duration
+	HotKeyTracking              bool
+	ReadCacheBytes              int64
+	ElectionPriority            uint32
+	BackendBatchInterval        time.Duration
+	TracerOptions               []otelgrpc.Option
+	DefragInterval              time.Duration
+	DefragRateBytesPerSec       uint
+	DefragOnLeader              bool
+	WriteRateLimitByUser        map[string]float64
+	RateLimitReads              bool
+	DedicatedWALDir             bool
+	DedicatedSnapDir            bool
+	DedicatedBackendDir         bool
 }
 
 // MustNewMember return an inited member with the given name. If peerTLS is
@@ -632,7 +869,14 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 
 	pln := NewLocalListener(t)
 	m.PeerListeners = []net.Listener{pln}
-	m.PeerURLs, err = types.NewURLs([]string{peerScheme + "://" + pln.Addr().String()})
+	peerAddr := pln.Addr().String()
+	if mcfg.UsePeerBridge {
+		m.UsePeerBridge = true
+		if peerAddr, err = m.addPeerBridge(t, pln); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.PeerURLs, err = types.NewURLs([]string{peerScheme + "://" + peerAddr})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -652,7 +896,25 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 	if err != nil {
 		t.Fatal(err)
 	}
-	clusterStr := fmt.Sprintf("%s=%s://%s", mcfg.Name, peerScheme, pln.Addr().String())
+	if mcfg.DedicatedWALDir {
+		m.ServerConfig.DedicatedWALDir, err = os.MkdirTemp(t.TempDir(), "etcd-wal")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mcfg.DedicatedSnapDir {
+		m.ServerConfig.DedicatedSnapDir, err = os.MkdirTemp(t.TempDir(), "etcd-snap")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mcfg.DedicatedBackendDir {
+		m.ServerConfig.DedicatedBackendDir, err = os.MkdirTemp(t.TempDir(), "etcd-backend")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	clusterStr := fmt.Sprintf("%s=%s://%s", mcfg.Name, peerScheme, peerAddr)
 	m.InitialPeerURLsMap, err = types.NewURLsMap(clusterStr)
 	if err != nil {
 		t.Fatal(err)
@@ -666,16 +928,22 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 	m.ElectionTicks = ElectionTicks
 	m.InitialElectionTickAdvance = true
 	m.TickMs = uint(framecfg.TickDuration / time.Millisecond)
+	if mcfg.ManualClock {
+		m.clock = newManualClock(framecfg.TickDuration)
+		m.TickClock = m.clock
+	}
 	m.PreVote = true
 	m.QuotaBackendBytes = mcfg.QuotaBackendBytes
 	m.MaxTxnOps = mcfg.MaxTxnOps
 	if m.MaxTxnOps == 0 {
 		m.MaxTxnOps = embed.DefaultMaxTxnOps
 	}
+	m.MaxTxnTotalOps = mcfg.MaxTxnTotalOps
 	m.MaxRequestBytes = mcfg.MaxRequestBytes
 	if m.MaxRequestBytes == 0 {
 		m.MaxRequestBytes = embed.DefaultMaxRequestBytes
 	}
+	m.MaxValueBytes = mcfg.MaxValueBytes
 	m.SnapshotCount = etcdserver.DefaultSnapshotCount
 	if mcfg.SnapshotCount != 0 {
 		m.SnapshotCount = mcfg.SnapshotCount
@@ -720,6 +988,20 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 	m.LeaseCheckpointPersist = mcfg.LeaseCheckpointPersist
 
 	m.WatchProgressNotifyInterval = mcfg.WatchProgressNotifyInterval
+	m.MaxWatchStreamsPerConnection = mcfg.MaxWatchStreamsPerConn
+	m.CompactionBatchLimit = mcfg.CompactionBatchLimit
+	m.CompactionSleepInterval = mcfg.CompactionSleepInterval
+	m.HotKeyTracking = mcfg.HotKeyTracking
+	m.ReadCacheBytes = mcfg.ReadCacheBytes
+	m.ElectionPriority = mcfg.ElectionPriority
+	m.BackendBatchInterval = mcfg.BackendBatchInterval
+	m.ExperimentalEnableDistributedTracing = len(mcfg.TracerOptions) > 0
+	m.ExperimentalTracerOptions = mcfg.TracerOptions
+	m.DefragInterval = mcfg.DefragInterval
+	m.DefragRateBytesPerSec = mcfg.DefragRateBytesPerSec
+	m.DefragOnLeader = mcfg.DefragOnLeader
+	m.WriteRateLimitByUser = mcfg.WriteRateLimitByUser
+	m.RateLimitReads = mcfg.RateLimitReads
 
 	m.InitialCorruptCheck = true
 	if mcfg.CorruptCheckTime > time.Duration(0) {
@@ -842,6 +1124,52 @@ func (m *Member) addBridge() (*bridge, error) {
 	return m.GrpcBridge, nil
 }
 
+// addPeerBridge starts a bridge in front of the given peer listener, returning
+// the bridge address to advertise to the other members. Unlike the grpc bridge,
+// it outlives member stops and restarts.
+func (m *Member) addPeerBridge(t testutil.TB, pln net.Listener) (string, error) {
+	bln := NewLocalListener(t)
+	var err error
+	m.peerBridge, err = newBridge(dialer{network: "unix", addr: pln.Addr().String()}, bln)
+	if err != nil {
+		bln.Close()
+		return "", err
+	}
+	return bln.Addr().String(), nil
+}
+
+// PeerBridge returns the bridge in front of the member peer listener. Raft streams
+// carry the messages of a member on the connections it accepts, so DropOutbound
+// keeps the member from reaching its peers while it still receives their messages.
+func (m *Member) PeerBridge() *bridge {
+	if !m.UsePeerBridge {
+		m.Logger.Panic("Peer bridge not available. Please configure using peer bridge before creating Cluster.")
+	}
+	return m.peerBridge
+}
+
+// AdvanceTicks delivers n raft ticks to the member, returning once its raft
+// node has received all of them. The member must use ManualClock.
+func (m *Member) AdvanceTicks(n int) {
+	if m.clock == nil {
+		m.Logger.Panic("Manual clock not available. Please configure using manual clock before creating Cluster.")
+	}
+	m.clock.advance(n)
+}
+
+// campaign ticks the member until it becomes the leader.
+func (m *Member) campaign(t testutil.TB) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+	for m.Server.Leader() != m.Server.MemberId() {
+		if ctx.Err() != nil {
+			t.Fatalf("member %s failed to become leader: %v", m.Name, ctx.Err())
+		}
+		m.AdvanceTicks(1)
+		time.Sleep(framecfg.TickDuration)
+	}
+}
+
 func (m *Member) Bridge() *bridge {
 	if !m.UseBridge {
 		m.Logger.Panic("Bridge not available. Please configure using bridge before creating Cluster.")
@@ -1178,6 +1506,10 @@ func (m *Member) Resume() {
 
 // Close stops the member'Server etcdserver and closes its connections
 func (m *Member) Close() {
+	if m.Server != nil {
+		// fail pending proposals right away, instead of after the request timeout
+		m.Server.StopProposals()
+	}
 	if m.GrpcBridge != nil {
 		m.GrpcBridge.Close()
 		m.GrpcBridge = nil
@@ -1217,6 +1549,8 @@ func (m *Member) Close() {
 		verify.MustVerifyIfEnabled(verify.Config{
 			Logger:     m.Logger,
 			DataDir:    m.DataDir,
+			WALDir:     m.ServerConfig.DedicatedWALDir,
+			BackendDir: m.ServerConfig.DedicatedBackendDir,
 			ExactIndex: false,
 		})
 	}
@@ -1305,10 +1639,22 @@ func (m *Member) Terminate(t testutil.TB) {
 		zap.String("grpc-url", m.GrpcURL),
 	)
 	m.Close()
+	if m.peerBridge != nil {
+		m.peerBridge.Close()
+		m.peerBridge = nil
+	}
 	if !m.KeepDataDirTerminate {
 		if err := os.RemoveAll(m.ServerConfig.DataDir); err != nil {
 			t.Fatal(err)
 		}
+		for _, dir := range []string{m.ServerConfig.DedicatedWALDir, m.ServerConfig.DedicatedSnapDir, m.ServerConfig.DedicatedBackendDir} {
+			if dir == "" {
+				continue
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				t.Fatal(err)
+			}
+		}
 	}
 	m.Logger.Info(
 		"terminated a member",
@@ -1374,6 +1720,119 @@ func (m *Member) RecoverPartition(t testutil.TB, others ...*Member) {
 	}
 }
 
+// WaitRaftState waits until the raft node of m is in the given state.
+func (m *Member) WaitRaftState(t testutil.TB, state raft.StateType) {
+	reached := make(chan struct{})
+	var once sync.Once
+	unregister := m.Server.RegisterRaftStateObserver(func(_, to raft.StateType, _ uint64) {
+		if to == state {
+			once.Do(func() { close(reached) })
+		}
+	})
+	defer unregister()
+	if m.Server.RaftState() == state {
+		return
+	}
+	select {
+	case <-reached:
+	case <-time.After(RequestWaitTimeout):
+		t.Fatalf("timed out waiting for %s to become %s, it is %s", m.Name, state, m.Server.RaftState())
+	}
+}
+
+// WaitAppliedIndex waits until m has applied the raft entries up to index idx.
+// It fails t if m is stopped.
+func (m *Member) WaitAppliedIndex(t testutil.TB, idx uint64) {
+	waitMembersAppliedIndex(t, []*Member{m}, idx)
+}
+
+// WaitMembersAppliedIndex waits until all the running members of c have applied
+// the raft entries up to index idx. The members stopped before the wait are
+// skipped and logged; a member stopping during the wait fails t. A member that
+// cannot reach idx, as when it is partitioned, fails t once the wait times out.
+func (c *Cluster) WaitMembersAppliedIndex(t testutil.TB, idx uint64) {
+	var membs []*Member
+	for _, m := range c.Members {
+		if m.isStopped() {
+			t.Logf("skipping stopped member %s while waiting for members to apply index %d", m.Name, idx)
+			continue
+		}
+		membs = append(membs, m)
+	}
+	waitMembersAppliedIndex(t, membs, idx)
+}
+
+func waitMembersAppliedIndex(t testutil.TB, membs []*Member, idx uint64) {
+	timeout := time.After(RequestWaitTimeout)
+	for {
+		var behind []string
+		for _, m := range membs {
+			if m.isStopped() {
+				t.Fatalf("member %s is stopped, it cannot apply index %d", m.Name, idx)
+			}
+			if applied := m.Server.AppliedIndex(); applied < idx {
+				behind = append(behind, fmt.Sprintf("%s (applied index %d)", m.Name, applied))
+			}
+		}
+		if len(behind) == 0 {
+			return
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for members to apply index %d: %s", idx, strings.Join(behind, ", "))
+		case <-time.After(framecfg.TickDuration):
+		}
+	}
+}
+
+// isStopped reports whether m has no running server, as when it was stopped.
+func (m *Member) isStopped() bool {
+	if m.Server == nil {
+		return true
+	}
+	select {
+	case <-m.Server.StopNotify():
+		return true
+	default:
+		return false
+	}
+}
+
+// LocalMemberList returns the membership as seen by m. The list is served from
+// m's local view without requiring a leader, so it can be inspected while m is
+// partitioned.
+func (m *Member) LocalMemberList(t testutil.TB) []*pb.Member {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestWaitTimeout)
+	defer cancel()
+	resp, err := m.Client.MemberList(ctx, clientv3.WithSerializable())
+	if err != nil {
+		t.Fatalf("failed to list members of %s: %v", m.Name, err)
+	}
+	return resp.Members
+}
+
+// Self returns the identity of m as reported by m itself.
+func (m *Member) Self(t testutil.TB) *clientv3.MemberSelfResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestWaitTimeout)
+	defer cancel()
+	resp, err := m.Client.MemberSelf(ctx, m.GrpcURL)
+	if err != nil {
+		t.Fatalf("failed to get the identity of %s: %v", m.Name, err)
+	}
+	return resp
+}
+
+// TriggerSnapshot makes m take a raft snapshot and compact its raft log.
+func (m *Member) TriggerSnapshot(t testutil.TB) *clientv3.TriggerSnapshotResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestWaitTimeout)
+	defer cancel()
+	resp, err := m.Client.TriggerSnapshot(ctx, m.GrpcURL)
+	if err != nil {
+		t.Fatalf("failed to trigger snapshot on %s: %v", m.Name, err)
+	}
+	return resp
+}
+
 func (m *Member) ReadyNotify() <-chan struct{} {
 	return m.Server.ReadyNotify()
 }
@@ -1588,7 +2047,7 @@ func (c *Cluster) AddAndLaunchLearnerMember(t testutil.TB) {
 	m.IsLearner = true
 
 	scheme := SchemeFromTLSInfo(c.Cfg.PeerTLS)
-	peerURLs := []string{scheme + "://" + m.PeerListeners[0].Addr().String()}
+	peerURLs := []string{scheme + "://" + m.PeerURLs[0].Host}
 
 	cli := c.Client(0)
 	_, err := cli.MemberAddAsLearner(context.Background(), peerURLs)
@@ -1612,6 +2071,72 @@ func (c *Cluster) AddAndLaunchLearnerMember(t testutil.TB) {
 	c.waitMembersMatch(t)
 }
 
+// AddLearners adds n learner members to Cluster via v3 MemberAddAsLearner API,
+// launches them and waits until each of them has caught up with the leader,
+// so that it can be promoted. It returns the IDs of the added learners.
+func (c *Cluster) AddLearners(t testing.TB, n int) []types.ID {
+	ids := make([]types.ID, 0, n)
+	for i := 0; i < n; i++ {
+		c.AddAndLaunchLearnerMember(t)
+		ids = append(ids, c.Members[len(c.Members)-1].ID())
+	}
+	for _, id := range ids {
+		c.WaitLearnerPromotable(t, id)
+	}
+	return ids
+}
+
+// WaitLearnerPromotable waits until the learner with given id has applied all
+// the entries committed by the leader at the time of the call. The leader
+// rejects promotion of a learner that has not caught up yet.
+func (c *Cluster) WaitLearnerPromotable(t testing.TB, id types.ID) {
+	var learner *Member
+	for _, m := range c.Members {
+		if m.ID() == id {
+			learner = m
+		}
+	}
+	if learner == nil {
+		t.Fatalf("learner %s not found in Cluster", id)
+	}
+	lead := c.Members[c.WaitLeader(t)]
+	target := lead.Server.CommittedIndex()
+
+	t.Logf("waiting for learner %s to catch up with leader %s (applied index: %d, target: %d)",
+		id, lead.ID(), learner.Server.AppliedIndex(), target)
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+	for learner.Server.AppliedIndex() < target {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("learner %s did not catch up with leader %s: %v", id, lead.ID(), ctx.Err())
+		case <-time.After(framecfg.TickDuration):
+		}
+	}
+}
+
+// LearnerGap returns the gap between the leader committed index and the applied
+// index, as reported in the status of the learner with given id. It is 0 once the
+// learner has caught up, or if the member is not a learner.
+func (c *Cluster) LearnerGap(t testing.TB, id types.ID) uint64 {
+	var learner *Member
+	for _, m := range c.Members {
+		if m.ID() == id {
+			learner = m
+		}
+	}
+	if learner == nil {
+		t.Fatalf("learner %s not found in Cluster", id)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+	resp, err := learner.Client.Status(ctx, learner.GRPCURL())
+	if err != nil {
+		t.Fatalf("failed to get status of learner %s: %v", id, err)
+	}
+	return resp.LearnerProgress
+}
+
 // getMembers returns a list of members in Cluster, in format of etcdserverpb.Member
 func (c *Cluster) getMembers() []*pb.Member {
 	var mems []*pb.Member
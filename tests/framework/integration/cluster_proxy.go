@@ -106,6 +106,10 @@ func (pc *proxyCloser) Close() error {
 	return err
 }
 
+func (pc *proxyCloser) Replay(ctx context.Context, key string, startRev, endRev int64, opts ...clientv3.OpOption) clientv3.WatchChan {
+	return clientv3.Replay(ctx, pc.Watcher, key, startRev, endRev, opts...)
+}
+
 func newClientV3(cfg clientv3.Config) (*clientv3.Client, error) {
 	c, err := clientv3.New(cfg)
 	if err != nil {
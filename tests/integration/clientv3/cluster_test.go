@@ -16,6 +16,7 @@ package clientv3test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"reflect"
@@ -23,7 +24,10 @@ import (
 	"testing"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/pkg/v3/types"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/etcdserver"
 	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
 )
 
@@ -45,6 +49,48 @@ func TestMemberList(t *testing.T) {
 	}
 }
 
+func TestMemberListSerializableWhenPartitioned(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	m := clus.Members[(lead+1)%3]
+	others := []*integration2.Member{clus.Members[lead], clus.Members[(lead+2)%3]}
+
+	// the isolated follower loses its leader after an election timeout
+	m.InjectPartition(t, others...)
+	clus.WaitMembersNoLeader([]*integration2.Member{m})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	_, err := m.Client.MemberList(ctx)
+	cancel()
+	if err == nil {
+		t.Fatal("expected linearizable member list to fail without a leader")
+	}
+
+	resp, err := m.Client.MemberList(context.Background(), clientv3.WithSerializable())
+	if err != nil {
+		t.Fatalf("failed to list members without a leader: %v", err)
+	}
+	if len(resp.Members) != 3 {
+		t.Errorf("number of members = %d, want %d", len(resp.Members), 3)
+	}
+	if resp.Header.MemberId != uint64(m.ID()) {
+		t.Errorf("header member ID = %x, want %x", resp.Header.MemberId, m.ID())
+	}
+	// the isolated member may campaign, so its term can only have grown since
+	if term := m.Server.Term(); resp.Header.RaftTerm == 0 || resp.Header.RaftTerm > term {
+		t.Errorf("header raft term = %d, want within (0, %d]", resp.Header.RaftTerm, term)
+	}
+	if membs := m.LocalMemberList(t); len(membs) != 3 {
+		t.Errorf("number of local members = %d, want %d", len(membs), 3)
+	}
+
+	m.RecoverPartition(t, others...)
+}
+
 func TestMemberAdd(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -88,6 +134,57 @@ func TestMemberAddWithExistingURLs(t *testing.T) {
 	}
 }
 
+// TestMemberAddRetryNoLeader ensures MemberAdd is retried under ReconfigRetryPolicy
+// while the serving member has no leader, and succeeds once a leader is elected.
+func TestMemberAddRetryNoLeader(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	leader := clus.Members[lead]
+	others := []*integration2.Member{clus.Members[(lead+1)%3], clus.Members[(lead+2)%3]}
+
+	cli, err := integration2.NewClient(t, clientv3.Config{
+		Endpoints: []string{leader.GRPCURL()},
+		ReconfigRetryPolicy: &clientv3.ReconfigRetryPolicy{
+			Budget:      10 * time.Second,
+			WaitBetween: 100 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	// peers must be connected for a health interval before reconfiguration is accepted
+	time.Sleep(etcdserver.HealthInterval)
+
+	// the isolated leader steps down once it loses quorum
+	leader.InjectPartition(t, others...)
+	clus.WaitMembersNoLeader([]*integration2.Member{leader})
+
+	var retries int
+	ctx := clientv3.WithReconfigRetries(context.Background(), &retries)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := cli.MemberAdd(ctx, []string{"http://127.0.0.1:12345"})
+		errc <- err
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	leader.RecoverPartition(t, others...)
+
+	if err = <-errc; err != nil {
+		t.Fatalf("failed to add member after a new leader was elected (%v)", err)
+	}
+	if retries == 0 {
+		t.Fatal("expected MemberAdd to be retried while the cluster had no leader")
+	}
+	t.Logf("MemberAdd succeeded after %d retries", retries)
+}
+
 func TestMemberRemove(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -126,6 +223,77 @@ func TestMemberRemove(t *testing.T) {
 	}
 }
 
+func TestMemberRemoveByName(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, DisableStrictReconfigCheck: true})
+	defer clus.Terminate(t)
+
+	capi := clus.Client(1)
+	if _, err := capi.MemberRemoveByName(context.Background(), "no-such-member"); !errors.Is(err, rpctypes.ErrMemberNameNotFound) {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrMemberNameNotFound, err)
+	}
+
+	if err := clus.RemoveMemberByName(t, capi, clus.Members[0].Name); err != nil {
+		t.Fatalf("failed to remove member %v", err)
+	}
+
+	resp, err := capi.MemberList(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list member %v", err)
+	}
+	if len(resp.Members) != 2 {
+		t.Errorf("number of members = %d, want %d", len(resp.Members), 2)
+	}
+}
+
+// TestMemberRemoveLeaderWithLeadershipTransfer ensures removing the leader with
+// WithLeadershipTransfer hands off its leadership before the removal, so that
+// the cluster has a leader as soon as the removal completes. With two members,
+// the leadership is handed off to the member serving the removal.
+func TestMemberRemoveLeaderWithLeadershipTransfer(t *testing.T) {
+	for _, size := range []int{2, 3} {
+		t.Run(fmt.Sprintf("%d members", size), func(t *testing.T) {
+			testMemberRemoveLeaderWithLeadershipTransfer(t, size)
+		})
+	}
+}
+
+func testMemberRemoveLeaderWithLeadershipTransfer(t *testing.T, size int) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: size, DisableStrictReconfigCheck: true})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	leadID := uint64(clus.Members[lead].Server.MemberId())
+	term := clus.Members[lead].Server.Term()
+	follower := (lead + 1) % size
+
+	capi := clus.Client(follower)
+	if _, err := capi.MemberRemove(context.Background(), leadID, clientv3.WithLeadershipTransfer()); err != nil {
+		t.Fatalf("failed to remove leader %v", err)
+	}
+
+	// the new leader is elected by the transfer, not after an election timeout
+	if l := clus.Members[follower].Server.Lead(); l == leadID || l == 0 {
+		t.Fatalf("leader after removal = %x, want a leader other than %x", l, leadID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), integration2.RequestTimeout)
+	_, err := capi.Put(ctx, "foo", "bar")
+	cancel()
+	if err != nil {
+		t.Fatalf("failed to put after removing leader %v", err)
+	}
+
+	clus.WaitMemberRemoved(t, leadID)
+	for _, m := range clus.Members {
+		if got := m.Server.Term(); got != term+1 {
+			t.Errorf("member %s term = %d, want %d after a single transfer", m.Name, got, term+1)
+		}
+	}
+}
+
 func TestMemberUpdate(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -292,6 +460,140 @@ func TestMemberPromote(t *testing.T) {
 	}
 }
 
+// TestMemberPromoteAddedLearners ensures that learners added by the Cluster
+// AddLearners helper can be promoted right away.
+func TestMemberPromoteAddedLearners(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, ExperimentalMaxLearners: 2, DisableStrictReconfigCheck: true})
+	defer clus.Terminate(t)
+
+	ids := clus.AddLearners(t, 2)
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 learners to be added, got %d", len(ids))
+	}
+	learners, err := clus.GetLearnerMembers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(learners) != 2 {
+		t.Fatalf("expected 2 learners in cluster, got %d", len(learners))
+	}
+
+	capi := clus.Client(clus.WaitLeader(t))
+	for _, id := range ids {
+		if _, err := capi.MemberPromote(context.Background(), uint64(id)); err != nil {
+			t.Fatalf("failed to promote learner %s: %v", id, err)
+		}
+	}
+}
+
+// TestMemberStatusLearnerProgress ensures a learner reports its gap to the leader
+// committed index in its status, and voting members always report no gap.
+func TestMemberStatusLearnerProgress(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, DisableStrictReconfigCheck: true})
+	defer clus.Terminate(t)
+
+	id := clus.AddLearners(t, 1)[0]
+
+	for i := 0; i < 100; i++ {
+		if _, err := clus.Client(0).Put(context.Background(), fmt.Sprintf("foo%d", i), "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timeout := time.After(10 * time.Second)
+	for gap := clus.LearnerGap(t, id); gap != 0; gap = clus.LearnerGap(t, id) {
+		select {
+		case <-timeout:
+			t.Fatalf("learner %s did not catch up with leader, gap %d", id, gap)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	for _, m := range clus.Members {
+		if m.IsLearner {
+			continue
+		}
+		resp, err := m.Client.Status(context.Background(), m.GRPCURL())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.LearnerProgress != 0 {
+			t.Errorf("expected no learner progress for voting member %s, got %d", m.Name, resp.LearnerProgress)
+		}
+	}
+}
+
+// TestMemberPromoteNonPromotableLearner ensures that a learner added with
+// WithNonPromotable can never be promoted, even across restarts, while it
+// still serves serializable reads.
+func TestMemberPromoteNonPromotableLearner(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, DisableStrictReconfigCheck: true})
+	defer clus.Terminate(t)
+
+	leaderIdx := clus.WaitLeader(t)
+	followerIdx := (leaderIdx + 1) % 3
+	if _, err := clus.Client(leaderIdx).Put(context.Background(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	urls := []string{"http://127.0.0.1:1234"}
+	memberAddResp, err := clientv3.MemberAddAsLearnerWithOptions(context.Background(), clus.Client(followerIdx), urls, clientv3.WithNonPromotable())
+	if err != nil {
+		t.Fatalf("failed to add member %v", err)
+	}
+	if !memberAddResp.Member.IsLearner || !memberAddResp.Member.NonPromotable {
+		t.Fatalf("added a non-promotable learner, got resp.Member = %+v", memberAddResp.Member)
+	}
+	learnerID := memberAddResp.Member.ID
+
+	learnerMember := clus.MustNewMember(t, memberAddResp)
+	if err := learnerMember.Launch(); err != nil {
+		t.Fatal(err)
+	}
+	clus.WaitLearnerPromotable(t, types.ID(learnerID))
+
+	// the learner is in sync, yet promoting it fails whichever member serves the request
+	for _, idx := range []int{leaderIdx, followerIdx} {
+		_, err = clus.Client(idx).MemberPromote(context.Background(), learnerID)
+		if !errors.Is(err, rpctypes.ErrMemberNotPromotable) {
+			t.Fatalf("expected %v, got %v", rpctypes.ErrMemberNotPromotable, err)
+		}
+	}
+
+	// the flag survives a restart of the learner
+	learnerMember.Stop(t)
+	if err := learnerMember.Restart(t); err != nil {
+		t.Fatal(err)
+	}
+	<-learnerMember.ReadyNotify()
+	if m := learnerMember.Server.Cluster().Member(types.ID(learnerID)); m == nil || !m.NonPromotable {
+		t.Fatalf("expected the restarted learner to be non-promotable, got %+v", m)
+	}
+
+	cli, err := integration2.NewClient(t, clientv3.Config{
+		Endpoints:   []string{learnerMember.GRPCURL()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("failed to create clientv3: %v", err)
+	}
+	defer cli.Close()
+
+	getResp, err := cli.Get(context.Background(), "foo", clientv3.WithSerializable())
+	if err != nil {
+		t.Fatalf("failed serializable read on learner: %v", err)
+	}
+	if len(getResp.Kvs) != 1 || string(getResp.Kvs[0].Value) != "bar" {
+		t.Fatalf("unexpected serializable read on learner: %+v", getResp.Kvs)
+	}
+}
+
 // TestMemberPromoteMemberNotLearner ensures that promoting a voting member fails.
 func TestMemberPromoteMemberNotLearner(t *testing.T) {
 	integration2.BeforeTest(t, integration2.WithFailpoint("raftBeforeAdvance", `sleep(100)`))
@@ -0,0 +1,89 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+func TestCompactLoop(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	wctx, wcancel := context.WithCancel(context.Background())
+	defer wcancel()
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		for i := 0; wctx.Err() == nil; i++ {
+			cli.Put(wctx, "foo", fmt.Sprintf("v%d", i))
+		}
+	}()
+
+	const retain = 20
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := clientv3.CompactLoop(ctx, cli, retain, 10*time.Millisecond)
+
+	// the first revisions are compacted away as the writes go on
+	require.Eventually(t, func() bool {
+		_, err := cli.Get(context.TODO(), "foo", clientv3.WithRev(2))
+		return err == rpctypes.ErrCompacted
+	}, 5*time.Second, 10*time.Millisecond)
+	wcancel()
+	<-donec
+
+	// the last revisions are kept
+	resp, err := cli.Get(context.TODO(), "foo")
+	require.NoError(t, err)
+	_, err = cli.Get(context.TODO(), "foo", clientv3.WithRev(resp.Header.Revision-retain+1))
+	require.NoError(t, err)
+
+	// a revision compacted by someone else is not an error of the loop
+	_, err = cli.Compact(context.TODO(), resp.Header.Revision)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	// the loop stops once the context is canceled
+	cancel()
+	for err := range errc {
+		require.NoError(t, err)
+	}
+}
+
+func TestCompactLoopInvalidRetainRevisions(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	for _, retain := range []int64{0, -1} {
+		errc := clientv3.CompactLoop(context.Background(), clus.RandClient(), retain, time.Millisecond)
+		require.ErrorIs(t, <-errc, clientv3.ErrInvalidRetainRevisions)
+		_, ok := <-errc
+		require.False(t, ok, "expected the loop not to start")
+	}
+}
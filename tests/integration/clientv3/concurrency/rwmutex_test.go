@@ -0,0 +1,236 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+func newRWMutexSessions(t *testing.T, n int, opts ...concurrency.SessionOption) []*concurrency.Session {
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cli.Close() })
+
+	sessions := make([]*concurrency.Session, n)
+	for i := range sessions {
+		if sessions[i], err = concurrency.NewSession(cli, opts...); err != nil {
+			t.Fatal(err)
+		}
+		s := sessions[i]
+		t.Cleanup(func() { s.Close() })
+	}
+	return sessions
+}
+
+// expectBlocked ensures f does not return within a while, and returns a channel
+// receiving its error once it does.
+func expectBlocked(t *testing.T, f func() error) <-chan error {
+	t.Helper()
+	errc := make(chan error, 1)
+	go func() { errc <- f() }()
+	select {
+	case err := <-errc:
+		t.Fatalf("expected to be blocked, returned %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	return errc
+}
+
+func expectUnblocked(t *testing.T, errc <-chan error) {
+	t.Helper()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("still blocked")
+	}
+}
+
+// TestRWMutexReadersAndWriter ensures readers share the lock, a writer waits
+// for the readers holding the lock, and the readers coming after a waiting
+// writer wait for it.
+func TestRWMutexReadersAndWriter(t *testing.T) {
+	ss := newRWMutexSessions(t, 4)
+	ctx := context.TODO()
+	r1, r2 := concurrency.NewRWMutex(ss[0], "/my-rwlock"), concurrency.NewRWMutex(ss[1], "/my-rwlock")
+	w := concurrency.NewRWMutex(ss[2], "/my-rwlock")
+	r3 := concurrency.NewRWMutex(ss[3], "/my-rwlock")
+
+	if err := r1.RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := r2.RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	werrc := expectBlocked(t, func() error { return w.Lock(ctx) })
+	// the writer waits, so r3 queues behind it
+	r3errc := expectBlocked(t, func() error { return r3.RLock(ctx) })
+
+	if err := r1.RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-werrc:
+		t.Fatalf("expected the writer to wait for r2, returned %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if err := r2.RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectUnblocked(t, werrc)
+
+	select {
+	case err := <-r3errc:
+		t.Fatalf("expected r3 to wait for the writer, returned %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if err := w.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectUnblocked(t, r3errc)
+	if err := r3.RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := r3.RUnlock(ctx); !errors.Is(err, concurrency.ErrLockReleased) {
+		t.Fatalf("expected %v, got %v", concurrency.ErrLockReleased, err)
+	}
+}
+
+// TestRWMutexMutualExclusion ensures a writer never holds the lock along with
+// another writer or a reader, while readers and writers contend for it.
+func TestRWMutexMutualExclusion(t *testing.T) {
+	const readers, writers, rounds = 4, 2, 5
+	ss := newRWMutexSessions(t, readers+writers)
+
+	var (
+		mu               sync.Mutex
+		reading, writing int
+	)
+	enter := func(write bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if write {
+			writing++
+		} else {
+			reading++
+		}
+		if writing > 1 || (writing == 1 && reading > 0) {
+			t.Errorf("%d writers and %d readers hold the lock", writing, reading)
+		}
+	}
+	leave := func(write bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if write {
+			writing--
+		} else {
+			reading--
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i, s := range ss {
+		write := i >= readers
+		rwm := concurrency.NewRWMutex(s, "/my-rwlock")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, unlock := rwm.RLock, rwm.RUnlock
+			if write {
+				lock, unlock = rwm.Lock, rwm.Unlock
+			}
+			for j := 0; j < rounds; j++ {
+				if err := lock(ctx); err != nil {
+					t.Error(err)
+					return
+				}
+				enter(write)
+				time.Sleep(20 * time.Millisecond)
+				leave(write)
+				if err := unlock(ctx); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestRWMutexSessionExpired ensures the lock held by a session is released
+// when the session is gone.
+func TestRWMutexSessionExpired(t *testing.T) {
+	ss := newRWMutexSessions(t, 2, concurrency.WithTTL(1))
+	ctx := context.TODO()
+	w, r := concurrency.NewRWMutex(ss[0], "/my-rwlock"), concurrency.NewRWMutex(ss[1], "/my-rwlock")
+
+	if err := w.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rerrc := expectBlocked(t, func() error { return r.RLock(ctx) })
+
+	// the writer dies without unlocking, its lease expires
+	ss[0].Orphan()
+	expectUnblocked(t, rerrc)
+	if err := r.RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRWMutexUpgrade ensures a session holding the lock in one mode cannot
+// lock it in the other, instead of waiting on its own key.
+func TestRWMutexUpgrade(t *testing.T) {
+	ss := newRWMutexSessions(t, 1)
+	ctx := context.TODO()
+	r, w := concurrency.NewRWMutex(ss[0], "/my-rwlock"), concurrency.NewRWMutex(ss[0], "/my-rwlock")
+
+	if err := r.RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Lock(ctx); !errors.Is(err, concurrency.ErrLockUpgrade) {
+		t.Fatalf("expected %v, got %v", concurrency.ErrLockUpgrade, err)
+	}
+	// the failed upgrade does not release nor take over the read lock
+	if err := w.Unlock(ctx); !errors.Is(err, concurrency.ErrLockReleased) {
+		t.Fatalf("expected %v, got %v", concurrency.ErrLockReleased, err)
+	}
+	if err := r.RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RLock(ctx); !errors.Is(err, concurrency.ErrLockUpgrade) {
+		t.Fatalf("expected %v, got %v", concurrency.ErrLockUpgrade, err)
+	}
+	if err := w.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
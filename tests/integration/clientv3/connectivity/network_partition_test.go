@@ -274,6 +274,87 @@ func testBalancerUnderNetworkPartitionWatch(t *testing.T, isolateLeader bool) {
 	}
 }
 
+// TestRequireLeaderConfigUnderNetworkPartition ensures the watches and the
+// lease keep-alives of a client configured with RequireLeader fail once its
+// member stops hearing from the others, while the watches of a client without
+// it keep waiting and serializable reads are still served.
+func TestRequireLeaderConfigUnderNetworkPartition(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{
+		Size:          3,
+		UsePeerBridge: true,
+	})
+	defer clus.Terminate(t)
+
+	target := (clus.WaitLeader(t) + 1) % 3
+	eps := []string{clus.Members[target].GRPCURL()}
+
+	reqLeaderCli, err := integration2.NewClient(t, clientv3.Config{Endpoints: eps, RequireLeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reqLeaderCli.Close()
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: eps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	clientv3test.MustWaitPinReady(t, reqLeaderCli)
+	clientv3test.MustWaitPinReady(t, cli)
+
+	lresp, err := reqLeaderCli.Grant(context.Background(), 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kch, err := reqLeaderCli.KeepAlive(context.Background(), lresp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqLeaderWch := reqLeaderCli.Watch(context.Background(), "foo", clientv3.WithCreatedNotify())
+	wch := cli.Watch(context.Background(), "foo", clientv3.WithCreatedNotify())
+	for _, ch := range []clientv3.WatchChan{reqLeaderWch, wch} {
+		select {
+		case <-ch:
+		case <-time.After(integration2.RequestWaitTimeout):
+			t.Fatal("took too long to create watch")
+		}
+	}
+
+	// the raft messages of a member are read from its peer listener, so
+	// blackholing the bridges of the others cuts eps[target] from the leader
+	clus.Members[(target+1)%3].PeerBridge().Blackhole()
+	clus.Members[(target+2)%3].PeerBridge().Blackhole()
+
+	select {
+	case ev := <-reqLeaderWch:
+		if err = ev.Err(); err != rpctypes.ErrNoLeader {
+			t.Fatalf("expected %v, got %v", rpctypes.ErrNoLeader, err)
+		}
+	case <-time.After(integration2.RequestWaitTimeout): // enough time to detect leader lost
+		t.Fatal("took too long to detect leader lost")
+	}
+	timeout := time.After(integration2.RequestWaitTimeout)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-kch:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("took too long to close the keep alive channel")
+		}
+	}
+	select {
+	case ev := <-wch:
+		t.Fatalf("expected watch without require leader to keep waiting, got %+v", ev)
+	case <-time.After(time.Second):
+	}
+
+	// unary requests do not require a leader
+	if _, err = reqLeaderCli.Get(context.Background(), "foo", clientv3.WithSerializable()); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDropReadUnderNetworkPartition(t *testing.T) {
 	integration2.BeforeTest(t)
 
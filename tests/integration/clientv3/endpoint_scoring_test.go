@@ -0,0 +1,66 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestEndpointHealthScoring ensures the requests of a client balancing its
+// endpoints by health scores are spread over healthy members, and that the
+// scores of the endpoints are reported.
+func TestEndpointHealthScoring(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	eps := []string{clus.Members[0].GRPCURL(), clus.Members[1].GRPCURL(), clus.Members[2].GRPCURL()}
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: eps, DialTimeout: 5 * time.Second, EndpointHealthScoring: true})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, err = cli.Put(context.TODO(), "foo", "bar")
+	require.NoError(t, err)
+
+	served := make(map[uint64]int)
+	for i := 0; i < 30; i++ {
+		resp, err := cli.Get(context.TODO(), "foo", clientv3.WithSerializable())
+		require.NoError(t, err)
+		served[resp.Header.MemberId]++
+	}
+	if len(served) < 2 {
+		t.Fatalf("expected reads to be spread across members, got %v", served)
+	}
+
+	scores := cli.EndpointScores()
+	require.NotEmpty(t, scores)
+	for ep, score := range scores {
+		require.Contains(t, eps, ep)
+		require.Zero(t, score.ErrorRate, "endpoint %s", ep)
+		require.Greater(t, score.Latency, time.Duration(0), "endpoint %s", ep)
+		require.Greater(t, score.Weight, 0.0, "endpoint %s", ep)
+	}
+
+	plain := clus.Client(0)
+	require.Nil(t, plain.EndpointScores())
+}
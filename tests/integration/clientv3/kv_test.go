@@ -17,6 +17,7 @@ package clientv3test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
@@ -29,6 +30,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/api/v3/version"
@@ -288,6 +290,209 @@ func TestKVRange(t *testing.T) {
 	}
 }
 
+// TestKVGetCountOnly ensures count only ranges return the counts of full ranges
+// at the same revision, on every member while keys are written, and fail the
+// same way at compacted revisions.
+func TestKVGetCountOnly(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy serves serializable reads from its cache")
+	}
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	ctx := context.TODO()
+	for i := 0; i < 20; i++ {
+		if _, err := clus.Client(0).Put(ctx, fmt.Sprintf("foo%d", i), "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	donec, errc := make(chan struct{}), make(chan error, 1)
+	go func() {
+		kv := clus.Client(0)
+		for i := 0; ; i++ {
+			select {
+			case <-donec:
+				errc <- nil
+				return
+			default:
+			}
+			op := clientv3.OpPut(fmt.Sprintf("foo%d", i%20), "bar")
+			if i%3 == 0 {
+				op = clientv3.OpDelete(fmt.Sprintf("foo%d", (i+7)%20))
+			}
+			if _, err := kv.Do(ctx, op); err != nil {
+				errc <- err
+				return
+			}
+		}
+	}()
+
+	// checkCount compares the count only range at rev, or at the current
+	// revision if rev is 0, with the full range at the same revision.
+	checkCount := func(kv clientv3.KV, rev int64, opts ...clientv3.OpOption) {
+		opts = append(opts, clientv3.WithPrefix())
+		cresp, err := kv.Get(ctx, "foo", append(opts, clientv3.WithRev(rev), clientv3.WithCountOnly())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cresp.Kvs) != 0 {
+			t.Fatalf("count only range returned %d keys", len(cresp.Kvs))
+		}
+		if rev == 0 {
+			rev = cresp.Header.Revision
+		}
+		resp, err := kv.Get(ctx, "foo", append(opts, clientv3.WithRev(rev))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cresp.Count != resp.Count || int64(len(resp.Kvs)) != resp.Count {
+			t.Fatalf("count only range counted %d keys at revision %d, full range got %d keys (count %d)", cresp.Count, rev, len(resp.Kvs), resp.Count)
+		}
+	}
+
+	for round := 0; round < 10; round++ {
+		for i := range clus.Members {
+			checkCount(clus.Client(i), 0, clientv3.WithSerializable())
+		}
+		if round != 5 {
+			continue
+		}
+
+		resp, err := clus.Client(0).Get(ctx, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		compactRev := resp.Header.Revision - 5
+		if _, err = clus.Client(0).Compact(ctx, compactRev); err != nil {
+			t.Fatal(err)
+		}
+		for _, opts := range [][]clientv3.OpOption{
+			{clientv3.WithPrefix(), clientv3.WithRev(compactRev - 1)},
+			{clientv3.WithPrefix(), clientv3.WithRev(compactRev - 1), clientv3.WithCountOnly()},
+		} {
+			if _, err = clus.Client(0).Get(ctx, "foo", opts...); !errors.Is(err, rpctypes.ErrCompacted) {
+				t.Fatalf("expected %v, got %v", rpctypes.ErrCompacted, err)
+			}
+		}
+		checkCount(clus.Client(0), compactRev)
+	}
+
+	close(donec)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestKVGetReverse ensures a reverse range pages through a large range
+// backward, within the range boundaries.
+func TestKVGetReverse(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	const n = 10000
+	for i := 0; i < n; i += 100 {
+		ops := make([]clientv3.Op, 100)
+		for j := range ops {
+			ops[j] = clientv3.OpPut(fmt.Sprintf("key/%05d", i+j), "v")
+		}
+		if _, err := kv.Txn(ctx).Then(ops...).Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// page through the keys backward
+	resp, err := kv.Get(ctx, "key/", clientv3.WithPrefix(), clientv3.WithReverse(), clientv3.WithLimit(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != n {
+		t.Fatalf("expected count %d, got %d", n, resp.Count)
+	}
+	rev := resp.Header.Revision
+	var keys []string
+	for {
+		if len(resp.Kvs) > 1000 {
+			t.Fatalf("expected at most 1000 keys, got %d", len(resp.Kvs))
+		}
+		for _, kvp := range resp.Kvs {
+			keys = append(keys, string(kvp.Key))
+		}
+		if !resp.More {
+			break
+		}
+		last := string(resp.Kvs[len(resp.Kvs)-1].Key)
+		if resp, err = kv.Get(ctx, "key/", clientv3.WithRange(last), clientv3.WithReverse(), clientv3.WithLimit(1000), clientv3.WithRev(rev)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(keys) != n {
+		t.Fatalf("expected %d keys, got %d", n, len(keys))
+	}
+	for i, key := range keys {
+		if wkey := fmt.Sprintf("key/%05d", n-1-i); key != wkey {
+			t.Fatalf("#%d: expected key %q, got %q", i, wkey, key)
+		}
+	}
+
+	tests := []struct {
+		key   string
+		opts  []clientv3.OpOption
+		wkeys []string
+	}{
+		{
+			"key/09990",
+			[]clientv3.OpOption{clientv3.WithFromKey(), clientv3.WithReverse(), clientv3.WithLimit(3)},
+			[]string{"key/09999", "key/09998", "key/09997"},
+		},
+		// the range end is excluded
+		{
+			"key/00010",
+			[]clientv3.OpOption{clientv3.WithRange("key/00013"), clientv3.WithReverse()},
+			[]string{"key/00012", "key/00011", "key/00010"},
+		},
+		// a sort order takes precedence
+		{
+			"key/",
+			[]clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithReverse(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend), clientv3.WithLimit(2)},
+			[]string{"key/00000", "key/00001"},
+		},
+	}
+	for i, tt := range tests {
+		resp, err := kv.Get(ctx, tt.key, tt.opts...)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		var keys []string
+		for _, kvp := range resp.Kvs {
+			keys = append(keys, string(kvp.Key))
+		}
+		if !reflect.DeepEqual(keys, tt.wkeys) {
+			t.Fatalf("#%d: expected keys %v, got %v", i, tt.wkeys, keys)
+		}
+	}
+
+	// a page at a compacted revision fails
+	presp, err := kv.Put(ctx, "key/new", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = kv.Compact(ctx, presp.Header.Revision); err != nil {
+		t.Fatal(err)
+	}
+	_, err = kv.Get(ctx, "key/", clientv3.WithRange("key/05000"), clientv3.WithReverse(), clientv3.WithLimit(1000), clientv3.WithRev(rev))
+	if err != rpctypes.ErrCompacted {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrCompacted, err)
+	}
+}
+
 func TestKVGetErrConnClosed(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -477,6 +682,137 @@ func TestKVCompact(t *testing.T) {
 	}
 }
 
+// TestKVCompactRevisionHeader ensures the response headers carry the compact
+// revision of the member.
+func TestKVCompactRevisionHeader(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	for i := 0; i < 10; i++ {
+		if _, err := kv.Put(ctx, "foo", "bar"); err != nil {
+			t.Fatalf("couldn't put 'foo' (%v)", err)
+		}
+	}
+	gresp, err := kv.Get(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gresp.Header.CompactRevision != 0 {
+		t.Fatalf("CompactRevision got %d, want 0 before compaction", gresp.Header.CompactRevision)
+	}
+
+	cresp, err := kv.Compact(ctx, 7)
+	if err != nil {
+		t.Fatalf("couldn't compact kv space (%v)", err)
+	}
+	if cresp.Header.CompactRevision != 7 {
+		t.Fatalf("compact CompactRevision got %d, want 7", cresp.Header.CompactRevision)
+	}
+
+	for i := range clus.Members {
+		// a linearizable read waits for the compaction to be applied
+		gresp, err = clus.Client(i).Get(ctx, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gresp.Header.CompactRevision != 7 {
+			t.Fatalf("#%d: get CompactRevision got %d, want 7", i, gresp.Header.CompactRevision)
+		}
+	}
+	presp, err := kv.Put(ctx, "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presp.Header.CompactRevision != 7 {
+		t.Fatalf("put CompactRevision got %d, want 7", presp.Header.CompactRevision)
+	}
+	tresp, err := kv.Txn(ctx).Then(clientv3.OpGet("foo")).Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tresp.Header.CompactRevision != 7 {
+		t.Fatalf("txn CompactRevision got %d, want 7", tresp.Header.CompactRevision)
+	}
+
+	// reads at the compact revision still succeed
+	if _, err = kv.Get(ctx, "foo", clientv3.WithRev(gresp.Header.CompactRevision)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = kv.Get(ctx, "foo", clientv3.WithRev(gresp.Header.CompactRevision-1)); err != rpctypes.ErrCompacted {
+		t.Fatalf("error got %v, want %v", err, rpctypes.ErrCompacted)
+	}
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wr := <-kv.Watch(wctx, "foo", clientv3.WithRev(7), clientv3.WithCreatedNotify())
+	if !wr.Created {
+		t.Fatalf("expected created notification, got %+v", wr)
+	}
+	if wr.Header.CompactRevision != 7 {
+		t.Fatalf("watch CompactRevision got %d, want 7", wr.Header.CompactRevision)
+	}
+}
+
+// TestKVCompactRevisionHeaderRetained ensures the compact revision of the
+// response headers is the revision from which no read fails when a compaction
+// retains prefixes.
+func TestKVCompactRevisionHeaderRetained(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	for i := 0; i < 5; i++ {
+		if _, err := kv.Put(ctx, "foo", "bar"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kv.Put(ctx, "retained/foo", "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// foo is compacted at 9 and retained/foo at 4
+	req := &pb.CompactionRequest{
+		Revision:         9,
+		Physical:         true,
+		RetainedPrefixes: []*pb.PrefixCompaction{{Prefix: []byte("retained/"), Revision: 4}},
+	}
+	cresp, err := pb.NewKVClient(kv.ActiveConnection()).Compact(ctx, req)
+	if err != nil {
+		t.Fatalf("couldn't compact kv space (%v)", err)
+	}
+	if cresp.Header.CompactRevision != 9 {
+		t.Fatalf("compact CompactRevision got %d, want 9", cresp.Header.CompactRevision)
+	}
+
+	gresp, err := kv.Get(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compactRev := gresp.Header.CompactRevision
+	if compactRev != 9 {
+		t.Fatalf("get CompactRevision got %d, want 9", compactRev)
+	}
+	if _, err = kv.Get(ctx, "foo", clientv3.WithRev(compactRev)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = kv.Get(ctx, "foo", clientv3.WithRev(compactRev-1)); err != rpctypes.ErrCompacted {
+		t.Fatalf("error got %v, want %v", err, rpctypes.ErrCompacted)
+	}
+	// the retained prefix is still read below the compact revision
+	if _, err = kv.Get(ctx, "retained/", clientv3.WithPrefix(), clientv3.WithRev(4)); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestKVGetRetry ensures get will retry on disconnect.
 func TestKVGetRetry(t *testing.T) {
 	integration2.BeforeTest(t)
@@ -896,3 +1232,289 @@ func TestBalancerSupportLearner(t *testing.T) {
 		t.Errorf("expect no error (balancer should retry when request to learner fails), got error: %v", err)
 	}
 }
+
+// TestKVSessionReadYourWrites ensures a serializable read of a session,
+// served by a follower lagging behind the session's write, waits until the
+// follower has applied the write.
+func TestKVSessionReadYourWrites(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	follower := (lead + 1) % len(clus.Members)
+
+	ctx := clientv3.WithSession(context.Background())
+	if _, err := clus.Client(lead).Put(ctx, "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clus.Client(follower).Get(ctx, "foo", clientv3.WithSerializable()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the follower misses the write until it is resumed
+	clus.Members[follower].Pause()
+	if _, err := clus.Client(lead).Put(ctx, "foo", "baz"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := clus.Client(follower).Get(context.Background(), "foo", clientv3.WithSerializable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Kvs[0].Value) != "bar" {
+		t.Fatalf("expected the follower to lag behind the write, got value %q", resp.Kvs[0].Value)
+	}
+
+	time.AfterFunc(500*time.Millisecond, clus.Members[follower].Resume)
+	resp, err = clus.Client(follower).Get(ctx, "foo", clientv3.WithSerializable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Kvs[0].Value) != "baz" {
+		t.Fatalf("expected the session read to reflect the write, got value %q", resp.Kvs[0].Value)
+	}
+}
+
+// TestKVReadForwardedTo ensures the header of a read reports the member that
+// served it and, for a linearizable read served by a follower, the leader it
+// was forwarded to.
+func TestKVReadForwardedTo(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy serves serializable reads from its cache")
+	}
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	follower := (lead + 1) % len(clus.Members)
+	leadID := uint64(clus.Members[lead].ID())
+	followerID := uint64(clus.Members[follower].ID())
+
+	if _, err := clus.Client(lead).Put(context.TODO(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name        string
+		member      int
+		opts        []clientv3.OpOption
+		memberID    uint64
+		forwardedTo uint64
+	}{
+		{"linearizable on follower", follower, nil, followerID, leadID},
+		{"serializable on follower", follower, []clientv3.OpOption{clientv3.WithSerializable()}, followerID, 0},
+		{"linearizable on leader", lead, nil, leadID, 0},
+		{"serializable on leader", lead, []clientv3.OpOption{clientv3.WithSerializable()}, leadID, 0},
+	} {
+		resp, err := clus.Client(tt.member).Get(context.TODO(), "foo", tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Header.MemberId != tt.memberID || resp.Header.ForwardedTo != tt.forwardedTo {
+			t.Errorf("%s: expected member %x forwarded to %x, got member %x forwarded to %x",
+				tt.name, tt.memberID, tt.forwardedTo, resp.Header.MemberId, resp.Header.ForwardedTo)
+		}
+		tresp, err := clus.Client(tt.member).Txn(context.TODO()).Then(clientv3.OpGet("foo", tt.opts...)).Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tresp.Header.MemberId != tt.memberID || tresp.Header.ForwardedTo != tt.forwardedTo {
+			t.Errorf("%s txn: expected member %x forwarded to %x, got member %x forwarded to %x",
+				tt.name, tt.memberID, tt.forwardedTo, tresp.Header.MemberId, tresp.Header.ForwardedTo)
+		}
+	}
+}
+
+// TestKVSessionPartitioned ensures a serializable read of a session fails
+// with a SessionTimeoutError if the follower serving it cannot catch up.
+func TestKVSessionPartitioned(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	follower := (lead + 1) % len(clus.Members)
+
+	clus.Members[follower].Pause()
+	defer clus.Members[follower].Resume()
+
+	sctx := clientv3.WithSession(context.Background())
+	presp, err := clus.Client(lead).Put(sctx, "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(sctx, time.Second)
+	defer cancel()
+	_, err = clus.Client(follower).Get(ctx, "foo", clientv3.WithSerializable())
+	var serr *clientv3.SessionTimeoutError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected %T, got %v", serr, err)
+	}
+	if serr.Revision != presp.Header.Revision || serr.EndpointRevision >= serr.Revision {
+		t.Fatalf("expected session revision %d ahead of the endpoint, got %+v", presp.Header.Revision, serr)
+	}
+}
+
+func TestKVMove(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	lresp, err := kv.Grant(ctx, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = kv.Put(ctx, "src", "val", clientv3.WithLease(lresp.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	// successful move keeps the value and the lease
+	resp, err := kv.Do(ctx, clientv3.OpMove("src", "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Txn().Succeeded {
+		t.Fatalf("expected move transaction to succeed, got %+v", resp.Txn())
+	}
+	mustMoved(t, kv, "src", "dst", "val", lresp.ID)
+
+	// move onto an existing key
+	if _, err = kv.Put(ctx, "src", "val2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = kv.Do(ctx, clientv3.OpMove("src", "dst")); err != clientv3.ErrMoveTargetExists {
+		t.Fatalf("expected %v, got %v", clientv3.ErrMoveTargetExists, err)
+	}
+	gresp, err := kv.Get(ctx, "src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Value) != "val2" {
+		t.Fatalf("expected failed move to keep src, got %+v", gresp.Kvs)
+	}
+	if _, err = kv.Do(ctx, clientv3.OpMove("src", "dst", clientv3.WithOverwrite())); err != nil {
+		t.Fatal(err)
+	}
+	mustMoved(t, kv, "src", "dst", "val2", clientv3.NoLease)
+
+	// detach the lease
+	if _, err = kv.Put(ctx, "src", "val3", clientv3.WithLease(lresp.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = kv.Do(ctx, clientv3.OpMove("src", "dst2", clientv3.WithDetachLease())); err != nil {
+		t.Fatal(err)
+	}
+	mustMoved(t, kv, "src", "dst2", "val3", clientv3.NoLease)
+
+	// move of a nonexistent key
+	if _, err = kv.Do(ctx, clientv3.OpMove("src", "dst3")); err != clientv3.ErrMoveSourceNotFound {
+		t.Fatalf("expected %v, got %v", clientv3.ErrMoveSourceNotFound, err)
+	}
+	if gresp, err = kv.Get(ctx, "dst3"); err != nil || len(gresp.Kvs) != 0 {
+		t.Fatalf("expected no dst3, got %+v (%v)", gresp, err)
+	}
+
+	// a move cannot be part of a transaction
+	if _, err = kv.Txn(ctx).Then(clientv3.OpMove("dst", "dst3")).Commit(); err != clientv3.ErrMoveInTxn {
+		t.Fatalf("expected %v, got %v", clientv3.ErrMoveInTxn, err)
+	}
+	nested := clientv3.OpTxn(nil, nil, []clientv3.Op{clientv3.OpMove("dst", "dst3")})
+	if _, err = kv.Do(ctx, clientv3.OpTxn(nil, []clientv3.Op{nested}, nil)); err != clientv3.ErrMoveInTxn {
+		t.Fatalf("expected %v, got %v", clientv3.ErrMoveInTxn, err)
+	}
+}
+
+func mustMoved(t *testing.T, kv clientv3.KV, src, dst, val string, lease clientv3.LeaseID) {
+	t.Helper()
+	resp, err := kv.Get(context.TODO(), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 0 {
+		t.Fatalf("expected %q to be deleted, got %+v", src, resp.Kvs)
+	}
+	if resp, err = kv.Get(context.TODO(), dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != val || clientv3.LeaseID(resp.Kvs[0].Lease) != lease {
+		t.Fatalf("expected %q with value %q and lease %x, got %+v", dst, val, lease, resp.Kvs)
+	}
+}
+
+func TestKVCompactKey(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	var revs []int64
+	for i := 0; i < 10; i++ {
+		resp, err := kv.Put(ctx, "foo", fmt.Sprintf("bar%d", i))
+		if err != nil {
+			t.Fatalf("couldn't put 'foo' (%v)", err)
+		}
+		revs = append(revs, resp.Header.Revision)
+	}
+
+	if _, err := kv.Do(ctx, clientv3.OpCompactKey("foo", 0)); err != rpctypes.ErrInvalidKeep {
+		t.Fatalf("error got %v, want %v", err, rpctypes.ErrInvalidKeep)
+	}
+	if _, err := kv.Txn(ctx).Else(clientv3.OpCompactKey("foo", 3)).Commit(); err != clientv3.ErrCompactKeyInTxn {
+		t.Fatalf("error got %v, want %v", err, clientv3.ErrCompactKeyInTxn)
+	}
+	nested := clientv3.OpTxn(nil, []clientv3.Op{clientv3.OpCompactKey("foo", 3)}, nil)
+	if _, err := kv.Do(ctx, clientv3.OpTxn(nil, nil, []clientv3.Op{nested})); err != clientv3.ErrCompactKeyInTxn {
+		t.Fatalf("error got %v, want %v", err, clientv3.ErrCompactKeyInTxn)
+	}
+	resp, err := kv.Do(ctx, clientv3.OpCompactKey("foo", 3))
+	if err != nil {
+		t.Fatalf("couldn't compact 'foo' (%v)", err)
+	}
+	if cresp := resp.CompactKey(); cresp.CompactRevision != revs[7] {
+		t.Fatalf("compact revision got %d, want %d", cresp.CompactRevision, revs[7])
+	}
+
+	for m := range clus.Members {
+		cli := clus.Client(m)
+		// ensure the member applied the key compaction
+		if _, err := cli.Get(ctx, "quorum-get"); err != nil {
+			t.Fatal(err)
+		}
+		for i, rev := range revs {
+			gresp, err := cli.Get(ctx, "foo", clientv3.WithRev(rev), clientv3.WithSerializable())
+			if i < 7 {
+				if err != rpctypes.ErrCompacted {
+					t.Fatalf("get at revision %d: error got %v, want %v", rev, err, rpctypes.ErrCompacted)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("get at revision %d: %v", rev, err)
+			}
+			if len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Value) != fmt.Sprintf("bar%d", i) {
+				t.Fatalf("get at revision %d: got %+v, want bar%d", rev, gresp.Kvs, i)
+			}
+		}
+	}
+
+	// the key compaction generates no event
+	wch := kv.Watch(ctx, "foo", clientv3.WithRev(revs[9]+1))
+	if _, err = kv.Put(ctx, "foo", "bar10"); err != nil {
+		t.Fatal(err)
+	}
+	wresp := <-wch
+	if len(wresp.Events) != 1 || wresp.Events[0].Type != mvccpb.PUT || string(wresp.Events[0].Kv.Value) != "bar10" {
+		t.Fatalf("expected only the put of bar10, got %+v", wresp.Events)
+	}
+}
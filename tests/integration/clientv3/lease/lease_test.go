@@ -16,6 +16,7 @@ package lease_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -23,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
@@ -69,6 +71,212 @@ func TestLeaseGrant(t *testing.T) {
 	}
 }
 
+func TestLeaseGrantBatch(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+
+	ttls := make([]int64, 100)
+	for i := range ttls {
+		ttls[i] = int64(10 + i)
+	}
+	resp, err := cli.GrantBatch(context.Background(), ttls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Leases) != len(ttls) {
+		t.Fatalf("expected %d leases, got %d", len(ttls), len(resp.Leases))
+	}
+	ids := make(map[clientv3.LeaseID]struct{})
+	for i, l := range resp.Leases {
+		if l.TTL != ttls[i] {
+			t.Errorf("expected TTL %d for lease %d, got %d", ttls[i], i, l.TTL)
+		}
+		if _, ok := ids[l.ID]; ok {
+			t.Fatalf("lease ID %x granted twice", l.ID)
+		}
+		ids[l.ID] = struct{}{}
+		if _, err = cli.Put(context.TODO(), fmt.Sprintf("foo%d", i), "bar", clientv3.WithLease(l.ID)); err != nil {
+			t.Fatalf("failed to create key with lease %x: %v", l.ID, err)
+		}
+	}
+
+	// a collision with an existing lease fails the whole batch
+	lc := integration2.ToGRPC(cli).Lease
+	_, err = lc.LeaseGrantBatch(context.Background(), &pb.LeaseGrantBatchRequest{Leases: []*pb.LeaseGrantRequest{
+		{ID: 1000, TTL: 10},
+		{ID: int64(resp.Leases[0].ID), TTL: 10},
+	}})
+	if rpctypes.Error(err) != rpctypes.ErrLeaseExist {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrLeaseExist)
+	}
+	lresp, err := cli.Leases(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lresp.Leases) != len(ttls) {
+		t.Fatalf("expected %d leases after failed batch, got %d", len(ttls), len(lresp.Leases))
+	}
+	tresp, err := cli.TimeToLive(context.Background(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tresp.TTL != -1 {
+		t.Fatalf("expected lease 1000 not to be granted, got TTL %d", tresp.TTL)
+	}
+}
+
+func TestLeaseReattach(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	// the remaining TTLs are checked by the leader, also through a follower
+	cli := clus.Client((clus.WaitLeader(t) + 1) % 3)
+	ctx := context.Background()
+
+	oldl, err := cli.Grant(ctx, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newl, err := cli.Grant(ctx, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if _, err = cli.Put(ctx, fmt.Sprintf("foo%02d", i), fmt.Sprint(i), clientv3.WithLease(oldl.ID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = cli.Put(ctx, "bar", "bar", clientv3.WithLease(newl.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := cli.Reattach(ctx, oldl.ID, newl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 50 {
+		t.Fatalf("count = %d, want 50", resp.Count)
+	}
+
+	tresp, err := cli.TimeToLive(ctx, oldl.ID, clientv3.WithAttachedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tresp.Keys) != 0 {
+		t.Fatalf("old lease keys = %q, want none", tresp.Keys)
+	}
+	tresp, err = cli.TimeToLive(ctx, newl.ID, clientv3.WithAttachedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tresp.Keys) != 51 {
+		t.Fatalf("expected 51 keys on the new lease, got %d", len(tresp.Keys))
+	}
+
+	// the keys survive the old lease and are rewritten in a single revision
+	if _, err = cli.Revoke(ctx, oldl.ID); err != nil {
+		t.Fatal(err)
+	}
+	gresp, err := cli.Get(ctx, "foo", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 50 {
+		t.Fatalf("expected 50 keys after revoking the old lease, got %d", len(gresp.Kvs))
+	}
+	for i, kv := range gresp.Kvs {
+		if kv.Lease != int64(newl.ID) || kv.ModRevision != resp.Header.Revision || string(kv.Value) != fmt.Sprint(i) {
+			t.Errorf("unexpected key %q=%q lease %x mod revision %d, want value %d lease %x mod revision %d",
+				kv.Key, kv.Value, kv.Lease, kv.ModRevision, i, newl.ID, resp.Header.Revision)
+		}
+	}
+
+	// the keys go away with the new lease
+	if _, err = cli.Revoke(ctx, newl.ID); err != nil {
+		t.Fatal(err)
+	}
+	if gresp, err = cli.Get(ctx, "foo", clientv3.WithPrefix(), clientv3.WithCountOnly()); err != nil {
+		t.Fatal(err)
+	}
+	if gresp.Count != 0 {
+		t.Fatalf("expected no keys after revoking the new lease, got %d", gresp.Count)
+	}
+}
+
+func TestLeaseReattachRejected(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx := context.Background()
+
+	oldl, err := cli.Grant(ctx, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortl, err := cli.Grant(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedl, err := cli.Grant(ctx, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Revoke(ctx, revokedl.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Put(ctx, "foo", "bar", clientv3.WithLease(oldl.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		oldID, newID clientv3.LeaseID
+		werr         error
+	}{
+		{"shorter TTL", oldl.ID, shortl.ID, rpctypes.ErrLeaseTTLTooShort},
+		{"revoked new lease", oldl.ID, revokedl.ID, rpctypes.ErrLeaseNotFound},
+		{"revoked old lease", revokedl.ID, shortl.ID, rpctypes.ErrLeaseNotFound},
+	}
+	for _, tt := range tests {
+		if _, err = cli.Reattach(ctx, tt.oldID, tt.newID); !errors.Is(err, tt.werr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.werr)
+		}
+	}
+
+	tresp, err := cli.TimeToLive(ctx, oldl.ID, clientv3.WithAttachedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tresp.Keys) != 1 {
+		t.Fatalf("expected the key to stay on the old lease, got keys %q", tresp.Keys)
+	}
+
+	// the new lease may expire first if requested
+	resp, err := cli.Reattach(ctx, oldl.ID, shortl.ID, clientv3.WithShorterTTL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 1 {
+		t.Fatalf("count = %d, want 1", resp.Count)
+	}
+	gresp, err := cli.Get(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 1 || gresp.Kvs[0].Lease != int64(shortl.ID) {
+		t.Fatalf("expected foo on lease %x, got %+v", shortl.ID, gresp.Kvs)
+	}
+}
+
 func TestLeaseRevoke(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -183,6 +391,78 @@ func TestLeaseKeepAliveOneSecond(t *testing.T) {
 	}
 }
 
+// TestLeaseKeepAliveMinTTL ensures a keep alive with a minimum TTL reports an
+// error and closes once the server grants the lease a shorter TTL, while a
+// keep alive without minimum TTL carries on. The minimum TTL is also checked
+// for Leases that do not implement LeaseKeepAliverWithOptions.
+func TestLeaseKeepAliveMinTTL(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	t.Run("KeepAliveWithOptions", func(t *testing.T) { testLeaseKeepAliveMinTTL(t, cli, cli) })
+	t.Run("KeepAlive", func(t *testing.T) { testLeaseKeepAliveMinTTL(t, cli, struct{ clientv3.Lease }{cli.Lease}) })
+}
+
+func testLeaseKeepAliveMinTTL(t *testing.T, cli *clientv3.Client, lease clientv3.Lease) {
+	resp, err := cli.Grant(context.Background(), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := clientv3.KeepAliveWithOptions(context.Background(), lease, resp.ID, clientv3.WithMinKeepAliveTTL(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcAny, err := cli.KeepAlive(context.Background(), resp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kresp, ok := <-rc
+	if !ok || kresp.Err() != nil || kresp.TTL != 6 {
+		t.Fatalf("expected keep alive response with TTL 6, got %+v (closed %v)", kresp, !ok)
+	}
+	<-rcAny
+
+	// the server resets the lease to a shorter TTL before the next renewal
+	if _, err = cli.Revoke(context.Background(), resp.ID); err != nil {
+		t.Fatal(err)
+	}
+	lresp, err := integration2.ToGRPC(cli).Lease.LeaseGrant(context.Background(), &pb.LeaseGrantRequest{ID: int64(resp.ID), TTL: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lresp.TTL != 3 {
+		t.Fatalf("expected TTL 3, got %d", lresp.TTL)
+	}
+
+	select {
+	case kresp, ok = <-rc:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for keep alive response")
+	}
+	if !ok {
+		t.Fatal("expected keep alive response reporting the shorter TTL, got closed channel")
+	}
+	var terr clientv3.ErrKeepAliveTTLTooShort
+	if !errors.As(kresp.Err(), &terr) || terr.TTL != 3 || terr.MinTTL != 5*time.Second {
+		t.Fatalf("expected %T with TTL 3, got %v", terr, kresp.Err())
+	}
+	if _, ok = <-rc; ok {
+		t.Fatal("expected keep alive channel to be closed")
+	}
+
+	select {
+	case kresp, ok = <-rcAny:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for keep alive response")
+	}
+	if !ok || kresp.Err() != nil || kresp.TTL != 3 {
+		t.Fatalf("expected keep alive response with TTL 3, got %+v (closed %v)", kresp, !ok)
+	}
+}
+
 // TestLeaseKeepAliveHandleFailure tests lease keep alive handling faillure
 // TODO: add a client that can connect to all the members of cluster via unix sock.
 // TODO: test handle more complicated failures.
@@ -621,6 +901,215 @@ func TestLeaseTimeToLiveLeaseNotFound(t *testing.T) {
 	}
 }
 
+// TestLeasePutLeaseTTL ensures a put attaching a lease returns the remaining
+// TTL of the lease whether it is served by the leader or by a follower.
+func TestLeasePutLeaseTTL(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	resp, err := clus.RandClient().Grant(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range clus.Members {
+		presp, err := clus.Client(i).Put(context.TODO(), "foo", "bar", clientv3.WithLease(resp.ID), clientv3.WithLeaseTTLInResponse())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if presp.LeaseTtl <= 0 || presp.LeaseTtl > 10 {
+			t.Errorf("member %d: expected lease TTL in (0, 10], got %d", i, presp.LeaseTtl)
+		}
+	}
+
+	presp, err := clus.RandClient().Put(context.TODO(), "foo", "bar", clientv3.WithLease(resp.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presp.LeaseTtl != 0 {
+		t.Errorf("expected no lease TTL without WithLeaseTTLInResponse, got %d", presp.LeaseTtl)
+	}
+
+	if _, err = clus.RandClient().Revoke(context.Background(), resp.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = clus.RandClient().Put(context.TODO(), "foo", "bar", clientv3.WithLease(resp.ID), clientv3.WithLeaseTTLInResponse())
+	if err != rpctypes.ErrLeaseNotFound {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrLeaseNotFound, err)
+	}
+}
+
+// TestLeasePutLeaseTTLExpiring ensures puts attaching a lease about to expire
+// never report a stale TTL, and fail once the lease is revoked.
+func TestLeasePutLeaseTTLExpiring(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	resp, err := clus.RandClient().Grant(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for i := 0; ; i++ {
+		if time.Now().After(deadline) {
+			t.Fatal("expected put to fail after the lease expired")
+		}
+		presp, err := clus.Client(i%len(clus.Members)).Put(context.TODO(), "foo", "bar", clientv3.WithLease(resp.ID), clientv3.WithLeaseTTLInResponse())
+		if err == rpctypes.ErrLeaseNotFound {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if presp.LeaseTtl == 0 || presp.LeaseTtl > 1 {
+			t.Fatalf("expected lease TTL of 1 or -1, got %d", presp.LeaseTtl)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestLeasePutWithTTL ensures keys put with a TTL share a lease granted by
+// the server, and are deleted once the TTL elapses.
+func TestLeasePutWithTTL(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	start := time.Now()
+	presp, err := cli.PutWithTTL(context.TODO(), "foo", "bar", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presp.Lease == 0 {
+		t.Fatal("expected the put to return the lease of the key")
+	}
+	presp2, err := cli.Put(context.TODO(), "foo2", "bar", clientv3.WithTTL(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presp2.Lease != presp.Lease {
+		t.Errorf("expected keys put with the same TTL to share lease %x, got %x", presp.Lease, presp2.Lease)
+	}
+
+	lresp, err := cli.TimeToLive(context.TODO(), clientv3.LeaseID(presp.Lease), clientv3.WithAttachedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lresp.GrantedTTL < 3 {
+		t.Errorf("expected a lease granted for the TTL and a second of slack, got %d", lresp.GrantedTTL)
+	}
+	var keys []string
+	for _, k := range lresp.Keys {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"foo", "foo2"}) {
+		t.Errorf("expected keys [foo foo2] attached to the lease, got %v", keys)
+	}
+
+	for {
+		gresp, err := clus.RandClient().Get(context.TODO(), "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gresp.Count == 0 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("expected the key to be deleted after its TTL")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if elapsed := time.Since(start); elapsed < 2*time.Second {
+		t.Errorf("expected the key to live for its TTL of 2s, deleted after %v", elapsed)
+	}
+
+	gresp, err := cli.Grant(context.TODO(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Put(context.TODO(), "foo", "bar", clientv3.WithTTL(2), clientv3.WithLease(gresp.ID)); err != rpctypes.ErrLeaseProvided {
+		t.Errorf("expected %v, got %v", rpctypes.ErrLeaseProvided, err)
+	}
+	if _, err = cli.Put(context.TODO(), "foo", "bar", clientv3.WithTTL(2), clientv3.WithIgnoreLease()); err != rpctypes.ErrKeyTTLIgnoreLease {
+		t.Errorf("expected %v, got %v", rpctypes.ErrKeyTTLIgnoreLease, err)
+	}
+	if _, err = cli.Put(context.TODO(), "foo", "bar", clientv3.WithTTL(-1)); err != rpctypes.ErrInvalidKeyTTL {
+		t.Errorf("expected %v, got %v", rpctypes.ErrInvalidKeyTTL, err)
+	}
+	_, err = cli.Txn(context.TODO()).Then(clientv3.OpPut("foo", "bar", clientv3.WithTTL(2))).Commit()
+	if err != rpctypes.ErrKeyTTLInTxn {
+		t.Errorf("expected %v, got %v", rpctypes.ErrKeyTTLInTxn, err)
+	}
+}
+
+// TestLeasePutWithTTLOverwrite ensures putting a key again with a TTL extends
+// its expiry, and detaches it from the lease of its previous TTL.
+func TestLeasePutWithTTLOverwrite(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	presp, err := cli.PutWithTTL(context.TODO(), "foo", "bar", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	presp2, err := cli.PutWithTTL(context.TODO(), "foo", "baz", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presp2.Lease == presp.Lease {
+		t.Fatal("expected the lease expiring before the TTL not to be shared")
+	}
+
+	// the key outlives the lease of its first put
+	for {
+		lresp, err := cli.TimeToLive(context.TODO(), clientv3.LeaseID(presp.Lease), clientv3.WithAttachedKeys())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lresp.Keys) != 0 {
+			t.Fatalf("expected the key to be detached from its first lease, got keys %q", lresp.Keys)
+		}
+		if lresp.TTL == -1 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	gresp, err := cli.Get(context.TODO(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Value) != "baz" || gresp.Kvs[0].Lease != presp2.Lease {
+		t.Fatalf("expected foo=baz attached to lease %x, got %v", presp2.Lease, gresp.Kvs)
+	}
+
+	presp3, err := cli.PutWithTTL(context.TODO(), "foo", "qux", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presp3.Lease == presp2.Lease {
+		t.Fatal("expected keys put with another TTL not to share the lease")
+	}
+	lresp, err := cli.TimeToLive(context.TODO(), clientv3.LeaseID(presp2.Lease), clientv3.WithAttachedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lresp.Keys) != 0 {
+		t.Errorf("expected the key to be detached from the lease of its previous TTL, got keys %q", lresp.Keys)
+	}
+}
+
 func TestLeaseLeases(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -652,6 +1141,73 @@ func TestLeaseLeases(t *testing.T) {
 	}
 }
 
+// TestLeaseListDetailed checks that the leases are listed with the number of
+// keys attached to them by every member, including followers that ask the
+// leader.
+func TestLeaseListDetailed(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+
+	keyCounts := make(map[clientv3.LeaseID]int64)
+	var lastID clientv3.LeaseID
+	for _, n := range []int{0, 1, 3, 10} {
+		resp, err := cli.Grant(context.Background(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			if _, err := cli.Put(context.Background(), fmt.Sprintf("lease%d/key%d", resp.ID, i), "v", clientv3.WithLease(resp.ID)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		keyCounts[resp.ID] = int64(n)
+		lastID = resp.ID
+	}
+	// a key put again without the lease is detached from it
+	if _, err := cli.Put(context.Background(), fmt.Sprintf("lease%d/key0", lastID), "v"); err != nil {
+		t.Fatal(err)
+	}
+	keyCounts[lastID]--
+
+	for i := range clus.Members {
+		resp, err := clus.Client(i).LeaseListDetailed(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := make(map[clientv3.LeaseID]int64)
+		for _, l := range resp.Leases {
+			if l.GrantedTTL != 100 {
+				t.Errorf("member %d: lease %d granted TTL expected 100, got %d", i, l.ID, l.GrantedTTL)
+			}
+			// followers would report leases that never expire
+			if l.TTL <= 0 || l.TTL > 100 {
+				t.Errorf("member %d: lease %d TTL expected in (0, 100], got %d", i, l.ID, l.TTL)
+			}
+			if len(l.Keys) != 0 {
+				t.Errorf("member %d: expected no keys without WithAttachedKeys, got %q", i, l.Keys)
+			}
+			counts[l.ID] = l.KeyCount
+		}
+		if !reflect.DeepEqual(counts, keyCounts) {
+			t.Fatalf("member %d: key counts expected %v, got %v", i, keyCounts, counts)
+		}
+	}
+
+	resp, err := cli.LeaseListDetailed(context.Background(), clientv3.WithAttachedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range resp.Leases {
+		if int64(len(l.Keys)) != keyCounts[l.ID] {
+			t.Fatalf("lease %d: expected %d keys, got %q", l.ID, keyCounts[l.ID], l.Keys)
+		}
+	}
+}
+
 // TestLeaseRenewLostQuorum ensures keepalives work after losing quorum
 // for a while.
 func TestLeaseRenewLostQuorum(t *testing.T) {
@@ -845,3 +1401,44 @@ func TestLeaseWithRequireLeader(t *testing.T) {
 		// wait some to detect any closes happening soon after kaReqLeader closing
 	}
 }
+
+// BenchmarkLeaseGrantBatch grants 1000 leases in a single request.
+func BenchmarkLeaseGrantBatch(b *testing.B) {
+	benchmarkLeaseGrant(b, func(cli *clientv3.Client, ttls []int64) error {
+		_, err := cli.GrantBatch(context.TODO(), ttls)
+		return err
+	})
+}
+
+// BenchmarkLeaseGrantSequential grants the same leases as
+// BenchmarkLeaseGrantBatch one request at a time.
+func BenchmarkLeaseGrantSequential(b *testing.B) {
+	benchmarkLeaseGrant(b, func(cli *clientv3.Client, ttls []int64) error {
+		for _, ttl := range ttls {
+			if _, err := cli.Grant(context.TODO(), ttl); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func benchmarkLeaseGrant(b *testing.B, grant func(cli *clientv3.Client, ttls []int64) error) {
+	integration2.BeforeTest(b, integration2.WithoutGoLeakDetection())
+
+	clus := integration2.NewCluster(b, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(b)
+
+	cli := clus.RandClient()
+	ttls := make([]int64, 1000)
+	for i := range ttls {
+		ttls[i] = 60
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := grant(cli, ttls); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/pkg/v3/testutil"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
@@ -1817,6 +1818,59 @@ func TestLeasingDo(t *testing.T) {
 	}
 }
 
+// TestLeasingMove checks a move invalidates the cached source and target keys.
+func TestLeasingMove(t *testing.T) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	lkv1, closeLKV1, err := leasing.NewKV(clus.Client(0), "pfx/")
+	testutil.AssertNil(t, err)
+	defer closeLKV1()
+	lkv2, closeLKV2, err := leasing.NewKV(clus.Client(0), "pfx/")
+	testutil.AssertNil(t, err)
+	defer closeLKV2()
+
+	if _, err = lkv1.Put(context.TODO(), "src", "v"); err != nil {
+		t.Fatal(err)
+	}
+	// cache both keys
+	for _, lkv := range []clientv3.KV{lkv1, lkv2} {
+		for _, key := range []string{"src", "dst"} {
+			if _, err = lkv.Get(context.TODO(), key); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	resp, err := lkv1.Do(context.TODO(), clientv3.OpMove("src", "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Txn().Succeeded {
+		t.Fatalf("expected move transaction to succeed, got %+v", resp.Txn())
+	}
+	for i, lkv := range []clientv3.KV{lkv1, lkv2} {
+		gresp, err := lkv.Get(context.TODO(), "src")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(gresp.Kvs) != 0 {
+			t.Errorf("#%d: expected no src, got %+v", i, gresp.Kvs)
+		}
+		if gresp, err = lkv.Get(context.TODO(), "dst"); err != nil {
+			t.Fatal(err)
+		}
+		if len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Value) != "v" {
+			t.Errorf("#%d: expected dst=v, got %+v", i, gresp.Kvs)
+		}
+	}
+
+	if _, err = lkv1.Do(context.TODO(), clientv3.OpMove("src", "dst")); err != clientv3.ErrMoveSourceNotFound {
+		t.Fatalf("expected %v, got %v", clientv3.ErrMoveSourceNotFound, err)
+	}
+}
+
 func TestLeasingTxnOwnerPutBranch(t *testing.T) {
 	integration2.BeforeTest(t)
 	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, UseBridge: true})
@@ -2056,3 +2110,46 @@ func waitForExpireAck(t *testing.T, kv clientv3.KV) {
 	}
 	t.Fatalf("waited too long to acknlowedge lease expiration")
 }
+
+func TestLeasingCompactKey(t *testing.T) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	lkv, closeLKV, err := leasing.NewKV(clus.Client(0), "pfx/")
+	testutil.AssertNil(t, err)
+	defer closeLKV()
+
+	for i := 0; i < 5; i++ {
+		if _, err = lkv.Put(context.TODO(), "k", fmt.Sprintf("v%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// cache the key
+	if _, err = lkv.Get(context.TODO(), "k"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := lkv.Do(context.TODO(), clientv3.OpCompactKey("k", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compactRev := resp.CompactKey().CompactRevision
+	cli := clus.Client(0)
+	if _, err = cli.Get(context.TODO(), "k", clientv3.WithRev(compactRev-1)); err != rpctypes.ErrCompacted {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrCompacted, err)
+	}
+	gresp, err := cli.Get(context.TODO(), "k", clientv3.WithRev(compactRev))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 1 {
+		t.Fatalf("expected k at the compaction revision, got %+v", gresp.Kvs)
+	}
+	if gresp, err = lkv.Get(context.TODO(), "k"); err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Value) != "v4" {
+		t.Fatalf("expected k=v4, got %+v", gresp.Kvs)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,6 +34,7 @@ import (
 	"go.etcd.io/etcd/server/v3/storage/backend"
 	"go.etcd.io/etcd/server/v3/storage/mvcc"
 	"go.etcd.io/etcd/server/v3/storage/mvcc/testutil"
+	"go.etcd.io/etcd/server/v3/storage/schema"
 	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
 
 	"github.com/stretchr/testify/require"
@@ -435,3 +437,534 @@ func TestMaintenanceStatus(t *testing.T) {
 		t.Fatal("no leader found")
 	}
 }
+
+func TestMaintenanceCompactionStatus(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1, CompactionBatchLimit: 100, CompactionSleepInterval: 10 * time.Millisecond})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ep := clus.Members[0].GRPCURL()
+
+	resp, err := cli.CompactionStatus(context.TODO(), ep)
+	require.NoError(t, err)
+	if resp.InProgress || resp.CompactRevision != 0 || resp.ScannedRevision != 0 || resp.RemainingKeys != 0 {
+		t.Fatalf("expected zeroed status without compaction, got %+v", resp)
+	}
+
+	// 10000 keys spread over 100 revisions take 100 compaction batches
+	var rev int64
+	for i := 0; i < 100; i++ {
+		ops := make([]clientv3.Op, 100)
+		for j := range ops {
+			ops[j] = clientv3.OpPut(fmt.Sprintf("foo%d-%d", i, j), "bar")
+		}
+		tresp, err := cli.Txn(context.TODO()).Then(ops...).Commit()
+		require.NoError(t, err)
+		rev = tresp.Header.Revision
+	}
+	_, err = cli.Compact(context.TODO(), rev)
+	require.NoError(t, err)
+
+	var sawProgress bool
+	timeout := time.After(30 * time.Second)
+	for {
+		resp, err = cli.CompactionStatus(context.TODO(), ep)
+		require.NoError(t, err)
+		if !resp.InProgress {
+			break
+		}
+		if resp.CompactRevision != rev {
+			t.Fatalf("expected compact revision %d, got %+v", rev, resp)
+		}
+		if resp.ScannedRevision > 0 && resp.RemainingKeys > 0 {
+			sawProgress = true
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("compaction still in progress: %+v", resp)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if !sawProgress {
+		t.Error("expected to observe compaction progress")
+	}
+	if resp.CompactRevision != 0 || resp.ScannedRevision != 0 || resp.RemainingKeys != 0 {
+		t.Errorf("expected zeroed status after compaction, got %+v", resp)
+	}
+}
+
+func TestMaintenanceHotKeys(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1, HotKeyTracking: true})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ep := clus.Members[0].GRPCURL()
+
+	// "foo" is read four times as often as "bar", which is written four times as often as "foo"
+	for i := 0; i < 800; i++ {
+		if i%4 == 0 {
+			_, err := cli.Get(context.TODO(), "bar")
+			require.NoError(t, err)
+			_, err = cli.Put(context.TODO(), "foo", "v")
+			require.NoError(t, err)
+		}
+		_, err := cli.Get(context.TODO(), "foo")
+		require.NoError(t, err)
+		if i%4 == 0 {
+			continue
+		}
+		_, err = cli.Put(context.TODO(), "bar", "v")
+		require.NoError(t, err)
+	}
+
+	resp, err := cli.HotKeys(context.TODO(), ep, 2, true)
+	require.NoError(t, err)
+	if len(resp.Reads) != 2 || string(resp.Reads[0].Key) != "foo" || string(resp.Reads[1].Key) != "bar" {
+		t.Errorf("expected most read keys [foo bar], got %v", resp.Reads)
+	}
+	if len(resp.Writes) != 2 || string(resp.Writes[0].Key) != "bar" || string(resp.Writes[1].Key) != "foo" {
+		t.Errorf("expected most written keys [bar foo], got %v", resp.Writes)
+	}
+
+	resp, err = cli.HotKeys(context.TODO(), ep, 0, false)
+	require.NoError(t, err)
+	if len(resp.Reads) != 0 || len(resp.Writes) != 0 {
+		t.Errorf("expected no hot keys after reset, got %v, %v", resp.Reads, resp.Writes)
+	}
+}
+
+func TestMaintenanceHotKeysDisabled(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	_, err := clus.RandClient().HotKeys(context.TODO(), clus.Members[0].GRPCURL(), 0, false)
+	if err != rpctypes.ErrHotKeyTrackingDisabled {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrHotKeyTrackingDisabled, err)
+	}
+}
+
+func TestMaintenanceSpaceReclaim(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ep := clus.Members[0].GRPCURL()
+
+	resp, err := cli.SpaceReclaim(context.TODO(), ep, true)
+	require.NoError(t, err)
+	require.True(t, resp.Enabled)
+	for _, m := range clus.Members[1:] {
+		enabled, _ := m.Server.Backend().SpaceReclaim()
+		require.False(t, enabled, "space reclaim is enabled per member")
+	}
+
+	for i := 0; i < 100; i++ {
+		_, err = cli.Put(context.TODO(), fmt.Sprintf("foo%d", i), "bar")
+		require.NoError(t, err)
+		_, err = cli.Delete(context.TODO(), fmt.Sprintf("foo%d", i))
+		require.NoError(t, err)
+	}
+
+	resp, err = cli.SpaceReclaim(context.TODO(), ep, false)
+	require.NoError(t, err)
+	require.False(t, resp.Enabled)
+	enabled, _ := clus.Members[0].Server.Backend().SpaceReclaim()
+	require.False(t, enabled)
+}
+
+// TestMaintenanceMemberSelf ensures the identity reported by each member matches
+// its entry in the member list.
+func TestMaintenanceMemberSelf(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	mresp, err := clus.RandClient().MemberList(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, mresp.Members, 3)
+
+	for _, m := range clus.Members {
+		resp := m.Self(t)
+		require.True(t, resp.Joined)
+		require.Equal(t, mresp.Header.ClusterId, resp.ClusterId)
+		require.Equal(t, uint64(m.ID()), resp.Member.ID)
+
+		found := false
+		for _, mm := range mresp.Members {
+			if mm.ID == resp.Member.ID {
+				require.Equal(t, mm, resp.Member)
+				found = true
+			}
+		}
+		require.True(t, found, "member %s not in the member list", m.Name)
+	}
+}
+
+// TestMaintenanceSnapshotFromResume ensures an interrupted snapshot download
+// resumed from its offset is byte-identical to an uninterrupted download, even
+// if the store changed in between, and that a new download takes a new
+// snapshot.
+func TestMaintenanceSnapshotFromResume(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	populateDataIntoCluster(t, clus, 3, 1024*1024)
+	cli := clus.RandClient()
+
+	// start the download to interrupt
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, err := cli.SnapshotFrom(ctx, 0, 0)
+	require.NoError(t, err)
+	id := resp.SnapshotID
+	require.NotZero(t, id)
+	require.NotZero(t, resp.Header.Revision)
+
+	offset := 1024*1024 + 123
+	got := make([]byte, offset)
+	_, err = io.ReadFull(resp.Snapshot, got)
+	require.NoError(t, err)
+	cancel()
+	resp.Snapshot.Close()
+
+	// the resumed download ignores changes made since the snapshot was taken,
+	// even if they do not change the key-value store revision
+	populateDataIntoCluster(t, clus, 3, 1024)
+	lresp, err := cli.Grant(context.Background(), 100)
+	require.NoError(t, err)
+
+	resumed, err := cli.SnapshotFrom(context.Background(), id, int64(offset))
+	require.NoError(t, err)
+	require.Equal(t, id, resumed.SnapshotID)
+	rest, err := io.ReadAll(resumed.Snapshot)
+	require.NoError(t, err)
+	resumed.Snapshot.Close()
+
+	// the stream ends with the digest of the snapshot data
+	got = append(got, rest...)
+	require.Greater(t, len(got), sha256.Size)
+	sha := sha256.Sum256(got[:len(got)-sha256.Size])
+	require.Equal(t, sha[:], got[len(got)-sha256.Size:], "resumed snapshot differs from the uninterrupted download")
+	require.False(t, snapshotHasLease(t, got, lresp.ID))
+
+	// a new download takes a new snapshot, which has the lease
+	full, err := cli.SnapshotFrom(context.Background(), id, 0)
+	require.NoError(t, err)
+	require.NotEqual(t, id, full.SnapshotID)
+	want, err := io.ReadAll(full.Snapshot)
+	require.NoError(t, err)
+	full.Snapshot.Close()
+	require.True(t, snapshotHasLease(t, want, lresp.ID))
+
+	// resuming a snapshot the server does not keep fails
+	_, err = cli.SnapshotFrom(context.Background(), id+1, int64(offset))
+	require.ErrorIs(t, err, rpctypes.ErrSnapshotExpired)
+
+	// an offset beyond the end of the stream is rejected
+	_, err = cli.SnapshotFrom(context.Background(), id, int64(len(got)+1))
+	require.ErrorIs(t, err, rpctypes.ErrSnapshotOffsetOutOfRange)
+}
+
+// snapshotHasLease returns whether the snapshot stream data has the lease id.
+func snapshotHasLease(t *testing.T, data []byte, id clientv3.LeaseID) bool {
+	dpath := filepath.Join(t.TempDir(), "snapshot.db")
+	require.NoError(t, os.WriteFile(dpath, data[:len(data)-sha256.Size], 0600))
+	b := backend.NewDefaultBackend(zaptest.NewLogger(t), dpath)
+	defer b.Close()
+	tx := b.ReadTx()
+	tx.RLock()
+	defer tx.RUnlock()
+	for _, l := range schema.MustUnsafeGetAllLeases(tx) {
+		if l.ID == int64(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestMaintenanceSnapshotAtRevision ensures snapshots of different members at
+// the same revision restore to the same key-value store.
+func TestMaintenanceSnapshotAtRevision(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		_, err := clus.RandClient().Put(ctx, fmt.Sprintf("foo%d", i%3), fmt.Sprintf("bar%d", i))
+		require.NoError(t, err)
+	}
+	resp, err := clus.RandClient().Get(ctx, "foo0")
+	require.NoError(t, err)
+	rev := resp.Header.Revision
+	_, err = clus.RandClient().Compact(ctx, rev-2, clientv3.WithCompactPhysical())
+	require.NoError(t, err)
+	hresp, err := clus.Client(0).HashKV(ctx, clus.Members[0].GRPCURL(), rev)
+	require.NoError(t, err)
+
+	// changes after rev are not part of the snapshots
+	for i := 0; i < 10; i++ {
+		_, err = clus.RandClient().Put(ctx, fmt.Sprintf("foo%d", i%3), fmt.Sprintf("baz%d", i))
+		require.NoError(t, err)
+	}
+
+	lg := zaptest.NewLogger(t)
+	for i := 0; i < 2; i++ {
+		cli := clus.Client(i)
+		// ensure writes are replicated
+		_, err = cli.Get(ctx, "foo0")
+		require.NoError(t, err)
+
+		sresp, err := cli.SnapshotAtRevision(ctx, rev)
+		require.NoError(t, err)
+		require.Equal(t, rev, sresp.Header.Revision)
+		data, err := io.ReadAll(sresp.Snapshot)
+		require.NoError(t, err)
+		sresp.Snapshot.Close()
+
+		// strip the digest ending the stream
+		require.Greater(t, len(data), sha256.Size)
+		dpath := filepath.Join(t.TempDir(), "snapshot.db")
+		require.NoError(t, os.WriteFile(dpath, data[:len(data)-sha256.Size], 0600))
+
+		b := backend.NewDefaultBackend(lg, dpath)
+		s := mvcc.NewStore(lg, b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+		require.Equal(t, rev, s.Rev())
+		hash, _, err := s.HashStorage().HashByRev(rev)
+		require.NoError(t, err)
+		require.Equal(t, hresp.Hash, hash.Hash, "member %d", i)
+		s.Close()
+		b.Close()
+	}
+
+	cli := clus.RandClient()
+	_, err = cli.SnapshotAtRevision(ctx, rev-3)
+	require.ErrorIs(t, err, rpctypes.ErrCompacted)
+	_, err = cli.SnapshotAtRevision(ctx, rev+100)
+	require.ErrorIs(t, err, rpctypes.ErrFutureRev)
+}
+
+func TestMaintenancePrefixQuota(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	ctx := context.TODO()
+
+	_, err := cli.PrefixQuotaSet(ctx, "/a/", 0, 2)
+	require.NoError(t, err)
+	_, err = cli.PrefixQuotaSet(ctx, "/c/", 10, 0)
+	require.NoError(t, err)
+	_, err = cli.PrefixQuotaSet(ctx, "/d/", -1, 0)
+	require.ErrorIs(t, err, rpctypes.ErrInvalidPrefixQuota)
+	_, err = cli.PrefixQuotaSet(ctx, "/d/", 0, -1)
+	require.ErrorIs(t, err, rpctypes.ErrInvalidPrefixQuota)
+
+	requireQuotaExceeded := func(err error, prefix string) {
+		t.Helper()
+		require.Error(t, err)
+		p, ok := rpctypes.QuotaExceededPrefix(err)
+		require.True(t, ok, "expected a quota exceeded error, got %v", err)
+		require.Equal(t, prefix, p)
+	}
+
+	// fill /a/ up to its quota
+	for _, key := range []string{"/a/1", "/a/2"} {
+		_, err = cli.Put(ctx, key, "v")
+		require.NoError(t, err)
+	}
+	_, err = cli.Put(ctx, "/a/3", "v")
+	requireQuotaExceeded(err, "/a/")
+	// nothing of a txn over the quota is written
+	_, err = cli.Txn(ctx).Then(clientv3.OpPut("/b/1", "v"), clientv3.OpPut("/a/3", "v")).Commit()
+	requireQuotaExceeded(err, "/a/")
+	gresp, err := cli.Get(ctx, "/", clientv3.WithPrefix(), clientv3.WithCountOnly())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), gresp.Count)
+
+	// the keys under /a/ can still be updated, and other prefixes accept writes
+	_, err = cli.Put(ctx, "/a/1", "updated")
+	require.NoError(t, err)
+	_, err = cli.Put(ctx, "/b/1", "v")
+	require.NoError(t, err)
+
+	// the byte quota counts the keys and the values
+	_, err = cli.Put(ctx, "/c/1", "123456")
+	require.NoError(t, err)
+	_, err = cli.Put(ctx, "/c/1", "1234567")
+	requireQuotaExceeded(err, "/c/")
+
+	// deletes free the quota
+	_, err = cli.Delete(ctx, "/a/1")
+	require.NoError(t, err)
+	_, err = cli.Txn(ctx).Then(clientv3.OpPut("/a/3", "v")).Commit()
+	require.NoError(t, err)
+	_, err = cli.Txn(ctx).Then(clientv3.OpDelete("/a/2"), clientv3.OpDelete("/a/3"), clientv3.OpPut("/a/4", "v"), clientv3.OpPut("/a/5", "v")).Commit()
+	require.NoError(t, err)
+
+	// all members agree on the quotas and the usage
+	for _, m := range clus.Members {
+		resp, err := cli.PrefixQuotaList(ctx, m.GRPCURL())
+		require.NoError(t, err)
+		require.Len(t, resp.Quotas, 2)
+		require.Equal(t, "/a/", string(resp.Quotas[0].Quota.Prefix))
+		require.Equal(t, int64(2), resp.Quotas[0].Keys)
+		require.Equal(t, int64(10), resp.Quotas[0].Bytes)
+		require.Equal(t, "/c/", string(resp.Quotas[1].Quota.Prefix))
+		require.Equal(t, int64(1), resp.Quotas[1].Keys)
+		require.Equal(t, int64(10), resp.Quotas[1].Bytes)
+	}
+
+	// removing the quota lifts the limit
+	_, err = cli.PrefixQuotaSet(ctx, "/a/", 0, 0)
+	require.NoError(t, err)
+	_, err = cli.Put(ctx, "/a/6", "v")
+	require.NoError(t, err)
+}
+
+func TestMaintenanceVerifyBackend(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ep := clus.Members[0].GRPCURL()
+	ctx := context.TODO()
+
+	for i := 0; i < 20; i++ {
+		_, err := cli.Put(ctx, fmt.Sprintf("foo%d", i%5), "v")
+		require.NoError(t, err)
+	}
+	_, err := cli.Delete(ctx, "foo1")
+	require.NoError(t, err)
+	presp, err := cli.Put(ctx, "foo2", "v")
+	require.NoError(t, err)
+	_, err = cli.Compact(ctx, presp.Header.Revision-5, clientv3.WithCompactPhysical())
+	require.NoError(t, err)
+
+	resp, err := cli.VerifyBackend(ctx, ep, 4, time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, presp.Header.Revision, resp.Revision)
+	require.Empty(t, resp.Anomalies)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = cli.VerifyBackend(cctx, ep, 0, 0)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMaintenanceRebuildIndex(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx := context.TODO()
+
+	for i := 0; i < 20; i++ {
+		_, err := cli.Put(ctx, fmt.Sprintf("foo%d", i%5), "v")
+		require.NoError(t, err)
+	}
+	_, err := cli.Delete(ctx, "foo1")
+	require.NoError(t, err)
+	presp, err := cli.Put(ctx, "foo2", "v")
+	require.NoError(t, err)
+	_, err = cli.Compact(ctx, presp.Header.Revision-5, clientv3.WithCompactPhysical())
+	require.NoError(t, err)
+
+	lead := clus.WaitLeader(t)
+	follower := (lead + 1) % len(clus.Members)
+	// the follower applied the writes once it serves a linearizable read
+	_, err = clus.Client(follower).Get(ctx, "foo2")
+	require.NoError(t, err)
+
+	_, err = cli.RebuildIndex(ctx, clus.Members[lead].GRPCURL(), false)
+	require.ErrorIs(t, err, rpctypes.ErrRebuildIndexOnLeader)
+
+	resp, err := cli.RebuildIndex(ctx, clus.Members[follower].GRPCURL(), false)
+	require.NoError(t, err)
+	require.Equal(t, presp.Header.Revision, resp.Revision)
+	require.Empty(t, resp.Before)
+	require.Empty(t, resp.After)
+
+	// a forced rebuild on the leader transfers the leadership first
+	resp, err = cli.RebuildIndex(ctx, clus.Members[lead].GRPCURL(), true)
+	require.NoError(t, err)
+	require.Equal(t, presp.Header.Revision, resp.Revision)
+	require.Empty(t, resp.Before)
+	require.Empty(t, resp.After)
+	require.NotEqual(t, lead, clus.WaitLeader(t))
+
+	// the members are still consistent, and accept writes again
+	presp, err = cli.Put(ctx, "foo3", "w")
+	require.NoError(t, err)
+	var hash uint32
+	for i, m := range clus.Members {
+		_, err = clus.Client(i).Get(ctx, "foo3")
+		require.NoError(t, err)
+		hresp, err := cli.HashKV(ctx, m.GRPCURL(), presp.Header.Revision)
+		require.NoError(t, err)
+		if i == 0 {
+			hash = hresp.Hash
+		}
+		require.Equal(t, hash, hresp.Hash, "member %s", m.Name)
+	}
+	gresp, err := clus.Client(follower).Get(ctx, "foo", clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.Len(t, gresp.Kvs, 4)
+}
+
+func TestMaintenanceKeyspaceStats(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy test clients write their keys under a namespace")
+	}
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ep := clus.Members[0].GRPCURL()
+	ctx := context.TODO()
+
+	// /small/ has the most keys, /large/ the most bytes
+	for i := 0; i < 10; i++ {
+		_, err := cli.Put(ctx, fmt.Sprintf("/small/%d", i), "v")
+		require.NoError(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := cli.Put(ctx, fmt.Sprintf("/large/%d", i), strings.Repeat("v", 1000))
+		require.NoError(t, err)
+	}
+	presp, err := cli.Put(ctx, "top", "v")
+	require.NoError(t, err)
+
+	resp, err := cli.KeyspaceStats(ctx, ep, 0, 2)
+	require.NoError(t, err)
+	require.Equal(t, presp.Header.Revision, resp.Revision)
+	require.Equal(t, int64(14), resp.Keys)
+	require.Equal(t, int64(10*len("/small/0")+3*len("/large/0")+len("top")), resp.KeyBytes)
+	require.Equal(t, int64(10+3*1000+1), resp.ValueBytes)
+	require.Len(t, resp.Prefixes, 2)
+	require.Equal(t, "/large/", string(resp.Prefixes[0].Prefix))
+	require.Equal(t, int64(3), resp.Prefixes[0].Keys)
+	require.Equal(t, "/small/", string(resp.Prefixes[1].Prefix))
+	require.Equal(t, int64(10), resp.Prefixes[1].Keys)
+}
@@ -16,8 +16,10 @@ package clientv3test
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -54,6 +56,35 @@ func TestNamespacePutGet(t *testing.T) {
 	}
 }
 
+func TestNamespaceMove(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	c := clus.Client(0)
+	nsKV := namespace.NewKV(c.KV, "foo/")
+
+	if _, err := nsKV.Put(context.TODO(), "abc", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := nsKV.Do(context.TODO(), clientv3.OpMove("abc", "def"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Txn().Succeeded {
+		t.Fatalf("expected move transaction to succeed, got %+v", resp.Txn())
+	}
+
+	gresp, err := c.Get(context.TODO(), "", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Key) != "foo/def" || string(gresp.Kvs[0].Value) != "bar" {
+		t.Fatalf("expected only foo/def=bar, got %+v", gresp.Kvs)
+	}
+}
+
 func TestNamespaceWatch(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -83,3 +114,65 @@ func TestNamespaceWatch(t *testing.T) {
 	// let client close teardown namespace watch
 	c.Watcher = nsWatcher
 }
+
+// TestNamespaceWatchRanges ensures the ranges added with WithWatchRange are
+// prefixed, so that a watch on several ranges only sees the namespace.
+func TestNamespaceWatchRanges(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy does not support watches on several ranges")
+	}
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	c := clus.Client(0)
+	nsWatcher := namespace.NewWatcher(c.Watcher, "foo/")
+	defer nsWatcher.Close()
+
+	for _, globalOrder := range []bool{false, true} {
+		t.Run(fmt.Sprintf("globalOrder=%v", globalOrder), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			resp, err := c.Get(ctx, "foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := []clientv3.OpOption{clientv3.WithWatchRange("b", clientv3.WithPrefix()), clientv3.WithRev(resp.Header.Revision + 1)}
+			if globalOrder {
+				opts = append(opts, clientv3.WithGlobalOrder())
+			}
+			nsWch := nsWatcher.Watch(ctx, "a", opts...)
+
+			// b1 and a are outside of the namespace
+			for _, key := range []string{"b1", "a", "foo/a", "foo/b1"} {
+				if _, err = c.Put(ctx, key, "v"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			type event struct {
+				key   string
+				index int32
+			}
+			want := []event{{"a", 0}, {"b1", 1}}
+			var got []event
+			for len(got) < len(want) {
+				select {
+				case wr := <-nsWch:
+					if err = wr.Err(); err != nil {
+						t.Fatal(err)
+					}
+					for _, ev := range wr.Events {
+						got = append(got, event{string(ev.Kv.Key), ev.RangeIndex})
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out waiting for events, got %v", got)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("expected events %v, got %v", want, got)
+			}
+		})
+	}
+}
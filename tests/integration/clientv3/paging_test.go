@@ -0,0 +1,173 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestGetPagedConcurrentWrites ensures a paged scan returns every key at the
+// pinned revision exactly once while the keys are modified concurrently.
+func TestGetPagedConcurrentWrites(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	for i := 0; i < 10000; i += 100 {
+		ops := make([]clientv3.Op, 100)
+		for j := range ops {
+			ops[j] = clientv3.OpPut(fmt.Sprintf("key/%05d", i+j), "v")
+		}
+		_, err := cli.Txn(context.TODO()).Then(ops...).Commit()
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wcli := clus.Client(0)
+		for i := 0; ctx.Err() == nil; i++ {
+			// add keys in between and delete keys ahead of and behind the scan
+			wcli.Put(ctx, fmt.Sprintf("key/%05d-new", (i*37)%10000), "v")
+			wcli.Delete(ctx, fmt.Sprintf("key/%05d", (i*53)%10000))
+		}
+	}()
+
+	var keys []string
+	p := clientv3.GetPaged(context.TODO(), clus.Client(1), "key/", 97)
+	pages := 0
+	for p.Next() {
+		pages++
+		for _, kv := range p.Page() {
+			keys = append(keys, string(kv.Key))
+		}
+	}
+	cancel()
+	wg.Wait()
+	require.NoError(t, p.Err())
+	if pages < 100 {
+		t.Fatalf("expected at least 100 pages, got %d", pages)
+	}
+
+	resp, err := cli.Get(context.TODO(), "key/", clientv3.WithPrefix(), clientv3.WithRev(p.Revision()))
+	require.NoError(t, err)
+	if resp.Header.Revision <= p.Revision() {
+		t.Fatalf("expected concurrent writes after revision %d", p.Revision())
+	}
+	wkeys := make([]string, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		wkeys[i] = string(kv.Key)
+	}
+	if !reflect.DeepEqual(keys, wkeys) {
+		t.Fatalf("paged scan returned %d keys, want the %d keys at revision %d", len(keys), len(wkeys), p.Revision())
+	}
+}
+
+func TestGetPagedNullBytes(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	wkeys := []string{"\x00", "\x00\x00", "a", "a\x00", "a\x00\x00", "a\x00b", "a\x01", "b", "\xff\xff"}
+	for _, k := range wkeys {
+		_, err := cli.Put(context.TODO(), k, "v")
+		require.NoError(t, err)
+	}
+
+	for _, pageSize := range []int64{1, 2, 100} {
+		// the empty prefix scans the entire keyspace
+		var keys []string
+		p := clientv3.GetPaged(context.TODO(), cli, "", pageSize)
+		for p.Next() {
+			for _, kv := range p.Page() {
+				keys = append(keys, string(kv.Key))
+			}
+		}
+		require.NoError(t, p.Err())
+		if !reflect.DeepEqual(keys, wkeys) {
+			t.Errorf("page size %d: expected keys %q, got %q", pageSize, wkeys, keys)
+		}
+	}
+
+	var keys []string
+	p := clientv3.GetPaged(context.TODO(), cli, "a\x00", 1)
+	for p.Next() {
+		for _, kv := range p.Page() {
+			keys = append(keys, string(kv.Key))
+		}
+	}
+	require.NoError(t, p.Err())
+	if wkeys := []string{"a\x00", "a\x00\x00", "a\x00b"}; !reflect.DeepEqual(keys, wkeys) {
+		t.Errorf("expected keys %q, got %q", wkeys, keys)
+	}
+
+	p = clientv3.GetPaged(context.TODO(), cli, "a", 0)
+	if p.Next() || p.Err() == nil {
+		t.Errorf("expected error for zero page size, got %v", p.Err())
+	}
+}
+
+func TestGetPagedCompacted(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	for i := 0; i < 100; i++ {
+		_, err := cli.Put(context.TODO(), fmt.Sprintf("key/%02d", i), "v")
+		require.NoError(t, err)
+	}
+
+	p := clientv3.GetPaged(context.TODO(), cli, "key/", 10)
+	require.True(t, p.Next())
+	require.Len(t, p.Page(), 10)
+
+	resp, err := cli.Put(context.TODO(), "key/00", "v2")
+	require.NoError(t, err)
+	_, err = cli.Compact(context.TODO(), resp.Header.Revision, clientv3.WithCompactPhysical())
+	require.NoError(t, err)
+
+	if p.Next() {
+		t.Fatal("expected paged scan to stop after compaction")
+	}
+	var cerr *clientv3.PagedScanCompactedError
+	if !errors.As(p.Err(), &cerr) {
+		t.Fatalf("expected %T, got %v", cerr, p.Err())
+	}
+	if cerr.Revision != p.Revision() || cerr.NextKey != "key/09\x00" {
+		t.Errorf("expected revision %d and next key %q, got %+v", p.Revision(), "key/09\x00", cerr)
+	}
+	if !errors.Is(p.Err(), rpctypes.ErrCompacted) {
+		t.Errorf("expected error to wrap %v", rpctypes.ErrCompacted)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestPutIfAbsentFirstWriteWins ensures exactly one of concurrent PutIfAbsent
+// calls for the same key succeeds, across all members.
+func TestPutIfAbsentFirstWriteWins(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	const writers = 30
+	var wg sync.WaitGroup
+	succeeded := make(chan string, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			val := fmt.Sprintf("v%d", i)
+			ok, err := clientv3.PutIfAbsent(context.TODO(), clus.Client(i%3), "foo", val)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				succeeded <- val
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(succeeded)
+
+	var winners []string
+	for val := range succeeded {
+		winners = append(winners, val)
+	}
+	require.Len(t, winners, 1)
+	resp, err := clus.RandClient().Get(context.TODO(), "foo")
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	require.Equal(t, winners[0], string(resp.Kvs[0].Value))
+	require.Equal(t, int64(1), resp.Kvs[0].Version)
+}
+
+func TestPutIfAbsent(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ok, err := clientv3.PutIfAbsent(context.TODO(), cli, "foo", "bar")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = clientv3.PutIfAbsent(context.TODO(), cli, "foo", "baz")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a deleted key is absent
+	_, err = cli.Delete(context.TODO(), "foo")
+	require.NoError(t, err)
+	lresp, err := cli.Grant(context.TODO(), 60)
+	require.NoError(t, err)
+	ok, err = clientv3.PutIfAbsent(context.TODO(), cli, "foo", "baz", clientv3.WithLease(lresp.ID))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	resp, err := cli.Get(context.TODO(), "foo")
+	require.NoError(t, err)
+	require.Equal(t, "baz", string(resp.Kvs[0].Value))
+	require.Equal(t, int64(lresp.ID), resp.Kvs[0].Lease)
+}
+
+func TestPutIfEqual(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	// version 0 expects the key not to exist
+	ok, err := clientv3.PutIfEqual(context.TODO(), cli, "foo", "bar1", 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = clientv3.PutIfEqual(context.TODO(), cli, "foo", "bar2", 0)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = clientv3.PutIfEqual(context.TODO(), cli, "foo", "bar2", 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = clientv3.PutIfEqual(context.TODO(), cli, "foo", "bar3", 1)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	resp, err := cli.Get(context.TODO(), "foo")
+	require.NoError(t, err)
+	require.Equal(t, "bar2", string(resp.Kvs[0].Value))
+	require.Equal(t, int64(2), resp.Kvs[0].Version)
+}
+
+func TestCompareAndSwapMany(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	_, err := cli.Put(context.TODO(), "a", "a1")
+	require.NoError(t, err)
+	_, err = cli.Put(context.TODO(), "b", "b1")
+	require.NoError(t, err)
+	_, err = cli.Put(context.TODO(), "b", "b2")
+	require.NoError(t, err)
+
+	// c is expected not to exist
+	ok, err := clientv3.CompareAndSwapMany(context.TODO(), cli,
+		map[string]int64{"a": 1, "b": 2, "c": 0},
+		map[string]string{"a": "a2", "b": "b3", "c": "c1"},
+	)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	resp, err := cli.Get(context.TODO(), "", clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 3)
+	for i, want := range []struct {
+		key, value string
+		version    int64
+	}{{"a", "a2", 2}, {"b", "b3", 3}, {"c", "c1", 1}} {
+		require.Equal(t, want.key, string(resp.Kvs[i].Key))
+		require.Equal(t, want.value, string(resp.Kvs[i].Value))
+		require.Equal(t, want.version, resp.Kvs[i].Version)
+	}
+}
+
+func TestCompareAndSwapManyStale(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	_, err := cli.Put(context.TODO(), "a", "a1")
+	require.NoError(t, err)
+	presp, err := cli.Put(context.TODO(), "b", "b1")
+	require.NoError(t, err)
+
+	for _, expected := range []map[string]int64{
+		// b was put once, not twice
+		{"a": 1, "b": 2},
+		// c does not exist
+		{"a": 1, "c": 1},
+		// a exists
+		{"a": 0, "b": 1},
+	} {
+		ok, err := clientv3.CompareAndSwapMany(context.TODO(), cli, expected,
+			map[string]string{"a": "a2", "b": "b2", "c": "c1"},
+		)
+		require.NoError(t, err)
+		require.False(t, ok, "expected %v", expected)
+	}
+
+	// nothing was written
+	resp, err := cli.Get(context.TODO(), "", clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.Equal(t, presp.Header.Revision, resp.Header.Revision)
+	require.Len(t, resp.Kvs, 2)
+	require.Equal(t, "a1", string(resp.Kvs[0].Value))
+	require.Equal(t, "b1", string(resp.Kvs[1].Value))
+}
+
+// TestIncrementConcurrent ensures concurrent increments of the same key, across
+// all members, are not lost.
+func TestIncrementConcurrent(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	const writers, increments = 10, 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				if _, err := clientv3.Increment(context.TODO(), clus.Client(i%3), "counter", int64(i+1)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	resp, err := clus.RandClient().Get(context.TODO(), "counter")
+	require.NoError(t, err)
+	// the sum of the deltas 1..writers, each added increments times
+	want := increments * writers * (writers + 1) / 2
+	require.Equal(t, fmt.Sprint(want), string(resp.Kvs[0].Value))
+}
+
+func TestIncrement(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	// a missing key starts from zero
+	v, err := clientv3.Increment(context.TODO(), cli, "foo", 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), v)
+	v, err = clientv3.Increment(context.TODO(), cli, "foo", -7)
+	require.NoError(t, err)
+	require.Equal(t, int64(-2), v)
+
+	// the key keeps its lease
+	lresp, err := cli.Grant(context.TODO(), 60)
+	require.NoError(t, err)
+	_, err = cli.Put(context.TODO(), "foo", "10", clientv3.WithLease(lresp.ID))
+	require.NoError(t, err)
+	v, err = clientv3.Increment(context.TODO(), cli, "foo", 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), v)
+	resp, err := cli.Get(context.TODO(), "foo")
+	require.NoError(t, err)
+	require.Equal(t, int64(lresp.ID), resp.Kvs[0].Lease)
+
+	_, err = cli.Put(context.TODO(), "bar", "baz")
+	require.NoError(t, err)
+	_, err = clientv3.Increment(context.TODO(), cli, "bar", 1)
+	var nerr *clientv3.NotANumberError
+	require.ErrorAs(t, err, &nerr)
+	require.Equal(t, "baz", nerr.Value)
+	resp, err = cli.Get(context.TODO(), "bar")
+	require.NoError(t, err)
+	require.Equal(t, "baz", string(resp.Kvs[0].Value))
+}
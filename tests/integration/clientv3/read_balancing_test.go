@@ -0,0 +1,67 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestReadLoadBalancing ensures serializable reads are served by the
+// followers, while linearizable reads still succeed.
+func TestReadLoadBalancing(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	leaderID := uint64(clus.Members[lead].ID())
+
+	eps := []string{clus.Members[0].GRPCURL(), clus.Members[1].GRPCURL(), clus.Members[2].GRPCURL()}
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: eps, DialTimeout: 5 * time.Second, ReadLoadBalancing: true})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, err = cli.Put(context.TODO(), "foo", "bar")
+	require.NoError(t, err)
+
+	served := make(map[uint64]int)
+	for i := 0; i < 30; i++ {
+		resp, err := cli.Get(context.TODO(), "foo", clientv3.WithSerializable())
+		require.NoError(t, err)
+		served[resp.Header.MemberId]++
+	}
+	if served[leaderID] != 0 {
+		t.Fatalf("expected no serializable read served by the leader, got %d", served[leaderID])
+	}
+	if len(served) != 2 {
+		t.Fatalf("expected serializable reads to be spread across both followers, got %v", served)
+	}
+
+	for i := 0; i < 10; i++ {
+		resp, err := cli.Get(context.TODO(), "foo")
+		require.NoError(t, err)
+		if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "bar" {
+			t.Fatalf("unexpected linearizable read %v", resp.Kvs)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+func mustGetPrefix(t *testing.T, cli *clientv3.Client, prefix string) map[string]string {
+	resp, err := cli.Get(context.TODO(), prefix, clientv3.WithPrefix())
+	require.NoError(t, err)
+	kvs := make(map[string]string)
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = string(kv.Value)
+	}
+	return kvs
+}
+
+// TestReplacePrefix ensures ReplacePrefix leaves exactly the new keys under
+// the prefix, without touching the keys around it, and that a watcher sees the
+// replacement at a single revision, so never an empty prefix.
+func TestReplacePrefix(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx := context.TODO()
+	for _, key := range []string{"conf", "conf/a", "conf/b", "conf/b/x", "conf/c", "conf0", "cone"} {
+		_, err := cli.Put(ctx, key, "old")
+		require.NoError(t, err)
+	}
+	old := mustGetPrefix(t, cli, "conf/")
+
+	wch := cli.Watch(ctx, "conf/", clientv3.WithPrefix())
+	want := map[string]string{"conf/": "new", "conf/b": "new", "conf/d": "new"}
+	resp, err := clientv3.ReplacePrefix(ctx, cli, "conf/", want)
+	require.NoError(t, err)
+	require.Equal(t, want, mustGetPrefix(t, cli, "conf/"))
+	for _, key := range []string{"conf", "conf0", "cone"} {
+		gresp, err := cli.Get(ctx, key)
+		require.NoError(t, err)
+		require.Len(t, gresp.Kvs, 1, "key %q outside the prefix was deleted", key)
+	}
+
+	// replay the events on the old keys
+	keys := old
+	for len(keys) != len(want) || keys["conf/d"] == "" {
+		select {
+		case wresp := <-wch:
+			require.NoError(t, wresp.Err())
+			for _, ev := range wresp.Events {
+				require.Equal(t, resp.Header.Revision, ev.Kv.ModRevision)
+				if ev.Type == mvccpb.DELETE {
+					delete(keys, string(ev.Kv.Key))
+				} else {
+					keys[string(ev.Kv.Key)] = string(ev.Kv.Value)
+				}
+			}
+			require.NotEmpty(t, keys)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, got keys %v", keys)
+		}
+	}
+	require.Equal(t, want, keys)
+
+	// an empty set deletes the prefix
+	_, err = clientv3.ReplacePrefix(ctx, cli, "conf/", nil)
+	require.NoError(t, err)
+	require.Empty(t, mustGetPrefix(t, cli, "conf/"))
+
+	_, err = clientv3.ReplacePrefix(ctx, cli, "conf/", map[string]string{"cone": "new"})
+	require.Error(t, err)
+}
+
+// TestReplacePrefixLease ensures the keys put by ReplacePrefix get the lease
+// given as option, and lose the lease they had otherwise.
+func TestReplacePrefixLease(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx := context.TODO()
+	lresp, err := cli.Grant(ctx, 60)
+	require.NoError(t, err)
+	_, err = cli.Put(ctx, "conf/a", "old", clientv3.WithLease(lresp.ID))
+	require.NoError(t, err)
+
+	_, err = clientv3.ReplacePrefix(ctx, cli, "conf/", map[string]string{"conf/a": "new", "conf/b": "new"})
+	require.NoError(t, err)
+	gresp, err := cli.Get(ctx, "conf/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	for _, kv := range gresp.Kvs {
+		require.Equal(t, int64(clientv3.NoLease), kv.Lease)
+	}
+
+	_, err = clientv3.ReplacePrefix(ctx, cli, "conf/", map[string]string{"conf/a": "new", "conf/b": "new"}, clientv3.WithLease(lresp.ID))
+	require.NoError(t, err)
+	gresp, err = cli.Get(ctx, "conf/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	for _, kv := range gresp.Kvs {
+		require.Equal(t, int64(lresp.ID), kv.Lease)
+	}
+}
+
+// TestReplacePrefixTooManyOps ensures a replacement exceeding --max-txn-ops
+// fails as a whole.
+func TestReplacePrefixTooManyOps(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1, MaxTxnOps: 8})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx := context.TODO()
+	_, err := cli.Put(ctx, "conf/a", "old")
+	require.NoError(t, err)
+
+	// 4 puts and 5 deletes
+	kvs := map[string]string{"conf/b": "new", "conf/c": "new", "conf/d": "new", "conf/e": "new"}
+	_, err = clientv3.ReplacePrefix(ctx, cli, "conf/", kvs)
+	require.ErrorIs(t, err, rpctypes.ErrTooManyOps)
+	require.Equal(t, map[string]string{"conf/a": "old"}, mustGetPrefix(t, cli, "conf/"))
+}
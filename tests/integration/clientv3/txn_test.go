@@ -17,6 +17,8 @@ package clientv3test
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,6 +26,9 @@ import (
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/embed"
 	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTxnError(t *testing.T) {
@@ -226,3 +231,286 @@ func TestTxnNested(t *testing.T) {
 		t.Errorf("unexpected Get response %+v", resp)
 	}
 }
+
+func TestTxnNestedBranches(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+
+	if _, err := kv.Put(context.TODO(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the outer txn succeeds, the nested one in Then fails and the nested
+	// one in Else is never evaluated
+	tresp, err := kv.Txn(context.TODO()).
+		If(clientv3.Compare(clientv3.Value("foo"), "=", "bar")).
+		Then(clientv3.OpTxn(
+			[]clientv3.Cmp{clientv3.Compare(clientv3.Version("abc"), ">", 0)},
+			[]clientv3.Op{clientv3.OpPut("abc", "then-then")},
+			[]clientv3.Op{clientv3.OpPut("abc", "then-else"), clientv3.OpGet("foo")},
+		)).
+		Else(clientv3.OpTxn(
+			nil,
+			[]clientv3.Op{clientv3.OpPut("abc", "else-then")},
+			nil,
+		)).Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tresp.Succeeded {
+		t.Fatal("expected outer txn to succeed")
+	}
+	ors := tresp.OpResponses()
+	if len(ors) != 1 || ors[0].Txn() == nil {
+		t.Fatalf("expected a single nested txn response, got %+v", tresp.Responses)
+	}
+	nested := ors[0].Txn()
+	if nested.Succeeded {
+		t.Fatal("expected nested txn to fail")
+	}
+	nors := nested.OpResponses()
+	if len(nors) != 2 || nors[0].Put() == nil || nors[1].Get() == nil {
+		t.Fatalf("expected put and get responses, got %+v", nested.Responses)
+	}
+	if kvs := nors[1].Get().Kvs; len(kvs) != 1 || string(kvs[0].Value) != "bar" {
+		t.Errorf("unexpected nested get response %+v", nors[1].Get())
+	}
+
+	// the outer txn fails and the nested one in Else succeeds
+	tresp, err = kv.Txn(context.TODO()).
+		If(clientv3.Compare(clientv3.Value("foo"), "=", "baz")).
+		Then(clientv3.OpPut("abc", "then")).
+		Else(clientv3.OpTxn(
+			[]clientv3.Cmp{clientv3.Compare(clientv3.Value("abc"), "=", "then-else")},
+			[]clientv3.Op{clientv3.OpPut("abc", "else-then")},
+			[]clientv3.Op{clientv3.OpPut("abc", "else-else")},
+		)).Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tresp.Succeeded {
+		t.Fatal("expected outer txn to fail")
+	}
+	ors = tresp.OpResponses()
+	if len(ors) != 1 || ors[0].Txn() == nil || !ors[0].Txn().Succeeded {
+		t.Fatalf("expected a succeeded nested txn response, got %+v", tresp.Responses)
+	}
+
+	resp, err := kv.Get(context.TODO(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "else-then" {
+		t.Errorf("unexpected Get response %+v", resp)
+	}
+}
+
+// TestTxnDryRun ensures a dry run txn reports the branch and the reads of an
+// actual execution of the same txn, without writing anything.
+func TestTxnDryRun(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	if _, err := kv.Put(context.TODO(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"bar", "baz"} {
+		txn := func() clientv3.Txn {
+			return kv.Txn(context.TODO()).
+				If(clientv3.Compare(clientv3.Value("foo"), "=", value)).
+				Then(
+					clientv3.OpGet("foo"),
+					clientv3.OpTxn(
+						[]clientv3.Cmp{clientv3.Compare(clientv3.Version("abc"), "=", 0)},
+						[]clientv3.Op{clientv3.OpPut("abc", "then"), clientv3.OpGet("foo")},
+						nil,
+					),
+				).
+				Else(clientv3.OpPut("foo", "else"), clientv3.OpGet("foo"))
+		}
+		gresp, err := kv.Get(context.TODO(), "foo")
+		require.NoError(t, err)
+		rev := gresp.Header.Revision
+
+		dresp, err := txn().WithDryRun().Commit()
+		require.NoError(t, err)
+		assert.Equal(t, rev, dresp.Header.Revision)
+		gresp, err = kv.Get(context.TODO(), "", clientv3.WithPrefix())
+		require.NoError(t, err)
+		assert.Equal(t, rev, gresp.Header.Revision, "a dry run must not write")
+
+		resp, err := txn().Commit()
+		require.NoError(t, err)
+		assert.Equal(t, rev+1, resp.Header.Revision)
+		require.Equal(t, resp.Succeeded, dresp.Succeeded)
+		require.Len(t, dresp.Responses, len(resp.Responses))
+		if resp.Succeeded {
+			assert.Equal(t, resp.Responses[0].GetResponseRange().Kvs, dresp.Responses[0].GetResponseRange().Kvs)
+			nested, dnested := resp.Responses[1].GetResponseTxn(), dresp.Responses[1].GetResponseTxn()
+			require.Equal(t, nested.Succeeded, dnested.Succeeded)
+			assert.Equal(t, nested.Responses[1].GetResponseRange().Kvs, dnested.Responses[1].GetResponseRange().Kvs)
+		} else {
+			// the get of the dry run does not observe the put before it
+			assert.Equal(t, "else", string(resp.Responses[1].GetResponseRange().Kvs[0].Value))
+			assert.Equal(t, "bar", string(dresp.Responses[1].GetResponseRange().Kvs[0].Value))
+		}
+	}
+}
+
+// TestTxnReadonlyNestedSerializable ensures a read-only transaction with
+// nested serializable reads is served locally, without a raft proposal, and
+// returns the reads of the taken branch.
+func TestTxnReadonlyNestedSerializable(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	if _, err := clus.Client(0).Put(context.TODO(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	clus.WaitMembersForLeader(t, clus.Members)
+	// wait for the write to be applied on every member
+	if _, err := clus.Client(1).Get(context.TODO(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clus.Client(2).Get(context.TODO(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// without quorum, only requests skipping raft can be served
+	clus.Members[1].Stop(t)
+	clus.Members[2].Stop(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	tresp, err := clus.Client(0).Txn(ctx).
+		If(clientv3.Compare(clientv3.Value("foo"), "=", "baz")).
+		Then(clientv3.OpGet("foo", clientv3.WithSerializable())).
+		Else(
+			clientv3.OpGet("foo", clientv3.WithSerializable()),
+			clientv3.OpTxn(
+				[]clientv3.Cmp{clientv3.Compare(clientv3.Version("abc"), ">", 0)},
+				nil,
+				[]clientv3.Op{clientv3.OpGet("foo", clientv3.WithSerializable())},
+			),
+		).Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tresp.Succeeded {
+		t.Fatal("expected txn to fail")
+	}
+	ors := tresp.OpResponses()
+	if len(ors) != 2 || ors[0].Get() == nil || ors[1].Txn() == nil {
+		t.Fatalf("expected get and nested txn responses, got %+v", tresp.Responses)
+	}
+	if kvs := ors[0].Get().Kvs; len(kvs) != 1 || string(kvs[0].Value) != "bar" {
+		t.Errorf("unexpected get response %+v", ors[0].Get())
+	}
+	nested := ors[1].Txn()
+	if nested.Succeeded {
+		t.Fatal("expected nested txn to fail")
+	}
+	nors := nested.OpResponses()
+	if len(nors) != 1 || nors[0].Get() == nil {
+		t.Fatalf("expected a nested get response, got %+v", nested.Responses)
+	}
+	if kvs := nors[0].Get().Kvs; len(kvs) != 1 || string(kvs[0].Value) != "bar" {
+		t.Errorf("unexpected nested get response %+v", nors[0].Get())
+	}
+}
+
+func TestTxnStream(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, MaxTxnOps: 2000})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	var ops []clientv3.Op
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("foo%04d", i)
+		if _, err := kv.Put(ctx, key, strings.Repeat("v", 1024)); err != nil {
+			t.Fatal(err)
+		}
+		ops = append(ops, clientv3.OpGet(key))
+	}
+	ops = append(ops, clientv3.OpTxn(nil, []clientv3.Op{clientv3.OpGet("foo", clientv3.WithPrefix(), clientv3.WithCountOnly())}, nil))
+
+	for _, succeeded := range []bool{true, false} {
+		t.Run(fmt.Sprintf("succeeded=%v", succeeded), func(t *testing.T) {
+			cmp := clientv3.Compare(clientv3.Value("foo0000"), "=", strings.Repeat("v", 1024))
+			if !succeeded {
+				cmp = clientv3.Compare(clientv3.Value("foo0000"), "!=", strings.Repeat("v", 1024))
+			}
+			want, err := kv.Txn(ctx).If(cmp).Then(ops...).Else(ops[1:]...).Commit()
+			require.NoError(t, err)
+
+			ts, err := clientv3.CommitStream(kv.Txn(ctx).If(cmp).Then(ops...).Else(ops[1:]...))
+			require.NoError(t, err)
+			assert.Equal(t, succeeded, ts.Succeeded)
+			got, err := ts.Response()
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	// a txn with writes is applied through raft and streamed afterwards
+	ts, err := clientv3.CommitStream(kv.Txn(ctx).Then(clientv3.OpPut("bar", "1"), clientv3.OpGet("bar")))
+	require.NoError(t, err)
+	resp, err := ts.Recv()
+	require.NoError(t, err)
+	assert.NotNil(t, resp.GetResponsePut())
+	resp, err = ts.Recv()
+	require.NoError(t, err)
+	if kvs := resp.GetResponseRange().Kvs; assert.Len(t, kvs, 1) {
+		assert.Equal(t, "1", string(kvs[0].Value))
+	}
+	_, err = ts.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func BenchmarkTxnReadonly(b *testing.B) {
+	benchmarkTxnReads(b, nil)
+}
+
+// BenchmarkTxnReadonlyThroughRaft runs the same reads as BenchmarkTxnReadonly,
+// but an untaken delete forces the transaction through a raft proposal.
+func BenchmarkTxnReadonlyThroughRaft(b *testing.B) {
+	benchmarkTxnReads(b, []clientv3.Op{clientv3.OpDelete("abc")})
+}
+
+func benchmarkTxnReads(b *testing.B, elseOps []clientv3.Op) {
+	integration2.BeforeTest(b, integration2.WithoutGoLeakDetection())
+
+	clus := integration2.NewCluster(b, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(b)
+
+	kv := clus.RandClient()
+	if _, err := kv.Put(context.TODO(), "foo", "bar"); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := kv.Txn(context.TODO()).
+			If(clientv3.Compare(clientv3.Value("foo"), "=", "bar")).
+			Then(clientv3.OpGet("foo"), clientv3.OpTxn(nil, []clientv3.Op{clientv3.OpGet("foo")}, nil)).
+			Else(elseOps...).
+			Commit()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+func TestWaitForValueAlreadySatisfied(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	_, err := cli.Put(context.TODO(), "foo", "ready")
+	require.NoError(t, err)
+
+	for _, p := range []clientv3.ValuePredicate{
+		{Kind: clientv3.ValueEquals, Value: "ready"},
+		{Kind: clientv3.ValueExists},
+		{Kind: clientv3.ValueHasPrefix, Value: "rea"},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		kv, err := clientv3.WaitForValue(ctx, cli, "foo", p)
+		cancel()
+		require.NoError(t, err)
+		require.Equal(t, "ready", string(kv.Value))
+	}
+}
+
+func TestWaitForValueAfterPut(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	_, err := clus.Client(0).Put(context.TODO(), "foo", "starting")
+	require.NoError(t, err)
+
+	type result struct {
+		val string
+		err error
+	}
+	resultc := make(chan result, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		kv, err := clientv3.WaitForValue(ctx, clus.Client(1), "foo", clientv3.ValuePredicate{Kind: clientv3.ValueHasPrefix, Value: "ready"})
+		if err != nil {
+			resultc <- result{err: err}
+			return
+		}
+		resultc <- result{val: string(kv.Value)}
+	}()
+
+	for _, val := range []string{"starting", "not ready", "ready: 3 members"} {
+		select {
+		case r := <-resultc:
+			t.Fatalf("unexpected result %+v before %q is put", r, val)
+		case <-time.After(100 * time.Millisecond):
+		}
+		_, err = clus.Client(2).Put(context.TODO(), "foo", val)
+		require.NoError(t, err)
+	}
+
+	select {
+	case r := <-resultc:
+		require.NoError(t, r.err)
+		require.Equal(t, "ready: 3 members", r.val)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for WaitForValue to return")
+	}
+}
+
+func TestWaitForValueTimeout(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	_, err := cli.Put(context.TODO(), "foo", "bar")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err = clientv3.WaitForValue(ctx, cli, "foo", clientv3.ValuePredicate{Kind: clientv3.ValueEquals, Value: "baz"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = cli.Delete(context.TODO(), "foo")
+	require.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err = clientv3.WaitForValue(ctx, cli, "foo", clientv3.ValuePredicate{Kind: clientv3.ValueExists})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
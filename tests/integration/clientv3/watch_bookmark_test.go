@@ -0,0 +1,148 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestWatchFromBookmark ensures a watch resumed from a persisted bookmark by
+// a new client receives every event after the bookmark, and only those.
+func TestWatchFromBookmark(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	ctx := context.TODO()
+	cli, err := integration2.NewClientV3(clus.Members[0])
+	require.NoError(t, err)
+
+	resp, err := cli.Get(ctx, "foo/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	b, err := clientv3.NewWatchBookmark("foo/", clientv3.WithPrefix(), clientv3.WithFilterDelete(), clientv3.WithRev(resp.Header.Revision+1))
+	require.NoError(t, err)
+	wch, err := clientv3.WatchFromBookmark(ctx, cli, b)
+	require.NoError(t, err)
+
+	put := func(key, val string) {
+		_, err := clus.Client(0).Put(ctx, key, val)
+		require.NoError(t, err)
+	}
+	put("foo/a", "1")
+	put("bar", "1")
+	_, err = clus.Client(0).Delete(ctx, "foo/a")
+	require.NoError(t, err)
+	put("foo/b", "2")
+	// process the events up to foo/b, then persist the bookmark
+	var seen []string
+	for len(seen) < 2 {
+		select {
+		case wr := <-wch:
+			require.NoError(t, wr.Err())
+			for _, ev := range wr.Events {
+				seen = append(seen, fmt.Sprintf("%s=%s", ev.Kv.Key, ev.Kv.Value))
+			}
+			b.Update(wr)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for events")
+		}
+	}
+	require.Equal(t, []string{"foo/a=1", "foo/b=2"}, seen)
+	data, err := json.Marshal(b)
+	require.NoError(t, err)
+	require.NoError(t, cli.Close())
+
+	// events while no client watches
+	put("foo/c", "3")
+	put("foo/a", "4")
+
+	cli2, err := integration2.NewClientV3(clus.Members[0])
+	require.NoError(t, err)
+	defer cli2.Close()
+	var resumed clientv3.WatchBookmark
+	require.NoError(t, json.Unmarshal(data, &resumed))
+	wch, err = clientv3.WatchFromBookmark(ctx, cli2, &resumed)
+	require.NoError(t, err)
+	_, err = cli2.Put(ctx, "foo/d", "5")
+	require.NoError(t, err)
+
+	seen = nil
+	for len(seen) < 3 {
+		select {
+		case wr := <-wch:
+			require.NoError(t, wr.Err())
+			for _, ev := range wr.Events {
+				seen = append(seen, fmt.Sprintf("%s=%s", ev.Kv.Key, ev.Kv.Value))
+			}
+			resumed.Update(wr)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, got %v", seen)
+		}
+	}
+	require.Equal(t, []string{"foo/c=3", "foo/a=4", "foo/d=5"}, seen)
+
+	// the history after the bookmark is lost once compacted
+	_, err = cli2.Put(ctx, "foo/e", "6")
+	require.NoError(t, err)
+	presp, err := cli2.Put(ctx, "foo/f", "7")
+	require.NoError(t, err)
+	_, err = cli2.Compact(ctx, presp.Header.Revision)
+	require.NoError(t, err)
+	_, err = clientv3.WatchFromBookmark(ctx, cli2, &resumed)
+	var cerr *clientv3.WatchBookmarkCompactedError
+	require.True(t, errors.As(err, &cerr), "expected compacted error, got %v", err)
+	require.Equal(t, resumed.Revision, cerr.Revision)
+	require.ErrorIs(t, err, rpctypes.ErrCompacted)
+}
+
+// TestWatchFromBookmarkRangeCompacted ensures a bookmark does not resume if
+// its revision was compacted in one of its additional ranges only.
+func TestWatchFromBookmarkRangeCompacted(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	ctx := context.TODO()
+	cli := clus.Client(0)
+	presp, err := cli.Put(ctx, "bar", "1")
+	require.NoError(t, err)
+	b, err := clientv3.NewWatchBookmark("foo/", clientv3.WithPrefix(), clientv3.WithWatchRange("bar"), clientv3.WithRev(presp.Header.Revision+1))
+	require.NoError(t, err)
+
+	for _, val := range []string{"2", "3"} {
+		_, err = cli.Put(ctx, "bar", val)
+		require.NoError(t, err)
+	}
+	// the history of foo/ is kept, but not the one of bar
+	_, err = cli.Do(ctx, clientv3.OpCompactKey("bar", 1))
+	require.NoError(t, err)
+	_, err = clientv3.WatchFromBookmark(ctx, cli, b)
+	var cerr *clientv3.WatchBookmarkCompactedError
+	require.True(t, errors.As(err, &cerr), "expected compacted error, got %v", err)
+	require.Equal(t, b.Revision, cerr.Revision)
+}
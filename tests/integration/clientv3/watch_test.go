@@ -523,6 +523,431 @@ func TestWatchCompactRevision(t *testing.T) {
 	}
 }
 
+// TestWatchAutoResumeFromCompaction ensures a watcher created with
+// WithAutoResumeFromCompaction continues from the compacted revision
+// instead of being canceled.
+func TestWatchAutoResumeFromCompaction(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	// set some keys at revisions 2-6
+	kv := clus.RandClient()
+	for i := 0; i < 5; i++ {
+		if _, err := kv.Put(context.TODO(), "foo", fmt.Sprintf("bar%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := kv.Compact(context.TODO(), 4); err != nil {
+		t.Fatal(err)
+	}
+
+	wch := clus.RandClient().Watch(context.Background(), "foo", clientv3.WithRev(2), clientv3.WithAutoResumeFromCompaction())
+
+	wresp, ok := <-wch
+	if !ok {
+		t.Fatalf("expected wresp, but got closed channel")
+	}
+	if !wresp.CompactionResumed() {
+		t.Fatalf("expected compaction resumed response, got %+v", wresp)
+	}
+	if wresp.Err() != nil || wresp.Canceled {
+		t.Fatalf("expected no error, got %v (%+v)", wresp.Err(), wresp)
+	}
+	if wresp.CompactRevision != 4 {
+		t.Fatalf("expected compact revision 4, got %d", wresp.CompactRevision)
+	}
+
+	if _, err := kv.Put(context.TODO(), "foo", "bar5"); err != nil {
+		t.Fatal(err)
+	}
+	wRev := int64(4)
+	for wRev <= 7 {
+		select {
+		case wresp, ok = <-wch:
+			if !ok {
+				t.Fatalf("expected wresp, but got closed channel")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("resumed watch timed out")
+		}
+		if wresp.Err() != nil {
+			t.Fatalf("unexpected error %v", wresp.Err())
+		}
+		for _, ev := range wresp.Events {
+			if ev.Kv.ModRevision != wRev {
+				t.Fatalf("expected modRev %v, got %+v", wRev, ev)
+			}
+			wRev++
+		}
+	}
+}
+
+// TestWatchAutoResumeFromCompactionOnReconnect ensures a watcher resuming to a
+// compacted revision after its server restarts continues without the caller
+// restarting it.
+func TestWatchAutoResumeFromCompactionOnReconnect(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, UseBridge: true})
+	defer clus.Terminate(t)
+
+	// create a waiting watcher at rev 1
+	wch := clus.Client(0).Watch(context.Background(), "foo", clientv3.WithRev(1), clientv3.WithAutoResumeFromCompaction())
+	clus.Members[0].Stop(t)
+
+	clus.WaitLeader(t)
+
+	// put some data and compact away
+	numPuts := 5
+	kv := clus.Client(1)
+	for i := 0; i < numPuts; i++ {
+		if _, err := kv.Put(context.TODO(), "foo", "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := kv.Compact(context.TODO(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	clus.Members[0].Restart(t)
+
+	// the watcher either stays synced and reads off all events, or resumes
+	// from the compacted revision; either way it receives the latest put
+	wRev := int64(2)
+	for int(wRev) <= numPuts+1 {
+		var wresp clientv3.WatchResponse
+		var ok bool
+		select {
+		case wresp, ok = <-wch:
+			if !ok {
+				t.Fatalf("expected wresp, but got closed channel")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("compacted watch timed out")
+		}
+		if wresp.Err() != nil {
+			t.Fatalf("unexpected error %v", wresp.Err())
+		}
+		if wresp.CompactionResumed() {
+			if wresp.CompactRevision != 3 {
+				t.Fatalf("expected compact revision 3, got %d", wresp.CompactRevision)
+			}
+			wRev = wresp.CompactRevision
+		}
+		for _, ev := range wresp.Events {
+			if ev.Kv.ModRevision != wRev {
+				t.Fatalf("expected modRev %v, got %+v", wRev, ev)
+			}
+			wRev++
+		}
+	}
+}
+
+// TestWatchWithMaxBatch ensures a watcher created with WithMaxBatch receives
+// the events in order, in batches of at most the maximum batch size, without
+// splitting the events of a revision across batches.
+func TestWatchWithMaxBatch(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	// put keys at revisions 2-5, 3 keys at revision 6 and keys at revisions 7-9
+	kv := clus.RandClient()
+	for i := 0; i < 4; i++ {
+		if _, err := kv.Put(context.TODO(), fmt.Sprintf("foo%d", i), "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := kv.Txn(context.TODO()).Then(
+		clientv3.OpPut("fooa", "bar"),
+		clientv3.OpPut("foob", "bar"),
+		clientv3.OpPut("fooc", "bar"),
+	).Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 4; i < 7; i++ {
+		if _, err := kv.Put(context.TODO(), fmt.Sprintf("foo%d", i), "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wch := clus.RandClient().Watch(ctx, "foo", clientv3.WithPrefix(), clientv3.WithRev(2), clientv3.WithMaxBatch(5, 100*time.Millisecond))
+
+	var revs []int64
+	var batches [][]int64
+	for len(revs) < 10 {
+		var wresp clientv3.WatchResponse
+		var ok bool
+		select {
+		case wresp, ok = <-wch:
+			if !ok {
+				t.Fatalf("expected wresp, but got closed channel")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("batched watch timed out")
+		}
+		if wresp.Err() != nil {
+			t.Fatalf("unexpected error %v", wresp.Err())
+		}
+		var batch []int64
+		for _, ev := range wresp.Events {
+			batch = append(batch, ev.Kv.ModRevision)
+		}
+		if len(batch) > 5 && batch[0] != batch[len(batch)-1] {
+			t.Fatalf("expected at most 5 events or a single revision, got revisions %v", batch)
+		}
+		if len(revs) != 0 && len(batch) != 0 && revs[len(revs)-1] == batch[0] {
+			t.Fatalf("revision %d split across batches %v", batch[0], batches)
+		}
+		batches = append(batches, batch)
+		revs = append(revs, batch...)
+	}
+
+	wrevs := []int64{2, 3, 4, 5, 6, 6, 6, 7, 8, 9}
+	if !reflect.DeepEqual(revs, wrevs) {
+		t.Fatalf("expected revisions %v, got %v", wrevs, revs)
+	}
+	if len(batches) < 2 {
+		t.Fatalf("expected the events in several batches, got %v", batches)
+	}
+}
+
+// TestWatchWithCoalesce checks that a watcher created with WithCoalesce
+// receives far fewer events of a rapidly updated key, never misses a delete
+// and ends with the latest value at its revision.
+func TestWatchWithCoalesce(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cli := clus.RandClient()
+	coalesced := cli.Watch(ctx, "foo", clientv3.WithCoalesce(200*time.Millisecond))
+	plain := cli.Watch(ctx, "foo")
+
+	const updates = 200
+	var deleteRev, lastRev int64
+	for i := 0; i < updates; i++ {
+		if i == updates/2 {
+			resp, err := cli.Delete(ctx, "foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			deleteRev = resp.Header.Revision
+		}
+		resp, err := cli.Put(ctx, "foo", fmt.Sprintf("v%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lastRev = resp.Header.Revision
+	}
+
+	collect := func(wch clientv3.WatchChan) (events []*clientv3.Event) {
+		for len(events) == 0 || events[len(events)-1].Kv.ModRevision != lastRev {
+			select {
+			case wresp := <-wch:
+				if err := wresp.Err(); err != nil {
+					t.Fatal(err)
+				}
+				if len(wresp.Events) != 0 && wresp.Header.Revision != wresp.Events[len(wresp.Events)-1].Kv.ModRevision {
+					t.Fatalf("expected header revision %d, got %d", wresp.Events[len(wresp.Events)-1].Kv.ModRevision, wresp.Header.Revision)
+				}
+				events = append(events, wresp.Events...)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for revision %d, got %d events", lastRev, len(events))
+			}
+		}
+		return events
+	}
+
+	if n := len(collect(plain)); n != updates+1 {
+		t.Fatalf("expected %d events without coalescing, got %d", updates+1, n)
+	}
+
+	events := collect(coalesced)
+	if len(events) > updates/4 {
+		t.Fatalf("expected far fewer than %d coalesced events, got %d", updates, len(events))
+	}
+	deleted := false
+	for i, ev := range events {
+		if i > 0 && ev.Kv.ModRevision <= events[i-1].Kv.ModRevision {
+			t.Fatalf("expected increasing revisions, got %d after %d", ev.Kv.ModRevision, events[i-1].Kv.ModRevision)
+		}
+		if ev.Type == clientv3.EventTypeDelete {
+			deleted = ev.Kv.ModRevision == deleteRev
+		}
+	}
+	if !deleted {
+		t.Fatalf("expected the delete at revision %d to be delivered", deleteRev)
+	}
+	last := events[len(events)-1]
+	if want := fmt.Sprintf("v%d", updates-1); string(last.Kv.Value) != want || last.Type != clientv3.EventTypePut {
+		t.Fatalf("expected final value %q, got %s %q", want, last.Type, last.Kv.Value)
+	}
+}
+
+// TestWatchWithWatchRange ensures a single watch on several disjoint ranges
+// delivers the events of all of them on one stream, tagged with the range
+// they matched.
+func TestWatchWithWatchRange(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy does not support watches on several ranges")
+	}
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cli := clus.RandClient()
+	wch := cli.Watch(ctx, "a/", clientv3.WithPrefix(),
+		clientv3.WithWatchRange("b/", clientv3.WithPrefix()),
+		clientv3.WithWatchRange("c/", clientv3.WithPrefix(), clientv3.WithFilterDelete()),
+		// overlaps with the first range, whose events take precedence
+		clientv3.WithWatchRange("a/x"),
+		clientv3.WithCreatedNotify(),
+	)
+	if wresp := <-wch; !wresp.Created {
+		t.Fatalf("expected created notification, got %+v", wresp)
+	}
+
+	type event struct {
+		typ   mvccpb.Event_EventType
+		key   string
+		index int32
+	}
+	want := []event{
+		{mvccpb.PUT, "a/1", 0},
+		{mvccpb.PUT, "b/1", 1},
+		{mvccpb.PUT, "c/1", 2},
+		{mvccpb.PUT, "a/x", 0},
+		{mvccpb.DELETE, "b/1", 1},
+	}
+	for _, key := range []string{"a/1", "b/1", "c/1", "d/1", "a/x"} {
+		if _, err := cli.Put(ctx, key, "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the delete of c/1 is filtered out of its range
+	for _, key := range []string{"c/1", "b/1"} {
+		if _, err := cli.Delete(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []event
+	for len(got) < len(want) {
+		select {
+		case wresp := <-wch:
+			if err := wresp.Err(); err != nil {
+				t.Fatal(err)
+			}
+			for _, ev := range wresp.Events {
+				got = append(got, event{ev.Type, string(ev.Kv.Key), ev.RangeIndex})
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	select {
+	case wresp := <-wch:
+		t.Fatalf("unexpected response %+v", wresp)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestWatchWithGlobalOrder ensures a watch on several ranges with
+// WithGlobalOrder delivers the events of writes interleaved across the ranges
+// in revision order.
+func TestWatchWithGlobalOrder(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy does not support watches on several ranges")
+	}
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cli := clus.RandClient()
+	wch := cli.Watch(ctx, "a/", clientv3.WithPrefix(),
+		clientv3.WithWatchRange("b/", clientv3.WithPrefix()),
+		clientv3.WithGlobalOrder(),
+		clientv3.WithCreatedNotify(),
+	)
+	if wresp := <-wch; !wresp.Created {
+		t.Fatalf("expected created notification, got %+v", wresp)
+	}
+	// the progress requested by the ranges does not reach the other watchers
+	// of ctx
+	owch := cli.Watch(ctx, "c", clientv3.WithCreatedNotify())
+	if wresp := <-owch; !wresp.Created {
+		t.Fatalf("expected created notification, got %+v", wresp)
+	}
+
+	const puts = 100
+	donec := make(chan error, 2)
+	for _, pfx := range []string{"a/", "b/"} {
+		go func(pfx string) {
+			for i := 0; i < puts; i++ {
+				if _, err := cli.Put(ctx, pfx+strconv.Itoa(i), "v"); err != nil {
+					donec <- err
+					return
+				}
+			}
+			donec <- nil
+		}(pfx)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-donec; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		lastRev int64
+		got     = map[int32]int{}
+	)
+	for n := 0; n < 2*puts; {
+		select {
+		case wresp := <-wch:
+			if err := wresp.Err(); err != nil {
+				t.Fatal(err)
+			}
+			for _, ev := range wresp.Events {
+				if ev.Kv.ModRevision < lastRev {
+					t.Fatalf("event %q at revision %d after revision %d", ev.Kv.Key, ev.Kv.ModRevision, lastRev)
+				}
+				lastRev = ev.Kv.ModRevision
+				got[ev.RangeIndex]++
+				n++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+	}
+	if want := map[int32]int{0: puts, 1: puts}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected events per range %v, got %v", want, got)
+	}
+	select {
+	case wresp := <-owch:
+		t.Fatalf("unexpected response %+v", wresp)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestWatchWithProgressNotify(t *testing.T)        { testWatchWithProgressNotify(t, true) }
 func TestWatchWithProgressNotifyNoEvent(t *testing.T) { testWatchWithProgressNotify(t, false) }
 
@@ -681,6 +1106,78 @@ func TestWatchRequestProgress(t *testing.T) {
 	}
 }
 
+// TestWatchWithProgressNotifyOnCreate ensures a watcher started in the past
+// receives the caught up notification exactly once, after its backlog.
+func TestWatchWithProgressNotifyOnCreate(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy does not support caught up notifications")
+	}
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	wc := clus.RandClient()
+
+	// more revisions than a single synchronization batch of a slow watcher
+	numPuts := 1200
+	var createRev int64
+	for i := 0; i < numPuts; i++ {
+		resp, err := wc.Put(context.Background(), fmt.Sprintf("/a/%d", i), "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		createRev = resp.Header.Revision
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rch := wc.Watch(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithRev(1), clientv3.WithProgressNotifyOnCreate())
+
+	events := 0
+	for caughtUp := false; !caughtUp; {
+		select {
+		case resp := <-rch:
+			if err := resp.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !resp.IsCaughtUp() {
+				events += len(resp.Events)
+				continue
+			}
+			if events != numPuts {
+				t.Fatalf("expected %d events before caught up notification, got %d", numPuts, events)
+			}
+			if resp.Header.Revision != createRev {
+				t.Fatalf("expected caught up notification at revision %d, got %d", createRev, resp.Header.Revision)
+			}
+			if resp.IsProgressNotify() {
+				t.Fatal("caught up notification should not be a progress notification")
+			}
+			caughtUp = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for caught up notification after %d events", events)
+		}
+	}
+
+	if _, err := wc.Put(context.Background(), "/a/b", "1"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case resp := <-rch:
+		if resp.IsCaughtUp() || len(resp.Events) != 1 {
+			t.Fatalf("expected a single event, got %+v", resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	select {
+	case resp := <-rch:
+		t.Fatalf("unexpected watch response %+v", resp)
+	case <-time.After(time.Second):
+	}
+}
+
 func TestWatchEventType(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -924,6 +1421,88 @@ func TestWatchWithFilter(t *testing.T) {
 	}
 }
 
+// TestWatchWithFilterValue checks that watch value filtering drops the put
+// events not matching the filter, and only them.
+func TestWatchWithFilterValue(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	cluster := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer cluster.Terminate(t)
+
+	client := cluster.RandClient()
+	ctx := context.Background()
+
+	watches := []struct {
+		name  string
+		wc    clientv3.WatchChan
+		wkeys []string
+	}{
+		{
+			"no filter",
+			client.Watch(ctx, "k/", clientv3.WithPrefix()),
+			[]string{"PUT k/a x1", "PUT k/a y1", "PUT k/a x2", "DELETE k/a ", "PUT k/a y2", "PUT k/a y2", "PUT k/end x"},
+		},
+		{
+			"value prefix",
+			client.Watch(ctx, "k/", clientv3.WithPrefix(), clientv3.WithFilterValue(clientv3.ValuePrefix("x"))),
+			[]string{"PUT k/a x1", "PUT k/a x2", "DELETE k/a ", "PUT k/end x"},
+		},
+		{
+			"value prefix and no delete",
+			client.Watch(ctx, "k/", clientv3.WithPrefix(), clientv3.WithFilterValue(clientv3.ValuePrefix("x")), clientv3.WithFilterDelete()),
+			[]string{"PUT k/a x1", "PUT k/a x2", "PUT k/end x"},
+		},
+		{
+			"create only",
+			client.Watch(ctx, "k/", clientv3.WithPrefix(), clientv3.WithFilterValue(clientv3.CreateOnly())),
+			[]string{"PUT k/a x1", "DELETE k/a ", "PUT k/a y2", "PUT k/end x"},
+		},
+		{
+			"value changed",
+			client.Watch(ctx, "k/", clientv3.WithPrefix(), clientv3.WithFilterValue(clientv3.ValueChanged())),
+			[]string{"PUT k/a x1", "PUT k/a y1", "PUT k/a x2", "DELETE k/a ", "PUT k/a y2", "PUT k/end x"},
+		},
+		{
+			"value changed and prev kv",
+			client.Watch(ctx, "k/", clientv3.WithPrefix(), clientv3.WithFilterValue(clientv3.ValueChanged()), clientv3.WithPrevKV()),
+			[]string{"PUT k/a x1", "PUT k/a y1 prev=x1", "PUT k/a x2 prev=y1", "DELETE k/a  prev=x2", "PUT k/a y2", "PUT k/end x"},
+		},
+	}
+
+	for _, kv := range [][2]string{{"k/a", "x1"}, {"k/a", "y1"}, {"k/a", "x2"}, {"k/a", ""}, {"k/a", "y2"}, {"k/a", "y2"}, {"k/end", "x"}} {
+		var err error
+		if kv[1] == "" {
+			_, err = client.Delete(ctx, kv[0])
+		} else {
+			_, err = client.Put(ctx, kv[0], kv[1])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, w := range watches {
+		var keys []string
+		for len(keys) == 0 || keys[len(keys)-1] != "PUT k/end x" {
+			select {
+			case resp := <-w.wc:
+				for _, ev := range resp.Events {
+					key := fmt.Sprintf("%s %s %s", ev.Type, ev.Kv.Key, ev.Kv.Value)
+					if ev.PrevKv != nil {
+						key += fmt.Sprintf(" prev=%s", ev.PrevKv.Value)
+					}
+					keys = append(keys, key)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: timed out waiting for events, got %q", w.name, keys)
+			}
+		}
+		if !reflect.DeepEqual(keys, w.wkeys) {
+			t.Errorf("%s: expected events %q, got %q", w.name, w.wkeys, keys)
+		}
+	}
+}
+
 // TestWatchWithCreatedNotification checks that WithCreatedNotify returns a
 // Created watch response.
 func TestWatchWithCreatedNotification(t *testing.T) {
@@ -1215,3 +1794,138 @@ func testWatchClose(t *testing.T, wctx *watchctx) {
 		t.Fatalf("read wch got %v; expected closed channel", wresp)
 	}
 }
+
+// TestWatchWithCancel ensures canceling one of several watches sharing a
+// context closes only that watch.
+func TestWatchWithCancel(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := []string{"a", "b", "c"}
+	wchs := make([]clientv3.WatchChan, len(keys))
+	cancels := make([]context.CancelFunc, len(keys))
+	for i, key := range keys {
+		wchs[i], cancels[i] = clientv3.WatchWithCancel(ctx, cli, key, clientv3.WithCreatedNotify())
+		defer cancels[i]()
+		if wresp := <-wchs[i]; !wresp.Created {
+			t.Fatalf("expected created response, got %+v", wresp)
+		}
+	}
+
+	for _, key := range keys {
+		if _, err := cli.Put(ctx, key, "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancels[1]()
+	// the responses of b not received before the cancel may be dropped
+	for range wchs[1] {
+	}
+
+	for _, key := range keys {
+		if _, err := cli.Put(ctx, key, "2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, i := range []int{0, 2} {
+		for _, want := range []string{"1", "2"} {
+			select {
+			case wresp := <-wchs[i]:
+				if len(wresp.Events) != 1 || string(wresp.Events[0].Kv.Value) != want {
+					t.Fatalf("%s: expected event with value %q, got %+v", keys[i], want, wresp)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: timed out waiting for event with value %q", keys[i], want)
+			}
+		}
+	}
+	if wresp, ok := <-wchs[1]; ok {
+		t.Fatalf("expected closed channel, got %+v", wresp)
+	}
+}
+
+// TestWatchReplay ensures Replay streams the events of a revision range in
+// order and completes.
+func TestWatchReplay(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx := context.TODO()
+
+	// revisions 2 to 11 alternate between foo and bar
+	for i := 0; i < 10; i++ {
+		key := []string{"foo", "bar"}[i%2]
+		if _, err := cli.Put(ctx, key, strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cli.Delete(ctx, "foo"); err != nil { // revision 12
+		t.Fatal(err)
+	}
+
+	type event struct {
+		typ mvccpb.Event_EventType
+		key string
+		rev int64
+	}
+	replay := func(key string, startRev, endRev int64, opts ...clientv3.OpOption) ([]event, int64, error) {
+		var (
+			evs []event
+			rev int64
+		)
+		for wresp := range clientv3.Replay(ctx, cli, key, startRev, endRev, opts...) {
+			if err := wresp.Err(); err != nil {
+				return nil, 0, err
+			}
+			rev = wresp.Header.Revision
+			for _, ev := range wresp.Events {
+				evs = append(evs, event{ev.Type, string(ev.Kv.Key), ev.Kv.ModRevision})
+			}
+		}
+		return evs, rev, nil
+	}
+
+	evs, _, err := replay("foo", 5, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []event{{mvccpb.PUT, "foo", 6}, {mvccpb.PUT, "foo", 8}}
+	if !reflect.DeepEqual(evs, want) {
+		t.Fatalf("expected %v, got %v", want, evs)
+	}
+
+	evs, _, err = replay("", 9, 12, clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []event{{mvccpb.PUT, "bar", 9}, {mvccpb.PUT, "foo", 10}, {mvccpb.PUT, "bar", 11}, {mvccpb.DELETE, "foo", 12}}
+	if !reflect.DeepEqual(evs, want) {
+		t.Fatalf("expected %v, got %v", want, evs)
+	}
+
+	// a future end revision is clamped to the current revision
+	evs, rev, err := replay("foo", 10, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []event{{mvccpb.PUT, "foo", 10}, {mvccpb.DELETE, "foo", 12}}
+	if !reflect.DeepEqual(evs, want) || rev != 12 {
+		t.Fatalf("expected %v at revision 12, got %v at revision %d", want, evs, rev)
+	}
+
+	if _, err = cli.Compact(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = replay("foo", 6, 0); err != rpctypes.ErrCompacted {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrCompacted, err)
+	}
+}
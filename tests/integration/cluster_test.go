@@ -16,15 +16,18 @@ package integration
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/etcdserver"
 	"go.etcd.io/etcd/tests/v3/framework/config"
@@ -201,6 +204,46 @@ func TestAddMemberAfterClusterFullRotation(t *testing.T) {
 	clusterMustProgress(t, c.Members)
 }
 
+// TestDeterministicMemberIDs ensures the members of a cluster created with
+// DeterministicIDs get sequential IDs, and that they can still be removed
+// and new members added.
+func TestDeterministicMemberIDs(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, DeterministicIDs: true, DisableStrictReconfigCheck: true})
+	defer c.Terminate(t)
+
+	for i, m := range c.Members {
+		if id := m.Server.MemberId(); id != integration.DeterministicMemberID(i) {
+			t.Fatalf("expected %s to have ID %s, got %s", m.Name, integration.DeterministicMemberID(i), id)
+		}
+	}
+
+	if err := c.RemoveMember(t, c.Members[0].Client, uint64(integration.DeterministicMemberID(2))); err != nil {
+		t.Fatal(err)
+	}
+	c.WaitMembersForLeader(t, c.Members)
+
+	c.AddMember(t)
+	c.WaitMembersForLeader(t, c.Members)
+	added := c.Members[len(c.Members)-1].Server.MemberId()
+	for i := 0; i < 3; i++ {
+		if added == integration.DeterministicMemberID(i) {
+			t.Fatalf("expected the added member to get a new ID, got %s", added)
+		}
+	}
+
+	// the IDs survive a restart
+	c.Members[0].Stop(t)
+	if err := c.Members[0].Restart(t); err != nil {
+		t.Fatal(err)
+	}
+	c.WaitMembersForLeader(t, c.Members)
+	if id := c.Members[0].Server.MemberId(); id != integration.DeterministicMemberID(0) {
+		t.Fatalf("expected %s to have ID %s after restart, got %s", c.Members[0].Name, integration.DeterministicMemberID(0), id)
+	}
+	clusterMustProgress(t, c.Members)
+}
+
 // TestIssue2681 ensures we can remove a member then add a new one back immediately.
 func TestIssue2681(t *testing.T) {
 	integration.BeforeTest(t)
@@ -352,8 +395,7 @@ func TestRejectUnhealthyAdd(t *testing.T) {
 		if err == nil {
 			t.Fatalf("should have failed adding peer")
 		}
-		// TODO: client should return descriptive error codes for internal errors
-		if !strings.Contains(err.Error(), "unhealthy cluster") {
+		if !errors.Is(err, rpctypes.ErrUnhealthy) {
 			t.Errorf("unexpected error (%v)", err)
 		}
 	}
@@ -379,12 +421,12 @@ func TestRejectUnhealthyAdd(t *testing.T) {
 // if quorum will be lost.
 func TestRejectUnhealthyRemove(t *testing.T) {
 	integration.BeforeTest(t)
-	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 5, UseBridge: true})
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 5, UseBridge: true, ManualClock: true})
 	defer c.Terminate(t)
 
 	// make cluster unhealthy and wait for downed peer; (3 up, 2 down)
-	c.Members[0].Stop(t)
-	c.Members[1].Stop(t)
+	c.Members[3].Stop(t)
+	c.Members[4].Stop(t)
 	leader := c.WaitLeader(t)
 
 	// reject remove active member since (3,2)-(1,0) => (2,2) lacks quorum
@@ -392,31 +434,386 @@ func TestRejectUnhealthyRemove(t *testing.T) {
 	if err == nil {
 		t.Fatalf("should reject quorum breaking remove: %s", err)
 	}
-	// TODO: client should return more descriptive error codes for internal errors
-	if !strings.Contains(err.Error(), "unhealthy cluster") {
+	if !errors.Is(err, rpctypes.ErrUnhealthy) {
 		t.Errorf("unexpected error (%v)", err)
 	}
 
-	// member stopped after launch; wait for missing heartbeats
-	time.Sleep(time.Duration(integration.ElectionTicks * int(config.TickDuration)))
+	// member stopped after launch; let the leader miss its heartbeats
+	c.AdvanceTicks(integration.ElectionTicks)
 
 	// permit remove dead member since (3,2) - (0,1) => (3,1) has quorum
-	if err = c.RemoveMember(t, c.Members[2].Client, uint64(c.Members[0].Server.MemberId())); err != nil {
+	if err = c.RemoveMember(t, c.Members[2].Client, uint64(c.Members[3].Server.MemberId())); err != nil {
 		t.Fatalf("should accept removing down member: %s", err)
 	}
 
 	// bring cluster to (4,1)
-	c.Members[0].Restart(t)
+	c.Members[3].Restart(t)
 
 	// restarted member must be connected for a HealthInterval before remove is accepted
 	time.Sleep((3 * etcdserver.HealthInterval) / 2)
 
 	// accept remove member since (4,1)-(1,0) => (3,1) has quorum
-	if err = c.RemoveMember(t, c.Members[1].Client, uint64(c.Members[0].Server.MemberId())); err != nil {
+	if err = c.RemoveMember(t, c.Members[1].Client, uint64(c.Members[3].Server.MemberId())); err != nil {
 		t.Fatalf("expected to remove member, got error %v", err)
 	}
 }
 
+// TestForceRemoveDownMember ensures a member whose host is gone for good can be
+// removed with WithForce while the remaining members form a quorum, and that
+// a forced removal breaking the quorum is still rejected.
+func TestForceRemoveDownMember(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 5, UseBridge: true})
+	defer c.Terminate(t)
+
+	// (3 up, 2 down)
+	c.Members[3].Stop(t)
+	c.Members[4].Stop(t)
+	c.WaitMembersForLeader(t, c.Members[:3])
+	// the remaining members must be connected for a HealthInterval
+	time.Sleep((3 * etcdserver.HealthInterval) / 2)
+
+	// reject removing an active member since (3,2)-(1,0) => (2,2) lacks quorum
+	ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+	_, err := c.Members[0].Client.MemberRemove(ctx, uint64(c.Members[2].Server.MemberId()), clientv3.WithForce())
+	cancel()
+	if !errors.Is(err, rpctypes.ErrUnhealthy) {
+		t.Fatalf("expected forced quorum breaking remove to fail with %v, got %v", rpctypes.ErrUnhealthy, err)
+	}
+
+	// a member coming back is not counted as healthy until it has been
+	// connected for a HealthInterval, so that the health checks reject its
+	// removal while (4,1)-(1,0) => (3,1) has quorum
+	if err = c.Members[4].Restart(t); err != nil {
+		t.Fatal(err)
+	}
+	c.WaitMembersForLeader(t, []*integration.Member{c.Members[0], c.Members[1], c.Members[2], c.Members[4]})
+	// let the members connect to the restarted member
+	time.Sleep(time.Second)
+	id := uint64(c.Members[4].Server.MemberId())
+	ctx, cancel = context.WithTimeout(context.Background(), integration.RequestTimeout)
+	_, err = c.Members[0].Client.MemberRemove(ctx, id)
+	cancel()
+	if !errors.Is(err, rpctypes.ErrUnhealthy) {
+		t.Fatalf("expected remove of a member just back to fail with %v, got %v", rpctypes.ErrUnhealthy, err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), integration.RequestTimeout)
+	_, err = c.Members[0].Client.MemberRemove(ctx, id, clientv3.WithForce())
+	cancel()
+	if err != nil {
+		t.Fatalf("should accept force removing a member just back: %v", err)
+	}
+	c.WaitMemberRemoved(t, id)
+
+	// permit removing a down member since (3,1)-(0,1) => (3,0) has quorum
+	id = uint64(c.Members[3].Server.MemberId())
+	ctx, cancel = context.WithTimeout(context.Background(), integration.RequestTimeout)
+	_, err = c.Members[0].Client.MemberRemove(ctx, id, clientv3.WithForce())
+	cancel()
+	if err != nil {
+		t.Fatalf("should accept force removing down member: %v", err)
+	}
+	c.WaitMemberRemoved(t, id)
+	c.WaitMembersForLeader(t, c.Members[:3])
+	clusterMustProgress(t, c.Members[:3])
+}
+
+// TestReconfigureMembersSwap ensures MemberReconfigure swaps two members of a
+// cluster for two new ones in a single change, while the cluster keeps serving
+// writes, and that all the members then agree on the membership.
+func TestReconfigureMembersSwap(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 5})
+	defer c.Terminate(t)
+
+	lead := c.WaitLeader(t)
+	// members must be connected for a HealthInterval before reconfigure is accepted
+	time.Sleep((3 * etcdserver.HealthInterval) / 2)
+	var removes []uint64
+	for i := len(c.Members) - 1; len(removes) < 2; i-- {
+		if i != lead && i != 0 {
+			removes = append(removes, uint64(c.Members[i].Server.MemberId()))
+		}
+	}
+
+	// write through the reconfiguration, each write must succeed in time
+	stopc, donec := make(chan struct{}), make(chan error, 1)
+	go func() {
+		defer close(donec)
+		for i := 0; ; i++ {
+			select {
+			case <-stopc:
+				return
+			default:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+			_, err := c.Members[0].Client.Put(ctx, fmt.Sprintf("foo%d", i), "bar")
+			cancel()
+			if err != nil {
+				donec <- err
+				return
+			}
+		}
+	}()
+
+	added := c.ReconfigureMembers(t, c.Client(0), 2, removes)
+	close(stopc)
+	if err := <-donec; err != nil {
+		t.Fatalf("write failed during the reconfiguration: %v", err)
+	}
+
+	var want []uint64
+	for _, m := range c.Members {
+		want = append(want, uint64(m.Server.MemberId()))
+	}
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+	for _, m := range added {
+		if m.Server.MemberId() == 0 {
+			t.Fatalf("added member %s did not join", m.Name)
+		}
+	}
+	for i, m := range c.Members {
+		ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+		resp, err := m.Client.MemberList(ctx, clientv3.WithSerializable())
+		cancel()
+		if err != nil {
+			t.Fatalf("#%d: member list error: %v", i, err)
+		}
+		var ids []uint64
+		for _, mm := range resp.Members {
+			ids = append(ids, mm.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if fmt.Sprint(ids) != fmt.Sprint(want) {
+			t.Fatalf("#%d: members = %x, want %x", i, ids, want)
+		}
+	}
+	clusterMustProgress(t, c.Members)
+}
+
+// TestReconfigureMembersRejectQuorumLoss ensures MemberReconfigure rejects a
+// change whose kept voting members do not form a quorum of the current or the
+// new voting members, as the removed members stop once they apply it.
+func TestReconfigureMembersRejectQuorumLoss(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer c.Terminate(t)
+	c.WaitLeader(t)
+	// members must be connected for a HealthInterval before reconfigure is accepted
+	time.Sleep((3 * etcdserver.HealthInterval) / 2)
+
+	adds := [][]string{{"http://127.0.0.1:1"}, {"http://127.0.0.1:2"}}
+	removes := []uint64{uint64(c.Members[1].Server.MemberId()), uint64(c.Members[2].Server.MemberId())}
+
+	// swapping two members of three keeps one member, less than a quorum of three
+	ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+	_, err := c.Members[0].Client.MemberReconfigure(ctx, adds, removes)
+	cancel()
+	if !errors.Is(err, rpctypes.ErrUnhealthy) {
+		t.Fatalf("expected quorum breaking reconfigure to fail with %v, got %v", rpctypes.ErrUnhealthy, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), integration.RequestTimeout)
+	_, err = c.Members[0].Client.MemberReconfigure(ctx, nil, nil)
+	cancel()
+	if !errors.Is(err, rpctypes.ErrMemberNoChange) {
+		t.Fatalf("expected empty reconfigure to fail with %v, got %v", rpctypes.ErrMemberNoChange, err)
+	}
+
+	// removing one member of three keeps a quorum of both configurations
+	c.ReconfigureMembers(t, c.Members[0].Client, 0, removes[:1])
+	clusterMustProgress(t, c.Members)
+}
+
+// TestManualClockElection ensures a new leader is elected purely by advancing
+// raft ticks once the leader of a cluster using a manual clock stops.
+func TestManualClockElection(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, ManualClock: true})
+	defer c.Terminate(t)
+
+	if lead := c.WaitLeader(t); lead != 0 {
+		t.Fatalf("expected the first member to lead, got member %d", lead)
+	}
+	c.Members[0].Stop(t)
+
+	// without ticks nobody notices the leader is gone
+	for _, m := range c.Members[1:] {
+		if lead := m.Server.Leader(); lead != c.Members[0].Server.MemberId() {
+			t.Fatalf("expected %s to still follow %s, got %s", m.Name, c.Members[0].Server.MemberId(), lead)
+		}
+	}
+
+	// the last member campaigns exactly once and is ignored by the second
+	// member, which still holds a lease on the old leader
+	c.Members[2].AdvanceTicks(2*integration.ElectionTicks - 1)
+	expectMemberLog(t, c.Members[1], 5*time.Second, fmt.Sprintf("ignored MsgPreVote from %s", c.Members[2].Server.MemberId()), 1)
+
+	// the second member campaigns exactly once and wins the vote of the
+	// last member, which no longer follows anyone
+	c.Members[1].AdvanceTicks(2*integration.ElectionTicks - 1)
+
+	if lead := c.WaitMembersForLeader(t, c.Members[1:]); lead != 0 {
+		t.Fatalf("expected %s to become leader, got %s", c.Members[1].Name, c.Members[1+lead].Name)
+	}
+}
+
+// TestWaitMembersForLeaderPartialMembership ensures the leader is found when
+// only a subset of the cluster members is running.
+func TestWaitMembersForLeaderPartialMembership(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 5})
+	defer c.Terminate(t)
+
+	c.Members[0].Stop(t)
+	c.Members[1].Stop(t)
+
+	membs := c.Members[2:]
+	l := c.WaitMembersForLeader(t, membs)
+	lead := uint64(membs[l].Server.MemberId())
+	for _, m := range membs {
+		if m.Server.Lead() != lead {
+			t.Errorf("member %s reports leader %x, expected %x", m.Name, m.Server.Lead(), lead)
+		}
+	}
+}
+
+// TestWaitMembersAppliedIndex ensures all the members have applied the writes
+// once they reach the applied index of the member that served them.
+func TestWaitMembersAppliedIndex(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer c.Terminate(t)
+
+	const n = 10
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+		_, err := c.Members[0].Client.Put(ctx, fmt.Sprintf("foo%d", i), "bar")
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := c.Members[0].Server.AppliedIndex()
+	c.WaitMembersAppliedIndex(t, idx)
+
+	for _, m := range c.Members {
+		ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+		resp, err := m.Client.Get(ctx, "foo", clientv3.WithPrefix(), clientv3.WithSerializable(), clientv3.WithCountOnly())
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Count != n {
+			t.Errorf("member %s has %d keys at applied index %d, expected %d", m.Name, resp.Count, m.Server.AppliedIndex(), n)
+		}
+	}
+}
+
+// TestWaitMembersAppliedIndexStoppedMember ensures waiting for the applied
+// index skips the members stopped before the wait.
+func TestWaitMembersAppliedIndexStoppedMember(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer c.Terminate(t)
+
+	lead := c.WaitLeader(t)
+	c.Members[(lead+1)%3].Stop(t)
+	ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+	_, err := c.Members[lead].Client.Put(ctx, "foo", "bar")
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.WaitMembersAppliedIndex(t, c.Members[lead].Server.AppliedIndex())
+}
+
+// TestRejectReconfigNoLeader ensures a cluster without leader rejects
+// membership changes with a typed no leader error, distinct from the
+// error returned when the change would break quorum.
+func TestRejectReconfigNoLeader(t *testing.T) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer c.Terminate(t)
+
+	// (1,2) has no quorum; the remaining member eventually loses its leader
+	c.Members[0].Stop(t)
+	c.Members[1].Stop(t)
+	c.WaitMembersNoLeader(c.Members[2:])
+
+	err := c.AddMemberByURL(t, c.Members[2].Client, "unix://foo:12345")
+	if !errors.Is(err, rpctypes.ErrNoLeader) {
+		t.Errorf("expected %v, got %v", rpctypes.ErrNoLeader, err)
+	}
+	if errors.Is(err, rpctypes.ErrMemberNotEnoughStarted) {
+		t.Errorf("expected no leader error to be distinct from %v", rpctypes.ErrMemberNotEnoughStarted)
+	}
+
+	err = c.RemoveMember(t, c.Members[2].Client, uint64(c.Members[0].Server.MemberId()))
+	if !errors.Is(err, rpctypes.ErrNoLeader) {
+		t.Errorf("expected %v, got %v", rpctypes.ErrNoLeader, err)
+	}
+}
+
+// TestSnapshotCatchUpSlowFollower ensures a follower that falls behind the
+// leader's compacted raft log recovers through a snapshot, when the cluster
+// is configured with small SnapshotCount and SnapshotCatchUpEntries.
+func TestSnapshotCatchUpSlowFollower(t *testing.T) {
+	testSnapshotCatchUpSlowFollower(t, false)
+}
+
+// TestSnapshotCatchUpSlowFollowerDedicatedDirs ensures a follower recovers
+// through a snapshot when its snapshots and backend are in dedicated dirs,
+// into which the received snapshot is moved.
+func TestSnapshotCatchUpSlowFollowerDedicatedDirs(t *testing.T) {
+	testSnapshotCatchUpSlowFollower(t, true)
+}
+
+func testSnapshotCatchUpSlowFollower(t *testing.T, dedicatedDirs bool) {
+	integration.BeforeTest(t)
+	c := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:                   3,
+		SnapshotCount:          10,
+		SnapshotCatchUpEntries: 5,
+		DedicatedWALDir:        dedicatedDirs,
+		DedicatedSnapDir:       dedicatedDirs,
+		DedicatedBackendDir:    dedicatedDirs,
+	})
+	defer c.Terminate(t)
+
+	for _, m := range c.Members {
+		if m.SnapshotCount != 10 || m.SnapshotCatchUpEntries != 5 {
+			t.Fatalf("expected member %s to use configured snapshot settings, got count %d, catch up entries %d",
+				m.Name, m.SnapshotCount, m.SnapshotCatchUpEntries)
+		}
+	}
+
+	lead := c.WaitLeader(t)
+	follower := c.Members[(lead+1)%3]
+	follower.Pause()
+
+	kvc := c.Members[lead].Client
+	for i := 0; i < 30; i++ {
+		if _, err := kvc.Put(context.TODO(), "foo", fmt.Sprintf("bar%d", i)); err != nil {
+			t.Fatalf("#%d: couldn't put key (%v)", i, err)
+		}
+	}
+	expectMemberLog(t, c.Members[lead], 5*time.Second, "compacted Raft logs", 2)
+
+	follower.Resume()
+	expectMemberLog(t, follower, 10*time.Second, "applied snapshot", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+	defer cancel()
+	resp, err := follower.Client.Get(ctx, "foo", clientv3.WithSerializable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "bar29" {
+		t.Fatalf("expected follower to catch up to bar29, got %+v", resp.Kvs)
+	}
+}
+
 // TestRestartRemoved ensures that restarting removed member must exit
 // if 'initial-cluster-state' is set 'new' and old data directory still exists
 // (see https://github.com/etcd-io/etcd/issues/7512 for more).
@@ -499,6 +896,50 @@ func clusterMustProgress(t *testing.T, members []*integration.Member) {
 	}
 }
 
+// TestStopFailsPendingProposals ensures proposals pending on a stopped leader
+// fail with ErrStopped right away, instead of waiting for the request timeout.
+func TestStopFailsPendingProposals(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	leader := clus.Members[lead]
+	// followers cannot acknowledge proposals, so they never commit
+	for i, m := range clus.Members {
+		if i != lead {
+			m.Pause()
+			defer m.Resume()
+		}
+	}
+
+	const proposals = 5
+	errc := make(chan error, proposals)
+	for i := 0; i < proposals; i++ {
+		go func(i int) {
+			_, err := leader.Client.Put(context.Background(), fmt.Sprintf("foo%d", i), "bar")
+			errc <- err
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	leader.Stop(t)
+	for i := 0; i < proposals; i++ {
+		select {
+		case err := <-errc:
+			if !errors.Is(err, rpctypes.ErrStopped) {
+				t.Errorf("expected %v, got %v", rpctypes.ErrStopped, err)
+			}
+		case <-time.After(leader.ServerConfig.ReqTimeout()):
+			t.Fatalf("proposal did not fail before the request timeout")
+		}
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("expected pending proposals to fail promptly, took %v", took)
+	}
+}
+
 func TestSpeedyTerminate(t *testing.T) {
 	integration.BeforeTest(t)
 	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, UseBridge: true})
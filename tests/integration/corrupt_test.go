@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/storage/mvcc/testutil"
 	"go.etcd.io/etcd/tests/v3/framework/integration"
@@ -103,6 +104,72 @@ func TestPeriodicCheckDetectsCorruption(t *testing.T) {
 	assert.Equal(t, []*etcdserverpb.AlarmMember{{Alarm: etcdserverpb.AlarmType_CORRUPT, MemberID: uint64(clus.Members[0].ID())}}, alarmResponse.Alarms)
 }
 
+func TestHashKVByRangeDetectsCorruptedRange(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	cc, err := clus.ClusterClient(t)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	var rev int64
+	for i := 0; i < 50; i++ {
+		resp, err := cc.Put(ctx, testutil.PickKey(int64(i)), fmt.Sprint(i))
+		require.NoError(t, err, "error on put")
+		rev = resp.Header.Revision
+	}
+
+	clus.Members[0].Stop(t)
+	clus.WaitLeader(t)
+	err = testutil.CorruptBBoltRange(clus.Members[0].BackendPath(), []byte("c"), []byte("e"))
+	require.NoError(t, err)
+	err = clus.Members[0].Restart(t)
+	require.NoError(t, err)
+	clus.WaitLeader(t)
+
+	ranges := []struct {
+		key, end string
+		corrupt  bool
+	}{
+		{key: "a", end: "c"},
+		{key: "c", end: "e", corrupt: true},
+		{key: "dominik", corrupt: true},
+		{key: "e", end: "\x00"},
+		{key: "\x00", end: "\x00", corrupt: true},
+	}
+	for _, r := range ranges {
+		var hashes []uint32
+		for _, m := range clus.Members {
+			resp, err := cc.HashKVByRange(ctx, m.GRPCURL(), rev, r.key, r.end)
+			require.NoError(t, err, "error on hash of range [%q, %q)", r.key, r.end)
+			hashes = append(hashes, resp.Hash)
+		}
+		assert.Equal(t, hashes[1], hashes[2], "healthy members disagree on range [%q, %q)", r.key, r.end)
+		if r.corrupt {
+			assert.NotEqual(t, hashes[0], hashes[1], "corruption of range [%q, %q) not detected", r.key, r.end)
+		} else {
+			assert.Equal(t, hashes[0], hashes[1], "range [%q, %q) reported corrupted", r.key, r.end)
+		}
+	}
+
+	var hashes []uint32
+	for _, m := range clus.Members {
+		resp, err := cc.HashKV(ctx, m.GRPCURL(), rev)
+		require.NoError(t, err, "error on hash")
+		hashes = append(hashes, resp.Hash)
+	}
+	assert.Equal(t, hashes[1], hashes[2])
+	assert.NotEqual(t, hashes[0], hashes[1])
+
+	_, err = cc.Compact(ctx, rev)
+	require.NoError(t, err)
+	_, err = cc.HashKVByRange(ctx, clus.Members[1].GRPCURL(), rev-1, "a", "c")
+	assert.ErrorIs(t, err, rpctypes.ErrCompacted)
+}
+
 func TestCompactHashCheck(t *testing.T) {
 	integration.BeforeTest(t)
 
@@ -0,0 +1,220 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !cluster_proxy
+
+package embed_test
+
+import (
+	"net"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"go.etcd.io/etcd/client/pkg/v3/testutil"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// TestEmbedEtcdGRPCKeepAlive ensures the server pings an otherwise idle client
+// connection at --grpc-keepalive-interval, closes it once the pings are not
+// acknowledged within --grpc-keepalive-timeout, and still sends away a client
+// pinging it faster than --grpc-keepalive-min-time.
+func TestEmbedEtcdGRPCKeepAlive(t *testing.T) {
+	testutil.SkipTestIfShortMode(t, "Cannot start embedded cluster in --short tests")
+
+	cfg := embed.NewConfig()
+	urls := newEmbedURLs(false, 2)
+	setupEmbedCfg(cfg, []url.URL{urls[0]}, []url.URL{urls[1]})
+	cfg.Dir = filepath.Join(t.TempDir(), "embed-etcd")
+	// gRPC does not ping more often than every second
+	cfg.GRPCKeepAliveInterval = time.Second
+	cfg.GRPCKeepAliveTimeout = time.Second
+	cfg.GRPCKeepAliveMinTime = time.Minute
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("failed to start embed.Etcd for tests")
+	}
+
+	t.Run("idle connection is kept alive", func(t *testing.T) {
+		c := newHTTP2Conn(t, urls[0].Host)
+		pings := 0
+		deadline := time.After(3500 * time.Millisecond)
+		for {
+			select {
+			case f, ok := <-c.framec:
+				if !ok {
+					t.Fatalf("connection closed after %d pings: %v", pings, c.err)
+				}
+				if f.goAway {
+					t.Fatalf("connection sent away after %d pings: %v", pings, f.errCode)
+				}
+				if f.ping && !f.pingAck {
+					pings++
+					c.mustWrite(t, func() error { return c.fr.WritePing(true, f.data) })
+				}
+				continue
+			case <-deadline:
+			}
+			break
+		}
+		if pings < 2 {
+			t.Fatalf("expected at least 2 pings from the server, got %d", pings)
+		}
+	})
+
+	t.Run("unresponsive connection is closed", func(t *testing.T) {
+		c := newHTTP2Conn(t, urls[0].Host)
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case f, ok := <-c.framec:
+				if !ok {
+					return
+				}
+				if f.goAway {
+					return
+				}
+			case <-deadline:
+				t.Fatal("expected the connection to be closed")
+			}
+		}
+	})
+
+	t.Run("aggressive pings are rejected", func(t *testing.T) {
+		c := newHTTP2Conn(t, urls[0].Host)
+		for i := 0; i < 10; i++ {
+			c.mu.Lock()
+			err := c.fr.WritePing(false, [8]byte{byte(i)})
+			c.mu.Unlock()
+			// the server closes the connection once it has sent it away
+			if err != nil {
+				break
+			}
+		}
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case f, ok := <-c.framec:
+				if !ok {
+					t.Fatalf("connection closed without going away: %v", c.err)
+				}
+				if f.goAway {
+					if f.errCode != http2.ErrCodeEnhanceYourCalm || f.debug != "too_many_pings" {
+						t.Fatalf("expected too_many_pings, got %v %q", f.errCode, f.debug)
+					}
+					return
+				}
+			case <-deadline:
+				t.Fatal("expected the connection to be sent away")
+			}
+		}
+	})
+}
+
+// http2Frame is a ping or go away frame received on an http2Conn.
+type http2Frame struct {
+	ping    bool
+	pingAck bool
+	data    [8]byte
+
+	goAway  bool
+	errCode http2.ErrCode
+	debug   string
+}
+
+// http2Conn is a raw HTTP/2 client connection, whose ping and go away frames
+// are received on framec until it is closed. Its frames are dropped once the
+// test is done.
+type http2Conn struct {
+	mu     sync.Mutex
+	fr     *http2.Framer
+	framec chan http2Frame
+	donec  chan struct{}
+	err    error
+}
+
+func newHTTP2Conn(t *testing.T, addr string) *http2Conn {
+	conn, err := net.Dial("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http2Conn{fr: http2.NewFramer(conn, conn), framec: make(chan http2Frame), donec: make(chan struct{})}
+	t.Cleanup(func() {
+		close(c.donec)
+		conn.Close()
+	})
+	if _, err = conn.Write([]byte(http2.ClientPreface)); err != nil {
+		t.Fatal(err)
+	}
+	c.mustWrite(t, func() error { return c.fr.WriteSettings() })
+	go func() {
+		defer close(c.framec)
+		for {
+			f, err := c.fr.ReadFrame()
+			if err != nil {
+				c.err = err
+				return
+			}
+			switch f := f.(type) {
+			case *http2.SettingsFrame:
+				if f.IsAck() {
+					continue
+				}
+				// the server may have closed the connection already, which
+				// the next read reports
+				c.mu.Lock()
+				c.fr.WriteSettingsAck()
+				c.mu.Unlock()
+			case *http2.PingFrame:
+				if !c.send(http2Frame{ping: true, pingAck: f.IsAck(), data: f.Data}) {
+					return
+				}
+			case *http2.GoAwayFrame:
+				if !c.send(http2Frame{goAway: true, errCode: f.ErrCode, debug: string(f.DebugData())}) {
+					return
+				}
+			}
+		}
+	}()
+	return c
+}
+
+// send sends f on framec, unless the test is done.
+func (c *http2Conn) send(f http2Frame) bool {
+	select {
+	case c.framec <- f:
+		return true
+	case <-c.donec:
+		return false
+	}
+}
+
+func (c *http2Conn) mustWrite(t *testing.T, write func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := write(); err != nil {
+		t.Fatal(err)
+	}
+}
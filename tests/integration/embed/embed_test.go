@@ -22,6 +22,9 @@ package embed_test
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -30,6 +33,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.etcd.io/etcd/client/pkg/v3/testutil"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -227,3 +231,69 @@ func TestEmbedEtcdAutoCompactionRetentionRetained(t *testing.T) {
 	assert.Equal(t, duration_to_compare, autoCompactionRetention)
 	e.Close()
 }
+
+func TestEmbedEtcdPprofAuth(t *testing.T) {
+	testutil.SkipTestIfShortMode(t, "Cannot start embedded cluster in --short tests")
+
+	cfg := embed.NewConfig()
+	urls := newEmbedURLs(false, 2)
+	setupEmbedCfg(cfg, []url.URL{urls[0]}, []url.URL{urls[1]})
+	cfg.Dir = filepath.Join(t.TempDir(), "embed-etcd")
+	cfg.EnablePprof = true
+	cfg.ExperimentalEnablePprofAuth = true
+
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+	<-e.Server.ReadyNotify()
+
+	hc := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", urls[0].Host)
+		},
+	}}
+	getProfile := func(token string) int {
+		req, rerr := http.NewRequest(http.MethodGet, "http://localhost/debug/pprof/goroutine?debug=1", nil)
+		require.NoError(t, rerr)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp, rerr := hc.Do(req)
+		require.NoError(t, rerr)
+		defer resp.Body.Close()
+		body, rerr := io.ReadAll(resp.Body)
+		require.NoError(t, rerr)
+		if resp.StatusCode == http.StatusOK {
+			assert.Contains(t, string(body), "goroutine profile")
+		}
+		return resp.StatusCode
+	}
+
+	// profiles are served to everyone while auth is disabled
+	assert.Equal(t, http.StatusOK, getProfile(""))
+
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: []string{urls[0].String()}})
+	require.NoError(t, err)
+	defer cli.Close()
+	ctx := context.TODO()
+	for _, user := range []string{"root", "alice"} {
+		_, err = cli.UserAdd(ctx, user, "123")
+		require.NoError(t, err)
+	}
+	_, err = cli.RoleAdd(ctx, "root")
+	require.NoError(t, err)
+	_, err = cli.UserGrantRole(ctx, "root", "root")
+	require.NoError(t, err)
+	_, err = cli.AuthEnable(ctx)
+	require.NoError(t, err)
+
+	token := func(user string) string {
+		resp, aerr := cli.Authenticate(ctx, user, "123")
+		require.NoError(t, aerr)
+		return resp.Token
+	}
+	assert.Equal(t, http.StatusOK, getProfile(token("root")))
+	assert.Equal(t, http.StatusForbidden, getProfile(token("alice")))
+	assert.Equal(t, http.StatusForbidden, getProfile(""))
+	assert.Equal(t, http.StatusUnauthorized, getProfile("invalid"))
+}
@@ -17,10 +17,14 @@ package integration
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.etcd.io/etcd/server/v3/storage/datadir"
 	"go.etcd.io/etcd/tests/v3/framework/integration"
 )
 
@@ -115,3 +119,131 @@ func TestSnapshotAndRestartMember(t *testing.T) {
 		}
 	}
 }
+
+func TestRestartMemberDedicatedDirs(t *testing.T) {
+	integration.BeforeTest(t)
+	m := integration.MustNewMember(t, integration.MemberConfig{
+		Name:                "dedicatedDirsTest",
+		UseBridge:           true,
+		DedicatedWALDir:     true,
+		DedicatedSnapDir:    true,
+		DedicatedBackendDir: true,
+	})
+	m.SnapshotCount = 100
+	m.Launch()
+	defer m.Terminate(t)
+	defer m.Client.Close()
+	m.WaitOK(t)
+
+	mustPutKeys(t, m, 120)
+	walDir := m.ServerConfig.DedicatedWALDir
+	assert.NotEmpty(t, mustGlob(t, filepath.Join(walDir, "*.wal")))
+	assert.NotEmpty(t, mustGlob(t, filepath.Join(m.ServerConfig.DedicatedSnapDir, "*.snap")))
+	assert.FileExists(t, filepath.Join(m.ServerConfig.DedicatedBackendDir, "db"))
+	assert.NoDirExists(t, datadir.ToWalDir(m.DataDir))
+	assert.NoDirExists(t, datadir.ToSnapDir(m.DataDir))
+
+	m.Stop(t)
+	if err := m.Restart(t); err != nil {
+		t.Fatal(err)
+	}
+	m.WaitOK(t)
+	mustGetKeys(t, m, 120)
+}
+
+func TestRestartMemberMovedWALDir(t *testing.T) {
+	integration.BeforeTest(t)
+	m := integration.MustNewMember(t, integration.MemberConfig{Name: "movedWALDirTest", UseBridge: true})
+	m.Launch()
+	defer m.Terminate(t)
+	defer m.Client.Close()
+	m.WaitOK(t)
+
+	mustPutKeys(t, m, 10)
+	m.Stop(t)
+
+	// the WAL is left in the data dir
+	walDir := filepath.Join(t.TempDir(), "wal")
+	m.ServerConfig.DedicatedWALDir = walDir
+	_, err := etcdserver.NewServer(m.ServerConfig)
+	assert.ErrorContains(t, err, "found WAL")
+
+	if err = os.Rename(datadir.ToWalDir(m.DataDir), walDir); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Restart(t); err != nil {
+		t.Fatal(err)
+	}
+	m.WaitOK(t)
+	mustGetKeys(t, m, 10)
+}
+
+func TestRestartMemberMovedSnapAndBackendDirs(t *testing.T) {
+	integration.BeforeTest(t)
+	m := integration.MustNewMember(t, integration.MemberConfig{Name: "movedSnapDirTest", UseBridge: true})
+	m.SnapshotCount = 5
+	m.Launch()
+	defer m.Terminate(t)
+	defer m.Client.Close()
+	m.WaitOK(t)
+
+	mustPutKeys(t, m, 10)
+	m.Stop(t)
+
+	// the snapshots and the backend are left in the data dir
+	snapDir, backendDir := filepath.Join(t.TempDir(), "snap"), filepath.Join(t.TempDir(), "backend")
+	m.ServerConfig.DedicatedSnapDir = snapDir
+	_, err := etcdserver.NewServer(m.ServerConfig)
+	assert.ErrorContains(t, err, "found snapshots")
+	m.ServerConfig.DedicatedBackendDir = backendDir
+	if err = os.Rename(datadir.ToSnapDir(m.DataDir), snapDir); err != nil {
+		t.Fatal(err)
+	}
+	_, err = etcdserver.NewServer(m.ServerConfig)
+	assert.ErrorContains(t, err, "found backend")
+
+	if err = os.Mkdir(backendDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(filepath.Join(snapDir, "db"), filepath.Join(backendDir, "db")); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Restart(t); err != nil {
+		t.Fatal(err)
+	}
+	m.WaitOK(t)
+	mustGetKeys(t, m, 10)
+}
+
+func mustPutKeys(t *testing.T, m *integration.Member, n int) {
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+		_, err := m.Client.Put(ctx, fmt.Sprintf("/foo%d", i), "bar")
+		cancel()
+		if err != nil {
+			t.Fatalf("#%d: put on %s error: %v", i, m.URL(), err)
+		}
+	}
+}
+
+func mustGetKeys(t *testing.T, m *integration.Member, n int) {
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), integration.RequestTimeout)
+		resp, err := m.Client.Get(ctx, fmt.Sprintf("/foo%d", i))
+		cancel()
+		if err != nil {
+			t.Fatalf("#%d: get on %s error: %v", i, m.URL(), err)
+		}
+		if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "bar" {
+			t.Errorf("#%d: got = %v, want %v", i, resp.Kvs, "bar")
+		}
+	}
+}
+
+func mustGlob(t *testing.T, pattern string) []string {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
@@ -24,6 +24,7 @@ import (
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/storage"
 	"go.etcd.io/etcd/tests/v3/framework/integration"
 )
@@ -210,3 +211,218 @@ func TestMetricsHealth(t *testing.T) {
 		t.Fatalf("expected '0' from etcd_server_health_failures, got %q", hv)
 	}
 }
+
+// TestMetricApplyEntryDuration checks that the apply latency of entries is
+// observed by request type.
+func TestMetricApplyEntryDuration(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	types := []string{"put", "delete", "txn", "lease", "auth", "config"}
+	counts := func() map[string]int {
+		m := make(map[string]int)
+		for _, typ := range types {
+			v, err := clus.Members[0].Metric("etcd_server_apply_entry_duration_seconds_count", fmt.Sprintf(`type="%s"`, typ))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v == "" {
+				continue
+			}
+			if m[typ], err = strconv.Atoi(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return m
+	}
+	before := counts()
+
+	cli := clus.Client(0)
+	if _, err := cli.Put(context.TODO(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Delete(context.TODO(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Txn(context.TODO()).Then(clientv3.OpPut("foo", "baz")).Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Grant(context.TODO(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.RoleAdd(context.TODO(), "role"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.MemberAddAsLearner(context.TODO(), []string{"http://127.0.0.1:1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	after := counts()
+	for _, typ := range types {
+		if after[typ] <= before[typ] {
+			t.Errorf("expected applied %s entries to be observed, got count %d before and %d after", typ, before[typ], after[typ])
+		}
+	}
+}
+
+// TestMetricReadIndexWait checks that the read index wait of linearizable
+// reads is observed, and that serializable reads do not wait for a read index.
+func TestMetricReadIndexWait(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	count := func() int {
+		v, err := clus.Members[0].Metric("etcd_server_read_index_wait_duration_seconds_count")
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	cli := clus.Client(0)
+	before := count()
+	for i := 0; i < 5; i++ {
+		if _, err := cli.Get(context.TODO(), "foo", clientv3.WithSerializable()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := count(); after != before {
+		t.Fatalf("expected serializable reads not to be observed, got count %d before and %d after", before, after)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := cli.Get(context.TODO(), "foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := count(); after < before+5 {
+		t.Fatalf("expected linearizable reads to be observed, got count %d before and %d after", before, after)
+	}
+}
+
+// TestMetricBackendBatchInterval checks that a shorter backend batch interval
+// commits the backend more often under a workload of small writes, so that a
+// write waits less for the commit that persists it to the backend.
+func TestMetricBackendBatchInterval(t *testing.T) {
+	integration.BeforeTest(t)
+
+	// commitLatency returns the number of backend commits while writing for a
+	// second, and the mean time a write waits until it is committed: half the
+	// time between two commits, plus the duration of a commit.
+	commitLatency := func(interval time.Duration) (int, time.Duration) {
+		clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1, BackendBatchInterval: interval})
+		defer clus.Terminate(t)
+
+		metric := func(name string) float64 {
+			v, err := clus.Members[0].Metric(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return f
+		}
+		countBefore := metric("etcd_disk_backend_commit_duration_seconds_count")
+		sumBefore := metric("etcd_disk_backend_commit_duration_seconds_sum")
+		cli := clus.Client(0)
+		start := time.Now()
+		for time.Since(start) < time.Second {
+			if _, err := cli.Put(context.TODO(), "foo", "bar"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		took := time.Since(start)
+		count := metric("etcd_disk_backend_commit_duration_seconds_count") - countBefore
+		sum := metric("etcd_disk_backend_commit_duration_seconds_sum") - sumBefore
+		if count == 0 {
+			t.Fatalf("expected backend commits with a %v batch interval", interval)
+		}
+		wait := time.Duration(float64(took) / count / 2)
+		return int(count), wait + time.Duration(sum/count*float64(time.Second))
+	}
+
+	minInterval := storage.MinimumBackendBatchInterval
+	defaultCommits, defaultLatency := commitLatency(0)
+	shortCommits, shortLatency := commitLatency(minInterval)
+	t.Logf("commit latency: %v in %d commits with the default interval, %v in %d commits with a %v interval",
+		defaultLatency, defaultCommits, shortLatency, shortCommits, minInterval)
+	if 3*shortLatency > defaultLatency {
+		t.Fatalf("expected a %v batch interval to commit writes much sooner than the default, got %v and %v", minInterval, shortLatency, defaultLatency)
+	}
+	// shorter intervals are raised to the minimum, so that the member does
+	// not sync the backend continuously
+	if clampedCommits, _ := commitLatency(time.Microsecond); clampedCommits > 2*shortCommits {
+		t.Fatalf("expected a 1µs batch interval to be raised to %v, got %d commits and %d at %v", minInterval, clampedCommits, shortCommits, minInterval)
+	}
+}
+
+// TestMetricRaftInstability checks that leader changes, rejected append
+// entries and dropped raft messages are counted when the network between
+// members breaks.
+func TestMetricRaftInstability(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, UsePeerBridge: true})
+	defer clus.Terminate(t)
+
+	metric := func(name string, labels ...string) float64 {
+		v, err := clus.Members[0].Metric(name, labels...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v == "" {
+			return 0
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	// the followers elect a new leader once they stop hearing from the leader
+	leaderChanges := metric("etcd_server_leader_changes_seen_total")
+	leadIndex := clus.WaitLeader(t)
+	oldLeader := clus.Members[leadIndex]
+	followers := getMembersByIndexSlice(clus, []int{(leadIndex + 1) % 3, (leadIndex + 2) % 3})
+	oldLeader.PeerBridge().Blackhole()
+	clus.WaitMembersForLeader(t, followers)
+	oldLeader.PeerBridge().Unblackhole()
+	clusterMustProgress(t, clus.Members)
+	if v := metric("etcd_server_leader_changes_seen_total"); v <= leaderChanges {
+		t.Errorf("expected leader changes to be counted, got %v before and %v after", leaderChanges, v)
+	}
+
+	// the leader keeps appending entries to a follower whose messages are
+	// dropped, which the follower rejects once it is reachable again
+	rejected := metric("etcd_server_append_entries_rejected_total", `Reason="log_mismatch"`)
+	dropped := metric("etcd_network_raft_messages_dropped_total", `Reason="peer_paused"`)
+	leadIndex = clus.WaitLeader(t)
+	follower := clus.Members[(leadIndex+1)%3]
+	others := getMembersByIndexSlice(clus, []int{leadIndex, (leadIndex + 2) % 3})
+	injectPartition(t, []*integration.Member{follower}, others)
+	cli := clus.Client(leadIndex)
+	for i := 0; i < 10; i++ {
+		if _, err := cli.Put(context.TODO(), fmt.Sprintf("foo%d", i), "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recoverPartition(t, []*integration.Member{follower}, others)
+	clusterMustProgress(t, clus.Members)
+
+	deadline := time.Now().Add(10 * follower.ElectionTimeout())
+	for metric("etcd_server_append_entries_rejected_total", `Reason="log_mismatch"`) <= rejected {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the follower to reject the entries appended while it was unreachable")
+		}
+		time.Sleep(follower.ElectionTimeout())
+	}
+	if v := metric("etcd_network_raft_messages_dropped_total", `Reason="peer_paused"`); v <= dropped {
+		t.Errorf("expected dropped messages to be counted, got %v before and %v after", dropped, v)
+	}
+}
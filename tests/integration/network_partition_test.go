@@ -139,6 +139,39 @@ func TestNetworkPartition4Members(t *testing.T) {
 	clusterMustProgress(t, clus.Members)
 }
 
+// TestNetworkPartitionAsymmetricLeaderStepsDown ensures a leader that still receives
+// messages from its followers, but cannot reach them, steps down and follows the
+// leader elected by the followers.
+func TestNetworkPartitionAsymmetricLeaderStepsDown(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, UsePeerBridge: true})
+	defer clus.Terminate(t)
+
+	leadIndex := clus.WaitLeader(t)
+	oldLeader := clus.Members[leadIndex]
+	followers := getMembersByIndexSlice(clus, []int{(leadIndex + 1) % 3, (leadIndex + 2) % 3})
+
+	// old leader can receive from followers, but cannot reply
+	oldLeader.PeerBridge().DropOutbound()
+
+	newLead := followers[clus.WaitMembersForLeader(t, followers)].Server.MemberId()
+
+	// old leader learns the new term from the new leader
+	timeout := time.After(10 * oldLeader.ElectionTimeout())
+	for oldLeader.Server.Leader() != newLead {
+		select {
+		case <-timeout:
+			t.Fatalf("old leader %s did not step down, leader %s, expected %s",
+				oldLeader.Server.MemberId(), oldLeader.Server.Leader(), newLead)
+		case <-time.After(oldLeader.ElectionTimeout()):
+		}
+	}
+
+	oldLeader.PeerBridge().Restore()
+	clusterMustProgress(t, clus.Members)
+}
+
 func getMembersByIndexSlice(clus *integration.Cluster, idxs []int) []*integration.Member {
 	ms := make([]*integration.Member, len(idxs))
 	for i, idx := range idxs {
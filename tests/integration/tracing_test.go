@@ -24,6 +24,7 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
 	traceservice "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 	"google.golang.org/grpc"
@@ -115,6 +116,75 @@ func TestTracing(t *testing.T) {
 	}
 }
 
+// TestTracingPutSpans ensures that a traced Put is recorded as a span tree
+// covering its proposal, its apply and its backend transaction, under the
+// span of the client that sent it.
+func TestTracingPutSpans(t *testing.T) {
+	if integration.ThroughProxy {
+		t.Skipf("grpc-proxy does not propagate the trace context")
+	}
+	integration.BeforeTest(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.TODO())
+	tracingOpts := []otelgrpc.Option{
+		otelgrpc.WithTracerProvider(tp),
+		otelgrpc.WithPropagators(propagation.TraceContext{}),
+	}
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1, TracerOptions: tracingOpts})
+	defer clus.Terminate(t)
+
+	cli, err := integration.NewClient(t, clientv3.Config{
+		Endpoints:   []string{clus.Members[0].GRPCURL()},
+		DialOptions: []grpc.DialOption{grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor(tracingOpts...))},
+	})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, err = cli.Put(context.TODO(), "foo", "bar")
+	require.NoError(t, err)
+
+	// the server span ends after the response is sent. The spans of the other
+	// requests served by the cluster are in other traces.
+	var spanByName map[string]tracetest.SpanStub
+	require.Eventually(t, func() bool {
+		spans := exporter.GetSpans()
+		var traceID trace.TraceID
+		for _, span := range spans {
+			if span.Name == "etcdserverpb.KV/Put" {
+				traceID = span.SpanContext.TraceID()
+			}
+		}
+		spanByName = make(map[string]tracetest.SpanStub)
+		for _, span := range spans {
+			if !traceID.IsValid() || span.SpanContext.TraceID() != traceID {
+				continue
+			}
+			name := span.Name
+			if span.SpanKind == trace.SpanKindClient {
+				name = "client"
+			}
+			spanByName[name] = span
+		}
+		return len(spanByName) == 5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, tt := range []struct{ child, parent string }{
+		{"etcdserverpb.KV/Put", "client"},
+		{"propose", "etcdserverpb.KV/Put"},
+		{"apply", "propose"},
+		{"backend", "apply"},
+	} {
+		child, parent := spanByName[tt.child], spanByName[tt.parent]
+		require.Equal(t, parent.SpanContext.SpanID(), child.Parent.SpanID(), "parent of %q", tt.child)
+		require.Equal(t, parent.SpanContext.TraceID(), child.SpanContext.TraceID())
+		require.False(t, child.StartTime.Before(parent.StartTime))
+		require.False(t, child.EndTime.After(parent.EndTime))
+	}
+}
+
 func containsNodeListSpan(req *traceservice.ExportTraceServiceRequest) bool {
 	for _, resourceSpans := range req.GetResourceSpans() {
 		for _, attr := range resourceSpans.GetResource().GetAttributes() {
@@ -0,0 +1,117 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestV3AuthExportImport exports the auth store of a cluster, imports it into
+// a fresh cluster and checks that both clusters enforce the same permissions.
+func TestV3AuthExportImport(t *testing.T) {
+	integration.BeforeTest(t)
+	src := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer src.Terminate(t)
+	// the clusters would share the unix sockets of their members
+	dst := integration.NewCluster(t, &integration.ClusterConfig{Size: 1, UseTCP: true})
+	defer dst.Terminate(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	users := []user{
+		{name: "alice", password: "alice-pw", role: "role-a", key: "a", end: "b"},
+		{name: "bob", password: "bob-pw", role: "role-b", key: "b", end: "c"},
+	}
+	authSetupUsers(t, integration.ToGRPC(src.Client(0)).Auth, users)
+	authSetupRoot(t, integration.ToGRPC(src.Client(0)).Auth)
+
+	// only root can export the auth store
+	_, err := src.Client(0).AuthExport(ctx)
+	require.Error(t, err)
+	srcRoot := newAuthClient(t, src, "root", "123")
+	exported, err := srcRoot.AuthExport(ctx)
+	require.NoError(t, err)
+
+	// the export is carried to the other cluster in its marshaled form
+	data, err := (*pb.AuthExportResponse)(exported).Marshal()
+	require.NoError(t, err)
+	var carried pb.AuthExportResponse
+	require.NoError(t, carried.Unmarshal(data))
+
+	_, err = dst.Client(0).AuthImport(ctx, (*clientv3.AuthExportResponse)(&carried), false)
+	require.NoError(t, err)
+	_, err = dst.Client(0).AuthEnable(ctx)
+	require.NoError(t, err)
+
+	for _, clus := range []*integration.Cluster{src, dst} {
+		alice := newAuthClient(t, clus, "alice", "alice-pw")
+		bob := newAuthClient(t, clus, "bob", "bob-pw")
+
+		_, err = alice.Put(ctx, "a1", "v")
+		assert.NoError(t, err)
+		_, err = alice.Put(ctx, "b1", "v")
+		assert.ErrorIs(t, err, rpctypes.ErrPermissionDenied)
+		_, err = bob.Put(ctx, "b1", "v")
+		assert.NoError(t, err)
+		_, err = bob.Get(ctx, "a1")
+		assert.ErrorIs(t, err, rpctypes.ErrPermissionDenied)
+
+		_, err = integration.NewClient(t, clientv3.Config{
+			Endpoints:   clus.Client(0).Endpoints(),
+			DialTimeout: 5 * time.Second,
+			Username:    "alice",
+			Password:    "bob-pw",
+		})
+		assert.ErrorIs(t, err, rpctypes.ErrAuthFailed)
+	}
+
+	// a user that was changed since cannot be merged, but can be replaced
+	dstRoot := newAuthClient(t, dst, "root", "123")
+	_, err = dstRoot.UserChangePassword(ctx, "alice", "changed")
+	require.NoError(t, err)
+	_, err = dstRoot.AuthImport(ctx, exported, false)
+	require.ErrorIs(t, err, rpctypes.ErrUserAlreadyExist)
+	_, err = dstRoot.AuthImport(ctx, exported, true)
+	require.NoError(t, err)
+	newAuthClient(t, dst, "alice", "alice-pw")
+
+	// the root user cannot lose the root role while auth is enabled
+	noRoot := &clientv3.AuthExportResponse{Users: exported.Users[:1], Roles: exported.Roles}
+	_, err = newAuthClient(t, dst, "root", "123").AuthImport(ctx, noRoot, true)
+	require.ErrorIs(t, err, rpctypes.ErrRootUserNotExist)
+}
+
+func newAuthClient(t *testing.T, clus *integration.Cluster, name, password string) *clientv3.Client {
+	cli, err := integration.NewClient(t, clientv3.Config{
+		Endpoints:   clus.Client(0).Endpoints(),
+		DialTimeout: 5 * time.Second,
+		Username:    name,
+		Password:    password,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { cli.Close() })
+	return cli
+}
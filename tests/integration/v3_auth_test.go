@@ -16,6 +16,7 @@ package integration
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -248,6 +249,46 @@ func TestV3AuthWithLeaseRevoke(t *testing.T) {
 	}
 }
 
+// TestV3AuthWithLeaseListDetailed ensures that only root can list the keys
+// attached to the leases, while any user can list the leases.
+func TestV3AuthWithLeaseListDetailed(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	users := []user{{name: "user1", password: "user1-123", role: "role1", key: "k1", end: "k2"}}
+	authSetupUsers(t, integration.ToGRPC(clus.Client(0)).Auth, users)
+	authSetupRoot(t, integration.ToGRPC(clus.Client(0)).Auth)
+
+	rootc := newAuthClient(t, clus, "root", "123")
+	leaseResp, err := rootc.Grant(context.TODO(), 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rootc.Put(context.TODO(), "k3", "val", clientv3.WithLease(leaseResp.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	userc := newAuthClient(t, clus, "user1", "user1-123")
+	resp, err := userc.LeaseListDetailed(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Leases) != 1 || resp.Leases[0].KeyCount != 1 {
+		t.Fatalf("expected a lease with 1 key, got %+v", resp.Leases)
+	}
+	if _, err = userc.LeaseListDetailed(context.TODO(), clientv3.WithAttachedKeys()); !errors.Is(err, rpctypes.ErrPermissionDenied) {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrPermissionDenied, err)
+	}
+	resp, err = rootc.LeaseListDetailed(context.TODO(), clientv3.WithAttachedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Leases) != 1 || len(resp.Leases[0].Keys) != 1 || string(resp.Leases[0].Keys[0]) != "k3" {
+		t.Fatalf("expected a lease with key k3, got %+v", resp.Leases)
+	}
+}
+
 func TestV3AuthWithLeaseAttach(t *testing.T) {
 	integration.BeforeTest(t)
 	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
@@ -506,3 +547,122 @@ func TestV3AuthWatchErrorAndWatchId0(t *testing.T) {
 
 	<-watchEndCh
 }
+
+func TestV3AuthWriteRateLimit(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1, WriteRateLimitByUser: map[string]float64{"user1": 10}})
+	defer clus.Terminate(t)
+
+	users := []user{
+		{
+			name:     "user1",
+			password: "user1-123",
+			role:     "role1",
+			key:      "k1",
+			end:      "k2",
+		},
+		{
+			name:     "user2",
+			password: "user2-123",
+			role:     "role2",
+			key:      "k2",
+			end:      "k3",
+		},
+	}
+	authSetupUsers(t, integration.ToGRPC(clus.Client(0)).Auth, users)
+	authSetupRoot(t, integration.ToGRPC(clus.Client(0)).Auth)
+
+	clients := make([]*clientv3.Client, len(users))
+	for i, u := range users {
+		c, cerr := integration.NewClient(t, clientv3.Config{Endpoints: clus.Client(0).Endpoints(), Username: u.name, Password: u.password})
+		if cerr != nil {
+			t.Fatal(cerr)
+		}
+		defer c.Close()
+		clients[i] = c
+	}
+
+	const puts = 100
+	var (
+		wg      sync.WaitGroup
+		ok      [2]int
+		limited [2]int
+		errs    = make(chan error, 2)
+	)
+	start := time.Now()
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < puts; j++ {
+				_, err := clients[i].Put(context.TODO(), fmt.Sprintf("%s-%d", users[i].key, j), "val")
+				switch {
+				case err == nil:
+					ok[i]++
+				case errors.Is(err, rpctypes.ErrUserRateLimited):
+					limited[i]++
+				default:
+					errs <- err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	// user1 gets a burst of 10 writes and 10 writes per second after that
+	if limited[0] == 0 {
+		t.Errorf("expected user1 to be throttled, %d writes succeeded", ok[0])
+	}
+	if max := 10 + int(10*elapsed.Seconds()) + 1; ok[0] > max {
+		t.Errorf("expected at most %d writes of user1 to succeed in %v, got %d", max, elapsed, ok[0])
+	}
+	if ok[1] != puts || limited[1] != 0 {
+		t.Errorf("expected all %d writes of user2 to succeed, got %d succeeded and %d throttled", puts, ok[1], limited[1])
+	}
+
+	// reads are not limited
+	for j := 0; j < 20; j++ {
+		if _, err := clients[0].Get(context.TODO(), "k1", clientv3.WithPrefix()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestV3RateLimitUnauthenticatedReads(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:                 1,
+		WriteRateLimitByUser: map[string]float64{"": 2},
+		RateLimitReads:       true,
+	})
+	defer clus.Terminate(t)
+
+	// requests without auth share the bucket of the empty user
+	cli := clus.Client(0)
+	var err error
+	for i := 0; i < 2; i++ {
+		if _, err = cli.Put(context.TODO(), "foo", "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = cli.Get(context.TODO(), "foo"); !errors.Is(err, rpctypes.ErrUserRateLimited) {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrUserRateLimited)
+	}
+	if _, err = cli.Put(context.TODO(), "foo", "bar"); !errors.Is(err, rpctypes.ErrUserRateLimited) {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrUserRateLimited)
+	}
+	if _, err = cli.Grant(context.TODO(), 10); !errors.Is(err, rpctypes.ErrUserRateLimited) {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrUserRateLimited)
+	}
+
+	time.Sleep(time.Second)
+	if _, err = cli.Get(context.TODO(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestV3ScheduledDefrag ensures scheduled defragmentation shrinks the
+// backend of the followers, but not of the leader, while they keep serving
+// requests.
+func TestV3ScheduledDefrag(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:                  3,
+		DefragInterval:        200 * time.Millisecond,
+		DefragRateBytesPerSec: 4 * 1024 * 1024,
+	})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	follower := (lead + 1) % len(clus.Members)
+	cli := clus.Client(lead)
+
+	// fill the backend and free most of it
+	val := string(make([]byte, 4096))
+	for i := 0; i < 2000; i += 100 {
+		ops := make([]clientv3.Op, 100)
+		for j := range ops {
+			ops[j] = clientv3.OpPut(fmt.Sprintf("key/%04d", i+j), val)
+		}
+		if _, err := cli.Txn(context.TODO()).Then(ops...).Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := cli.Delete(context.TODO(), "key/", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Compact(context.TODO(), resp.Header.Revision, clientv3.WithCompactPhysical()); err != nil {
+		t.Fatal(err)
+	}
+	leadSize := clus.Members[lead].Server.Backend().Size()
+	if leadSize < 2000*4096 {
+		t.Fatalf("expected the leader's backend to hold the values, got size %d", leadSize)
+	}
+
+	fcli := clus.Client(follower)
+	deadline := time.Now().Add(30 * time.Second)
+	for i := 0; clus.Members[follower].Server.Backend().Size() > leadSize/4; i++ {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the follower's backend to shrink from %d, got size %d", leadSize, clus.Members[follower].Server.Backend().Size())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err = fcli.Put(ctx, "foo", fmt.Sprintf("bar%d", i))
+		if err == nil {
+			_, err = fcli.Get(ctx, "foo")
+		}
+		cancel()
+		if err != nil {
+			t.Fatalf("expected the follower to stay responsive, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if size := clus.Members[lead].Server.Backend().Size(); size < leadSize {
+		t.Errorf("expected the leader's backend not to be defragmented, got size %d, want >= %d", size, leadSize)
+	}
+	gresp, err := fcli.Get(context.TODO(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 1 {
+		t.Fatalf("expected the key written during defragmentation, got %v", gresp.Kvs)
+	}
+}
@@ -31,7 +31,9 @@ import (
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/tests/v3/framework/config"
 	"go.etcd.io/etcd/tests/v3/framework/integration"
+	"go.etcd.io/raft/v3"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -214,7 +216,7 @@ func TestV3TxnTooManyOps(t *testing.T) {
 	i := new(int)
 	keyf := func() []byte {
 		*i++
-		return []byte(fmt.Sprintf("key-%d", i))
+		return []byte(fmt.Sprintf("key-%d", *i))
 	}
 
 	addCompareOps := func(txn *pb.TxnRequest) {
@@ -278,6 +280,95 @@ func TestV3TxnTooManyOps(t *testing.T) {
 	}
 }
 
+func TestV3TxnTooManyTotalOps(t *testing.T) {
+	integration.BeforeTest(t)
+	maxTxnTotalOps := 128
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, MaxTxnTotalOps: uint(maxTxnTotalOps)})
+	defer clus.Terminate(t)
+
+	kvc := integration.ToGRPC(clus.RandClient()).KV
+
+	// unique keys
+	i := 0
+	putOp := func() *pb.RequestOp {
+		i++
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte(fmt.Sprintf("key-%d", i)), Value: []byte("bar")}}}
+	}
+	// nestedTxn returns a txn with a comparison, half of the limit minus one
+	// operations in its failure branch and a nested txn of n operations in
+	// its success branch, so that every branch is under --max-txn-ops
+	nestedTxn := func(n int) *pb.TxnRequest {
+		txn, newTxn := &pb.TxnRequest{}, &pb.TxnRequest{}
+		txn.Compare = append(txn.Compare, &pb.Compare{Result: pb.Compare_GREATER, Target: pb.Compare_CREATE, Key: []byte("key-0")})
+		for j := 0; j < maxTxnTotalOps/2-1; j++ {
+			txn.Failure = append(txn.Failure, putOp())
+		}
+		for j := 0; j < n; j++ {
+			newTxn.Success = append(newTxn.Success, putOp())
+		}
+		txn.Success = append(txn.Success, &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: newTxn}})
+		return txn
+	}
+	committedIndex := func() uint64 {
+		var idx uint64
+		for _, m := range clus.Members {
+			if ci := m.Server.CommittedIndex(); ci > idx {
+				idx = ci
+			}
+		}
+		return idx
+	}
+
+	idx := committedIndex()
+	_, err := kvc.Txn(context.Background(), nestedTxn(maxTxnTotalOps/2))
+	if !eqErrGRPC(err, rpctypes.ErrGRPCTooManyOps) {
+		t.Errorf("nested txn over the limit: err = %v, want %v", err, rpctypes.ErrGRPCTooManyOps)
+	}
+	if ci := committedIndex(); ci != idx {
+		t.Errorf("nested txn over the limit was proposed, committed index %d, want %d", ci, idx)
+	}
+	if _, err = kvc.Txn(context.Background(), nestedTxn(maxTxnTotalOps/2-1)); err != nil {
+		t.Errorf("nested txn at the limit: err = %v", err)
+	}
+}
+
+// TestV3TxnTooManyTotalOpsDefault ensures the comparisons and operations of a
+// txn are limited to three times --max-txn-ops by default, even when every
+// branch of its nested txns is under --max-txn-ops.
+func TestV3TxnTooManyTotalOpsDefault(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	kvc := integration.ToGRPC(clus.RandClient()).KV
+
+	// unique keys
+	i := 0
+	// nestedTxns returns a txn whose success branch holds n nested txns of
+	// 100 puts each
+	nestedTxns := func(n int) *pb.TxnRequest {
+		txn := &pb.TxnRequest{}
+		for j := 0; j < n; j++ {
+			newTxn := &pb.TxnRequest{}
+			for k := 0; k < 100; k++ {
+				i++
+				newTxn.Success = append(newTxn.Success, &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte(fmt.Sprintf("key-%d", i)), Value: []byte("bar")}}})
+			}
+			txn.Success = append(txn.Success, &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: newTxn}})
+		}
+		return txn
+	}
+
+	// 4 + 400 operations, over 3 * 128
+	_, err := kvc.Txn(context.Background(), nestedTxns(4))
+	if !eqErrGRPC(err, rpctypes.ErrGRPCTooManyOps) {
+		t.Errorf("nested txns over the default limit: err = %v, want %v", err, rpctypes.ErrGRPCTooManyOps)
+	}
+	if _, err = kvc.Txn(context.Background(), nestedTxns(3)); err != nil {
+		t.Errorf("nested txns under the default limit: err = %v", err)
+	}
+}
+
 func TestV3TxnDuplicateKeys(t *testing.T) {
 	integration.BeforeTest(t)
 	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
@@ -1184,6 +1275,62 @@ func TestV3TooLargeRequest(t *testing.T) {
 	}
 }
 
+// TestV3TooLargeValue ensures values larger than MaxValueBytes are rejected
+// before being proposed, and that txns report the op putting such a value.
+func TestV3TooLargeValue(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1, MaxValueBytes: 1024})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	kvc := integration.ToGRPC(cli).KV
+
+	presp, err := cli.Put(context.TODO(), "foo", strings.Repeat("a", 1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rev := presp.Header.Revision
+
+	if _, err = cli.Put(context.TODO(), "foo", strings.Repeat("a", 1025)); err != rpctypes.ErrValueTooLarge {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrValueTooLarge)
+	}
+
+	txn := &pb.TxnRequest{
+		Success: []*pb.RequestOp{
+			{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("bar"), Value: []byte("small")}}},
+			{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("baz"), Value: make([]byte, 2048)}}},
+		},
+	}
+	_, err = kvc.Txn(context.TODO(), txn)
+	if !eqErrGRPC(err, rpctypes.ErrGRPCValueTooLarge) {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrGRPCValueTooLarge)
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = append(violations, br.FieldViolations...)
+		}
+	}
+	if len(violations) != 1 || violations[0].Field != "success[1]" {
+		t.Fatalf("field violations = %v, want a single violation for success[1]", violations)
+	}
+
+	_, err = cli.Txn(context.TODO()).Then(clientv3.OpPut("bar", "small"), clientv3.OpPut("baz", strings.Repeat("a", 2048))).Commit()
+	if err != rpctypes.ErrValueTooLarge {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrValueTooLarge)
+	}
+
+	// rejected requests must not be proposed
+	gresp, err := cli.Get(context.TODO(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gresp.Header.Revision != rev {
+		t.Errorf("revision = %d, want %d", gresp.Header.Revision, rev)
+	}
+}
+
 // TestV3Hash tests hash.
 func TestV3Hash(t *testing.T) {
 	integration.BeforeTest(t)
@@ -1817,8 +1964,8 @@ func TestGRPCRequireLeader(t *testing.T) {
 	}
 	defer client.Close()
 
-	// wait for election timeout, then member[0] will not have a leader.
-	time.Sleep(time.Duration(3*integration.ElectionTicks) * config.TickDuration)
+	// member[0] campaigns once it notices it has no leader.
+	clus.Members[0].WaitRaftState(t, raft.StatePreCandidate)
 
 	md := metadata.Pairs(rpctypes.MetadataRequireLeaderKey, rpctypes.MetadataHasLeader)
 	ctx := metadata.NewOutgoingContext(context.Background(), md)
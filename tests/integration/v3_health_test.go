@@ -0,0 +1,81 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	"go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestV3HealthQuorum ensures the quorum health check fails on a member cut off
+// from the cluster, while the liveness check and the serializable health
+// check keep succeeding.
+func TestV3HealthQuorum(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, UsePeerBridge: true})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	m := clus.Members[(lead+1)%len(clus.Members)]
+
+	mustHealthStatus(t, m, "/health", http.StatusOK)
+	mustHealthStatus(t, m, "/livez", http.StatusOK)
+
+	// the member keeps its leader, but its requests do not reach the others
+	m.PeerBridge().Blackhole()
+	waitHealthStatus(t, m, "/health", http.StatusServiceUnavailable)
+	mustHealthStatus(t, m, "/health?serializable=true", http.StatusOK)
+	mustHealthStatus(t, m, "/livez", http.StatusOK)
+
+	m.PeerBridge().Unblackhole()
+	waitHealthStatus(t, m, "/health", http.StatusOK)
+	mustHealthStatus(t, m, "/livez", http.StatusOK)
+}
+
+func healthStatus(t *testing.T, m *integration.Member, path string) int {
+	tr, err := transport.NewTimeoutTransport(transport.TLSInfo{}, 5*time.Second, 5*time.Second, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := &http.Client{Transport: tr}
+	resp, err := cli.Get(m.ClientURLs[0].String() + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func mustHealthStatus(t *testing.T, m *integration.Member, path string, code int) {
+	if got := healthStatus(t, m, path); got != code {
+		t.Fatalf("expected status %d from %s, got %d", code, path, got)
+	}
+}
+
+func waitHealthStatus(t *testing.T, m *integration.Member, path string, code int) {
+	deadline := time.Now().Add(10 * time.Second)
+	for healthStatus(t, m, path) != code {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected status %d from %s", code, path)
+		}
+		mustHealthStatus(t, m, "/livez", http.StatusOK)
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -25,7 +25,9 @@ import (
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
 	"go.etcd.io/etcd/tests/v3/framework/integration"
+	"go.etcd.io/raft/v3"
 )
 
 func TestMoveLeader(t *testing.T)        { testMoveLeader(t, true) }
@@ -98,6 +100,54 @@ func testMoveLeader(t *testing.T, auto bool) {
 	}
 }
 
+// TestRaftStateObserver ensures that the raft state observers of the old and
+// new leaders are notified of a leader transfer, with the term it happened in.
+func TestRaftStateObserver(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	oldLeadIdx := clus.WaitLeader(t)
+	oldLead := clus.Members[oldLeadIdx]
+	newLead := clus.Members[(oldLeadIdx+1)%3]
+	term := oldLead.Server.Term()
+
+	type change struct {
+		from, to raft.StateType
+		term     uint64
+	}
+	oldLeadc, newLeadc := make(chan change, 8), make(chan change, 8)
+	defer oldLead.Server.RegisterRaftStateObserver(func(from, to raft.StateType, term uint64) { oldLeadc <- change{from, to, term} })()
+	defer newLead.Server.RegisterRaftStateObserver(func(from, to raft.StateType, term uint64) { newLeadc <- change{from, to, term} })()
+
+	ctx, cancel := context.WithTimeout(context.Background(), integration.RequestWaitTimeout)
+	defer cancel()
+	if err := oldLead.Server.MoveLeader(ctx, uint64(oldLead.Server.MemberId()), uint64(newLead.Server.MemberId())); err != nil {
+		t.Fatal(err)
+	}
+	newLead.WaitRaftState(t, raft.StateLeader)
+
+	waitChange := func(c <-chan change, to raft.StateType) change {
+		for {
+			select {
+			case ch := <-c:
+				if ch.to == to {
+					return ch
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("timed out waiting for transition to %s", to)
+			}
+		}
+	}
+	if ch := waitChange(oldLeadc, raft.StateFollower); ch.from != raft.StateLeader || ch.term <= term {
+		t.Errorf("old leader transition = %+v, want from %s in a term after %d", ch, raft.StateLeader, term)
+	}
+	if ch := waitChange(newLeadc, raft.StateLeader); ch.from != raft.StateCandidate || ch.term <= term {
+		t.Errorf("new leader transition = %+v, want from %s in a term after %d", ch, raft.StateCandidate, term)
+	}
+}
+
 // TestMoveLeaderError ensures that request to non-leader fail.
 func TestMoveLeaderError(t *testing.T) {
 	integration.BeforeTest(t)
@@ -273,3 +323,57 @@ func checkFirstCommitNotification(
 
 	return nil
 }
+
+// TestElectionPriority ensures the leadership goes to the healthy member with
+// the highest election priority after elections, and that the other members
+// still elect a leader while that member is down.
+func TestElectionPriority(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, ElectionPriorities: []uint32{0, 1, 10}})
+	defer clus.Terminate(t)
+
+	const preferred = 2
+	waitLeader := func(want int) {
+		deadline := time.Now().Add(10 * time.Second)
+		for clus.WaitLeader(t) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for member %d to become the leader", want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitLeader(preferred)
+
+	prefID := clus.Members[preferred].Server.MemberId()
+	if m := clus.Members[0].Server.Cluster().Member(prefID); m.ElectionPriority != 10 {
+		t.Fatalf("expected published election priority 10, got %d", m.ElectionPriority)
+	}
+
+	for i := 0; i < 3; i++ {
+		term := clus.Members[preferred].Server.Term()
+		// the leadership is handed back right away, so the transfer may end
+		// before the old leader sees it complete
+		target := uint64(clus.Members[i%2].Server.MemberId())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := clus.Members[preferred].Server.MoveLeader(ctx, uint64(prefID), target)
+		cancel()
+		if err != nil && err != errors.ErrTimeoutLeaderTransfer {
+			t.Fatal(err)
+		}
+		waitLeader(preferred)
+		if got := clus.Members[preferred].Server.Term(); got < term+2 {
+			t.Fatalf("expected two elections after term %d, got term %d", term, got)
+		}
+	}
+
+	// the other members do not wait for the preferred one to elect a leader
+	clus.Members[preferred].Stop(t)
+	if lead := clus.WaitMembersForLeader(t, clus.Members[:preferred]); lead == preferred {
+		t.Fatalf("expected a leader among the running members, got %d", lead)
+	}
+	if err := clus.Members[preferred].Restart(t); err != nil {
+		t.Fatal(err)
+	}
+	waitLeader(preferred)
+}
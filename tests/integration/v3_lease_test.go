@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
 	"testing"
 	"time"
 
@@ -424,6 +425,186 @@ func TestV3LeaseCheckpoint(t *testing.T) {
 	}
 }
 
+// TestV3LeaseCheckpointInterval ensures the remaining TTL of a lease is
+// checkpointed at the configured interval, so that it is close to the expected
+// TTL after a leader failover, and that the checkpoints are reported by the
+// metrics.
+func TestV3LeaseCheckpointInterval(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:                    3,
+		EnableLeaseCheckpoint:   true,
+		LeaseCheckpointInterval: 2 * time.Second,
+	})
+	defer clus.Terminate(t)
+
+	// the members of the cluster share the metrics
+	metric := func(m *integration.Member, name string) float64 {
+		v, err := m.Metric(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v == "" {
+			return 0
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	persisted := metric(clus.Members[0], "etcd_debugging_lease_checkpoint_persisted_total")
+	restored := metric(clus.Members[0], "etcd_debugging_lease_checkpoint_restored_total")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lresp, err := integration.ToGRPC(clus.RandClient()).Lease.LeaseGrant(ctx, &pb.LeaseGrantRequest{TTL: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	granted := time.Now()
+
+	// wait for a few checkpoints
+	time.Sleep(5 * time.Second)
+	if metric(clus.Members[0], "etcd_debugging_lease_checkpoint_persisted_total") <= persisted {
+		t.Fatal("expected lease checkpoints to be persisted")
+	}
+
+	leader := clus.Members[clus.WaitLeader(t)]
+	leader.Stop(t)
+	defer leader.Restart(t)
+	newLeader := clus.WaitMembersForLeader(t, clus.Members)
+	if metric(clus.Members[newLeader], "etcd_debugging_lease_checkpoint_restored_total") <= restored {
+		t.Fatal("expected the checkpointed remaining TTL to be restored by the new leader")
+	}
+
+	var ttlresp *pb.LeaseTimeToLiveResponse
+	for i := 0; i < 10; i++ {
+		ttlresp, err = integration.ToGRPC(clus.Members[newLeader].Client).Lease.LeaseTimeToLive(ctx, &pb.LeaseTimeToLiveRequest{ID: lresp.ID})
+		if err == nil {
+			break
+		}
+		if status, ok := status.FromError(err); !ok || status.Code() != codes.Unavailable {
+			t.Fatal(err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the remaining TTL is at most one checkpoint interval longer than
+	// expected, plus the election timeout the new leader extends it by
+	expected := 60*time.Second - time.Since(granted)
+	ttl := time.Duration(ttlresp.TTL) * time.Second
+	if ttl < expected-2*time.Second || ttl > expected+5*time.Second {
+		t.Fatalf("expected lease ttl close to %v, got %v", expected, ttl)
+	}
+}
+
+// TestV3LeaseMetrics ensures the lease metrics follow the grants, renewals,
+// checkpoints and revocations of the leases, and tell expired leases from
+// the leases revoked on request.
+func TestV3LeaseMetrics(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:                    1,
+		EnableLeaseCheckpoint:   true,
+		LeaseCheckpointInterval: time.Second,
+	})
+	defer clus.Terminate(t)
+
+	m := clus.Members[0]
+	metric := func(name string) float64 {
+		v, err := m.Metric(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v == "" {
+			return 0
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	names := []string{
+		"etcd_debugging_lease_granted_total",
+		"etcd_debugging_lease_renewed_total",
+		"etcd_debugging_lease_checkpointed_total",
+		"etcd_debugging_lease_revoked_total",
+		"etcd_debugging_server_lease_expired_total",
+		"etcd_debugging_server_lease_revoke_requested_total",
+	}
+	base := make(map[string]float64)
+	for _, name := range names {
+		base[name] = metric(name)
+	}
+	// expectDeltas checks the increases of the metrics in deltas
+	expectDeltas := func(deltas map[string]float64, active float64) {
+		t.Helper()
+		for name, delta := range deltas {
+			if got := metric(name) - base[name]; got != delta {
+				t.Errorf("%s increased by %v, expected %v", name, got, delta)
+			}
+		}
+		if got := metric("etcd_debugging_lease_active"); got != active {
+			t.Errorf("etcd_debugging_lease_active = %v, expected %v", got, active)
+		}
+	}
+	waitDelta := func(name string, delta float64) {
+		t.Helper()
+		for i := 0; i < 100 && metric(name)-base[name] < delta; i++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	ctx := context.TODO()
+	lc := m.Client.Lease
+	kept, err := lc.Grant(ctx, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := lc.Grant(ctx, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = lc.KeepAliveOnce(ctx, kept.ID); err != nil {
+		t.Fatal(err)
+	}
+	// both leases are checkpointed every second
+	waitDelta("etcd_debugging_lease_checkpointed_total", 2)
+	checkpointed := metric("etcd_debugging_lease_checkpointed_total") - base["etcd_debugging_lease_checkpointed_total"]
+	if checkpointed < 2 {
+		t.Fatalf("expected the leases to be checkpointed, got %v checkpoints", checkpointed)
+	}
+	expectDeltas(map[string]float64{
+		"etcd_debugging_lease_granted_total":                 2,
+		"etcd_debugging_lease_renewed_total":                 1,
+		"etcd_debugging_lease_revoked_total":                 0,
+		"etcd_debugging_server_lease_expired_total":          0,
+		"etcd_debugging_server_lease_revoke_requested_total": 0,
+	}, 2)
+
+	if _, err = lc.Revoke(ctx, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = lc.Grant(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	waitDelta("etcd_debugging_server_lease_expired_total", 1)
+	expectDeltas(map[string]float64{
+		"etcd_debugging_lease_granted_total":                 3,
+		"etcd_debugging_lease_renewed_total":                 1,
+		"etcd_debugging_lease_revoked_total":                 2,
+		"etcd_debugging_server_lease_expired_total":          1,
+		"etcd_debugging_server_lease_revoke_requested_total": 1,
+	}, 1)
+}
+
 // TestV3LeaseExists creates a lease on a random client and confirms it exists in the cluster.
 func TestV3LeaseExists(t *testing.T) {
 	integration.BeforeTest(t)
@@ -0,0 +1,79 @@
+// Copyright 2023 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestV3TriggerSnapshotRecoverFollower ensures a snapshot triggered on the
+// leader compacts its raft log, so that a follower that was down while the
+// entries were written is recovered from that snapshot.
+func TestV3TriggerSnapshotRecoverFollower(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:                   3,
+		SnapshotCount:          1000,
+		SnapshotCatchUpEntries: 5,
+	})
+	defer clus.Terminate(t)
+
+	lead := clus.WaitLeader(t)
+	follower := (lead + 1) % 3
+	clus.Members[follower].Stop(t)
+
+	cli := clus.Client(lead)
+	for i := 0; i < 20; i++ {
+		_, err := cli.Put(context.TODO(), fmt.Sprintf("foo%d", i), "bar")
+		require.NoError(t, err)
+	}
+
+	// the snapshot count is never reached, so only the trigger snapshots
+	resp := clus.Members[lead].TriggerSnapshot(t)
+	require.False(t, resp.InProgress)
+	require.Equal(t, clus.Members[lead].Server.AppliedIndex(), resp.SnapshotIndex)
+	expectMemberLog(t, clus.Members[lead], 5*time.Second, "compacted Raft logs", 1)
+
+	// nothing was applied since, so there is nothing new to snapshot
+	again := clus.Members[lead].TriggerSnapshot(t)
+	require.False(t, again.InProgress)
+	require.Equal(t, resp.SnapshotIndex, again.SnapshotIndex)
+
+	require.NoError(t, clus.Members[follower].Restart(t))
+	clus.WaitLeader(t)
+	expectMemberLog(t, clus.Members[follower], 5*time.Second, "received and saved database snapshot", 1)
+
+	fcli := clus.Client(follower)
+	var gresp *clientv3.GetResponse
+	for i := 0; i < 50; i++ {
+		var err error
+		gresp, err = fcli.Get(context.TODO(), "foo", clientv3.WithPrefix(), clientv3.WithSerializable())
+		if err == nil && gresp.Count == 20 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NotNil(t, gresp)
+	require.Equal(t, int64(20), gresp.Count)
+}
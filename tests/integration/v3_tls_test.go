@@ -17,10 +17,14 @@ package integration
 import (
 	"context"
 	"crypto/tls"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -142,3 +146,62 @@ func TestTLSMinMaxVersion(t *testing.T) {
 		})
 	}
 }
+
+// TestTLSReloadRotate ensures rotated certificate files are served on new
+// handshakes without restarting the cluster, and that a malformed
+// replacement is rejected in favor of the previous certificate.
+func TestTLSReloadRotate(t *testing.T) {
+	integration.BeforeTest(t)
+
+	certsDir := t.TempDir()
+	tlsInfo, err := copyTLSFiles(integration.TestTLSInfo, certsDir)
+	require.NoError(t, err)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:      3,
+		PeerTLS:   &tlsInfo,
+		ClientTLS: &tlsInfo,
+		UseTCP:    true,
+	})
+	defer clus.Terminate(t)
+
+	cc, err := integration.TestTLSInfo.ClientConfig()
+	require.NoError(t, err)
+	servedCN := func() string {
+		u, uerr := url.Parse(clus.Members[0].GRPCURL())
+		require.NoError(t, uerr)
+		conn, derr := tls.Dial("tcp", u.Host, cc)
+		require.NoError(t, derr)
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	put := func() {
+		for _, m := range clus.Members {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_, perr := m.Client.Put(ctx, "foo", "bar")
+			cancel()
+			require.NoError(t, perr)
+		}
+	}
+
+	assert.Equal(t, "example.com", servedCN())
+	put()
+
+	// rotate the certificates in place
+	fixtures := filepath.Dir(integration.TestTLSInfo.CertFile)
+	require.NoError(t, copyFile(filepath.Join(fixtures, "server2.crt"), tlsInfo.CertFile))
+	require.NoError(t, copyFile(filepath.Join(fixtures, "server2.key.insecure"), tlsInfo.KeyFile))
+	assert.Equal(t, "example2.com", servedCN())
+	put()
+
+	// new peer connections are established with the rotated certificates
+	clus.Members[1].Stop(t)
+	require.NoError(t, clus.Members[1].Restart(t))
+	clus.WaitLeader(t)
+	put()
+
+	// a malformed replacement keeps the previous certificate in use
+	require.NoError(t, os.WriteFile(tlsInfo.CertFile, []byte("not a certificate"), 0600))
+	assert.Equal(t, "example2.com", servedCN())
+	put()
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"sort"
 	"sync"
@@ -28,6 +29,7 @@ import (
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3rpc"
 	"go.etcd.io/etcd/tests/v3/framework/integration"
@@ -1467,3 +1469,71 @@ func TestV3WatchProgressWaitsForSync(t *testing.T) {
 		t.Fatal("Wrong revision in progress notification!")
 	}
 }
+
+// TestV3WatchStreamsPerConnectionLimit ensures a client connection cannot
+// open more watch streams than the limit, and that closing streams lets it
+// open new ones.
+func TestV3WatchStreamsPerConnectionLimit(t *testing.T) {
+	integration.BeforeTest(t)
+
+	const limit = 3
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1, MaxWatchStreamsPerConn: limit})
+	defer clus.Terminate(t)
+
+	openStream := func(cli *clientv3.Client) (context.CancelFunc, error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ws, err := integration.ToGRPC(cli).Watch.Watch(ctx)
+		if err == nil {
+			req := &pb.WatchRequest{RequestUnion: &pb.WatchRequest_CreateRequest{
+				CreateRequest: &pb.WatchCreateRequest{Key: []byte("foo")}}}
+			// a rejected stream may be closed before the request is sent,
+			// in which case Recv returns the reason
+			if err = ws.Send(req); err == nil || err == io.EOF {
+				_, err = ws.Recv()
+			}
+		}
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return cancel, nil
+	}
+
+	cli := clus.Client(0)
+	var cancels []context.CancelFunc
+	for i := 0; i < limit; i++ {
+		cancel, err := openStream(cli)
+		require.NoError(t, err, "stream #%d", i)
+		cancels = append(cancels, cancel)
+	}
+	_, err := openStream(cli)
+	require.ErrorIs(t, rpctypes.Error(err), rpctypes.ErrTooManyWatchStreams)
+
+	// the streams of other connections are counted separately
+	other, err := integration.NewClient(t, clientv3.Config{Endpoints: cli.Endpoints()})
+	require.NoError(t, err)
+	defer other.Close()
+	cancel, err := openStream(other)
+	require.NoError(t, err)
+	cancel()
+
+	// closing streams frees their slots, once the server notices
+	cancels[0]()
+	cancels[1]()
+	for i := 0; i < 2; i++ {
+		require.Eventually(t, func() bool {
+			cancel, err = openStream(cli)
+			if err != nil {
+				return false
+			}
+			cancels = append(cancels, cancel)
+			return true
+		}, 5*time.Second, 50*time.Millisecond)
+	}
+	_, err = openStream(cli)
+	require.ErrorIs(t, rpctypes.Error(err), rpctypes.ErrTooManyWatchStreams)
+
+	for _, cancel := range cancels[2:] {
+		cancel()
+	}
+}